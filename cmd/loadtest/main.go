@@ -0,0 +1,398 @@
+// Package main is a load-testing client simulator for the ofenes WebSocket hub.
+//
+// It registers (or logs in) N users over the REST API, connects each one to
+// the same room over WebSocket, and sends chat and video_sync messages at the
+// configured rates. Every probe carries its send time, so the receiving side
+// can measure end-to-end fan-out latency and count missing deliveries.
+//
+// Usage:
+//
+//	go run ./cmd/loadtest -url http://localhost:8080 -clients 200 -duration 1m \
+//	    -chat-rate 0.5 -sync-rate 0.2 -churn 2s
+//
+// Chat probes are persisted like real chat messages, so point this at a
+// disposable database.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/ws"
+
+	"github.com/gorilla/websocket"
+)
+
+// probePrefix marks payloads generated by this tool.
+const probePrefix = "loadtest:"
+
+type options struct {
+	baseURL  string
+	clients  int
+	room     string
+	duration time.Duration
+	chatRate float64
+	syncRate float64
+	churn    time.Duration
+	ramp     time.Duration
+	password string
+}
+
+// stats aggregates results across all simulated clients.
+type stats struct {
+	connects      atomic.Int64
+	connectErrors atomic.Int64
+	reconnects    atomic.Int64
+	sent          atomic.Int64
+	expected      atomic.Int64
+	received      atomic.Int64
+
+	mu         sync.Mutex
+	latencies  []time.Duration
+	closeCodes map[int]int
+}
+
+func (s *stats) observeLatency(d time.Duration) {
+	s.mu.Lock()
+	s.latencies = append(s.latencies, d)
+	s.mu.Unlock()
+}
+
+func (s *stats) observeClose(code int) {
+	s.mu.Lock()
+	s.closeCodes[code]++
+	s.mu.Unlock()
+}
+
+// simClient is one simulated user.
+type simClient struct {
+	opts     *options
+	stats    *stats
+	online   *atomic.Int64
+	username string
+	token    string
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:8080", "server base URL")
+	flag.IntVar(&opts.clients, "clients", 50, "number of simulated clients")
+	flag.StringVar(&opts.room, "room", "loadtest", "room to join")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long to send traffic")
+	flag.Float64Var(&opts.chatRate, "chat-rate", 0.5, "chat messages per second per client")
+	flag.Float64Var(&opts.syncRate, "sync-rate", 0.1, "video_sync messages per second per client")
+	flag.DurationVar(&opts.churn, "churn", 0, "reconnect one random client every interval (0 disables)")
+	flag.DurationVar(&opts.ramp, "ramp", 5*time.Second, "spread initial connections over this period")
+	flag.StringVar(&opts.password, "password", "loadtest-password", "password for generated users")
+	flag.Parse()
+
+	st := &stats{closeCodes: make(map[int]int)}
+	var online atomic.Int64
+	run := strconv.FormatInt(time.Now().Unix(), 36)
+
+	// --- Create users and connect ---
+	clients := make([]*simClient, opts.clients)
+	var wg sync.WaitGroup
+	for i := range clients {
+		c := &simClient{
+			opts:     opts,
+			stats:    st,
+			online:   &online,
+			username: fmt.Sprintf("lt-%s-%d", run, i),
+		}
+		clients[i] = c
+
+		wg.Add(1)
+		go func(delay time.Duration) {
+			defer wg.Done()
+			time.Sleep(delay)
+			if err := c.authenticate(); err != nil {
+				log.Printf("loadtest: auth failed for %s: %v", c.username, err)
+				st.connectErrors.Add(1)
+				return
+			}
+			c.connect()
+		}(time.Duration(i) * opts.ramp / time.Duration(max(opts.clients, 1)))
+	}
+	wg.Wait()
+	log.Printf("loadtest: %d/%d clients connected", online.Load(), opts.clients)
+
+	// --- Generate traffic ---
+	stop := make(chan struct{})
+	var traffic sync.WaitGroup
+	for _, c := range clients {
+		traffic.Add(1)
+		go func() {
+			defer traffic.Done()
+			c.generate(stop)
+		}()
+	}
+	if opts.churn > 0 {
+		go churn(clients, opts.churn, stop, st)
+	}
+
+	time.Sleep(opts.duration)
+	close(stop)
+	traffic.Wait()
+
+	// Let in-flight messages arrive before tearing down.
+	time.Sleep(2 * time.Second)
+	for _, c := range clients {
+		c.disconnect()
+	}
+
+	report(st, opts)
+}
+
+// authenticate registers the user, falling back to login if it already exists.
+func (c *simClient) authenticate() error {
+	body, _ := json.Marshal(models.RegisterRequest{Username: c.username, Password: c.opts.password})
+
+	resp, err := http.Post(c.opts.baseURL+"/api/register", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
+		resp, err = http.Post(c.opts.baseURL+"/api/login", "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var env struct {
+		Data models.AuthResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return err
+	}
+	c.token = env.Data.Token
+	return nil
+}
+
+// connect dials the hub and starts the read loop.
+func (c *simClient) connect() {
+	u, err := url.Parse(c.opts.baseURL)
+	if err != nil {
+		log.Fatalf("loadtest: invalid url: %v", err)
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = "/ws"
+	u.RawQuery = url.Values{"token": {c.token}, "room": {c.opts.room}}.Encode()
+
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{ws.ProtocolJSONv1}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		c.stats.connectErrors.Add(1)
+		return
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	c.stats.connects.Add(1)
+	c.online.Add(1)
+
+	go c.readLoop(conn)
+}
+
+// disconnect closes the current connection, if any.
+func (c *simClient) disconnect() {
+	c.mu.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "loadtest done"),
+			time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
+
+// readLoop consumes frames and records latency for every probe received.
+func (c *simClient) readLoop(conn *websocket.Conn) {
+	defer c.online.Add(-1)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ce, ok := err.(*websocket.CloseError); ok {
+				c.stats.observeClose(ce.Code)
+			}
+			return
+		}
+
+		for _, msg := range splitFrame(data) {
+			if !strings.HasPrefix(msg.Payload, probePrefix) {
+				continue
+			}
+			sentAt, err := strconv.ParseInt(strings.TrimPrefix(msg.Payload, probePrefix), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.stats.received.Add(1)
+			c.stats.observeLatency(time.Since(time.Unix(0, sentAt)))
+		}
+	}
+}
+
+// generate sends chat and video_sync probes until stop is closed.
+func (c *simClient) generate(stop <-chan struct{}) {
+	chat := ticker(c.opts.chatRate)
+	sync := ticker(c.opts.syncRate)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-chat:
+			c.sendProbe(models.MsgTypeChat)
+		case <-sync:
+			c.sendProbe(models.MsgTypeVideoSync)
+		}
+	}
+}
+
+// sendProbe writes one timestamped message. Every client currently online
+// (including the sender) is expected to receive it.
+func (c *simClient) sendProbe(msgType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return
+	}
+
+	now := time.Now()
+	data, _ := json.Marshal(models.Message{
+		Type:      msgType,
+		Sender:    c.username,
+		Payload:   probePrefix + strconv.FormatInt(now.UnixNano(), 10),
+		Timestamp: now,
+	})
+
+	c.conn.SetWriteDeadline(now.Add(5 * time.Second))
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return
+	}
+	c.stats.sent.Add(1)
+	c.stats.expected.Add(c.online.Load())
+}
+
+// churn periodically reconnects a random client.
+func churn(clients []*simClient, every time.Duration, stop <-chan struct{}, st *stats) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			c := clients[rand.IntN(len(clients))]
+			if c.token == "" {
+				continue
+			}
+			c.disconnect()
+			c.connect()
+			st.reconnects.Add(1)
+		}
+	}
+}
+
+// ticker returns a channel firing at rate per second, jittered so clients
+// don't send in lockstep. A non-positive rate never fires.
+func ticker(rate float64) <-chan time.Time {
+	if rate <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	ch := make(chan time.Time)
+	go func() {
+		time.Sleep(time.Duration(rand.Int64N(int64(interval))))
+		t := time.NewTicker(interval)
+		for now := range t.C {
+			ch <- now
+		}
+	}()
+	return ch
+}
+
+// splitFrame decodes a hub frame in any WS_BATCH_MODE.
+func splitFrame(data []byte) []models.Message {
+	var out []models.Message
+	for _, part := range bytes.Split(data, []byte{'\n'}) {
+		part = bytes.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		if part[0] == '[' {
+			var batch []models.Message
+			if json.Unmarshal(part, &batch) == nil {
+				out = append(out, batch...)
+			}
+			continue
+		}
+		var msg models.Message
+		if json.Unmarshal(part, &msg) == nil {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// report prints the final statistics.
+func report(st *stats, opts *options) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sort.Slice(st.latencies, func(i, j int) bool { return st.latencies[i] < st.latencies[j] })
+	pct := func(p float64) time.Duration {
+		if len(st.latencies) == 0 {
+			return 0
+		}
+		return st.latencies[int(p*float64(len(st.latencies)-1))]
+	}
+
+	expected, received := st.expected.Load(), st.received.Load()
+	delivery := 0.0
+	if expected > 0 {
+		delivery = float64(received) / float64(expected) * 100
+	}
+
+	fmt.Println("=== ofenes load test ===")
+	fmt.Printf("clients:          %d (room=%s, duration=%s)\n", opts.clients, opts.room, opts.duration)
+	fmt.Printf("connects:         %d ok, %d failed, %d churn reconnects\n",
+		st.connects.Load(), st.connectErrors.Load(), st.reconnects.Load())
+	fmt.Printf("messages sent:    %d\n", st.sent.Load())
+	fmt.Printf("deliveries:       %d / %d expected (%.2f%%)\n", received, expected, delivery)
+	fmt.Printf("dropped (approx): %d\n", max(expected-received, 0))
+	fmt.Printf("latency:          p50=%s p90=%s p99=%s max=%s\n",
+		pct(0.50), pct(0.90), pct(0.99), pct(1))
+	if len(st.closeCodes) > 0 {
+		fmt.Printf("server closes:    %v\n", st.closeCodes)
+	}
+}
@@ -6,58 +6,673 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"ofenes/internal/analytics"
 	"ofenes/internal/app"
+	"ofenes/internal/auth"
+	"ofenes/internal/authz"
+	"ofenes/internal/challenge"
+	"ofenes/internal/clock"
 	"ofenes/internal/config"
 	"ofenes/internal/database"
+	"ofenes/internal/entitlement"
+	"ofenes/internal/handoff"
+	"ofenes/internal/hlsproxy"
+	"ofenes/internal/idgen"
+	"ofenes/internal/jobs"
+	"ofenes/internal/ldap"
+	"ofenes/internal/legal"
+	"ofenes/internal/media"
+	"ofenes/internal/metadata"
+	"ofenes/internal/metrics"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/origin"
+	"ofenes/internal/quota"
 	"ofenes/internal/repository"
+	"ofenes/internal/retention"
 	"ofenes/internal/router"
+	"ofenes/internal/stats"
+	"ofenes/internal/transcode"
+	"ofenes/internal/trust"
+	"ofenes/internal/webhook"
 	"ofenes/internal/ws"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish once the
+// server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// --- Load Configuration ---
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
+	if report := cfg.Report(); report != "" {
+		log.Print(report)
+	}
+	// With PRODUCTION set, Load has rejected these already.
+	for _, unsafe := range cfg.Unsafe() {
+		log.Printf("config: warning: %s (refused with PRODUCTION=true)", unsafe.Message)
+	}
 
-	// --- Connect to PostgreSQL ---
-	ctx := context.Background()
-	pool, err := database.Connect(ctx, cfg.DatabaseURL, cfg.DatabasePoolSize)
-	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+	// --- Create Metrics Registry ---
+	metricsRegistry := metrics.NewRegistry()
+
+	// Cancelled on SIGINT/SIGTERM, or once handed over on SIGUSR2: stops
+	// background jobs and starts the shutdown below.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// --- Connect to Storage and Create Repositories ---
+	var (
+		pool             *pgxpool.Pool // nil unless STORAGE_BACKEND=postgres
+		userRepo         repository.UserRepository
+		roomRepo         repository.RoomRepository
+		messageRepo      repository.MessageRepository
+		mediaRepo        repository.MediaSessionRepository
+		fileRepo         repository.SharedFileRepository
+		mediaFileRepo    repository.MediaFileRepository
+		subtitleRepo     repository.SubtitleRepository
+		libraryRepo      repository.LibraryRepository
+		metadataRepo     repository.MetadataRepository
+		auditRepo        repository.AuditRepository
+		deadLetterRepo   repository.DeadLetterRepository
+		reportRepo       repository.ReportRepository
+		wordFilterRepo   repository.WordFilterRepository
+		originRepo       repository.AllowedOriginRepository
+		announcementRepo repository.AnnouncementRepository
+		instanceRepo     repository.InstanceRepository
+		inviteRepo       repository.InviteRepository
+		legalRepo        repository.LegalRepository
+		roleRepo         repository.RoleRepository
+	)
+	switch cfg.StorageBackend {
+	case "mongo":
+		db, err := database.ConnectMongo(ctx, cfg.MongoURL, cfg.MongoDatabase)
+		if err != nil {
+			log.Fatalf("failed to connect to database: %v", err)
+		}
+		defer db.Client().Disconnect(context.Background())
+
+		if err := database.MigrateMongo(ctx, db); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
+		}
+
+		userRepo = repository.NewMongoUserRepo(db)
+		roomRepo = repository.NewMongoRoomRepo(db)
+		messageRepo = repository.NewMongoMessageRepo(db)
+		mediaRepo = repository.NewMongoMediaSessionRepo(db)
+		fileRepo = repository.NewMongoSharedFileRepo(db)
+		mediaFileRepo = repository.NewMongoMediaFileRepo(db)
+		subtitleRepo = repository.NewMongoSubtitleRepo(db)
+		libraryRepo = repository.NewMongoLibraryRepo(db)
+		metadataRepo = repository.NewMongoMetadataRepo(db)
+		auditRepo = repository.NewMongoAuditRepo(db)
+		deadLetterRepo = repository.NewMongoDeadLetterRepo(db)
+		reportRepo = repository.NewMongoReportRepo(db)
+		wordFilterRepo = repository.NewMongoWordFilterRepo(db)
+		originRepo = repository.NewMongoAllowedOriginRepo(db)
+		announcementRepo = repository.NewMongoAnnouncementRepo(db)
+		instanceRepo = repository.NewMongoInstanceRepo(db)
+		inviteRepo = repository.NewMongoInviteRepo(db)
+		legalRepo = repository.NewMongoLegalRepo(db)
+		roleRepo = repository.NewMongoRoleRepo(db)
+
+	case "bolt":
+		db, err := database.OpenBolt(cfg.BoltPath, cfg.BoltCompact)
+		if err != nil {
+			log.Fatalf("failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		if err := database.MigrateBolt(db); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
+		}
+
+		userRepo = repository.NewBoltUserRepo(db)
+		roomRepo = repository.NewBoltRoomRepo(db)
+		messageRepo = repository.NewBoltMessageRepo(db)
+		mediaRepo = repository.NewBoltMediaSessionRepo(db)
+		fileRepo = repository.NewBoltSharedFileRepo(db)
+		mediaFileRepo = repository.NewBoltMediaFileRepo(db)
+		subtitleRepo = repository.NewBoltSubtitleRepo(db)
+		libraryRepo = repository.NewBoltLibraryRepo(db)
+		metadataRepo = repository.NewBoltMetadataRepo(db)
+		auditRepo = repository.NewBoltAuditRepo(db)
+		deadLetterRepo = repository.NewBoltDeadLetterRepo(db)
+		reportRepo = repository.NewBoltReportRepo(db)
+		wordFilterRepo = repository.NewBoltWordFilterRepo(db)
+		originRepo = repository.NewBoltAllowedOriginRepo(db)
+		announcementRepo = repository.NewBoltAnnouncementRepo(db)
+		instanceRepo = repository.NewBoltInstanceRepo(db)
+		inviteRepo = repository.NewBoltInviteRepo(db)
+		legalRepo = repository.NewBoltLegalRepo(db)
+		roleRepo = repository.NewBoltRoleRepo(db)
+
+	default:
+		pool, err = database.Connect(ctx, cfg.DatabaseURL, database.PoolOptions{
+			MaxConns:         cfg.DatabasePoolSize,
+			MinConns:         cfg.DatabaseMinConns,
+			MaxConnLifetime:  cfg.DatabaseConnMaxLifetime,
+			MaxConnIdleTime:  cfg.DatabaseConnMaxIdle,
+			StatementTimeout: cfg.DatabaseStatementTimeout,
+			SlowQuery:        cfg.DatabaseSlowQuery,
+			Metrics:          metricsRegistry,
+		})
+		if err != nil {
+			log.Fatalf("failed to connect to database: %v", err)
+		}
+		defer pool.Close()
+
+		if err := database.Migrate(ctx, pool); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
+		}
+
+		userRepo = repository.NewPgUserRepo(pool)
+		roomRepo = repository.NewPgRoomRepo(pool)
+		messageRepo = repository.NewPgMessageRepo(pool)
+		mediaRepo = repository.NewPgMediaSessionRepo(pool)
+		fileRepo = repository.NewPgSharedFileRepo(pool)
+		mediaFileRepo = repository.NewPgMediaFileRepo(pool)
+		subtitleRepo = repository.NewPgSubtitleRepo(pool)
+		libraryRepo = repository.NewPgLibraryRepo(pool)
+		metadataRepo = repository.NewPgMetadataRepo(pool)
+		auditRepo = repository.NewPgAuditRepo(pool)
+		deadLetterRepo = repository.NewPgDeadLetterRepo(pool)
+		reportRepo = repository.NewPgReportRepo(pool)
+		wordFilterRepo = repository.NewPgWordFilterRepo(pool)
+		originRepo = repository.NewPgAllowedOriginRepo(pool)
+		announcementRepo = repository.NewPgAnnouncementRepo(pool)
+		instanceRepo = repository.NewPgInstanceRepo(pool)
+		inviteRepo = repository.NewPgInviteRepo(pool)
+		legalRepo = repository.NewPgLegalRepo(pool)
+		roleRepo = repository.NewPgRoleRepo(pool)
+	}
+	userRepo = repository.NewCachedUserRepo(userRepo, cfg.UserCacheSize, cfg.UserCacheTTL)
+
+	// Transactions are only atomic on PostgreSQL; other backends run the steps in turn.
+	var uow repository.UnitOfWork = repository.NewNoTxUnitOfWork(repository.Repos{
+		Users: userRepo, Rooms: roomRepo, Messages: messageRepo, Media: mediaRepo, Files: fileRepo, MediaFiles: mediaFileRepo, Subtitles: subtitleRepo,
+		Library: libraryRepo, Metadata: metadataRepo, Audit: auditRepo, DeadLetters: deadLetterRepo, Reports: reportRepo, WordFilters: wordFilterRepo, AllowedOrigins: originRepo, Announcements: announcementRepo, Instance: instanceRepo, Invites: inviteRepo, Legal: legalRepo, Roles: roleRepo,
+	})
+	if pool != nil {
+		uow = repository.NewPgUnitOfWork(pool, userRepo)
 	}
-	defer pool.Close()
 
-	// --- Run Migrations ---
-	if err := database.Migrate(ctx, pool); err != nil {
-		log.Fatalf("failed to run migrations: %v", err)
+	// --- Create Ephemeral Stores (Redis if configured, else in-memory) ---
+	ephemeral := repository.NewMemoryEphemeralStores()
+	if cfg.RedisURL != "" {
+		rdb, err := database.ConnectRedis(ctx, cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("failed to connect to redis: %v", err)
+		}
+		defer rdb.Close()
+		ephemeral = repository.NewRedisEphemeralStores(rdb, cfg.RedisKeyPrefix)
 	}
 
-	// --- Create Repositories (PostgreSQL) ---
-	userRepo := repository.NewPgUserRepo(pool)
-	roomRepo := repository.NewPgRoomRepo(pool)
-	messageRepo := repository.NewPgMessageRepo(pool)
-	mediaRepo := repository.NewPgMediaSessionRepo(pool)
-	fileRepo := repository.NewPgSharedFileRepo(pool)
+	// --- Create Usage Stats Collector (admin overview) ---
+	statsCollector := stats.NewCollector(cfg.StatsRetentionDays)
+
+	// --- Create Watch Analytics Tracker (optional) ---
+	var tracker *analytics.Tracker
+	var watchRecorder ws.WatchRecorder // stays a nil interface when disabled
+	if cfg.AnalyticsEnabled {
+		tracker = analytics.NewTracker(cfg.AnalyticsHistory)
+		watchRecorder = tracker
+	}
+
+	// --- Create HLS Proxy (optional) ---
+	var hlsProxy *hlsproxy.Proxy
+	var videoSources ws.VideoSourceTracker // stays a nil interface when disabled
+	if cfg.HLSProxyEnabled {
+		hlsProxy = hlsproxy.New(hlsproxy.Options{
+			Secret:       cfg.JWTSecret,
+			CacheBytes:   int64(cfg.HLSProxyCacheMB) << 20,
+			AllowPrivate: cfg.HLSProxyAllowPrivate,
+		})
+		videoSources = hlsProxy
+	}
 
 	// --- Create WebSocket Hub ---
-	hub := ws.NewHub(messageRepo)
-	go hub.Run()
+	slowClientPolicy, err := ws.ParseSlowClientPolicy(cfg.WSSlowClientPolicy)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	backend, err := ws.ParseBackend(cfg.WSBackend)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	batchMode, err := ws.ParseBatchMode(cfg.WSBatchMode)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	keepaliveMode, err := ws.ParseKeepaliveMode(cfg.WSKeepaliveMode)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	payloadLimits, err := ws.ParsePayloadLimits(cfg.WSPayloadLimits)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	rateLimits, err := ws.ParseRateLimits(cfg.WSRateLimits)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	hostFailover, err := ws.ParseHostFailover(cfg.WSHostFailover)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	qualityMode, err := ws.ParseQualityMode(cfg.WSQualityMode)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	pauseOnDisconnect, err := ws.ParsePauseOnDisconnect(cfg.WSPauseOnDisconnect)
+	if err != nil {
+		log.Fatalf("invalid websocket config: %v", err)
+	}
+	webhookURLs, err := webhook.ParseURLs(cfg.NowPlayingWebhookURLs)
+	if err != nil {
+		log.Fatalf("invalid webhook config: %v", err)
+	}
+	corsOptions, err := middleware.NewCORSOptions(cfg.AllowOrigins, cfg.CORSExposeHeaders, cfg.CORSRouteOrigins)
+	if err != nil {
+		log.Fatalf("invalid CORS config: %v", err)
+	}
+
+	// Origins added by admins at runtime extend CORS_ORIGINS (not the
+	// per-route origins) for CORS and WebSocket upgrades alike.
+	runtimeOrigins := &origin.Dynamic{}
+	if err := runtimeOrigins.Reload(ctx, originRepo); err != nil {
+		log.Fatalf("failed to load allowed origins: %v", err)
+	}
+	corsOptions.Default.AllowOriginFunc = func(_ *http.Request, o string) bool {
+		return runtimeOrigins.Allowed(o)
+	}
+
+	// The current legal documents; users must accept them (see RequireLegal).
+	legalTracker := legal.New(legalRepo)
+	if err := legalTracker.Reload(ctx); err != nil {
+		log.Fatalf("failed to load legal documents: %v", err)
+	}
+
+	// Custom roles; the built-in ones need no loading.
+	authorizer := &authz.Authorizer{}
+	if err := authorizer.Reload(ctx, roleRepo); err != nil {
+		log.Fatalf("failed to load roles: %v", err)
+	}
+	// The real clock and random IDs; tests swap in clock.Fake and idgen.Sequence.
+	clk, ids := clock.System{}, idgen.UUID{}
+
+	// now_playing events are posted to webhooks only if any are set.
+	var nowPlaying ws.NowPlayingListener // stays a nil interface when none are
+	if len(webhookURLs) > 0 {
+		webhooks := webhook.New(webhookURLs, cfg.NowPlayingWebhookSecret)
+		nowPlaying = webhooks
+		go webhooks.Run(ctx)
+		log.Printf("Posting now playing to %d webhook(s)", len(webhookURLs))
+	}
+
+	// Dead letters are kept in memory; the repository also stores them
+	// only if asked to.
+	var deadLetters repository.DeadLetterRepository
+	if cfg.WSDeadLetterPersist {
+		deadLetters = deadLetterRepo
+	}
+
+	hub := ws.NewHub(messageRepo, ws.Options{
+		WriteWait:             cfg.WSWriteWait,
+		PongWait:              cfg.WSPongWait,
+		PingPeriod:            cfg.WSPingPeriod,
+		KeepaliveMode:         keepaliveMode,
+		LatencyReportInterval: cfg.WSLatencyReport,
+		MaxMessageSize:        cfg.WSMaxMessageSize,
+		PayloadLimits:         payloadLimits,
+		RateLimits:            rateLimits,
+		SendBufferSize:        cfg.WSSendBufferSize,
+		ReadBufferSize:        cfg.WSReadBufferSize,
+		WriteBufferSize:       cfg.WSWriteBufferSize,
+		Backend:               backend,
+		BatchMode:             batchMode,
+		BatchMaxBytes:         cfg.WSBatchMaxBytes,
+		SlowClientPolicy:      slowClientPolicy,
+		OverflowQueueSize:     cfg.WSOverflowQueueSize,
+		ShardCount:            cfg.WSShardCount,
+		ShardThreshold:        cfg.WSShardThreshold,
+		IdleTimeout:           cfg.WSIdleTimeout,
+		IdleWarning:           cfg.WSIdleWarning,
+		MaxLifetime:           cfg.WSMaxLifetime,
+		LifetimeNotice:        cfg.WSLifetimeNotice,
+		ResumeSecret:          cfg.JWTSecret,
+		UserListInterval:      cfg.WSUserListInterval,
+		UserListWindow:        cfg.WSUserListWindow,
+		UserListResync:        cfg.WSUserListResync,
+		SpectatorThreshold:    cfg.WSSpectatorThreshold,
+		SpectatorChatRate:     float64(cfg.WSSpectatorChatPerMinute) / 60,
+		MaxConnections:        cfg.WSMaxConnections,
+		MaxConnectionsPerIP:   cfg.WSMaxConnectionsPerIP,
+		Origins:               origin.New(cfg.AllowOrigins),
+		RuntimeOrigins:        runtimeOrigins,
+		AllowAnyOrigin:        cfg.WSAllowAnyOrigin,
+		TrustProxy:            cfg.WSTrustProxy,
+		Metrics:               metricsRegistry,
+		Stats:                 statsCollector,
+		Analytics:             watchRecorder,
+		VideoSources:          videoSources,
+		LinkTrustLevel:        cfg.TrustLevelLinks,
+		Authz:                 authorizer,
+		Rooms:                 roomRepo,
+		HostFailover:          hostFailover,
+		QualityMode:           qualityMode,
+		SyncTolerance:         cfg.WSSyncTolerance,
+		SyncNudge:             cfg.WSSyncNudge,
+		PauseOnDisconnect:     pauseOnDisconnect,
+		NowPlaying:            nowPlaying,
+		NowPlayingInterval:    cfg.WSNowPlayingInterval,
+		Clock:                 clk,
+		IDs:                   ids,
+		DeadLetterBuffer:      cfg.WSDeadLetterBuffer,
+		DeadLetterRepo:        deadLetters,
+		Announcements:         announcementRepo,
+		Presence:              ephemeral.Presence,
+	})
+
+	// Shadow bans are stored on the user; the Hub enforces them from memory.
+	for filter := (repository.UserFilter{ShadowBanned: true, Limit: 500}); ; filter.Offset += filter.Limit {
+		banned, err := userRepo.List(ctx, filter)
+		if err != nil {
+			log.Fatalf("failed to load shadow bans: %v", err)
+		}
+		for _, u := range banned {
+			hub.SetShadowBanned(u.ID, true)
+		}
+		if len(banned) < filter.Limit {
+			break
+		}
+	}
+	if err := hub.ReloadWordFilters(ctx, wordFilterRepo); err != nil {
+		log.Fatalf("failed to load word filters: %v", err)
+	}
+	if err := hub.ReloadAnnouncements(ctx, announcementRepo); err != nil {
+		log.Fatalf("failed to load announcements: %v", err)
+	}
+	// Not ctx: the Hub outlives the signal to drain (see below), then Stop
+	// ends it.
+	go hub.Run(context.Background())
+
+	// --- Create LDAP Directory (optional) ---
+	var directory *ldap.Directory
+	if cfg.LDAPURL != "" {
+		groupRoles, err := ldap.ParseGroupRoles(cfg.LDAPGroupRoles)
+		if err != nil {
+			log.Fatalf("invalid LDAP config: %v", err)
+		}
+		roles := []string{cfg.LDAPDefaultRole}
+		for _, gr := range groupRoles {
+			roles = append(roles, gr.Role)
+		}
+		for _, role := range roles {
+			if !authorizer.Exists(role) {
+				log.Fatalf("invalid LDAP config: unknown role %q", role)
+			}
+		}
+		directory, err = ldap.New(ldap.Config{
+			URL:            cfg.LDAPURL,
+			StartTLS:       cfg.LDAPStartTLS,
+			BindDN:         cfg.LDAPBindDN,
+			BindPassword:   cfg.LDAPBindPassword,
+			BaseDN:         cfg.LDAPBaseDN,
+			UserFilter:     cfg.LDAPUserFilter,
+			GroupAttribute: cfg.LDAPGroupAttribute,
+			GroupRoles:     groupRoles,
+			DefaultRole:    cfg.LDAPDefaultRole,
+		})
+		if err != nil {
+			log.Fatalf("invalid LDAP config: %v", err)
+		}
+		log.Printf("LDAP logins via %s", cfg.LDAPURL)
+	}
+
+	mediaStore, err := media.NewDiskStore(cfg.MediaDir)
+	if err != nil {
+		log.Fatalf("failed to open media directory: %v", err)
+	}
+	mediaStreams := media.NewStreams(cfg.MediaStreamsPerUser)
+
+	// --- Create Transcode Queue (optional) ---
+	var transcodeJobs *transcode.Jobs
+	if cfg.TranscodeMode != "off" {
+		transcodeJobs = transcode.NewJobs(mediaFileRepo, mediaStore, hub)
+		log.Printf("Transcoding uploads to HLS (%s workers)", cfg.TranscodeMode)
+	}
+
+	// --- Create Metadata Enricher (optional) ---
+	var enricher *metadata.Enricher
+	switch cfg.MetadataProvider {
+	case "tmdb":
+		enricher = metadata.NewEnricher(metadata.NewTMDB(cfg.MetadataAPIKey), metadataRepo, cfg.MetadataCacheTTL)
+	case "omdb":
+		enricher = metadata.NewEnricher(metadata.NewOMDb(cfg.MetadataAPIKey), metadataRepo, cfg.MetadataCacheTTL)
+	}
+	if enricher != nil {
+		log.Printf("Looking up library metadata with %s", cfg.MetadataProvider)
+	}
+
+	// --- Create Password Hasher (bcrypt on a bounded pool) ---
+	passwords := auth.NewHasher(cfg.PasswordHashWorkers, cfg.PasswordHashQueue, metricsRegistry)
+
+	// --- Create Token Revocations (kept in the ephemeral stores) ---
+	revocations := auth.NewRevocations(ephemeral.RevokedTokens, userRepo)
+
+	// --- Create Entitlements (perks of paid tiers, optional) ---
+	var entitlementProvider entitlement.Provider
+	switch cfg.EntitlementsBackend {
+	case "static":
+		static, err := entitlement.ParseStatic(cfg.EntitlementsStatic)
+		if err != nil {
+			log.Fatalf("invalid entitlements config: %v", err)
+		}
+		entitlementProvider = static
+	case "webhook":
+		hook, err := entitlement.NewWebhook(cfg.EntitlementsWebhookURL, cfg.EntitlementsWebhookSecret)
+		if err != nil {
+			log.Fatalf("invalid entitlements config: %v", err)
+		}
+		entitlementProvider = hook
+	}
+	entitlements := entitlement.New(entitlementProvider, cfg.EntitlementsCacheTTL)
+	if entitlementProvider != nil {
+		log.Printf("Entitlements from %s", cfg.EntitlementsBackend)
+	}
+
+	// --- Create Challenges (CAPTCHA or proof of work on registration and login, optional) ---
+	var challengeVerifier challenge.Verifier
+	switch cfg.ChallengeProvider {
+	case challenge.ProviderHCaptcha:
+		challengeVerifier = challenge.NewHCaptcha(cfg.ChallengeSecret, cfg.ChallengeSiteKey)
+	case challenge.ProviderTurnstile:
+		challengeVerifier = challenge.NewTurnstile(cfg.ChallengeSecret, cfg.ChallengeSiteKey)
+	case challenge.ProviderPoW:
+		challengeVerifier = challenge.NewProofOfWork(cfg.JWTSecret, cfg.ChallengePoWDifficulty, ephemeral.Counters)
+	}
+	var challenges *challenge.Guard
+	if challengeVerifier != nil {
+		challenges = challenge.NewGuard(challengeVerifier, cfg.ChallengeMode == "always", ephemeral.Counters, int64(cfg.ChallengeRiskAttempts), cfg.ChallengeRiskWindow)
+		log.Printf("Challenges on registration and login with %s (%s)", cfg.ChallengeProvider, cfg.ChallengeMode)
+	}
+
+	// --- Create Quotas (rooms owned, personal library, upload storage) ---
+	quotaLimits, err := quota.ParseLimits(cfg.Quotas)
+	if err != nil {
+		log.Fatalf("invalid quota config: %v", err)
+	}
+	quotaRoles, err := quota.ParseRoleLimits(cfg.QuotaRoles)
+	if err != nil {
+		log.Fatalf("invalid quota config: %v", err)
+	}
+	quotas := quota.New(quotaLimits, quotaRoles, authorizer, entitlements)
+	quotas.Register(quota.Rooms, func(ctx context.Context, userID string) (int64, error) {
+		n, err := roomRepo.CountOwned(ctx, userID)
+		return int64(n), err
+	})
+	quotas.Register(quota.Library, func(ctx context.Context, userID string) (int64, error) {
+		n, err := libraryRepo.Count(ctx, userID, "")
+		return int64(n), err
+	})
+	quotas.Register(quota.Storage, mediaFileRepo.TotalSizeByUploader)
 
 	// --- Create Application Container ---
-	application := app.New(cfg, pool, userRepo, roomRepo, messageRepo, mediaRepo, fileRepo, hub)
+	application := app.New(cfg, pool, userRepo, roomRepo, messageRepo, mediaRepo, fileRepo, mediaFileRepo, subtitleRepo, libraryRepo, mediaStore, mediaStreams, auditRepo, deadLetters, reportRepo, wordFilterRepo, originRepo, runtimeOrigins, announcementRepo, instanceRepo, inviteRepo, legalRepo, legalTracker, roleRepo, authorizer, quotas, entitlements, challenges, directory, passwords, revocations, uow, ephemeral, hub, metricsRegistry, statsCollector, tracker, hlsProxy, transcodeJobs, enricher, clk, ids)
+
+	// --- Start Background Jobs ---
+	defaultRetention := models.RetentionPolicy{Mode: cfg.MessageRetention}
+	if defaultRetention.Mode == models.RetentionDays {
+		defaultRetention.Days = cfg.MessageRetentionDays
+	}
+	retentionPolicies, err := retention.ParsePolicies(cfg.RetentionPolicies)
+	if err != nil {
+		log.Fatalf("invalid retention config: %v", err)
+	}
+	retentionEngine := retention.NewEngine(retentionPolicies, cfg.RetentionDryRun, auditRepo)
+	retentionEngine.Register(retention.TargetAuditLog, retention.Target{Count: auditRepo.CountBefore, Delete: auditRepo.DeleteBefore})
+	retentionEngine.Register(retention.TargetSessions, retention.Target{
+		Count: ephemeral.Sessions.CountCreatedBefore, Delete: ephemeral.Sessions.DeleteCreatedBefore,
+	})
+	retentionEngine.Register(retention.TargetUploads, media.AbandonedUploads(mediaFileRepo, mediaStore))
+	if tracker != nil {
+		retentionEngine.Register(retention.TargetAnalytics, retention.Target{
+			Count:  func(_ context.Context, before time.Time) (int, error) { return tracker.CountIdle(before), nil },
+			Delete: func(_ context.Context, before time.Time) (int, error) { return tracker.PurgeIdle(before), nil },
+		})
+	}
+	if deadLetters != nil {
+		retentionEngine.Register(retention.TargetDeadLetters, retention.Target{Count: deadLetters.CountBefore, Delete: deadLetters.DeleteBefore})
+	}
+
+	scheduler := jobs.NewScheduler()
+	scheduler.Add("message-retention", cfg.CleanupInterval, jobs.NewMessageRetention(roomRepo, messageRepo, defaultRetention).Run)
+	scheduler.Add("retention", cfg.CleanupInterval, retentionEngine.Run)
+	scheduler.Add("trust-levels", cfg.TrustInterval, jobs.NewTrustLevels(userRepo, messageRepo, trust.Policy{
+		MemberAge:       time.Duration(cfg.TrustMemberDays) * 24 * time.Hour,
+		MemberMessages:  cfg.TrustMemberMessages,
+		RegularAge:      time.Duration(cfg.TrustRegularDays) * 24 * time.Hour,
+		RegularMessages: cfg.TrustRegularMessages,
+	}).Run)
+	if cfg.WordFilterReloadInterval > 0 {
+		// Admin changes reload this instance immediately; the job picks up
+		// changes made through other instances.
+		scheduler.Add("word-filters", cfg.WordFilterReloadInterval, func(ctx context.Context) error {
+			return hub.ReloadWordFilters(ctx, wordFilterRepo)
+		})
+	}
+	if cfg.AnnouncementReloadInterval > 0 {
+		// Like word filters; the Hub also starts and ends scheduled
+		// announcements on its own.
+		scheduler.Add("announcements", cfg.AnnouncementReloadInterval, func(ctx context.Context) error {
+			return hub.ReloadAnnouncements(ctx, announcementRepo)
+		})
+	}
+	if cfg.OriginReloadInterval > 0 {
+		scheduler.Add("origins", cfg.OriginReloadInterval, func(ctx context.Context) error {
+			return runtimeOrigins.Reload(ctx, originRepo)
+		})
+	}
+	if cfg.RoleReloadInterval > 0 {
+		scheduler.Add("roles", cfg.RoleReloadInterval, func(ctx context.Context) error {
+			return authorizer.Reload(ctx, roleRepo)
+		})
+	}
+	if cfg.LegalReloadInterval > 0 {
+		scheduler.Add("legal", cfg.LegalReloadInterval, func(ctx context.Context) error {
+			return legalTracker.Reload(ctx)
+		})
+	}
+	if cfg.RotatesJWTSecret() {
+		scheduler.Add("jwt-secret", cfg.SecretsRotationInterval, func(ctx context.Context) error {
+			rotated, err := cfg.RotateJWTSecret(ctx)
+			if rotated {
+				log.Printf("JWT secret rotated; tokens signed with the previous one are accepted for %s", cfg.JWTExpiry)
+			}
+			return err
+		})
+	}
+	if cfg.TranscodeMode == "local" {
+		hostname, _ := os.Hostname()
+		worker := &transcode.Worker{
+			Queue:      transcodeJobs.Local("local:" + hostname),
+			FFmpeg:     cfg.FFmpegPath,
+			Renditions: cfg.TranscodeRenditions,
+		}
+		scheduler.Add("transcode", cfg.TranscodeInterval, worker.Run)
+	}
+	scheduler.Start(ctx)
 
 	// --- Create Router (wires routes + middleware) ---
-	handler := router.New(application)
+	handler := router.New(application, corsOptions)
 
 	// --- Start Server ---
+	// The listener may be handed over by the previous process or systemd,
+	// and is handed over to the next one on SIGUSR2 (see handoff).
 	addr := ":" + cfg.Port
-	log.Printf("Backend server starting on http://localhost%s", addr)
-	if err := http.ListenAndServe(addr, handler); err != nil {
+	ln, how, err := handoff.Listen(addr, cfg.Handoff())
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", addr, err)
+	}
+	upgrades := make(chan os.Signal, 1)
+	if cfg.HandoffEnabled {
+		handoff.NotifyUpgrade(upgrades)
+	}
+	server := &http.Server{Handler: handler}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for ctx.Err() == nil {
+			select {
+			case <-ctx.Done():
+			case <-upgrades:
+				log.Println("Handing over to a new process")
+				pid, err := handoff.Upgrade(ln, cfg.HandoffReadyTimeout)
+				if err != nil {
+					log.Printf("handoff: %v; still serving", err)
+					continue
+				}
+				log.Printf("Handed over to pid %d", pid)
+				stop()
+			}
+		}
+		log.Println("Shutting down")
+		// Stop accepting first, so clients reconnecting below reach the
+		// next process, or wait for it in the socket's backlog.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.WSDrainWindow+shutdownTimeout)
+		defer cancel()
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- server.Shutdown(shutdownCtx) }()
+		// WebSocket connections are hijacked, so Shutdown does not see them:
+		// the Hub moves them over, then closes the rest.
+		hub.Drain(cfg.WSDrainWindow)
+		hub.Stop()
+		if err := <-shutdownErr; err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+
+	log.Printf("Backend server starting on http://localhost%s (listener %s)", addr, how)
+	if err := handoff.Ready(cfg.Handoff()); err != nil {
+		log.Printf("handoff: %v", err)
+	}
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("server failed: %v", err)
 	}
+	<-stopped
+	log.Println("Server stopped")
 }
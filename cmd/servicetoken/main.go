@@ -0,0 +1,55 @@
+// Package main issues service tokens for internal callers of the ofenes
+// server — metrics scrapers, SFU webhooks, job workers.
+//
+// It reads SERVICE_JWT_SECRET, JWT_ISSUER and JWT_AUDIENCE from the
+// environment like the server, so run it with the server's configuration:
+//
+//	go run ./cmd/servicetoken -service prometheus -scopes metrics -ttl 2160h
+//
+// The token is printed to stdout. Callers send it as
+// "Authorization: Bearer <token>". To revoke service tokens, rotate
+// SERVICE_JWT_SECRET.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ofenes/internal/auth"
+	"ofenes/internal/config"
+)
+
+func main() {
+	service := flag.String("service", "", "name of the calling service (required)")
+	scopes := flag.String("scopes", auth.ScopeMetrics, "comma-separated scopes to grant")
+	ttl := flag.Duration("ttl", 90*24*time.Hour, "token lifetime (0 = never expires)")
+	flag.Parse()
+
+	if *service == "" {
+		log.Fatal("servicetoken: -service is required")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("servicetoken: %v", err)
+	}
+	if cfg.ServiceJWTSecret == "" {
+		log.Fatal("servicetoken: SERVICE_JWT_SECRET is not set")
+	}
+
+	var granted []string
+	for _, s := range strings.Split(*scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			granted = append(granted, s)
+		}
+	}
+	tc := cfg.ServiceToken()
+	tc.Expiry = *ttl
+	token, err := auth.GenerateServiceToken(*service, granted, tc)
+	if err != nil {
+		log.Fatalf("servicetoken: %v", err)
+	}
+	fmt.Println(token)
+}
@@ -0,0 +1,75 @@
+// Package main is a remote transcode worker for the ofenes server.
+//
+// With TRANSCODE_MODE=remote the server queues completed uploads for
+// workers like this one instead of running ffmpeg itself. The worker claims
+// a job, downloads the upload, makes its poster and preview sprite and
+// converts it to HLS renditions with ffmpeg, uploads all of it back, then
+// looks for the next.
+//
+// It authenticates with a service token granting the transcode scope,
+// read from a file so it does not show up in process lists. The token's
+// service name is the worker's name, so issue one per worker:
+//
+//	go run ./cmd/servicetoken -service transcoder-1 -scopes transcode > transcoder-1.token
+//	go run ./cmd/transcoder -url https://watch.example.com -token-file transcoder-1.token -renditions 1080,720,480
+//
+// Run as many workers as there are machines to spare; each transcodes one
+// upload at a time.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"ofenes/internal/config"
+	"ofenes/internal/transcode"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "base URL of the server")
+	tokenFile := flag.String("token-file", "", "file holding the service token (required)")
+	ffmpeg := flag.String("ffmpeg", "ffmpeg", "path of the ffmpeg binary")
+	renditions := flag.String("renditions", "720,480", "comma-separated heights of the renditions")
+	interval := flag.Duration("interval", 10*time.Second, "how often to look for jobs when idle")
+	flag.Parse()
+
+	if *tokenFile == "" {
+		log.Fatal("transcoder: -token-file is required")
+	}
+	raw, err := os.ReadFile(*tokenFile)
+	if err != nil {
+		log.Fatalf("transcoder: %v", err)
+	}
+	token := strings.TrimSpace(string(raw))
+	heights, err := config.ParseRenditions(*renditions)
+	if err != nil {
+		log.Fatalf("transcoder: -renditions: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker := &transcode.Worker{
+		Queue:      &transcode.RemoteQueue{BaseURL: *baseURL, Token: token},
+		FFmpeg:     *ffmpeg,
+		Renditions: heights,
+	}
+	log.Printf("transcoder: taking jobs from %s", *baseURL)
+	for {
+		if err := worker.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("transcoder: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("transcoder: stopped")
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
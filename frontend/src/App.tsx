@@ -162,9 +162,10 @@ function ThemePicker() {
 function Dashboard() {
     const { user, token, logout } = useAuth()
 
-    const { messages, sendMessage, sendDirect, readyState } = useWebSocket({
+    const { messages, sendMessage, sendDirect, readyState, closeInfo } = useWebSocket({
         token,
         username: user?.username ?? '',
+        onAuthExpired: logout,
     })
 
     const isConnected = readyState === 'open'
@@ -275,6 +276,7 @@ function Dashboard() {
                             messages={messages}
                             onSend={sendMessage}
                             readyState={readyState}
+                            closeMessage={closeInfo?.message}
                             currentUsername={user?.username ?? ''}
                             connectedUsers={webrtc.connectedUsers}
                             width={chatWidth}
@@ -8,6 +8,8 @@ interface ChatProps {
     messages: Message[]
     onSend: (type: Message['type'], payload: string) => void
     readyState: string
+    /** Why the server closed the connection, if it did */
+    closeMessage?: string
     currentUsername: string
     connectedUsers: string[]
     width: number
@@ -18,6 +20,7 @@ export function Chat({
     messages,
     onSend,
     readyState,
+    closeMessage,
     currentUsername,
     connectedUsers,
     width,
@@ -28,7 +31,7 @@ export function Chat({
     const isDragging = useRef(false)
 
     const isConnected = readyState === 'open'
-    const chatMessages = messages.filter((m) => m.type === 'chat' || m.type === 'system')
+    const chatMessages = messages.filter((m) => m.type === 'chat' || m.type === 'system' || m.type === 'error' || m.type === 'room_role' || (m.type === 'media' && !isPreviewsEvent(m)))
 
     useEffect(() => {
         messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' })
@@ -117,7 +120,7 @@ export function Chat({
                     )}
 
                     {chatMessages.map((msg, i) => {
-                        if (msg.type === 'system') {
+                        if (msg.type === 'system' || msg.type === 'error' || msg.type === 'room_role' || msg.type === 'media') {
                             return <SystemMessage key={i} msg={msg} />
                         }
                         const isOwn = msg.sender === currentUsername
@@ -135,7 +138,7 @@ export function Chat({
                             value={input}
                             onChange={(e) => setInput(e.target.value)}
                             disabled={!isConnected}
-                            placeholder={isConnected ? 'Text here' : 'Reconnecting...'}
+                            placeholder={isConnected ? 'Text here' : closeMessage ?? 'Reconnecting...'}
                             className="min-w-0 flex-1 px-3 py-2 rounded-lg text-sm outline-none transition-all duration-200 disabled:opacity-50"
                             style={{
                                 backgroundColor: 'var(--bg-input)',
@@ -263,14 +266,43 @@ function ChatBubble({ msg, isOwn }: { msg: Message; isOwn: boolean }) {
     )
 }
 
+const ROOM_ROLE_LABELS: Record<string, string> = {
+    owner: 'the owner',
+    cohost: 'a co-host',
+    moderator: 'a moderator',
+    member: 'a member',
+    viewer: 'a viewer',
+}
+
+// Previews arriving are for media lists, not worth a line in the chat.
+function isPreviewsEvent(msg: Message) {
+    try {
+        return (JSON.parse(msg.payload) as { event?: string }).event === 'previews'
+    } catch {
+        return false
+    }
+}
+
 function SystemMessage({ msg }: { msg: Message }) {
     let text = msg.payload
     try {
-        const data = JSON.parse(msg.payload) as { event: string; username: string }
-        if (data.event === 'user_joined') {
+        const data = JSON.parse(msg.payload) as { event: string; username: string; message?: string; closesInSeconds?: number; role?: string; media?: { fileName: string } }
+        if (msg.type === 'error') {
+            text = data.message ?? 'message rejected'
+        } else if (msg.type === 'room_role') {
+            const who = data.username || 'A member'
+            text = data.role ? `${who} is now ${ROOM_ROLE_LABELS[data.role] ?? data.role}` : `${who} was removed from the room`
+        } else if (msg.type === 'media') {
+            const name = data.media?.fileName ?? 'A video'
+            text = data.event === 'playable' ? `${name} is ready to play` : `${name} could not be converted`
+        } else if (data.event === 'user_joined') {
             text = `${data.username} joined`
         } else if (data.event === 'user_left') {
             text = `${data.username} left`
+        } else if (data.event === 'host_changed') {
+            text = data.username ? `${data.username} is now hosting` : 'Nobody is hosting'
+        } else if (data.event === 'idle_warning') {
+            text = `You seem idle — disconnecting in ${data.closesInSeconds ?? 60}s`
         }
     } catch {
         // Fallback to raw payload
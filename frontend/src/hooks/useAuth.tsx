@@ -1,6 +1,6 @@
 import { useState, useCallback, useEffect, createContext, useContext } from 'react'
 import type { ReactNode } from 'react'
-import type { User, AuthResponse, ApiError } from '../types/models'
+import type { User, AuthResponse, ApiResponse } from '../types/models'
 
 const TOKEN_KEY = 'ofenes_token'
 const USER_KEY = 'ofenes_user'
@@ -81,7 +81,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     }, []) // Only on mount — not on every token change
 
     const handleAuthResponse = useCallback((data: AuthResponse) => {
-        setToken(data.token)
+        setToken(data.token ?? null)
         setUser(data.user)
         setError(null)
     }, [])
@@ -97,14 +97,13 @@ export function AuthProvider({ children }: { children: ReactNode }) {
                 body: JSON.stringify({ username, password }),
             })
 
-            if (!res.ok) {
-                const err = (await res.json()) as ApiError
-                setError(err.error)
+            const body = (await res.json()) as ApiResponse<AuthResponse>
+            if (!res.ok || !body.data) {
+                setError(body.error?.message ?? 'Request failed')
                 return false
             }
 
-            const data = (await res.json()) as AuthResponse
-            handleAuthResponse(data)
+            handleAuthResponse(body.data)
             return true
         } catch {
             setError('Network error — is the backend running?')
@@ -125,14 +124,13 @@ export function AuthProvider({ children }: { children: ReactNode }) {
                 body: JSON.stringify({ username, password }),
             })
 
-            if (!res.ok) {
-                const err = (await res.json()) as ApiError
-                setError(err.error)
+            const body = (await res.json()) as ApiResponse<AuthResponse>
+            if (!res.ok || !body.data) {
+                setError(body.error?.message ?? 'Request failed')
                 return false
             }
 
-            const data = (await res.json()) as AuthResponse
-            handleAuthResponse(data)
+            handleAuthResponse(body.data)
             return true
         } catch {
             setError('Network error — is the backend running?')
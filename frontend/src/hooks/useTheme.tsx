@@ -399,7 +399,8 @@ export function ThemeProvider({ children }: { children: ReactNode }) {
 
         fetch('/api/me', { headers: { Authorization: `Bearer ${token}` } })
             .then((res) => res.ok ? res.json() : null)
-            .then((user) => {
+            .then((body) => {
+                const user = body?.data
                 const dbTheme = user?.preferences?.theme
                 if (dbTheme?.mode && dbTheme?.accentColor) {
                     const restored = { ...DEFAULT_THEME, ...dbTheme }
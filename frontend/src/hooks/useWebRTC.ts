@@ -1,5 +1,5 @@
 import { useState, useEffect, useRef, useCallback } from 'react'
-import type { Message } from '../types/models'
+import type { Message, UserListDelta } from '../types/models'
 
 // --- ICE Server Configuration ---
 // STUN servers for NAT traversal. Add TURN servers here for strict NAT/firewall environments.
@@ -63,6 +63,7 @@ export function useWebRTC({ messages, sendDirect, username, isConnected: _isConn
     const isInCallRef = useRef(false)
     const usernameRef = useRef(username)
     const processedIndexRef = useRef(0)
+    const connectedUsersRef = useRef<string[]>([])
 
     usernameRef.current = username
 
@@ -71,7 +72,6 @@ export function useWebRTC({ messages, sendDirect, username, isConnected: _isConn
         sendDirect('webrtc', JSON.stringify({
             event,
             target,
-            sender: usernameRef.current,
             ...data,
         }))
     }, [sendDirect])
@@ -400,8 +400,21 @@ export function useWebRTC({ messages, sendDirect, username, isConnected: _isConn
         const handleMessages = async () => {
             for (const msg of newMessages) {
                 try {
-                    if (msg.type === 'user_list') {
-                        const users = JSON.parse(msg.payload) as string[]
+                    if (msg.type === 'user_list' || msg.type === 'user_list_delta') {
+                        let users: string[]
+                        if (msg.type === 'user_list') {
+                            users = JSON.parse(msg.payload) as string[]
+                        } else {
+                            // Apply the joins and leaves (one name per connection) to the last list.
+                            const delta = JSON.parse(msg.payload) as UserListDelta
+                            users = [...connectedUsersRef.current]
+                            for (const name of delta.left ?? []) {
+                                const i = users.indexOf(name)
+                                if (i !== -1) users.splice(i, 1)
+                            }
+                            users.push(...(delta.joined ?? []))
+                        }
+                        connectedUsersRef.current = users
                         setConnectedUsers(users)
 
                         // Remove peers that are no longer connected
@@ -411,7 +424,8 @@ export function useWebRTC({ messages, sendDirect, username, isConnected: _isConn
                             }
                         })
                     } else if (msg.type === 'webrtc') {
-                        const data = JSON.parse(msg.payload)
+                        // The envelope's sender is set by the server; a sender in the payload could be forged.
+                        const data = { ...JSON.parse(msg.payload), sender: msg.sender }
                         if (data.target !== usernameRef.current) continue
 
                         switch (data.event) {
@@ -1,5 +1,6 @@
 import { useState, useEffect, useRef, useCallback } from 'react'
 import type { Message } from '../types/models'
+import { describeClose, type CloseInfo } from '../types/closeCodes'
 
 export type ReadyState = 'connecting' | 'open' | 'closing' | 'closed'
 
@@ -7,9 +8,25 @@ const INITIAL_RETRY_MS = 1000
 const MAX_RETRY_MS = 30000
 const RETRY_MULTIPLIER = 2
 
+// Wire format negotiated with the server (internal/ws/protocol.go)
+const WS_PROTOCOL = 'ofenes.v1.json'
+
+const ACTIVITY_EVENTS = ['pointerdown', 'keydown', 'mousemove'] as const
+
+/** Runs fn once, on the user's next interaction with the page. */
+function onNextActivity(fn: () => void) {
+    const handler = () => {
+        ACTIVITY_EVENTS.forEach((e) => window.removeEventListener(e, handler))
+        fn()
+    }
+    ACTIVITY_EVENTS.forEach((e) => window.addEventListener(e, handler, { passive: true }))
+}
+
 interface UseWebSocketOptions {
     token: string | null
     username: string
+    /** Called when the server rejects the session (e.g. JWT expired) */
+    onAuthExpired?: () => void
 }
 
 /**
@@ -20,15 +37,17 @@ interface UseWebSocketOptions {
  * - Handles React StrictMode double-mount (debounces connection)
  * - Provides typed sendMessage() and raw sendDirect() for WebRTC signaling
  */
-export function useWebSocket({ token, username }: UseWebSocketOptions) {
+export function useWebSocket({ token, username, onAuthExpired }: UseWebSocketOptions) {
     const [messages, setMessages] = useState<Message[]>([])
     const [readyState, setReadyState] = useState<ReadyState>('closed')
+    const [closeInfo, setCloseInfo] = useState<CloseInfo | null>(null)
 
     const wsRef = useRef<WebSocket | null>(null)
     const retryMs = useRef(INITIAL_RETRY_MS)
     const retryTimeout = useRef<ReturnType<typeof setTimeout> | undefined>(undefined)
     const connectTimeout = useRef<ReturnType<typeof setTimeout> | undefined>(undefined)
     const unmounted = useRef(false)
+    const resumeToken = useRef<string | null>(null)
 
     const connect = useCallback(() => {
         if (!token || unmounted.current) return
@@ -39,40 +58,98 @@ export function useWebSocket({ token, username }: UseWebSocketOptions) {
         }
 
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
-        const wsUrl = `${protocol}//${window.location.host}/ws?token=${encodeURIComponent(token)}&room=general`
+        let wsUrl = `${protocol}//${window.location.host}/ws?token=${encodeURIComponent(token)}&room=general`
+        if (resumeToken.current) {
+            wsUrl += `&resume=${encodeURIComponent(resumeToken.current)}`
+            resumeToken.current = null
+        }
 
-        const ws = new WebSocket(wsUrl)
+        const ws = new WebSocket(wsUrl, [WS_PROTOCOL])
         wsRef.current = ws
 
         ws.onopen = () => {
             if (unmounted.current) return
             setReadyState('open')
+            setCloseInfo(null)
             retryMs.current = INITIAL_RETRY_MS
             console.log('[ws] connected')
         }
 
         ws.onmessage = (event) => {
             if (unmounted.current) return
-            // Go's writePump batches messages with \n separator in a single frame
+            // Go's writePump batches messages into one frame, either joined by
+            // \n (WS_BATCH_MODE=newline) or as a JSON array (json_array)
             const rawData = event.data as string
             const parts = rawData.split('\n').filter(Boolean)
             const parsed: Message[] = []
             for (const part of parts) {
                 try {
-                    parsed.push(JSON.parse(part) as Message)
+                    const value = JSON.parse(part) as Message | Message[]
+                    if (Array.isArray(value)) {
+                        parsed.push(...value)
+                    } else {
+                        parsed.push(value)
+                    }
                 } catch (err) {
                     console.error('[ws] failed to parse message part:', err)
                 }
             }
-            if (parsed.length > 0) {
-                setMessages((prev) => [...prev, ...parsed])
+            // App-level heartbeat (WS_KEEPALIVE_MODE=heartbeat): echo it back
+            // and keep it out of the message list
+            if (parsed.some((m) => m.type === 'heartbeat')) {
+                ws.send(JSON.stringify({ type: 'heartbeat', sender: username, payload: '', timestamp: new Date().toISOString() }))
+            }
+            const visible = parsed.filter((m) => m.type !== 'heartbeat')
+
+            if (visible.length > 0) {
+                setMessages((prev) => [...prev, ...visible])
+            }
+
+            // Server is about to rotate this connection: keep the resume token
+            // so the replacement rejoins without a leave/join announcement
+            const hint = parsed.find((m) => m.type === 'reconnect')
+            if (hint) {
+                try {
+                    resumeToken.current = (JSON.parse(hint.payload) as { resumeToken: string }).resumeToken
+                } catch (err) {
+                    console.error('[ws] invalid reconnect hint:', err)
+                }
+            }
+
+            // Idle warning: prove we're still here on the next user interaction
+            if (parsed.some(isIdleWarning)) {
+                onNextActivity(() => {
+                    if (ws.readyState === WebSocket.OPEN) {
+                        ws.send(JSON.stringify({ type: 'activity', sender: username, payload: '', timestamp: new Date().toISOString() }))
+                    }
+                })
             }
         }
 
         ws.onclose = (event) => {
             if (unmounted.current) return
             setReadyState('closed')
-            console.log(`[ws] disconnected (code=${event.code})`)
+            console.log(`[ws] disconnected (code=${event.code}, reason=${event.reason})`)
+
+            const info = describeClose(event.code)
+            setCloseInfo(info)
+
+            switch (info.reconnect) {
+                case 'immediate':
+                    retryMs.current = INITIAL_RETRY_MS
+                    connect()
+                    return
+                case 'on_activity':
+                    onNextActivity(() => {
+                        if (!unmounted.current) connect()
+                    })
+                    return
+                case 'reauth':
+                    onAuthExpired?.()
+                    return
+                case 'never':
+                    return
+            }
 
             retryTimeout.current = setTimeout(() => {
                 if (unmounted.current) return
@@ -86,7 +163,7 @@ export function useWebSocket({ token, username }: UseWebSocketOptions) {
             if (unmounted.current) return
             setReadyState('closed')
         }
-    }, [token])
+    }, [token, username, onAuthExpired])
 
     useEffect(() => {
         unmounted.current = false
@@ -150,6 +227,16 @@ export function useWebSocket({ token, username }: UseWebSocketOptions) {
         sendMessage,
         sendDirect,
         readyState,
+        closeInfo,
         clearMessages: () => setMessages([]),
     }
 }
+
+function isIdleWarning(msg: Message): boolean {
+    if (msg.type !== 'system') return false
+    try {
+        return (JSON.parse(msg.payload) as { event?: string }).event === 'idle_warning'
+    } catch {
+        return false
+    }
+}
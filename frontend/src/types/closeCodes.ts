@@ -0,0 +1,44 @@
+// WebSocket close codes sent by the server, mirroring internal/ws/closecodes.go.
+
+/** What the client should do after the server closes the connection. */
+export type ReconnectPolicy =
+    | 'immediate'   // reconnect right away (with a resume token if we have one)
+    | 'on_activity' // wait for the user to interact with the page
+    | 'backoff'     // reconnect with exponential backoff
+    | 'reauth'      // the session is no longer valid — log in again
+    | 'never'       // stay disconnected
+
+export interface CloseInfo {
+    code: number
+    message: string
+    reconnect: ReconnectPolicy
+}
+
+export const CLOSE_IDLE_TIMEOUT = 4000
+export const CLOSE_MAX_LIFETIME = 4001
+export const CLOSE_AUTH_EXPIRED = 4002
+export const CLOSE_KICKED = 4003
+export const CLOSE_BANNED = 4004
+export const CLOSE_POLICY_VIOLATION = 4005
+export const CLOSE_REPLACED = 4006
+export const CLOSE_SERVER_RESTART = 4007
+export const CLOSE_SERVER_SHUTDOWN = 1001
+export const CLOSE_SLOW_CLIENT = 1013
+
+const CLOSE_CODES: Record<number, Omit<CloseInfo, 'code'>> = {
+    [CLOSE_IDLE_TIMEOUT]: { message: 'Disconnected while idle', reconnect: 'on_activity' },
+    [CLOSE_MAX_LIFETIME]: { message: 'Refreshing connection…', reconnect: 'immediate' },
+    [CLOSE_AUTH_EXPIRED]: { message: 'Your session expired — please log in again', reconnect: 'reauth' },
+    [CLOSE_KICKED]: { message: 'You were removed from the room', reconnect: 'backoff' },
+    [CLOSE_BANNED]: { message: 'You are banned from this room', reconnect: 'never' },
+    [CLOSE_POLICY_VIOLATION]: { message: 'Disconnected for sending too many invalid messages', reconnect: 'backoff' },
+    [CLOSE_REPLACED]: { message: 'Opened in another tab or device', reconnect: 'never' },
+    [CLOSE_SERVER_RESTART]: { message: 'Server updating — reconnecting…', reconnect: 'immediate' },
+    [CLOSE_SERVER_SHUTDOWN]: { message: 'Server restarting — reconnecting…', reconnect: 'backoff' },
+    [CLOSE_SLOW_CLIENT]: { message: 'Connection too slow — reconnecting…', reconnect: 'backoff' },
+}
+
+/** describeClose maps a close code to a user-facing message and reconnect policy. */
+export function describeClose(code: number): CloseInfo {
+    return { code, ...(CLOSE_CODES[code] ?? { message: 'Connection lost — reconnecting…', reconnect: 'backoff' }) }
+}
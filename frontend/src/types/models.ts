@@ -3,7 +3,8 @@
 export interface User {
     id: string
     username: string
-    role: 'admin' | 'member' | 'viewer'
+    role: BuiltInRole | string // custom roles are defined by admins
+    trustLevel: 'new' | 'member' | 'regular' // raised automatically; refresh the token (POST /api/token/refresh) to use it
     displayName?: string | null
     avatarUrl?: string | null
     status: 'online' | 'offline' | 'away' | 'busy'
@@ -11,13 +12,108 @@ export interface User {
     preferences?: Record<string, unknown>
     createdAt: string
     updatedAt: string
+    deletedAt?: string | null
+    anonymizedAt?: string | null
+    shadowBannedAt?: string | null // admin views only
 }
 
 export interface Message {
-    type: 'chat' | 'system' | 'video_sync' | 'webrtc' | 'user_list' | 'admin'
-    sender: string
+    id?: string // ours to choose; echoed in broadcasts and in the error if the server rejects the message
+    type: 'chat' | 'system' | 'video_sync' | 'webrtc' | 'user_list' | 'admin' | 'error' | 'activity' | 'reconnect' | 'heartbeat' | 'moderation' | 'cohost' | 'room_role' | 'media' | 'playback_stats' | 'quality' | 'latency' | 'position' | 'sync_rate' | 'now_playing' | 'spectator' | 'user_list_delta' | 'announcements'
+    sender: string // set by the server to the sending user, whatever we send
+    senderId?: string // absent on system messages
     payload: string
-    timestamp: string
+    timestamp: string // server time on messages relayed from clients, whatever we send
+    seq?: number // increasing per room on messages relayed from clients; order by it
+}
+
+/** Payload of an 'error' message — the server rejected one of our messages. */
+export interface ErrorPayload {
+    code: 'invalid_message' | 'payload_too_large' | 'rate_limited' | 'unknown_type' | 'forbidden' | 'muted' | 'blocked_content' | 'trust_level' | 'unknown_target' | 'spectator'
+    message: string
+    refType?: string
+    refId?: string
+    limit?: number
+}
+
+/** Payload of a 'cohost' message — sent by the room's owner to a connected member. */
+export interface CoHostPayload {
+    userId: string
+    grant: boolean // false makes a co-host a member again
+}
+
+/** Payload of a 'room_role' message — a member's room role changed. */
+export interface RoomRoleEvent {
+    userId: string
+    username?: string // set if the member is connected
+    role: RoomRole | '' // '' = removed from the room
+}
+
+/** Payload of a 'media' message — an uploaded video became playable, got its previews, or could not be transcoded. */
+export interface MediaEvent {
+    event: 'playable' | 'previews' | 'transcode_failed'
+    media: MediaFile
+    url?: string // playable: what to load, the HLS playlist if transcoded
+}
+
+/** Payload of a 'playback_stats' message — sent every few seconds while the player plays. */
+export interface PlaybackStatsPayload {
+    bandwidth: number // kbit/s measured while loading, 0 if unknown
+    bitrate: number // kbit/s of the quality played, 0 if unknown
+    buffer: number // seconds buffered ahead
+    stalls: number // rebuffering stops since the last report
+}
+
+/** Payload of a 'quality' message — a bitrate suggested to the room, or a member struggling or recovered (to its hosts). */
+export interface QualityEvent {
+    event: 'suggestion' | 'struggling' | 'recovered'
+    maxBitrate?: number // suggestion: kbit/s to pick the quality under; absent = no cap
+    userId?: string
+    username?: string
+    stats?: PlaybackStatsPayload
+}
+
+/** Payload of a 'position' message — where our player is, every few seconds while a video is loaded. */
+export interface PositionPayload {
+    position: number // seconds
+    screen?: string // absent = the main screen
+}
+
+/** Payload of a 'sync_rate' message — play at this rate until told 1. */
+export interface SyncRatePayload {
+    rate: number
+    driftMs: number // how far ahead (positive) or behind we were
+    screen?: string // absent = the main screen
+}
+
+/** Payload of a 'now_playing' message — what the room's main screen plays. */
+export interface NowPlaying {
+    roomId: string
+    url: string // '' once nothing plays
+    title?: string
+    thumbnail?: string
+    position: number // seconds, as of updatedAt
+    duration?: number // seconds; absent if unknown
+    playing: boolean
+    updatedAt: string
+}
+
+/** Payload of a 'user_list_delta' message — changes since the last 'user_list' or delta, one name per connection. */
+export interface UserListDelta {
+    joined?: string[]
+    left?: string[]
+}
+
+/** Payload of a 'spectator' message — the client joined a full room as a spectator, or was promoted. */
+export interface SpectatorEvent {
+    spectator: boolean // false once it took a participant's place
+    chat: boolean // whether any spectator chat is let through
+    threshold?: number // participants the room takes
+}
+
+/** Payload of a 'latency' message — the room's round-trip times, to those who control its playback. */
+export interface LatencyReport {
+    members: { userId: string; username: string; rttMs: number }[] // slowest first
 }
 
 export interface ChatMessage {
@@ -31,10 +127,12 @@ export interface ChatMessage {
     createdAt: string
 }
 
+/** The main screen's playback as last saved by the server. */
 export interface VideoState {
     url: string
     playing: boolean
     timestamp: number
+    subtitle?: string
 }
 
 export interface Room {
@@ -46,15 +144,58 @@ export interface Room {
     isActive: boolean
     videoState: VideoState
     maxMembers: number
+    retention?: RetentionPolicy // absent = server default
+    sync?: SyncPolicy // absent = server default
+    screens?: Screen[] // besides the main one
+    tags?: string[] // lowercase, sorted; GET /api/rooms/public?tag= finds rooms by them
     createdAt: string
     updatedAt: string
 }
 
+export interface RetentionPolicy {
+    mode: 'forever' | 'days' | 'on_close'
+    days?: number
+}
+
+/** PUT /api/rooms/{id}/sync — how far players may drift before the server corrects them. */
+export interface SyncPolicy {
+    toleranceMs: number // past this, seek; 0 = no corrections
+    nudgeMs: number // past this, play faster or slower; 0 = only seek
+    pauseOnDisconnect?: 'host' | 'everyone' // pause while they are gone; absent = keep playing
+}
+
+/** PUT /api/rooms/{id}/screens — a video slot besides the room's main one, synced on its own. */
+export interface Screen {
+    id: string // named in video_sync, position and sync_rate payloads
+    name?: string
+    control?: string // room permission needed to control it; absent = 'video.control'
+}
+
+/** Per-room role, highest first; the owner hosts the room. */
+export type RoomRole = 'owner' | 'cohost' | 'moderator' | 'member' | 'viewer'
+
+/** Room permissions granted by a RoomRole. */
+export type RoomPermission =
+    | 'room.chat'
+    | 'video.control'
+    | 'room.invite'
+    | 'room.moderate'
+    | 'room.roles'
+    | 'room.delete'
+
+/** GET /api/rooms/{id}/permissions */
+export interface RoomPermissions {
+    roomId: string
+    /** '' if the caller is not a member */
+    role: RoomRole | ''
+    permissions: RoomPermission[]
+}
+
 export interface RoomMember {
     roomId: string
     userId: string
     username: string
-    role: 'owner' | 'moderator' | 'member' | 'viewer'
+    role: RoomRole
     joinedAt: string
 }
 
@@ -87,25 +228,157 @@ export interface SharedFile {
     createdAt: string
 }
 
+/** A video uploaded for a room, streamed from GET /media/{id} once ready. */
+export interface MediaFile {
+    id: string
+    roomId: string
+    uploadedBy: string
+    fileName: string
+    mimeType: string
+    size: number // bytes
+    received: number // bytes uploaded so far; resume with PUT /api/media/{id}/content?offset=<received>
+    status: 'uploading' | 'ready'
+    transcode?: Transcode // only with TRANSCODE_MODE set, once the upload is complete
+    posterUrl?: string // JPEG still, once made
+    previewUrl?: string // WebVTT thumbnails track for seek bar previews, once made
+    createdAt: string
+    updatedAt: string
+}
+
+/** URL streaming an upload without the JWT, for <video src> — POST /api/media/{id}/playback-token. */
+export interface PlaybackToken {
+    token: string
+    url: string // /api/media/{id}/stream?token=...
+    expiresAt: string // open the URL before this; it keeps working while it plays
+}
+
+/** Subtitle track of an upload, converted to WebVTT — GET /api/media/{id}/subtitles. */
+export interface Subtitle {
+    id: string
+    mediaId: string
+    roomId: string
+    uploadedBy: string
+    label: string
+    language?: string // BCP 47, e.g. 'en'
+    format: 'srt' | 'ass' // of the uploaded file
+    url: string // WebVTT, for <track src>
+    createdAt: string
+}
+
+/** Saved video, personal (ownerId) or a room's (roomId) — GET /api/library, GET /api/rooms/{id}/library. */
+export interface LibraryItem {
+    id: string
+    ownerId?: string
+    roomId?: string
+    addedBy: string
+    title: string
+    url: string // http(s), or an upload's /media/ path
+    folder?: string
+    tags: string[] // lowercase, sorted
+    metadata?: MediaMetadata // when METADATA_PROVIDER recognized the title
+    createdAt: string
+    updatedAt: string
+}
+
+/** What TMDB or OMDb knows of the movie or episode a library item is. */
+export interface MediaMetadata {
+    kind: 'movie' | 'episode'
+    title: string // the movie's, or the series'
+    year?: number
+    season?: number
+    episode?: number
+    episodeTitle?: string
+    posterUrl?: string
+    runtime?: number // minutes
+    source: 'tmdb' | 'omdb'
+    sourceId: string
+}
+
+/** Conversion of an upload to HLS, played from GET /media/{id}/hls/master.m3u8 once done. */
+export interface Transcode {
+    status: 'queued' | 'running' | 'done' | 'failed'
+    progress: number // 0 to 1
+    worker?: string
+    error?: string // failed only
+    updatedAt: string
+}
+
 // --- Auth DTOs ---
 
 export interface RegisterRequest {
     username: string
     password: string
+    inviteCode?: string // required when REGISTRATION_MODE is invite-only
+    challengeResponse?: string // the answer to GET /api/challenge, when one is required
 }
 
 export interface LoginRequest {
     username: string
     password: string
+    /** Also start a long-lived session for this device */
+    rememberMe?: boolean
+    challengeResponse?: string // as in RegisterRequest
+}
+
+/** GET /api/challenge. What to answer before registering or logging in, if asked (403 challenge_required). */
+export interface Challenge {
+    provider: 'hcaptcha' | 'turnstile' | 'pow' | 'off'
+    siteKey?: string // hcaptcha and turnstile
+    /** pow: answer puzzle + ":" + a solution whose SHA-256 with it starts with difficulty zero bits */
+    puzzle?: string
+    difficulty?: number // pow
+    always: boolean // required on every attempt, not only from IPs making many
 }
 
 export interface AuthResponse {
-    token: string
+    /** Omitted in cookie mode (AUTH_COOKIE); the session is an HttpOnly cookie */
+    token?: string
+    /** "Remember me" logins only; omitted in cookie mode */
+    refreshToken?: string
     user: User
 }
 
+/** Body of POST /api/sessions/refresh (cookie mode reads the cookie instead) */
+export interface RefreshSessionRequest {
+    refreshToken: string
+}
+
+/** A "remember me" session, from GET /api/me/sessions */
+export interface Session {
+    id: string
+    userId: string
+    userAgent?: string
+    ip?: string
+    createdAt: string
+    lastUsedAt: string
+    expiresAt: string
+}
+
+// --- API envelope ---
+
+/** Body of every JSON API response (204 responses have no body). */
+export interface ApiResponse<T> {
+    data: T | null // null on error
+    error: ApiError | null
+    meta: ApiMeta
+}
+
 export interface ApiError {
-    error: string
+    code?: string   // stable error code, e.g. "room_not_found"
+    message: string // translated (Accept-Language or the "language" preference)
+}
+
+export interface ApiMeta {
+    requestId?: string // also sent as X-Request-ID; quote it in bug reports
+    pagination?: Pagination // list endpoints only
+}
+
+export interface Pagination {
+    limit: number
+    offset: number
+    count: number // items in this page
+    hasMore: boolean // the page is full, so the next one may not be empty
+    nextCursor?: string // cursor-based lists (messages): pass as ?before= for the next page
 }
 
 // --- Room DTOs ---
@@ -115,12 +388,43 @@ export interface CreateRoomRequest {
     description?: string
     type: 'public' | 'private' | 'direct'
     maxMembers?: number
+    retention?: RetentionPolicy
+    tags?: string[] // up to 5: letters, digits, dashes and underscores
 }
 
 export interface UpdateRoomRequest {
     name?: string
     description?: string
     maxMembers?: number
+    tags?: string[] // replaces the room's tags; [] removes them
+}
+
+/** GET /api/rooms/tags — a tag of recent public rooms, most used first. */
+export interface TagCount {
+    tag: string
+    rooms: number
+}
+
+/** POST /api/rooms/{id}/members */
+export interface AddRoomMemberRequest {
+    userId: string
+    role?: 'member' | 'viewer'
+}
+
+/** PUT /api/rooms/{id}/members/{userId}/role. Ownership cannot be assigned. */
+export interface SetRoomRoleRequest {
+    role: Exclude<RoomRole, 'owner'>
+}
+
+/** POST /api/rooms/{id}/media */
+export interface CreateMediaUploadRequest {
+    fileName: string
+    mimeType: string // video/*
+    size: number // bytes, at most MEDIA_MAX_UPLOAD_SIZE
+}
+
+export interface TransferOwnershipRequest {
+    userId: string
 }
 
 export interface UpdateProfileRequest {
@@ -129,6 +433,404 @@ export interface UpdateProfileRequest {
     bio?: string | null
 }
 
+export interface UserListResponse {
+    users: User[]
+    roleCounts: Partial<Record<User['role'], number>>
+}
+
+export interface AdminOverview {
+    since: string
+    dailyActiveUsers: number
+    weeklyActiveUsers: number
+    currentConnections: number
+    peakConnections: number
+    peakConnectionsAt?: string
+    days: {
+        date: string
+        registrations: number
+        activeUsers: number
+        roomsCreated: number
+        messages: number
+        peakConnections: number
+    }[]
+}
+
+/** POST /api/admin/broadcast — an 'admin' message to every room, or one. */
+export interface BroadcastRequest {
+    message: string
+    roomId?: string // absent = every room
+}
+
+/** Response of POST /api/admin/broadcast — what it reached on this instance. */
+export interface BroadcastResult {
+    rooms: number
+    connections: number
+}
+
+/** GET /api/admin/connections — this instance's open WebSocket connections. */
+export interface Connection {
+    userId: string
+    username: string
+    roomId: string
+    roomRole?: RoomRole
+    protocol?: string
+    connectedAt: string
+    lastSeen: string
+    rttMs?: number // absent until measured
+    spectator?: boolean
+}
+
+/** GET /api/rooms/{id}/hls (HLS_PROXY_ENABLED). Players load /hls/{roomId}/index.m3u8. */
+export interface RoomHLSStatus {
+    roomId: string
+    source?: string // absent = the room is not playing an HLS stream
+    live: boolean
+    targetDuration?: number
+    mediaSequence?: number // of the newest segment
+    edgeSeconds: number // stream time at the end of the newest segment
+    updatedAt?: string
+    viewers: {
+        userId: string
+        positionSeconds: number // start of the last segment their player loaded
+        behindSeconds: number // live streams only
+        at: string
+    }[]
+}
+
+export interface RoomAnalytics {
+    roomId: string
+    currentViewers: number
+    peakViewers: number
+    viewers: { time: string; viewers: number }[]
+    sessions: number
+    totalWatchSeconds: number
+    avgWatchSeconds: number
+    syncCorrections: number
+    videos: {
+        url: string
+        loads: number
+        watchSeconds: number
+        syncCorrections: number
+        dropOffs: { minute: number; count: number }[]
+    }[]
+}
+
+/** Summary of a room's last finished watch party — GET /api/rooms/{id}/recap. */
+export interface RoomRecap {
+    roomId: string
+    startedAt: string
+    endedAt: string
+    durationSeconds: number
+    peakViewers: number
+    sessions: number
+    syncCorrections: number
+    videos: { url: string; watchSeconds: number }[] // in the order first played
+    messages: number
+    topChatters: { userId: string; username: string; messages: number }[] // at most 5
+}
+
+export interface AuditEntry {
+    id: string
+    actorId?: string // absent for background jobs
+    action: string
+    targetType?: string
+    targetId?: string
+    details?: unknown
+    createdAt: string
+}
+
+export interface DeadLetter {
+    id: string
+    userId: string
+    username: string
+    roomId: string
+    type?: string // absent if the message did not parse
+    messageId?: string
+    code: 'invalid_message' | 'unknown_type' | 'unknown_target'
+    reason: string
+    data: string // the message as received, cut to 2048 bytes
+    truncated?: boolean
+    createdAt: string
+}
+
+export interface Report {
+    id: string
+    reporterId: string
+    targetType: 'message' | 'user' | 'room'
+    targetId: string
+    roomId?: string // room of a reported message, or the reported room
+    reason: 'spam' | 'harassment' | 'inappropriate' | 'other'
+    details?: string
+    status: 'open' | 'resolved' | 'dismissed'
+    createdAt: string
+    resolution?: ReportResolution // set once resolved or dismissed
+}
+
+export interface ReportResolution {
+    action?: 'delete_message' | 'mute' | 'ban' | 'shadow_ban'
+    note?: string
+    resolvedBy: string
+    resolvedAt: string
+    mutedUntil?: string // mute only
+}
+
+export interface CreateReportRequest {
+    targetType: Report['targetType']
+    targetId: string
+    reason: Report['reason']
+    details?: string // required when reason is 'other'
+}
+
+/** PUT /api/admin/users/{id}/shadow-ban */
+export interface ShadowBanRequest {
+    shadowBanned: boolean
+}
+
+export interface ResolveReportRequest {
+    status?: 'resolved' | 'dismissed' // default 'resolved'
+    action?: ReportResolution['action'] // not allowed when dismissing
+    note?: string
+    muteMinutes?: number // mute only (default 60)
+}
+
+/** A report with its evidence (GET /api/admin/reports/{id}). */
+export interface ReportDetail {
+    report: Report
+    reporter?: User
+    message?: ChatMessage // the reported message
+    context?: ChatMessage[] // messages around it, oldest first
+    user?: User // the reported user, or the sender of the reported message
+    room?: Room
+}
+
+/** Payload of a 'moderation' WebSocket message (admins and room moderators only). */
+export interface ModerationEvent {
+    event: 'report_created' | 'report_resolved' | 'message_flagged'
+    report?: Report
+    flag?: FlaggedMessage // 'message_flagged' only (sent to admins)
+}
+
+/** A chat message that matched a 'flag' word filter. */
+export interface FlaggedMessage {
+    roomId: string
+    senderId: string
+    sender: string
+    content: string
+    filterId: string
+    pattern: string
+    timestamp: string
+}
+
+/** GET /api/admin/word-filters. Room filters override the global filter with the same pattern. */
+export interface WordFilter {
+    id: string
+    pattern: string
+    regex: boolean
+    action: 'block' | 'flag' | 'allow' // 'allow' is for room filters only
+    roomId?: string
+    createdBy: string
+    createdAt: string
+    updatedAt: string
+}
+
+/** POST /api/admin/word-filters, PUT /api/admin/word-filters/{id} */
+export interface WordFilterRequest {
+    pattern: string
+    regex?: boolean
+    action: WordFilter['action']
+    roomId?: string
+}
+
+/** A site-wide banner. GET /api/announcements and the 'announcements' message list those to show; GET /api/admin/announcements lists all. */
+export interface Announcement {
+    id: string
+    title: string
+    body?: string
+    level: 'info' | 'warning' | 'critical'
+    startsAt: string
+    endsAt?: string // absent = until deleted
+    createdBy: string
+    createdAt: string
+    updatedAt: string
+}
+
+/** POST /api/admin/announcements, PUT /api/admin/announcements/{id} */
+export interface AnnouncementRequest {
+    title: string
+    body?: string
+    level?: Announcement['level'] // default: 'info'
+    startsAt?: string // default: now
+    endsAt?: string
+}
+
+/** GET /api/instance (public). What this deployment calls itself, for branding and the message of the day. */
+export interface Instance {
+    name: string
+    description?: string
+    logoUrl?: string // absent = the frontend's own
+    motd?: string
+    contact?: string
+    updatedAt?: string // absent = never saved, the INSTANCE_* settings
+}
+
+/** PUT /api/admin/instance. Replaces every field. */
+export interface InstanceRequest {
+    name: string
+    description?: string
+    logoUrl?: string
+    motd?: string
+    contact?: string
+}
+
+/** GET /api/me/quotas. One per resource. */
+export interface Quota {
+    resource: 'rooms' | 'library' | 'storage' // storage in bytes
+    limit: number // 0 = unlimited
+    used: number
+}
+
+/** GET /api/me/entitlements. Features of the caller's tier and their limits (0 = no limit); absent = not granted. */
+export type Entitlements = Partial<Record<'room_size' | 'rooms' | 'library' | 'storage', number>> & Record<string, number>
+
+/** GET /api/admin/invites. A code that lets people register under REGISTRATION_MODE=invite-only. */
+export interface Invite {
+    id: string
+    code: string
+    maxUses: number
+    uses: number
+    expiresAt?: string // absent = never
+    createdBy: string
+    createdAt: string
+}
+
+/** POST /api/admin/invites */
+export interface InviteRequest {
+    maxUses?: number // default 1
+    expiresAt?: string // default: never
+}
+
+/** GET /api/legal, GET /api/legal/{kind}/{version}. A version of a document users must accept. */
+export interface LegalDocument {
+    kind: 'terms' | 'privacy'
+    version: string // unique per kind; the newest published is current
+    title: string
+    body: string // Markdown
+    publishedBy: string
+    publishedAt: string
+}
+
+/** POST /api/admin/legal/{kind} */
+export interface LegalDocumentRequest {
+    version: string // letters, digits, '.', '-' and '_'
+    title: string
+    body: string
+}
+
+export interface LegalAcceptance {
+    userId: string
+    kind: 'terms' | 'privacy'
+    version: string
+    acceptedAt: string
+}
+
+/** POST /api/me/legal/accept: the version of each document shown, by kind. */
+export interface LegalAcceptRequest {
+    versions: Partial<Record<'terms' | 'privacy', string>>
+}
+
+/** GET /api/me/legal. While pending is not empty, the API answers 428 legal_acceptance_required. */
+export interface LegalStatus {
+    pending: LegalDocument[]
+    accepted: LegalAcceptance[] // newest first
+}
+
+/** GET /api/admin/origins. Origins allowed at runtime on top of CORS_ORIGINS. */
+export interface AllowedOrigin {
+    id: string
+    origin: string // e.g. https://app.example.com or https://*.example.com
+    createdBy: string
+    createdAt: string
+}
+
+/** POST /api/admin/origins */
+export interface AllowedOriginRequest {
+    origin: string
+}
+
+export type BuiltInRole = 'admin' | 'moderator' | 'member' | 'viewer'
+
+/** GET /api/admin/permissions lists them all. */
+export type Permission =
+    | 'chat.send'
+    | 'rooms.create'
+    | 'rooms.moderate'
+    | 'reports.review'
+    | 'users.read'
+    | 'users.moderate'
+    | 'users.manage'
+    | 'audit.read'
+    | 'stats.read'
+    | 'word_filters.manage'
+    | 'origins.manage'
+    | 'roles.manage'
+    | 'broadcast.send'
+    | 'announcements.manage'
+    | 'instance.manage'
+    | 'trust.bypass'
+    | 'quota.bypass'
+    | 'invites.manage'
+    | 'legal.manage'
+
+/** GET /api/admin/roles. Built-in roles cannot be changed. */
+export interface RoleDefinition {
+    name: string
+    description?: string
+    permissions: Permission[]
+    builtIn: boolean
+    createdAt?: string // not set on built-in roles
+    updatedAt?: string
+}
+
+/** POST /api/admin/roles, PUT /api/admin/roles/{name} (name ignored) */
+export interface RoleRequest {
+    name: string
+    description: string
+    permissions: Permission[]
+}
+
+/** PUT /api/admin/users/{id}/role */
+export interface SetRoleRequest {
+    role: string
+}
+
+/** GET /api/me/permissions */
+export interface MyPermissions {
+    role: string
+    trustLevel: string
+    /** In effect: rooms.create is withheld below TRUST_LEVEL_CREATE_ROOMS */
+    permissions: Permission[]
+    canPostLinks: boolean
+}
+
+export interface BulkUser {
+    username: string
+    password?: string
+    passwordHash?: string
+    role?: User['role']
+    displayName?: string | null
+    avatarUrl?: string | null
+    bio?: string | null
+    createdAt?: string
+}
+
+export interface UserImportReport {
+    dryRun: boolean
+    total: number
+    created: number
+    errors: { row: number; username?: string; error: string; code: string }[]
+    generatedPasswords?: Record<string, string>
+}
+
 // --- Video Sync ---
 
 export interface VideoSyncPayload {
@@ -136,5 +838,26 @@ export interface VideoSyncPayload {
     url: string
     playing: boolean
     timestamp: number
+    subtitle?: string // ID of the Subtitle track shown, if any
+    screen?: string // ID of the room's Screen it controls; absent = the main one
+    title?: string // of the video, for now_playing; kept by the server until the URL changes
+    thumbnail?: string // http(s) URL of an image of the video, likewise
+    duration?: number // seconds, likewise
     triggeredBy: string
 }
+
+// --- Batch (POST /api/batch) ---
+
+export interface BatchRequest {
+    id?: string // echoed in the result
+    method: 'GET' | 'POST' | 'PUT' | 'DELETE'
+    path: string // must start with /api/
+    body?: unknown
+}
+
+export interface BatchResult {
+    id?: string
+    status: number
+    location?: string
+    body: ApiResponse<unknown> | string | null // string for non-JSON bodies, null for 204
+}
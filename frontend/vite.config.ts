@@ -15,6 +15,16 @@ export default defineConfig({
                 target: 'http://localhost:8080',
                 changeOrigin: true,
             },
+            // Forward uploaded video streams to the Go backend
+            '/media': {
+                target: 'http://localhost:8080',
+                changeOrigin: true,
+            },
+            // Forward proxied HLS streams to the Go backend
+            '/hls': {
+                target: 'http://localhost:8080',
+                changeOrigin: true,
+            },
             // Forward WebSocket connections to the Go backend
             '/ws': {
                 target: 'http://localhost:8080',
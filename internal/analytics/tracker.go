@@ -0,0 +1,481 @@
+// Package analytics records anonymized watch analytics per room.
+//
+// The Tracker is fed by the WebSocket hub: viewers joining and leaving
+// (identified only by a random per-connection session ID, never by user)
+// and video_sync events. From those it derives viewer counts over time,
+// watch durations, sync corrections (seeks), the video positions at
+// which viewers leave, and watch parties: from the first viewer joining
+// an empty room until the last one leaves. Users can opt out per connection (see ws.ServeWs);
+// opted-out viewers are not counted at all.
+//
+// Data is kept in memory for the configured history window.
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// maxVideosPerRoom bounds per-video stats; the least recently used
+// video is evicted first.
+const maxVideosPerRoom = 50
+
+// maxPartiesPerRoom bounds the finished watch parties kept per room.
+const maxPartiesPerRoom = 20
+
+// Tracker aggregates watch events per room. Safe for concurrent use.
+type Tracker struct {
+	history time.Duration // viewer samples and idle rooms kept this long
+	now     func() time.Time
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+// room is the live state and running totals of one room.
+type room struct {
+	sessions map[string]*session
+
+	// Current video, as last reported by video_sync.
+	url        string
+	playing    bool
+	position   float64 // seconds, at positionAt
+	positionAt time.Time
+
+	samples     []ViewerSample // one per minute, oldest first
+	peak        int
+	ended       int           // sessions that have left
+	endedWatch  time.Duration // watch time of sessions that have left
+	corrections int
+	videos      map[string]*video
+	lastActive  time.Time
+
+	party   *party  // in progress; nil while nobody watches
+	parties []Party // finished, oldest first
+}
+
+// party is a watch party in progress.
+type party struct {
+	start       time.Time
+	peak        int
+	sessions    int
+	corrections int
+	videos      []*PartyVideo // in the order first played
+}
+
+// session is one anonymous viewer connection.
+type session struct {
+	watched   time.Duration
+	watchFrom time.Time // start of the current, not yet counted stretch
+}
+
+// video holds per-URL totals within a room.
+type video struct {
+	loads       int
+	watch       time.Duration
+	corrections int
+	dropOffs    map[int]int // video minute -> viewers who left there
+	lastUsed    time.Time
+}
+
+// NewTracker creates a tracker keeping history of viewer counts (and
+// rooms with no viewers) for the given duration (default 24h).
+func NewTracker(history time.Duration) *Tracker {
+	if history <= 0 {
+		history = 24 * time.Hour
+	}
+	return &Tracker{
+		history: history,
+		now:     time.Now,
+		rooms:   make(map[string]*room),
+	}
+}
+
+// ViewerJoined records a viewer session starting in roomID.
+// Implements ws.WatchRecorder.
+func (t *Tracker) ViewerJoined(roomID, sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	r := t.room(roomID, now)
+	r.sessions[sessionID] = &session{watchFrom: now}
+	r.peak = max(r.peak, len(r.sessions))
+	if r.party == nil {
+		r.party = &party{start: now}
+	}
+	r.party.sessions++
+	r.party.peak = max(r.party.peak, len(r.sessions))
+	r.sample(now, t.history)
+}
+
+// ViewerLeft records a viewer session ending, including the video
+// position it left at. Implements ws.WatchRecorder.
+func (t *Tracker) ViewerLeft(roomID, sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.rooms[roomID]
+	if !ok {
+		return
+	}
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		return
+	}
+
+	now := t.now()
+	r.flush(s, now)
+	delete(r.sessions, sessionID)
+	r.ended++
+	r.endedWatch += s.watched
+	if r.url != "" {
+		minute := int(r.positionNow(now) / 60)
+		r.video(r.url, now).dropOffs[minute]++
+	}
+	r.lastActive = now
+	r.sample(now, t.history)
+	if len(r.sessions) == 0 {
+		r.endParty(now, t.history)
+	}
+}
+
+// VideoSync records a video_sync event in roomID. Implements ws.WatchRecorder.
+func (t *Tracker) VideoSync(roomID string, p models.VideoSyncPayload) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	r := t.room(roomID, now)
+	for _, s := range r.sessions {
+		r.flush(s, now)
+	}
+
+	switch p.Event {
+	case models.VideoEventLoad:
+		r.url = p.URL
+		r.video(p.URL, now).loads++
+		if r.party != nil {
+			r.party.video(p.URL)
+		}
+	case models.VideoEventSeek:
+		r.corrections++
+		if r.url != "" {
+			r.video(r.url, now).corrections++
+		}
+		if r.party != nil {
+			r.party.corrections++
+		}
+	}
+	if r.url == "" {
+		r.url = p.URL
+	}
+	r.playing = p.Playing || p.Event == models.VideoEventPlay
+	if p.Event == models.VideoEventPause {
+		r.playing = false
+	}
+	r.position, r.positionAt = p.Timestamp, now
+	r.lastActive = now
+}
+
+// Room returns the analytics for roomID. Rooms nobody has watched yet
+// return zero values.
+func (t *Tracker) Room(roomID string) RoomAnalytics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := RoomAnalytics{RoomID: roomID, Viewers: []ViewerSample{}, Videos: []VideoAnalytics{}}
+	r, ok := t.rooms[roomID]
+	if !ok {
+		return out
+	}
+
+	now := t.now()
+	cutoff := now.Add(-t.history)
+	for _, s := range r.samples {
+		if s.Time.After(cutoff) {
+			out.Viewers = append(out.Viewers, s)
+		}
+	}
+
+	// Count stretches still in progress without committing them.
+	watch := r.endedWatch
+	running := time.Duration(0)
+	for _, s := range r.sessions {
+		watch += s.watched
+		if r.playing {
+			running += now.Sub(s.watchFrom)
+		}
+	}
+	watch += running
+
+	out.CurrentViewers = len(r.sessions)
+	out.PeakViewers = r.peak
+	out.Sessions = r.ended + len(r.sessions)
+	out.TotalWatchSeconds = watch.Seconds()
+	if out.Sessions > 0 {
+		out.AvgWatchSeconds = out.TotalWatchSeconds / float64(out.Sessions)
+	}
+	out.SyncCorrections = r.corrections
+
+	for url, v := range r.videos {
+		va := VideoAnalytics{
+			URL:             url,
+			Loads:           v.loads,
+			WatchSeconds:    v.watch.Seconds(),
+			SyncCorrections: v.corrections,
+			DropOffs:        []DropOff{},
+		}
+		if url == r.url {
+			va.WatchSeconds += running.Seconds()
+		}
+		for minute, n := range v.dropOffs {
+			va.DropOffs = append(va.DropOffs, DropOff{Minute: minute, Count: n})
+		}
+		sort.Slice(va.DropOffs, func(i, j int) bool { return va.DropOffs[i].Minute < va.DropOffs[j].Minute })
+		out.Videos = append(out.Videos, va)
+	}
+	sort.Slice(out.Videos, func(i, j int) bool {
+		return r.videos[out.Videos[i].URL].lastUsed.After(r.videos[out.Videos[j].URL].lastUsed)
+	})
+	return out
+}
+
+// LastParty returns the last finished watch party of roomID, if one ended
+// within the history window.
+func (t *Tracker) LastParty(roomID string) (Party, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.rooms[roomID]
+	if !ok || len(r.parties) == 0 {
+		return Party{}, false
+	}
+	last := r.parties[len(r.parties)-1]
+	if !last.End.After(t.now().Add(-t.history)) {
+		return Party{}, false
+	}
+	last.Videos = append([]PartyVideo(nil), last.Videos...)
+	return last, true
+}
+
+// CountIdle returns how many rooms have had no viewers or events since
+// before.
+func (t *Tracker) CountIdle(before time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for _, r := range t.rooms {
+		if r.idleSince(before) {
+			n++
+		}
+	}
+	return n
+}
+
+// PurgeIdle drops the analytics of rooms idle since before and returns
+// how many were dropped. Rooms are otherwise kept for the history window.
+func (t *Tracker) PurgeIdle(before time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for id, r := range t.rooms {
+		if r.idleSince(before) {
+			delete(t.rooms, id)
+			n++
+		}
+	}
+	return n
+}
+
+// room returns the state for roomID, creating it if needed. Creating a
+// room also drops rooms with no viewers and no activity within the
+// history window. Must be called with t.mu held.
+func (t *Tracker) room(roomID string, now time.Time) *room {
+	if r, ok := t.rooms[roomID]; ok {
+		r.lastActive = now
+		return r
+	}
+
+	for id, r := range t.rooms {
+		if r.idleSince(now.Add(-t.history)) {
+			delete(t.rooms, id)
+		}
+	}
+	r := &room{
+		sessions:   make(map[string]*session),
+		videos:     make(map[string]*video),
+		lastActive: now,
+	}
+	t.rooms[roomID] = r
+	return r
+}
+
+// idleSince reports whether the room has no viewers and no activity since before.
+func (r *room) idleSince(before time.Time) bool {
+	return len(r.sessions) == 0 && r.lastActive.Before(before)
+}
+
+// flush adds the viewer's watch time since watchFrom, if the video was
+// playing, and starts a new stretch at now.
+func (r *room) flush(s *session, now time.Time) {
+	if r.playing {
+		d := now.Sub(s.watchFrom)
+		s.watched += d
+		if r.url != "" {
+			r.video(r.url, now).watch += d
+			if r.party != nil {
+				r.party.video(r.url).WatchSeconds += d.Seconds()
+			}
+		}
+	}
+	s.watchFrom = now
+}
+
+// endParty files the party in progress as finished, dropping the oldest
+// beyond maxPartiesPerRoom and those older than history.
+func (r *room) endParty(now time.Time, history time.Duration) {
+	p := r.party
+	if p == nil {
+		return
+	}
+	r.party = nil
+
+	videos := make([]PartyVideo, 0, len(p.videos))
+	for _, v := range p.videos {
+		videos = append(videos, *v)
+	}
+	r.parties = append(r.parties, Party{
+		Start:           p.start,
+		End:             now,
+		PeakViewers:     p.peak,
+		Sessions:        p.sessions,
+		SyncCorrections: p.corrections,
+		Videos:          videos,
+	})
+
+	cutoff := now.Add(-history)
+	i := max(0, len(r.parties)-maxPartiesPerRoom)
+	for i < len(r.parties) && !r.parties[i].End.After(cutoff) {
+		i++
+	}
+	r.parties = r.parties[i:]
+}
+
+// video returns the party's totals for url, adding it if it is new.
+func (p *party) video(url string) *PartyVideo {
+	for _, v := range p.videos {
+		if v.URL == url {
+			return v
+		}
+	}
+	if len(p.videos) >= maxVideosPerRoom {
+		return &PartyVideo{} // not kept
+	}
+	v := &PartyVideo{URL: url}
+	p.videos = append(p.videos, v)
+	return v
+}
+
+// positionNow extrapolates the playback position to now.
+func (r *room) positionNow(now time.Time) float64 {
+	if !r.playing {
+		return r.position
+	}
+	return r.position + now.Sub(r.positionAt).Seconds()
+}
+
+// sample records the current viewer count, keeping the per-minute maximum.
+func (r *room) sample(now time.Time, history time.Duration) {
+	minute := now.Truncate(time.Minute)
+	n := len(r.sessions)
+	if last := len(r.samples) - 1; last >= 0 && r.samples[last].Time.Equal(minute) {
+		r.samples[last].Viewers = max(r.samples[last].Viewers, n)
+		return
+	}
+	r.samples = append(r.samples, ViewerSample{Time: minute, Viewers: n})
+
+	cutoff := now.Add(-history)
+	i := 0
+	for i < len(r.samples) && !r.samples[i].Time.After(cutoff) {
+		i++
+	}
+	r.samples = r.samples[i:]
+}
+
+// video returns the stats for url, evicting the least recently used
+// video when the room tracks too many.
+func (r *room) video(url string, now time.Time) *video {
+	if v, ok := r.videos[url]; ok {
+		v.lastUsed = now
+		return v
+	}
+	if len(r.videos) >= maxVideosPerRoom {
+		var oldest string
+		for u, v := range r.videos {
+			if oldest == "" || v.lastUsed.Before(r.videos[oldest].lastUsed) {
+				oldest = u
+			}
+		}
+		delete(r.videos, oldest)
+	}
+	v := &video{dropOffs: make(map[int]int), lastUsed: now}
+	r.videos[url] = v
+	return v
+}
+
+// RoomAnalytics is returned by GET /api/rooms/{id}/analytics.
+type RoomAnalytics struct {
+	RoomID            string           `json:"roomId"`
+	CurrentViewers    int              `json:"currentViewers"`
+	PeakViewers       int              `json:"peakViewers"`
+	Viewers           []ViewerSample   `json:"viewers"` // per-minute peaks, oldest first
+	Sessions          int              `json:"sessions"`
+	TotalWatchSeconds float64          `json:"totalWatchSeconds"`
+	AvgWatchSeconds   float64          `json:"avgWatchSeconds"`
+	SyncCorrections   int              `json:"syncCorrections"` // seeks
+	Videos            []VideoAnalytics `json:"videos"`          // most recently used first
+}
+
+// ViewerSample is the peak viewer count within one minute.
+type ViewerSample struct {
+	Time    time.Time `json:"time"`
+	Viewers int       `json:"viewers"`
+}
+
+// VideoAnalytics holds totals for one video URL in a room.
+type VideoAnalytics struct {
+	URL             string    `json:"url"`
+	Loads           int       `json:"loads"`
+	WatchSeconds    float64   `json:"watchSeconds"`
+	SyncCorrections int       `json:"syncCorrections"`
+	DropOffs        []DropOff `json:"dropOffs"` // by video minute, ascending
+}
+
+// Party is a finished watch party: from the first viewer joining an
+// empty room until the last one left.
+type Party struct {
+	Start           time.Time
+	End             time.Time
+	PeakViewers     int
+	Sessions        int // viewer connections
+	SyncCorrections int // seeks
+	Videos          []PartyVideo
+}
+
+// PartyVideo is a video played during a Party.
+type PartyVideo struct {
+	URL          string
+	WatchSeconds float64 // summed over viewers
+}
+
+// DropOff counts viewers who left while the video was at Minute.
+type DropOff struct {
+	Minute int `json:"minute"`
+	Count  int `json:"count"`
+}
@@ -11,8 +11,25 @@
 package app
 
 import (
+	"ofenes/internal/analytics"
+	"ofenes/internal/auth"
+	"ofenes/internal/authz"
+	"ofenes/internal/challenge"
+	"ofenes/internal/clock"
 	"ofenes/internal/config"
+	"ofenes/internal/entitlement"
+	"ofenes/internal/hlsproxy"
+	"ofenes/internal/idgen"
+	"ofenes/internal/ldap"
+	"ofenes/internal/legal"
+	"ofenes/internal/media"
+	"ofenes/internal/metadata"
+	"ofenes/internal/metrics"
+	"ofenes/internal/origin"
+	"ofenes/internal/quota"
 	"ofenes/internal/repository"
+	"ofenes/internal/stats"
+	"ofenes/internal/transcode"
 	"ofenes/internal/ws"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -21,14 +38,48 @@ import (
 // App is the dependency injection container for the application.
 // All handlers and middleware receive a pointer to this struct.
 type App struct {
-	Config      *config.Config
-	DB          *pgxpool.Pool
-	UserRepo    repository.UserRepository
-	RoomRepo    repository.RoomRepository
-	MessageRepo repository.MessageRepository
-	MediaRepo   repository.MediaSessionRepository
-	FileRepo    repository.SharedFileRepository
-	Hub         *ws.Hub
+	Config           *config.Config
+	DB               *pgxpool.Pool // nil unless STORAGE_BACKEND=postgres
+	UserRepo         repository.UserRepository
+	RoomRepo         repository.RoomRepository
+	MessageRepo      repository.MessageRepository
+	MediaRepo        repository.MediaSessionRepository
+	FileRepo         repository.SharedFileRepository
+	MediaFileRepo    repository.MediaFileRepository
+	SubtitleRepo     repository.SubtitleRepository
+	LibraryRepo      repository.LibraryRepository
+	MediaStore       media.Store    // bytes of uploaded videos, keyed by MediaFile.ID
+	Streams          *media.Streams // streams of uploads played with playback tokens, per user
+	AuditRepo        repository.AuditRepository
+	DeadLetterRepo   repository.DeadLetterRepository // nil unless WS_DEAD_LETTER_PERSIST=true
+	ReportRepo       repository.ReportRepository
+	WordFilterRepo   repository.WordFilterRepository
+	OriginRepo       repository.AllowedOriginRepository
+	Origins          *origin.Dynamic // origins added at runtime, shared by CORS and the Hub
+	AnnouncementRepo repository.AnnouncementRepository
+	InstanceRepo     repository.InstanceRepository
+	InviteRepo       repository.InviteRepository
+	LegalRepo        repository.LegalRepository
+	Legal            *legal.Tracker // current legal documents and who accepted them
+	RoleRepo         repository.RoleRepository
+	Authz            *authz.Authorizer         // role permissions, shared by the handlers, middleware and the Hub
+	Quotas           *quota.Quotas             // what each user may own: rooms, personal library, upload storage
+	Entitlements     *entitlement.Entitlements // perks of paid tiers; also consulted by Quotas
+	Challenges       *challenge.Guard          // CAPTCHA or proof of work on registration and login; nil unless CHALLENGE_PROVIDER is set
+	Directory        *ldap.Directory           // nil unless LDAP_URL is set
+	Passwords        *auth.Hasher              // bcrypt on a bounded pool; hash and check passwords only through it
+	Revocations      *auth.Revocations         // user tokens withdrawn before they expire
+	Tx               repository.UnitOfWork
+	Ephemeral        repository.EphemeralStores
+	Hub              *ws.Hub
+	Metrics          *metrics.Registry
+	Stats            *stats.Collector
+	Analytics        *analytics.Tracker // nil when ANALYTICS_ENABLED=false
+	HLS              *hlsproxy.Proxy    // nil unless HLS_PROXY_ENABLED=true
+	Transcode        *transcode.Jobs    // nil when TRANSCODE_MODE=off
+	Metadata         *metadata.Enricher // nil when METADATA_PROVIDER=off
+	Clock            clock.Clock        // stamps records; fake in tests
+	IDs              idgen.Generator    // IDs of new records; fake in tests
 }
 
 // New creates a new App with the given dependencies.
@@ -40,16 +91,84 @@ func New(
 	messageRepo repository.MessageRepository,
 	mediaRepo repository.MediaSessionRepository,
 	fileRepo repository.SharedFileRepository,
+	mediaFileRepo repository.MediaFileRepository,
+	subtitleRepo repository.SubtitleRepository,
+	libraryRepo repository.LibraryRepository,
+	mediaStore media.Store,
+	mediaStreams *media.Streams,
+	auditRepo repository.AuditRepository,
+	deadLetterRepo repository.DeadLetterRepository,
+	reportRepo repository.ReportRepository,
+	wordFilterRepo repository.WordFilterRepository,
+	originRepo repository.AllowedOriginRepository,
+	origins *origin.Dynamic,
+	announcementRepo repository.AnnouncementRepository,
+	instanceRepo repository.InstanceRepository,
+	inviteRepo repository.InviteRepository,
+	legalRepo repository.LegalRepository,
+	legalTracker *legal.Tracker,
+	roleRepo repository.RoleRepository,
+	authorizer *authz.Authorizer,
+	quotas *quota.Quotas,
+	entitlements *entitlement.Entitlements,
+	challenges *challenge.Guard,
+	directory *ldap.Directory,
+	passwords *auth.Hasher,
+	revocations *auth.Revocations,
+	uow repository.UnitOfWork,
+	ephemeral repository.EphemeralStores,
 	hub *ws.Hub,
+	metricsRegistry *metrics.Registry,
+	statsCollector *stats.Collector,
+	tracker *analytics.Tracker,
+	hlsProxy *hlsproxy.Proxy,
+	transcodeJobs *transcode.Jobs,
+	enricher *metadata.Enricher,
+	clk clock.Clock,
+	ids idgen.Generator,
 ) *App {
 	return &App{
-		Config:      cfg,
-		DB:          db,
-		UserRepo:    userRepo,
-		RoomRepo:    roomRepo,
-		MessageRepo: messageRepo,
-		MediaRepo:   mediaRepo,
-		FileRepo:    fileRepo,
-		Hub:         hub,
+		Config:           cfg,
+		DB:               db,
+		UserRepo:         userRepo,
+		RoomRepo:         roomRepo,
+		MessageRepo:      messageRepo,
+		MediaRepo:        mediaRepo,
+		FileRepo:         fileRepo,
+		MediaFileRepo:    mediaFileRepo,
+		SubtitleRepo:     subtitleRepo,
+		LibraryRepo:      libraryRepo,
+		MediaStore:       mediaStore,
+		Streams:          mediaStreams,
+		AuditRepo:        auditRepo,
+		DeadLetterRepo:   deadLetterRepo,
+		ReportRepo:       reportRepo,
+		WordFilterRepo:   wordFilterRepo,
+		OriginRepo:       originRepo,
+		Origins:          origins,
+		AnnouncementRepo: announcementRepo,
+		InstanceRepo:     instanceRepo,
+		InviteRepo:       inviteRepo,
+		LegalRepo:        legalRepo,
+		Legal:            legalTracker,
+		RoleRepo:         roleRepo,
+		Authz:            authorizer,
+		Quotas:           quotas,
+		Entitlements:     entitlements,
+		Challenges:       challenges,
+		Directory:        directory,
+		Passwords:        passwords,
+		Revocations:      revocations,
+		Tx:               uow,
+		Ephemeral:        ephemeral,
+		Hub:              hub,
+		Metrics:          metricsRegistry,
+		Stats:            statsCollector,
+		Analytics:        tracker,
+		HLS:              hlsProxy,
+		Transcode:        transcodeJobs,
+		Metadata:         enricher,
+		Clock:            clk,
+		IDs:              ids,
 	}
 }
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"ofenes/internal/metrics"
+)
+
+// ErrOverloaded is returned by Hasher when its queue is full. Callers
+// answer 503 so clients back off rather than pile up.
+var ErrOverloaded = errors.New("auth: too many passwords waiting to be hashed")
+
+// Hasher runs bcrypt on a bounded number of goroutines, so a burst of
+// logins or registrations can't take every CPU from the Hub and the rest
+// of the server. Callers wait for a free slot or for their context,
+// whichever comes first: a request whose client hung up or whose deadline
+// passed leaves the queue instead of hashing for nobody. Once maxQueue
+// callers are waiting, more are turned away with ErrOverloaded.
+//
+// bcrypt itself can't be interrupted, so a hash already running when its
+// caller gives up finishes in the background (still holding its slot).
+type Hasher struct {
+	slots    chan struct{}
+	maxQueue int64
+	waiting  atomic.Int64
+
+	queueTime *metrics.Summary
+	duration  *metrics.Summary
+	shed      *metrics.Counter
+}
+
+// NewHasher creates a Hasher running at most workers hashes at once, with
+// at most maxQueue callers waiting for one. workers < 1 means half the
+// CPUs (at least one), leaving the others to the Hub; maxQueue < 1 means
+// no limit. Queue times, hash durations and shed requests are recorded in
+// reg, unless it is nil.
+func NewHasher(workers, maxQueue int, reg *metrics.Registry) *Hasher {
+	if workers < 1 {
+		workers = max(1, runtime.GOMAXPROCS(0)/2)
+	}
+	h := &Hasher{slots: make(chan struct{}, workers), maxQueue: int64(maxQueue)}
+	if reg != nil {
+		h.queueTime = reg.Summary("password_hash_queue_seconds",
+			"Time a password waited for a free hashing slot.")
+		h.duration = reg.Summary("password_hash_duration_seconds",
+			"Time bcrypt took to hash or check a password.")
+		h.shed = reg.Counter("password_hash_shed_total",
+			"Passwords turned away because PASSWORD_HASH_QUEUE callers were already waiting.")
+		reg.GaugeFunc("password_hash_waiting", "Passwords waiting for a free hashing slot.",
+			func() float64 { return float64(h.waiting.Load()) })
+		reg.GaugeFunc("password_hash_workers", "Passwords that can be hashed at once (PASSWORD_HASH_WORKERS).",
+			func() float64 { return float64(workers) })
+	}
+	return h
+}
+
+// Hash is HashPassword on the pool. It returns ctx.Err() if ctx is done
+// before the hash is ready, and ErrOverloaded if the queue is full.
+func (h *Hasher) Hash(ctx context.Context, password string) (string, error) {
+	var hash string
+	var err error
+	if cerr := h.do(ctx, func() { hash, err = HashPassword(password) }); cerr != nil {
+		return "", cerr
+	}
+	return hash, err
+}
+
+// Check is CheckPassword on the pool. It returns ctx.Err() if ctx is done
+// before the comparison is over, and ErrOverloaded if the queue is full,
+// so callers must not take every error for a wrong password.
+func (h *Hasher) Check(ctx context.Context, hash, password string) error {
+	var err error
+	if cerr := h.do(ctx, func() { err = CheckPassword(hash, password) }); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// do runs fn in a goroutine once a slot is free and waits for it. It
+// returns ctx.Err() if ctx is done first; fn is then not started, or left
+// to finish on its own (its results must not be read).
+func (h *Hasher) do(ctx context.Context, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := h.acquire(ctx); err != nil {
+		return err
+	}
+	// Both may have been ready; don't start work for a caller that left.
+	if err := ctx.Err(); err != nil {
+		<-h.slots
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer func() { <-h.slots }()
+		start := time.Now()
+		fn()
+		if h.duration != nil {
+			h.duration.Observe(time.Since(start).Seconds())
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire takes a slot, queueing for one unless the queue is full.
+func (h *Hasher) acquire(ctx context.Context) error {
+	select {
+	case h.slots <- struct{}{}:
+		h.observeQueue(0)
+		return nil
+	default:
+	}
+
+	if n := h.waiting.Add(1); h.maxQueue > 0 && n > h.maxQueue {
+		h.waiting.Add(-1)
+		if h.shed != nil {
+			h.shed.Inc()
+		}
+		return ErrOverloaded
+	}
+	defer h.waiting.Add(-1)
+
+	start := time.Now()
+	select {
+	case h.slots <- struct{}{}:
+		h.observeQueue(time.Since(start))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hasher) observeQueue(d time.Duration) {
+	if h.queueTime != nil {
+		h.queueTime.Observe(d.Seconds())
+	}
+}
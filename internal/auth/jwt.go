@@ -4,53 +4,113 @@ import (
 	"errors"
 	"time"
 
+	"ofenes/pkg/apperr"
+
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Custom errors for JWT operations.
 var (
-	ErrInvalidToken = errors.New("auth: invalid or expired token")
+	ErrInvalidToken = apperr.New(apperr.Unauthorized, "invalid_or_expired_token", "auth: invalid or expired token")
 )
 
 // Claims defines the JWT payload structure.
-// Embeds jwt.RegisteredClaims for standard fields (exp, iat, sub).
+// Embeds jwt.RegisteredClaims for standard fields (jti, exp, iat, sub, iss, aud).
 type Claims struct {
-	UserID   string `json:"userId"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID     string `json:"userId"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	TrustLevel string `json:"trust,omitempty"` // as of issue time; refresh the token to pick up a promotion
 	jwt.RegisteredClaims
 }
 
+// TokenConfig is how tokens are signed and checked. Issuer (iss) and
+// Audience (aud) tie a token to one deployment: a token issued by another
+// environment (staging vs prod) or another app sharing the secret has
+// different values and is rejected. Empty Issuer or Audience is neither
+// set nor checked. Keys, if set, replaces Secret, so the secret can be
+// rotated (see Keys).
+type TokenConfig struct {
+	Secret   string
+	Keys     *Keys
+	Expiry   time.Duration
+	Issuer   string
+	Audience string
+}
+
+// secrets returns the secrets tokens may be signed with, the one to sign
+// new tokens with first.
+func (tc TokenConfig) secrets() []string {
+	if tc.Keys != nil {
+		return tc.Keys.accepted()
+	}
+	return []string{tc.Secret}
+}
+
 // GenerateToken creates a signed JWT for the given user.
-// The secret and expiry are passed in (from config) — not hardcoded.
-func GenerateToken(userID, username, role, secret string, expiry time.Duration) (string, error) {
+// The secret, expiry, issuer and audience are passed in (from config) — not hardcoded.
+func GenerateToken(userID, username, role, trustLevel string, tc TokenConfig) (string, error) {
+	token, _, err := IssueToken(userID, username, role, trustLevel, tc)
+	return token, err
+}
+
+// IssueToken is GenerateToken that also returns the token's claims, for
+// callers that keep its ID (jti) and expiry to revoke it later (see
+// Revocations).
+func IssueToken(userID, username, role, trustLevel string, tc TokenConfig) (string, *Claims, error) {
 	now := time.Now()
 
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:     userID,
+		Username:   username,
+		Role:       role,
+		TrustLevel: trustLevel,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Subject:   userID,
+			Issuer:    tc.Issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tc.Expiry)),
 		},
 	}
+	if tc.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{tc.Audience}
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(tc.secrets()[0]))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
 }
 
-// ValidateToken parses and validates a JWT string.
+// ValidateToken parses and validates a JWT string, including its issuer
+// and audience if tc sets them. After a key rollover, tokens signed with
+// the previous secret are accepted too.
 // Returns the claims on success, or ErrInvalidToken on failure.
-func ValidateToken(tokenStr, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
-		// Ensure the signing method is HMAC (prevent algorithm confusion attacks)
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
+func ValidateToken(tokenStr string, tc TokenConfig) (*Claims, error) {
+	var opts []jwt.ParserOption
+	if tc.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(tc.Issuer))
+	}
+	if tc.Audience != "" {
+		opts = append(opts, jwt.WithAudience(tc.Audience))
+	}
+	var token *jwt.Token
+	var err error
+	for _, secret := range tc.secrets() {
+		token, err = jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
+			// Ensure the signing method is HMAC (prevent algorithm confusion attacks)
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, ErrInvalidToken
+			}
+			return []byte(secret), nil
+		}, opts...)
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break
 		}
-		return []byte(secret), nil
-	})
+	}
 
 	if err != nil {
 		return nil, ErrInvalidToken
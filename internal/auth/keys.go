@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Keys holds the secret user tokens are signed with, for rolling it over
+// without logging everyone out: after Rotate, new tokens are signed with
+// the new secret and those signed with the previous one are still
+// accepted until they would have expired anyway. Safe for concurrent use.
+type Keys struct {
+	grace time.Duration
+	set   atomic.Pointer[keySet]
+}
+
+// keySet is the secrets in use at one time.
+type keySet struct {
+	current  string
+	previous string    // "" before the first rotation
+	retires  time.Time // when previous stops being accepted
+}
+
+// NewKeys creates Keys signing with secret. After a rotation, the
+// previous secret is accepted for grace, the lifetime of a token.
+func NewKeys(secret string, grace time.Duration) *Keys {
+	k := &Keys{grace: grace}
+	k.set.Store(&keySet{current: secret})
+	return k
+}
+
+// Current returns the secret new tokens are signed with.
+func (k *Keys) Current() string {
+	return k.set.Load().current
+}
+
+// Rotate makes secret the current one, still accepting the one it
+// replaces for the grace period. It reports false, changing nothing, if
+// secret is already current.
+func (k *Keys) Rotate(secret string) bool {
+	old := k.set.Load()
+	if secret == old.current {
+		return false
+	}
+	k.set.Store(&keySet{current: secret, previous: old.current, retires: time.Now().Add(k.grace)})
+	return true
+}
+
+// accepted returns the secrets tokens may be signed with, current first.
+func (k *Keys) accepted() []string {
+	s := k.set.Load()
+	if s.previous != "" && time.Now().Before(s.retires) {
+		return []string{s.current, s.previous}
+	}
+	return []string{s.current}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// NewRefreshToken returns a refresh token for the session sessionID, as
+// "<sessionID>.<secret>", and the hash of the secret to store with the
+// session. The token itself is never stored.
+func NewRefreshToken(sessionID string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return sessionID + "." + secret, hashSecret(secret), nil
+}
+
+// ParseRefreshToken splits a refresh token into its session ID and secret.
+func ParseRefreshToken(token string) (sessionID, secret string, ok bool) {
+	sessionID, secret, ok = strings.Cut(token, ".")
+	return sessionID, secret, ok && sessionID != "" && secret != ""
+}
+
+// CheckRefreshSecret reports whether secret matches hash, in constant time.
+func CheckRefreshSecret(hash, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(hash)) == 1
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
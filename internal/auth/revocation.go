@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ofenes/internal/repository"
+	"ofenes/pkg/apperr"
+)
+
+// Errors returned by Revocations.Check.
+var (
+	ErrTokenRevoked = apperr.New(apperr.Unauthorized, "token_revoked", "auth: token revoked")
+	ErrAccountGone  = apperr.New(apperr.Unauthorized, "account_gone", "auth: account deleted or banned")
+)
+
+// Revocations withdraws user tokens before they expire: on logout, when
+// the session a token was issued for ends, and when the user is banned.
+// A revoked token's ID (jti) is kept in the revoked-token store until the
+// token would have expired anyway; with the Redis stores every instance
+// sees it. Tokens not tracked anywhere, such as those of a login without
+// "remember me", are refused too once their user is deleted or banned.
+type Revocations struct {
+	tokens repository.RevokedTokenRepository
+	users  repository.UserRepository
+}
+
+// NewRevocations returns Revocations kept in tokens, checking the users
+// tokens belong to in users. Pass the cached user repository: every
+// authenticated request is checked.
+func NewRevocations(tokens repository.RevokedTokenRepository, users repository.UserRepository) *Revocations {
+	return &Revocations{tokens: tokens, users: users}
+}
+
+// Check returns ErrTokenRevoked if the token with claims has been revoked,
+// or ErrAccountGone if its user has been deleted or banned. Tokens issued
+// without an ID cannot be revoked, but their user is checked all the same.
+func (v *Revocations) Check(ctx context.Context, claims *Claims) error {
+	if claims.ID != "" {
+		revoked, err := v.tokens.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return fmt.Errorf("auth: check token %s: %w", claims.ID, err)
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+	if _, err := v.users.GetByID(ctx, claims.UserID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAccountGone
+		}
+		return fmt.Errorf("auth: check user %s: %w", claims.UserID, err)
+	}
+	return nil
+}
+
+// Revoke withdraws the token with ID tokenID, which expires at expiresAt.
+// An empty tokenID (a token issued without an ID) is ignored.
+func (v *Revocations) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return nil
+	}
+	return v.tokens.Revoke(ctx, tokenID, expiresAt)
+}
@@ -0,0 +1,272 @@
+// Package authz decides what users may do from their role.
+//
+// A role is a named set of permissions. The built-in roles (admin,
+// moderator, member, viewer) are fixed; admins can define more, e.g. a
+// "streamer" or a "bot" role, through /api/admin/roles. The role is
+// embedded in the JWT, but its permissions are looked up on every check,
+// so editing a role takes effect at once, while assigning a user another
+// role takes effect once they refresh their token or log in again.
+//
+// Within a room, what a user may do is governed by their room role
+// (owner, co-host, moderator, member or viewer; see RoomCan), whatever
+// their global role. Only the rooms.moderate permission reaches into every
+// room, granting what the room's owner could do.
+//
+// The same Authorizer is used by the HTTP middleware (RequirePermission),
+// the handlers and the Hub.
+package authz
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync/atomic"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// Permissions. Keep All in sync.
+const (
+	PermChatSend            = "chat.send"            // send chat messages
+	PermRoomsCreate         = "rooms.create"         // create rooms (subject to the trust level too)
+	PermRoomsModerate       = "rooms.moderate"       // moderate any room, as its owner could
+	PermReportsReview       = "reports.review"       // work the moderation queue; notified of new reports
+	PermUsersRead           = "users.read"           // list and view accounts
+	PermUsersModerate       = "users.moderate"       // shadow-ban users
+	PermUsersManage         = "users.manage"         // delete, restore, import, export and assign roles
+	PermAuditRead           = "audit.read"           // read the audit log
+	PermStatsRead           = "stats.read"           // the admin overview
+	PermWordFiltersManage   = "word_filters.manage"  // chat word filters
+	PermOriginsManage       = "origins.manage"       // origins allowed at runtime
+	PermRolesManage         = "roles.manage"         // define custom roles
+	PermBroadcast           = "broadcast.send"       // send "admin" WebSocket messages
+	PermAnnouncementsManage = "announcements.manage" // site-wide announcement banners
+	PermInstanceManage      = "instance.manage"      // edit the instance metadata (name, logo, MOTD...)
+	PermInvitesManage       = "invites.manage"       // create and revoke invite codes for registration
+	PermLegalManage         = "legal.manage"         // publish new versions of the terms of service and privacy policy
+	PermTrustBypass         = "trust.bypass"         // use capabilities gated by trust level regardless of it
+	PermQuotaBypass         = "quota.bypass"         // create rooms, library items and uploads beyond the quotas
+)
+
+// All lists every permission.
+var All = []string{
+	PermChatSend, PermRoomsCreate, PermRoomsModerate, PermReportsReview,
+	PermUsersRead, PermUsersModerate, PermUsersManage, PermAuditRead,
+	PermStatsRead, PermWordFiltersManage, PermOriginsManage, PermRolesManage,
+	PermBroadcast, PermAnnouncementsManage, PermInstanceManage, PermTrustBypass,
+	PermQuotaBypass, PermInvitesManage, PermLegalManage,
+}
+
+// builtIn holds the built-in roles, in the order they are listed. Admins
+// get every permission, including ones added later.
+var builtIn = []*models.RoleDefinition{
+	{Name: models.RoleAdmin, Description: "Full access", Permissions: All},
+	{Name: models.RoleModerator, Description: "Moderates rooms, reports and users", Permissions: []string{
+		PermChatSend, PermRoomsCreate, PermRoomsModerate, PermReportsReview, PermUsersRead, PermUsersModerate,
+	}},
+	{Name: models.RoleMember, Description: "Chats and creates rooms", Permissions: []string{PermChatSend, PermRoomsCreate}},
+	{Name: models.RoleViewer, Description: "Watches and chats", Permissions: []string{PermChatSend}},
+}
+
+func init() {
+	for _, r := range builtIn {
+		r.BuiltIn = true
+	}
+}
+
+// ValidPermission reports whether perm is one of the Perm* constants.
+func ValidPermission(perm string) bool {
+	return slices.Contains(All, perm)
+}
+
+// BuiltIn returns copies of the built-in roles.
+func BuiltIn() []*models.RoleDefinition {
+	roles := make([]*models.RoleDefinition, len(builtIn))
+	for i, r := range builtIn {
+		c := *r
+		c.Permissions = slices.Clone(r.Permissions)
+		roles[i] = &c
+	}
+	return roles
+}
+
+// IsBuiltIn reports whether name is a built-in role.
+func IsBuiltIn(name string) bool {
+	for _, r := range builtIn {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// roleSet is an immutable snapshot of the roles.
+type roleSet struct {
+	roles []*models.RoleDefinition // built-in first, then custom by name
+	perms map[string]map[string]bool
+}
+
+func newRoleSet(custom []*models.RoleDefinition) *roleSet {
+	s := &roleSet{perms: make(map[string]map[string]bool)}
+	add := func(r *models.RoleDefinition) {
+		if _, dup := s.perms[r.Name]; dup {
+			return // a stored role shadowing a built-in one
+		}
+		s.roles = append(s.roles, r)
+		s.perms[r.Name] = make(map[string]bool, len(r.Permissions))
+		for _, p := range r.Permissions {
+			s.perms[r.Name][p] = true
+		}
+	}
+	for _, r := range builtIn {
+		add(r)
+	}
+	custom = slices.Clone(custom)
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+	for _, r := range custom {
+		add(r)
+	}
+	return s
+}
+
+// defaultSet holds the built-in roles only.
+var defaultSet = newRoleSet(nil)
+
+// Authorizer answers permission checks. The zero value knows the built-in
+// roles only; Reload adds the custom ones. It is safe for concurrent use.
+type Authorizer struct {
+	set atomic.Pointer[roleSet]
+}
+
+func (a *Authorizer) load() *roleSet {
+	if s := a.set.Load(); s != nil {
+		return s
+	}
+	return defaultSet
+}
+
+// Can reports whether role grants perm. Unknown roles (e.g. a custom
+// role deleted while tokens naming it are still valid) grant nothing.
+func (a *Authorizer) Can(role, perm string) bool {
+	return a.load().perms[role][perm]
+}
+
+// Permissions returns the permissions role grants, in the order of All.
+func (a *Authorizer) Permissions(role string) []string {
+	granted := a.load().perms[role]
+	perms := []string{}
+	for _, p := range All {
+		if granted[p] {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// Exists reports whether role is a built-in or custom role.
+func (a *Authorizer) Exists(role string) bool {
+	_, ok := a.load().perms[role]
+	return ok
+}
+
+// Roles returns every role, built-in ones first, then custom ones by name.
+func (a *Authorizer) Roles() []*models.RoleDefinition {
+	return slices.Clone(a.load().roles)
+}
+
+// Set replaces the custom roles.
+func (a *Authorizer) Set(custom []*models.RoleDefinition) {
+	a.set.Store(newRoleSet(custom))
+}
+
+// Reload replaces the custom roles with those stored in repo.
+func (a *Authorizer) Reload(ctx context.Context, repo repository.RoleRepository) error {
+	roles, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	a.Set(roles)
+	return nil
+}
+
+// --- Room roles ---
+
+// Room permissions, granted by a user's role in a room (models.RoomRole*)
+// rather than their global role. Keep RoomPermissionsAll in sync.
+const (
+	RoomPermChat         = "room.chat"     // send chat messages in the room
+	RoomPermVideoControl = "video.control" // play, pause, seek and load the room's video
+	RoomPermInvite       = "room.invite"   // add users as members or viewers
+	RoomPermModerate     = "room.moderate" // edit settings and retention, remove members, export chat, read analytics
+	RoomPermAssignRoles  = "room.roles"    // change the room roles of members ranked below oneself
+	RoomPermDelete       = "room.delete"   // close the room
+)
+
+// RoomPermissionsAll lists every room permission.
+var RoomPermissionsAll = []string{
+	RoomPermChat, RoomPermVideoControl, RoomPermInvite, RoomPermModerate, RoomPermAssignRoles, RoomPermDelete,
+}
+
+// roomRoles holds the room roles, highest first, with their permissions.
+// Unlike global roles they are fixed.
+var roomRoles = []struct {
+	name  string
+	perms []string
+}{
+	{models.RoomRoleOwner, RoomPermissionsAll},
+	{models.RoomRoleCoHost, []string{RoomPermChat, RoomPermVideoControl, RoomPermInvite, RoomPermModerate, RoomPermAssignRoles}},
+	{models.RoomRoleModerator, []string{RoomPermChat, RoomPermInvite, RoomPermModerate, RoomPermAssignRoles}},
+	{models.RoomRoleMember, []string{RoomPermChat}},
+	{models.RoomRoleViewer, nil},
+}
+
+// RoomCan reports whether roomRole grants the room permission perm.
+// Unknown room roles, including "" for non-members, grant nothing.
+func RoomCan(roomRole, perm string) bool {
+	for _, r := range roomRoles {
+		if r.name == roomRole {
+			return slices.Contains(r.perms, perm)
+		}
+	}
+	return false
+}
+
+// RoomPermissions returns the room permissions roomRole grants, in the
+// order of RoomPermissionsAll.
+func RoomPermissions(roomRole string) []string {
+	perms := []string{}
+	for _, p := range RoomPermissionsAll {
+		if RoomCan(roomRole, p) {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// RoomRoleRank orders room roles: higher ranks outrank lower ones, and
+// unknown roles rank lowest (-1). Members may only change the roles of,
+// or remove, members ranked below themselves.
+func RoomRoleRank(roomRole string) int {
+	for i, r := range roomRoles {
+		if r.name == roomRole {
+			return len(roomRoles) - 1 - i
+		}
+	}
+	return -1
+}
+
+// NonMemberRoomRole returns the room role of users who are not members
+// of a room of type roomType: viewers in public rooms, and nothing ("")
+// in private and direct rooms, which are for invited members.
+func NonMemberRoomRole(roomType string) string {
+	if roomType == models.RoomTypePublic {
+		return models.RoomRoleViewer
+	}
+	return ""
+}
+
+// ValidRoomRole reports whether roomRole is a models.RoomRole* constant.
+func ValidRoomRole(roomRole string) bool {
+	return RoomRoleRank(roomRole) >= 0
+}
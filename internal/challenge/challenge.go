@@ -0,0 +1,99 @@
+// Package challenge keeps bots off registration and login on public
+// instances: before an attempt goes through, the client may have to pass
+// a CAPTCHA (hCaptcha or Cloudflare Turnstile, verified server-side) or
+// spend CPU time on a built-in proof of work.
+//
+// A Verifier checks the answer a client sends along with its request. A
+// Guard decides when one is required: on every attempt, or only once an
+// IP has made too many attempts within a window, counted in the ephemeral
+// counters so every instance sees the same count.
+//
+// Usage:
+//
+//	guard := challenge.NewGuard(challenge.NewTurnstile(secret, siteKey), false, counters, 5, 10*time.Minute)
+//	err := guard.Check(ctx, ip, req.ChallengeResponse) // ErrRequired, ErrFailed or nil
+package challenge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/apperr"
+)
+
+// Providers, as in CHALLENGE_PROVIDER.
+const (
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderTurnstile = "turnstile"
+	ProviderPoW       = "pow"
+)
+
+var (
+	// ErrRequired is returned by Guard.Check when the attempt needs an
+	// answer and came without one.
+	ErrRequired = apperr.New(apperr.Forbidden, "challenge_required", "challenge: required")
+
+	// ErrFailed is returned when an answer is wrong, expired or already
+	// used.
+	ErrFailed = apperr.New(apperr.Forbidden, "challenge_failed", "challenge: failed")
+)
+
+// Verifier hands out challenges and checks the answers.
+type Verifier interface {
+	// Challenge returns what a client needs to answer a challenge; for
+	// the proof of work, a fresh puzzle.
+	Challenge() (*models.Challenge, error)
+
+	// Verify checks a client's answer, returning an error wrapping
+	// ErrFailed if it is wrong. remoteIP, if known, helps providers judge
+	// it.
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+// Guard decides which attempts need a challenge and checks their answers.
+type Guard struct {
+	verifier Verifier
+	always   bool
+	counters repository.CounterRepository
+	attempts int64
+	window   time.Duration
+}
+
+// NewGuard creates a Guard checking answers with verifier. With always
+// set every attempt needs one; otherwise only those from an IP that made
+// more than attempts attempts within window.
+func NewGuard(verifier Verifier, always bool, counters repository.CounterRepository, attempts int64, window time.Duration) *Guard {
+	return &Guard{verifier: verifier, always: always, counters: counters, attempts: attempts, window: window}
+}
+
+// Challenge returns what a client needs to answer a challenge.
+func (g *Guard) Challenge() (*models.Challenge, error) {
+	c, err := g.verifier.Challenge()
+	if err != nil {
+		return nil, err
+	}
+	c.Always = g.always
+	return c, nil
+}
+
+// Check counts an attempt from ip and, if it needs a challenge, checks
+// response, the client's answer. It returns ErrRequired if the answer is
+// missing and an error wrapping ErrFailed if it is wrong.
+func (g *Guard) Check(ctx context.Context, ip, response string) error {
+	if !g.always {
+		n, err := g.counters.Incr(ctx, "challenge:"+ip, g.window)
+		if err != nil {
+			return fmt.Errorf("challenge: count attempts: %w", err)
+		}
+		if n <= g.attempts {
+			return nil
+		}
+	}
+	if response == "" {
+		return ErrRequired
+	}
+	return g.verifier.Verify(ctx, response, ip)
+}
@@ -0,0 +1,122 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// powTTL is how long a proof-of-work puzzle may be answered.
+const powTTL = 5 * time.Minute
+
+// ProofOfWork is the built-in challenge, needing no third party: the
+// client gets a puzzle and must find a solution such that
+// SHA-256(puzzle + ":" + solution) starts with Difficulty zero bits, and
+// answers with puzzle + ":" + solution. Each bit of difficulty doubles
+// the work, on average 2^Difficulty hashes.
+//
+// Puzzles are "expiry.nonce.difficulty.mac", signed with an HMAC so the
+// server keeps no state until one is answered; every instance sharing the
+// key accepts the others' puzzles. Each is accepted once: answered
+// nonces are remembered in the ephemeral counters until they expire.
+type ProofOfWork struct {
+	key        []byte
+	difficulty int
+	counters   repository.CounterRepository
+	now        func() time.Time
+}
+
+// NewProofOfWork creates the proof-of-work Verifier, signing puzzles with
+// key and asking for difficulty zero bits.
+func NewProofOfWork(key string, difficulty int, counters repository.CounterRepository) *ProofOfWork {
+	mac := sha256.Sum256([]byte("ofenes challenge:" + key))
+	return &ProofOfWork{key: mac[:], difficulty: difficulty, counters: counters, now: time.Now}
+}
+
+// Challenge returns a fresh puzzle.
+func (p *ProofOfWork) Challenge() (*models.Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	body := fmt.Sprintf("%d.%s.%d", p.now().Add(powTTL).Unix(), hex.EncodeToString(nonce), p.difficulty)
+	return &models.Challenge{
+		Provider:   ProviderPoW,
+		Puzzle:     body + "." + p.sign(body),
+		Difficulty: p.difficulty,
+	}, nil
+}
+
+// Verify checks response, puzzle + ":" + solution.
+func (p *ProofOfWork) Verify(ctx context.Context, response, _ string) error {
+	puzzle, solution, ok := strings.Cut(response, ":")
+	if !ok || solution == "" || len(solution) > 64 {
+		return fmt.Errorf("challenge: malformed answer: %w", ErrFailed)
+	}
+	body, mac, ok := cutLast(puzzle, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(p.sign(body))) {
+		return fmt.Errorf("challenge: bad puzzle signature: %w", ErrFailed)
+	}
+	parts := strings.Split(body, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("challenge: malformed puzzle: %w", ErrFailed)
+	}
+	expiry, err1 := strconv.ParseInt(parts[0], 10, 64)
+	difficulty, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("challenge: malformed puzzle: %w", ErrFailed)
+	}
+	if !p.now().Before(time.Unix(expiry, 0)) {
+		return fmt.Errorf("challenge: puzzle expired: %w", ErrFailed)
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(puzzle+":"+solution))) < difficulty {
+		return fmt.Errorf("challenge: not enough work: %w", ErrFailed)
+	}
+
+	// Last, so wrong answers don't use the puzzle up.
+	n, err := p.counters.Incr(ctx, "challenge:pow:"+parts[1], powTTL)
+	if err != nil {
+		return fmt.Errorf("challenge: remember puzzle: %w", err)
+	}
+	if n > 1 {
+		return fmt.Errorf("challenge: puzzle already used: %w", ErrFailed)
+	}
+	return nil
+}
+
+// sign returns the hex HMAC of a puzzle's body.
+func (p *ProofOfWork) sign(body string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cutLast slices s around the last sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// leadingZeroBits counts the zero bits sum starts with.
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+const (
+	// hCaptchaVerifyURL is hCaptcha's siteverify endpoint.
+	hCaptchaVerifyURL = "https://api.hcaptcha.com/siteverify"
+
+	// turnstileVerifyURL is Cloudflare Turnstile's siteverify endpoint.
+	turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// SiteVerify checks the tokens of a CAPTCHA widget (hCaptcha or Turnstile,
+// which share the protocol) with the provider's siteverify endpoint.
+type SiteVerify struct {
+	provider string
+	verify   string
+	secret   string
+	siteKey  string
+	client   *http.Client
+}
+
+// NewHCaptcha creates a Verifier for hCaptcha. secret is the account's
+// secret key, siteKey the widget's, handed to clients.
+func NewHCaptcha(secret, siteKey string) *SiteVerify {
+	return newSiteVerify(ProviderHCaptcha, hCaptchaVerifyURL, secret, siteKey)
+}
+
+// NewTurnstile creates a Verifier for Cloudflare Turnstile, like
+// NewHCaptcha.
+func NewTurnstile(secret, siteKey string) *SiteVerify {
+	return newSiteVerify(ProviderTurnstile, turnstileVerifyURL, secret, siteKey)
+}
+
+func newSiteVerify(provider, verify, secret, siteKey string) *SiteVerify {
+	return &SiteVerify{
+		provider: provider,
+		verify:   verify,
+		secret:   secret,
+		siteKey:  siteKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Challenge returns the provider and site key the client's widget needs.
+func (s *SiteVerify) Challenge() (*models.Challenge, error) {
+	return &models.Challenge{Provider: s.provider, SiteKey: s.siteKey}, nil
+}
+
+// Verify asks the provider whether response, the widget's token, is valid.
+// Tokens are single-use; the provider rejects a replayed one.
+func (s *SiteVerify) Verify(ctx context.Context, response, remoteIP string) error {
+	form := url.Values{"secret": {s.secret}, "response": {response}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.verify, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("challenge: %s: %w", s.provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("challenge: %s: status %d", s.provider, resp.StatusCode)
+	}
+	var out struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out); err != nil {
+		return fmt.Errorf("challenge: %s: decode: %w", s.provider, err)
+	}
+	if !out.Success {
+		return fmt.Errorf("challenge: %s %v: %w", s.provider, out.ErrorCodes, ErrFailed)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// envFiles are the files Load reads variables from, in the working
+// directory, highest precedence first. Both are optional. The process
+// environment wins over either, and either over the defaults, so a shell
+// export or docker-compose can still override a value for one run.
+var envFiles = []string{".env.local", ".env"}
+
+// fileValue is a variable read from one of envFiles.
+type fileValue struct {
+	value string
+	file  string
+}
+
+// readEnvFiles loads envFiles into env.files. A missing file is skipped;
+// one that can't be read or parsed is an error from Load.
+func readEnvFiles() {
+	for _, name := range envFiles {
+		data, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			env.invalid.add(name, "%s: %v", name, err)
+			continue
+		}
+		vars, err := parseEnvFile(string(data))
+		if err != nil {
+			env.invalid.add(name, "%s: %v", name, err)
+			continue
+		}
+		for key, value := range vars {
+			if _, ok := env.files[key]; !ok {
+				env.files[key] = fileValue{value: value, file: name}
+			}
+		}
+	}
+}
+
+// parseEnvFile parses dotenv syntax: KEY=VALUE lines, optionally after
+// "export ", with blank lines and # comments. A value in double quotes
+// may use \n, \", \\ and \$ escapes; one in single quotes is taken as is;
+// an unquoted one ends at " #" and is trimmed. Values span one line and
+// are not expanded. A key given twice takes the last value.
+func parseEnvFile(data string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validEnvKey(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", i+1, key, err)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// parseEnvValue unquotes the value of a dotenv line.
+func parseEnvValue(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		var b strings.Builder
+		for i := 1; i < len(v); i++ {
+			switch c := v[i]; c {
+			case '"':
+				if rest := strings.TrimSpace(v[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+					return "", errors.New("text after the closing quote")
+				}
+				return b.String(), nil
+			case '\\':
+				if i+1 == len(v) {
+					return "", errors.New("unterminated double quote")
+				}
+				i++
+				switch v[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case '"', '\\', '$':
+					b.WriteByte(v[i])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(v[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", errors.New("unterminated double quote")
+	case strings.HasPrefix(v, "'"):
+		end := strings.IndexByte(v[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		if rest := strings.TrimSpace(v[end+2:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", errors.New("text after the closing quote")
+		}
+		return v[1 : end+1], nil
+	default:
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = v[:i]
+		}
+		return strings.TrimSpace(v), nil
+	}
+}
+
+// validEnvKey reports whether key is a variable name: letters, digits and
+// underscores, not starting with a digit.
+func validEnvKey(key string) bool {
+	if key == "" || key[0] >= '0' && key[0] <= '9' {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
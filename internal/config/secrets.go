@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"ofenes/internal/secrets"
+)
+
+// secretSetting is a setting that may hold a secret:// reference instead
+// of a value.
+type secretSetting struct {
+	name  string
+	value *string
+}
+
+// secretSettings returns the settings that may hold references. Database
+// and Redis URLs are read once, at startup; only JWT_SECRET is read again
+// (see RotateJWTSecret).
+func (c *Config) secretSettings() []secretSetting {
+	return []secretSetting{
+		{"JWT_SECRET", &c.JWTSecret},
+		{"SERVICE_JWT_SECRET", &c.ServiceJWTSecret},
+		{"DATABASE_URL", &c.DatabaseURL},
+		{"MONGO_URL", &c.MongoURL},
+		{"REDIS_URL", &c.RedisURL},
+		{"LDAP_BIND_PASSWORD", &c.LDAPBindPassword},
+		{"CHALLENGE_SECRET", &c.ChallengeSecret},
+		{"METADATA_API_KEY", &c.MetadataAPIKey},
+		{"ENTITLEMENTS_WEBHOOK_SECRET", &c.EntitlementsWebhookSecret},
+		{"NOW_PLAYING_WEBHOOK_SECRET", &c.NowPlayingWebhookSecret},
+	}
+}
+
+// resolveSecrets replaces the secret:// references in secretSettings with
+// the secrets, fetched with SECRETS_PROVIDER. A reference that can't be
+// resolved is an error from Load. Report still shows the references.
+func (c *Config) resolveSecrets() {
+	store, err := c.newSecretStore()
+	if err != nil {
+		env.invalid.add("SECRETS_PROVIDER", "%v", err)
+		return
+	}
+	c.secrets = store
+	for _, s := range c.secretSettings() {
+		name, value := s.name, s.value
+		if !secrets.IsRef(*value) {
+			continue
+		}
+		if store == nil {
+			env.invalid.add(name, "%s is a %s reference, but SECRETS_PROVIDER is off", name, secrets.Scheme)
+			continue
+		}
+		ref := *value
+		resolved, err := store.Get(context.Background(), ref)
+		if err != nil {
+			env.invalid.add(name, "%s: %v", name, err)
+			continue
+		}
+		*value = resolved
+		if name == "JWT_SECRET" {
+			c.jwtSecretRef = ref
+		}
+	}
+}
+
+// newSecretStore returns the Store for SECRETS_PROVIDER, nil if it is off.
+func (c *Config) newSecretStore() (*secrets.Store, error) {
+	var provider secrets.Provider
+	var err error
+	switch c.SecretsProvider {
+	case "off":
+		return nil, nil
+	case "vault":
+		provider, err = secrets.NewVault(c.VaultAddr, c.VaultToken, c.VaultKVMount, c.VaultNamespace)
+	case "aws":
+		provider, err = secrets.NewAWS(c.AWSRegion, c.AWSSecretsEndpoint, secrets.AWSCredentials{
+			AccessKeyID:     c.AWSAccessKeyID,
+			SecretAccessKey: c.AWSSecretAccessKey,
+			SessionToken:    c.AWSSessionToken,
+		})
+	default:
+		return nil, fmt.Errorf("SECRETS_PROVIDER must be off, vault or aws (got %q)", c.SecretsProvider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SECRETS_PROVIDER=%s: %v", c.SecretsProvider, err)
+	}
+	return secrets.New(provider, c.SecretsCacheTTL), nil
+}
+
+// RotatesJWTSecret reports whether RotateJWTSecret has anything to do:
+// JWT_SECRET is a secret:// reference and SECRETS_ROTATION_INTERVAL_MS is
+// set.
+func (c *Config) RotatesJWTSecret() bool {
+	return c.jwtSecretRef != "" && c.SecretsRotationInterval > 0
+}
+
+// RotateJWTSecret reads a secret:// JWT_SECRET again and, if it changed,
+// signs new tokens with the new secret while still accepting those signed
+// with the old one until they expire (see auth.Keys). It reports whether
+// the secret changed. A new secret is refused if it is empty, equals
+// SERVICE_JWT_SECRET or, with PRODUCTION set, is weak.
+//
+// Only tokens roll over: WebSocket resume tokens, playback and HLS proxy
+// URLs and proof-of-work challenges stay signed with the secret the
+// server started with, JWTSecret.
+func (c *Config) RotateJWTSecret(ctx context.Context) (bool, error) {
+	if c.jwtSecretRef == "" {
+		return false, nil
+	}
+	secret, err := c.secrets.Get(ctx, c.jwtSecretRef)
+	if err != nil {
+		return false, fmt.Errorf("config: JWT_SECRET: %w", err)
+	}
+	switch {
+	case secret == "":
+		return false, fmt.Errorf("config: JWT_SECRET: the new secret is empty")
+	case secret == c.ServiceJWTSecret:
+		return false, fmt.Errorf("config: JWT_SECRET: the new secret equals SERVICE_JWT_SECRET")
+	case c.Production && secretBits(secret) < minSecretBits:
+		return false, fmt.Errorf("config: JWT_SECRET: the new secret is too weak (about %.0f bits, %d needed)", secretBits(secret), minSecretBits)
+	}
+	return c.jwtKeys.Rotate(secret), nil
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ofenes/internal/username"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBuckets lists the top-level buckets, one per entity plus secondary
+// indexes. Keys and value formats are documented in repository/bolt.go.
+var boltBuckets = []string{
+	"users", "users_by_username", "users_by_skeleton",
+	"rooms", "room_members", "member_rooms",
+	"messages", "messages_by_id",
+	"media_sessions", "media_session_participants",
+	"shared_files",
+	"media_files",
+	"subtitles",
+	"library",
+	"metadata_lookups",
+	"audit_log",
+	"dead_letters",
+	"reports", "reports_by_id",
+	"word_filters",
+	"allowed_origins",
+	"announcements", "announcement_dismissals",
+	"instance",
+	"invites", "invites_by_code",
+	"legal_documents", "legal_acceptances",
+	"roles",
+}
+
+// boltCompactTxSize caps how much data the compaction copies per transaction.
+const boltCompactTxSize = 64 << 20
+
+// OpenBolt opens (or creates) the bbolt database file at path.
+//
+// With compact set, an existing file is first rewritten into a fresh file,
+// reclaiming the space bbolt never returns to the OS after deletes.
+func OpenBolt(path string, compact bool) (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("database: failed to create bolt directory: %w", err)
+	}
+
+	if compact {
+		if err := compactBolt(path); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("database: failed to open bolt file: %w", err)
+	}
+
+	log.Printf("database: opened bolt file %s", path)
+	return db, nil
+}
+
+// MigrateBolt creates any missing buckets, re-keys usernames indexed
+// before they were case-insensitive and indexes the skeletons of usernames
+// created before there was a skeleton index.
+func MigrateBolt(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		indexSkeletons := tx.Bucket([]byte("users_by_skeleton")) == nil
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("database: failed to create bucket %s: %w", name, err)
+			}
+		}
+		if err := rekeyBoltUsernames(tx); err != nil {
+			return err
+		}
+		if indexSkeletons {
+			return indexBoltSkeletons(tx)
+		}
+		return nil
+	})
+}
+
+// rekeyBoltUsernames moves users_by_username entries to username.Key. Like
+// PostgreSQL migration 000021, it fails if two users only differ by case,
+// leaving the file as it was; rename one of them first.
+func rekeyBoltUsernames(tx *bolt.Tx) error {
+	byName := tx.Bucket([]byte("users_by_username"))
+	var stale [][]byte
+	byName.ForEach(func(k, _ []byte) error {
+		if username.Key(string(k)) != string(k) {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+		return nil
+	})
+
+	for _, k := range stale {
+		id := append([]byte(nil), byName.Get(k)...)
+		key := []byte(username.Key(string(k)))
+		if other := byName.Get(key); other != nil && !bytes.Equal(other, id) {
+			return fmt.Errorf("database: username %q of user %s only differs by case from user %s's; rename one of them first", k, id, other)
+		}
+		if err := byName.Put(key, id); err != nil {
+			return fmt.Errorf("database: failed to re-key username %q: %w", k, err)
+		}
+		if err := byName.Delete(k); err != nil {
+			return fmt.Errorf("database: failed to re-key username %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// indexBoltSkeletons fills users_by_skeleton from users_by_username, whose
+// keys have the same skeletons as the usernames.
+func indexBoltSkeletons(tx *bolt.Tx) error {
+	bySkel := tx.Bucket([]byte("users_by_skeleton"))
+	return tx.Bucket([]byte("users_by_username")).ForEach(func(k, id []byte) error {
+		key := bytes.Join([][]byte{[]byte(username.Skeleton(string(k))), id}, []byte{0})
+		if err := bySkel.Put(key, id); err != nil {
+			return fmt.Errorf("database: failed to index username %q: %w", k, err)
+		}
+		return nil
+	})
+}
+
+// compactBolt rewrites the file at path into a compacted copy and swaps it in.
+// A missing file is not an error.
+func compactBolt(path string) error {
+	before, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("database: failed to stat bolt file: %w", err)
+	}
+
+	src, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true, Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("database: failed to open bolt file for compaction: %w", err)
+	}
+
+	tmp := path + ".compact"
+	os.Remove(tmp) // left over from an interrupted compaction
+	dst, err := bolt.Open(tmp, 0o600, nil)
+	if err != nil {
+		src.Close()
+		return fmt.Errorf("database: failed to create compacted bolt file: %w", err)
+	}
+
+	err = bolt.Compact(dst, src, boltCompactTxSize)
+	src.Close()
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("database: bolt compaction failed: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("database: failed to replace bolt file: %w", err)
+	}
+
+	if after, err := os.Stat(path); err == nil {
+		log.Printf("database: compacted bolt file (%d -> %d bytes)", before.Size(), after.Size())
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+-- 000002_user_soft_delete.down.sql
+
+DROP INDEX IF EXISTS idx_users_deleted;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
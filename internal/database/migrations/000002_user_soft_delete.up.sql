@@ -0,0 +1,7 @@
+-- 000002_user_soft_delete.up.sql
+-- Soft-delete for users: deleted rows keep their username and data until
+-- restored, but are hidden from regular queries.
+
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_users_deleted ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+-- 000003_user_list_indexes.down.sql
+
+DROP INDEX IF EXISTS idx_users_created;
+DROP INDEX IF EXISTS idx_users_role_created;
+DROP INDEX IF EXISTS idx_users_username_prefix;
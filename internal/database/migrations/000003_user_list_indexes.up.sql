@@ -0,0 +1,9 @@
+-- 000003_user_list_indexes.up.sql
+-- Indexes for the filtered admin user listing.
+
+-- Username prefix search (LIKE 'abc%'); the UNIQUE index can't serve it
+-- outside the C collation.
+CREATE INDEX idx_users_username_prefix ON users (username text_pattern_ops);
+
+CREATE INDEX idx_users_role_created ON users (role, created_at);
+CREATE INDEX idx_users_created ON users (created_at);
@@ -0,0 +1,3 @@
+-- 000004_room_retention.down.sql
+
+ALTER TABLE rooms DROP COLUMN IF EXISTS retention;
@@ -0,0 +1,5 @@
+-- 000004_room_retention.up.sql
+-- Per-room message retention policy ({"mode": "days", "days": 30}).
+-- NULL means the server default (MESSAGE_RETENTION).
+
+ALTER TABLE rooms ADD COLUMN retention JSONB;
@@ -0,0 +1,3 @@
+-- 000005_audit_log.down.sql
+
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,16 @@
+-- 000005_audit_log.up.sql
+-- Append-only log of administrative and automated actions.
+
+CREATE TABLE audit_log (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id    UUID,              -- NULL for background jobs; no FK so entries outlive users
+    action      TEXT NOT NULL,
+    target_type TEXT NOT NULL DEFAULT '',
+    target_id   TEXT NOT NULL DEFAULT '',
+    details     JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_created ON audit_log (created_at DESC);
+CREATE INDEX idx_audit_log_action_created ON audit_log (action, created_at DESC);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id) WHERE actor_id IS NOT NULL;
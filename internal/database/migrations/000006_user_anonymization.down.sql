@@ -0,0 +1,3 @@
+-- 000006_user_anonymization.down.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS anonymized_at;
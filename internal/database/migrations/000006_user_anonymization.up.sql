@@ -0,0 +1,6 @@
+-- 000006_user_anonymization.up.sql
+-- Anonymized users keep their row (so messages and audit entries still
+-- resolve) but their username is replaced by a pseudonym and their
+-- profile data is erased. They are also soft-deleted and cannot be restored.
+
+ALTER TABLE users ADD COLUMN anonymized_at TIMESTAMPTZ;
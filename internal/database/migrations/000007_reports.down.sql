@@ -0,0 +1,3 @@
+-- 000007_reports.down.sql
+
+DROP TABLE IF EXISTS reports;
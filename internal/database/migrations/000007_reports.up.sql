@@ -0,0 +1,19 @@
+-- 000007_reports.up.sql
+-- Content reports (abuse flags) raised by users against messages, users
+-- or rooms, reviewed by moderators.
+
+CREATE TABLE reports (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reporter_id UUID NOT NULL REFERENCES users(id),
+    target_type TEXT NOT NULL,             -- 'message', 'user' or 'room'
+    target_id   TEXT NOT NULL,
+    room_id     UUID,                      -- room of a reported message, or the reported room
+    reason      TEXT NOT NULL,
+    details     TEXT NOT NULL DEFAULT '',
+    status      TEXT NOT NULL DEFAULT 'open',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_reports_status_created ON reports (status, created_at DESC);
+CREATE INDEX idx_reports_target ON reports (target_type, target_id);
+CREATE INDEX idx_reports_reporter ON reports (reporter_id);
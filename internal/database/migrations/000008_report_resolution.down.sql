@@ -0,0 +1,3 @@
+-- 000008_report_resolution.down.sql
+
+ALTER TABLE reports DROP COLUMN IF EXISTS resolution;
@@ -0,0 +1,5 @@
+-- 000008_report_resolution.up.sql
+-- How a moderator handled a report ({"action": "mute", "resolvedBy": ...}).
+-- NULL while the report is open.
+
+ALTER TABLE reports ADD COLUMN resolution JSONB;
@@ -0,0 +1,3 @@
+-- 000009_user_shadow_ban.down.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS shadow_banned_at;
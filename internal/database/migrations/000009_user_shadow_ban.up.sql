@@ -0,0 +1,4 @@
+-- 000009_user_shadow_ban.up.sql
+-- Shadow-banned users' chat is only echoed back to themselves.
+
+ALTER TABLE users ADD COLUMN shadow_banned_at TIMESTAMPTZ;
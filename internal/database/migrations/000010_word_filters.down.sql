@@ -0,0 +1,3 @@
+-- 000010_word_filters.down.sql
+
+DROP TABLE IF EXISTS word_filters;
@@ -0,0 +1,14 @@
+-- 000010_word_filters.up.sql
+-- Chat word filters managed by admins. Filters without a room apply
+-- everywhere; a room filter with the same pattern overrides the global one.
+
+CREATE TABLE word_filters (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pattern    TEXT NOT NULL,
+    regex      BOOLEAN NOT NULL DEFAULT false,
+    action     TEXT NOT NULL,                  -- 'block', 'flag' or 'allow'
+    room_id    UUID REFERENCES rooms(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- 000011_user_trust_level.down.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS trust_level;
@@ -0,0 +1,4 @@
+-- 000011_user_trust_level.up.sql
+-- Trust level from account age and activity, raised by the trust job.
+
+ALTER TABLE users ADD COLUMN trust_level TEXT NOT NULL DEFAULT 'new';
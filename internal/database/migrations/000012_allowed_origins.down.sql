@@ -0,0 +1,3 @@
+-- 000012_allowed_origins.down.sql
+
+DROP TABLE IF EXISTS allowed_origins;
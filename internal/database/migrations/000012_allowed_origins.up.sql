@@ -0,0 +1,10 @@
+-- 000012_allowed_origins.up.sql
+-- Browser origins added by admins at runtime, allowed on top of
+-- CORS_ORIGINS for CORS and WebSocket upgrades.
+
+CREATE TABLE allowed_origins (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    origin     TEXT NOT NULL UNIQUE,           -- lowercased origin or wildcard pattern
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,6 @@
+-- 000013_roles.down.sql
+
+DROP TABLE IF EXISTS roles;
+
+UPDATE users SET role = 'member' WHERE role NOT IN ('admin', 'member', 'viewer');
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'member', 'viewer'));
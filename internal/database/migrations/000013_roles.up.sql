@@ -0,0 +1,13 @@
+-- 000013_roles.up.sql
+-- Custom roles defined by admins (see internal/authz). The built-in
+-- roles are not stored; users.role may now name any role.
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+
+CREATE TABLE roles (
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT[] NOT NULL DEFAULT '{}',   -- authz.Perm* values
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
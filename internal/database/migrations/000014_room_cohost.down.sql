@@ -0,0 +1,6 @@
+-- 000014_room_cohost.down.sql
+
+UPDATE room_members SET role = 'moderator' WHERE role = 'cohost';
+ALTER TABLE room_members DROP CONSTRAINT IF EXISTS room_members_role_check;
+ALTER TABLE room_members ADD CONSTRAINT room_members_role_check
+    CHECK (role IN ('owner', 'moderator', 'member', 'viewer'));
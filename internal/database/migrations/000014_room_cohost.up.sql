@@ -0,0 +1,7 @@
+-- 000014_room_cohost.up.sql
+-- Co-hosts share playback control with the room's owner (see
+-- authz.RoomCan).
+
+ALTER TABLE room_members DROP CONSTRAINT IF EXISTS room_members_role_check;
+ALTER TABLE room_members ADD CONSTRAINT room_members_role_check
+    CHECK (role IN ('owner', 'cohost', 'moderator', 'member', 'viewer'));
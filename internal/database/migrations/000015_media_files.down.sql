@@ -0,0 +1,3 @@
+-- 000015_media_files.down.sql
+
+DROP TABLE IF EXISTS media_files;
@@ -0,0 +1,18 @@
+-- 000015_media_files.up.sql
+-- Videos uploaded for rooms and streamed from GET /media/{id}. The bytes
+-- live in the media store (MEDIA_DIR) under the record's ID.
+
+CREATE TABLE media_files (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id     UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    uploaded_by UUID NOT NULL REFERENCES users(id),
+    file_name   TEXT NOT NULL,
+    mime_type   TEXT NOT NULL,
+    size        BIGINT NOT NULL CHECK (size > 0),
+    status      TEXT NOT NULL DEFAULT 'uploading' CHECK (status IN ('uploading', 'ready')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_media_files_room ON media_files (room_id, created_at DESC);
+CREATE INDEX idx_media_files_uploading ON media_files (created_at) WHERE status = 'uploading';
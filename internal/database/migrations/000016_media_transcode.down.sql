@@ -0,0 +1,10 @@
+-- 000016_media_transcode.down.sql
+
+DROP INDEX IF EXISTS idx_media_files_transcode;
+
+ALTER TABLE media_files
+    DROP COLUMN IF EXISTS transcode_status,
+    DROP COLUMN IF EXISTS transcode_progress,
+    DROP COLUMN IF EXISTS transcode_worker,
+    DROP COLUMN IF EXISTS transcode_error,
+    DROP COLUMN IF EXISTS transcode_updated_at;
@@ -0,0 +1,13 @@
+-- 000016_media_transcode.up.sql
+-- Jobs converting uploaded media to HLS renditions (TRANSCODE_MODE).
+-- transcode_status is NULL for media that was never queued.
+
+ALTER TABLE media_files
+    ADD COLUMN transcode_status     TEXT CHECK (transcode_status IN ('queued', 'running', 'done', 'failed')),
+    ADD COLUMN transcode_progress   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN transcode_worker     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN transcode_error      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN transcode_updated_at TIMESTAMPTZ;
+
+CREATE INDEX idx_media_files_transcode ON media_files (transcode_updated_at)
+    WHERE transcode_status IN ('queued', 'running');
@@ -0,0 +1,3 @@
+-- 000017_subtitles.down.sql
+
+DROP TABLE IF EXISTS subtitles;
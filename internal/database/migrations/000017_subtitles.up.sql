@@ -0,0 +1,17 @@
+-- 000017_subtitles.up.sql
+-- Subtitle tracks uploaded for media files, converted to WebVTT. The
+-- converted files live in the media store next to the media.
+
+CREATE TABLE subtitles (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    media_id    UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    room_id     UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    uploaded_by UUID NOT NULL REFERENCES users(id),
+    label       TEXT NOT NULL,
+    language    TEXT NOT NULL DEFAULT '',
+    format      TEXT NOT NULL CHECK (format IN ('srt', 'ass')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_subtitles_media ON subtitles (media_id, created_at);
+CREATE INDEX idx_subtitles_room ON subtitles (room_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- 000018_library.down.sql
+
+DROP TABLE IF EXISTS library_items;
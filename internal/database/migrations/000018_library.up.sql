@@ -0,0 +1,20 @@
+-- 000018_library.up.sql
+-- Saved video sources: personal ones (owner_id) and rooms' (room_id).
+
+CREATE TABLE library_items (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id   UUID REFERENCES users(id) ON DELETE CASCADE,
+    room_id    UUID REFERENCES rooms(id) ON DELETE CASCADE,
+    added_by   UUID NOT NULL REFERENCES users(id),
+    title      TEXT NOT NULL,
+    url        TEXT NOT NULL,
+    folder     TEXT NOT NULL DEFAULT '',
+    tags       TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((owner_id IS NULL) <> (room_id IS NULL))
+);
+
+CREATE INDEX idx_library_items_owner ON library_items (owner_id, created_at DESC) WHERE owner_id IS NOT NULL;
+CREATE INDEX idx_library_items_room ON library_items (room_id, created_at DESC) WHERE room_id IS NOT NULL;
+CREATE INDEX idx_library_items_tags ON library_items USING GIN (tags);
@@ -0,0 +1,4 @@
+-- 000019_metadata.down.sql
+
+DROP TABLE IF EXISTS metadata_lookups;
+ALTER TABLE library_items DROP COLUMN IF EXISTS metadata;
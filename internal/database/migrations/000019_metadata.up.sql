@@ -0,0 +1,11 @@
+-- 000019_metadata.up.sql
+-- Movie and episode metadata of library items (METADATA_PROVIDER), and the
+-- provider's answers cached by query; metadata is NULL for misses.
+
+ALTER TABLE library_items ADD COLUMN metadata JSONB;
+
+CREATE TABLE metadata_lookups (
+    key        TEXT PRIMARY KEY,
+    metadata   JSONB,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
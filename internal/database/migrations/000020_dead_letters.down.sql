@@ -0,0 +1,3 @@
+-- 000020_dead_letters.down.sql
+
+DROP TABLE IF EXISTS dead_letters;
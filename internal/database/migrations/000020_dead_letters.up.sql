@@ -0,0 +1,21 @@
+-- 000020_dead_letters.up.sql
+-- WebSocket messages the Hub could not route (WS_DEAD_LETTER_PERSIST).
+-- No foreign keys: dead letters are debugging records that outlive users
+-- and rooms until retention deletes them.
+
+CREATE TABLE dead_letters (
+    id         UUID PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    username   TEXT NOT NULL,
+    room_id    TEXT NOT NULL,
+    type       TEXT NOT NULL DEFAULT '',
+    message_id TEXT NOT NULL DEFAULT '',
+    code       TEXT NOT NULL,
+    reason     TEXT NOT NULL DEFAULT '',
+    data       TEXT NOT NULL,
+    truncated  BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_dead_letters_created ON dead_letters (created_at DESC);
+CREATE INDEX idx_dead_letters_user_created ON dead_letters (user_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- 000021_username_case_insensitive.down.sql
+
+DROP INDEX IF EXISTS idx_users_username_lower;
@@ -0,0 +1,6 @@
+-- 000021_username_case_insensitive.up.sql
+-- Usernames are unique without case: "Bob" can't register next to "bob",
+-- and logins look users up by lower(username). Fails if existing users
+-- already differ only by case; rename one of them first.
+
+CREATE UNIQUE INDEX idx_users_username_lower ON users (lower(username));
@@ -0,0 +1,3 @@
+-- 000022_room_sync_policy.down.sql
+
+ALTER TABLE rooms DROP COLUMN IF EXISTS sync_policy;
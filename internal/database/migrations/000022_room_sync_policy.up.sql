@@ -0,0 +1,5 @@
+-- 000022_room_sync_policy.up.sql
+-- Per-room sync tolerance ({"toleranceMs": 750, "nudgeMs": 150}).
+-- NULL means the server default (WS_SYNC_TOLERANCE_MS, WS_SYNC_NUDGE_MS).
+
+ALTER TABLE rooms ADD COLUMN sync_policy JSONB;
@@ -0,0 +1,3 @@
+-- 000023_room_screens.down.sql
+
+ALTER TABLE rooms DROP COLUMN IF EXISTS screens;
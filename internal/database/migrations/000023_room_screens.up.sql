@@ -0,0 +1,5 @@
+-- 000023_room_screens.up.sql
+-- A room's screens besides the main one ([{"id": "stage", "name": "Stage",
+-- "control": "room.chat"}]). NULL means only the main screen.
+
+ALTER TABLE rooms ADD COLUMN screens JSONB;
@@ -0,0 +1,4 @@
+-- 000024_room_tags.down.sql
+
+DROP INDEX IF EXISTS idx_rooms_tags;
+ALTER TABLE rooms DROP COLUMN IF EXISTS tags;
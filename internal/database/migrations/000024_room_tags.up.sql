@@ -0,0 +1,6 @@
+-- 000024_room_tags.up.sql
+-- Tags of a room for discovery (lowercase, sorted), e.g. {anime,study-with-me}.
+
+ALTER TABLE rooms ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_rooms_tags ON rooms USING GIN (tags);
@@ -0,0 +1,4 @@
+-- 000025_announcements.down.sql
+
+DROP TABLE IF EXISTS announcement_dismissals;
+DROP TABLE IF EXISTS announcements;
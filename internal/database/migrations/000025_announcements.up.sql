@@ -0,0 +1,26 @@
+-- 000025_announcements.up.sql
+-- Site-wide announcement banners managed by admins, shown from starts_at
+-- until ends_at (NULL = until deleted), and which users dismissed them.
+
+CREATE TABLE announcements (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    title      TEXT NOT NULL,
+    body       TEXT NOT NULL DEFAULT '',
+    level      TEXT NOT NULL,                  -- 'info', 'warning' or 'critical'
+    starts_at  TIMESTAMPTZ NOT NULL,
+    ends_at    TIMESTAMPTZ,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_announcements_ends ON announcements (ends_at);
+
+CREATE TABLE announcement_dismissals (
+    announcement_id UUID NOT NULL REFERENCES announcements(id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dismissed_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, announcement_id)
+);
+
+CREATE INDEX idx_announcement_dismissals_announcement ON announcement_dismissals (announcement_id);
@@ -0,0 +1,3 @@
+-- 000026_instance.down.sql
+
+DROP TABLE IF EXISTS instance;
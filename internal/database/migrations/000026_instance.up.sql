@@ -0,0 +1,14 @@
+-- 000026_instance.up.sql
+-- Instance metadata saved by an admin (GET /api/instance). A single row:
+-- id is always TRUE.
+
+CREATE TABLE instance (
+    id          BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    logo_url    TEXT NOT NULL DEFAULT '',
+    motd        TEXT NOT NULL DEFAULT '',
+    contact     TEXT NOT NULL DEFAULT '',
+    updated_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- 000027_invites.down.sql
+
+DROP TABLE IF EXISTS invites;
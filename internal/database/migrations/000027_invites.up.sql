@@ -0,0 +1,15 @@
+-- 000027_invites.up.sql
+-- Invite codes for REGISTRATION_MODE=invite-only, usable max_uses times
+-- until expires_at (NULL = never).
+
+CREATE TABLE invites (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code       TEXT NOT NULL UNIQUE,
+    max_uses   INTEGER NOT NULL CHECK (max_uses > 0),
+    uses       INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_invites_created ON invites (created_at DESC);
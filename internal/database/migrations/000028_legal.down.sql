@@ -0,0 +1,4 @@
+-- 000028_legal.down.sql
+
+DROP TABLE IF EXISTS legal_acceptances;
+DROP TABLE IF EXISTS legal_documents;
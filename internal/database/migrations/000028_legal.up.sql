@@ -0,0 +1,24 @@
+-- 000028_legal.up.sql
+-- Versions of the legal documents users must accept (terms of service,
+-- privacy policy) and who accepted which version when. Neither is ever
+-- updated: the newest version of a kind is the current one.
+
+CREATE TABLE legal_documents (
+    kind         TEXT NOT NULL,                 -- 'terms' or 'privacy'
+    version      TEXT NOT NULL,
+    title        TEXT NOT NULL,
+    body         TEXT NOT NULL,
+    published_by UUID NOT NULL REFERENCES users(id),
+    published_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (kind, version)
+);
+
+CREATE INDEX idx_legal_documents_published ON legal_documents (kind, published_at DESC);
+
+CREATE TABLE legal_acceptances (
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind        TEXT NOT NULL,
+    version     TEXT NOT NULL,
+    accepted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, kind, version)
+);
@@ -0,0 +1,4 @@
+-- 000029_username_skeleton.down.sql
+
+DROP INDEX IF EXISTS idx_users_username_skeleton;
+ALTER TABLE users DROP COLUMN IF EXISTS username_skeleton;
@@ -0,0 +1,10 @@
+-- 000029_username_skeleton.up.sql
+-- username.Skeleton of each username, so a new name can be checked against
+-- every existing one it reads the same as ("bob" against "bоb" with a
+-- Cyrillic "о").
+-- Computed in Go: Create and Anonymize set it, and Migrate fills it in for
+-- existing users.
+
+ALTER TABLE users ADD COLUMN username_skeleton TEXT;
+
+CREATE INDEX idx_users_username_skeleton ON users (username_skeleton);
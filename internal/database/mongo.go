@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"ofenes/internal/username"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ConnectMongo connects to MongoDB and returns the named database.
+// It validates the connection before returning. Disconnect with
+// db.Client().Disconnect.
+func ConnectMongo(ctx context.Context, mongoURL, dbName string) (*mongo.Database, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(mongoURL))
+	if err != nil {
+		return nil, fmt.Errorf("database: invalid Mongo URL: %w", err)
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("database: failed to ping Mongo: %w", err)
+	}
+
+	log.Printf("database: connected to Mongo (db=%s)", dbName)
+	return client.Database(dbName), nil
+}
+
+// mongoIndexes lists the indexes each collection needs. They mirror the
+// PostgreSQL constraints and indexes in migrations/.
+var mongoIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Case-insensitive uniqueness and lookups (repository.MongoUserRepo.GetByUsername).
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true).SetName("username_ci").
+			SetCollation(&options.Collation{Locale: "en", Strength: 2})},
+		{Keys: bson.D{{Key: "username_skeleton", Value: 1}}}, // repository.MongoUserRepo.GetBySkeleton
+		{Keys: bson.D{{Key: "deleted_at", Value: -1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "role", Value: 1}, {Key: "created_at", Value: 1}}},
+	},
+	"rooms": {
+		{Keys: bson.D{{Key: "created_by", Value: 1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+	},
+	"room_members": {
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	},
+	"messages": {
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "sender_id", Value: 1}}},
+	},
+	"media_sessions": {
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "started_at", Value: -1}}},
+	},
+	"media_session_participants": {
+		{Keys: bson.D{{Key: "session_id", Value: 1}, {Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	},
+	"shared_files": {
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"media_files": {
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "transcode.status", Value: 1}, {Key: "transcode.updated_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	},
+	"subtitles": {
+		{Keys: bson.D{{Key: "media_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"library_items": {
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetSparse(true)},
+	},
+	"audit_log": {
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	},
+	"dead_letters": {
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"reports": {
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}}},
+		{Keys: bson.D{{Key: "reporter_id", Value: 1}}},
+	},
+	"allowed_origins": {
+		{Keys: bson.D{{Key: "origin", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"announcements": {
+		{Keys: bson.D{{Key: "ends_at", Value: 1}}},
+	},
+	"invites": {
+		{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	},
+	"legal_documents": {
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "version", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "published_at", Value: -1}}},
+	},
+	"legal_acceptances": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}, {Key: "version", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"announcement_dismissals": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "announcement_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "announcement_id", Value: 1}}},
+	},
+}
+
+// MigrateMongo creates the collections' indexes and sets the username
+// skeleton of users stored before it was. Creating an index that already
+// exists is a no-op, so this is safe to run on every startup.
+func MigrateMongo(ctx context.Context, db *mongo.Database) error {
+	for coll, indexes := range mongoIndexes {
+		if _, err := db.Collection(coll).Indexes().CreateMany(ctx, indexes); err != nil {
+			return fmt.Errorf("database: failed to create indexes on %s: %w", coll, err)
+		}
+	}
+	log.Printf("database: mongo indexes ensured (%d collections)", len(mongoIndexes))
+	return indexMongoSkeletons(ctx, db.Collection("users"))
+}
+
+// indexMongoSkeletons sets username_skeleton on users without one.
+func indexMongoSkeletons(ctx context.Context, users *mongo.Collection) error {
+	cur, err := users.Find(ctx, bson.M{"username_skeleton": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"username": 1}))
+	if err != nil {
+		return fmt.Errorf("database: failed to list usernames to index: %w", err)
+	}
+	var docs []struct {
+		ID       string `bson:"_id"`
+		Username string `bson:"username"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return fmt.Errorf("database: failed to list usernames to index: %w", err)
+	}
+	for _, d := range docs {
+		_, err := users.UpdateByID(ctx, d.ID, bson.M{"$set": bson.M{"username_skeleton": username.Skeleton(d.Username)}})
+		if err != nil {
+			return fmt.Errorf("database: failed to index username %q: %w", d.Username, err)
+		}
+	}
+	if len(docs) > 0 {
+		log.Printf("database: indexed %d username skeletons", len(docs))
+	}
+	return nil
+}
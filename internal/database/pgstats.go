@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"ofenes/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// queryTracer logs slow queries and counts queries by outcome.
+// It implements pgx.QueryTracer.
+type queryTracer struct {
+	slow     time.Duration
+	queries  *metrics.Counter
+	errors   *metrics.Counter
+	slowHits *metrics.Counter
+}
+
+// queryStartKey carries the query text and start time from
+// TraceQueryStart to TraceQueryEnd.
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+func newQueryTracer(slow time.Duration, reg *metrics.Registry) *queryTracer {
+	t := &queryTracer{slow: slow}
+	if reg != nil {
+		t.queries = reg.Counter("db_queries_total", "Queries executed against PostgreSQL.")
+		t.errors = reg.Counter("db_query_errors_total", "Queries that returned an error.")
+		t.slowHits = reg.Counter("db_slow_queries_total", "Queries slower than DATABASE_SLOW_QUERY_MS.")
+	}
+	return t
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	qs, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(qs.start)
+
+	if t.queries != nil {
+		t.queries.Inc()
+		if data.Err != nil {
+			t.errors.Inc()
+		}
+	}
+	if t.slow <= 0 || elapsed < t.slow {
+		return
+	}
+	if t.slowHits != nil {
+		t.slowHits.Inc()
+	}
+	log.Printf("database: slow query (%s): %s", elapsed.Round(time.Millisecond), compactSQL(qs.sql))
+}
+
+// compactSQL collapses whitespace so multi-line queries log on one line.
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// registerPoolMetrics exports pgxpool statistics. Values are read from
+// pool.Stat() on every scrape.
+func registerPoolMetrics(reg *metrics.Registry, pool *pgxpool.Pool) {
+	stat := func(fn func(*pgxpool.Stat) float64) func() float64 {
+		return func() float64 { return fn(pool.Stat()) }
+	}
+
+	reg.GaugeFunc("db_pool_max_conns", "Maximum size of the connection pool.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }))
+	reg.GaugeFunc("db_pool_total_conns", "Connections currently open.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) }))
+	reg.GaugeFunc("db_pool_idle_conns", "Open connections not in use.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }))
+	reg.GaugeFunc("db_pool_acquired_conns", "Connections currently checked out.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }))
+	reg.GaugeFunc("db_pool_constructing_conns", "Connections being established.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.ConstructingConns()) }))
+
+	reg.CounterFunc("db_pool_acquire_total", "Successful connection acquisitions.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }))
+	reg.CounterFunc("db_pool_empty_acquire_total", "Acquisitions that had to wait for a connection.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }))
+	reg.CounterFunc("db_pool_canceled_acquire_total", "Acquisitions canceled by their context.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) }))
+	reg.CounterFunc("db_pool_acquire_wait_seconds_total", "Total time spent waiting for a connection.",
+		stat(func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }))
+	reg.CounterFunc("db_pool_new_conns_total", "Connections opened.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.NewConnsCount()) }))
+	reg.CounterFunc("db_pool_lifetime_destroy_total", "Connections closed for exceeding DATABASE_CONN_MAX_LIFETIME_MS.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxLifetimeDestroyCount()) }))
+	reg.CounterFunc("db_pool_idle_destroy_total", "Connections closed for exceeding DATABASE_CONN_MAX_IDLE_MS.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxIdleDestroyCount()) }))
+}
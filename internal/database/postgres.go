@@ -1,4 +1,5 @@
-// Package database manages PostgreSQL connections and schema migrations.
+// Package database manages storage connections (PostgreSQL, MongoDB, bbolt,
+// Redis) and schema migrations.
 //
 // Migrations are embedded in the binary via go:embed, so no external
 // files are needed at runtime.
@@ -10,7 +11,12 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"ofenes/internal/metrics"
+	"ofenes/internal/username"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -18,15 +24,46 @@ import (
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
+// PoolOptions configures the PostgreSQL connection pool.
+type PoolOptions struct {
+	MaxConns        int           // max open connections
+	MinConns        int           // idle connections kept open
+	MaxConnLifetime time.Duration // recycle connections older than this
+	MaxConnIdleTime time.Duration // close idle connections after this
+
+	// StatementTimeout aborts statements running longer than this
+	// (PostgreSQL statement_timeout). 0 disables.
+	StatementTimeout time.Duration
+
+	// SlowQuery logs queries taking at least this long. 0 disables.
+	SlowQuery time.Duration
+
+	// Metrics receives pool stats (db_pool_*) and query counters. Optional.
+	Metrics *metrics.Registry
+}
+
 // Connect creates a connection pool to PostgreSQL.
 // It validates the connection before returning.
-func Connect(ctx context.Context, databaseURL string, poolSize int) (*pgxpool.Pool, error) {
+func Connect(ctx context.Context, databaseURL string, opts PoolOptions) (*pgxpool.Pool, error) {
 	cfg, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("database: invalid URL: %w", err)
 	}
 
-	cfg.MaxConns = int32(poolSize)
+	cfg.MaxConns = int32(opts.MaxConns)
+	cfg.MinConns = int32(opts.MinConns)
+	if opts.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.StatementTimeout > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+	if opts.SlowQuery > 0 || opts.Metrics != nil {
+		cfg.ConnConfig.Tracer = newQueryTracer(opts.SlowQuery, opts.Metrics)
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
@@ -38,7 +75,12 @@ func Connect(ctx context.Context, databaseURL string, poolSize int) (*pgxpool.Po
 		return nil, fmt.Errorf("database: failed to ping: %w", err)
 	}
 
-	log.Printf("database: connected (pool_size=%d)", poolSize)
+	if opts.Metrics != nil {
+		registerPoolMetrics(opts.Metrics, pool)
+	}
+
+	log.Printf("database: connected (max_conns=%d, min_conns=%d, statement_timeout=%s, slow_query=%s)",
+		cfg.MaxConns, cfg.MinConns, opts.StatementTimeout, opts.SlowQuery)
 	return pool, nil
 }
 
@@ -114,5 +156,37 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
 		log.Printf("database: applied migration %s", version)
 	}
 
+	return indexUsernameSkeletons(ctx, pool)
+}
+
+// indexUsernameSkeletons sets users.username_skeleton where it is missing:
+// for users created before migration 000029, which can't compute it in SQL.
+func indexUsernameSkeletons(ctx context.Context, pool *pgxpool.Pool) error {
+	rows, err := pool.Query(ctx, `SELECT id, username FROM users WHERE username_skeleton IS NULL`)
+	if err != nil {
+		return fmt.Errorf("database: failed to list usernames to index: %w", err)
+	}
+	var ids, names []string
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return fmt.Errorf("database: failed to list usernames to index: %w", err)
+		}
+		ids, names = append(ids, id), append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("database: failed to list usernames to index: %w", err)
+	}
+
+	for i, id := range ids {
+		if _, err := pool.Exec(ctx, `UPDATE users SET username_skeleton = $2 WHERE id = $1`, id, username.Skeleton(names[i])); err != nil {
+			return fmt.Errorf("database: failed to index username %q: %w", names[i], err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("database: indexed %d username skeletons", len(ids))
+	}
 	return nil
 }
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ConnectRedis creates a Redis client from a redis:// or rediss:// URL.
+// It validates the connection before returning.
+func ConnectRedis(ctx context.Context, redisURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("database: invalid Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("database: failed to ping Redis: %w", err)
+	}
+
+	log.Printf("database: connected to Redis (addr=%s, db=%d)", opts.Addr, opts.DB)
+	return client, nil
+}
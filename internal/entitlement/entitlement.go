@@ -0,0 +1,181 @@
+// Package entitlement tells which perks each user is entitled to, for
+// hosts who sell supporter tiers: a paying user may own more rooms, upload
+// more or create larger rooms than the defaults allow, without a fork.
+//
+// A user's entitlements are Features, each with a limit. They come from a
+// Provider: Static, set in ENTITLEMENTS_STATIC, or Webhook, which asks the
+// host's billing system. Entitlements caches the answers per user. Quota
+// resources (quota.Rooms, quota.Library, quota.Storage) are features too:
+// an entitled limit replaces the user's quota.
+//
+// Usage:
+//
+//	static, _ := entitlement.ParseStatic(cfg.EntitlementsStatic)
+//	entitlements := entitlement.New(static, cfg.EntitlementsCacheTTL)
+//	if limit, ok := entitlements.Limit(ctx, userID, entitlement.RoomSize); ok { ... }
+package entitlement
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feature names, besides the quota resources.
+const (
+	RoomSize = "room_size" // the most maxMembers the user may give a room
+)
+
+const (
+	// Everyone is the Static key of the features of users not listed.
+	Everyone = "*"
+
+	// failTTL is how long a user is left without entitlements after their
+	// lookup failed, so a provider that is down isn't asked on every
+	// request.
+	failTTL = 30 * time.Second
+
+	// maxCached bounds the users whose entitlements are cached; expired
+	// entries are dropped once it is reached.
+	maxCached = 10000
+)
+
+// Features maps each feature a user is entitled to to its limit, 0 if it
+// has none. Features not in the map are not granted.
+type Features map[string]int64
+
+// Provider looks up users' entitlements.
+type Provider interface {
+	// Lookup returns the features userID is entitled to.
+	Lookup(ctx context.Context, userID string) (Features, error)
+}
+
+// Static grants the features configured per user ID; users not listed get
+// those of Everyone.
+type Static map[string]Features
+
+// ParseStatic parses entitlements from config, as
+// "user:feature=n,feature,...;user:...", e.g.
+// "*:room_size=50;3f2b...:room_size=500,rooms=100". A feature without a
+// limit is granted with none.
+func ParseStatic(s string) (Static, error) {
+	static := make(Static)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		user, list, ok := strings.Cut(entry, ":")
+		user = strings.TrimSpace(user)
+		if !ok || user == "" {
+			return nil, fmt.Errorf("entitlement: invalid entry %q (want user:feature=n,...)", entry)
+		}
+		if static[user] == nil {
+			static[user] = make(Features)
+		}
+		for _, f := range strings.Split(list, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			name, nStr, hasLimit := strings.Cut(f, "=")
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return nil, fmt.Errorf("entitlement: invalid feature %q", f)
+			}
+			var n int64
+			if hasLimit {
+				var err error
+				n, err = strconv.ParseInt(strings.TrimSpace(nStr), 10, 64)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("entitlement: invalid limit %q", f)
+				}
+			}
+			static[user][name] = n
+		}
+	}
+	return static, nil
+}
+
+// Lookup returns the features configured for userID.
+func (s Static) Lookup(_ context.Context, userID string) (Features, error) {
+	if f, ok := s[userID]; ok {
+		return f, nil
+	}
+	return s[Everyone], nil
+}
+
+// cached is a user's features and when they expire.
+type cached struct {
+	features Features
+	expires  time.Time
+}
+
+// Entitlements answers what users are entitled to through a Provider,
+// caching each answer for a TTL. Safe for concurrent use.
+type Entitlements struct {
+	provider Provider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// New creates Entitlements looking users up through provider and keeping
+// answers for ttl. With a nil provider nobody is entitled to anything.
+func New(provider Provider, ttl time.Duration) *Entitlements {
+	return &Entitlements{provider: provider, ttl: ttl, now: time.Now, cache: make(map[string]cached)}
+}
+
+// Features returns the features userID is entitled to. If the lookup
+// fails, the last answer is kept for now, or the user has none; the
+// failure is logged.
+func (e *Entitlements) Features(ctx context.Context, userID string) Features {
+	if e.provider == nil {
+		return nil
+	}
+	now := e.now()
+	e.mu.Lock()
+	c, ok := e.cache[userID]
+	e.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.features
+	}
+
+	features, err := e.provider.Lookup(ctx, userID)
+	ttl := e.ttl
+	if err != nil {
+		log.Printf("entitlement: lookup of %s failed: %v", userID, err)
+		features, ttl = c.features, failTTL
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.cache) >= maxCached {
+		for id, c := range e.cache {
+			if !now.Before(c.expires) {
+				delete(e.cache, id)
+			}
+		}
+	}
+	e.cache[userID] = cached{features: features, expires: now.Add(ttl)}
+	return features
+}
+
+// Allowed reports whether userID is entitled to feature.
+func (e *Entitlements) Allowed(ctx context.Context, userID, feature string) bool {
+	_, ok := e.Features(ctx, userID)[feature]
+	return ok
+}
+
+// Limit returns userID's limit of feature, 0 if it has none, and whether
+// they are entitled to it at all.
+func (e *Entitlements) Limit(ctx context.Context, userID, feature string) (int64, bool) {
+	n, ok := e.Features(ctx, userID)[feature]
+	return n, ok
+}
@@ -0,0 +1,83 @@
+package entitlement
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookTimeout bounds one lookup.
+const webhookTimeout = 5 * time.Second
+
+// Webhook asks the host's billing system for a user's entitlements: it
+// POSTs {"userId": "..."} to a URL, signed like the now_playing webhooks
+// (X-Ofenes-Signature: sha256=<hex HMAC of the body>) if it has a secret,
+// and expects 200 with {"features": {"room_size": 500, "rooms": 100}}, the
+// limits as in Features. Any other status is a failed lookup.
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// webhookResponse is the body a Webhook expects.
+type webhookResponse struct {
+	Features Features `json:"features"`
+}
+
+// NewWebhook creates a Webhook posting to rawURL, signing with secret
+// unless it is empty.
+func NewWebhook(rawURL, secret string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("entitlement: %q is not an http(s) URL", rawURL)
+	}
+	w := &Webhook{url: rawURL, client: &http.Client{Timeout: webhookTimeout}}
+	if secret != "" {
+		w.secret = []byte(secret)
+	}
+	return w, nil
+}
+
+// Lookup asks the webhook for userID's features.
+func (w *Webhook) Lookup(ctx context.Context, userID string) (Features, error) {
+	body, _ := json.Marshal(map[string]string{"userId": userID})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != nil {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set("X-Ofenes-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var out webhookResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	for name, n := range out.Features {
+		if n < 0 {
+			return nil, fmt.Errorf("negative limit of %s", name)
+		}
+	}
+	return out.Features, nil
+}
@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// Overview handles GET /api/admin/overview (admin only).
+// ?days=N selects how many days of history to return (default 30, capped
+// at STATS_RETENTION_DAYS).
+func (h *Handler) Overview(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			h.fail(w, r, http.StatusBadRequest, "invalid_days")
+			return
+		}
+		days = n
+	}
+
+	response.JSON(w, http.StatusOK, h.app.Stats.Overview(days, h.app.Hub.ConnectionCount()))
+}
+
+// ListConnections handles GET /api/admin/connections (admin only).
+// Returns this instance's open WebSocket connections with their smoothed
+// round-trip times, sorted by room and username. ?room= narrows it to one
+// room.
+func (h *Handler) ListConnections(w http.ResponseWriter, r *http.Request) {
+	conns, err := h.app.Hub.Connections(r.Context(), r.URL.Query().Get("room"))
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_connections")
+		return
+	}
+	if conns == nil {
+		conns = []models.Connection{}
+	}
+	response.JSON(w, http.StatusOK, conns)
+}
+
+// ListUsers handles GET /api/admin/users (admin only).
+//
+// Query parameters (all optional):
+//
+//	role=member            exact role
+//	created_after=RFC3339  created strictly after this instant
+//	q=ali                  username prefix
+//	include_deleted=true   also return soft-deleted users
+//	sort=created_at|username, order=asc|desc, limit, offset
+//
+// The response carries per-role counts for the other filters, so a client
+// can show role facets next to the list. With ?format=ndjson (or Accept:
+// application/x-ndjson) every match from offset on is streamed instead,
+// one user per line, ignoring limit and without the counts.
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := parsePagination(r)
+	filter := repository.UserFilter{
+		Role:           q.Get("role"),
+		UsernamePrefix: q.Get("q"),
+		Limit:          limit,
+		Offset:         offset,
+	}
+
+	if v := q.Get("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.fail(w, r, http.StatusBadRequest, "invalid_created_after")
+			return
+		}
+		filter.CreatedAfter = t
+	}
+	if v := q.Get("include_deleted"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			h.fail(w, r, http.StatusBadRequest, "invalid_include_deleted")
+			return
+		}
+		filter.IncludeDeleted = b
+	}
+	switch q.Get("sort") {
+	case "", repository.UserSortCreatedAt:
+	case repository.UserSortUsername:
+		filter.Sort = repository.UserSortUsername
+	default:
+		h.fail(w, r, http.StatusBadRequest, "invalid_sort")
+		return
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		h.fail(w, r, http.StatusBadRequest, "invalid_order")
+		return
+	}
+
+	ctx := r.Context()
+	if response.WantsNDJSON(r) {
+		streamAll(h, w, r, "failed_to_list_users", offsetPages(offset, func(limit, offset int) ([]*models.User, error) {
+			filter.Limit, filter.Offset = limit, offset
+			return h.app.UserRepo.List(ctx, filter)
+		}))
+		return
+	}
+
+	users, err := h.app.UserRepo.List(ctx, filter)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_users")
+		return
+	}
+	counts, err := h.app.UserRepo.CountByRole(ctx, filter)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_count_users")
+		return
+	}
+	if users == nil {
+		users = []*models.User{}
+	}
+
+	response.Paginated(w, models.UserListResponse{Users: users, RoleCounts: counts}, response.Page(limit, offset, len(users)))
+}
+
+// DeleteUser handles DELETE /api/admin/users/{id} (admin only).
+//
+// In soft mode the user can no longer log in or be looked up, but the
+// account can be brought back with RestoreUser. In anonymize mode their
+// personal data is erased for good: the username is replaced by an opaque
+// pseudonym, also on stored messages so room history stays intact, and
+// the profile, preferences and password are cleared. Audit entries keep
+// the user ID, which now resolves to the pseudonym. Soft-deleted users can
+// be anonymized later. Either way the user is locked out at once: their
+// sessions end, their connections close and their tokens stop working.
+//
+// The mode is ACCOUNT_DELETION_MODE unless ?mode=soft|anonymize is given.
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+	actorID := middleware.GetUserID(ctx)
+	if userID == actorID {
+		h.fail(w, r, http.StatusBadRequest, "cannot_delete_your_own_account")
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = h.app.Config.AccountDeletionMode
+	}
+	if mode != models.DeletionSoft && mode != models.DeletionAnonymize {
+		h.fail(w, r, http.StatusBadRequest, "invalid_delete_mode")
+		return
+	}
+
+	if mode == models.DeletionSoft {
+		err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+			if err := tx.Users.Delete(ctx, userID); err != nil {
+				return err
+			}
+			return tx.Audit.Create(ctx, h.userAuditEntry(actorID, models.AuditUserDelete, userID, nil))
+		})
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				h.fail(w, r, http.StatusNotFound, "user_not_found")
+				return
+			}
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_user")
+			return
+		}
+		h.lockOut(ctx, userID)
+		response.NoContent(w)
+		return
+	}
+
+	pseudonym, err := newPseudonym()
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_anonymize_user")
+		return
+	}
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Users.Anonymize(ctx, userID, pseudonym); err != nil {
+			return err
+		}
+		renamed, err := tx.Messages.RenameSender(ctx, userID, pseudonym)
+		if err != nil {
+			return err
+		}
+		details := map[string]int{"messages": renamed}
+		return tx.Audit.Create(ctx, h.userAuditEntry(actorID, models.AuditUserAnonymize, userID, details))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "user_not_found_or_anonymized")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_anonymize_user")
+		return
+	}
+
+	h.lockOut(ctx, userID)
+	response.JSON(w, http.StatusOK, models.AnonymizeResponse{Username: pseudonym})
+}
+
+// RestoreUser handles POST /api/admin/users/{id}/restore (admin only).
+// Anonymized users cannot be restored.
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Users.Restore(ctx, userID); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.userAuditEntry(middleware.GetUserID(ctx), models.AuditUserRestore, userID, nil))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "deleted_user_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_restore_user")
+		return
+	}
+
+	user, err := h.app.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_load_user")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, user)
+}
+
+// SetShadowBan handles PUT /api/admin/users/{id}/shadow-ban (admin only).
+//
+// A shadow-banned user's chat messages are echoed back to them, so they
+// look sent, but nobody else receives them. The user is not told: the
+// flag is hidden from their own view of their account.
+func (h *Handler) SetShadowBan(w http.ResponseWriter, r *http.Request) {
+	var req models.ShadowBanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	ctx := r.Context()
+	userID := r.PathValue("id")
+	actorID := middleware.GetUserID(ctx)
+	if userID == actorID {
+		h.fail(w, r, http.StatusBadRequest, "cannot_shadow_ban_yourself")
+		return
+	}
+
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Users.SetShadowBanned(ctx, userID, req.ShadowBanned); err != nil {
+			return err
+		}
+		details := map[string]bool{"shadowBanned": req.ShadowBanned}
+		return tx.Audit.Create(ctx, h.userAuditEntry(actorID, models.AuditUserShadowBan, userID, details))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "user_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_shadow_ban")
+		return
+	}
+	h.app.Hub.SetShadowBanned(userID, req.ShadowBanned)
+
+	user, err := h.app.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_load_user")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, user)
+}
+
+// GetUserAdmin handles GET /api/admin/users/{id} (admin only).
+// Unlike the regular lookups it also returns soft-deleted users.
+func (h *Handler) GetUserAdmin(w http.ResponseWriter, r *http.Request) {
+	user, err := h.app.UserRepo.GetByIDWithDeleted(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "user_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_user")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, user)
+}
+
+// ListDeletedUsers handles GET /api/admin/users/deleted (admin only).
+// Returns soft-deleted users, most recently deleted first. Supports NDJSON
+// streaming like ListUsers.
+func (h *Handler) ListDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	if response.WantsNDJSON(r) {
+		streamAll(h, w, r, "failed_to_list_deleted_users", offsetPages(offset, func(limit, offset int) ([]*models.User, error) {
+			return h.app.UserRepo.ListDeleted(r.Context(), limit, offset)
+		}))
+		return
+	}
+
+	users, err := h.app.UserRepo.ListDeleted(r.Context(), limit, offset)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_deleted_users")
+		return
+	}
+	if users == nil {
+		users = []*models.User{}
+	}
+
+	response.Paginated(w, users, response.Page(limit, offset, len(users)))
+}
+
+// ListAuditLog handles GET /api/admin/audit (admin only).
+// Returns audit entries newest first. Query parameters: action, actor
+// (user ID), target (target ID), limit, offset. Supports NDJSON streaming
+// like ListUsers.
+func (h *Handler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := parsePagination(r)
+	filter := repository.AuditFilter{
+		Action:   q.Get("action"),
+		ActorID:  q.Get("actor"),
+		TargetID: q.Get("target"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if filter.ActorID != "" {
+		if _, err := uuid.Parse(filter.ActorID); err != nil {
+			h.fail(w, r, http.StatusBadRequest, "invalid_actor")
+			return
+		}
+	}
+
+	if response.WantsNDJSON(r) {
+		streamAll(h, w, r, "failed_to_list_audit_log", offsetPages(offset, func(limit, offset int) ([]*models.AuditEntry, error) {
+			filter.Limit, filter.Offset = limit, offset
+			return h.app.AuditRepo.List(r.Context(), filter)
+		}))
+		return
+	}
+
+	entries, err := h.app.AuditRepo.List(r.Context(), filter)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_audit_log")
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+
+	response.Paginated(w, entries, response.Page(limit, offset, len(entries)))
+}
+
+// userAuditEntry builds the audit entry for an admin action on a user.
+// details, if not nil, is stored as JSON.
+func (h *Handler) userAuditEntry(actorID, action, userID string, details any) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   userID,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	if details != nil {
+		entry.Details, _ = json.Marshal(details)
+	}
+	return entry
+}
+
+// newPseudonym returns a random username for an anonymized user. It
+// carries no information about the original account.
+func newPseudonym() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "deleted-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/origin"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+// ListAllowedOrigins handles GET /api/admin/origins (admin only).
+//
+// Returns the origins added at runtime, oldest first. CORS_ORIGINS is
+// not included.
+func (h *Handler) ListAllowedOrigins(w http.ResponseWriter, r *http.Request) {
+	origins, err := h.app.OriginRepo.List(r.Context())
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_origins")
+		return
+	}
+	if origins == nil {
+		origins = []*models.AllowedOrigin{}
+	}
+	response.JSON(w, http.StatusOK, origins)
+}
+
+// AddAllowedOrigin handles POST /api/admin/origins (admin only).
+// The origin is allowed for CORS and WebSocket upgrades as soon as the
+// request returns; other instances pick it up on their next reload
+// (ORIGIN_RELOAD_INTERVAL_MS).
+func (h *Handler) AddAllowedOrigin(w http.ResponseWriter, r *http.Request) {
+	var req models.AllowedOriginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	pattern, ok := origin.Canonical(req.Origin)
+	if !ok {
+		h.fail(w, r, http.StatusBadRequest, "invalid_origin")
+		return
+	}
+
+	ctx := r.Context()
+	actorID := middleware.GetUserID(ctx)
+	allowed := &models.AllowedOrigin{
+		ID:        h.app.IDs.New(),
+		Origin:    pattern,
+		CreatedBy: actorID,
+		CreatedAt: h.app.Clock.Now(),
+	}
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.AllowedOrigins.Create(ctx, allowed); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.originAuditEntry(actorID, models.AuditOriginAdd, allowed))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.fail(w, r, http.StatusConflict, "origin_already_allowed")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_add_origin")
+		return
+	}
+	h.reloadOrigins(r)
+
+	response.Created(w, "/api/admin/origins/"+allowed.ID, allowed)
+}
+
+// RemoveAllowedOrigin handles DELETE /api/admin/origins/{id} (admin only).
+func (h *Handler) RemoveAllowedOrigin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	allowed, err := h.app.OriginRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "origin_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_origin")
+		return
+	}
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.AllowedOrigins.Delete(ctx, allowed.ID); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.originAuditEntry(actorID, models.AuditOriginRemove, allowed))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "origin_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_remove_origin")
+		return
+	}
+	h.reloadOrigins(r)
+
+	response.NoContent(w)
+}
+
+// reloadOrigins swaps the stored origins into the CORS and WebSocket
+// checks after a change. As with word filters, a failure is only logged;
+// the periodic reload retries it.
+func (h *Handler) reloadOrigins(r *http.Request) {
+	if err := h.app.Origins.Reload(r.Context(), h.app.OriginRepo); err != nil {
+		log.Printf("origins: reload failed: %v", err)
+	}
+}
+
+// originAuditEntry builds an audit entry for a change to allowed,
+// recording the origin itself as the details.
+func (h *Handler) originAuditEntry(actorID, action string, allowed *models.AllowedOrigin) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "origin",
+		TargetID:   allowed.ID,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(allowed)
+	return entry
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ofenes/internal/i18n"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+const (
+	// maxAnnouncementTitle caps the length of an announcement's title.
+	maxAnnouncementTitle = 200
+
+	// maxAnnouncementBody caps the length of an announcement's body.
+	maxAnnouncementBody = 4000
+)
+
+// ListAnnouncements handles GET /api/announcements.
+//
+// Returns the announcements in effect that the caller hasn't dismissed,
+// earliest start first: what the "announcements" WebSocket message
+// carries, for clients that are not connected to a room.
+func (h *Handler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	now := h.app.Clock.Now()
+	current, err := h.app.AnnouncementRepo.ListCurrent(ctx, now)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_announcements")
+		return
+	}
+	ids, err := h.app.AnnouncementRepo.ListDismissed(ctx, middleware.GetUserID(ctx))
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_announcements")
+		return
+	}
+	dismissed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		dismissed[id] = true
+	}
+
+	out := make([]*models.Announcement, 0, len(current))
+	for _, a := range current {
+		if !a.StartsAt.After(now) && !dismissed[a.ID] {
+			out = append(out, a)
+		}
+	}
+	response.JSON(w, http.StatusOK, out)
+}
+
+// DismissAnnouncement handles POST /api/announcements/{id}/dismiss.
+// The announcement is hidden from the caller for good, on every
+// connection; dismissing it again is a no-op.
+func (h *Handler) DismissAnnouncement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	userID := middleware.GetUserID(ctx)
+	if err := h.app.AnnouncementRepo.Dismiss(ctx, id, userID, h.app.Clock.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "announcement_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_dismiss_announcement")
+		return
+	}
+	h.app.Hub.DismissAnnouncement(userID, id)
+
+	response.NoContent(w)
+}
+
+// ListAllAnnouncements handles GET /api/admin/announcements
+// (announcements.manage).
+//
+// Returns every announcement, scheduled and ended ones included, latest
+// start first.
+func (h *Handler) ListAllAnnouncements(w http.ResponseWriter, r *http.Request) {
+	announcements, err := h.app.AnnouncementRepo.List(r.Context())
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_announcements")
+		return
+	}
+	if announcements == nil {
+		announcements = []*models.Announcement{}
+	}
+	response.JSON(w, http.StatusOK, announcements)
+}
+
+// CreateAnnouncement handles POST /api/admin/announcements
+// (announcements.manage). Without startsAt the announcement is shown as
+// soon as the request returns; without endsAt, until it is deleted.
+func (h *Handler) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	ctx := r.Context()
+	now := h.app.Clock.Now()
+	actorID := middleware.GetUserID(ctx)
+	a := &models.Announcement{
+		ID:        h.app.IDs.New(),
+		CreatedBy: actorID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if msg := applyAnnouncement(a, req, now); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Announcements.Create(ctx, a); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.announcementAuditEntry(actorID, models.AuditAnnouncementCreate, a))
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_announcement")
+		return
+	}
+	h.reloadAnnouncements(r)
+
+	response.Created(w, "/api/admin/announcements/"+a.ID, a)
+}
+
+// UpdateAnnouncement handles PUT /api/admin/announcements/{id}
+// (announcements.manage). The request replaces the title, body, level and
+// schedule, with the same defaults as CreateAnnouncement; users who
+// dismissed the announcement don't see it again.
+func (h *Handler) UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	ctx := r.Context()
+	a, err := h.app.AnnouncementRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "announcement_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_announcement")
+		return
+	}
+	now := h.app.Clock.Now()
+	if msg := applyAnnouncement(a, req, now); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+	a.UpdatedAt = now
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Announcements.Update(ctx, a); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.announcementAuditEntry(actorID, models.AuditAnnouncementUpdate, a))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "announcement_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_announcement")
+		return
+	}
+	h.reloadAnnouncements(r)
+
+	response.JSON(w, http.StatusOK, a)
+}
+
+// DeleteAnnouncement handles DELETE /api/admin/announcements/{id}
+// (announcements.manage). Connected clients stop showing it at once.
+func (h *Handler) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	a, err := h.app.AnnouncementRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "announcement_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_announcement")
+		return
+	}
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Announcements.Delete(ctx, a.ID); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.announcementAuditEntry(actorID, models.AuditAnnouncementDelete, a))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "announcement_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_announcement")
+		return
+	}
+	h.reloadAnnouncements(r)
+
+	response.NoContent(w)
+}
+
+// reloadAnnouncements pushes the current announcements to the Hub after a
+// change. The change is already saved, so a failure is only logged: the
+// periodic reload (ANNOUNCEMENT_RELOAD_INTERVAL_MS) retries it.
+func (h *Handler) reloadAnnouncements(r *http.Request) {
+	if err := h.app.Hub.ReloadAnnouncements(r.Context(), h.app.AnnouncementRepo); err != nil {
+		log.Printf("announcements: reload failed: %v", err)
+	}
+}
+
+// applyAnnouncement checks an announcement request and copies it onto a,
+// filling in the defaults as of now. It returns the problem, or a zero
+// Message if the request is valid.
+func applyAnnouncement(a *models.Announcement, req models.AnnouncementRequest, now time.Time) i18n.Message {
+	title := strings.TrimSpace(req.Title)
+	body := strings.TrimSpace(req.Body)
+	if title == "" {
+		return i18n.Msg("announcement_title_required")
+	}
+	if utf8.RuneCountInString(title) > maxAnnouncementTitle {
+		return i18n.Msg("announcement_title_too_long", maxAnnouncementTitle)
+	}
+	if utf8.RuneCountInString(body) > maxAnnouncementBody {
+		return i18n.Msg("announcement_body_too_long", maxAnnouncementBody)
+	}
+
+	level := req.Level
+	switch level {
+	case "":
+		level = models.AnnouncementInfo
+	case models.AnnouncementInfo, models.AnnouncementWarning, models.AnnouncementCritical:
+	default:
+		return i18n.Msg("invalid_announcement_level")
+	}
+
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt.UTC()
+	}
+	var endsAt *time.Time
+	if req.EndsAt != nil {
+		t := req.EndsAt.UTC()
+		if !t.After(startsAt) {
+			return i18n.Msg("invalid_announcement_schedule")
+		}
+		endsAt = &t
+	}
+
+	a.Title, a.Body, a.Level, a.StartsAt, a.EndsAt = title, body, level, startsAt, endsAt
+	return i18n.Message{}
+}
+
+// announcementAuditEntry builds an audit entry for a change to a,
+// recording the announcement itself as the details.
+func (h *Handler) announcementAuditEntry(actorID, action string, a *models.Announcement) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "announcement",
+		TargetID:   a.ID,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(a)
+	return entry
+}
@@ -1,124 +1,406 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
-	"time"
+	"strings"
 
 	"ofenes/internal/auth"
+	"ofenes/internal/i18n"
+	"ofenes/internal/ldap"
+	"ofenes/internal/middleware"
 	"ofenes/internal/models"
 	"ofenes/internal/repository"
+	"ofenes/internal/username"
+	"ofenes/pkg/apperr"
 	"ofenes/pkg/response"
-
-	"github.com/google/uuid"
 )
 
 // Register handles POST /api/register.
 //
-// Request:  { "username": "...", "password": "..." }
-// Response: { "token": "...", "user": { ... } }
+// Self-registration is off (403) when logins go through LDAP; directory
+// users get an account on their first login instead. REGISTRATION_MODE
+// closes it too, or, set to invite-only, requires an invite code from
+// /api/admin/invites, which the registration uses up once.
+//
+// With CHALLENGE_PROVIDER set, the attempt may first need the answer to
+// GET /api/challenge in "challengeResponse" (see checkChallenge).
+//
+// The username is stored NFKC-normalized and must meet the username
+// policy (see checkNewUsername); it is unique without case.
+//
+// Request:  { "username": "...", "password": "...", "inviteCode": "..." }
+// Response: { "token": "...", "user": { ... } } (no token in cookie mode)
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	if h.app.Directory != nil {
+		h.fail(w, r, http.StatusForbidden, "registration_disabled")
+		return
+	}
+	if h.app.Config.RegistrationMode == "closed" {
+		h.fail(w, r, http.StatusForbidden, "registration_closed")
+		return
+	}
+
 	var req models.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !h.checkChallenge(w, r, req.ChallengeResponse) {
 		return
 	}
 
 	// --- Validation ---
+	req.Username = username.Normalize(req.Username)
 	if req.Username == "" {
-		response.Error(w, http.StatusBadRequest, "username is required")
+		h.fail(w, r, http.StatusBadRequest, "username_required")
 		return
 	}
 	if len(req.Password) < 6 {
-		response.Error(w, http.StatusBadRequest, "password must be at least 6 characters")
+		h.fail(w, r, http.StatusBadRequest, "password_too_short")
+		return
+	}
+	inviteOnly := h.app.Config.RegistrationMode == "invite-only"
+	inviteCode := strings.ToUpper(strings.TrimSpace(req.InviteCode))
+	if inviteOnly && inviteCode == "" {
+		h.fail(w, r, http.StatusForbidden, "invite_required")
+		return
+	}
+	msg, err := h.checkNewUsername(r.Context(), req.Username)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_check_usernames")
+		return
+	}
+	if msg.Code == "username_confusable" {
+		h.fail(w, r, http.StatusConflict, msg.Code)
+		return
+	}
+	if msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
 		return
 	}
 
 	// --- Hash password ---
-	hash, err := auth.HashPassword(req.Password)
+	hash, err := h.app.Passwords.Hash(r.Context(), req.Password)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to process password")
+		h.failPassword(w, r, err, http.StatusInternalServerError, "failed_to_process_password")
 		return
 	}
 
 	// --- Create user ---
-	now := time.Now()
+	now := h.app.Clock.Now()
 	user := &models.User{
-		ID:           uuid.New().String(),
+		ID:           h.app.IDs.New(),
 		Username:     req.Username,
 		PasswordHash: hash,
 		Role:         models.RoleMember, // Default role
+		TrustLevel:   models.TrustNew,
 		Status:       models.StatusOffline,
 		Preferences:  json.RawMessage(`{}`), // Default empty JSON object
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
-	if err := h.app.UserRepo.Create(r.Context(), user); err != nil {
-		if errors.Is(err, repository.ErrAlreadyExists) {
-			response.Error(w, http.StatusConflict, "username already taken")
-			return
+	// Outside PostgreSQL the invite stays used up if the user can't be
+	// created after all, e.g. because someone took the name meanwhile.
+	ctx := r.Context()
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if inviteOnly {
+			if _, err := tx.Invites.Redeem(ctx, inviteCode, now); err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					return errInvalidInvite
+				}
+				return err
+			}
+		}
+		return tx.Users.Create(ctx, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidInvite):
+			h.fail(w, r, http.StatusForbidden, "invalid_invite")
+		case errors.Is(err, repository.ErrAlreadyExists):
+			h.fail(w, r, http.StatusConflict, "username_taken")
+		default:
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_create_user")
 		}
-		response.Error(w, http.StatusInternalServerError, "failed to create user")
 		return
 	}
 
+	h.app.Stats.UserRegistered()
+	h.app.Stats.UserActive(user.ID)
+
 	// --- Generate JWT ---
-	token, err := auth.GenerateToken(
-		user.ID, user.Username, user.Role,
-		h.app.Config.JWTSecret,
-		h.app.Config.JWTExpiry,
-	)
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, user.TrustLevel, h.app.Config.Token())
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to generate token")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_generate_token")
 		return
 	}
 
-	response.JSON(w, http.StatusCreated, models.AuthResponse{
-		Token: token,
+	response.Created(w, "", models.AuthResponse{
+		Token: h.issueSession(w, token),
 		User:  *user,
 	})
 }
 
+// failPassword answers a request whose password could not be hashed or
+// checked with status and code, unless the password pool turned it away
+// (auth.ErrOverloaded): that is 503 server_busy, to retry in a second.
+func (h *Handler) failPassword(w http.ResponseWriter, r *http.Request, err error, status int, code string) {
+	if errors.Is(err, auth.ErrOverloaded) {
+		w.Header().Set("Retry-After", "1")
+		status, code = http.StatusServiceUnavailable, "server_busy"
+	}
+	h.fail(w, r, status, code)
+}
+
+// checkNewUsername checks a normalized username against the username
+// policy (USERNAME_*) and against existing users it reads the same as.
+// It returns the problem, or a zero Message if name may be registered.
+func (h *Handler) checkNewUsername(ctx context.Context, name string) (i18n.Message, error) {
+	if err := h.app.Config.Usernames().Check(name); err != nil {
+		code, args := apperr.CodeOf(err)
+		return i18n.Msg(code, args...), nil
+	}
+
+	// A name must not pass for an existing user's that reads the same,
+	// whichever of the two is spelled with look-alike letters. The user
+	// with this very name is left to Create to report as taken.
+	other, err := h.app.UserRepo.GetBySkeleton(ctx, username.Skeleton(name))
+	if err == nil && username.Key(other.Username) != username.Key(name) {
+		return i18n.Msg("username_confusable"), nil
+	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return i18n.Message{}, err
+	}
+	return i18n.Message{}, nil
+}
+
 // Login handles POST /api/login.
 //
-// Request:  { "username": "...", "password": "..." }
-// Response: { "token": "...", "user": { ... } }
+// With "rememberMe", the response also carries a refresh token for a
+// long-lived session bound to this device (see RefreshSession).
+//
+// With LDAP configured, the password is checked against the directory and
+// the local account is created on first login; its role follows the
+// user's directory groups on every login. Usernames not in the directory
+// fall back to local accounts (e.g. a bootstrap admin).
+//
+// As with Register, the attempt may need a "challengeResponse" first.
+//
+// Request:  { "username": "...", "password": "...", "rememberMe": true }
+// Response: { "token": "...", "refreshToken": "...", "user": { ... } } (no tokens in cookie mode)
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !h.checkChallenge(w, r, req.ChallengeResponse) {
 		return
 	}
 
-	// --- Find user ---
-	user, err := h.app.UserRepo.GetByUsername(r.Context(), req.Username)
+	// --- Check the directory ---
+	var user *models.User
+	if h.app.Directory != nil {
+		entry, err := h.app.Directory.Authenticate(r.Context(), req.Username, req.Password)
+		switch {
+		case errors.Is(err, ldap.ErrInvalidCredentials):
+			h.fail(w, r, http.StatusUnauthorized, "invalid_username_or_password")
+			return
+		case errors.Is(err, ldap.ErrUserNotFound):
+			// Not a directory user: try local accounts.
+		case err != nil:
+			log.Printf("login: %v", err)
+			h.fail(w, r, http.StatusServiceUnavailable, "directory_unavailable")
+			return
+		default:
+			if user, err = h.directoryUser(r.Context(), req.Username, entry); err != nil {
+				h.failPassword(w, r, err, http.StatusInternalServerError, "failed_to_provision_user")
+				return
+			}
+		}
+	}
+
+	if user == nil {
+		// --- Find user ---
+		var err error
+		user, err = h.app.UserRepo.GetByUsername(r.Context(), req.Username)
+		if err != nil {
+			// Don't leak whether the username exists or not
+			h.fail(w, r, http.StatusUnauthorized, "invalid_username_or_password")
+			return
+		}
+
+		// --- Check password ---
+		if err := h.app.Passwords.Check(r.Context(), user.PasswordHash, req.Password); err != nil {
+			// A busy pool or a request that gave up (see abandoned) is
+			// never answered as a wrong password.
+			h.failPassword(w, r, err, http.StatusUnauthorized, "invalid_username_or_password")
+			return
+		}
+	}
+
+	h.app.Stats.UserActive(user.ID)
+
+	// --- Generate JWT ---
+	token, claims, err := auth.IssueToken(user.ID, user.Username, user.Role, user.TrustLevel, h.app.Config.Token())
 	if err != nil {
-		// Don't leak whether the username exists or not
-		response.Error(w, http.StatusUnauthorized, "invalid username or password")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_generate_token")
 		return
 	}
 
-	// --- Check password ---
-	if err := auth.CheckPassword(user.PasswordHash, req.Password); err != nil {
-		response.Error(w, http.StatusUnauthorized, "invalid username or password")
+	resp := models.AuthResponse{
+		Token: h.issueSession(w, token),
+		User:  *selfView(user),
+	}
+	if req.RememberMe {
+		refresh, err := h.startSession(r, user.ID, claims.ID)
+		if err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_create_session")
+			return
+		}
+		resp.RefreshToken = h.issueRefresh(w, refresh)
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// RefreshToken handles POST /api/token/refresh.
+//
+// Issues a new token for the current user with their role and trust level
+// as stored now, so promotions and role changes take effect without
+// logging in again. Deleted users get 401.
+//
+// Response: { "token": "...", "user": { ... } } (no token in cookie mode)
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	user, err := h.app.UserRepo.GetByID(r.Context(), middleware.GetUserID(r.Context()))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusUnauthorized, "account_gone")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_user")
 		return
 	}
 
-	// --- Generate JWT ---
-	token, err := auth.GenerateToken(
-		user.ID, user.Username, user.Role,
-		h.app.Config.JWTSecret,
-		h.app.Config.JWTExpiry,
-	)
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, user.TrustLevel, h.app.Config.Token())
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to generate token")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_generate_token")
 		return
 	}
 
 	response.JSON(w, http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token: h.issueSession(w, token),
+		User:  *selfView(user),
 	})
 }
+
+// directoryUser returns the local account of a user the directory has
+// just authenticated, creating it on first login and updating its role to
+// match the user's directory groups.
+func (h *Handler) directoryUser(ctx context.Context, username string, entry *ldap.User) (*models.User, error) {
+	user, err := h.app.UserRepo.GetByUsername(ctx, username)
+	if errors.Is(err, repository.ErrNotFound) {
+		// The password stays in the directory; the local one is random
+		// and never handed out.
+		var password, hash string
+		if password, err = auth.GeneratePassword(); err != nil {
+			return nil, err
+		}
+		if hash, err = h.app.Passwords.Hash(ctx, password); err != nil {
+			return nil, err
+		}
+		now := h.app.Clock.Now()
+		user = &models.User{
+			ID:           h.app.IDs.New(),
+			Username:     username,
+			PasswordHash: hash,
+			Role:         entry.Role,
+			TrustLevel:   models.TrustNew,
+			Status:       models.StatusOffline,
+			Preferences:  json.RawMessage(`{}`),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if entry.DisplayName != "" {
+			user.DisplayName = &entry.DisplayName
+		}
+		err = h.app.UserRepo.Create(ctx, user)
+		if err == nil {
+			h.app.Stats.UserRegistered()
+			return user, nil
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			// A concurrent first login created it; use that one.
+			user, err = h.app.UserRepo.GetByUsername(ctx, username)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role != entry.Role {
+		if err := h.app.UserRepo.SetRole(ctx, user.ID, entry.Role); err != nil {
+			return nil, err
+		}
+		user.Role = entry.Role
+	}
+	return user, nil
+}
+
+// Logout handles POST /api/logout.
+//
+// Revokes the token the request carries, if valid, clears the session
+// cookie (cookie mode) and ends the "remember me" session in the refresh
+// cookie, if any. Clients sending bearer tokens end their remembered
+// session with DELETE /api/me/sessions/{id}.
+//
+// Response: 204 No Content
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if token := middleware.RequestToken(r); token != "" {
+		if claims, err := auth.ValidateToken(token, h.app.Config.Token()); err == nil && claims.ExpiresAt != nil {
+			if err := h.app.Revocations.Revoke(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+				h.fail(w, r, http.StatusInternalServerError, "failed_to_revoke_token")
+				return
+			}
+		}
+	}
+	if c, err := r.Cookie(middleware.RefreshCookieName); err == nil {
+		if id, _, ok := auth.ParseRefreshToken(c.Value); ok {
+			if err := h.app.Ephemeral.Sessions.Delete(r.Context(), id); err != nil {
+				h.fail(w, r, http.StatusInternalServerError, "failed_to_revoke_session")
+				return
+			}
+		}
+		http.SetCookie(w, h.cookie(middleware.RefreshCookieName, "", -1))
+	}
+	http.SetCookie(w, h.cookie(middleware.AuthCookieName, "", -1))
+	response.NoContent(w)
+}
+
+// issueSession returns the token to put in an auth response. In cookie
+// mode (AUTH_COOKIE) it sets the session cookie instead and returns "", so
+// the token never reaches scripts.
+func (h *Handler) issueSession(w http.ResponseWriter, token string) string {
+	if !h.app.Config.AuthCookie {
+		return token
+	}
+	http.SetCookie(w, h.cookie(middleware.AuthCookieName, token, int(h.app.Config.JWTExpiry.Seconds())))
+	return ""
+}
+
+// cookie returns an HttpOnly auth cookie; maxAge < 0 deletes it.
+func (h *Handler) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.app.Config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
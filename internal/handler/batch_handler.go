@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ofenes/internal/i18n"
+	"ofenes/internal/models"
+	"ofenes/pkg/response"
+)
+
+const (
+	maxBatchRequests = 20
+	maxBatchBytes    = 1 << 20 // request body limit for batches
+)
+
+// batchHeaders are the request headers passed on to sub-requests. Cookie
+// carries the session in cookie mode; the batch itself passed the CSRF check.
+var batchHeaders = []string{"Authorization", "Cookie", "Accept-Language"}
+
+// Batch returns the handler for POST /api/batch. routes is the router's
+// mux, which the sub-requests are dispatched to.
+//
+// The body is a JSON array of models.BatchRequest. The sub-requests run
+// one after another, in order, with the caller's Authorization, cookies and
+// language headers, through the same routes, auth and role checks as
+// direct requests. The response holds one models.BatchResult per
+// sub-request; a failing sub-request does not stop the others. Only /api/
+// paths are accepted, and batches cannot be nested.
+func (h *Handler) Batch(routes http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBatchBytes)
+		var reqs []models.BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+			return
+		}
+		if len(reqs) == 0 || len(reqs) > maxBatchRequests {
+			h.fail(w, r, http.StatusBadRequest, "batch_size", maxBatchRequests)
+			return
+		}
+		for i, req := range reqs {
+			if msg := validateBatchRequest(i+1, req); msg.Code != "" {
+				h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+				return
+			}
+		}
+
+		requestID := w.Header().Get(response.RequestIDHeader)
+		results := make([]models.BatchResult, len(reqs))
+		for i, req := range reqs {
+			results[i] = h.runBatchRequest(routes, r, req, fmt.Sprintf("%s.%d", requestID, i+1))
+		}
+		response.JSON(w, http.StatusOK, results)
+	}
+}
+
+// runBatchRequest serves one sub-request of r. Its response envelope
+// carries requestID.
+func (h *Handler) runBatchRequest(routes http.Handler, r *http.Request, req models.BatchRequest, requestID string) models.BatchResult {
+	var body io.Reader = http.NoBody
+	if len(req.Body) > 0 {
+		body = bytes.NewReader(req.Body)
+	}
+	sub, err := http.NewRequestWithContext(r.Context(), req.Method, req.Path, body)
+	if err != nil {
+		// Method and path were validated; this is not expected.
+		return models.BatchResult{ID: req.ID, Status: http.StatusBadRequest, Body: json.RawMessage("null")}
+	}
+	for _, name := range batchHeaders {
+		if v := r.Header.Get(name); v != "" {
+			sub.Header.Set(name, v)
+		}
+	}
+	if len(req.Body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	sub.RemoteAddr = r.RemoteAddr
+
+	rec := &batchRecorder{header: http.Header{}}
+	rec.header.Set(response.RequestIDHeader, requestID)
+	routes.ServeHTTP(rec, sub)
+
+	result := models.BatchResult{ID: req.ID, Status: rec.status, Location: rec.header.Get("Location")}
+	out := bytes.TrimSpace(rec.body.Bytes())
+	switch {
+	case rec.status == 0:
+		result.Status = http.StatusOK
+		result.Body = json.RawMessage("null")
+	case len(out) == 0:
+		result.Body = json.RawMessage("null")
+	case json.Valid(out):
+		result.Body = out
+	default:
+		// Not a single JSON value (CSV, NDJSON): pass it on as a string.
+		result.Body, _ = json.Marshal(string(out))
+	}
+	return result
+}
+
+// validateBatchRequest checks sub-request n (1-based) and returns the
+// problem, or a zero Message if it is valid.
+func validateBatchRequest(n int, req models.BatchRequest) i18n.Message {
+	switch req.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete:
+	default:
+		return i18n.Msg("invalid_batch_method", n)
+	}
+	u, err := url.ParseRequestURI(req.Path)
+	if err != nil || u.Host != "" || !strings.HasPrefix(u.Path, "/api/") || u.Path == "/api/batch" {
+		return i18n.Msg("invalid_batch_path", n)
+	}
+	return i18n.Message{}
+}
+
+// batchRecorder is the http.ResponseWriter of a sub-request.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *batchRecorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+// maxBroadcastLength bounds a broadcast message in bytes, as the default
+// payload limit of "admin" messages does over the WebSocket.
+const maxBroadcastLength = 4096
+
+// Broadcast handles POST /api/admin/broadcast (broadcast.send).
+//
+// Sends an "admin" message from the caller to everyone connected to this
+// instance, or to one room with "roomId", as if they had sent it over the
+// WebSocket, so operators can warn users about maintenance without opening
+// a client. It is audited before it is sent. Returns how many rooms and
+// connections it reached; rooms nobody is in are not an error.
+func (h *Handler) Broadcast(w http.ResponseWriter, r *http.Request) {
+	var req models.BroadcastRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" || len(req.Message) > maxBroadcastLength {
+		h.fail(w, r, http.StatusBadRequest, "invalid_broadcast", maxBroadcastLength)
+		return
+	}
+
+	ctx := r.Context()
+	actorID := middleware.GetUserID(ctx)
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		return tx.Audit.Create(ctx, h.broadcastAuditEntry(actorID, req))
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_broadcast")
+		return
+	}
+
+	res, err := h.app.Hub.Announce(ctx, req.RoomID, actorID, middleware.GetUsername(ctx), req.Message)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_broadcast")
+		return
+	}
+	response.JSON(w, http.StatusOK, res)
+}
+
+// broadcastAuditEntry builds the audit entry of a broadcast.
+func (h *Handler) broadcastAuditEntry(actorID string, req models.BroadcastRequest) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:        h.app.IDs.New(),
+		ActorID:   actorID,
+		Action:    models.AuditBroadcast,
+		CreatedAt: h.app.Clock.Now(),
+	}
+	if req.RoomID != "" {
+		entry.TargetType, entry.TargetID = "room", req.RoomID
+	}
+	entry.Details, _ = json.Marshal(req)
+	return entry
+}
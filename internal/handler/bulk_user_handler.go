@@ -0,0 +1,429 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"ofenes/internal/auth"
+	"ofenes/internal/authz"
+	"ofenes/internal/i18n"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/internal/username"
+	"ofenes/pkg/response"
+)
+
+const (
+	maxImportBytes = 10 << 20 // request body limit for imports
+	maxImportRows  = 10000
+	exportPageSize = 500
+)
+
+// bulkUserColumns are the CSV columns for import and export, in export order.
+var bulkUserColumns = []string{"username", "password", "password_hash", "role", "display_name", "avatar_url", "bio", "created_at"}
+
+// ImportUsers handles POST /api/admin/users/import (admin only).
+//
+// The body is a JSON array of models.BulkUser (Content-Type
+// application/json) or a CSV file with a header row (text/csv). Each row
+// carries either a plaintext password, a bcrypt password_hash, or neither,
+// in which case a password is generated and returned in the report.
+//
+// Usernames are normalized and checked like at registration.
+// All rows are validated first; if any fail, nothing is imported and the
+// report lists every problem (422). With ?dry_run=true the report is
+// returned without importing anything. Otherwise all users are created in
+// one unit of work.
+func (h *Handler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	rows, err := decodeBulkUsers(r)
+	if err != nil {
+		msg := i18n.Msg("invalid_request_body")
+		errors.As(err, &msg)
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+	if len(rows) > maxImportRows {
+		h.fail(w, r, http.StatusRequestEntityTooLarge, "too_many_import_rows", maxImportRows)
+		return
+	}
+
+	ctx := r.Context()
+	lang := h.language(r)
+	report := models.UserImportReport{DryRun: dryRun, Total: len(rows), Errors: []models.UserImportError{}}
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		row.Username = username.Normalize(row.Username)
+		rows[i] = row
+		msg := validateBulkUser(row, h.app.Authz)
+		if msg.Code == "" && seen[username.Key(row.Username)] {
+			msg = i18n.Msg("duplicate_username_in_file")
+		}
+		if msg.Code == "" {
+			if msg, err = h.checkNewUsername(ctx, row.Username); err != nil {
+				h.fail(w, r, http.StatusInternalServerError, "failed_to_check_usernames")
+				return
+			}
+		}
+		if msg.Code == "" {
+			taken, err := h.usernameTaken(r, row.Username)
+			if err != nil {
+				h.fail(w, r, http.StatusInternalServerError, "failed_to_check_usernames")
+				return
+			}
+			if taken {
+				msg = i18n.Msg("username_taken")
+			}
+		}
+		seen[username.Key(row.Username)] = true
+		if msg.Code != "" {
+			report.Errors = append(report.Errors, models.UserImportError{
+				Row:      i + 1,
+				Username: row.Username,
+				Error:    i18n.T(lang, msg.Code, msg.Args...),
+				Code:     msg.Code,
+			})
+		}
+	}
+
+	if dryRun {
+		response.JSON(w, http.StatusOK, report)
+		return
+	}
+	if len(report.Errors) > 0 {
+		response.JSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	users, generated, err := h.buildBulkUsers(ctx, rows)
+	if err != nil {
+		h.failPassword(w, r, err, http.StatusInternalServerError, "failed_to_process_passwords")
+		return
+	}
+
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		for _, u := range users {
+			if err := tx.Users.Create(ctx, u); err != nil {
+				return fmt.Errorf("%s: %w", u.Username, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			// A user registered between validation and import.
+			h.fail(w, r, http.StatusConflict, "username_taken_during_import", err)
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_import_users")
+		return
+	}
+
+	report.Created = len(users)
+	for range users {
+		h.app.Stats.UserRegistered()
+	}
+	if len(generated) > 0 {
+		report.GeneratedPasswords = generated
+	}
+	response.Created(w, "", report)
+}
+
+// ExportUsers handles GET /api/admin/users/export (admin only).
+//
+// Query parameters: format=json|csv|ndjson (default json), include_deleted=true,
+// include_hashes=true (adds bcrypt password hashes, so the file can be
+// imported elsewhere with passwords intact — handle it like a credential).
+// ndjson is streamed page by page, so use it for large user bases.
+func (h *Handler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	includeDeleted, _ := strconv.ParseBool(q.Get("include_deleted"))
+	includeHashes, _ := strconv.ParseBool(q.Get("include_hashes"))
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "ndjson" {
+		h.fail(w, r, http.StatusBadRequest, "invalid_export_format")
+		return
+	}
+
+	toBulk := func(u *models.User) models.BulkUser {
+		b := models.BulkUser{
+			Username: u.Username, Role: u.Role,
+			DisplayName: u.DisplayName, AvatarURL: u.AvatarURL, Bio: u.Bio,
+			CreatedAt: &u.CreatedAt,
+		}
+		if includeHashes {
+			b.PasswordHash = u.PasswordHash
+		}
+		return b
+	}
+	filter := repository.UserFilter{IncludeDeleted: includeDeleted, Limit: exportPageSize}
+
+	filename := "users-" + h.app.Clock.Now().UTC().Format("20060102-150405") + "." + format
+	if format == "ndjson" {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		streamAll(h, w, r, "failed_to_list_users", offsetPages(0, func(limit, offset int) ([]models.BulkUser, error) {
+			filter.Limit, filter.Offset = limit, offset
+			page, err := h.app.UserRepo.List(r.Context(), filter)
+			out := make([]models.BulkUser, len(page))
+			for i, u := range page {
+				out[i] = toBulk(u)
+			}
+			return out, err
+		}))
+		return
+	}
+
+	var out []models.BulkUser
+	for {
+		page, err := h.app.UserRepo.List(r.Context(), filter)
+		if err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_list_users")
+			return
+		}
+		for _, u := range page {
+			out = append(out, toBulk(u))
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+		filter.Offset += exportPageSize
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "json" {
+		if out == nil {
+			out = []models.BulkUser{}
+		}
+		response.JSON(w, http.StatusOK, out)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write(bulkUserColumns)
+	for _, b := range out {
+		cw.Write([]string{
+			b.Username, "", b.PasswordHash, b.Role,
+			derefString(b.DisplayName), derefString(b.AvatarURL), derefString(b.Bio),
+			b.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+}
+
+// usernameTaken reports whether any user, including soft-deleted ones,
+// holds name. GetByUsername finds active users whatever the case; soft-
+// deleted users keep their username but only match it exactly.
+func (h *Handler) usernameTaken(r *http.Request, name string) (bool, error) {
+	if _, err := h.app.UserRepo.GetByUsername(r.Context(), name); err == nil {
+		return true, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	}
+	users, err := h.app.UserRepo.List(r.Context(), repository.UserFilter{
+		UsernamePrefix: name,
+		IncludeDeleted: true,
+		Sort:           repository.UserSortUsername,
+		Limit:          1,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(users) > 0 && users[0].Username == name, nil
+}
+
+// decodeBulkUsers parses the import body according to its Content-Type.
+// Errors are i18n.Messages.
+func decodeBulkUsers(r *http.Request) ([]models.BulkUser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json", "":
+		var rows []models.BulkUser
+		if err := json.NewDecoder(r.Body).Decode(&rows); err != nil {
+			return nil, i18n.Msg("invalid_import_json")
+		}
+		return rows, nil
+	case "text/csv":
+		return decodeBulkUsersCSV(r.Body)
+	default:
+		return nil, i18n.Msg("unsupported_import_type")
+	}
+}
+
+// decodeBulkUsersCSV reads a CSV file whose header row names the columns.
+// Columns may appear in any order; created_at is ignored.
+func decodeBulkUsersCSV(body io.Reader) ([]models.BulkUser, error) {
+	cr := csv.NewReader(body)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, i18n.Msg("csv_missing_header")
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		if !slices.Contains(bulkUserColumns, name) {
+			return nil, i18n.Msg("csv_unknown_column", name)
+		}
+		col[name] = i
+	}
+	if _, ok := col["username"]; !ok {
+		return nil, i18n.Msg("csv_missing_username")
+	}
+
+	var rows []models.BulkUser
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, i18n.Msg("invalid_csv", err)
+		}
+		field := func(name string) string {
+			if i, ok := col[name]; ok {
+				return rec[i]
+			}
+			return ""
+		}
+		optional := func(name string) *string {
+			if v := field(name); v != "" {
+				return &v
+			}
+			return nil
+		}
+		rows = append(rows, models.BulkUser{
+			Username:     field("username"),
+			Password:     field("password"),
+			PasswordHash: field("password_hash"),
+			Role:         field("role"),
+			DisplayName:  optional("display_name"),
+			AvatarURL:    optional("avatar_url"),
+			Bio:          optional("bio"),
+		})
+	}
+}
+
+// validateBulkUser checks one row on its own and returns the problem, or
+// a zero Message if the row is valid. The role may be any role az knows.
+func validateBulkUser(u models.BulkUser, az *authz.Authorizer) i18n.Message {
+	switch {
+	case u.Username == "":
+		return i18n.Msg("username_required")
+	case u.Password != "" && u.PasswordHash != "":
+		return i18n.Msg("password_and_hash")
+	case u.Password != "" && len(u.Password) < 6:
+		return i18n.Msg("password_too_short")
+	}
+	if u.PasswordHash != "" && auth.ValidateHash(u.PasswordHash) != nil {
+		return i18n.Msg("invalid_password_hash")
+	}
+	if u.Role != "" && !az.Exists(u.Role) {
+		return i18n.Msg("invalid_role")
+	}
+	return i18n.Message{}
+}
+
+// buildBulkUsers turns validated rows into users, hashing plaintext
+// passwords and generating the missing ones. bcrypt is slow by design, so
+// rows are hashed in parallel on the password pool, and the rest skipped
+// once ctx is done. It returns the generated passwords by username.
+func (h *Handler) buildBulkUsers(ctx context.Context, rows []models.BulkUser) ([]*models.User, map[string]string, error) {
+	now := h.app.Clock.Now()
+	users := make([]*models.User, len(rows))
+	generated := make(map[string]string)
+	plaintext := make([]string, len(rows))
+
+	for i, row := range rows {
+		role := row.Role
+		if role == "" {
+			role = models.RoleMember
+		}
+		users[i] = &models.User{
+			ID:           h.app.IDs.New(),
+			Username:     row.Username,
+			PasswordHash: row.PasswordHash,
+			Role:         role,
+			TrustLevel:   models.TrustNew,
+			DisplayName:  row.DisplayName,
+			AvatarURL:    row.AvatarURL,
+			Status:       models.StatusOffline,
+			Bio:          row.Bio,
+			Preferences:  json.RawMessage(`{}`),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		switch {
+		case row.Password != "":
+			plaintext[i] = row.Password
+		case row.PasswordHash == "":
+			pw, err := auth.GeneratePassword()
+			if err != nil {
+				return nil, nil, err
+			}
+			plaintext[i] = pw
+			generated[row.Username] = pw
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		next     = make(chan int)
+	)
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				hash, err := h.app.Passwords.Hash(ctx, plaintext[i])
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				users[i].PasswordHash = hash
+			}
+		}()
+	}
+	for i := range plaintext {
+		if plaintext[i] != "" {
+			next <- i
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return users, generated, nil
+}
+
+// derefString returns *s, or "" for nil.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
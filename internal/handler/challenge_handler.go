@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"ofenes/internal/models"
+	"ofenes/internal/ws"
+	"ofenes/pkg/response"
+)
+
+// GetChallenge handles GET /api/challenge (public).
+//
+// Returns what the client needs to answer the challenge registration and
+// login may require: the provider and site key for a CAPTCHA widget, or a
+// fresh proof-of-work puzzle. "always" says whether every attempt needs an
+// answer; otherwise only a 403 challenge_required asks for one. Without
+// CHALLENGE_PROVIDER, the provider is "off".
+func (h *Handler) GetChallenge(w http.ResponseWriter, r *http.Request) {
+	if h.app.Challenges == nil {
+		response.JSON(w, http.StatusOK, models.Challenge{Provider: "off"})
+		return
+	}
+	challenge, err := h.app.Challenges.Challenge()
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_get_challenge")
+		return
+	}
+	response.JSON(w, http.StatusOK, challenge)
+}
+
+// checkChallenge counts a registration or login attempt and checks its
+// answer if it needs one, writing the error response and returning false
+// if it fails: 403 challenge_required without an answer, 403
+// challenge_failed with a wrong one.
+func (h *Handler) checkChallenge(w http.ResponseWriter, r *http.Request, answer string) bool {
+	if h.app.Challenges == nil {
+		return true
+	}
+	ip := ws.ClientIP(r, h.app.Config.WSTrustProxy)
+	if err := h.app.Challenges.Check(r.Context(), ip, answer); err != nil {
+		h.failErr(w, r, err, "failed_to_check_challenge")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// ListDeadLetters handles GET /api/admin/dead-letters (admin only).
+// Returns WebSocket messages the Hub could not route, newest first: from
+// the database with WS_DEAD_LETTER_PERSIST, otherwise the last
+// WS_DEAD_LETTER_BUFFER kept in memory. Query parameters: user (user ID),
+// room, code, limit, offset.
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := parsePagination(r)
+	filter := repository.DeadLetterFilter{
+		UserID: q.Get("user"),
+		RoomID: q.Get("room"),
+		Code:   q.Get("code"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if filter.UserID != "" {
+		if _, err := uuid.Parse(filter.UserID); err != nil {
+			h.fail(w, r, http.StatusBadRequest, "invalid_user_filter")
+			return
+		}
+	}
+
+	var letters []*models.DeadLetter
+	if h.app.DeadLetterRepo != nil {
+		var err error
+		letters, err = h.app.DeadLetterRepo.List(r.Context(), filter)
+		if err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_list_dead_letters")
+			return
+		}
+	} else {
+		letters = h.app.Hub.DeadLetters(filter)
+	}
+	if letters == nil {
+		letters = []*models.DeadLetter{}
+	}
+
+	response.Paginated(w, letters, response.Page(limit, offset, len(letters)))
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"ofenes/internal/entitlement"
+	"ofenes/internal/middleware"
+	"ofenes/pkg/response"
+)
+
+// MyEntitlements handles GET /api/me/entitlements.
+//
+// Returns the features the caller is entitled to, each with its limit (0
+// for none), so clients can show the perks of their tier: an empty object
+// without ENTITLEMENTS_BACKEND or without perks.
+func (h *Handler) MyEntitlements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	features := h.app.Entitlements.Features(ctx, middleware.GetUserID(ctx))
+	if features == nil {
+		features = entitlement.Features{}
+	}
+	response.JSON(w, http.StatusOK, features)
+}
+
+// roomSizeLimit returns the most maxMembers the caller may give a room,
+// 0 if they are not entitled to a room_size (no cap) or it has no limit.
+func (h *Handler) roomSizeLimit(r *http.Request) int {
+	ctx := r.Context()
+	n, _ := h.app.Entitlements.Limit(ctx, middleware.GetUserID(ctx), entitlement.RoomSize)
+	return int(n)
+}
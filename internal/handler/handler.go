@@ -6,9 +6,15 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"ofenes/internal/app"
+	"ofenes/internal/i18n"
+	"ofenes/internal/middleware"
 	"ofenes/pkg/response"
 )
 
@@ -30,3 +36,58 @@ func (h *Handler) HelloHandler(w http.ResponseWriter, r *http.Request) {
 		"status":  "ok",
 	})
 }
+
+// fail writes an error response for code (see package i18n), translated
+// into the caller's language and formatted with args.
+//
+// Once r's context is done, whatever failed most likely failed because of
+// it, so the request is answered as abandoned instead, whatever status and
+// code were given.
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, status int, code string, args ...any) {
+	if err := r.Context().Err(); err != nil {
+		status, code, args = abandoned(err)
+	}
+	lang := h.language(r)
+	w.Header().Set("Content-Language", lang)
+	response.ErrorCode(w, status, code, i18n.T(lang, code, args...))
+}
+
+// failErr writes the error response for err: its apperr kind picks the
+// status and its code the message (see response.StatusOf and CodeOf).
+// Internal errors without a code are answered with internalCode, e.g.
+// "failed_to_update_room", and logged with their whole chain.
+func (h *Handler) failErr(w http.ResponseWriter, r *http.Request, err error, internalCode string) {
+	status := response.StatusOf(err)
+	code, args := response.CodeOf(err, internalCode)
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	h.fail(w, r, status, code, args...)
+}
+
+// abandoned returns the status and code answering a request whose context
+// ended with err: 503 request_timeout if its deadline passed (see
+// middleware.Timeout), 499 request_canceled if the client hung up.
+func abandoned(err error) (int, string, []any) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable, "request_timeout", nil
+	}
+	return response.StatusClientClosedRequest, "request_canceled", nil
+}
+
+// language returns the language to answer r in: the signed-in user's
+// "language" preference if it is supported, otherwise the best match for
+// the Accept-Language header, otherwise English.
+func (h *Handler) language(r *http.Request) string {
+	if userID := middleware.GetUserID(r.Context()); userID != "" {
+		if user, err := h.app.UserRepo.GetByID(r.Context(), userID); err == nil {
+			var prefs struct {
+				Language string `json:"language"`
+			}
+			if json.Unmarshal(user.Preferences, &prefs) == nil && i18n.Supported(i18n.Base(prefs.Language)) {
+				return i18n.Base(prefs.Language)
+			}
+		}
+	}
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"ofenes/internal/hlsproxy"
+	"ofenes/internal/middleware"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+// HLSPlaylist handles GET /hls/{id}/index.m3u8.
+//
+// Serves the room's current HLS stream through the proxy (see package
+// hlsproxy): players load this instead of the .m3u8 URL in video_sync.
+// Every URI in it points back at HLSResource.
+func (h *Handler) HLSPlaylist(w http.ResponseWriter, r *http.Request) {
+	roomID, ok := h.hlsRoom(w, r)
+	if !ok {
+		return
+	}
+	content, err := h.app.HLS.Playlist(r.Context(), roomID)
+	h.writeHLS(w, r, content, err)
+}
+
+// HLSResource handles GET /hls/{id}/r?u=...&s=..., the signed links in
+// the playlists served by HLSPlaylist.
+func (h *Handler) HLSResource(w http.ResponseWriter, r *http.Request) {
+	roomID, ok := h.hlsRoom(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	content, err := h.app.HLS.Resource(r.Context(), roomID, middleware.GetUserID(r.Context()), q.Get("u"), q.Get("s"))
+	h.writeHLS(w, r, content, err)
+}
+
+// GetRoomHLS handles GET /api/rooms/{id}/hls.
+//
+// Reports the room's proxied stream: whether it is live, the stream time
+// at its live edge, and where each member's player is, from the segments
+// it loaded through the proxy.
+func (h *Handler) GetRoomHLS(w http.ResponseWriter, r *http.Request) {
+	roomID, ok := h.hlsRoom(w, r)
+	if !ok {
+		return
+	}
+	response.JSON(w, http.StatusOK, h.app.HLS.Status(roomID))
+}
+
+// hlsRoom returns the id path value if the HLS proxy is enabled and the
+// caller may watch that room, and writes the error response otherwise.
+func (h *Handler) hlsRoom(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.app.HLS == nil {
+		h.fail(w, r, http.StatusNotFound, "hls_proxy_disabled")
+		return "", false
+	}
+	roomID := r.PathValue("id")
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return "", false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return "", false
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return "", false
+	}
+	return roomID, true
+}
+
+// writeHLS writes a playlist or segment from the proxy, or its error.
+func (h *Handler) writeHLS(w http.ResponseWriter, r *http.Request, content *hlsproxy.Content, err error) {
+	switch {
+	case errors.Is(err, hlsproxy.ErrNoSource):
+		h.fail(w, r, http.StatusNotFound, "no_hls_source")
+		return
+	case errors.Is(err, hlsproxy.ErrBadLink):
+		h.fail(w, r, http.StatusForbidden, "invalid_hls_link")
+		return
+	case errors.Is(err, hlsproxy.ErrUpstream):
+		log.Printf("hls: %v", err)
+		h.fail(w, r, http.StatusBadGateway, "hls_upstream_failed")
+		return
+	case err != nil:
+		// The player went away while the segment was being fetched.
+		return
+	}
+
+	w.Header().Set("Content-Type", content.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if content.Playlist {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=600")
+	}
+	w.Write(content.Body)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"ofenes/internal/i18n"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+const (
+	// maxInstanceName caps the length of the instance name.
+	maxInstanceName = 100
+
+	// maxInstanceDescription caps the length of the instance description.
+	maxInstanceDescription = 1000
+
+	// maxInstanceLogoURL caps the length of the logo URL.
+	maxInstanceLogoURL = 2048
+
+	// maxInstanceMOTD caps the length of the message of the day.
+	maxInstanceMOTD = 4000
+
+	// maxInstanceContact caps the length of the contact.
+	maxInstanceContact = 200
+)
+
+// GetInstance handles GET /api/instance (public).
+//
+// Returns the instance metadata an admin saved, or the INSTANCE_* settings
+// if none was, so the frontend can show the instance's name, logo and
+// message of the day, even on the login page.
+func (h *Handler) GetInstance(w http.ResponseWriter, r *http.Request) {
+	instance, err := h.app.InstanceRepo.Get(r.Context())
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_get_instance")
+			return
+		}
+		cfg := h.app.Config
+		instance = &models.Instance{
+			Name:        cfg.InstanceName,
+			Description: cfg.InstanceDescription,
+			LogoURL:     cfg.InstanceLogoURL,
+			MOTD:        cfg.InstanceMOTD,
+			Contact:     cfg.InstanceContact,
+		}
+	}
+	instance.UpdatedBy = "" // a user ID; admins find it in the audit log
+	response.JSON(w, http.StatusOK, instance)
+}
+
+// UpdateInstance handles PUT /api/admin/instance (instance.manage).
+// The request replaces every field; from then on the INSTANCE_* settings
+// are ignored.
+func (h *Handler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
+	var req models.InstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	instance, msg := newInstance(req)
+	if msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	now := h.app.Clock.Now()
+	actorID := middleware.GetUserID(ctx)
+	instance.UpdatedBy, instance.UpdatedAt = actorID, &now
+
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Instance.Put(ctx, instance); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.instanceAuditEntry(actorID, instance))
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_instance")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, instance)
+}
+
+// newInstance checks an instance request and returns the metadata it
+// describes, or the problem with it.
+func newInstance(req models.InstanceRequest) (*models.Instance, i18n.Message) {
+	instance := &models.Instance{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		LogoURL:     strings.TrimSpace(req.LogoURL),
+		MOTD:        strings.TrimSpace(req.MOTD),
+		Contact:     strings.TrimSpace(req.Contact),
+	}
+	if instance.Name == "" {
+		return nil, i18n.Msg("instance_name_required")
+	}
+	for _, f := range []struct {
+		name, value string
+		max         int
+	}{
+		{"name", instance.Name, maxInstanceName},
+		{"description", instance.Description, maxInstanceDescription},
+		{"logoUrl", instance.LogoURL, maxInstanceLogoURL},
+		{"motd", instance.MOTD, maxInstanceMOTD},
+		{"contact", instance.Contact, maxInstanceContact},
+	} {
+		if utf8.RuneCountInString(f.value) > f.max {
+			return nil, i18n.Msg("instance_field_too_long", f.name, f.max)
+		}
+	}
+	if instance.LogoURL != "" {
+		u, err := url.Parse(instance.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, i18n.Msg("invalid_instance_logo_url")
+		}
+	}
+	return instance, i18n.Message{}
+}
+
+// instanceAuditEntry builds an audit entry for a change to the instance
+// metadata, recording the metadata itself as the details.
+func (h *Handler) instanceAuditEntry(actorID string, instance *models.Instance) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     models.AuditInstanceUpdate,
+		TargetType: "instance",
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(instance)
+	return entry
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/apperr"
+	"ofenes/pkg/response"
+)
+
+// maxInviteUses caps how many registrations one invite may admit.
+const maxInviteUses = 10000
+
+// errInvalidInvite is returned inside Register's unit of work when the
+// invite code is unknown, expired or used up.
+var errInvalidInvite = apperr.New(apperr.Forbidden, "invalid_invite", "invalid invite")
+
+// ListInvites handles GET /api/admin/invites (invites.manage).
+//
+// Returns every invite, newest first, used up and expired ones included.
+func (h *Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.app.InviteRepo.List(r.Context())
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_invites")
+		return
+	}
+	if invites == nil {
+		invites = []*models.Invite{}
+	}
+	response.JSON(w, http.StatusOK, invites)
+}
+
+// CreateInvite handles POST /api/admin/invites (invites.manage).
+// The server picks the code; without maxUses the invite admits one
+// registration, without expiresAt it never expires. Invites work in any
+// REGISTRATION_MODE but are only required under invite-only.
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	var req models.InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 0 || req.MaxUses > maxInviteUses {
+		h.fail(w, r, http.StatusBadRequest, "invalid_invite_max_uses", maxInviteUses)
+		return
+	}
+	now := h.app.Clock.Now()
+	if req.ExpiresAt != nil {
+		t := req.ExpiresAt.UTC()
+		if !t.After(now) {
+			h.fail(w, r, http.StatusBadRequest, "invalid_invite_expiry")
+			return
+		}
+		req.ExpiresAt = &t
+	}
+	code, err := newInviteCode()
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_invite")
+		return
+	}
+
+	ctx := r.Context()
+	actorID := middleware.GetUserID(ctx)
+	invite := &models.Invite{
+		ID:        h.app.IDs.New(),
+		Code:      code,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: actorID,
+		CreatedAt: now,
+	}
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Invites.Create(ctx, invite); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.inviteAuditEntry(actorID, models.AuditInviteCreate, invite))
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_invite")
+		return
+	}
+
+	response.Created(w, "/api/admin/invites/"+invite.ID, invite)
+}
+
+// DeleteInvite handles DELETE /api/admin/invites/{id} (invites.manage).
+// The code stops working at once; accounts registered with it stay.
+func (h *Handler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	invite, err := h.app.InviteRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "invite_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_invite")
+		return
+	}
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Invites.Delete(ctx, invite.ID); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.inviteAuditEntry(actorID, models.AuditInviteDelete, invite))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "invite_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_invite")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// newInviteCode returns a random invite code: 16 characters of base32,
+// easy to read out or type.
+func newInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
+
+// inviteAuditEntry builds an audit entry for a change to invite, recording
+// the invite itself as the details.
+func (h *Handler) inviteAuditEntry(actorID, action string, invite *models.Invite) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "invite",
+		TargetID:   invite.ID,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(invite)
+	return entry
+}
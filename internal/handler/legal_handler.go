@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"ofenes/internal/i18n"
+	"ofenes/internal/legal"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+const (
+	// maxLegalVersion caps the length of a document version.
+	maxLegalVersion = 64
+
+	// maxLegalTitle caps the length of a document title.
+	maxLegalTitle = 200
+
+	// maxLegalBody caps the length of a document body.
+	maxLegalBody = 200000
+)
+
+// GetLegal handles GET /api/legal (public).
+//
+// Returns the current version of each published document (terms of
+// service, privacy policy), so clients can show them before registration
+// and whenever the API answers 428 legal_acceptance_required. Empty until
+// an admin publishes one.
+func (h *Handler) GetLegal(w http.ResponseWriter, r *http.Request) {
+	current := h.app.Legal.Current()
+	if current == nil {
+		current = []*models.LegalDocument{}
+	}
+	response.JSON(w, http.StatusOK, current)
+}
+
+// GetLegalVersion handles GET /api/legal/{kind}/{version} (public).
+//
+// Returns any published version of a document, e.g. one a user accepted
+// before.
+func (h *Handler) GetLegalVersion(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if !legal.IsKind(kind) {
+		h.fail(w, r, http.StatusNotFound, "legal_document_not_found")
+		return
+	}
+	doc, err := h.app.LegalRepo.GetVersion(r.Context(), kind, r.PathValue("version"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "legal_document_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_legal_document")
+		return
+	}
+	response.JSON(w, http.StatusOK, doc)
+}
+
+// MyLegal handles GET /api/me/legal.
+//
+// Returns the current documents the caller has yet to accept and
+// everything they accepted, with versions and timestamps. Not subject to
+// the acceptance gate.
+func (h *Handler) MyLegal(w http.ResponseWriter, r *http.Request) {
+	status, err := h.legalStatus(r)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_legal_status")
+		return
+	}
+	response.JSON(w, http.StatusOK, status)
+}
+
+// AcceptLegal handles POST /api/me/legal/accept.
+//
+// Records that the caller accepted the given version of each document,
+// which must be the current one: a client that showed an older version
+// gets 409 legal_version_outdated and should show the new one. Accepting
+// a version again keeps the first acceptance. Not subject to the
+// acceptance gate.
+//
+// Request:  { "versions": { "terms": "2026-10", "privacy": "3" } }
+// Response: as GET /api/me/legal
+func (h *Handler) AcceptLegal(w http.ResponseWriter, r *http.Request) {
+	var req models.LegalAcceptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if len(req.Versions) == 0 {
+		h.fail(w, r, http.StatusBadRequest, "legal_versions_required")
+		return
+	}
+
+	ctx := r.Context()
+	userID := middleware.GetUserID(ctx)
+	now := h.app.Clock.Now()
+	var accepted []*models.LegalAcceptance
+	for kind, version := range req.Versions {
+		if !legal.IsKind(kind) {
+			h.fail(w, r, http.StatusBadRequest, "invalid_legal_kind", kind)
+			return
+		}
+		current, err := h.app.LegalRepo.Current(ctx, kind)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				h.fail(w, r, http.StatusNotFound, "legal_document_not_found")
+				return
+			}
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_accept_legal")
+			return
+		}
+		if current.Version != version {
+			h.fail(w, r, http.StatusConflict, "legal_version_outdated", kind)
+			return
+		}
+		accepted = append(accepted, &models.LegalAcceptance{UserID: userID, Kind: kind, Version: version, AcceptedAt: now})
+	}
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		for _, a := range accepted {
+			if err := tx.Legal.Accept(ctx, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_accept_legal")
+		return
+	}
+	for _, a := range accepted {
+		h.app.Legal.Remember(a.UserID, a.Kind, a.Version)
+	}
+
+	status, err := h.legalStatus(r)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_legal_status")
+		return
+	}
+	response.JSON(w, http.StatusOK, status)
+}
+
+// ListLegalVersions handles GET /api/admin/legal/{kind} (legal.manage).
+//
+// Returns every published version of a document, newest first.
+func (h *Handler) ListLegalVersions(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if !legal.IsKind(kind) {
+		h.fail(w, r, http.StatusNotFound, "legal_document_not_found")
+		return
+	}
+	docs, err := h.app.LegalRepo.ListVersions(r.Context(), kind)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_legal_versions")
+		return
+	}
+	if docs == nil {
+		docs = []*models.LegalDocument{}
+	}
+	response.JSON(w, http.StatusOK, docs)
+}
+
+// PublishLegal handles POST /api/admin/legal/{kind} (legal.manage).
+//
+// Publishes a new version of a document, which becomes the current one:
+// from then on every user must accept it before using the API again.
+// Versions can't be changed or removed once published, as acceptances
+// refer to them; a version already published is 409.
+//
+// Request: { "version": "2026-10", "title": "Terms of Service", "body": "..." }
+func (h *Handler) PublishLegal(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if !legal.IsKind(kind) {
+		h.fail(w, r, http.StatusNotFound, "legal_document_not_found")
+		return
+	}
+	var req models.LegalDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if msg := checkLegalDocument(&req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	actorID := middleware.GetUserID(ctx)
+	doc := &models.LegalDocument{
+		Kind:        kind,
+		Version:     req.Version,
+		Title:       req.Title,
+		Body:        req.Body,
+		PublishedBy: actorID,
+		PublishedAt: h.app.Clock.Now(),
+	}
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Legal.Publish(ctx, doc); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.legalAuditEntry(actorID, doc))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.fail(w, r, http.StatusConflict, "legal_version_exists")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_publish_legal")
+		return
+	}
+	// As with origins, a failure is only logged; the periodic reload
+	// retries it.
+	if err := h.app.Legal.Reload(ctx); err != nil {
+		log.Printf("legal: reload failed: %v", err)
+	}
+
+	response.Created(w, "/api/legal/"+kind+"/"+doc.Version, doc)
+}
+
+// legalStatus returns the caller's pending documents and acceptances.
+func (h *Handler) legalStatus(r *http.Request) (*models.LegalStatus, error) {
+	ctx := r.Context()
+	userID := middleware.GetUserID(ctx)
+	pending, err := h.app.Legal.Pending(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	accepted, err := h.app.LegalRepo.ListAcceptances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.LegalStatus{Pending: pending, Accepted: accepted}
+	if status.Pending == nil {
+		status.Pending = []*models.LegalDocument{}
+	}
+	if status.Accepted == nil {
+		status.Accepted = []*models.LegalAcceptance{}
+	}
+	return status, nil
+}
+
+// checkLegalDocument trims and checks a document to publish. It returns
+// the problem, or a zero Message if the request is valid.
+func checkLegalDocument(req *models.LegalDocumentRequest) i18n.Message {
+	req.Version = strings.TrimSpace(req.Version)
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if !validLegalVersion(req.Version) {
+		return i18n.Msg("invalid_legal_version", maxLegalVersion)
+	}
+	if req.Title == "" {
+		return i18n.Msg("legal_title_required")
+	}
+	if utf8.RuneCountInString(req.Title) > maxLegalTitle {
+		return i18n.Msg("legal_title_too_long", maxLegalTitle)
+	}
+	if req.Body == "" {
+		return i18n.Msg("legal_body_required")
+	}
+	if utf8.RuneCountInString(req.Body) > maxLegalBody {
+		return i18n.Msg("legal_body_too_long", maxLegalBody)
+	}
+	return i18n.Message{}
+}
+
+// validLegalVersion reports whether v is 1 to maxLegalVersion letters,
+// digits, dots, dashes and underscores, so it can go in URLs as is.
+func validLegalVersion(v string) bool {
+	if v == "" || len(v) > maxLegalVersion {
+		return false
+	}
+	for _, c := range v {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// legalAuditEntry builds the audit entry for publishing doc, recording
+// its kind and version (not the body) as the details.
+func (h *Handler) legalAuditEntry(actorID string, doc *models.LegalDocument) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     models.AuditLegalPublish,
+		TargetType: "legal",
+		TargetID:   doc.Kind,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(map[string]string{"kind": doc.Kind, "version": doc.Version})
+	return entry
+}
@@ -0,0 +1,420 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/metadata"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/quota"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxLibraryTitleLength bounds item titles, in characters.
+	maxLibraryTitleLength = 200
+
+	// maxLibraryURLLength bounds item URLs, in bytes.
+	maxLibraryURLLength = 2048
+
+	// maxLibraryFolderLength bounds folder names, in characters.
+	maxLibraryFolderLength = 100
+
+	// maxLibraryTags bounds the tags of an item, and maxLibraryTagLength
+	// each tag, in characters.
+	maxLibraryTags, maxLibraryTagLength = 20, 30
+
+	// maxLibraryItems bounds the items of one user's or room's library.
+	maxLibraryItems = 1000
+
+	// metadataTimeout bounds looking up an item's metadata. Items are
+	// saved without it if the provider is slower.
+	metadataTimeout = 5 * time.Second
+)
+
+// libraryTag matches the tags of library items and rooms: letters,
+// digits, dashes and underscores.
+var libraryTag = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+
+// CreateLibraryItem handles POST /api/library.
+//
+// Saves a video to the caller's own library, seen only by them, within
+// their library quota.
+func (h *Handler) CreateLibraryItem(w http.ResponseWriter, r *http.Request) {
+	var req models.LibraryItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !h.validLibraryItem(w, r, &req) {
+		return
+	}
+	if !h.checkQuota(w, r, quota.Library, 1, "failed_to_save_library_item") {
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+	h.createLibraryItem(w, r, userID, "", req, h.libraryMetadata(r, req.Title, req.URL))
+}
+
+// ListLibrary handles GET /api/library?q=&folder=&tag=.
+//
+// Lists the caller's own library, newest first. q searches titles and
+// URLs, ignoring case; folder and tag narrow to one folder or tag.
+func (h *Handler) ListLibrary(w http.ResponseWriter, r *http.Request) {
+	h.listLibrary(w, r, repository.LibraryFilter{OwnerID: middleware.GetUserID(r.Context())})
+}
+
+// CreateRoomLibraryItem handles POST /api/rooms/{id}/library
+// (video.control).
+//
+// Saves a video to the room's library, seen by everyone who may watch the
+// room.
+func (h *Handler) CreateRoomLibraryItem(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if !h.roomCan(r, roomID, authz.RoomPermVideoControl) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	var req models.LibraryItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !h.validLibraryItem(w, r, &req) {
+		return
+	}
+	if !h.activeRoom(w, r, roomID) {
+		return
+	}
+	h.createLibraryItem(w, r, "", roomID, req, h.libraryMetadata(r, req.Title, req.URL))
+}
+
+// ListRoomLibrary handles GET /api/rooms/{id}/library?q=&folder=&tag=.
+//
+// Lists the room's library, newest first, to those who may watch the room.
+// The query parameters are those of GET /api/library.
+func (h *Handler) ListRoomLibrary(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	h.listLibrary(w, r, repository.LibraryFilter{RoomID: roomID})
+}
+
+// GetLibraryItem handles GET /api/library/{id}.
+func (h *Handler) GetLibraryItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.libraryItem(w, r, false)
+	if !ok {
+		return
+	}
+	response.JSON(w, http.StatusOK, item)
+}
+
+// UpdateLibraryItem handles PUT /api/library/{id}.
+//
+// Replaces the title, URL, folder and tags of an item. Personal items are
+// edited by their owner, room items by those with video.control.
+func (h *Handler) UpdateLibraryItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.libraryItem(w, r, true)
+	if !ok {
+		return
+	}
+	var req models.LibraryItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !h.validLibraryItem(w, r, &req) {
+		return
+	}
+
+	if req.Title != item.Title || req.URL != item.URL || item.Metadata == nil {
+		item.Metadata = h.libraryMetadata(r, req.Title, req.URL)
+	}
+	item.Title, item.URL, item.Folder, item.Tags = req.Title, req.URL, req.Folder, req.Tags
+	item.UpdatedAt = h.app.Clock.Now()
+	if err := h.app.LibraryRepo.Update(r.Context(), item); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "library_item_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_save_library_item")
+		return
+	}
+	response.JSON(w, http.StatusOK, item)
+}
+
+// DeleteLibraryItem handles DELETE /api/library/{id}.
+//
+// Personal items are deleted by their owner, room items by those with
+// video.control.
+func (h *Handler) DeleteLibraryItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.libraryItem(w, r, true)
+	if !ok {
+		return
+	}
+	if err := h.app.LibraryRepo.Delete(r.Context(), item.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "library_item_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_library_item")
+		return
+	}
+	response.NoContent(w)
+}
+
+// ShareLibraryItem handles POST /api/library/{id}/share.
+//
+// Copies an item the caller can see into the library of a room where they
+// have video.control. Later edits to either copy don't affect the other.
+func (h *Handler) ShareLibraryItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.libraryItem(w, r, false)
+	if !ok {
+		return
+	}
+	var req models.ShareLibraryItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if _, err := uuid.Parse(req.RoomID); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_room_id")
+		return
+	}
+	if !h.roomCan(r, req.RoomID, authz.RoomPermVideoControl) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	if !h.activeRoom(w, r, req.RoomID) {
+		return
+	}
+	h.createLibraryItem(w, r, "", req.RoomID, models.LibraryItemRequest{
+		Title: item.Title, URL: item.URL, Folder: item.Folder, Tags: item.Tags,
+	}, item.Metadata)
+}
+
+// createLibraryItem stores req, already validated, with its metadata in
+// the library of ownerID or roomID, unless that library is full.
+func (h *Handler) createLibraryItem(w http.ResponseWriter, r *http.Request, ownerID, roomID string, req models.LibraryItemRequest, meta *models.MediaMetadata) {
+	ctx := r.Context()
+	n, err := h.app.LibraryRepo.Count(ctx, ownerID, roomID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_save_library_item")
+		return
+	}
+	if n >= maxLibraryItems {
+		h.fail(w, r, http.StatusConflict, "library_full", maxLibraryItems)
+		return
+	}
+
+	now := h.app.Clock.Now()
+	item := &models.LibraryItem{
+		ID:        h.app.IDs.New(),
+		OwnerID:   ownerID,
+		RoomID:    roomID,
+		AddedBy:   middleware.GetUserID(ctx),
+		Title:     req.Title,
+		URL:       req.URL,
+		Folder:    req.Folder,
+		Tags:      req.Tags,
+		Metadata:  meta,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.app.LibraryRepo.Create(ctx, item); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_save_library_item")
+		return
+	}
+	response.Created(w, "/api/library/"+item.ID, item)
+}
+
+// libraryMetadata returns the movie or episode an item is, named by its
+// title or else the file name in its URL; nil if neither names one, the
+// provider knows neither, or enrichment is off. Failed lookups are logged.
+func (h *Handler) libraryMetadata(r *http.Request, title, rawURL string) *models.MediaMetadata {
+	if h.app.Metadata == nil {
+		return nil
+	}
+	name := title
+	if _, ok := metadata.Parse(title); !ok {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil
+		}
+		name = path.Base(u.Path)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), metadataTimeout)
+	defer cancel()
+	meta, err := h.app.Metadata.Lookup(ctx, name)
+	if err != nil {
+		log.Printf("library: metadata of %q: %v", name, err)
+		return nil
+	}
+	return meta
+}
+
+// listLibrary writes the page of items matching filter and the query
+// parameters.
+func (h *Handler) listLibrary(w http.ResponseWriter, r *http.Request, filter repository.LibraryFilter) {
+	limit, offset := parsePagination(r)
+	query := r.URL.Query()
+	filter.Query = strings.TrimSpace(query.Get("q"))
+	filter.Folder = strings.TrimSpace(query.Get("folder"))
+	filter.Tag = strings.ToLower(strings.TrimSpace(query.Get("tag")))
+	filter.Limit, filter.Offset = limit, offset
+
+	items, err := h.app.LibraryRepo.List(r.Context(), filter)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_library")
+		return
+	}
+	if items == nil {
+		items = []*models.LibraryItem{}
+	}
+	response.Paginated(w, items, response.Page(limit, offset, len(items)))
+}
+
+// libraryItem loads the item in the path, writing the error response if it
+// cannot or the caller may not see it, or with write, change it. Others'
+// personal items are reported missing.
+func (h *Handler) libraryItem(w http.ResponseWriter, r *http.Request, write bool) (*models.LibraryItem, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		h.fail(w, r, http.StatusNotFound, "library_item_not_found")
+		return nil, false
+	}
+	ctx := r.Context()
+	item, err := h.app.LibraryRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "library_item_not_found")
+			return nil, false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_library")
+		return nil, false
+	}
+
+	if item.RoomID == "" {
+		if item.OwnerID != middleware.GetUserID(ctx) {
+			h.fail(w, r, http.StatusNotFound, "library_item_not_found")
+			return nil, false
+		}
+		return item, true
+	}
+	if write {
+		if !h.roomCan(r, item.RoomID, authz.RoomPermVideoControl) {
+			h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+			return nil, false
+		}
+		return item, true
+	}
+	room, err := h.app.RoomRepo.GetByID(ctx, item.RoomID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return nil, false
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return nil, false
+	}
+	return item, true
+}
+
+// activeRoom reports whether roomID is open, writing the error response
+// if it is missing or closed.
+func (h *Handler) activeRoom(w http.ResponseWriter, r *http.Request, roomID string) bool {
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return false
+	}
+	if !room.IsActive {
+		h.fail(w, r, http.StatusGone, "room_inactive")
+		return false
+	}
+	return true
+}
+
+// validLibraryItem trims and checks req, writing the error response if it
+// is invalid. Tags are lowercased, deduplicated and sorted.
+func (h *Handler) validLibraryItem(w http.ResponseWriter, r *http.Request, req *models.LibraryItemRequest) bool {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || utf8.RuneCountInString(req.Title) > maxLibraryTitleLength {
+		h.fail(w, r, http.StatusBadRequest, "invalid_library_title", maxLibraryTitleLength)
+		return false
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if !validLibraryURL(req.URL) {
+		h.fail(w, r, http.StatusBadRequest, "invalid_library_url")
+		return false
+	}
+	req.Folder = strings.TrimSpace(req.Folder)
+	if utf8.RuneCountInString(req.Folder) > maxLibraryFolderLength {
+		h.fail(w, r, http.StatusBadRequest, "invalid_library_folder", maxLibraryFolderLength)
+		return false
+	}
+
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !libraryTag.MatchString(tag) || utf8.RuneCountInString(tag) > maxLibraryTagLength {
+			h.fail(w, r, http.StatusBadRequest, "invalid_library_tag", tag, maxLibraryTagLength)
+			return false
+		}
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	req.Tags = slices.Compact(tags)
+	if len(req.Tags) > maxLibraryTags {
+		h.fail(w, r, http.StatusBadRequest, "too_many_library_tags", maxLibraryTags)
+		return false
+	}
+	return true
+}
+
+// validLibraryURL reports whether s is something players can load: an
+// absolute http(s) URL, or the path of an upload on this server.
+func validLibraryURL(s string) bool {
+	if s == "" || len(s) > maxLibraryURLLength {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return strings.HasPrefix(u.Path, "/media/") && !strings.Contains(u.Path, "..")
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
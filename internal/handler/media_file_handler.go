@@ -0,0 +1,506 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/media"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/quota"
+	"ofenes/internal/repository"
+	"ofenes/internal/transcode"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// maxMediaFileNameLength bounds the file names of uploads.
+const maxMediaFileNameLength = 255
+
+// CreateMediaUpload handles POST /api/rooms/{id}/media (video.control).
+//
+// Starts uploading a video for the room: the record is created with
+// status "uploading", and the bytes are sent with UploadMediaChunk.
+// Below TRUST_LEVEL_UPLOADS the router rejects the request.
+//
+// Request: { "fileName": "movie.mp4", "mimeType": "video/mp4", "size": 73400320 }
+func (h *Handler) CreateMediaUpload(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if !h.roomCan(r, roomID, authz.RoomPermVideoControl) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var req models.CreateMediaUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	req.FileName = strings.TrimSpace(req.FileName)
+	if req.FileName == "" {
+		h.fail(w, r, http.StatusBadRequest, "file_name_required")
+		return
+	}
+	if len(req.FileName) > maxMediaFileNameLength || strings.ContainsAny(req.FileName, `/\`) {
+		h.fail(w, r, http.StatusBadRequest, "invalid_file_name")
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(req.MimeType); err != nil || !strings.HasPrefix(mt, "video/") {
+		h.fail(w, r, http.StatusBadRequest, "invalid_media_type", req.MimeType)
+		return
+	}
+	if req.Size <= 0 || req.Size > h.app.Config.MediaMaxUploadSize {
+		h.fail(w, r, http.StatusBadRequest, "invalid_media_size", h.app.Config.MediaMaxUploadSize)
+		return
+	}
+
+	ctx := r.Context()
+	room, err := h.app.RoomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !room.IsActive {
+		h.fail(w, r, http.StatusGone, "room_inactive")
+		return
+	}
+	if !h.checkQuota(w, r, quota.Storage, req.Size, "failed_to_create_media_upload") {
+		return
+	}
+
+	now := h.app.Clock.Now()
+	file := &models.MediaFile{
+		ID:         h.app.IDs.New(),
+		RoomID:     roomID,
+		UploadedBy: middleware.GetUserID(ctx),
+		FileName:   req.FileName,
+		MimeType:   req.MimeType,
+		Size:       req.Size,
+		Status:     models.MediaFileUploading,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := h.app.MediaFileRepo.Create(ctx, file); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_media_upload")
+		return
+	}
+
+	response.Created(w, "/api/media/"+file.ID, file)
+}
+
+// GetMediaFile handles GET /api/media/{id}.
+//
+// Returns the record of an upload to those who may watch its room. While
+// it is uploading, received tells a client resuming it where to go on.
+func (h *Handler) GetMediaFile(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	if err := h.setStored(file); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+		return
+	}
+	response.JSON(w, http.StatusOK, file)
+}
+
+// UploadMediaChunk handles PUT /api/media/{id}/content?offset=N.
+//
+// Appends the raw request body to the caller's upload. offset must be the
+// number of bytes received so far; otherwise nothing is written and the
+// response is 409, with the current count in the message (GET the record
+// for it as a number). Chunks of one upload are sent one at a time, and
+// bytes past size are refused with 413. The upload becomes ready once size
+// bytes are received, and the response is the updated record. The room
+// is then told it can be played, or, with TRANSCODE_MODE set, it is queued
+// for transcoding and the room is told once that is done.
+func (h *Handler) UploadMediaChunk(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.mediaFile(w, r)
+	if !ok {
+		return
+	}
+	if file.UploadedBy != middleware.GetUserID(r.Context()) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	if file.Status == models.MediaFileReady {
+		h.fail(w, r, http.StatusConflict, "media_upload_complete")
+		return
+	}
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 || offset > file.Size {
+		h.fail(w, r, http.StatusBadRequest, "invalid_upload_offset")
+		return
+	}
+
+	received, err := h.app.MediaStore.Append(file.ID, offset, http.MaxBytesReader(w, r.Body, file.Size-offset))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, media.ErrOffsetMismatch):
+		h.fail(w, r, http.StatusConflict, "upload_offset_mismatch", received)
+		return
+	case errors.As(err, &tooLarge):
+		h.fail(w, r, http.StatusRequestEntityTooLarge, "request_body_too_large")
+		return
+	case err != nil:
+		h.failErr(w, r, err, "failed_to_store_media") // media.ErrBusy is a Conflict
+		return
+	}
+
+	file.Received = received
+	if received == file.Size {
+		now := h.app.Clock.Now()
+		if err := h.app.MediaFileRepo.MarkReady(r.Context(), file.ID, now); err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_store_media")
+			return
+		}
+		file.Status = models.MediaFileReady
+		file.UpdatedAt = now
+		h.mediaReady(r.Context(), file)
+	}
+	response.JSON(w, http.StatusOK, file)
+}
+
+// ListRoomMedia handles GET /api/rooms/{id}/media.
+//
+// Lists the videos uploaded for the room, newest first, including those
+// still uploading.
+func (h *Handler) ListRoomMedia(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	ctx := r.Context()
+	room, err := h.app.RoomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	files, err := h.app.MediaFileRepo.ListByRoom(ctx, roomID, limit, offset)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+		return
+	}
+	if files == nil {
+		files = []*models.MediaFile{}
+	}
+	for _, f := range files {
+		if err := h.setStored(f); err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+			return
+		}
+	}
+
+	response.Paginated(w, files, response.Page(limit, offset, len(files)))
+}
+
+// DeleteMediaFile handles DELETE /api/media/{id}.
+//
+// Deletes an upload, its bytes and its subtitle tracks. Allowed for the
+// uploader and for those with room.moderate in its room.
+func (h *Handler) DeleteMediaFile(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.mediaFile(w, r)
+	if !ok {
+		return
+	}
+	if file.UploadedBy != middleware.GetUserID(r.Context()) && !h.roomCan(r, file.RoomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	if err := h.app.SubtitleRepo.DeleteByMedia(r.Context(), file.ID); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_media")
+		return
+	}
+	if err := h.app.MediaStore.DeletePrefix(file.ID); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_media")
+		return
+	}
+	if err := h.app.MediaFileRepo.Delete(r.Context(), file.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "media_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_media")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// StreamMedia handles GET /media/{id}.
+//
+// Serves a ready upload to those who may watch its room, with Range
+// support so players can seek. Browsers in cookie mode can point a
+// <video> element at it directly.
+func (h *Handler) StreamMedia(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	if file.Status != models.MediaFileReady {
+		h.fail(w, r, http.StatusConflict, "media_not_ready")
+		return
+	}
+	h.serveMediaFile(w, r, file)
+}
+
+// CreatePlaybackToken handles POST /api/media/{id}/playback-token.
+//
+// Issues a token streaming a ready upload from GET /api/media/{id}/stream
+// to a caller who may watch its room, for players that cannot send the
+// JWT, such as <video> elements in bearer mode. The URL must be opened
+// within MEDIA_STREAM_TOKEN_TTL_MS; each token is one stream.
+func (h *Handler) CreatePlaybackToken(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	if file.Status != models.MediaFileReady {
+		h.fail(w, r, http.StatusConflict, "media_not_ready")
+		return
+	}
+
+	expires := h.app.Clock.Now().Add(h.app.Config.MediaStreamTokenTTL)
+	token := media.SignPlayback(h.app.Config.JWTSecret, media.Playback{
+		MediaID:  file.ID,
+		UserID:   middleware.GetUserID(r.Context()),
+		StreamID: h.app.IDs.New(),
+		Expires:  expires,
+	})
+	response.JSON(w, http.StatusOK, models.PlaybackToken{
+		Token:     token,
+		URL:       "/api/media/" + file.ID + "/stream?token=" + url.QueryEscape(token),
+		ExpiresAt: expires,
+	})
+}
+
+// StreamMediaWithToken handles GET /api/media/{id}/stream?token=....
+//
+// Serves a ready upload like StreamMedia, Range requests included, to
+// holders of a token from CreatePlaybackToken instead of a JWT. An
+// expired token is still honored while its stream is playing, so seeking
+// keeps working; users play at most MEDIA_STREAMS_PER_USER streams at
+// once on each instance.
+func (h *Handler) StreamMediaWithToken(w http.ResponseWriter, r *http.Request) {
+	playback, err := media.ParsePlayback(h.app.Config.JWTSecret, r.URL.Query().Get("token"))
+	if err != nil || playback.MediaID != r.PathValue("id") {
+		h.fail(w, r, http.StatusForbidden, "invalid_playback_token")
+		return
+	}
+	if h.app.Clock.Now().After(playback.Expires) && !h.app.Streams.Playing(playback.UserID, playback.StreamID) {
+		h.fail(w, r, http.StatusForbidden, "playback_token_expired")
+		return
+	}
+	file, ok := h.mediaFile(w, r)
+	if !ok {
+		return
+	}
+	if file.Status != models.MediaFileReady {
+		h.fail(w, r, http.StatusConflict, "media_not_ready")
+		return
+	}
+
+	done, ok := h.app.Streams.Start(playback.UserID, playback.StreamID)
+	if !ok {
+		h.fail(w, r, http.StatusTooManyRequests, "too_many_streams", h.app.Config.MediaStreamsPerUser)
+		return
+	}
+	defer done()
+	h.serveMediaFile(w, r, file)
+}
+
+// StreamMediaHLS handles GET /media/{id}/hls/{name}.
+//
+// Serves the playlists and segments transcoded from an upload, to those
+// who may watch its room. Players load master.m3u8 (the "media" message
+// announcing it carries the URL), which links to the rest.
+func (h *Handler) StreamMediaHLS(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	contentType := ""
+	switch path.Ext(name) {
+	case ".m3u8":
+		contentType = "application/vnd.apple.mpegurl"
+	case ".ts":
+		contentType = "video/mp2t"
+	}
+	if file.Transcode == nil || file.Transcode.Status != models.TranscodeDone || contentType == "" || !transcode.ValidName(name) {
+		h.fail(w, r, http.StatusNotFound, "media_not_found")
+		return
+	}
+
+	content, err := h.app.MediaStore.Open(media.DerivedKey(file.ID, name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			h.fail(w, r, http.StatusNotFound, "media_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, name, file.Transcode.UpdatedAt, content)
+}
+
+// StreamMediaPreview handles GET /media/{id}/preview/{name}.
+//
+// Serves the previews made from an upload, to those who may watch its
+// room: poster.jpg, and sprite.vtt, a WebVTT thumbnails track for seek
+// bar previews pointing into sprite.jpg. They are available as soon as
+// made, before the renditions; the record's posterUrl and previewUrl say
+// whether they are.
+func (h *Handler) StreamMediaPreview(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	contentType := ""
+	switch name {
+	case transcode.PosterFile, transcode.SpriteFile:
+		contentType = "image/jpeg"
+	case transcode.SpriteVTT:
+		contentType = "text/vtt; charset=utf-8"
+	}
+	if file.Transcode == nil || contentType == "" {
+		h.fail(w, r, http.StatusNotFound, "media_not_found")
+		return
+	}
+
+	content, err := h.app.MediaStore.Open(media.DerivedKey(file.ID, name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			h.fail(w, r, http.StatusNotFound, "media_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, name, time.Time{}, content)
+}
+
+// serveMediaFile writes the bytes of a ready upload, honoring Range
+// requests.
+func (h *Handler) serveMediaFile(w http.ResponseWriter, r *http.Request, file *models.MediaFile) {
+	content, err := h.app.MediaStore.Open(file.ID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", file.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, file.FileName, file.UpdatedAt, content)
+}
+
+// mediaReady announces an upload that just completed, or queues it for
+// transcoding, in which case the transcode job announces it. Queueing
+// failures are logged: the upload itself is stored.
+func (h *Handler) mediaReady(ctx context.Context, file *models.MediaFile) {
+	if h.app.Transcode == nil {
+		h.app.Hub.NotifyMedia(models.MediaEvent{Event: models.MediaEventPlayable, Media: file, URL: "/media/" + file.ID})
+		return
+	}
+	if err := h.app.Transcode.Enqueue(ctx, file); err != nil {
+		log.Printf("media: failed to queue %s for transcoding: %v", file.ID, err)
+	}
+}
+
+// mediaFile loads the record named by the id path value, writing the
+// error response if it cannot.
+func (h *Handler) mediaFile(w http.ResponseWriter, r *http.Request) (*models.MediaFile, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		h.fail(w, r, http.StatusNotFound, "media_not_found")
+		return nil, false
+	}
+	file, err := h.app.MediaFileRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "media_not_found")
+			return nil, false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media")
+		return nil, false
+	}
+	return file, true
+}
+
+// watchableMediaFile is mediaFile for records the caller may watch.
+func (h *Handler) watchableMediaFile(w http.ResponseWriter, r *http.Request) (*models.MediaFile, bool) {
+	file, ok := h.mediaFile(w, r)
+	if !ok {
+		return nil, false
+	}
+	room, err := h.app.RoomRepo.GetByID(r.Context(), file.RoomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "media_not_found")
+			return nil, false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return nil, false
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return nil, false
+	}
+	return file, true
+}
+
+// canWatch reports whether the caller may see the media of room: its
+// members, anyone in a public room, and those acting as its owner.
+func (h *Handler) canWatch(r *http.Request, room *models.Room) bool {
+	return authz.NonMemberRoomRole(room.Type) != "" || h.callerRoomRole(r, room.ID) != ""
+}
+
+// setStored fills in file.Received and the preview URLs from the media
+// store.
+func (h *Handler) setStored(file *models.MediaFile) error {
+	if file.Status == models.MediaFileReady {
+		file.Received = file.Size
+		return transcode.SetPreviews(h.app.MediaStore, file)
+	}
+	received, err := h.app.MediaStore.Size(file.ID)
+	if err != nil {
+		return err
+	}
+	file.Received = received
+	return nil
+}
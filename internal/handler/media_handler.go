@@ -11,7 +11,7 @@ import (
 func (h *Handler) GetRoomMediaSessions(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
@@ -19,21 +19,21 @@ func (h *Handler) GetRoomMediaSessions(w http.ResponseWriter, r *http.Request) {
 
 	sessions, err := h.app.MediaRepo.GetByRoom(r.Context(), roomID, limit, offset)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to get media sessions")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_media_sessions")
 		return
 	}
 	if sessions == nil {
 		sessions = []*models.MediaSession{}
 	}
 
-	response.JSON(w, http.StatusOK, sessions)
+	response.Paginated(w, sessions, response.Page(limit, offset, len(sessions)))
 }
 
 // GetRoomFiles handles GET /api/rooms/{id}/files.
 func (h *Handler) GetRoomFiles(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
@@ -41,12 +41,12 @@ func (h *Handler) GetRoomFiles(w http.ResponseWriter, r *http.Request) {
 
 	files, err := h.app.FileRepo.GetByRoom(r.Context(), roomID, limit, offset)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to get files")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_files")
 		return
 	}
 	if files == nil {
 		files = []*models.SharedFile{}
 	}
 
-	response.JSON(w, http.StatusOK, files)
+	response.Paginated(w, files, response.Page(limit, offset, len(files)))
 }
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"ofenes/internal/authz"
 	"ofenes/internal/models"
+	"ofenes/internal/repository"
 	"ofenes/pkg/response"
 )
 
@@ -14,12 +17,12 @@ import (
 func (h *Handler) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
 	// Parse cursor (before timestamp)
-	before := time.Now()
+	before := h.app.Clock.Now()
 	if v := r.URL.Query().Get("before"); v != "" {
 		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
 			before = t
@@ -36,12 +39,52 @@ func (h *Handler) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
 
 	messages, err := h.app.MessageRepo.GetByRoom(r.Context(), roomID, before, limit)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to get messages")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_messages")
 		return
 	}
 	if messages == nil {
 		messages = []*models.ChatMessage{}
 	}
 
-	response.JSON(w, http.StatusOK, messages)
+	// Newest first, so the next (older) page starts before the last message.
+	page := response.Page(limit, 0, len(messages))
+	if page.HasMore {
+		page.NextCursor = messages[len(messages)-1].CreatedAt.Format(time.RFC3339Nano)
+	}
+	response.Paginated(w, messages, page)
+}
+
+// ExportRoomMessages handles GET /api/rooms/{id}/messages/export.
+//
+// Streams a room's whole chat history as NDJSON, one message per line,
+// oldest first. Only site moderators and room members with the
+// room.moderate permission may export. Pages are fetched by timestamp, so messages sharing the exact
+// timestamp of a page's last message could be skipped; with microsecond
+// timestamps that needs a page boundary to fall inside a single tick.
+func (h *Handler) ExportRoomMessages(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if _, err := h.app.RoomRepo.GetByID(r.Context(), roomID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !h.roomCan(r, roomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	filename := "messages-" + roomID + "-" + h.app.Clock.Now().UTC().Format("20060102-150405") + ".ndjson"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	var after time.Time
+	streamAll(h, w, r, "failed_to_get_messages", func() ([]*models.ChatMessage, error) {
+		page, err := h.app.MessageRepo.GetByRoomAfter(r.Context(), roomID, after, streamPageSize)
+		if len(page) > 0 {
+			after = page[len(page)-1].CreatedAt
+		}
+		return page, err
+	})
 }
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"ofenes/internal/models"
+	"ofenes/pkg/response"
+)
+
+// ListNowPlaying handles GET /api/now-playing, for bots and rich presence
+// integrations holding a service token with the presence scope. Returns
+// what the main screen of each room with playback on this instance plays,
+// sorted by room; ?room= narrows it to one room.
+func (h *Handler) ListNowPlaying(w http.ResponseWriter, r *http.Request) {
+	list, err := h.app.Hub.NowPlaying(r.Context(), r.URL.Query().Get("room"))
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_now_playing")
+		return
+	}
+	if list == nil {
+		list = []models.NowPlaying{}
+	}
+	response.JSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/internal/trust"
+	"ofenes/pkg/response"
+)
+
+// MyPermissions handles GET /api/me/permissions.
+//
+// Returns the permissions the caller's token grants, as the server checks
+// them, so frontends can show or hide controls without duplicating the
+// rules. rooms.create is left out below TRUST_LEVEL_CREATE_ROOMS, unless
+// the role has trust.bypass. Like the checks themselves, this reflects
+// the role in the token: a new role shows up after a token refresh.
+func (h *Handler) MyPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	role, level := middleware.GetRole(ctx), middleware.GetTrustLevel(ctx)
+	bypass := h.app.Authz.Can(role, authz.PermTrustBypass)
+	trusted := func(min string) bool { return bypass || trust.Allows(level, min) }
+
+	perms := h.app.Authz.Permissions(role)
+	if !trusted(h.app.Config.TrustLevelCreateRooms) {
+		perms = slices.DeleteFunc(perms, func(p string) bool { return p == authz.PermRoomsCreate })
+	}
+
+	response.JSON(w, http.StatusOK, models.MyPermissions{
+		Role:         role,
+		TrustLevel:   level,
+		Permissions:  perms,
+		CanPostLinks: slices.Contains(perms, authz.PermChatSend) && trusted(h.app.Config.TrustLevelLinks),
+	})
+}
+
+// RoomPermissions handles GET /api/rooms/{id}/permissions.
+//
+// Returns the caller's role in the room and the room permissions it
+// grants, as the handlers and the Hub check them. Non-members get those
+// of viewers in public rooms and none in private ones; roles with the
+// rooms.moderate permission get the owner's. room.chat also needs the
+// chat.send permission.
+func (h *Handler) RoomPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID := r.PathValue("id")
+	room, err := h.app.RoomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+
+	role, err := h.app.RoomRepo.GetMemberRole(ctx, roomID, middleware.GetUserID(ctx))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
+		return
+	}
+	effective := role
+	if err != nil {
+		effective = authz.NonMemberRoomRole(room.Type)
+	}
+	globalRole := middleware.GetRole(ctx)
+	if h.app.Authz.Can(globalRole, authz.PermRoomsModerate) {
+		effective = models.RoomRoleOwner
+	}
+
+	perms := authz.RoomPermissions(effective)
+	if !h.app.Authz.Can(globalRole, authz.PermChatSend) {
+		perms = slices.DeleteFunc(perms, func(p string) bool { return p == authz.RoomPermChat })
+	}
+
+	response.JSON(w, http.StatusOK, models.RoomPermissions{
+		RoomID:      roomID,
+		Role:        role,
+		Permissions: perms,
+	})
+}
@@ -13,7 +13,7 @@ import (
 func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	var req models.UpdateProfileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
@@ -22,7 +22,7 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.app.UserRepo.GetByID(ctx, userID)
 	if err != nil {
-		response.Error(w, http.StatusNotFound, "user not found")
+		h.fail(w, r, http.StatusNotFound, "user_not_found")
 		return
 	}
 
@@ -31,18 +31,18 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	user.Bio = req.Bio
 
 	if err := h.app.UserRepo.Update(ctx, user); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to update profile")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_profile")
 		return
 	}
 
-	response.JSON(w, http.StatusOK, user)
+	response.JSON(w, http.StatusOK, selfView(user))
 }
 
 // UpdatePreferences handles PUT /api/me/preferences.
 func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
 	var prefs json.RawMessage
 	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid JSON body")
+		h.fail(w, r, http.StatusBadRequest, "invalid_json_body")
 		return
 	}
 
@@ -50,9 +50,9 @@ func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(ctx)
 
 	if err := h.app.UserRepo.UpdatePreferences(ctx, userID, prefs); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to update preferences")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_preferences")
 		return
 	}
 
-	response.JSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	response.NoContent(w)
 }
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"ofenes/internal/middleware"
+	"ofenes/pkg/response"
+)
+
+// MyQuotas handles GET /api/me/quotas.
+//
+// Returns, per resource, how much the caller may own and how much they
+// do, so clients can warn before a create is refused. A limit of 0 means
+// there is none, e.g. for roles with quota.bypass.
+func (h *Handler) MyQuotas(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	usage, err := h.app.Quotas.Usage(ctx, middleware.GetUserID(ctx), middleware.GetRole(ctx))
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_get_quotas")
+		return
+	}
+	response.JSON(w, http.StatusOK, usage)
+}
+
+// checkQuota reports whether the caller may add n of resource (see
+// package quota). If not, it has answered r: 403 quota_exceeded, or
+// internalCode if their usage could not be counted.
+func (h *Handler) checkQuota(w http.ResponseWriter, r *http.Request, resource string, n int64, internalCode string) bool {
+	ctx := r.Context()
+	err := h.app.Quotas.Check(ctx, middleware.GetUserID(ctx), middleware.GetRole(ctx), resource, n)
+	if err != nil {
+		h.failErr(w, r, err, internalCode)
+		return false
+	}
+	return true
+}
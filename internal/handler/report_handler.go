@@ -0,0 +1,480 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/i18n"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/apperr"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// maxReportDetails caps the free-text part of a report.
+const maxReportDetails = 1000
+
+// CreateReport handles POST /api/reports.
+//
+// Any user can report a message, another user or a room. The report is
+// queued for moderators, and every connected admin, plus the owner and
+// moderators of the room concerned, is notified over WebSocket. A user
+// can have only one open report per target (409 otherwise).
+func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if msg := validateReport(req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	reporterID := middleware.GetUserID(ctx)
+	report := &models.Report{
+		ID:         h.app.IDs.New(),
+		ReporterID: reporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		Status:     models.ReportStatusOpen,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+
+	// Resolve the target, which also tells us the room it belongs to.
+	var err error
+	switch req.TargetType {
+	case models.ReportTargetMessage:
+		var msg *models.ChatMessage
+		if msg, err = h.app.MessageRepo.GetByID(ctx, req.TargetID); err == nil {
+			if msg.SenderID == reporterID {
+				h.fail(w, r, http.StatusBadRequest, "cannot_report_your_own_message")
+				return
+			}
+			report.RoomID = msg.RoomID
+		}
+	case models.ReportTargetUser:
+		if req.TargetID == reporterID {
+			h.fail(w, r, http.StatusBadRequest, "cannot_report_yourself")
+			return
+		}
+		_, err = h.app.UserRepo.GetByID(ctx, req.TargetID)
+	case models.ReportTargetRoom:
+		if _, err = h.app.RoomRepo.GetByID(ctx, req.TargetID); err == nil {
+			report.RoomID = req.TargetID
+		}
+	}
+	if err != nil {
+		// {message,user,room}_not_found and failed_to_get_{message,user,room}.
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, req.TargetType+"_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_"+req.TargetType)
+		return
+	}
+
+	open, err := h.app.ReportRepo.List(ctx, repository.ReportFilter{
+		Status:     models.ReportStatusOpen,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		ReporterID: reporterID,
+		Limit:      1,
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_check_reports")
+		return
+	}
+	if len(open) > 0 {
+		h.fail(w, r, http.StatusConflict, "already_reported_"+req.TargetType)
+		return
+	}
+
+	if err := h.app.ReportRepo.Create(ctx, report); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_report")
+		return
+	}
+
+	h.app.Hub.NotifyModerators(models.ModerationEvent{Event: models.ModEventReportCreated, Report: report},
+		h.roomModerators(r, report.RoomID))
+
+	response.Created(w, "/api/admin/reports/"+report.ID, report)
+}
+
+// roomModerators returns the user IDs of the members of roomID whose
+// room role has the room.moderate permission, or nil if roomID is empty.
+// Lookup failures are logged and yield nil, since admins are notified
+// regardless.
+func (h *Handler) roomModerators(r *http.Request, roomID string) []string {
+	if roomID == "" {
+		return nil
+	}
+	members, err := h.app.RoomRepo.GetMembers(r.Context(), roomID)
+	if err != nil {
+		log.Printf("reports: failed to load moderators of room %s: %v", roomID, err)
+		return nil
+	}
+	var ids []string
+	for _, m := range members {
+		if authz.RoomCan(m.Role, authz.RoomPermModerate) {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+// validateReport checks a report request and returns the problem, or a
+// zero Message if it is valid.
+func validateReport(req models.CreateReportRequest) i18n.Message {
+	switch req.TargetType {
+	case models.ReportTargetMessage, models.ReportTargetUser, models.ReportTargetRoom:
+	default:
+		return i18n.Msg("invalid_target_type")
+	}
+	if _, err := uuid.Parse(req.TargetID); err != nil {
+		return i18n.Msg("invalid_target_id")
+	}
+	switch req.Reason {
+	case models.ReportReasonSpam, models.ReportReasonHarassment, models.ReportReasonInappropriate:
+	case models.ReportReasonOther:
+		if req.Details == "" {
+			return i18n.Msg("details_required")
+		}
+	default:
+		return i18n.Msg("invalid_reason")
+	}
+	if utf8.RuneCountInString(req.Details) > maxReportDetails {
+		return i18n.Msg("details_too_long", maxReportDetails)
+	}
+	return i18n.Message{}
+}
+
+// Moderation queue limits.
+const (
+	reportContextSize = 5           // messages shown on each side of a reported message
+	maxMuteMinutes    = 7 * 24 * 60 // one week
+	maxResolutionNote = 1000
+)
+
+// errReportClosed is returned inside ResolveReport's unit of work when
+// another moderator got to the report first.
+var errReportClosed = apperr.New(apperr.Conflict, "report_already_resolved", "report already resolved")
+
+// ListReports handles GET /api/admin/reports (admin only).
+//
+// Query parameters: status=open|resolved|dismissed|all (default open),
+// type, target, room, limit, offset. Newest reports come first. Supports
+// NDJSON streaming like ListUsers.
+func (h *Handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := parsePagination(r)
+	filter := repository.ReportFilter{
+		Status:     q.Get("status"),
+		TargetType: q.Get("type"),
+		TargetID:   q.Get("target"),
+		RoomID:     q.Get("room"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = models.ReportStatusOpen
+	case "all":
+		filter.Status = ""
+	case models.ReportStatusOpen, models.ReportStatusResolved, models.ReportStatusDismissed:
+	default:
+		h.fail(w, r, http.StatusBadRequest, "invalid_report_status_filter")
+		return
+	}
+
+	if response.WantsNDJSON(r) {
+		streamAll(h, w, r, "failed_to_list_reports", offsetPages(offset, func(limit, offset int) ([]*models.Report, error) {
+			filter.Limit, filter.Offset = limit, offset
+			return h.app.ReportRepo.List(r.Context(), filter)
+		}))
+		return
+	}
+
+	reports, err := h.app.ReportRepo.List(r.Context(), filter)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_reports")
+		return
+	}
+	if reports == nil {
+		reports = []*models.Report{}
+	}
+	response.Paginated(w, reports, response.Page(limit, offset, len(reports)))
+}
+
+// GetReport handles GET /api/admin/reports/{id} (admin only).
+//
+// The report comes with its evidence: the reporter, the reported message
+// with up to five messages on each side of it, the reported user (or the
+// message's sender) and the room. Evidence that has since been deleted
+// is left out.
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	report, err := h.app.ReportRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "report_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_report")
+		return
+	}
+
+	detail := models.ReportDetail{Report: report}
+	if detail.Reporter, err = h.app.UserRepo.GetByIDWithDeleted(ctx, report.ReporterID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_reporter")
+		return
+	}
+
+	userID := ""
+	switch report.TargetType {
+	case models.ReportTargetMessage:
+		msg, err := h.app.MessageRepo.GetByID(ctx, report.TargetID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_get_message")
+			return
+		}
+		if msg != nil {
+			detail.Message, userID = msg, msg.SenderID
+			if detail.Context, err = h.messageContext(r, msg); err != nil {
+				h.fail(w, r, http.StatusInternalServerError, "failed_to_get_surrounding_messages")
+				return
+			}
+		}
+	case models.ReportTargetUser:
+		userID = report.TargetID
+	}
+
+	if userID != "" {
+		if detail.User, err = h.app.UserRepo.GetByIDWithDeleted(ctx, userID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_get_user")
+			return
+		}
+	}
+	if report.RoomID != "" {
+		if detail.Room, err = h.app.RoomRepo.GetByID(ctx, report.RoomID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+			return
+		}
+	}
+
+	response.JSON(w, http.StatusOK, detail)
+}
+
+// messageContext returns the messages around msg in its room, oldest
+// first, msg included.
+func (h *Handler) messageContext(r *http.Request, msg *models.ChatMessage) ([]*models.ChatMessage, error) {
+	before, err := h.app.MessageRepo.GetByRoom(r.Context(), msg.RoomID, msg.CreatedAt, reportContextSize)
+	if err != nil {
+		return nil, err
+	}
+	after, err := h.app.MessageRepo.GetByRoomAfter(r.Context(), msg.RoomID, msg.CreatedAt, reportContextSize)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*models.ChatMessage, 0, len(before)+1+len(after))
+	for i := len(before) - 1; i >= 0; i-- {
+		out = append(out, before[i])
+	}
+	out = append(out, msg)
+	return append(out, after...), nil
+}
+
+// ResolveReport handles POST /api/admin/reports/{id}/resolve (admin only).
+//
+// The report is closed as resolved, optionally with an action against
+// the offender (the sender of a reported message, or the reported user):
+//
+//   - delete_message deletes the reported message
+//   - mute blocks the offender's chat messages for muteMinutes (default 60),
+//     on this instance and until it restarts
+//   - ban soft-deletes the offender's account and closes their WebSocket
+//     connections
+//   - shadow_ban shadow-bans the offender (see SetShadowBan)
+//
+// or as dismissed, with no action. The resolution and the action are
+// recorded on the report and in the audit log in one unit of work, and
+// moderators are notified. A report can only be closed once (409).
+func (h *Handler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	var req models.ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if req.Status == "" {
+		req.Status = models.ReportStatusResolved
+	}
+	if req.Action == models.ModActionMute && req.MuteMinutes == 0 {
+		req.MuteMinutes = 60
+	}
+	if msg := validateResolution(req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	actorID := middleware.GetUserID(ctx)
+	report, err := h.app.ReportRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "report_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_report")
+		return
+	}
+	if report.Status != models.ReportStatusOpen {
+		h.fail(w, r, http.StatusConflict, "report_already_resolved")
+		return
+	}
+
+	// Find who the action is against.
+	offenderID := ""
+	switch report.TargetType {
+	case models.ReportTargetMessage:
+		if req.Action != "" {
+			msg, err := h.app.MessageRepo.GetByID(ctx, report.TargetID)
+			if err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					h.fail(w, r, http.StatusNotFound, "reported_message_no_longer_exists")
+					return
+				}
+				h.fail(w, r, http.StatusInternalServerError, "failed_to_get_message")
+				return
+			}
+			offenderID = msg.SenderID
+		}
+	case models.ReportTargetUser:
+		offenderID = report.TargetID
+	}
+	switch {
+	case req.Action == models.ModActionDeleteMessage && report.TargetType != models.ReportTargetMessage:
+		h.fail(w, r, http.StatusBadRequest, "delete_message_requires_message_report")
+		return
+	case req.Action != "" && offenderID == "":
+		h.fail(w, r, http.StatusBadRequest, "action_not_for_room_reports", req.Action)
+		return
+	case req.Action != "" && offenderID == actorID:
+		h.fail(w, r, http.StatusBadRequest, "cannot_take_action_against_yourself")
+		return
+	}
+
+	now := h.app.Clock.Now()
+	res := &models.ReportResolution{Action: req.Action, Note: req.Note, ResolvedBy: actorID, ResolvedAt: now}
+	if req.Action == models.ModActionMute {
+		until := now.Add(time.Duration(req.MuteMinutes) * time.Minute)
+		res.MutedUntil = &until
+	}
+
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Reports.Resolve(ctx, report.ID, req.Status, res); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return errReportClosed
+			}
+			return err
+		}
+
+		// Evidence already gone (deleted message, deleted account) is not
+		// an error: the action's outcome is the same.
+		var err error
+		switch req.Action {
+		case models.ModActionDeleteMessage:
+			err = tx.Messages.Delete(ctx, report.TargetID)
+		case models.ModActionBan:
+			err = tx.Users.Delete(ctx, offenderID)
+		case models.ModActionShadowBan:
+			err = tx.Users.SetShadowBanned(ctx, offenderID, true)
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		details := map[string]any{"status": req.Status}
+		if req.Action != "" {
+			details["action"] = req.Action
+			details["offenderId"] = offenderID
+		}
+		if res.MutedUntil != nil {
+			details["mutedUntil"] = res.MutedUntil
+		}
+		if req.Note != "" {
+			details["note"] = req.Note
+		}
+		entry := &models.AuditEntry{
+			ID:         h.app.IDs.New(),
+			ActorID:    actorID,
+			Action:     models.AuditReportResolve,
+			TargetType: "report",
+			TargetID:   report.ID,
+			CreatedAt:  now,
+		}
+		entry.Details, _ = json.Marshal(details)
+		return tx.Audit.Create(ctx, entry)
+	})
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_resolve_report")
+		return
+	}
+
+	// The Hub only holds connection state, so these follow the commit.
+	switch req.Action {
+	case models.ModActionMute:
+		h.app.Hub.Mute(offenderID, *res.MutedUntil)
+	case models.ModActionBan:
+		h.lockOut(ctx, offenderID)
+	case models.ModActionShadowBan:
+		h.app.Hub.SetShadowBanned(offenderID, true)
+	}
+
+	report.Status, report.Resolution = req.Status, res
+	h.app.Hub.NotifyModerators(models.ModerationEvent{Event: models.ModEventReportResolved, Report: report},
+		h.roomModerators(r, report.RoomID))
+
+	response.JSON(w, http.StatusOK, report)
+}
+
+// validateResolution checks a resolution request (with defaults applied)
+// and returns the problem, or a zero Message if it is valid.
+func validateResolution(req models.ResolveReportRequest) i18n.Message {
+	switch req.Status {
+	case models.ReportStatusResolved:
+	case models.ReportStatusDismissed:
+		if req.Action != "" {
+			return i18n.Msg("dismissed_with_action")
+		}
+	default:
+		return i18n.Msg("invalid_resolution_status")
+	}
+	switch req.Action {
+	case "", models.ModActionDeleteMessage, models.ModActionBan, models.ModActionShadowBan:
+		if req.MuteMinutes != 0 {
+			return i18n.Msg("mute_minutes_without_mute")
+		}
+	case models.ModActionMute:
+		if req.MuteMinutes < 1 || req.MuteMinutes > maxMuteMinutes {
+			return i18n.Msg("invalid_mute_minutes", maxMuteMinutes)
+		}
+	default:
+		return i18n.Msg("invalid_mod_action")
+	}
+	if utf8.RuneCountInString(req.Note) > maxResolutionNote {
+		return i18n.Msg("note_too_long", maxResolutionNote)
+	}
+	return i18n.Message{}
+}
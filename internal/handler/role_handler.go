@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/i18n"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+// roleNamePattern is what custom role names may look like.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// ListRoles handles GET /api/admin/roles (roles.manage).
+//
+// Returns the built-in roles, then the custom ones by name.
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	custom, err := h.app.RoleRepo.List(r.Context())
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_roles")
+		return
+	}
+	roles := append(authz.BuiltIn(), custom...)
+	for _, role := range roles {
+		if role.Permissions == nil {
+			role.Permissions = []string{}
+		}
+	}
+	response.JSON(w, http.StatusOK, roles)
+}
+
+// ListPermissions handles GET /api/admin/permissions (roles.manage).
+//
+// Returns every permission a role can grant.
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, authz.All)
+}
+
+// CreateRole handles POST /api/admin/roles (roles.manage).
+//
+// Request: { "name": "streamer", "description": "...", "permissions": ["chat.send", ...] }
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !roleNamePattern.MatchString(req.Name) {
+		h.fail(w, r, http.StatusBadRequest, "invalid_role_name")
+		return
+	}
+	if authz.IsBuiltIn(req.Name) {
+		h.fail(w, r, http.StatusConflict, "role_exists")
+		return
+	}
+	if msg := validateRole(req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	now := h.app.Clock.Now()
+	role := &models.RoleDefinition{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: normalizePermissions(req.Permissions),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	actorID := middleware.GetUserID(ctx)
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Roles.Create(ctx, role); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.roleAuditEntry(actorID, models.AuditRoleCreate, role))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.fail(w, r, http.StatusConflict, "role_exists")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_role")
+		return
+	}
+	h.reloadRoles(r)
+
+	response.Created(w, "/api/admin/roles/"+role.Name, role)
+}
+
+// UpdateRole handles PUT /api/admin/roles/{name} (roles.manage).
+//
+// Replaces the description and permissions of a custom role; built-in
+// roles cannot be changed. Users with the role get the new permissions on
+// their next request.
+//
+// Request: { "description": "...", "permissions": ["chat.send", ...] }
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if authz.IsBuiltIn(name) {
+		h.fail(w, r, http.StatusForbidden, "role_built_in")
+		return
+	}
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if msg := validateRole(req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	role, err := h.app.RoleRepo.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "role_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_role")
+		return
+	}
+	role.Description = req.Description
+	role.Permissions = normalizePermissions(req.Permissions)
+	role.UpdatedAt = h.app.Clock.Now()
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Roles.Update(ctx, role); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.roleAuditEntry(actorID, models.AuditRoleUpdate, role))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "role_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_role")
+		return
+	}
+	h.reloadRoles(r)
+
+	response.JSON(w, http.StatusOK, role)
+}
+
+// DeleteRole handles DELETE /api/admin/roles/{name} (roles.manage).
+//
+// Only custom roles nobody has can be deleted (409 role_in_use otherwise;
+// soft-deleted users count).
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if authz.IsBuiltIn(name) {
+		h.fail(w, r, http.StatusForbidden, "role_built_in")
+		return
+	}
+
+	ctx := r.Context()
+	role, err := h.app.RoleRepo.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "role_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_role")
+		return
+	}
+	counts, err := h.app.UserRepo.CountByRole(ctx, repository.UserFilter{IncludeDeleted: true})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_role")
+		return
+	}
+	if n := counts[name]; n > 0 {
+		h.fail(w, r, http.StatusConflict, "role_in_use", n)
+		return
+	}
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Roles.Delete(ctx, name); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.roleAuditEntry(actorID, models.AuditRoleDelete, role))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "role_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_role")
+		return
+	}
+	h.reloadRoles(r)
+
+	response.NoContent(w)
+}
+
+// SetUserRole handles PUT /api/admin/users/{id}/role (users.manage).
+//
+// Assigns a built-in or custom role. Like trust level changes, the new
+// role takes effect once the user refreshes their token or logs in again.
+// With LDAP logins, directory users get their mapped role back on their
+// next login.
+//
+// Request: { "role": "moderator" }
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var req models.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !h.app.Authz.Exists(req.Role) {
+		h.fail(w, r, http.StatusBadRequest, "unknown_role", req.Role)
+		return
+	}
+
+	ctx := r.Context()
+	userID := r.PathValue("id")
+	actorID := middleware.GetUserID(ctx)
+	if userID == actorID {
+		h.fail(w, r, http.StatusBadRequest, "cannot_change_own_role")
+		return
+	}
+	user, err := h.app.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "user_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_user")
+		return
+	}
+
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Users.SetRole(ctx, userID, req.Role); err != nil {
+			return err
+		}
+		details := map[string]string{"from": user.Role, "to": req.Role}
+		return tx.Audit.Create(ctx, h.userAuditEntry(actorID, models.AuditUserRole, userID, details))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "user_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_role")
+		return
+	}
+	user.Role = req.Role
+
+	response.JSON(w, http.StatusOK, user)
+}
+
+// validateRole checks the permissions of a role request and returns the
+// problem, or a zero Message if they are valid.
+func validateRole(req models.RoleRequest) i18n.Message {
+	if len(req.Description) > 200 {
+		return i18n.Msg("role_description_too_long", 200)
+	}
+	for _, p := range req.Permissions {
+		if !authz.ValidPermission(p) {
+			return i18n.Msg("unknown_permission", p)
+		}
+	}
+	return i18n.Message{}
+}
+
+// normalizePermissions returns perms without duplicates, in the order of
+// authz.All.
+func normalizePermissions(perms []string) []string {
+	granted := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		granted[p] = true
+	}
+	out := []string{}
+	for _, p := range authz.All {
+		if granted[p] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// reloadRoles swaps the stored roles into the authorizer after a change.
+// As with allowed origins, a failure is only logged; the periodic reload
+// retries it.
+func (h *Handler) reloadRoles(r *http.Request) {
+	if err := h.app.Authz.Reload(r.Context(), h.app.RoleRepo); err != nil {
+		log.Printf("roles: reload failed: %v", err)
+	}
+}
+
+// roleAuditEntry builds an audit entry for a change to role, recording the
+// role itself as the details.
+func (h *Handler) roleAuditEntry(actorID, action string, role *models.RoleDefinition) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "role",
+		TargetID:   role.Name,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(role)
+	return entry
+}
@@ -4,63 +4,93 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
-	"time"
+	"strings"
+	"unicode/utf8"
 
+	"ofenes/internal/authz"
+	"ofenes/internal/i18n"
 	"ofenes/internal/middleware"
 	"ofenes/internal/models"
+	"ofenes/internal/quota"
 	"ofenes/internal/repository"
 	"ofenes/pkg/response"
-
-	"github.com/google/uuid"
 )
 
 // CreateRoom handles POST /api/rooms.
 func (h *Handler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateRoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
 	if req.Name == "" {
-		response.Error(w, http.StatusBadRequest, "name is required")
+		h.fail(w, r, http.StatusBadRequest, "name_required")
 		return
 	}
 	if req.Type == "" {
 		req.Type = models.RoomTypePublic
 	}
+	maxSize := h.roomSizeLimit(r)
 	if req.MaxMembers <= 0 {
 		req.MaxMembers = 50
+		if maxSize > 0 {
+			req.MaxMembers = min(req.MaxMembers, maxSize)
+		}
+	}
+	if maxSize > 0 && req.MaxMembers > maxSize {
+		h.fail(w, r, http.StatusForbidden, "room_too_large", maxSize)
+		return
+	}
+	if req.Retention != nil {
+		if msg := validateRetention(*req.Retention); msg.Code != "" {
+			h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+			return
+		}
+	}
+	tags, msg := normalizeRoomTags(req.Tags)
+	if msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+	if !h.checkQuota(w, r, quota.Rooms, 1, "failed_to_create_room") {
+		return
 	}
 
 	userID := middleware.GetUserID(r.Context())
-	now := time.Now()
+	now := h.app.Clock.Now()
 
 	room := &models.Room{
-		ID:        uuid.New().String(),
+		ID:        h.app.IDs.New(),
 		Name:      req.Name,
 		Description: req.Description,
 		Type:      req.Type,
 		CreatedBy: userID,
 		IsActive:  true,
 		MaxMembers: req.MaxMembers,
+		Retention: req.Retention,
+		Tags:      tags,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	if err := h.app.RoomRepo.Create(r.Context(), room); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to create room")
-		return
-	}
-
-	// Add the creator as owner
-	if err := h.app.RoomRepo.AddMember(r.Context(), room.ID, userID, models.RoomRoleOwner); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to add creator as member")
+	// Create the room and add the creator as owner atomically
+	err := h.app.Tx.WithinTx(r.Context(), func(tx repository.Repos) error {
+		if err := tx.Rooms.Create(r.Context(), room); err != nil {
+			return err
+		}
+		return tx.Rooms.AddMember(r.Context(), room.ID, userID, models.RoomRoleOwner)
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_room")
 		return
 	}
+	h.app.Stats.RoomCreated()
 
-	response.JSON(w, http.StatusCreated, room)
+	response.Created(w, "/api/rooms/"+room.ID, room)
 }
 
 // ListRooms handles GET /api/rooms.
@@ -71,47 +101,119 @@ func (h *Handler) ListRooms(w http.ResponseWriter, r *http.Request) {
 
 	rooms, err := h.app.RoomRepo.List(r.Context(), userID, limit, offset)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to list rooms")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_rooms")
 		return
 	}
 	if rooms == nil {
 		rooms = []*models.Room{}
 	}
 
-	response.JSON(w, http.StatusOK, rooms)
+	response.Paginated(w, rooms, response.Page(limit, offset, len(rooms)))
 }
 
 // ListPublicRooms handles GET /api/rooms/public.
+// ?tag= narrows it to the rooms carrying that tag.
 func (h *Handler) ListPublicRooms(w http.ResponseWriter, r *http.Request) {
 	limit, offset := parsePagination(r)
+	tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
 
-	rooms, err := h.app.RoomRepo.ListPublic(r.Context(), limit, offset)
+	rooms, err := h.app.RoomRepo.ListPublic(r.Context(), tag, limit, offset)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to list rooms")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_rooms")
 		return
 	}
 	if rooms == nil {
 		rooms = []*models.Room{}
 	}
 
-	response.JSON(w, http.StatusOK, rooms)
+	response.Paginated(w, rooms, response.Page(limit, offset, len(rooms)))
+}
+
+const (
+	// maxRoomTags bounds the tags of a room, and maxRoomTagLength each
+	// tag, in characters.
+	maxRoomTags, maxRoomTagLength = 5, 30
+
+	// trendingTagDays is the default window of GET /api/rooms/tags, and
+	// maxTrendingTagDays the largest one asked for.
+	trendingTagDays, maxTrendingTagDays = 7, 90
+
+	// trendingTagLimit is the default number of tags GET /api/rooms/tags
+	// returns, and maxTrendingTagLimit the most.
+	trendingTagLimit, maxTrendingTagLimit = 20, 100
+)
+
+// ListTrendingTags handles GET /api/rooms/tags, for the lobby page.
+// Returns the tags of active public rooms created in the last ?days=
+// (default 7, at most 90), each with how many of them carry it, most
+// used first; ?limit= caps the list (default 20, at most 100).
+func (h *Handler) ListTrendingTags(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	days := trendingTagDays
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			h.fail(w, r, http.StatusBadRequest, "invalid_days")
+			return
+		}
+		days = min(n, maxTrendingTagDays)
+	}
+	limit := trendingTagLimit
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = min(n, maxTrendingTagLimit)
+		}
+	}
+
+	since := h.app.Clock.Now().AddDate(0, 0, -days)
+	counts, err := h.app.RoomRepo.TagCounts(r.Context(), since, limit)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_tags")
+		return
+	}
+	if counts == nil {
+		counts = []models.TagCount{}
+	}
+	response.JSON(w, http.StatusOK, counts)
+}
+
+// normalizeRoomTags lowercases, deduplicates and sorts tags, returning
+// the problem instead if any is invalid or there are too many.
+func normalizeRoomTags(tags []string) ([]string, i18n.Message) {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !libraryTag.MatchString(tag) || utf8.RuneCountInString(tag) > maxRoomTagLength {
+			return nil, i18n.Msg("invalid_room_tag", tag, maxRoomTagLength)
+		}
+		out = append(out, tag)
+	}
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if len(out) > maxRoomTags {
+		return nil, i18n.Msg("too_many_room_tags", maxRoomTags)
+	}
+	if len(out) == 0 {
+		return nil, i18n.Message{}
+	}
+	return out, i18n.Message{}
 }
 
 // GetRoom handles GET /api/rooms/{id}.
 func (h *Handler) GetRoom(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
 	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			response.Error(w, http.StatusNotFound, "room not found")
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
 			return
 		}
-		response.Error(w, http.StatusInternalServerError, "failed to get room")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
 		return
 	}
 
@@ -122,27 +224,24 @@ func (h *Handler) GetRoom(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateRoom(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
-	// Check membership + role
-	userID := middleware.GetUserID(r.Context())
-	role, err := h.app.RoomRepo.GetMemberRole(r.Context(), roomID, userID)
-	if err != nil || (role != models.RoomRoleOwner && role != models.RoomRoleModerator) {
-		response.Error(w, http.StatusForbidden, "insufficient permissions")
+	if !h.roomCan(r, roomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
 		return
 	}
 
 	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
 	if err != nil {
-		response.Error(w, http.StatusNotFound, "room not found")
+		h.fail(w, r, http.StatusNotFound, "room_not_found")
 		return
 	}
 
 	var req models.UpdateRoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
@@ -153,11 +252,23 @@ func (h *Handler) UpdateRoom(w http.ResponseWriter, r *http.Request) {
 		room.Description = req.Description
 	}
 	if req.MaxMembers != nil {
+		if maxSize := h.roomSizeLimit(r); maxSize > 0 && *req.MaxMembers > maxSize {
+			h.fail(w, r, http.StatusForbidden, "room_too_large", maxSize)
+			return
+		}
 		room.MaxMembers = *req.MaxMembers
 	}
+	if req.Tags != nil {
+		tags, msg := normalizeRoomTags(*req.Tags)
+		if msg.Code != "" {
+			h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+			return
+		}
+		room.Tags = tags
+	}
 
 	if err := h.app.RoomRepo.Update(r.Context(), room); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to update room")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_room")
 		return
 	}
 
@@ -168,85 +279,507 @@ func (h *Handler) UpdateRoom(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteRoom(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
-	userID := middleware.GetUserID(r.Context())
-	role, err := h.app.RoomRepo.GetMemberRole(r.Context(), roomID, userID)
-	if err != nil || role != models.RoomRoleOwner {
-		response.Error(w, http.StatusForbidden, "only the room owner can delete")
+	if !h.roomCan(r, roomID, authz.RoomPermDelete) {
+		h.fail(w, r, http.StatusForbidden, "only_owner_can_delete_room")
 		return
 	}
 
 	if err := h.app.RoomRepo.Delete(r.Context(), roomID); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to delete room")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_room")
 		return
 	}
 
-	response.JSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	response.NoContent(w)
 }
 
 // JoinRoom handles POST /api/rooms/{id}/join.
+// Anyone may join a public room as a member; private and direct rooms
+// need an invitation (see AddRoomMember).
 func (h *Handler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
 	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			response.Error(w, http.StatusNotFound, "room not found")
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
 			return
 		}
-		response.Error(w, http.StatusInternalServerError, "failed to get room")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
 		return
 	}
 
 	if !room.IsActive {
-		response.Error(w, http.StatusGone, "room is no longer active")
+		h.fail(w, r, http.StatusGone, "room_inactive")
 		return
 	}
 
 	userID := middleware.GetUserID(r.Context())
+	if _, err := h.app.RoomRepo.GetMemberRole(r.Context(), roomID, userID); err == nil {
+		response.NoContent(w) // already a member; keep the current role
+		return
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_join_room")
+		return
+	}
+	if room.Type != models.RoomTypePublic {
+		h.fail(w, r, http.StatusForbidden, "room_invite_required")
+		return
+	}
+
 	if err := h.app.RoomRepo.AddMember(r.Context(), roomID, userID, models.RoomRoleMember); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to join room")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_join_room")
 		return
 	}
+	h.app.Hub.SetRoomRole(roomID, userID, models.RoomRoleMember)
 
-	response.JSON(w, http.StatusOK, map[string]string{"status": "joined"})
+	response.NoContent(w)
 }
 
 // LeaveRoom handles POST /api/rooms/{id}/leave.
 func (h *Handler) LeaveRoom(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
 		return
 	}
 
 	userID := middleware.GetUserID(r.Context())
 	if err := h.app.RoomRepo.RemoveMember(r.Context(), roomID, userID); err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to leave room")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_leave_room")
 		return
 	}
 
-	response.JSON(w, http.StatusOK, map[string]string{"status": "left"})
+	// Connections stay open in public rooms, as a viewer.
+	h.app.Hub.SetRoomRole(roomID, userID, authz.NonMemberRoomRole(room.Type))
+
+	response.NoContent(w)
+}
+
+// GetRoomAnalytics handles GET /api/rooms/{id}/analytics.
+// Only room members with the room.moderate permission may read it.
+func (h *Handler) GetRoomAnalytics(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if roomID == "" {
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+
+	if !h.roomCan(r, roomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	if h.app.Analytics == nil {
+		h.fail(w, r, http.StatusNotFound, "analytics_disabled")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, h.app.Analytics.Room(roomID))
+}
+
+const (
+	// recapPageSize is how many messages the recap reads at a time.
+	recapPageSize = 500
+
+	// maxRecapMessages bounds the messages a recap reads; counts stop there.
+	maxRecapMessages = 20000
+
+	// recapChatters is how many top chatters a recap lists.
+	recapChatters = 5
+)
+
+// GetRoomRecap handles GET /api/rooms/{id}/recap.
+//
+// Summarizes the room's last finished watch party, from the first viewer
+// joining the empty room until the last one left, for those who may
+// watch it: when and how long, peak attendance, the videos played and
+// chat activity. Parties come from the analytics tracker, so recaps need
+// ANALYTICS_ENABLED and last as long as ANALYTICS_HISTORY_MS; viewers
+// who opted out of analytics are not counted.
+func (h *Handler) GetRoomRecap(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	ctx := r.Context()
+	room, err := h.app.RoomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	if h.app.Analytics == nil {
+		h.fail(w, r, http.StatusNotFound, "analytics_disabled")
+		return
+	}
+	party, ok := h.app.Analytics.LastParty(roomID)
+	if !ok {
+		h.fail(w, r, http.StatusNotFound, "no_recap")
+		return
+	}
+
+	recap := models.RoomRecap{
+		RoomID:          roomID,
+		StartedAt:       party.Start,
+		EndedAt:         party.End,
+		DurationSeconds: party.End.Sub(party.Start).Seconds(),
+		PeakViewers:     party.PeakViewers,
+		Sessions:        party.Sessions,
+		SyncCorrections: party.SyncCorrections,
+		Videos:          make([]models.RecapVideo, 0, len(party.Videos)),
+		TopChatters:     []models.RecapChatter{},
+	}
+	for _, v := range party.Videos {
+		recap.Videos = append(recap.Videos, models.RecapVideo{URL: v.URL, WatchSeconds: v.WatchSeconds})
+	}
+
+	chatters := make(map[string]*models.RecapChatter)
+	after, read := party.Start, 0
+	for read < maxRecapMessages {
+		page, err := h.app.MessageRepo.GetByRoomAfter(ctx, roomID, after, recapPageSize)
+		if err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_get_messages")
+			return
+		}
+		for _, m := range page {
+			if m.CreatedAt.After(party.End) {
+				break
+			}
+			if m.Type != models.MsgTypeChat {
+				continue
+			}
+			recap.Messages++
+			c, ok := chatters[m.SenderID]
+			if !ok {
+				c = &models.RecapChatter{UserID: m.SenderID, Username: m.Sender}
+				chatters[m.SenderID] = c
+			}
+			c.Messages++
+		}
+		read += len(page)
+		if len(page) < recapPageSize || page[len(page)-1].CreatedAt.After(party.End) {
+			break
+		}
+		after = page[len(page)-1].CreatedAt
+	}
+	for _, c := range chatters {
+		recap.TopChatters = append(recap.TopChatters, *c)
+	}
+	slices.SortFunc(recap.TopChatters, func(a, b models.RecapChatter) int {
+		if a.Messages != b.Messages {
+			return b.Messages - a.Messages
+		}
+		return strings.Compare(a.Username, b.Username)
+	})
+	if len(recap.TopChatters) > recapChatters {
+		recap.TopChatters = recap.TopChatters[:recapChatters]
+	}
+
+	response.JSON(w, http.StatusOK, recap)
+}
+
+// UpdateRoomRetention handles PUT /api/rooms/{id}/retention.
+// Sets the room's message retention policy; room members with the
+// room.moderate permission and site moderators may change it.
+func (h *Handler) UpdateRoomRetention(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if roomID == "" {
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+	if !h.roomCan(r, roomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var policy models.RetentionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if msg := validateRetention(policy); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	h.setRoomRetention(w, r, roomID, &policy)
+}
+
+// ResetRoomRetention handles DELETE /api/rooms/{id}/retention.
+// Returns the room to the server's default retention policy.
+func (h *Handler) ResetRoomRetention(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if roomID == "" {
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+	if !h.roomCan(r, roomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	h.setRoomRetention(w, r, roomID, nil)
+}
+
+// setRoomRetention stores policy and responds with the updated room.
+func (h *Handler) setRoomRetention(w http.ResponseWriter, r *http.Request, roomID string, policy *models.RetentionPolicy) {
+	if err := h.app.RoomRepo.UpdateRetention(r.Context(), roomID, policy); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_retention")
+		return
+	}
+
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	response.JSON(w, http.StatusOK, room)
+}
+
+// roomCan reports whether the caller's role in roomID grants the room
+// permission perm (authz.RoomPerm*).
+func (h *Handler) roomCan(r *http.Request, roomID, perm string) bool {
+	return authz.RoomCan(h.callerRoomRole(r, roomID), perm)
+}
+
+// callerRoomRole returns the caller's role in roomID, or "" if they are
+// not a member (or it cannot be looked up). Callers whose global role has
+// the rooms.moderate permission act as the room's owner.
+func (h *Handler) callerRoomRole(r *http.Request, roomID string) string {
+	if h.app.Authz.Can(middleware.GetRole(r.Context()), authz.PermRoomsModerate) {
+		return models.RoomRoleOwner
+	}
+	role, err := h.app.RoomRepo.GetMemberRole(r.Context(), roomID, middleware.GetUserID(r.Context()))
+	if err != nil {
+		return ""
+	}
+	return role
+}
+
+// validateRetention returns the problem, or a zero Message if the policy is valid.
+func validateRetention(p models.RetentionPolicy) i18n.Message {
+	switch p.Mode {
+	case models.RetentionForever, models.RetentionOnClose:
+		if p.Days != 0 {
+			return i18n.Msg("days_requires_days_mode")
+		}
+	case models.RetentionDays:
+		if p.Days <= 0 {
+			return i18n.Msg("days_not_positive")
+		}
+	default:
+		return i18n.Msg("invalid_retention_mode")
+	}
+	return i18n.Message{}
+}
+
+// Bounds of a room's sync tolerance: looser than maxSyncToleranceMs and
+// members may as well be watching alone.
+const (
+	minSyncToleranceMs = 100
+	maxSyncToleranceMs = 10000
+)
+
+// UpdateRoomSync handles PUT /api/rooms/{id}/sync.
+// Sets how far the room's players may drift before the server corrects
+// them; room members with the video.control permission and site
+// moderators may change it.
+func (h *Handler) UpdateRoomSync(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if roomID == "" {
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+	if !h.roomCan(r, roomID, authz.RoomPermVideoControl) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var policy models.SyncPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if msg := validateSyncPolicy(policy); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	h.setRoomSync(w, r, roomID, &policy)
+}
+
+// ResetRoomSync handles DELETE /api/rooms/{id}/sync.
+// Returns the room to the server's default sync tolerance.
+func (h *Handler) ResetRoomSync(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if roomID == "" {
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+	if !h.roomCan(r, roomID, authz.RoomPermVideoControl) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	h.setRoomSync(w, r, roomID, nil)
+}
+
+// setRoomSync stores policy, applies it to the room's open connections
+// and responds with the updated room.
+func (h *Handler) setRoomSync(w http.ResponseWriter, r *http.Request, roomID string, policy *models.SyncPolicy) {
+	if err := h.app.RoomRepo.UpdateSyncPolicy(r.Context(), roomID, policy); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_sync")
+		return
+	}
+	h.app.Hub.SetRoomSyncPolicy(roomID, policy)
+
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	response.JSON(w, http.StatusOK, room)
+}
+
+// validateSyncPolicy returns the problem, or a zero Message if the policy
+// is valid. A tolerance of 0 turns corrections off in the room.
+func validateSyncPolicy(p models.SyncPolicy) i18n.Message {
+	if p.ToleranceMs != 0 && (p.ToleranceMs < minSyncToleranceMs || p.ToleranceMs > maxSyncToleranceMs) {
+		return i18n.Msg("invalid_sync_tolerance", minSyncToleranceMs, maxSyncToleranceMs)
+	}
+	if p.NudgeMs < 0 || (p.NudgeMs > 0 && p.NudgeMs >= p.ToleranceMs) {
+		return i18n.Msg("invalid_sync_nudge")
+	}
+	switch p.PauseOnDisconnect {
+	case "", models.PauseOnHost, models.PauseOnEveryone:
+	default:
+		return i18n.Msg("invalid_pause_on_disconnect")
+	}
+	return i18n.Message{}
+}
+
+const (
+	// maxScreens is how many screens a room may have besides its main one.
+	maxScreens = 4
+
+	// maxScreenNameLen bounds a screen's display name, in bytes.
+	maxScreenNameLen = 64
+)
+
+// screenIDPattern is what a screen ID looks like: it names the screen in
+// video_sync payloads.
+var screenIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// UpdateRoomScreens handles PUT /api/rooms/{id}/screens.
+// Replaces the room's screens besides the main one; an empty list leaves
+// it only the main one.
+func (h *Handler) UpdateRoomScreens(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if roomID == "" {
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
+		return
+	}
+	if !h.roomCan(r, roomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var req struct {
+		Screens []models.Screen `json:"screens"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if msg := validateScreens(req.Screens); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	if err := h.app.RoomRepo.UpdateScreens(r.Context(), roomID, req.Screens); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_screens")
+		return
+	}
+	h.app.Hub.SetRoomScreens(roomID, req.Screens)
+
+	room, err := h.app.RoomRepo.GetByID(r.Context(), roomID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	response.JSON(w, http.StatusOK, room)
+}
+
+// validateScreens returns the problem, or a zero Message if the screens
+// are valid.
+func validateScreens(screens []models.Screen) i18n.Message {
+	if len(screens) > maxScreens {
+		return i18n.Msg("too_many_screens", maxScreens)
+	}
+	seen := make(map[string]bool, len(screens))
+	for _, s := range screens {
+		if !screenIDPattern.MatchString(s.ID) {
+			return i18n.Msg("invalid_screen_id")
+		}
+		if seen[s.ID] {
+			return i18n.Msg("duplicate_screen_id", s.ID)
+		}
+		seen[s.ID] = true
+		if len(s.Name) > maxScreenNameLen {
+			return i18n.Msg("invalid_screen_name", maxScreenNameLen)
+		}
+		if s.Control != "" && !slices.Contains(authz.RoomPermissionsAll, s.Control) {
+			return i18n.Msg("invalid_screen_control", s.Control)
+		}
+	}
+	return i18n.Message{}
 }
 
 // GetRoomMembers handles GET /api/rooms/{id}/members.
 func (h *Handler) GetRoomMembers(w http.ResponseWriter, r *http.Request) {
 	roomID := r.PathValue("id")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room id")
+		h.fail(w, r, http.StatusBadRequest, "missing_room_id")
 		return
 	}
 
 	members, err := h.app.RoomRepo.GetMembers(r.Context(), roomID)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, "failed to get members")
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
 		return
 	}
 	if members == nil {
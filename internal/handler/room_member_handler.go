@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/quota"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+// AddRoomMember handles POST /api/rooms/{id}/members (room.invite).
+//
+// Invites a user into the room as a member or viewer; this is how users
+// get into private rooms. Higher roles are granted with SetRoomMemberRole.
+// A user already connected to the room gets the role at once.
+//
+// Request: { "userId": "...", "role": "viewer" }
+func (h *Handler) AddRoomMember(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if !h.roomCan(r, roomID, authz.RoomPermInvite) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var req models.AddRoomMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if req.UserID == "" {
+		h.fail(w, r, http.StatusBadRequest, "user_id_required")
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoomRoleMember
+	}
+	if req.Role != models.RoomRoleMember && req.Role != models.RoomRoleViewer {
+		h.fail(w, r, http.StatusBadRequest, "invalid_invite_role", req.Role)
+		return
+	}
+
+	ctx := r.Context()
+	room, err := h.app.RoomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !room.IsActive {
+		h.fail(w, r, http.StatusGone, "room_inactive")
+		return
+	}
+	user, err := h.app.UserRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "user_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_user")
+		return
+	}
+	if _, err := h.app.RoomRepo.GetMemberRole(ctx, roomID, user.ID); err == nil {
+		h.fail(w, r, http.StatusConflict, "already_room_member")
+		return
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_add_member")
+		return
+	}
+
+	if err := h.app.RoomRepo.AddMember(ctx, roomID, user.ID, req.Role); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_add_member")
+		return
+	}
+	h.app.Hub.SetRoomRole(roomID, user.ID, req.Role)
+
+	member, err := h.roomMember(r, roomID, user.ID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
+		return
+	}
+	response.Created(w, "/api/rooms/"+roomID+"/members", member)
+}
+
+// SetRoomMemberRole handles PUT /api/rooms/{id}/members/{userId}/role
+// (room.roles).
+//
+// Changes a member's room role. Callers may only change the roles of
+// members ranked below themselves, to roles ranked below their own, so a
+// co-host can appoint moderators but only the owner can appoint co-hosts.
+// Ownership itself cannot be assigned here. The member's open connections
+// get the new role at once.
+//
+// Request: { "role": "cohost" }
+func (h *Handler) SetRoomMemberRole(w http.ResponseWriter, r *http.Request) {
+	roomID, targetID := r.PathValue("id"), r.PathValue("userId")
+	callerRole := h.callerRoomRole(r, roomID)
+	if !authz.RoomCan(callerRole, authz.RoomPermAssignRoles) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var req models.SetRoomRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if !authz.ValidRoomRole(req.Role) || req.Role == models.RoomRoleOwner {
+		h.fail(w, r, http.StatusBadRequest, "invalid_room_role", req.Role)
+		return
+	}
+	if targetID == middleware.GetUserID(r.Context()) {
+		h.fail(w, r, http.StatusBadRequest, "cannot_change_own_room_role")
+		return
+	}
+
+	ctx := r.Context()
+	current, err := h.app.RoomRepo.GetMemberRole(ctx, roomID, targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_member_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
+		return
+	}
+	rank := authz.RoomRoleRank(callerRole)
+	if authz.RoomRoleRank(current) >= rank || authz.RoomRoleRank(req.Role) >= rank {
+		h.fail(w, r, http.StatusForbidden, "room_role_outranks_you")
+		return
+	}
+
+	if err := h.app.RoomRepo.SetMemberRole(ctx, roomID, targetID, req.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_member_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_room_role")
+		return
+	}
+	h.app.Hub.SetRoomRole(roomID, targetID, req.Role)
+
+	member, err := h.roomMember(r, roomID, targetID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
+		return
+	}
+	response.JSON(w, http.StatusOK, member)
+}
+
+// RemoveRoomMember handles DELETE /api/rooms/{id}/members/{userId}
+// (room.moderate).
+//
+// Removes a member ranked below the caller from the room and closes their
+// connections to it with ws.CloseKicked. Members leave on their own with
+// LeaveRoom.
+func (h *Handler) RemoveRoomMember(w http.ResponseWriter, r *http.Request) {
+	roomID, targetID := r.PathValue("id"), r.PathValue("userId")
+	callerRole := h.callerRoomRole(r, roomID)
+	if !authz.RoomCan(callerRole, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	if targetID == middleware.GetUserID(r.Context()) {
+		h.fail(w, r, http.StatusBadRequest, "cannot_remove_yourself")
+		return
+	}
+
+	ctx := r.Context()
+	current, err := h.app.RoomRepo.GetMemberRole(ctx, roomID, targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_member_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
+		return
+	}
+	if authz.RoomRoleRank(current) >= authz.RoomRoleRank(callerRole) {
+		h.fail(w, r, http.StatusForbidden, "room_role_outranks_you")
+		return
+	}
+
+	if err := h.app.RoomRepo.RemoveMember(ctx, roomID, targetID); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_remove_member")
+		return
+	}
+	h.app.Hub.SetRoomRole(roomID, targetID, "")
+
+	response.NoContent(w)
+}
+
+// TransferRoomOwnership handles POST /api/rooms/{id}/transfer-ownership.
+//
+// Makes another member the room's owner; the previous owner stays on as a
+// co-host. Only the owner may do this, or a caller with the rooms.moderate
+// permission on the owner's behalf, and only to a member with room left in
+// their rooms quota. Open connections follow at once, and the room is told
+// of its new host.
+//
+// Request: { "userId": "..." }
+func (h *Handler) TransferRoomOwnership(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	if h.callerRoomRole(r, roomID) != models.RoomRoleOwner {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	if req.UserID == "" {
+		h.fail(w, r, http.StatusBadRequest, "user_id_required")
+		return
+	}
+
+	ctx := r.Context()
+	members, err := h.app.RoomRepo.GetMembers(ctx, roomID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_members")
+		return
+	}
+	var owner, target *models.RoomMember
+	for _, m := range members {
+		switch {
+		case m.UserID == req.UserID:
+			target = m
+		case m.Role == models.RoomRoleOwner:
+			owner = m
+		}
+	}
+	if target == nil {
+		h.fail(w, r, http.StatusNotFound, "room_member_not_found")
+		return
+	}
+	if target.Role == models.RoomRoleOwner {
+		h.fail(w, r, http.StatusBadRequest, "already_room_owner")
+		return
+	}
+	user, err := h.app.UserRepo.GetByID(ctx, target.UserID)
+	if err != nil {
+		h.fail(w, r, http.StatusNotFound, "user_not_found")
+		return
+	}
+	if err := h.app.Quotas.Check(ctx, user.ID, user.Role, quota.Rooms, 1); err != nil {
+		if errors.Is(err, quota.ErrExceeded) {
+			h.fail(w, r, http.StatusForbidden, "new_owner_quota_exceeded")
+			return
+		}
+		h.failErr(w, r, err, "failed_to_transfer_ownership")
+		return
+	}
+
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.Rooms.SetMemberRole(ctx, roomID, target.UserID, models.RoomRoleOwner); err != nil {
+			return err
+		}
+		if owner == nil {
+			return nil
+		}
+		return tx.Rooms.SetMemberRole(ctx, roomID, owner.UserID, models.RoomRoleCoHost)
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_transfer_ownership")
+		return
+	}
+	// Promote first, so the Hub never sees the room without an owner.
+	h.app.Hub.SetRoomRole(roomID, target.UserID, models.RoomRoleOwner)
+	if owner != nil {
+		h.app.Hub.SetRoomRole(roomID, owner.UserID, models.RoomRoleCoHost)
+	}
+
+	target.Role = models.RoomRoleOwner
+	response.JSON(w, http.StatusOK, target)
+}
+
+// roomMember returns userID's membership of roomID, with their username.
+func (h *Handler) roomMember(r *http.Request, roomID, userID string) (*models.RoomMember, error) {
+	members, err := h.app.RoomRepo.GetMembers(r.Context(), roomID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.UserID == userID {
+			return m, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
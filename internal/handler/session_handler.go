@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	"ofenes/internal/auth"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/internal/ws"
+	"ofenes/pkg/response"
+)
+
+// RefreshSession handles POST /api/sessions/refresh.
+//
+// Exchanges the refresh token of a "remember me" session for a new JWT,
+// so the client stays signed in after the JWT expires. The refresh token
+// is rotated: the response carries a new one and the old one stops
+// working. The session is bound to the device it was created on; a
+// refresh token presented by another device (User-Agent) or a rotated-out
+// token ends the session, since it has most likely been stolen. Each
+// refresh extends the session by REMEMBER_ME_EXPIRY_DAYS.
+//
+// In cookie mode the refresh token is read from and written to its cookie.
+//
+// Request:  { "refreshToken": "..." }
+// Response: { "token": "...", "refreshToken": "...", "user": { ... } } (no tokens in cookie mode)
+func (h *Handler) RefreshSession(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshSessionRequest
+	if c, err := r.Cookie(middleware.RefreshCookieName); err == nil {
+		req.RefreshToken = c.Value
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	id, secret, ok := auth.ParseRefreshToken(req.RefreshToken)
+	if !ok {
+		h.fail(w, r, http.StatusUnauthorized, "invalid_refresh_token")
+		return
+	}
+	sessions := h.app.Ephemeral.Sessions
+	session, err := sessions.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusUnauthorized, "invalid_refresh_token")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_session")
+		return
+	}
+	if !auth.CheckRefreshSecret(session.TokenHash, secret) || session.UserAgent != r.UserAgent() {
+		if err := sessions.Delete(r.Context(), id); err != nil {
+			h.fail(w, r, http.StatusInternalServerError, "failed_to_revoke_session")
+			return
+		}
+		h.fail(w, r, http.StatusUnauthorized, "invalid_refresh_token")
+		return
+	}
+
+	user, err := h.app.UserRepo.GetByID(r.Context(), session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sessions.Delete(r.Context(), id)
+			h.fail(w, r, http.StatusUnauthorized, "account_gone")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_user")
+		return
+	}
+
+	refresh, hash, err := auth.NewRefreshToken(session.ID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_generate_token")
+		return
+	}
+	token, claims, err := auth.IssueToken(user.ID, user.Username, user.Role, user.TrustLevel, h.app.Config.Token())
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_generate_token")
+		return
+	}
+	now := h.app.Clock.Now()
+	session.TokenHash = hash
+	session.TokenID = claims.ID
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(h.app.Config.RememberMeExpiry)
+	if err := sessions.Update(r.Context(), session); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// Revoked while we were refreshing it.
+			h.fail(w, r, http.StatusUnauthorized, "invalid_refresh_token")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_session")
+		return
+	}
+
+	h.app.Stats.UserActive(user.ID)
+	response.JSON(w, http.StatusOK, models.AuthResponse{
+		Token:        h.issueSession(w, token),
+		RefreshToken: h.issueRefresh(w, refresh),
+		User:         *selfView(user),
+	})
+}
+
+// ListSessions handles GET /api/me/sessions.
+//
+// Lists the current user's "remember me" sessions, oldest first.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.app.Ephemeral.Sessions.ListByUser(r.Context(), middleware.GetUserID(r.Context()))
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_sessions")
+		return
+	}
+	for _, s := range sessions {
+		s.TokenHash, s.TokenID = "", ""
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+	response.JSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /api/me/sessions/{id}.
+//
+// Ends one of the current user's "remember me" sessions; its refresh token
+// stops working and the last JWT issued from it is revoked.
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessions := h.app.Ephemeral.Sessions
+	session, err := sessions.Get(r.Context(), r.PathValue("id"))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_session")
+		return
+	}
+	if err != nil || session.UserID != middleware.GetUserID(r.Context()) {
+		h.fail(w, r, http.StatusNotFound, "session_not_found")
+		return
+	}
+	if err := sessions.Delete(r.Context(), session.ID); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_revoke_session")
+		return
+	}
+	if err := h.revokeSessionToken(r.Context(), session); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_revoke_session")
+		return
+	}
+	response.NoContent(w)
+}
+
+// lockOut keeps userID out after their account is deleted or banned: it
+// ends their sessions and closes their connections with CloseBanned.
+// Their other tokens are refused from now on (see auth.Revocations).
+func (h *Handler) lockOut(ctx context.Context, userID string) {
+	if err := h.endSessions(ctx, userID); err != nil {
+		log.Printf("sessions: failed to end sessions of %s: %v", userID, err)
+	}
+	h.app.Hub.Disconnect(userID, ws.CloseBanned)
+}
+
+// endSessions ends all of userID's "remember me" sessions and revokes the
+// last JWT issued from each.
+func (h *Handler) endSessions(ctx context.Context, userID string) error {
+	sessions, err := h.app.Ephemeral.Sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := h.app.Ephemeral.Sessions.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if err := h.revokeSessionToken(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeSessionToken revokes the last JWT issued from session, which
+// expires within JWT_EXPIRY_HOURS from now.
+func (h *Handler) revokeSessionToken(ctx context.Context, session *models.Session) error {
+	return h.app.Revocations.Revoke(ctx, session.TokenID, h.app.Clock.Now().Add(h.app.Config.JWTExpiry))
+}
+
+// startSession creates a "remember me" session for userID on the device
+// making r, for the JWT with ID tokenID, and returns its refresh token.
+func (h *Handler) startSession(r *http.Request, userID, tokenID string) (string, error) {
+	id := h.app.IDs.New()
+	refresh, hash, err := auth.NewRefreshToken(id)
+	if err != nil {
+		return "", err
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	now := h.app.Clock.Now()
+	err = h.app.Ephemeral.Sessions.Create(r.Context(), &models.Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  r.UserAgent(),
+		IP:         ip,
+		TokenHash:  hash,
+		TokenID:    tokenID,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(h.app.Config.RememberMeExpiry),
+	})
+	return refresh, err
+}
+
+// issueRefresh returns the refresh token to put in an auth response, or
+// sets its cookie and returns "" in cookie mode, like issueSession.
+func (h *Handler) issueRefresh(w http.ResponseWriter, refresh string) string {
+	if !h.app.Config.AuthCookie {
+		return refresh
+	}
+	http.SetCookie(w, h.cookie(middleware.RefreshCookieName, refresh, int(h.app.Config.RememberMeExpiry.Seconds())))
+	return ""
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"ofenes/internal/i18n"
+	"ofenes/pkg/response"
+)
+
+// streamPageSize is how many items a streamed listing fetches per query.
+const streamPageSize = 500
+
+// streamAll writes every item produced by next as NDJSON. next returns
+// the following page of at most streamPageSize items; a shorter page is
+// the last. Each page is flushed to the client before the next query.
+//
+// If the first page fails, the client gets a normal errCode response;
+// later failures end the stream with an errCode error line.
+func streamAll[T any](h *Handler, w http.ResponseWriter, r *http.Request, errCode string, next func() ([]T, error)) {
+	page, err := next()
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, errCode)
+		return
+	}
+
+	stream := response.NewStream(w)
+	for {
+		for _, item := range page {
+			if stream.Send(item) != nil {
+				return // client gone
+			}
+		}
+		if stream.Flush() != nil || len(page) < streamPageSize {
+			return
+		}
+		if page, err = next(); err != nil {
+			log.Printf("%s: stream aborted: %v", r.URL.Path, err)
+			stream.Fail(errCode, i18n.T(h.language(r), errCode))
+			return
+		}
+	}
+}
+
+// offsetPages adapts an offset-paginated query to streamAll, starting at
+// offset.
+func offsetPages[T any](offset int, fetch func(limit, offset int) ([]T, error)) func() ([]T, error) {
+	return func() ([]T, error) {
+		page, err := fetch(streamPageSize, offset)
+		offset += len(page)
+		return page, err
+	}
+}
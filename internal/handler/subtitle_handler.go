@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/media"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/internal/subtitle"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxSubtitleSize bounds uploaded subtitle files, in bytes.
+	maxSubtitleSize = 2 << 20
+
+	// maxSubtitleLabelLength bounds track labels, in characters.
+	maxSubtitleLabelLength = 100
+
+	// maxSubtitlesPerMedia bounds the tracks of one video.
+	maxSubtitlesPerMedia = 20
+)
+
+// languageTag loosely matches BCP 47 language tags, such as en or pt-BR.
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// UploadSubtitle handles POST /api/media/{id}/subtitles?label=English&language=en
+// (video.control).
+//
+// Adds a subtitle track to an uploaded video: the raw request body is a
+// SubRip (.srt) or ASS (.ass, .ssa) file in UTF-8, up to 2 MiB, which is
+// converted to WebVTT. language is optional. Below TRUST_LEVEL_UPLOADS the
+// router rejects the request. The response is the track, whose url players
+// load.
+func (h *Handler) UploadSubtitle(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.mediaFile(w, r)
+	if !ok {
+		return
+	}
+	if !h.roomCan(r, file.RoomID, authz.RoomPermVideoControl) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+	if file.Status != models.MediaFileReady {
+		h.fail(w, r, http.StatusConflict, "media_not_ready")
+		return
+	}
+
+	query := r.URL.Query()
+	label := strings.TrimSpace(query.Get("label"))
+	if label == "" || utf8.RuneCountInString(label) > maxSubtitleLabelLength {
+		h.fail(w, r, http.StatusBadRequest, "invalid_subtitle_label", maxSubtitleLabelLength)
+		return
+	}
+	language := query.Get("language")
+	if language != "" && !languageTag.MatchString(language) {
+		h.fail(w, r, http.StatusBadRequest, "invalid_subtitle_language", language)
+		return
+	}
+
+	ctx := r.Context()
+	existing, err := h.app.SubtitleRepo.ListByMedia(ctx, file.ID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_subtitles")
+		return
+	}
+	if len(existing) >= maxSubtitlesPerMedia {
+		h.fail(w, r, http.StatusConflict, "too_many_subtitles", maxSubtitlesPerMedia)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSubtitleSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.fail(w, r, http.StatusRequestEntityTooLarge, "request_body_too_large")
+			return
+		}
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	vtt, format, err := subtitle.Convert(data)
+	if err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_subtitle_file", strings.TrimPrefix(err.Error(), "subtitle: "))
+		return
+	}
+
+	sub := &models.Subtitle{
+		ID:         h.app.IDs.New(),
+		MediaID:    file.ID,
+		RoomID:     file.RoomID,
+		UploadedBy: middleware.GetUserID(ctx),
+		Label:      label,
+		Language:   language,
+		Format:     format,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	if _, err := h.app.MediaStore.Append(subtitleKey(sub), 0, bytes.NewReader(vtt)); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_store_subtitle")
+		return
+	}
+	if err := h.app.SubtitleRepo.Create(ctx, sub); err != nil {
+		h.app.MediaStore.Delete(subtitleKey(sub))
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_store_subtitle")
+		return
+	}
+
+	sub.URL = subtitleURL(sub)
+	response.Created(w, sub.URL, sub)
+}
+
+// ListMediaSubtitles handles GET /api/media/{id}/subtitles.
+//
+// Lists the subtitle tracks of a video, oldest first, to those who may
+// watch its room.
+func (h *Handler) ListMediaSubtitles(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	subs, err := h.app.SubtitleRepo.ListByMedia(r.Context(), file.ID)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_subtitles")
+		return
+	}
+	if subs == nil {
+		subs = []*models.Subtitle{}
+	}
+	for _, s := range subs {
+		s.URL = subtitleURL(s)
+	}
+	response.JSON(w, http.StatusOK, subs)
+}
+
+// ListRoomSubtitles handles GET /api/rooms/{id}/subtitles.
+//
+// Lists the subtitle tracks of all the room's videos, newest first. A
+// video_sync payload's subtitle names one of them by ID.
+func (h *Handler) ListRoomSubtitles(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("id")
+	ctx := r.Context()
+	room, err := h.app.RoomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return
+	}
+	if !h.canWatch(r, room) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	subs, err := h.app.SubtitleRepo.ListByRoom(ctx, roomID, limit, offset)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_subtitles")
+		return
+	}
+	if subs == nil {
+		subs = []*models.Subtitle{}
+	}
+	for _, s := range subs {
+		s.URL = subtitleURL(s)
+	}
+
+	response.Paginated(w, subs, response.Page(limit, offset, len(subs)))
+}
+
+// DeleteSubtitle handles DELETE /api/subtitles/{id}.
+//
+// Deletes a subtitle track. Allowed for its uploader and for those with
+// room.moderate in its room.
+func (h *Handler) DeleteSubtitle(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.subtitle(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if sub.UploadedBy != middleware.GetUserID(r.Context()) && !h.roomCan(r, sub.RoomID, authz.RoomPermModerate) {
+		h.fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		return
+	}
+
+	if err := h.app.SubtitleRepo.Delete(r.Context(), sub.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "subtitle_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_subtitle")
+		return
+	}
+	if err := h.app.MediaStore.Delete(subtitleKey(sub)); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_subtitle")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// StreamSubtitle handles GET /media/{id}/subtitles/{subtitle}.
+//
+// Serves a subtitle track as WebVTT to those who may watch the video's
+// room, for <track> elements.
+func (h *Handler) StreamSubtitle(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.watchableMediaFile(w, r)
+	if !ok {
+		return
+	}
+	sub, ok := h.subtitle(w, r, r.PathValue("subtitle"))
+	if !ok {
+		return
+	}
+	if sub.MediaID != file.ID {
+		h.fail(w, r, http.StatusNotFound, "subtitle_not_found")
+		return
+	}
+
+	content, err := h.app.MediaStore.Open(subtitleKey(sub))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			h.fail(w, r, http.StatusNotFound, "subtitle_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_subtitles")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", sub.CreatedAt, content)
+}
+
+// subtitle loads the track id, writing the error response if it cannot.
+func (h *Handler) subtitle(w http.ResponseWriter, r *http.Request, id string) (*models.Subtitle, bool) {
+	if _, err := uuid.Parse(id); err != nil {
+		h.fail(w, r, http.StatusNotFound, "subtitle_not_found")
+		return nil, false
+	}
+	sub, err := h.app.SubtitleRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "subtitle_not_found")
+			return nil, false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_subtitles")
+		return nil, false
+	}
+	return sub, true
+}
+
+// subtitleKey returns where the WebVTT file of sub is kept, next to its
+// video so deleting the video deletes it too.
+func subtitleKey(sub *models.Subtitle) string {
+	return media.DerivedKey(sub.MediaID, "subtitle-"+sub.ID+".vtt")
+}
+
+// subtitleURL returns where players load sub.
+func subtitleURL(sub *models.Subtitle) string {
+	return "/media/" + sub.MediaID + "/subtitles/" + sub.ID
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/transcode"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// The handlers below are the queue of remote transcode workers
+// (TRANSCODE_MODE=remote, see transcode.RemoteQueue). They require a
+// service token with the transcode scope, whose service name is the
+// worker's name. A worker reporting on a job it no longer holds gets 409.
+
+// ClaimTranscode handles POST /api/transcode/claim.
+//
+// Hands the worker the upload waiting longest for transcoding, or 204 if
+// there is none.
+func (h *Handler) ClaimTranscode(w http.ResponseWriter, r *http.Request) {
+	file, err := h.app.Transcode.Claim(r.Context(), middleware.GetService(r.Context()))
+	if errors.Is(err, transcode.ErrNoJob) {
+		response.NoContent(w)
+		return
+	}
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_claim_transcode")
+		return
+	}
+	response.JSON(w, http.StatusOK, file)
+}
+
+// GetTranscodeSource handles GET /api/transcode/{id}/source.
+//
+// Serves the upload of the worker's job, with Range support.
+func (h *Handler) GetTranscodeSource(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.transcodeJobID(w, r)
+	if !ok {
+		return
+	}
+	content, err := h.app.Transcode.Source(r.Context(), id, middleware.GetService(r.Context()))
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_update_transcode")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
+
+// ReportTranscodeProgress handles PUT /api/transcode/{id}/progress.
+//
+// Request: { "progress": 0.42 }
+func (h *Handler) ReportTranscodeProgress(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.transcodeJobID(w, r)
+	if !ok {
+		return
+	}
+	var req models.TranscodeProgressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	err := h.app.Transcode.Progress(r.Context(), id, middleware.GetService(r.Context()), req.Progress)
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_update_transcode")
+		return
+	}
+	response.NoContent(w)
+}
+
+// PutTranscodeFile handles PUT /api/transcode/{id}/files/{name}.
+//
+// Stores the raw request body as a preview, playlist or segment of the
+// worker's job, up to MEDIA_MAX_UPLOAD_SIZE bytes.
+func (h *Handler) PutTranscodeFile(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.transcodeJobID(w, r)
+	if !ok {
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, h.app.Config.MediaMaxUploadSize)
+	err := h.app.Transcode.Put(r.Context(), id, middleware.GetService(r.Context()), r.PathValue("name"), body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.fail(w, r, http.StatusRequestEntityTooLarge, "request_body_too_large")
+		return
+	}
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_update_transcode")
+		return
+	}
+	response.NoContent(w)
+}
+
+// FinishTranscode handles POST /api/transcode/{id}/finish.
+//
+// Ends the worker's job: done without an error, failed with one. The
+// room is told either way.
+//
+// Request: { "error": "" }
+func (h *Handler) FinishTranscode(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.transcodeJobID(w, r)
+	if !ok {
+		return
+	}
+	var req models.FinishTranscodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	err := h.app.Transcode.Finish(r.Context(), id, middleware.GetService(r.Context()), req.Error)
+	if err != nil {
+		h.failErr(w, r, err, "failed_to_update_transcode")
+		return
+	}
+	response.NoContent(w)
+}
+
+// transcodeJobID returns the id path value, writing the error response if
+// it cannot be a job.
+func (h *Handler) transcodeJobID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		h.fail(w, r, http.StatusNotFound, "media_not_found")
+		return "", false
+	}
+	return id, true
+}
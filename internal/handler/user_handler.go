@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"ofenes/internal/middleware"
+	"ofenes/internal/models"
 	"ofenes/pkg/response"
 )
 
@@ -15,9 +16,16 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.app.UserRepo.GetByID(ctx, userID)
 	if err != nil {
-		response.Error(w, http.StatusNotFound, "user not found")
+		h.fail(w, r, http.StatusNotFound, "user_not_found")
 		return
 	}
 
-	response.JSON(w, http.StatusOK, user)
+	response.JSON(w, http.StatusOK, selfView(user))
+}
+
+// selfView prepares a user for display to that same user. Shadow bans
+// only work while the user doesn't know about them.
+func selfView(u *models.User) *models.User {
+	u.ShadowBannedAt = nil
+	return u
 }
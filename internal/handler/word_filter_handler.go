@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"ofenes/internal/i18n"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/internal/ws"
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// maxWordFilterPattern caps the length of a filter pattern.
+const maxWordFilterPattern = 200
+
+// ListWordFilters handles GET /api/admin/word-filters (admin only).
+//
+// Returns every filter, oldest first. With ?room={id} only that room's
+// overrides are returned; ?room=global returns only the global filters.
+func (h *Handler) ListWordFilters(w http.ResponseWriter, r *http.Request) {
+	filters, err := h.app.WordFilterRepo.List(r.Context())
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_list_word_filters")
+		return
+	}
+
+	room := r.URL.Query().Get("room")
+	out := make([]*models.WordFilter, 0, len(filters))
+	for _, f := range filters {
+		switch {
+		case room == "":
+		case room == "global" && f.RoomID == "":
+		case room != "global" && f.RoomID == room:
+		default:
+			continue
+		}
+		out = append(out, f)
+	}
+	response.JSON(w, http.StatusOK, out)
+}
+
+// CreateWordFilter handles POST /api/admin/word-filters (admin only).
+// The filter applies to chat messages as soon as the request returns.
+func (h *Handler) CreateWordFilter(w http.ResponseWriter, r *http.Request) {
+	var req models.WordFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	req.Pattern = strings.TrimSpace(req.Pattern)
+	if msg := validateWordFilter(req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	if !h.wordFilterRoomExists(w, r, req.RoomID) {
+		return
+	}
+
+	now := h.app.Clock.Now()
+	actorID := middleware.GetUserID(ctx)
+	filter := &models.WordFilter{
+		ID:        h.app.IDs.New(),
+		Pattern:   req.Pattern,
+		Regex:     req.Regex,
+		Action:    req.Action,
+		RoomID:    req.RoomID,
+		CreatedBy: actorID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.WordFilters.Create(ctx, filter); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.wordFilterAuditEntry(actorID, models.AuditWordFilterCreate, filter))
+	})
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_create_word_filter")
+		return
+	}
+	h.reloadWordFilters(r)
+
+	response.Created(w, "/api/admin/word-filters/"+filter.ID, filter)
+}
+
+// UpdateWordFilter handles PUT /api/admin/word-filters/{id} (admin only).
+// The request replaces the pattern, regex flag, action and room.
+func (h *Handler) UpdateWordFilter(w http.ResponseWriter, r *http.Request) {
+	var req models.WordFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+	req.Pattern = strings.TrimSpace(req.Pattern)
+	if msg := validateWordFilter(req); msg.Code != "" {
+		h.fail(w, r, http.StatusBadRequest, msg.Code, msg.Args...)
+		return
+	}
+
+	ctx := r.Context()
+	filter, err := h.app.WordFilterRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "word_filter_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_word_filter")
+		return
+	}
+	if req.RoomID != filter.RoomID && !h.wordFilterRoomExists(w, r, req.RoomID) {
+		return
+	}
+
+	filter.Pattern, filter.Regex, filter.Action, filter.RoomID = req.Pattern, req.Regex, req.Action, req.RoomID
+	filter.UpdatedAt = h.app.Clock.Now()
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.WordFilters.Update(ctx, filter); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.wordFilterAuditEntry(actorID, models.AuditWordFilterUpdate, filter))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "word_filter_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_update_word_filter")
+		return
+	}
+	h.reloadWordFilters(r)
+
+	response.JSON(w, http.StatusOK, filter)
+}
+
+// DeleteWordFilter handles DELETE /api/admin/word-filters/{id} (admin only).
+func (h *Handler) DeleteWordFilter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter, err := h.app.WordFilterRepo.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "word_filter_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_word_filter")
+		return
+	}
+
+	actorID := middleware.GetUserID(ctx)
+	err = h.app.Tx.WithinTx(ctx, func(tx repository.Repos) error {
+		if err := tx.WordFilters.Delete(ctx, filter.ID); err != nil {
+			return err
+		}
+		return tx.Audit.Create(ctx, h.wordFilterAuditEntry(actorID, models.AuditWordFilterDelete, filter))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "word_filter_not_found")
+			return
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_delete_word_filter")
+		return
+	}
+	h.reloadWordFilters(r)
+
+	response.NoContent(w)
+}
+
+// wordFilterRoomExists reports whether roomID (if set) names an existing
+// room, writing the error response if not.
+func (h *Handler) wordFilterRoomExists(w http.ResponseWriter, r *http.Request, roomID string) bool {
+	if roomID == "" {
+		return true
+	}
+	if _, err := h.app.RoomRepo.GetByID(r.Context(), roomID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.fail(w, r, http.StatusNotFound, "room_not_found")
+			return false
+		}
+		h.fail(w, r, http.StatusInternalServerError, "failed_to_get_room")
+		return false
+	}
+	return true
+}
+
+// reloadWordFilters pushes the stored filters to the Hub after a change.
+// The change is already saved, so a failure is only logged: the periodic
+// reload (WORD_FILTER_RELOAD_INTERVAL_MS) retries it.
+func (h *Handler) reloadWordFilters(r *http.Request) {
+	if err := h.app.Hub.ReloadWordFilters(r.Context(), h.app.WordFilterRepo); err != nil {
+		log.Printf("word filters: reload failed: %v", err)
+	}
+}
+
+// validateWordFilter checks a word filter request (with the pattern
+// trimmed) and returns the problem, or a zero Message if it is valid.
+func validateWordFilter(req models.WordFilterRequest) i18n.Message {
+	if req.Pattern == "" {
+		return i18n.Msg("pattern_required")
+	}
+	if utf8.RuneCountInString(req.Pattern) > maxWordFilterPattern {
+		return i18n.Msg("pattern_too_long", maxWordFilterPattern)
+	}
+	if req.RoomID != "" {
+		if _, err := uuid.Parse(req.RoomID); err != nil {
+			return i18n.Msg("invalid_room_id")
+		}
+	}
+	switch req.Action {
+	case models.WordFilterBlock, models.WordFilterFlag:
+	case models.WordFilterAllow:
+		if req.RoomID == "" {
+			return i18n.Msg("allow_requires_room")
+		}
+	default:
+		return i18n.Msg("invalid_word_filter_action")
+	}
+	if _, err := ws.CompileWordFilter(models.WordFilter{Pattern: req.Pattern, Regex: req.Regex}); err != nil {
+		return i18n.Msg("invalid_regex", err)
+	}
+	return i18n.Message{}
+}
+
+// wordFilterAuditEntry builds an audit entry for a change to filter,
+// recording the filter itself as the details.
+func (h *Handler) wordFilterAuditEntry(actorID, action string, filter *models.WordFilter) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:         h.app.IDs.New(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "word_filter",
+		TargetID:   filter.ID,
+		CreatedAt:  h.app.Clock.Now(),
+	}
+	entry.Details, _ = json.Marshal(filter)
+	return entry
+}
@@ -0,0 +1,194 @@
+// Package handoff lets a new server process take over the listening
+// socket of a running one, so a deploy refuses no connections and drops
+// no WebSocket without a resume token. The listener comes from one of:
+//
+//   - the process handing over: Upgrade (on SIGUSR2, see NotifyUpgrade)
+//     starts a new copy of the binary with the listener as fd 3 and returns
+//     once it is Ready; the old process then stops accepting and drains
+//     its WebSocket clients into the new one (see ws.Hub.Drain);
+//   - systemd socket activation (LISTEN_FDS), which keeps the socket open
+//     across restarts, so connections wait in its backlog meanwhile;
+//   - a fresh bind, with SO_REUSEPORT if asked, so a new process started by
+//     other means can bind the same port before the old one exits.
+//
+// Usage:
+//
+//	ln, how, err := handoff.Listen(":8080", opts)
+//	// ... once the server is ready to serve:
+//	handoff.Ready(opts)
+//	go server.Serve(ln)
+//	// ... on SIGUSR2:
+//	pid, err := handoff.Upgrade(ln, time.Minute)
+package handoff
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+// File descriptors of a process started by Upgrade or systemd.
+const (
+	listenerFD = 3 // the listener: SD_LISTEN_FDS_START, and where Upgrade puts it
+	readyFD    = 4 // the pipe Ready reports on after an Upgrade
+)
+
+// Options say where the listener comes from; config.Load reads them from
+// the environment (see Config.Handoff).
+type Options struct {
+	ReusePort bool // bind with SO_REUSEPORT
+
+	ParentPID int // the process that started this one with Upgrade, 0 if none
+
+	// ListenPID and ListenFDs are set by systemd socket activation; the
+	// first socket passed is the listener. Ignored unless ListenPID is this
+	// process.
+	ListenPID int
+	ListenFDs int
+
+	// NotifySocket is systemd's notification socket, told by Ready.
+	NotifySocket string
+}
+
+// Listen returns the listener for addr: the one handed over, the one from
+// systemd, or a new one. how describes which, for logging.
+func Listen(addr string, o Options) (ln net.Listener, how string, err error) {
+	switch {
+	case o.ParentPID != 0:
+		ln, err = fileListener(listenerFD)
+		return ln, fmt.Sprintf("taken over from pid %d", o.ParentPID), err
+	case o.ListenFDs > 0 && o.ListenPID == os.Getpid():
+		ln, err = fileListener(listenerFD)
+		return ln, "from systemd", err
+	}
+	lc := net.ListenConfig{}
+	how = "bound"
+	if o.ReusePort {
+		if reusePort == nil {
+			return nil, "", fmt.Errorf("handoff: SO_REUSEPORT is not supported on this system")
+		}
+		lc.Control = reusePort
+		how = "bound with SO_REUSEPORT"
+	}
+	ln, err = lc.Listen(context.Background(), "tcp", addr)
+	return ln, how, err
+}
+
+// fileListener returns the listener inherited as fd.
+func fileListener(fd uintptr) (net.Listener, error) {
+	f := os.NewFile(fd, "listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("handoff: inherited listener (fd %d): %w", fd, err)
+	}
+	return ln, nil
+}
+
+// Ready tells whoever started the process that it is serving: the process
+// handing over to it, which then drains, and systemd (with NotifySocket),
+// to which it becomes the service's main process. Call it once the server
+// is ready to answer requests.
+func Ready(o Options) error {
+	if o.ParentPID != 0 {
+		f := os.NewFile(readyFD, "ready")
+		_, err := f.Write([]byte{1})
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("handoff: telling pid %d: %w", o.ParentPID, err)
+		}
+	}
+	if o.NotifySocket != "" {
+		return notifySystemd(o.NotifySocket, "READY=1\nMAINPID="+strconv.Itoa(os.Getpid()))
+	}
+	return nil
+}
+
+// notifySystemd sends state to systemd's notification socket (sd_notify).
+func notifySystemd(socket, state string) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("handoff: notifying systemd: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("handoff: notifying systemd: %w", err)
+	}
+	return nil
+}
+
+// Upgrade starts a new copy of the running binary — the file now at its
+// path, so a deploy replaces that first — with the same arguments,
+// environment and working directory, hands it ln and waits up to timeout
+// for it to call Ready. It returns the new process's pid; the caller
+// should then stop accepting and drain. If the new process exits or isn't
+// ready in time (it is killed then), Upgrade returns an error and the
+// caller keeps serving.
+func Upgrade(ln net.Listener, timeout time.Duration) (int, error) {
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return 0, fmt.Errorf("handoff: a %T listener can't be handed over", ln)
+	}
+	lnFile, err := filer.File()
+	if err != nil {
+		return 0, fmt.Errorf("handoff: %w", err)
+	}
+	defer lnFile.Close()
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("handoff: %w", err)
+	}
+	defer readyR.Close()
+	exe, err := os.Executable()
+	if err != nil {
+		readyW.Close()
+		return 0, fmt.Errorf("handoff: %w", err)
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), "HANDOFF_PARENT_PID="+strconv.Itoa(os.Getpid()))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{lnFile, readyW} // listenerFD, readyFD
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return 0, fmt.Errorf("handoff: starting %s: %w", exe, err)
+	}
+	pid := cmd.Process.Pid
+
+	// The pipe closes without a byte if the new process exits first.
+	ready := make(chan error, 1)
+	go func() {
+		_, err := readyR.Read(make([]byte, 1))
+		ready <- err
+	}()
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-ready:
+		if err == nil {
+			return pid, nil
+		}
+		return 0, fmt.Errorf("handoff: pid %d exited before it was ready: %v", pid, <-exited)
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		<-exited
+		return 0, fmt.Errorf("handoff: pid %d was not ready after %s", pid, timeout)
+	}
+}
+
+// NotifyUpgrade relays SIGUSR2, the signal asking for an Upgrade, to c.
+// It does nothing where there is no such signal.
+func NotifyUpgrade(c chan<- os.Signal) {
+	if upgradeSignal != nil {
+		signal.Notify(c, upgradeSignal)
+	}
+}
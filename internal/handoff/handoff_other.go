@@ -0,0 +1,15 @@
+//go:build !unix || solaris
+
+package handoff
+
+import (
+	"os"
+	"syscall"
+)
+
+// Elsewhere, such as on Windows and Solaris, listeners can't be bound
+// with SO_REUSEPORT and no signal asks for an Upgrade.
+var (
+	upgradeSignal os.Signal
+	reusePort     func(network, address string, c syscall.RawConn) error
+)
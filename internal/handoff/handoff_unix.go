@@ -0,0 +1,25 @@
+//go:build unix && !solaris
+
+package handoff
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// upgradeSignal asks for an Upgrade.
+var upgradeSignal os.Signal = syscall.SIGUSR2
+
+// reusePort sets SO_REUSEPORT on a socket before it is bound.
+var reusePort = func(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
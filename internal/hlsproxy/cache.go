@@ -0,0 +1,76 @@
+package hlsproxy
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cache is an LRU of upstream responses by URL, bounded by the bytes of
+// their bodies. Safe for concurrent use.
+type cache struct {
+	max int64
+
+	mu    sync.Mutex
+	size  int64
+	lru   *list.List               // front = most recently used
+	byURL map[string]*list.Element // URL -> element holding *cached
+}
+
+// cached is one cache entry. Bodies are never modified once stored.
+type cached struct {
+	url         string
+	body        []byte
+	contentType string
+	expires     time.Time
+}
+
+func newCache(max int64) *cache {
+	return &cache{max: max, lru: list.New(), byURL: make(map[string]*list.Element)}
+}
+
+// get returns the entry for url unless it is missing or expired.
+func (c *cache) get(url string, now time.Time) (*cached, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.byURL[url]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cached)
+	if now.After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// put stores e, evicting the least recently used entries to make room.
+// Entries larger than the whole cache are not stored.
+func (c *cache) put(e *cached) {
+	n := int64(len(e.body))
+	if n > c.max {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.byURL[e.url]; ok {
+		c.remove(el)
+	}
+	for c.size+n > c.max {
+		c.remove(c.lru.Back())
+	}
+	c.byURL[e.url] = c.lru.PushFront(e)
+	c.size += n
+}
+
+// remove drops el. The caller holds c.mu.
+func (c *cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cached)
+	delete(c.byURL, e.url)
+	c.size -= int64(len(e.body))
+}
@@ -0,0 +1,44 @@
+package hlsproxy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is returned when an upstream host resolves to an
+// address the proxy must not reach.
+var errPrivateAddress = errors.New("hlsproxy: refusing to connect to a private address")
+
+// newClient returns the client fetching upstream. Unless allowPrivate is
+// set it refuses to connect to loopback, private, link-local and other
+// non-public addresses, so room members cannot use the proxy to reach
+// the server's own network. The check runs on the resolved address of
+// every connection, redirects included.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(ap.Addr()) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+}
+
+// publicAddr reports whether addr is a public unicast address.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() &&
+		!netip.MustParsePrefix("100.64.0.0/10").Contains(addr) // carrier-grade NAT
+}
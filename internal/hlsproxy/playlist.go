@@ -0,0 +1,110 @@
+package hlsproxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errNotPlaylist is returned by rewrite for bodies that are not M3U8.
+var errNotPlaylist = errors.New("hlsproxy: not an HLS playlist")
+
+// uriAttr matches the URI attribute of tags such as EXT-X-KEY, EXT-X-MAP
+// and EXT-X-MEDIA.
+var uriAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+// playlist is what rewrite learned about a playlist.
+type playlist struct {
+	media          bool      // a media playlist (segments), not a multivariant one
+	ended          bool      // EXT-X-ENDLIST: the stream is not live
+	targetDuration float64   // seconds
+	firstSeq       int64     // media sequence of the first segment
+	durations      []float64 // of each segment, in order
+	segments       []string  // absolute URL of each segment, in order
+}
+
+// rewrite parses the playlist body fetched from base and returns it with
+// every URI replaced by link(absolute URI).
+func rewrite(body []byte, base *url.URL, link func(string) string) ([]byte, playlist, error) {
+	var pl playlist
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(make([]byte, 64*1024), maxPlaylistBytes)
+
+	first := true
+	duration := -1.0 // of the next segment, from EXTINF
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if first {
+			if !strings.HasPrefix(line, "#EXTM3U") {
+				return nil, pl, errNotPlaylist
+			}
+			first = false
+		}
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			line = rewriteTag(line, &pl, &duration, base, link)
+		default:
+			abs, err := base.Parse(line)
+			if err != nil {
+				return nil, pl, err
+			}
+			if duration >= 0 {
+				pl.media = true
+				pl.durations = append(pl.durations, duration)
+				pl.segments = append(pl.segments, abs.String())
+				duration = -1
+			}
+			line = link(abs.String())
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return nil, pl, err
+	}
+	if first {
+		return nil, pl, errNotPlaylist
+	}
+	return out.Bytes(), pl, nil
+}
+
+// rewriteTag records what the tag line says about the playlist and
+// returns it with its URI attribute, if any, rewritten.
+func rewriteTag(line string, pl *playlist, duration *float64, base *url.URL, link func(string) string) string {
+	name, value, _ := strings.Cut(line, ":")
+	switch name {
+	case "#EXTINF":
+		d, _, _ := strings.Cut(value, ",")
+		if f, err := strconv.ParseFloat(strings.TrimSpace(d), 64); err == nil && f >= 0 {
+			*duration = f
+		} else {
+			*duration = 0
+		}
+	case "#EXT-X-TARGETDURATION":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			pl.targetDuration = f
+		}
+	case "#EXT-X-MEDIA-SEQUENCE":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			pl.firstSeq = n
+		}
+	case "#EXT-X-ENDLIST":
+		pl.ended = true
+	}
+
+	return uriAttr.ReplaceAllStringFunc(line, func(attr string) string {
+		raw := uriAttr.FindStringSubmatch(attr)[1]
+		abs, err := base.Parse(raw)
+		if err != nil || (abs.Scheme != "http" && abs.Scheme != "https") {
+			return attr // data: URIs and the like stay as they are
+		}
+		return `URI="` + link(abs.String()) + `"`
+	})
+}
@@ -0,0 +1,485 @@
+// Package hlsproxy pulls HLS streams server-side and re-serves them to
+// room members.
+//
+// Players often cannot load a stream straight from its origin, which may
+// send no CORS headers or only allow its own site. The Proxy learns each
+// room's source from the Hub — the .m3u8 URL of its current video — and
+// serves its playlists with every URI rewritten into a signed link back
+// to the proxy, so nothing but what that stream refers to can be fetched
+// through it. Upstream responses are cached, live playlists for half
+// their target duration, and concurrent requests for one URL share a
+// single fetch.
+//
+// As every member's player loads the stream through it, the proxy also
+// knows where the live edge is and which segment each member reached
+// last (see Status).
+package hlsproxy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/pkg/apperr"
+)
+
+const (
+	// maxPlaylistBytes and maxSegmentBytes bound upstream responses.
+	maxPlaylistBytes = 1 << 20
+	maxSegmentBytes  = 64 << 20
+
+	// segmentTTL is how long segments, multivariant playlists and the
+	// playlists of ended streams are cached.
+	segmentTTL = 10 * time.Minute
+
+	// viewerTTL drops members from Status who loaded no segment for this long.
+	viewerTTL = time.Minute
+
+	defaultCacheBytes = 256 << 20
+)
+
+var (
+	// ErrNoSource is returned when the room is not playing an HLS stream.
+	ErrNoSource = apperr.New(apperr.NotFound, "no_hls_source", "hlsproxy: the room is not playing an HLS stream")
+
+	// ErrBadLink is returned for links not signed for the room's current source.
+	ErrBadLink = apperr.New(apperr.Invalid, "invalid_hls_link", "hlsproxy: invalid link")
+
+	// ErrUpstream wraps failures to fetch from the stream's origin.
+	ErrUpstream = apperr.New(apperr.Internal, "hls_upstream_failed", "hlsproxy: upstream request failed")
+)
+
+// targetDurationTag finds the target duration of a playlist without
+// parsing all of it.
+var targetDurationTag = regexp.MustCompile(`#EXT-X-TARGETDURATION:\s*([0-9.]+)`)
+
+// Options configures a Proxy.
+type Options struct {
+	// Secret keys the signed links; instances sharing it accept each
+	// other's links. Empty = a random key per process.
+	Secret string
+
+	// CacheBytes bounds the cached upstream responses (default: 256 MiB).
+	CacheBytes int64
+
+	// AllowPrivate lets sources on loopback, private and link-local
+	// addresses be fetched (development only).
+	AllowPrivate bool
+}
+
+// Proxy fetches and re-serves the HLS streams of rooms. Safe for
+// concurrent use.
+type Proxy struct {
+	key    []byte
+	client *http.Client
+	cache  *cache
+	now    func() time.Time
+
+	mu       sync.Mutex
+	rooms    map[string]*room
+	fetching map[string]*call // upstream fetches in flight, by URL
+}
+
+// room is what the proxy knows about one room's stream.
+type room struct {
+	source string
+
+	// The newest media playlist seen, placed on the stream's timeline:
+	// windowStart is the stream time at the start of segment firstSeq.
+	loaded         bool
+	live           bool
+	targetDuration float64
+	firstSeq       int64
+	durations      []float64
+	windowStart    float64
+	updatedAt      time.Time
+
+	segments map[string]segment // recent segments by upstream URL
+	viewers  map[string]viewer  // by user ID
+}
+
+// segment places a segment on the stream's timeline.
+type segment struct {
+	seq   int64
+	start float64
+}
+
+// viewer is the segment a member loaded last.
+type viewer struct {
+	position float64
+	at       time.Time
+}
+
+// call is an upstream fetch in flight; done is closed once entry or err is set.
+type call struct {
+	done  chan struct{}
+	entry *cached
+	err   error
+}
+
+// Content is a playlist or segment ready to be served.
+type Content struct {
+	Body        []byte
+	ContentType string
+	Playlist    bool // rewritten playlists must not be cached by clients
+}
+
+// New creates a proxy.
+func New(opts Options) *Proxy {
+	if opts.CacheBytes <= 0 {
+		opts.CacheBytes = defaultCacheBytes
+	}
+	key := make([]byte, sha256.Size)
+	if opts.Secret == "" {
+		rand.Read(key)
+	} else {
+		mac := hmac.New(sha256.New, []byte(opts.Secret))
+		mac.Write([]byte("ofenes hls proxy links"))
+		key = mac.Sum(nil)
+	}
+	return &Proxy{
+		key:      key,
+		client:   newClient(opts.AllowPrivate),
+		cache:    newCache(opts.CacheBytes),
+		now:      time.Now,
+		rooms:    make(map[string]*room),
+		fetching: make(map[string]*call),
+	}
+}
+
+// VideoSync follows the room's current video: an http(s) URL ending in
+// .m3u8 becomes its source, any other video ends proxying. Relative URLs,
+// such as the proxy's own, are ignored. Implements ws.VideoSourceTracker.
+func (p *Proxy) VideoSync(roomID string, payload models.VideoSyncPayload) {
+	u, err := url.Parse(payload.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !strings.HasSuffix(strings.ToLower(u.Path), ".m3u8") {
+		delete(p.rooms, roomID)
+		return
+	}
+	if r, ok := p.rooms[roomID]; ok && r.source == payload.URL {
+		return
+	}
+	p.rooms[roomID] = &room{
+		source:   payload.URL,
+		segments: make(map[string]segment),
+		viewers:  make(map[string]viewer),
+	}
+}
+
+// RoomEmpty forgets a room nobody is connected to any more. Implements
+// ws.VideoSourceTracker.
+func (p *Proxy) RoomEmpty(roomID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.rooms, roomID)
+}
+
+// Playlist returns the room's source playlist, rewritten.
+func (p *Proxy) Playlist(ctx context.Context, roomID string) (*Content, error) {
+	source, ok := p.source(roomID)
+	if !ok {
+		return nil, ErrNoSource
+	}
+	return p.serve(ctx, roomID, "", source, source)
+}
+
+// Resource returns what a link from one of the room's rewritten playlists
+// points to: target is its u parameter and sig its s parameter. Playlists
+// come rewritten; a segment is recorded as the last one userID loaded.
+func (p *Proxy) Resource(ctx context.Context, roomID, userID, target, sig string) (*Content, error) {
+	source, ok := p.source(roomID)
+	if !ok {
+		return nil, ErrNoSource
+	}
+	if !hmac.Equal([]byte(sig), []byte(p.sign(roomID, source, target))) {
+		return nil, ErrBadLink
+	}
+	return p.serve(ctx, roomID, userID, source, target)
+}
+
+// source returns the room's current source.
+func (p *Proxy) source(roomID string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.rooms[roomID]
+	if !ok {
+		return "", false
+	}
+	return r.source, true
+}
+
+// serve fetches target and rewrites it if it is a playlist.
+func (p *Proxy) serve(ctx context.Context, roomID, userID, source, target string) (*Content, error) {
+	e, err := p.get(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(target)
+	if err != nil {
+		return nil, ErrBadLink
+	}
+
+	if !isPlaylist(base, e.contentType) {
+		p.segmentLoaded(roomID, userID, target)
+		contentType := e.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		return &Content{Body: e.body, ContentType: contentType}, nil
+	}
+
+	body, pl, err := rewrite(e.body, base, func(abs string) string {
+		return "r?u=" + url.QueryEscape(abs) + "&s=" + p.sign(roomID, source, abs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	if pl.media {
+		p.playlistLoaded(roomID, source, pl)
+	}
+	return &Content{Body: body, ContentType: "application/vnd.apple.mpegurl", Playlist: true}, nil
+}
+
+// sign returns the signature of a link to target in roomID's playlists,
+// valid while the room plays source.
+func (p *Proxy) sign(roomID, source, target string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(roomID + "\n" + source + "\n" + target))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// get returns target from the cache, or fetches it. Concurrent calls for
+// the same URL wait for a single fetch, which is not canceled with ctx so
+// one player going away does not fail the others.
+func (p *Proxy) get(ctx context.Context, target string) (*cached, error) {
+	if e, ok := p.cache.get(target, p.now()); ok {
+		return e, nil
+	}
+
+	p.mu.Lock()
+	c, ok := p.fetching[target]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		p.fetching[target] = c
+		go p.fetch(target, c)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.entry, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch performs the upstream request of c and caches the response.
+func (p *Proxy) fetch(target string, c *call) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.fetching, target)
+		p.mu.Unlock()
+		close(c.done)
+	}()
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		c.err = fmt.Errorf("%w: unsupported URL %q", ErrUpstream, target)
+		return
+	}
+	resp, err := p.client.Get(target)
+	if err != nil {
+		c.err = fmt.Errorf("%w: %v", ErrUpstream, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.err = fmt.Errorf("%w: %s returned %s", ErrUpstream, u.Host, resp.Status)
+		return
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	limit := int64(maxSegmentBytes)
+	if isPlaylist(u, contentType) {
+		limit = maxPlaylistBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		c.err = fmt.Errorf("%w: %v", ErrUpstream, err)
+		return
+	}
+	if int64(len(body)) > limit {
+		c.err = fmt.Errorf("%w: response larger than %d bytes", ErrUpstream, limit)
+		return
+	}
+
+	ttl := segmentTTL
+	if isPlaylist(u, contentType) {
+		ttl = playlistTTL(body)
+	}
+	c.entry = &cached{url: target, body: body, contentType: contentType, expires: p.now().Add(ttl)}
+	p.cache.put(c.entry)
+}
+
+// isPlaylist reports whether a response from u with contentType is an
+// M3U8 playlist.
+func isPlaylist(u *url.URL, contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "mpegurl") ||
+		strings.HasSuffix(strings.ToLower(u.Path), ".m3u8")
+}
+
+// playlistTTL returns how long a playlist may be cached: half the target
+// duration for live media playlists, which grow as the stream goes on,
+// and segmentTTL for those that do not change.
+func playlistTTL(body []byte) time.Duration {
+	if strings.Contains(string(body), "#EXT-X-ENDLIST") || !strings.Contains(string(body), "#EXTINF") {
+		return segmentTTL
+	}
+	if m := targetDurationTag.FindSubmatch(body); m != nil {
+		if d, err := strconv.ParseFloat(string(m[1]), 64); err == nil && d > 0 {
+			return time.Duration(d * float64(time.Second) / 2)
+		}
+	}
+	return time.Second
+}
+
+// playlistLoaded places a media playlist of the room's source on the
+// stream's timeline. Segments that left the window since the last one
+// move its start forward; a sequence going back means the stream
+// restarted.
+func (p *Proxy) playlistLoaded(roomID, source string, pl playlist) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.rooms[roomID]
+	if !ok || r.source != source {
+		return
+	}
+
+	switch {
+	case !r.loaded || pl.firstSeq < r.firstSeq:
+		r.windowStart = 0
+	case pl.firstSeq > r.firstSeq:
+		gone := pl.firstSeq - r.firstSeq
+		known := min(gone, int64(len(r.durations)))
+		for _, d := range r.durations[:known] {
+			r.windowStart += d
+		}
+		r.windowStart += float64(gone-known) * pl.targetDuration
+	}
+	r.loaded = true
+	r.live = !pl.ended
+	r.targetDuration = pl.targetDuration
+	r.firstSeq = pl.firstSeq
+	r.durations = pl.durations
+	r.updatedAt = p.now()
+
+	start := r.windowStart
+	for i, u := range pl.segments {
+		r.segments[u] = segment{seq: pl.firstSeq + int64(i), start: start}
+		start += pl.durations[i]
+	}
+	for u, s := range r.segments {
+		if s.seq < pl.firstSeq-int64(len(pl.segments)) {
+			delete(r.segments, u)
+		}
+	}
+}
+
+// segmentLoaded records target as the last segment userID loaded.
+func (p *Proxy) segmentLoaded(roomID, userID, target string) {
+	if userID == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.rooms[roomID]
+	if !ok {
+		return
+	}
+	if s, ok := r.segments[target]; ok {
+		r.viewers[userID] = viewer{position: s.start, at: p.now()}
+	}
+}
+
+// Status returns what the proxy knows about roomID's stream. Rooms not
+// playing an HLS stream return zero values.
+func (p *Proxy) Status(roomID string) Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := Status{RoomID: roomID, Viewers: []ViewerPosition{}}
+	r, ok := p.rooms[roomID]
+	if !ok {
+		return out
+	}
+	out.Source = r.source
+	if !r.loaded {
+		return out
+	}
+
+	edge := r.windowStart
+	for _, d := range r.durations {
+		edge += d
+	}
+	updated := r.updatedAt
+	out.Live = r.live
+	out.TargetDuration = r.targetDuration
+	out.MediaSequence = r.firstSeq + int64(len(r.durations)) - 1
+	out.EdgeSeconds = edge
+	out.UpdatedAt = &updated
+
+	now := p.now()
+	for userID, v := range r.viewers {
+		if now.Sub(v.at) > viewerTTL {
+			delete(r.viewers, userID)
+			continue
+		}
+		vp := ViewerPosition{UserID: userID, PositionSeconds: v.position, At: v.at}
+		if r.live {
+			vp.BehindSeconds = edge - v.position
+		}
+		out.Viewers = append(out.Viewers, vp)
+	}
+	sort.Slice(out.Viewers, func(i, j int) bool { return out.Viewers[i].UserID < out.Viewers[j].UserID })
+	return out
+}
+
+// Status is returned by GET /api/rooms/{id}/hls.
+type Status struct {
+	RoomID         string           `json:"roomId"`
+	Source         string           `json:"source,omitempty"` // "" = not playing an HLS stream
+	Live           bool             `json:"live"`
+	TargetDuration float64          `json:"targetDuration,omitempty"`
+	MediaSequence  int64            `json:"mediaSequence,omitempty"` // of the newest segment
+	EdgeSeconds    float64          `json:"edgeSeconds"`             // stream time at the end of the newest segment
+	UpdatedAt      *time.Time       `json:"updatedAt,omitempty"`     // last media playlist load
+	Viewers        []ViewerPosition `json:"viewers"`                 // members who loaded a segment in the last minute
+}
+
+// ViewerPosition is where in the stream a member's player is, from the
+// last segment it loaded through the proxy.
+type ViewerPosition struct {
+	UserID          string    `json:"userId"`
+	PositionSeconds float64   `json:"positionSeconds"` // stream time at the start of the segment
+	BehindSeconds   float64   `json:"behindSeconds"`   // live streams: how far behind the edge
+	At              time.Time `json:"at"`
+}
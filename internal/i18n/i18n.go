@@ -0,0 +1,132 @@
+// Package i18n translates user-facing API error messages.
+//
+// Every message has a stable code such as "room_not_found". Error
+// responses carry the code next to the translated text, so clients can
+// branch on the code and show the text as is. The catalogs live in
+// locales/<language>.json, one flat code -> message object per language;
+// messages may contain fmt verbs, filled from the arguments passed to T.
+// English is the reference catalog and the fallback for any code missing
+// from another one. Adding a language is adding a file.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language used when nothing better is known.
+const Default = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs maps language -> code -> message.
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: %v", err))
+	}
+	out := make(map[string]map[string]string, len(files))
+	for _, f := range files {
+		data, err := localeFS.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", f.Name(), err))
+		}
+		out[strings.TrimSuffix(f.Name(), ".json")] = messages
+	}
+	if _, ok := out[Default]; !ok {
+		panic("i18n: missing " + Default + " catalog")
+	}
+	return out
+}
+
+// Languages returns the supported languages, sorted.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Supported reports whether there is a catalog for lang.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// T returns the message for code in lang, formatted with args. Codes
+// missing from lang fall back to English, and codes missing from English
+// to the code itself.
+func T(lang, code string, args ...any) string {
+	format, ok := catalogs[lang][code]
+	if !ok {
+		if format, ok = catalogs[Default][code]; !ok {
+			return code
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Negotiate picks the best supported language from an Accept-Language
+// header, or Default if none matches. Regional tags match their base
+// language ("de-AT" selects "de").
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang := Base(tag)
+		if q > bestQ && Supported(lang) {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Base returns the lowercased primary subtag of a language tag, e.g. "pt"
+// for "pt-BR".
+func Base(tag string) string {
+	base, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	return strings.ToLower(base)
+}
+
+// Message is a code with its arguments, not yet translated. It is also
+// an error whose text is the English message, so functions that return
+// errors can carry one to the code that writes the response.
+type Message struct {
+	Code string
+	Args []any
+}
+
+// Msg creates a Message.
+func Msg(code string, args ...any) Message {
+	return Message{Code: code, Args: args}
+}
+
+// Error returns the English message.
+func (m Message) Error() string {
+	return T(Default, m.Code, m.Args...)
+}
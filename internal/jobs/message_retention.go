@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// retentionPageSize is how many rooms MessageRetention loads at a time.
+const retentionPageSize = 200
+
+// MessageRetention enforces per-room message retention policies:
+//
+//   - forever:  nothing is deleted
+//   - days:     messages older than Days are deleted
+//   - on_close: all messages are deleted once the room is closed (deleted)
+//
+// Rooms without their own policy use the server default.
+type MessageRetention struct {
+	rooms    repository.RoomRepository
+	messages repository.MessageRepository
+	def      models.RetentionPolicy
+	now      func() time.Time
+}
+
+// NewMessageRetention creates the retention job with the given default policy.
+func NewMessageRetention(rooms repository.RoomRepository, messages repository.MessageRepository, def models.RetentionPolicy) *MessageRetention {
+	return &MessageRetention{rooms: rooms, messages: messages, def: def, now: time.Now}
+}
+
+// Run applies every room's policy once. A failing room does not stop the
+// others; the first error is returned after all rooms were visited.
+func (j *MessageRetention) Run(ctx context.Context) error {
+	var (
+		deleted, rooms, failed int
+		firstErr               error
+	)
+	now := j.now()
+
+	for offset := 0; ; offset += retentionPageSize {
+		page, err := j.rooms.ListAll(ctx, retentionPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("list rooms: %w", err)
+		}
+		for _, room := range page {
+			n, err := j.apply(ctx, room, now)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				failed++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("room %s: %w", room.ID, err)
+				}
+				continue
+			}
+			if n > 0 {
+				deleted += n
+				rooms++
+			}
+		}
+		if len(page) < retentionPageSize {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		log.Printf("jobs: message retention deleted %d messages in %d rooms", deleted, rooms)
+	}
+	if firstErr != nil {
+		return fmt.Errorf("%d rooms failed, first: %w", failed, firstErr)
+	}
+	return nil
+}
+
+// apply enforces the effective policy of one room.
+func (j *MessageRetention) apply(ctx context.Context, room *models.Room, now time.Time) (int, error) {
+	policy := j.def
+	if room.Retention != nil {
+		policy = *room.Retention
+	}
+
+	switch policy.Mode {
+	case models.RetentionDays:
+		return j.messages.DeleteBefore(ctx, room.ID, now.AddDate(0, 0, -policy.Days))
+	case models.RetentionOnClose:
+		if room.IsActive {
+			return 0, nil
+		}
+		return j.messages.DeleteByRoom(ctx, room.ID)
+	default:
+		return 0, nil
+	}
+}
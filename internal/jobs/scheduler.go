@@ -0,0 +1,67 @@
+// Package jobs runs periodic background work such as retention cleanup.
+//
+// Usage:
+//
+//	scheduler := jobs.NewScheduler()
+//	scheduler.Add("message-retention", time.Hour, retention.Run)
+//	scheduler.Start(ctx) // returns immediately; jobs stop when ctx is done
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Func is the body of a job. It should return promptly once ctx is done.
+type Func func(ctx context.Context) error
+
+// job is one registered periodic job.
+type job struct {
+	name     string
+	interval time.Duration
+	run      Func
+}
+
+// Scheduler runs registered jobs on fixed intervals. Each job runs in its
+// own goroutine, once at start and then every interval; a slow run delays
+// the next one instead of overlapping it.
+type Scheduler struct {
+	jobs []job
+}
+
+// NewScheduler creates an empty scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job. It must be called before Start.
+func (s *Scheduler) Add(name string, interval time.Duration, run Func) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start launches every registered job. Jobs stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		go j.loop(ctx)
+	}
+}
+
+// loop runs the job until ctx is done.
+func (j job) loop(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := j.run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("jobs: %s failed after %s: %v", j.name, time.Since(start).Round(time.Millisecond), err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ofenes/internal/repository"
+	"ofenes/internal/trust"
+)
+
+// trustPageSize is how many users TrustLevels loads at a time.
+const trustPageSize = 500
+
+// TrustLevels promotes users whose account age and message count meet a
+// higher trust level (see trust.Policy). Users are never demoted, and
+// soft-deleted users are skipped.
+type TrustLevels struct {
+	users    repository.UserRepository
+	messages repository.MessageRepository
+	policy   trust.Policy
+	now      func() time.Time
+}
+
+// NewTrustLevels creates the trust level job.
+func NewTrustLevels(users repository.UserRepository, messages repository.MessageRepository, policy trust.Policy) *TrustLevels {
+	return &TrustLevels{users: users, messages: messages, policy: policy, now: time.Now}
+}
+
+// Run evaluates every user once. A failing user does not stop the others;
+// the first error is returned after all users were visited.
+func (j *TrustLevels) Run(ctx context.Context) error {
+	counts, err := j.messages.CountBySender(ctx)
+	if err != nil {
+		return fmt.Errorf("count messages: %w", err)
+	}
+
+	var (
+		promoted, failed int
+		firstErr         error
+	)
+	now := j.now()
+	filter := repository.UserFilter{Limit: trustPageSize}
+	for ; ; filter.Offset += trustPageSize {
+		page, err := j.users.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("list users: %w", err)
+		}
+		for _, u := range page {
+			level := j.policy.Level(u.CreatedAt, counts[u.ID], now)
+			if trust.Rank(level) < trust.Rank(u.TrustLevel) || level == u.TrustLevel {
+				continue
+			}
+			if err := j.users.SetTrustLevel(ctx, u.ID, level); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				failed++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("user %s: %w", u.ID, err)
+				}
+				continue
+			}
+			promoted++
+		}
+		if len(page) < trustPageSize {
+			break
+		}
+	}
+
+	if promoted > 0 {
+		log.Printf("jobs: trust levels promoted %d users", promoted)
+	}
+	if firstErr != nil {
+		return fmt.Errorf("%d users failed, first: %w", failed, firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package ldap authenticates users against an LDAP directory or Active
+// Directory, using github.com/go-ldap/ldap/v3.
+//
+// A login binds as the service account (BindDN), searches BaseDN for the
+// user with UserFilter, then binds as the entry found with the user's
+// password. Each login uses its own connection.
+//
+// Usage:
+//
+//	dir, err := ldap.New(ldap.Config{URL: "ldaps://ldap.example.com", BaseDN: "ou=people,dc=example,dc=com", UserFilter: "(uid=%s)"})
+//	user, err := dir.Authenticate(ctx, "alice", "secret")
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"ofenes/pkg/apperr"
+)
+
+var (
+	// ErrInvalidCredentials is returned for a wrong password.
+	ErrInvalidCredentials = apperr.New(apperr.Unauthorized, "", "ldap: invalid credentials")
+
+	// ErrUserNotFound is returned when no directory entry matches the
+	// username.
+	ErrUserNotFound = apperr.New(apperr.NotFound, "", "ldap: user not found")
+)
+
+// Config configures a Directory.
+type Config struct {
+	// URL is the server, "ldap://host[:389]" or "ldaps://host[:636]".
+	URL string
+
+	// StartTLS upgrades ldap:// connections to TLS before binding.
+	StartTLS bool
+
+	// BindDN and BindPassword are the service account that looks users
+	// up. Empty BindDN searches anonymously.
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is where users are searched (the whole subtree).
+	BaseDN string
+
+	// UserFilter finds the user's entry; "%s" is replaced by the username,
+	// e.g. "(uid=%s)" or "(&(objectClass=user)(sAMAccountName=%s))".
+	UserFilter string
+
+	// GroupAttribute is the attribute of the user's entry listing the DNs
+	// of their groups (default: memberOf).
+	GroupAttribute string
+
+	// GroupRoles maps the user's groups to a role; the first entry that
+	// matches wins (see ParseGroupRoles). Users in none of the groups get
+	// DefaultRole.
+	GroupRoles  []GroupRole
+	DefaultRole string
+
+	// Timeout bounds each login (default: 10s).
+	Timeout time.Duration
+
+	// TLSConfig is used for ldaps:// and StartTLS (default: verify the
+	// server against the system roots).
+	TLSConfig *tls.Config
+}
+
+// User is an authenticated directory entry.
+type User struct {
+	DN          string
+	DisplayName string
+	Groups      []string // group DNs, from GroupAttribute
+	Role        string   // from GroupRoles, else DefaultRole
+}
+
+// Directory authenticates users against one LDAP server.
+type Directory struct {
+	cfg Config
+}
+
+// New validates cfg and returns a Directory. It does not connect.
+func New(cfg Config) (*Directory, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("ldap: invalid URL %q", cfg.URL)
+	}
+	switch u.Scheme {
+	case "ldap":
+	case "ldaps":
+		if cfg.StartTLS {
+			return nil, errors.New("ldap: StartTLS is for ldap:// URLs")
+		}
+	default:
+		return nil, fmt.Errorf("ldap: URL %q must start with ldap:// or ldaps://", cfg.URL)
+	}
+	if cfg.BaseDN == "" {
+		return nil, errors.New("ldap: base DN is required")
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, fmt.Errorf("ldap: user filter %q must contain %%s", cfg.UserFilter)
+	}
+	if _, err := goldap.CompileFilter(strings.ReplaceAll(cfg.UserFilter, "%s", "x")); err != nil {
+		return nil, fmt.Errorf("ldap: user filter %q: %w", cfg.UserFilter, err)
+	}
+	d := &Directory{cfg: cfg}
+	if d.cfg.GroupAttribute == "" {
+		d.cfg.GroupAttribute = "memberOf"
+	}
+	if d.cfg.Timeout <= 0 {
+		d.cfg.Timeout = 10 * time.Second
+	}
+	if d.cfg.TLSConfig == nil {
+		d.cfg.TLSConfig = &tls.Config{ServerName: u.Hostname()}
+	}
+	return d, nil
+}
+
+// Authenticate checks username and password against the directory and
+// returns the user's entry. It returns ErrUserNotFound if no entry
+// matches username and ErrInvalidCredentials if the password is wrong.
+// Other errors mean the directory could not be asked.
+func (d *Directory) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	if username == "" || password == "" {
+		// An empty password would be an unauthenticated bind, which
+		// many servers accept for any DN.
+		return nil, ErrInvalidCredentials
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	c, err := d.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if d.cfg.BindDN != "" {
+		if err := c.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			// Not %w: a rejected service account is not the user's wrong password.
+			return nil, fmt.Errorf("ldap: service bind: %v", err)
+		}
+	}
+	user, err := d.searchUser(c, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Bind(user.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: bind: %w", err)
+	}
+	user.Role = roleFor(user.Groups, d.cfg.GroupRoles, d.cfg.DefaultRole)
+	return user, nil
+}
+
+// dial connects, upgrading with StartTLS if configured. The connection is
+// closed when ctx ends, failing any request in flight.
+func (d *Directory) dial(ctx context.Context) (*goldap.Conn, error) {
+	dialer := &net.Dialer{}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	c, err := goldap.DialURL(d.cfg.URL,
+		goldap.DialWithDialer(dialer),
+		goldap.DialWithTLSConfig(d.cfg.TLSConfig))
+	if err != nil {
+		return nil, fmt.Errorf("ldap: %w", err)
+	}
+	c.SetTimeout(d.cfg.Timeout)
+	context.AfterFunc(ctx, func() { c.Close() })
+
+	if d.cfg.StartTLS {
+		if err := c.StartTLS(d.cfg.TLSConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("ldap: StartTLS: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// searchUser finds the single entry matching d's user filter.
+func (d *Directory) searchUser(c *goldap.Conn, username string) (*User, error) {
+	req := goldap.NewSearchRequest(d.cfg.BaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		2, // size limit: we only need to know if there is more than one
+		int(d.cfg.Timeout.Seconds()), false,
+		strings.ReplaceAll(d.cfg.UserFilter, "%s", goldap.EscapeFilter(username)),
+		[]string{d.cfg.GroupAttribute, "displayName"}, nil)
+	res, err := c.Search(req)
+	if err != nil && (res == nil || len(res.Entries) < 2) {
+		return nil, fmt.Errorf("ldap: search: %w", err)
+	}
+	switch len(res.Entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+		e := res.Entries[0]
+		return &User{
+			DN:          e.DN,
+			DisplayName: e.GetEqualFoldAttributeValue("displayName"),
+			Groups:      e.GetEqualFoldAttributeValues(d.cfg.GroupAttribute),
+		}, nil
+	default:
+		return nil, fmt.Errorf("ldap: %d entries match user %q; make the user filter more specific", len(res.Entries), username)
+	}
+}
@@ -0,0 +1,46 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupRole grants Role to members of the group GroupDN.
+type GroupRole struct {
+	Role    string
+	GroupDN string
+}
+
+// ParseGroupRoles parses a group-to-role mapping of the form
+// "role=groupDN;role=groupDN", e.g.
+// "admin=cn=admins,ou=groups,dc=example,dc=com;viewer=cn=guests,ou=groups,dc=example,dc=com".
+// Entries are checked in order, so list the most privileged role first.
+func ParseGroupRoles(s string) ([]GroupRole, error) {
+	var mapping []GroupRole
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		role, dn, ok := strings.Cut(entry, "=")
+		role, dn = strings.TrimSpace(role), strings.TrimSpace(dn)
+		if !ok || role == "" || dn == "" {
+			return nil, fmt.Errorf("ldap: invalid group mapping %q (want role=groupDN)", entry)
+		}
+		mapping = append(mapping, GroupRole{Role: role, GroupDN: dn})
+	}
+	return mapping, nil
+}
+
+// roleFor returns the role of the first mapping entry whose group is in
+// groups, or fallback if none is. DNs are compared case-insensitively.
+func roleFor(groups []string, mapping []GroupRole, fallback string) string {
+	for _, m := range mapping {
+		for _, g := range groups {
+			if strings.EqualFold(strings.TrimSpace(g), m.GroupDN) {
+				return m.Role
+			}
+		}
+	}
+	return fallback
+}
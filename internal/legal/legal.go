@@ -0,0 +1,123 @@
+// Package legal tracks the documents users must accept on public
+// instances: the terms of service and the privacy policy. Admins publish
+// versions of each (see models.LegalDocument); the newest is the current
+// one. Until a user has accepted the current version of every published
+// document, the API answers them 428 (see middleware.RequireLegal).
+//
+// A Tracker keeps the current versions in memory, reloaded on change and
+// periodically so other instances' changes are seen, and remembers the
+// acceptances it has seen. Acceptances are never withdrawn, so those can
+// be kept until the cache fills up.
+//
+// Usage:
+//
+//	tracker := legal.New(legalRepo)
+//	_ = tracker.Reload(ctx)
+//	pending, err := tracker.Pending(ctx, userID) // empty once all are accepted
+package legal
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// Kinds are the documents, in the order they are listed.
+var Kinds = []string{models.LegalTerms, models.LegalPrivacy}
+
+// maxCached bounds the acceptances remembered; the cache is emptied once
+// it is reached.
+const maxCached = 100000
+
+// Tracker answers which current documents users have yet to accept. The
+// zero value is not usable; create one with New. Safe for concurrent use.
+type Tracker struct {
+	repo    repository.LegalRepository
+	current atomic.Pointer[[]*models.LegalDocument]
+
+	mu       sync.Mutex
+	accepted map[string]struct{} // acceptanceKey -> present
+}
+
+// New creates a Tracker backed by repo. It knows no documents until
+// Reload.
+func New(repo repository.LegalRepository) *Tracker {
+	return &Tracker{repo: repo, accepted: make(map[string]struct{})}
+}
+
+// IsKind reports whether kind is one of Kinds.
+func IsKind(kind string) bool {
+	return slices.Contains(Kinds, kind)
+}
+
+// Reload replaces the current documents with the newest versions in
+// storage.
+func (t *Tracker) Reload(ctx context.Context) error {
+	var current []*models.LegalDocument
+	for _, kind := range Kinds {
+		doc, err := t.repo.Current(ctx, kind)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		current = append(current, doc)
+	}
+	t.current.Store(&current)
+	return nil
+}
+
+// Current returns the current version of each published document, in the
+// order of Kinds.
+func (t *Tracker) Current() []*models.LegalDocument {
+	if current := t.current.Load(); current != nil {
+		return *current
+	}
+	return nil
+}
+
+// Pending returns the current documents userID has yet to accept.
+func (t *Tracker) Pending(ctx context.Context, userID string) ([]*models.LegalDocument, error) {
+	var pending []*models.LegalDocument
+	for _, doc := range t.Current() {
+		key := acceptanceKey(userID, doc.Kind, doc.Version)
+		t.mu.Lock()
+		_, ok := t.accepted[key]
+		t.mu.Unlock()
+		if ok {
+			continue
+		}
+		ok, err := t.repo.HasAccepted(ctx, userID, doc.Kind, doc.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			pending = append(pending, doc)
+			continue
+		}
+		t.Remember(userID, doc.Kind, doc.Version)
+	}
+	return pending, nil
+}
+
+// Remember notes that userID accepted that version of kind, once it is
+// stored, so Pending need not ask storage again.
+func (t *Tracker) Remember(userID, kind, version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.accepted) >= maxCached {
+		clear(t.accepted)
+	}
+	t.accepted[acceptanceKey(userID, kind, version)] = struct{}{}
+}
+
+// acceptanceKey identifies an acceptance in the cache.
+func acceptanceKey(userID, kind, version string) string {
+	return userID + "\x00" + kind + "\x00" + version
+}
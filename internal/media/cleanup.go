@@ -0,0 +1,38 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/repository"
+	"ofenes/internal/retention"
+)
+
+// AbandonedUploads returns the retention target for uploads started before
+// the cutoff that never finished: their records and bytes are deleted.
+func AbandonedUploads(files repository.MediaFileRepository, store Store) retention.Target {
+	return retention.Target{
+		Count: func(ctx context.Context, before time.Time) (int, error) {
+			stale, err := files.ListUploadingBefore(ctx, before)
+			return len(stale), err
+		},
+		Delete: func(ctx context.Context, before time.Time) (int, error) {
+			stale, err := files.ListUploadingBefore(ctx, before)
+			if err != nil {
+				return 0, err
+			}
+			deleted := 0
+			for _, f := range stale {
+				if err := store.DeletePrefix(f.ID); err != nil {
+					return deleted, err
+				}
+				if err := files.Delete(ctx, f.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return deleted, err
+				}
+				deleted++
+			}
+			return deleted, nil
+		},
+	}
+}
@@ -0,0 +1,185 @@
+// Package media stores uploaded videos, cleans up abandoned uploads and
+// gates streaming them with playback tokens.
+//
+// The bytes of each upload live in a Store under the ID of its
+// models.MediaFile record; the record says whether the upload is complete.
+// Files derived from it by package transcode — HLS renditions, poster and
+// preview sprite — are stored next to it, under DerivedKey.
+// Uploads are resumable: each chunk is appended at the offset the client
+// believes the file has reached, and rejected if that is not the current
+// size, so a client that lost track asks for the record (whose Received is
+// the stored size) and carries on from there.
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"ofenes/pkg/apperr"
+)
+
+var (
+	// ErrOffsetMismatch is returned by Append when the offset is not the
+	// stored size.
+	ErrOffsetMismatch = apperr.New(apperr.Conflict, "", "media: offset does not match the stored size")
+
+	// ErrBusy is returned by Append while another chunk is being written
+	// to the same key.
+	ErrBusy = apperr.New(apperr.Conflict, "upload_in_progress", "media: another chunk is being written")
+)
+
+// Store keeps the bytes of uploaded media. Keys are media file IDs.
+type Store interface {
+	// Append copies r to the end of key, which must hold exactly offset
+	// bytes (a missing key holds none), and returns the new size. If r
+	// fails midway, what was written is kept and the size returned with
+	// the error.
+	Append(key string, offset int64, r io.Reader) (int64, error)
+
+	// Size returns how many bytes key holds (0 if missing).
+	Size(key string) (int64, error)
+
+	// Open opens key for reading.
+	Open(key string) (io.ReadSeekCloser, error)
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key string) error
+
+	// DeletePrefix removes every key starting with prefix, such as an
+	// upload and the files derived from it (see DerivedKey).
+	DeletePrefix(prefix string) error
+}
+
+// DerivedKey returns the key of file name (a playlist, segment or image)
+// derived from the upload stored under id.
+func DerivedKey(id, name string) string {
+	return id + "." + name
+}
+
+// DiskStore is a Store keeping each key in a file of its own in a
+// directory. It is safe for concurrent use.
+type DiskStore struct {
+	dir string
+
+	mu      sync.Mutex
+	writing map[string]bool
+}
+
+// NewDiskStore creates a store in dir, creating the directory if needed.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("media: failed to create %s: %w", dir, err)
+	}
+	return &DiskStore{dir: dir, writing: make(map[string]bool)}, nil
+}
+
+// path returns the file holding key. Keys are IDs, never paths.
+func (s *DiskStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("media: invalid key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Append implements Store.
+func (s *DiskStore) Append(key string, offset int64, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	if s.writing[key] {
+		s.mu.Unlock()
+		return 0, ErrBusy
+	}
+	s.writing[key] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.writing, key)
+		s.mu.Unlock()
+	}()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if info.Size() != offset {
+		f.Close()
+		return info.Size(), ErrOffsetMismatch
+	}
+
+	n, copyErr := io.Copy(f, r)
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	return offset + n, copyErr
+}
+
+// Size implements Store.
+func (s *DiskStore) Size(key string) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Open implements Store.
+func (s *DiskStore) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete implements Store.
+func (s *DiskStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix implements Store.
+func (s *DiskStore) DeletePrefix(prefix string) error {
+	if _, err := s.path(prefix); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		if err := s.Delete(e.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ofenes/pkg/apperr"
+)
+
+// streamIdle is how long a stream with no requests in flight still counts
+// as playing: players pause loading once they have buffered enough.
+const streamIdle = time.Minute
+
+// ErrInvalidPlayback is returned by ParsePlayback for tokens that are
+// malformed or not signed with the secret.
+var ErrInvalidPlayback = apperr.New(apperr.Unauthorized, "invalid_playback_token", "media: invalid playback token")
+
+// Playback is what a playback token grants: streaming one upload as one
+// user. Every token is a stream of its own, counted by Streams.
+type Playback struct {
+	MediaID  string
+	UserID   string
+	StreamID string
+	Expires  time.Time
+}
+
+// SignPlayback creates a token for p.
+// Format: base64url("mediaID|userID|streamID|expiresUnix") + "." + base64url(HMAC-SHA256).
+func SignPlayback(secret string, p Playback) string {
+	body := strings.Join([]string{p.MediaID, p.UserID, p.StreamID, strconv.FormatInt(p.Expires.Unix(), 10)}, "|")
+	enc := base64.RawURLEncoding.EncodeToString([]byte(body))
+	return enc + "." + playbackMAC(secret, enc)
+}
+
+// ParsePlayback checks the signature of token and returns what it grants.
+// It does not check the expiry: a stream started in time may go on (see
+// Streams.Playing).
+func ParsePlayback(secret, token string) (*Playback, error) {
+	enc, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(playbackMAC(secret, enc))) {
+		return nil, ErrInvalidPlayback
+	}
+	body, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, ErrInvalidPlayback
+	}
+	parts := strings.Split(string(body), "|")
+	if len(parts) != 4 {
+		return nil, ErrInvalidPlayback
+	}
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPlayback
+	}
+	return &Playback{MediaID: parts[0], UserID: parts[1], StreamID: parts[2], Expires: time.Unix(expires, 0)}, nil
+}
+
+// playbackMAC signs data, set apart from other tokens signed with the same
+// secret.
+func playbackMAC(secret, data string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte("playback\n" + data))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// Streams counts the streams each user is playing on this instance and
+// caps them. A stream is playing while it has requests in flight and for
+// streamIdle after the last one ends; a player's many Range requests are
+// one stream. Safe for concurrent use.
+type Streams struct {
+	limit int // 0 = unlimited
+
+	mu        sync.Mutex
+	users     map[string]map[string]*stream // user ID -> stream ID
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type stream struct {
+	inFlight int
+	last     time.Time // when the last request ended
+}
+
+// NewStreams creates a Streams allowing each user limit streams at once,
+// any number if limit is 0.
+func NewStreams(limit int) *Streams {
+	return &Streams{limit: limit, users: make(map[string]map[string]*stream), now: time.Now}
+}
+
+// Start counts a request of stream streamID by userID. It reports false,
+// counting nothing, if this would be one stream more than the user may
+// play; otherwise done must be called when the request ends.
+func (s *Streams) Start(userID, streamID string) (done func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= streamIdle {
+		s.lastSweep = now
+		for id := range s.users {
+			s.prune(id, now)
+		}
+	} else {
+		s.prune(userID, now)
+	}
+
+	st, playing := s.users[userID][streamID]
+	if !playing {
+		if s.limit > 0 && len(s.users[userID]) >= s.limit {
+			return nil, false
+		}
+		if s.users[userID] == nil {
+			s.users[userID] = make(map[string]*stream)
+		}
+		st = &stream{}
+		s.users[userID][streamID] = st
+	}
+	st.inFlight++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			st.inFlight--
+			st.last = s.now()
+		})
+	}, true
+}
+
+// Playing reports whether stream streamID of userID is playing.
+func (s *Streams) Playing(userID, streamID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(userID, s.now())
+	_, ok := s.users[userID][streamID]
+	return ok
+}
+
+// prune forgets the streams of userID that stopped playing.
+func (s *Streams) prune(userID string, now time.Time) {
+	streams := s.users[userID]
+	for id, st := range streams {
+		if st.inFlight == 0 && now.Sub(st.last) >= streamIdle {
+			delete(streams, id)
+		}
+	}
+	if len(streams) == 0 {
+		delete(s.users, userID)
+	}
+}
@@ -0,0 +1,173 @@
+// Package metadata recognizes movies and TV episodes in video titles and
+// looks up their title, year, poster and runtime with The Movie Database
+// (TMDB) or the Open Movie Database (OMDb).
+//
+// Only titles that name a year ("Alien (1979)") or an episode ("The
+// Office S02E01", "Lost 1x05") are recognized; scene-style file names
+// ("Alien.1979.1080p.BluRay.mkv") work too. Answers, misses included, are
+// cached in a repository.MetadataRepository so each title is looked up
+// once per cache TTL.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/apperr"
+)
+
+// missTTL is how long a title nothing matched is remembered, shorter than
+// hits since providers add titles.
+const missTTL = 24 * time.Hour
+
+// Query is what a title was recognized as.
+type Query struct {
+	Title   string // the movie's or series' name
+	Year    int    // 0 if not given
+	Season  int    // episodes only
+	Episode int    // episodes only
+}
+
+// IsEpisode reports whether q is a TV episode rather than a movie.
+func (q Query) IsEpisode() bool {
+	return q.Episode > 0
+}
+
+// Key identifies q in the cache: the same title written differently maps
+// to the same key.
+func (q Query) Key() string {
+	key := strings.Join(strings.FieldsFunc(strings.ToLower(q.Title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+	if q.Year > 0 {
+		key += fmt.Sprintf(" (%d)", q.Year)
+	}
+	if q.IsEpisode() {
+		key += fmt.Sprintf(" s%02de%02d", q.Season, q.Episode)
+	}
+	return key
+}
+
+var (
+	// videoExt matches the extensions of video file names.
+	videoExt = regexp.MustCompile(`(?i)^\.(mkv|mp4|m4v|avi|mov|webm|wmv|ts|m2ts|mpg|mpeg)$`)
+
+	// episodeMarker matches S01E02 and 1x02.
+	episodeMarker = regexp.MustCompile(`(?i)\bs(\d{1,2})\s?e(\d{1,3})\b|\b(\d{1,2})x(\d{2,3})\b`)
+
+	// yearMarker matches release years, bare or in brackets.
+	yearMarker = regexp.MustCompile(`[(\[]?\b((?:19|20)\d{2})\b[)\]]?`)
+
+	// releaseTag matches what release names put after the title.
+	releaseTag = regexp.MustCompile(`(?i)\b(2160p|1080p|720p|480p|4k|uhd|bluray|blu-ray|bdrip|brrip|web-?dl|webrip|hdtv|dvdrip|x26[45]|h\.?26[45]|hevc|remux|proper|repack)\b`)
+)
+
+// Parse recognizes a movie or episode in a video's title or file name.
+// It reports false for titles naming neither a year nor an episode.
+func Parse(title string) (Query, bool) {
+	s := strings.TrimSpace(title)
+	if videoExt.MatchString(path.Ext(s)) {
+		s = strings.TrimSuffix(s, path.Ext(s))
+	}
+	if !strings.Contains(s, " ") {
+		s = strings.NewReplacer(".", " ", "_", " ").Replace(s)
+	}
+	if m := releaseTag.FindStringIndex(s); m != nil {
+		s = s[:m[0]]
+	}
+
+	var q Query
+	if m := episodeMarker.FindStringSubmatchIndex(s); m != nil {
+		i := 2 // S01E02, or else 1x02
+		if m[i] < 0 {
+			i = 6
+		}
+		season, episode := s[m[i]:m[i+1]], s[m[i+2]:m[i+3]]
+		q.Season, _ = strconv.Atoi(season)
+		q.Episode, _ = strconv.Atoi(episode)
+		s = s[:m[0]]
+	}
+	// The last year, so titles like "2001: A Space Odyssey (1968)" keep
+	// theirs; years to come are part of titles, as in "Blade Runner 2049".
+	if ms := yearMarker.FindAllStringSubmatchIndex(s, -1); ms != nil {
+		m := ms[len(ms)-1]
+		if y, _ := strconv.Atoi(s[m[2]:m[3]]); m[0] > 0 && y <= time.Now().Year()+1 {
+			q.Year = y
+			s = s[:m[0]]
+		}
+	}
+
+	q.Title = strings.Trim(strings.Join(strings.Fields(s), " "), " -–:|")
+	if q.Title == "" || (q.Year == 0 && !q.IsEpisode()) {
+		return Query{}, false
+	}
+	return q, true
+}
+
+// ErrNoMatch is returned by providers when nothing matches a query.
+var ErrNoMatch = apperr.New(apperr.NotFound, "", "metadata: no match")
+
+// Provider looks up queries.
+type Provider interface {
+	// Lookup returns the metadata of the best match for q, or ErrNoMatch.
+	Lookup(ctx context.Context, q Query) (*models.MediaMetadata, error)
+}
+
+// Enricher looks titles up through a Provider and a cache. Safe for
+// concurrent use.
+type Enricher struct {
+	provider Provider
+	cache    repository.MetadataRepository
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewEnricher creates an Enricher keeping answers in cache for ttl.
+func NewEnricher(provider Provider, cache repository.MetadataRepository, ttl time.Duration) *Enricher {
+	return &Enricher{provider: provider, cache: cache, ttl: ttl, now: time.Now}
+}
+
+// Lookup returns the metadata of the movie or episode title names, or nil
+// if it names none or nothing matched. Provider errors are returned and
+// not cached; cache errors are only logged.
+func (e *Enricher) Lookup(ctx context.Context, title string) (*models.MediaMetadata, error) {
+	q, ok := Parse(title)
+	if !ok {
+		return nil, nil
+	}
+	key := q.Key()
+
+	cached, err := e.cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		ttl := e.ttl
+		if cached.Metadata == nil {
+			ttl = min(ttl, missTTL)
+		}
+		if e.now().Sub(cached.FetchedAt) < ttl {
+			return cached.Metadata, nil
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		log.Printf("metadata: cache: %v", err)
+	}
+
+	m, err := e.provider.Lookup(ctx, q)
+	if err != nil && !errors.Is(err, ErrNoMatch) {
+		return nil, err
+	}
+	lookup := &models.MetadataLookup{Key: key, Metadata: m, FetchedAt: e.now()}
+	if err := e.cache.Put(ctx, lookup); err != nil {
+		log.Printf("metadata: cache: %v", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,142 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// omdbAPI is the base URL of OMDb's API.
+const omdbAPI = "https://www.omdbapi.com/"
+
+// OMDb looks queries up with the Open Movie Database.
+type OMDb struct {
+	key    string
+	base   string
+	client *http.Client
+}
+
+// NewOMDb creates an OMDb provider with API key key.
+func NewOMDb(key string) *OMDb {
+	return &OMDb{key: key, base: omdbAPI, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// omdbTitle is OMDb's answer to a title lookup. Missing fields are "N/A".
+type omdbTitle struct {
+	Response string `json:"Response"` // "True" or "False"
+	Error    string `json:"Error"`    // if Response is "False"
+	Title    string `json:"Title"`
+	Year     string `json:"Year"`    // "1979", or "2005–" for series
+	Runtime  string `json:"Runtime"` // "117 min"
+	Poster   string `json:"Poster"`
+	IMDbID   string `json:"imdbID"`
+	SeriesID string `json:"seriesID"` // episodes only
+}
+
+// Lookup returns OMDb's match for the title, and for episodes the
+// series' title and poster.
+func (o *OMDb) Lookup(ctx context.Context, q Query) (*models.MediaMetadata, error) {
+	params := url.Values{"t": {q.Title}}
+	if q.IsEpisode() {
+		params.Set("type", "series")
+	} else {
+		params.Set("type", "movie")
+		if q.Year > 0 {
+			params.Set("y", strconv.Itoa(q.Year))
+		}
+	}
+	found, err := o.get(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !q.IsEpisode() {
+		return &models.MediaMetadata{
+			Kind:      models.MetadataKindMovie,
+			Title:     found.Title,
+			Year:      year(found.Year),
+			PosterURL: omdbValue(found.Poster),
+			Runtime:   minutes(found.Runtime),
+			Source:    models.MetadataSourceOMDb,
+			SourceID:  found.IMDbID,
+		}, nil
+	}
+
+	series := found
+	episode, err := o.get(ctx, url.Values{
+		"i":       {series.IMDbID},
+		"Season":  {strconv.Itoa(q.Season)},
+		"Episode": {strconv.Itoa(q.Episode)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	poster := omdbValue(episode.Poster)
+	if poster == "" {
+		poster = omdbValue(series.Poster)
+	}
+	return &models.MediaMetadata{
+		Kind:         models.MetadataKindEpisode,
+		Title:        series.Title,
+		Year:         year(episode.Year),
+		Season:       q.Season,
+		Episode:      q.Episode,
+		EpisodeTitle: episode.Title,
+		PosterURL:    poster,
+		Runtime:      minutes(episode.Runtime),
+		Source:       models.MetadataSourceOMDb,
+		SourceID:     episode.IMDbID,
+	}, nil
+}
+
+// get returns OMDb's answer to params, ErrNoMatch if it found nothing.
+func (o *OMDb) get(ctx context.Context, params url.Values) (*omdbTitle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.base, nil)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("apikey", o.key)
+	req.URL.RawQuery = params.Encode()
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("metadata: omdb: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+	// Invalid keys get a 401 with a JSON body like any other error.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return nil, fmt.Errorf("metadata: omdb: %s", resp.Status)
+	}
+	var title omdbTitle
+	if err := json.NewDecoder(resp.Body).Decode(&title); err != nil {
+		return nil, fmt.Errorf("metadata: omdb: %w", err)
+	}
+	if title.Response != "True" {
+		if strings.HasSuffix(title.Error, "not found!") {
+			return nil, ErrNoMatch
+		}
+		return nil, fmt.Errorf("metadata: omdb: %s", title.Error)
+	}
+	return &title, nil
+}
+
+// omdbValue returns s, or "" if OMDb marked it missing.
+func omdbValue(s string) string {
+	if s == "N/A" {
+		return ""
+	}
+	return s
+}
+
+// minutes parses an OMDb runtime, "117 min", 0 if missing.
+func minutes(runtime string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(runtime, " min"))
+	return n
+}
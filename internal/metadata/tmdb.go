@@ -0,0 +1,190 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+const (
+	// tmdbAPI is the base URL of TMDB's API, version 3.
+	tmdbAPI = "https://api.themoviedb.org/3"
+
+	// tmdbPosters is where TMDB serves posters, 342 pixels wide.
+	tmdbPosters = "https://image.tmdb.org/t/p/w342"
+)
+
+// TMDB looks queries up with The Movie Database.
+type TMDB struct {
+	key    string
+	base   string
+	client *http.Client
+}
+
+// NewTMDB creates a TMDB provider. key is an API key (v3) or a read access
+// token (v4).
+func NewTMDB(key string) *TMDB {
+	return &TMDB{key: key, base: tmdbAPI, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Lookup returns the first match of TMDB's search, with the runtime from
+// its details.
+func (t *TMDB) Lookup(ctx context.Context, q Query) (*models.MediaMetadata, error) {
+	if q.IsEpisode() {
+		return t.episode(ctx, q)
+	}
+	return t.movie(ctx, q)
+}
+
+func (t *TMDB) movie(ctx context.Context, q Query) (*models.MediaMetadata, error) {
+	params := url.Values{"query": {q.Title}}
+	if q.Year > 0 {
+		params.Set("year", strconv.Itoa(q.Year))
+	}
+	var search struct {
+		Results []struct {
+			ID          int    `json:"id"`
+			Title       string `json:"title"`
+			ReleaseDate string `json:"release_date"`
+			PosterPath  string `json:"poster_path"`
+		} `json:"results"`
+	}
+	if err := t.get(ctx, "/search/movie", params, &search); err != nil {
+		return nil, err
+	}
+	if len(search.Results) == 0 {
+		return nil, ErrNoMatch
+	}
+	found := search.Results[0]
+
+	var details struct {
+		Runtime int `json:"runtime"`
+	}
+	if err := t.get(ctx, "/movie/"+strconv.Itoa(found.ID), nil, &details); err != nil {
+		return nil, err
+	}
+	return &models.MediaMetadata{
+		Kind:      models.MetadataKindMovie,
+		Title:     found.Title,
+		Year:      year(found.ReleaseDate),
+		PosterURL: tmdbPoster(found.PosterPath),
+		Runtime:   details.Runtime,
+		Source:    models.MetadataSourceTMDB,
+		SourceID:  strconv.Itoa(found.ID),
+	}, nil
+}
+
+func (t *TMDB) episode(ctx context.Context, q Query) (*models.MediaMetadata, error) {
+	params := url.Values{"query": {q.Title}}
+	if q.Year > 0 {
+		params.Set("first_air_date_year", strconv.Itoa(q.Year))
+	}
+	var search struct {
+		Results []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			PosterPath string `json:"poster_path"`
+		} `json:"results"`
+	}
+	if err := t.get(ctx, "/search/tv", params, &search); err != nil {
+		return nil, err
+	}
+	if len(search.Results) == 0 {
+		return nil, ErrNoMatch
+	}
+	series := search.Results[0]
+
+	var episode struct {
+		ID      int    `json:"id"`
+		Name    string `json:"name"`
+		AirDate string `json:"air_date"`
+		Runtime int    `json:"runtime"`
+	}
+	path := fmt.Sprintf("/tv/%d/season/%d/episode/%d", series.ID, q.Season, q.Episode)
+	if err := t.get(ctx, path, nil, &episode); err != nil {
+		return nil, err
+	}
+	return &models.MediaMetadata{
+		Kind:         models.MetadataKindEpisode,
+		Title:        series.Name,
+		Year:         year(episode.AirDate),
+		Season:       q.Season,
+		Episode:      q.Episode,
+		EpisodeTitle: episode.Name,
+		PosterURL:    tmdbPoster(series.PosterPath),
+		Runtime:      episode.Runtime,
+		Source:       models.MetadataSourceTMDB,
+		SourceID:     strconv.Itoa(episode.ID),
+	}, nil
+}
+
+// get decodes the response of the API endpoint path into v. A 404, for
+// an episode a series does not have, is ErrNoMatch.
+func (t *TMDB) get(ctx context.Context, path string, params url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.base+path, nil)
+	if err != nil {
+		return err
+	}
+	// v4 read access tokens are JWTs; v3 API keys go in the query.
+	if strings.HasPrefix(t.key, "eyJ") {
+		req.Header.Set("Authorization", "Bearer "+t.key)
+	} else {
+		if params == nil {
+			params = url.Values{}
+		}
+		params.Set("api_key", t.key)
+	}
+	req.URL.RawQuery = params.Encode()
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("metadata: tmdb %s: %w", path, withoutURL(err))
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNoMatch
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("metadata: tmdb %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("metadata: tmdb %s: %w", path, err)
+	}
+	return nil
+}
+
+// withoutURL strips the request URL, which holds the API key, from errors
+// of http.Client.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// tmdbPoster returns the URL of a TMDB poster path, "" if there is none.
+func tmdbPoster(path string) string {
+	if path == "" {
+		return ""
+	}
+	return tmdbPosters + path
+}
+
+// year returns the year of a date starting yyyy, 0 if it does not.
+func year(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	y, _ := strconv.Atoi(date[:4])
+	return y
+}
@@ -0,0 +1,386 @@
+// Package metrics provides a small, dependency-free metrics registry.
+//
+// Metrics are registered by name (plus optional label pairs) and exposed
+// in the Prometheus text exposition format via Registry.Handler, so any
+// standard scraper can consume them without pulling in a client library.
+//
+// Usage:
+//
+//	reg := metrics.NewRegistry()
+//	dropped := reg.Counter("ws_messages_dropped_total", "Messages dropped.", "policy", "drop_oldest")
+//	dropped.Inc()
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metric is implemented by every series type in the registry.
+type metric interface {
+	writeTo(w io.Writer, name, labels string)
+}
+
+// family groups all series sharing a metric name.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	series map[string]metric // keyed by rendered label set
+}
+
+// Registry holds all registered metrics. Safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Counter returns the counter for name and the given label pairs,
+// creating it on first use. Labels are passed as key, value, key, value...
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return r.lookup(name, help, "counter", labels, func() metric { return &Counter{} }).(*Counter)
+}
+
+// Gauge returns the gauge for name and the given label pairs, creating it on first use.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return r.lookup(name, help, "gauge", labels, func() metric { return &Gauge{} }).(*Gauge)
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape.
+// Use it for values owned by another component, such as connection pool
+// stats. Registering the same name and labels again keeps the first fn.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	r.lookup(name, help, "gauge", labels, func() metric { return funcMetric(fn) })
+}
+
+// CounterFunc registers a counter whose value is read from fn on every
+// scrape. fn must never decrease.
+func (r *Registry) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	r.lookup(name, help, "counter", labels, func() metric { return funcMetric(fn) })
+}
+
+// Summary returns the summary for name and the given label pairs, creating it
+// on first use. Quantiles are computed over the most recent observations.
+func (r *Registry) Summary(name, help string, labels ...string) *Summary {
+	return r.lookup(name, help, "summary", labels, func() metric { return newSummary(summaryWindow) }).(*Summary)
+}
+
+// Histogram returns the histogram for name and the given label pairs,
+// creating it on first use with the given upper bounds, which must be
+// sorted. Unlike a Summary, histograms can be aggregated across series
+// and instances.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return r.lookup(name, help, "histogram", labels, func() metric { return newHistogram(buckets) }).(*Histogram)
+}
+
+// Remove drops the series for name and the given label pairs, e.g. one
+// labeled with a room that no longer exists, so labels that come and go
+// don't accumulate. Holders of the series can keep using it; it is just
+// no longer exported.
+func (r *Registry) Remove(name string, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		return
+	}
+	delete(f.series, renderLabels(labels))
+	if len(f.series) == 0 {
+		delete(r.families, name)
+	}
+}
+
+// lookup finds or creates a series, enforcing one kind per metric name.
+func (r *Registry) lookup(name, help, kind string, labels []string, create func() metric) metric {
+	key := renderLabels(labels)
+
+	r.mu.RLock()
+	if f, ok := r.families[name]; ok {
+		if m, ok := f.series[key]; ok {
+			r.mu.RUnlock()
+			return m
+		}
+	}
+	r.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: kind, series: make(map[string]metric)}
+		r.families[name] = f
+	}
+	if f.kind != kind {
+		panic(fmt.Sprintf("metrics: %s registered as %s, requested as %s", name, f.kind, kind))
+	}
+	m, ok := f.series[key]
+	if !ok {
+		m = create()
+		f.series[key] = m
+	}
+	return m
+}
+
+// WritePrometheus writes every metric in the Prometheus text format.
+func (r *Registry) WritePrometheus(w io.Writer) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		if f.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f.series[k].writeTo(w, f.name, k)
+		}
+	}
+}
+
+// Handler returns an http.Handler serving the registry's metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WritePrometheus(w)
+	})
+}
+
+// renderLabels formats label pairs as {k="v",...}. Odd trailing keys are ignored.
+func renderLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", labels[i], labels[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// --- Counter ---
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add increments the counter by n.
+func (c *Counter) Add(n uint64) { c.v.Add(n) }
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+func (c *Counter) writeTo(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s%s %d\n", name, labels, c.Value())
+}
+
+// --- Gauge ---
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+// Add adds delta (which may be negative) to the gauge.
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if g.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// SetMax raises the gauge to v if v is greater than the current value.
+func (g *Gauge) SetMax(v float64) {
+	for {
+		old := g.bits.Load()
+		if math.Float64frombits(old) >= v {
+			return
+		}
+		if g.bits.CompareAndSwap(old, math.Float64bits(v)) {
+			return
+		}
+	}
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+func (g *Gauge) writeTo(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s%s %g\n", name, labels, g.Value())
+}
+
+// --- Func ---
+
+// funcMetric is a series whose value is computed at scrape time.
+type funcMetric func() float64
+
+func (f funcMetric) writeTo(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s%s %g\n", name, labels, f())
+}
+
+// --- Histogram ---
+
+// Histogram counts observations in cumulative buckets, plus their count
+// and sum.
+type Histogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // per bucket, not cumulative; the last is +Inf
+	count  atomic.Uint64
+	sum    Gauge
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(v)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 { return h.count.Load() }
+
+func (h *Histogram) writeTo(w io.Writer, name, labels string) {
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i].Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", fmt.Sprint(bound)), cumulative)
+	}
+	cumulative += h.counts[len(h.bounds)].Load()
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), cumulative)
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.sum.Value())
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, cumulative)
+}
+
+// --- Summary ---
+
+// summaryWindow is how many recent observations a Summary keeps for quantiles.
+const summaryWindow = 1024
+
+// summaryQuantiles are the quantiles reported for every Summary.
+var summaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Summary tracks a count and sum of observations plus quantiles over a
+// sliding window of the most recent values.
+type Summary struct {
+	mu     sync.Mutex
+	window []float64
+	next   int
+	filled bool
+	count  uint64
+	sum    float64
+}
+
+func newSummary(size int) *Summary {
+	return &Summary{window: make([]float64, size)}
+}
+
+// Observe records a single value.
+func (s *Summary) Observe(v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.window[s.next] = v
+	s.next = (s.next + 1) % len(s.window)
+	if s.next == 0 {
+		s.filled = true
+	}
+	s.count++
+	s.sum += v
+}
+
+// Quantile returns the q-quantile (0..1) of the current window, or 0 if empty.
+func (s *Summary) Quantile(q float64) float64 {
+	s.mu.Lock()
+	sorted := s.snapshot()
+	s.mu.Unlock()
+	return quantile(sorted, q)
+}
+
+// snapshot returns a sorted copy of the window. Callers must hold s.mu.
+func (s *Summary) snapshot() []float64 {
+	n := s.next
+	if s.filled {
+		n = len(s.window)
+	}
+	sorted := make([]float64, n)
+	copy(sorted, s.window[:n])
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func (s *Summary) writeTo(w io.Writer, name, labels string) {
+	s.mu.Lock()
+	sorted := s.snapshot()
+	count, sum := s.count, s.sum
+	s.mu.Unlock()
+
+	for _, q := range summaryQuantiles {
+		fmt.Fprintf(w, "%s%s %g\n", name, withLabel(labels, "quantile", fmt.Sprint(q)), quantile(sorted, q))
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, count)
+}
+
+// withLabel appends one more label pair to an already rendered label set.
+func withLabel(labels, key, value string) string {
+	pair := fmt.Sprintf("%s=%q", key, value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
@@ -2,10 +2,17 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"ofenes/internal/auth"
+	"ofenes/internal/authz"
+	"ofenes/internal/i18n"
+	"ofenes/internal/legal"
+	"ofenes/internal/trust"
 	"ofenes/pkg/response"
 )
 
@@ -21,37 +28,61 @@ const UsernameKey contextKey = "username"
 // RoleKey is the context key for the authenticated user's role.
 const RoleKey contextKey = "role"
 
-// Auth returns middleware that validates JWT tokens from the Authorization header.
+// TrustLevelKey is the context key for the authenticated user's trust level.
+const TrustLevelKey contextKey = "trustLevel"
+
+// TokenIDKey is the context key for the ID (jti) of the request's token.
+const TokenIDKey contextKey = "tokenID"
+
+// TokenExpiryKey is the context key for the expiry of the request's token.
+const TokenExpiryKey contextKey = "tokenExpiry"
+
+// AuthCookieName is the cookie that authenticates browser sessions.
+// Unsafe requests carrying it must pass the CSRF check (see CSRF).
+const AuthCookieName = "ofenes_session"
+
+// RefreshCookieName is the cookie holding a "remember me" refresh token in
+// cookie mode. It is checked for CSRF like AuthCookieName.
+const RefreshCookieName = "ofenes_refresh"
+
+// Auth returns middleware that validates JWT tokens from the Authorization header,
+// or, without one, from the session cookie (AuthCookieName) set in cookie mode.
+// Tokens revoked with rev, and those of deleted or banned users, are refused.
 // Protected routes should be wrapped with this middleware.
 //
-// On success, it injects userID, username, and role into the request context.
+// On success, it injects userID, username, role, trust level and the
+// token's ID and expiry into the request context.
 // On failure, it returns 401 Unauthorized.
 //
 // Usage:
 //
-//	protectedHandler := middleware.Auth(cfg.JWTSecret)(myHandler)
-func Auth(jwtSecret string) func(http.Handler) http.Handler {
+//	protectedHandler := middleware.Auth(cfg.Token(), revocations)(myHandler)
+func Auth(tc auth.TokenConfig, rev *auth.Revocations) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from "Authorization: Bearer <token>"
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				response.Error(w, http.StatusUnauthorized, "missing authorization header")
+			tokenStr, code := requestToken(r)
+			if code != "" {
+				fail(w, r, http.StatusUnauthorized, code)
 				return
 			}
 
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
-				response.Error(w, http.StatusUnauthorized, "invalid authorization format")
-				return
-			}
-
-			tokenStr := parts[1]
-
 			// Validate the token
-			claims, err := auth.ValidateToken(tokenStr, jwtSecret)
+			claims, err := auth.ValidateToken(tokenStr, tc)
 			if err != nil {
-				response.Error(w, http.StatusUnauthorized, "invalid or expired token")
+				fail(w, r, http.StatusUnauthorized, "invalid_or_expired_token")
+				return
+			}
+			if err := rev.Check(r.Context(), claims); err != nil {
+				if errors.Is(err, auth.ErrTokenRevoked) {
+					fail(w, r, http.StatusUnauthorized, "token_revoked")
+					return
+				}
+				if errors.Is(err, auth.ErrAccountGone) {
+					fail(w, r, http.StatusUnauthorized, "account_gone")
+					return
+				}
+				log.Printf("auth: %v", err)
+				fail(w, r, http.StatusInternalServerError, "failed_to_check_token")
 				return
 			}
 
@@ -60,12 +91,145 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 			ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, UsernameKey, claims.Username)
 			ctx = context.WithValue(ctx, RoleKey, claims.Role)
+			ctx = context.WithValue(ctx, TrustLevelKey, claims.TrustLevel)
+			ctx = context.WithValue(ctx, TokenIDKey, claims.ID)
+			if claims.ExpiresAt != nil {
+				ctx = context.WithValue(ctx, TokenExpiryKey, claims.ExpiresAt.Time)
+			}
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// RequestToken returns the JWT r carries, as Auth finds it, or "" if it
+// carries none or a malformed Authorization header. Auth checks nothing
+// else on routes without it, such as logout.
+func RequestToken(r *http.Request) string {
+	token, _ := requestToken(r)
+	return token
+}
+
+// requestToken extracts the token from "Authorization: Bearer <token>" or
+// the session cookie. Without one it returns the error code to answer.
+func requestToken(r *http.Request) (token, code string) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+			return "", "invalid_authorization_format"
+		}
+		return parts[1], ""
+	}
+	if c, err := r.Cookie(AuthCookieName); err == nil && c.Value != "" {
+		return c.Value, ""
+	}
+	return "", "missing_authorization_header"
+}
+
+// RequireRole returns middleware that rejects requests whose JWT role is not
+// one of roles with 403 Forbidden. It must run after Auth.
+//
+// Usage:
+//
+//	adminOnly := middleware.RequireRole(models.RoleAdmin)
+//	mux.Handle("DELETE /api/admin/users/{id}", authMw(adminOnly(handler)))
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := GetRole(r.Context())
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			fail(w, r, http.StatusForbidden, "insufficient_permissions")
+		})
+	}
+}
+
+// RequirePermission returns middleware that rejects requests whose JWT
+// role does not grant perm (authz.Perm*) with 403 Forbidden. It must run
+// after Auth.
+//
+// Usage:
+//
+//	canAudit := middleware.RequirePermission(application.Authz, authz.PermAuditRead)
+//	mux.Handle("GET /api/admin/audit", authMw(canAudit(handler)))
+func RequirePermission(az *authz.Authorizer, perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !az.Can(GetRole(r.Context()), perm) {
+				fail(w, r, http.StatusForbidden, "permission_required", perm)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireTrust returns middleware that rejects requests whose JWT trust
+// level is below min with 403 Forbidden. Roles with the trust.bypass
+// permission always pass. It must run after Auth.
+//
+// Usage:
+//
+//	mux.Handle("POST /api/rooms", authMw(middleware.RequireTrust(application.Authz, models.TrustMember)(handler)))
+func RequireTrust(az *authz.Authorizer, min string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if !az.Can(GetRole(ctx), authz.PermTrustBypass) && !trust.Allows(GetTrustLevel(ctx), min) {
+				fail(w, r, http.StatusForbidden, "trust_level_required", min)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireLegal returns middleware that answers 428 Precondition Required
+// (legal_acceptance_required) to users who have not accepted the current
+// version of every published legal document, until they do with
+// POST /api/me/legal/accept. Requests exempt reports true for pass, so
+// users can still read and accept the documents. It must run after Auth.
+//
+// Usage:
+//
+//	gate := middleware.RequireLegal(application.Legal, func(r *http.Request) bool { ... })
+//	mux.Handle("GET /api/rooms", authMw(gate(handler)))
+func RequireLegal(tracker *legal.Tracker, exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID := GetUserID(r.Context())
+			pending, err := tracker.Pending(r.Context(), userID)
+			if err != nil {
+				log.Printf("legal: pending documents of %s: %v", userID, err)
+				fail(w, r, http.StatusInternalServerError, "failed_to_check_legal")
+				return
+			}
+			if len(pending) > 0 {
+				fail(w, r, http.StatusPreconditionRequired, "legal_acceptance_required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// fail writes an error response for code in the language negotiated from
+// the Accept-Language header. Middleware runs before the user is loaded,
+// so unlike the handlers it cannot honor the user's language preference.
+func fail(w http.ResponseWriter, r *http.Request, status int, code string, args ...any) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	response.ErrorCode(w, status, code, i18n.T(lang, code, args...))
+}
+
 // --- Context Helpers ---
 // These functions extract user info from the request context.
 // Use these in handlers instead of accessing context keys directly.
@@ -87,3 +251,24 @@ func GetRole(ctx context.Context) string {
 	val, _ := ctx.Value(RoleKey).(string)
 	return val
 }
+
+// GetTrustLevel extracts the user's trust level from the request context.
+// Tokens issued before trust levels existed carry none ("").
+func GetTrustLevel(ctx context.Context) string {
+	val, _ := ctx.Value(TrustLevelKey).(string)
+	return val
+}
+
+// GetTokenID extracts the ID (jti) of the request's token from the
+// request context. Tokens issued before token IDs existed carry none ("").
+func GetTokenID(ctx context.Context) string {
+	val, _ := ctx.Value(TokenIDKey).(string)
+	return val
+}
+
+// GetTokenExpiry extracts the expiry of the request's token from the
+// request context.
+func GetTokenExpiry(ctx context.Context) time.Time {
+	val, _ := ctx.Value(TokenExpiryKey).(time.Time)
+	return val
+}
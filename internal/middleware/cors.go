@@ -2,36 +2,150 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"ofenes/internal/origin"
 )
 
-// CORS returns middleware that handles Cross-Origin Resource Sharing.
-// The allowedOrigins parameter comes from config — not hardcoded.
+// CORSPolicy is how CORS requests to a set of routes are answered.
+type CORSPolicy struct {
+	// Origins are the allowed origins, in the patterns described in
+	// package origin.
+	Origins *origin.Matcher
+
+	// AllowOriginFunc, if set, is asked about origins that Origins rejects,
+	// for origins only known at runtime (per-tenant domains, say). It runs
+	// on every cross-origin request and must be fast.
+	AllowOriginFunc func(r *http.Request, origin string) bool
+
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string // response headers scripts may read
+	AllowCredentials bool
+	MaxAge           time.Duration // how long browsers may cache a preflight
+}
+
+// DefaultCORSPolicy returns the policy for the API: the given origins, the
+// methods and headers the API uses, and credentials allowed.
+func DefaultCORSPolicy(origins *origin.Matcher) CORSPolicy {
+	return CORSPolicy{
+		Origins:          origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Request-ID", IdempotencyKeyHeader, CSRFHeaderName},
+		ExposeHeaders:    []string{"X-Request-ID", "Location", IdempotentReplayedHeader},
+		AllowCredentials: true,
+		MaxAge:           24 * time.Hour,
+	}
+}
+
+// allows reports whether the policy accepts origin for r.
+func (p *CORSPolicy) allows(r *http.Request, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.Origins != nil && p.Origins.Allowed(origin) {
+		return true
+	}
+	return p.AllowOriginFunc != nil && p.AllowOriginFunc(r, origin)
+}
+
+// CORSRoute applies Policy to requests whose path starts with PathPrefix.
+type CORSRoute struct {
+	PathPrefix string
+	Policy     CORSPolicy
+}
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	Default CORSPolicy
+	Routes  []CORSRoute // the longest matching PathPrefix wins over Default
+}
+
+// NewCORSOptions builds CORS options from config: allowedOrigins for the
+// default policy, extra exposed headers (comma-separated) for every
+// policy, and per-route origins as "prefix=origins;..." where origins is a
+// comma-separated pattern list, e.g.
+// "/api/embed/=*;/api/admin/=https://admin.example.com". Route policies
+// are copies of the default with their own origins.
+func NewCORSOptions(allowedOrigins, exposeHeaders, routeOrigins string) (CORSOptions, error) {
+	def := DefaultCORSPolicy(origin.New(allowedOrigins))
+	for _, h := range strings.Split(exposeHeaders, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			def.ExposeHeaders = append(def.ExposeHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+
+	opts := CORSOptions{Default: def}
+	for _, entry := range strings.Split(routeOrigins, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, origins, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return CORSOptions{}, fmt.Errorf("cors: invalid route entry %q (want /prefix=origins)", entry)
+		}
+		policy := def
+		policy.Origins = origin.New(origins)
+		opts.Routes = append(opts.Routes, CORSRoute{PathPrefix: prefix, Policy: policy})
+	}
+	return opts, nil
+}
+
+// CORS returns middleware that handles Cross-Origin Resource Sharing,
+// choosing the policy by request path. The origin is echoed in
+// Access-Control-Allow-Origin only if the policy allows it; preflight
+// requests (OPTIONS) are answered here and never reach the routes.
 //
 // Usage:
 //
-//	handler = middleware.CORS(cfg.AllowOrigins)(handler)
-func CORS(allowedOrigins string) func(http.Handler) http.Handler {
-	origins := strings.Split(allowedOrigins, ",")
-	originSet := make(map[string]bool, len(origins))
-	for _, o := range origins {
-		originSet[strings.TrimSpace(o)] = true
+//	opts, err := middleware.NewCORSOptions(cfg.AllowOrigins, cfg.CORSExposeHeaders, cfg.CORSRouteOrigins)
+//	handler = middleware.CORS(opts)(handler)
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	def := compileCORSPolicy(opts.Default)
+	routes := make([]compiledCORSRoute, len(opts.Routes))
+	for i, route := range opts.Routes {
+		routes[i] = compiledCORSRoute{prefix: route.PathPrefix, policy: compileCORSPolicy(route.Policy)}
 	}
+	// Longest prefix first, so the first match is the most specific.
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
+			policy := def
+			for _, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.prefix) {
+					policy = route.policy
+					break
+				}
+			}
 
 			// Check if the request origin is allowed
-			if originSet[origin] || originSet["*"] {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
+			requestOrigin := r.Header.Get("Origin")
+			if policy.allows(r, requestOrigin) {
+				w.Header().Set("Access-Control-Allow-Origin", requestOrigin)
+				w.Header().Add("Vary", "Origin")
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Max-Age", "86400")
+			w.Header().Set("Access-Control-Allow-Methods", policy.methods)
+			w.Header().Set("Access-Control-Allow-Headers", policy.headers)
+			if policy.expose != "" {
+				w.Header().Set("Access-Control-Expose-Headers", policy.expose)
+			}
+			if policy.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if policy.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", policy.maxAge)
+			}
 
 			// Handle preflight
 			if r.Method == http.MethodOptions {
@@ -43,3 +157,27 @@ func CORS(allowedOrigins string) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// compiledCORSPolicy is a CORSPolicy with its header values joined once.
+type compiledCORSPolicy struct {
+	*CORSPolicy
+	methods, headers, expose, maxAge string
+}
+
+type compiledCORSRoute struct {
+	prefix string
+	policy compiledCORSPolicy
+}
+
+func compileCORSPolicy(p CORSPolicy) compiledCORSPolicy {
+	c := compiledCORSPolicy{
+		CORSPolicy: &p,
+		methods:    strings.Join(p.AllowMethods, ", "),
+		headers:    strings.Join(p.AllowHeaders, ", "),
+		expose:     strings.Join(p.ExposeHeaders, ", "),
+	}
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return c
+}
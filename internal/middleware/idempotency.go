@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/response"
+)
+
+// IdempotencyKeyHeader is the request header naming an idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to "true" on replayed responses.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const (
+	maxIdempotencyKeyLen  = 255
+	maxIdempotentBodySize = 1 << 20 // bodies are hashed in memory
+)
+
+// idempotentHeaders are the response headers stored with a response and
+// sent again on replay. Set-Cookie, which may be repeated, is stored
+// apart: a replayed registration in cookie mode must set the session and
+// CSRF cookies the first response did, or the client is not signed in.
+var idempotentHeaders = []string{"Content-Type", "Content-Language", "Location"}
+
+// Idempotency returns middleware that makes retries of a request carrying
+// an Idempotency-Key header safe: the first request runs and its response
+// is stored for ttl; a retry with the same key gets that response again,
+// with Idempotent-Replayed: true, instead of repeating the request. The
+// cookies the response set are set again.
+//
+// Keys are scoped to the authenticated user (behind Auth) and to the
+// method and path; on public routes all anonymous clients share one
+// scope, so keys should be random (a UUID). A retry while the first
+// request is still running gets 409, and reusing a key with a different
+// body gets 422. Server errors (5xx) are not stored, so the request can
+// be retried. Requests without the header are not affected. If the store
+// fails, the request runs as if it had no key.
+//
+// Usage:
+//
+//	idem := middleware.Idempotency(app.Ephemeral.Idempotency, cfg.IdempotencyTTL)
+//	mux.Handle("POST /api/rooms", authMw(idem(handler)))
+func Idempotency(store repository.IdempotencyRepository, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idemKey := r.Header.Get(IdempotencyKeyHeader)
+			if idemKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(idemKey) > maxIdempotencyKeyLen {
+				fail(w, r, http.StatusBadRequest, "invalid_idempotency_key", maxIdempotencyKeyLen)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					fail(w, r, http.StatusRequestEntityTooLarge, "request_body_too_large")
+				} else {
+					fail(w, r, http.StatusBadRequest, "invalid_request_body")
+				}
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			fingerprint := hex.EncodeToString(sum[:])
+
+			scope := GetUserID(r.Context())
+			if scope == "" {
+				scope = "anonymous"
+			}
+			key := scope + " " + r.Method + " " + r.URL.Path + " " + idemKey
+
+			// Store calls outlive a client that hangs up mid-request.
+			ctx := context.WithoutCancel(r.Context())
+			existing, err := store.Reserve(ctx, key, fingerprint, ttl)
+			if err != nil {
+				log.Printf("idempotency: reserve %q: %v", idemKey, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != fingerprint:
+					fail(w, r, http.StatusUnprocessableEntity, "idempotency_key_reused")
+				case existing.Status == 0:
+					fail(w, r, http.StatusConflict, "idempotent_request_in_progress")
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w}
+			// Cookies set before this point come from outer middleware,
+			// which sets them again on replay.
+			cookiesBefore := len(w.Header().Values("Set-Cookie"))
+			stored := false
+			defer func() {
+				// Server errors, panics and requests abandoned by the client
+				// leave the key free for a retry.
+				if !stored {
+					if err := store.Release(ctx, key); err != nil {
+						log.Printf("idempotency: release %q: %v", idemKey, err)
+					}
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK // handler wrote nothing
+			}
+			if rec.status >= http.StatusInternalServerError || rec.status == response.StatusClientClosedRequest {
+				return
+			}
+			stored = true
+
+			record := &models.IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Status:      rec.status,
+				Header:      make(map[string]string),
+				Body:        rec.body.Bytes(),
+			}
+			for _, name := range idempotentHeaders {
+				if v := w.Header().Get(name); v != "" {
+					record.Header[name] = v
+				}
+			}
+			record.Cookies = w.Header().Values("Set-Cookie")[cookiesBefore:]
+			if err := store.Complete(ctx, key, record, ttl); err != nil {
+				log.Printf("idempotency: complete %q: %v", idemKey, err)
+			}
+		})
+	}
+}
+
+// replay writes a stored response.
+func replay(w http.ResponseWriter, record *models.IdempotencyRecord) {
+	for name, v := range record.Header {
+		w.Header().Set(name, v)
+	}
+	for _, c := range record.Cookies {
+		w.Header().Add("Set-Cookie", c)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// recordingWriter passes a response through and keeps a copy of its
+// status and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(p)
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -23,6 +23,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap returns the underlying writer, so http.ResponseController can
+// reach its Flush and deadline methods.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Hijack implements http.Hijacker by delegating to the underlying writer.
 // This is REQUIRED for WebSocket upgrades — gorilla/websocket calls Hijack()
 // to take over the raw TCP connection.
@@ -35,11 +41,11 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 }
 
 // Logging returns middleware that logs every request with method, path,
-// status code, and duration.
+// status code, duration and, behind RequestID, the request ID.
 //
 // Example output:
 //
-//	POST /api/login 200 12.34ms
+//	POST /api/login 200 12.34ms 3f2b1c9e-8d4a-4e0f-9a52-6c1d7e0b4f21
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -49,11 +55,12 @@ func Logging(next http.Handler) http.Handler {
 
 		next.ServeHTTP(wrapped, r)
 
-		log.Printf("%s %s %d %s",
+		log.Printf("%s %s %d %s %s",
 			r.Method,
 			r.URL.Path,
 			wrapped.statusCode,
 			time.Since(start).Round(time.Microsecond),
+			GetRequestID(r.Context()),
 		)
 	})
 }
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"ofenes/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDKey is the context key for the request ID.
+const RequestIDKey contextKey = "requestID"
+
+// maxRequestIDLen bounds request IDs accepted from clients.
+const maxRequestIDLen = 128
+
+// RequestID returns middleware that gives every request an ID, echoed in
+// the X-Request-ID response header, in meta.requestId of JSON responses
+// and in the request log. An X-Request-ID sent by the client (or a proxy)
+// is reused if it is a reasonable token; otherwise a UUID is generated.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(response.RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(response.RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetRequestID extracts the request ID from the request context.
+func GetRequestID(ctx context.Context) string {
+	if v, ok := ctx.Value(RequestIDKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// validRequestID reports whether id is non-empty, short and made only of
+// letters, digits, '-', '_', '.' and ':', so it is safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"ofenes/internal/auth"
+)
+
+// ServiceKey is the context key for the name of the authenticated service.
+const ServiceKey contextKey = "service"
+
+// RequireService returns middleware for routes called by internal services
+// rather than users: the request must carry "Authorization: Bearer <token>"
+// with a service token (see auth.GenerateServiceToken) signed with
+// tc.Secret that grants scope. User tokens are rejected. It returns 401 for
+// a missing or invalid token and 403 if the token lacks the scope.
+//
+// On success, it injects the service name into the request context.
+//
+// Usage:
+//
+//	mux.Handle("GET /metrics", middleware.RequireService(cfg.ServiceToken(), auth.ScopeMetrics)(handler))
+func RequireService(tc auth.TokenConfig, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				fail(w, r, http.StatusUnauthorized, "missing_authorization_header")
+				return
+			}
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+				fail(w, r, http.StatusUnauthorized, "invalid_authorization_format")
+				return
+			}
+
+			claims, err := auth.ValidateServiceToken(parts[1], tc)
+			if err != nil {
+				fail(w, r, http.StatusUnauthorized, "invalid_service_token")
+				return
+			}
+			if !claims.HasScope(scope) {
+				fail(w, r, http.StatusForbidden, "insufficient_scope", scope)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ServiceKey, claims.Service)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetService extracts the authenticated service's name from the request
+// context ("" for user requests).
+func GetService(ctx context.Context) string {
+	val, _ := ctx.Value(ServiceKey).(string)
+	return val
+}
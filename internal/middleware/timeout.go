@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout returns middleware that gives each request d to finish. Once d
+// has passed its context is cancelled, so repository calls and password
+// hashing made with r.Context() stop and the handler answers 503
+// request_timeout. Unlike http.TimeoutHandler it writes nothing itself:
+// handlers stay in charge of the response.
+//
+// Requests for which exempt returns true (streams, uploads, exports) run
+// without a deadline, as long as the client stays. d <= 0 disables the
+// middleware.
+func Timeout(d time.Duration, exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt != nil && exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -12,24 +12,40 @@ import (
 
 // User represents a registered participant.
 type User struct {
-	ID           string          `json:"id"`
-	Username     string          `json:"username"`
-	PasswordHash string          `json:"-"` // Never serialized to JSON
-	Role         string          `json:"role"`
-	DisplayName  *string         `json:"displayName,omitempty"`
-	AvatarURL    *string         `json:"avatarUrl,omitempty"`
-	Status       string          `json:"status"`
-	Bio          *string         `json:"bio,omitempty"`
-	Preferences  json.RawMessage `json:"preferences,omitempty"`
-	CreatedAt    time.Time       `json:"createdAt"`
-	UpdatedAt    time.Time       `json:"updatedAt"`
+	ID             string          `json:"id"`
+	Username       string          `json:"username"`
+	PasswordHash   string          `json:"-"` // Never serialized to JSON
+	Role           string          `json:"role"`
+	TrustLevel     string          `json:"trustLevel"` // one of the Trust* constants; raised by the trust job
+	DisplayName    *string         `json:"displayName,omitempty"`
+	AvatarURL      *string         `json:"avatarUrl,omitempty"`
+	Status         string          `json:"status"`
+	Bio            *string         `json:"bio,omitempty"`
+	Preferences    json.RawMessage `json:"preferences,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	DeletedAt      *time.Time      `json:"deletedAt,omitempty"`      // Set while soft-deleted
+	AnonymizedAt   *time.Time      `json:"anonymizedAt,omitempty"`   // Set once personal data has been erased
+	ShadowBannedAt *time.Time      `json:"shadowBannedAt,omitempty"` // Set while shadow-banned; never shown to the user themselves
 }
 
-// UserRole constants -- use these instead of raw strings.
+// UserRole constants for the built-in roles -- use these instead of raw
+// strings. Admins can define more roles; what each role may do is decided
+// by internal/authz.
 const (
-	RoleAdmin  = "admin"
-	RoleMember = "member"
-	RoleViewer = "viewer"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+	RoleViewer    = "viewer"
+)
+
+// Trust levels, lowest first. New accounts start at TrustNew and are
+// promoted automatically by account age and activity; each level unlocks
+// more capabilities (see internal/trust).
+const (
+	TrustNew     = "new"
+	TrustMember  = "member"
+	TrustRegular = "regular"
 )
 
 // UserStatus constants.
@@ -44,22 +60,215 @@ const (
 
 // Message is a chat or system event sent through WebSocket.
 type Message struct {
+	ID        string    `json:"id,omitempty"` // chosen by the sender; echoed in broadcasts and in errors about the message
 	Type      string    `json:"type"`
-	Sender    string    `json:"sender"`
+	Sender    string    `json:"sender"`             // username; set by the Hub from the connection, whatever the client sent
+	SenderID  string    `json:"senderId,omitempty"` // user ID, set with Sender; empty for system messages
 	Payload   string    `json:"payload"`
-	Timestamp time.Time `json:"timestamp"`
+	Timestamp time.Time `json:"timestamp"`     // set by the Hub to server time on messages from clients
+	Seq       int64     `json:"seq,omitempty"` // increasing per room across messages from clients, to order them by; 0 on system messages
 }
 
 // MessageType constants for WebSocket routing.
 const (
-	MsgTypeChat      = "chat"
-	MsgTypeSystem    = "system"
-	MsgTypeVideoSync = "video_sync"
-	MsgTypeWebRTC    = "webrtc"
-	MsgTypeUserList  = "user_list"
-	MsgTypeAdmin     = "admin"
+	MsgTypeChat          = "chat"
+	MsgTypeSystem        = "system"
+	MsgTypeVideoSync     = "video_sync"
+	MsgTypeWebRTC        = "webrtc"
+	MsgTypeUserList      = "user_list"
+	MsgTypeAdmin         = "admin"
+	MsgTypeError         = "error"
+	MsgTypeActivity      = "activity"        // client → server only: resets the idle timer
+	MsgTypeReconnect     = "reconnect"       // server → client: connection closing soon, carries a resume token
+	MsgTypeHeartbeat     = "heartbeat"       // both ways, WS_KEEPALIVE_MODE=heartbeat only
+	MsgTypeModeration    = "moderation"      // server → moderators only, see ModerationEvent
+	MsgTypeCoHost        = "cohost"          // client → server: the room's owner grants or revokes co-host rights, see CoHostPayload
+	MsgTypeRoomRole      = "room_role"       // server → room: a member's room role changed, see RoomRoleEvent
+	MsgTypeMedia         = "media"           // server → room: an uploaded video became playable or failed to transcode, see MediaEvent
+	MsgTypePlaybackStats = "playback_stats"  // client → server: the player's bandwidth and buffer, see PlaybackStatsPayload
+	MsgTypeQuality       = "quality"         // server → room or its hosts: a suggested quality, or a struggling member, see QualityEvent
+	MsgTypeLatency       = "latency"         // server → those who control a room's playback: its members' round-trip times, see LatencyReport
+	MsgTypePosition      = "position"        // client → server: where the player is, see PositionPayload; may be answered with a video_sync seek or a sync_rate
+	MsgTypeSyncRate      = "sync_rate"       // server → one client: play at this rate until back in sync, see SyncRatePayload
+	MsgTypeNowPlaying    = "now_playing"     // server → room: what its main screen plays, on change and every WS_NOW_PLAYING_INTERVAL_MS, see NowPlaying
+	MsgTypeSpectator     = "spectator"       // server → one client: it joined an oversized room as a spectator, or was promoted, see SpectatorEvent
+	MsgTypeUserListDelta = "user_list_delta" // server → room: who joined and left since the last user_list or user_list_delta, see UserListDelta
+	MsgTypeAnnouncements = "announcements"   // server → one client: the site-wide announcements in effect it hasn't dismissed, on connect and on change; payload is a JSON array of Announcement
 )
 
+// ErrorPayload is the JSON payload of an "error" message sent by the server
+// to a single client when one of its messages is rejected.
+type ErrorPayload struct {
+	Code    string `json:"code"`              // machine-readable, see WSErr* constants
+	Message string `json:"message"`           // human-readable description
+	RefType string `json:"refType,omitempty"` // type of the rejected message
+	RefID   string `json:"refId,omitempty"`   // ID of the rejected message, if it had one
+	Limit   int    `json:"limit,omitempty"`   // applicable limit, if any
+}
+
+// WebSocket error codes.
+const (
+	WSErrInvalidMessage  = "invalid_message"
+	WSErrPayloadTooLarge = "payload_too_large"
+	WSErrRateLimited     = "rate_limited"
+	WSErrUnknownType     = "unknown_type"
+	WSErrForbidden       = "forbidden"
+	WSErrMuted           = "muted"
+	WSErrBlockedContent  = "blocked_content"
+	WSErrTrustLevel      = "trust_level"
+	WSErrUnknownTarget   = "unknown_target" // webrtc: the target user is not connected
+	WSErrSpectator       = "spectator"      // chat or webrtc a spectator may not send, or chat left out of the sample
+)
+
+// CoHostPayload is the JSON payload of a "cohost" message, sent by a
+// room's owner to grant or revoke a connected member's co-host rights.
+type CoHostPayload struct {
+	UserID string `json:"userId"`
+	Grant  bool   `json:"grant"` // false makes a co-host a member again
+}
+
+// RoomRoleEvent is the JSON payload of a "room_role" message, broadcast
+// to a room when one of its members' room roles changes.
+type RoomRoleEvent struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username,omitempty"` // set if the member is connected
+	Role     string `json:"role"`               // RoomRole*; "" = removed from the room
+}
+
+// MediaEvent is the JSON payload of a "media" message, sent to a room
+// when one of its uploaded videos changes.
+type MediaEvent struct {
+	Event string     `json:"event"` // MediaEvent*
+	Media *MediaFile `json:"media"`
+	URL   string     `json:"url,omitempty"` // MediaEventPlayable: what to load, the HLS playlist if transcoded
+}
+
+// MediaEvent constants for MediaEvent.Event.
+const (
+	MediaEventPreviews        = "previews" // the poster and preview sprite are ready, before the video itself
+	MediaEventPlayable        = "playable"
+	MediaEventTranscodeFailed = "transcode_failed"
+)
+
+// PlaybackStatsPayload is the JSON payload of a "playback_stats" message,
+// sent by a client every few seconds while it plays.
+type PlaybackStatsPayload struct {
+	Bandwidth int     `json:"bandwidth"` // kbit/s the player measured while loading, 0 if unknown
+	Bitrate   int     `json:"bitrate"`   // kbit/s of the quality it plays, 0 if unknown
+	Buffer    float64 `json:"buffer"`    // seconds buffered ahead of the playhead
+	Stalls    int     `json:"stalls"`    // times playback stopped to buffer since the last report
+}
+
+// QualityEvent is the JSON payload of a "quality" message. Suggestions go
+// to the whole room; struggling and recovered go to those who control its
+// playback.
+type QualityEvent struct {
+	Event      string                `json:"event"`                // QualityEvent*
+	MaxBitrate int                   `json:"maxBitrate,omitempty"` // QualityEventSuggestion: kbit/s every member can keep up with, 0 = no cap
+	UserID     string                `json:"userId,omitempty"`     // the member, for struggling and recovered
+	Username   string                `json:"username,omitempty"`
+	Stats      *PlaybackStatsPayload `json:"stats,omitempty"` // the member's last report
+}
+
+// QualityEvent constants for QualityEvent.Event.
+const (
+	QualityEventSuggestion = "suggestion"
+	QualityEventStruggling = "struggling"
+	QualityEventRecovered  = "recovered"
+)
+
+// PositionPayload is the JSON payload of a "position" message, sent by
+// players every few seconds while a video is loaded.
+type PositionPayload struct {
+	Position float64 `json:"position"`         // seconds
+	Screen   string  `json:"screen,omitempty"` // "" = the main screen
+}
+
+// SyncRatePayload is the JSON payload of a "sync_rate" message, sent to a
+// player drifting from its room by more than the room's SyncPolicy.NudgeMs
+// but less than its ToleranceMs, and again with Rate 1 once it is back.
+type SyncRatePayload struct {
+	Rate    float64 `json:"rate"`             // playback rate to use, 1 = normal
+	DriftMs int     `json:"driftMs"`          // how far ahead (positive) or behind the player was
+	Screen  string  `json:"screen,omitempty"` // the player's screen; "" = the main one
+}
+
+// NowPlaying is what a room's main screen plays: the payload of a
+// "now_playing" message, sent to the room when the video changes, starts
+// or stops and every WS_NOW_PLAYING_INTERVAL_MS while it plays. It is
+// also posted to NOW_PLAYING_WEBHOOK_URLS and returned by
+// GET /api/now-playing, for bots and rich presence.
+type NowPlaying struct {
+	RoomID    string    `json:"roomId"`
+	URL       string    `json:"url"` // "" once nothing plays: the room emptied
+	Title     string    `json:"title,omitempty"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+	Position  float64   `json:"position"`           // seconds, as of UpdatedAt
+	Duration  float64   `json:"duration,omitempty"` // seconds; 0 if unknown
+	Playing   bool      `json:"playing"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// UserListDelta is the JSON payload of a "user_list_delta" message: the
+// changes to the room's user list since the last "user_list" or
+// "user_list_delta". Like the list, it names a user once per connection.
+type UserListDelta struct {
+	Joined []string `json:"joined,omitempty"`
+	Left   []string `json:"left,omitempty"`
+}
+
+// SpectatorEvent is the JSON payload of a "spectator" message, sent to a
+// client that joins a room past WS_SPECTATOR_THRESHOLD participants, and
+// again when it takes a departed participant's place. Spectators get
+// everything sent to the room but are left out of its user list, join and
+// leave events and WebRTC calls, and only a sample of their chat is sent.
+type SpectatorEvent struct {
+	Spectator bool `json:"spectator"`           // false once promoted to participant
+	Chat      bool `json:"chat"`                // whether any of its chat is let through
+	Threshold int  `json:"threshold,omitempty"` // participants the room takes
+}
+
+// LatencyReport is the JSON payload of a "latency" message, sent every
+// WS_LATENCY_REPORT_INTERVAL_MS to those who control a room's playback, so
+// they can see who is lagging.
+type LatencyReport struct {
+	Members []MemberLatency `json:"members"` // connected members with a measured round-trip time, slowest first
+}
+
+// MemberLatency is one connection's round-trip time to the server.
+type MemberLatency struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	RTTMs    int    `json:"rttMs"` // smoothed over the last keepalive probes
+}
+
+// BroadcastRequest is the body of POST /api/admin/broadcast.
+type BroadcastRequest struct {
+	Message string `json:"message"`
+	RoomID  string `json:"roomId,omitempty"` // "" = every room
+}
+
+// BroadcastResult is the response of POST /api/admin/broadcast: how many
+// rooms and connections on this instance the message was sent to.
+type BroadcastResult struct {
+	Rooms       int `json:"rooms"`
+	Connections int `json:"connections"`
+}
+
+// Connection is one open WebSocket connection, as returned by
+// GET /api/admin/connections.
+type Connection struct {
+	UserID      string    `json:"userId"`
+	Username    string    `json:"username"`
+	RoomID      string    `json:"roomId"`
+	RoomRole    string    `json:"roomRole,omitempty"` // RoomRole*; empty in rooms without room roles
+	Protocol    string    `json:"protocol,omitempty"` // negotiated subprotocol; empty for legacy clients
+	ConnectedAt time.Time `json:"connectedAt"`
+	LastSeen    time.Time `json:"lastSeen"`        // last frame from the client, data or control
+	RTTMs       int       `json:"rttMs,omitempty"` // smoothed round-trip time; absent until measured
+	Spectator   bool      `json:"spectator,omitempty"`
+}
+
 // --- ChatMessage (persisted) ---
 
 // ChatMessage is a persisted chat message stored in the database.
@@ -76,29 +285,99 @@ type ChatMessage struct {
 
 // --- Video ---
 
-// VideoState tracks the synchronized video playback position.
+// VideoState tracks the synchronized video playback position. The Hub
+// saves the main screen's as it changes, and when the room empties or the
+// server stops, and restores it when the room is next joined.
 type VideoState struct {
 	URL       string  `json:"url"`
 	Playing   bool    `json:"playing"`
-	Timestamp float64 `json:"timestamp"` // seconds
+	Timestamp float64 `json:"timestamp"`          // seconds
+	Subtitle  string  `json:"subtitle,omitempty"` // ID of the Subtitle track shown, if any
+}
+
+// VideoSyncPayload is the JSON carried in the Payload of a video_sync message.
+type VideoSyncPayload struct {
+	Event       string  `json:"event"` // VideoEvent*
+	URL         string  `json:"url"`
+	Playing     bool    `json:"playing"`
+	Timestamp   float64 `json:"timestamp"`           // seconds
+	Subtitle    string  `json:"subtitle,omitempty"`  // ID of the Subtitle track shown, if any
+	Screen      string  `json:"screen,omitempty"`    // ID of the room's Screen it controls; "" = the main one
+	Title       string  `json:"title,omitempty"`     // of the video, for now_playing; kept by the Hub until the URL changes
+	Thumbnail   string  `json:"thumbnail,omitempty"` // http(s) URL of an image of the video, likewise
+	Duration    float64 `json:"duration,omitempty"`  // seconds, likewise; 0 if unknown (live streams)
+	TriggeredBy string  `json:"triggeredBy"`
 }
 
+// VideoEvent constants for VideoSyncPayload.Event.
+const (
+	VideoEventPlay  = "play"
+	VideoEventPause = "pause"
+	VideoEventSeek  = "seek"
+	VideoEventLoad  = "load"
+)
+
 // --- Room ---
 
 // Room represents a collaborative session that users can join.
 type Room struct {
-	ID          string     `json:"id"`
-	Name        string     `json:"name"`
-	Description *string    `json:"description,omitempty"`
-	Type        string     `json:"type"`
-	CreatedBy   string     `json:"createdBy"`
-	IsActive    bool       `json:"isActive"`
-	VideoState  VideoState `json:"videoState"`
-	MaxMembers  int        `json:"maxMembers"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	Type        string           `json:"type"`
+	CreatedBy   string           `json:"createdBy"`
+	IsActive    bool             `json:"isActive"`
+	VideoState  VideoState       `json:"videoState"`
+	MaxMembers  int              `json:"maxMembers"`
+	Retention   *RetentionPolicy `json:"retention,omitempty"` // nil = server default
+	Sync        *SyncPolicy      `json:"sync,omitempty"`      // nil = server default
+	Screens     []Screen         `json:"screens,omitempty"`   // besides the main one
+	Tags        []string         `json:"tags,omitempty"`      // lowercase, sorted; for discovery (GET /api/rooms/public?tag=)
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// RetentionPolicy controls how long a room's chat messages are kept.
+// It is enforced by the periodic message retention job.
+type RetentionPolicy struct {
+	Mode string `json:"mode"`           // one of the Retention* constants
+	Days int    `json:"days,omitempty"` // for RetentionDays
+}
+
+// SyncPolicy sets how far a member's player may drift from a room's
+// playback before the server corrects it: past ToleranceMs it is told to
+// seek, past NudgeMs to play slightly faster or slower until it is back.
+// PauseOnDisconnect has the server pause playback when the host or
+// everyone is gone, and resume it when they are back.
+type SyncPolicy struct {
+	ToleranceMs       int    `json:"toleranceMs"`
+	NudgeMs           int    `json:"nudgeMs"`                     // 0 = never nudge, only seek
+	PauseOnDisconnect string `json:"pauseOnDisconnect,omitempty"` // one of the PauseOn* constants; "" = keep playing
+}
+
+// Screen is a video slot of a room besides its main one, such as a
+// secondary stream next to the movie. Each has its own playback, synced
+// by video_sync messages naming it, and its own controllers: those with
+// the Control room permission (authz.RoomPerm*; "" = video.control).
+type Screen struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Control string `json:"control,omitempty"`
 }
 
+// Pause-on-disconnect modes.
+const (
+	PauseOnHost     = "host"     // pause when the room's owner is gone (or everyone, in rooms without room roles)
+	PauseOnEveryone = "everyone" // pause when nobody is left
+)
+
+// Retention modes.
+const (
+	RetentionForever = "forever"  // keep messages
+	RetentionDays    = "days"     // delete messages older than Days
+	RetentionOnClose = "on_close" // delete all messages once the room is closed
+)
+
 // RoomType constants.
 const (
 	RoomTypePublic  = "public"
@@ -106,7 +385,9 @@ const (
 	RoomTypeDirect  = "direct"
 )
 
-// RoomMember represents a user's membership in a room.
+// RoomMember represents a user's membership in a room. Role is a
+// RoomRole* constant and governs what the user may do in that room (see
+// authz.RoomCan), independently of their global role.
 type RoomMember struct {
 	RoomID   string    `json:"roomId"`
 	UserID   string    `json:"userId"`
@@ -115,9 +396,11 @@ type RoomMember struct {
 	JoinedAt time.Time `json:"joinedAt"`
 }
 
-// RoomRole constants (per-room roles, distinct from global user roles).
+// RoomRole constants (per-room roles, distinct from global user roles),
+// highest first. The owner hosts the room.
 const (
 	RoomRoleOwner     = "owner"
+	RoomRoleCoHost    = "cohost"
 	RoomRoleModerator = "moderator"
 	RoomRoleMember    = "member"
 	RoomRoleViewer    = "viewer"
@@ -167,42 +450,721 @@ type SharedFile struct {
 	CreatedAt   time.Time `json:"createdAt"`
 }
 
+// --- Uploaded media ---
+
+// MediaFile is a video uploaded to the server for a room, streamed from
+// GET /media/{id} to those who may watch the room. It is uploaded in
+// chunks, resumable from Received, and playable once Status is
+// MediaFileReady.
+type MediaFile struct {
+	ID         string     `json:"id"`
+	RoomID     string     `json:"roomId"`
+	UploadedBy string     `json:"uploadedBy"`
+	FileName   string     `json:"fileName"`
+	MimeType   string     `json:"mimeType"`
+	Size       int64      `json:"size"`                 // bytes, declared when the upload starts
+	Received   int64      `json:"received"`             // bytes stored so far; not persisted, set from the media store
+	Status     string     `json:"status"`               // MediaFile*
+	Transcode  *Transcode `json:"transcode,omitempty"`  // nil unless transcoding is enabled and the upload is complete
+	PosterURL  string     `json:"posterUrl,omitempty"`  // still image for lists and players; not persisted, set from the media store
+	PreviewURL string     `json:"previewUrl,omitempty"` // WebVTT thumbnails track for hover previews on the seek bar; likewise
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// MediaFile statuses.
+const (
+	MediaFileUploading = "uploading"
+	MediaFileReady     = "ready"
+)
+
+// PlaybackToken is returned by POST /api/media/{id}/playback-token. URL
+// streams the upload without the caller's JWT, so it can be a <video>
+// element's src; it must be opened before ExpiresAt.
+type PlaybackToken struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Transcode is the job converting an upload to HLS renditions, served
+// from GET /media/{id}/hls/master.m3u8 once done.
+type Transcode struct {
+	Status    string    `json:"status"`           // Transcode*
+	Progress  float64   `json:"progress"`         // 0 to 1
+	Worker    string    `json:"worker,omitempty"` // running it, or the last to run it
+	Error     string    `json:"error,omitempty"`  // TranscodeFailed only
+	UpdatedAt time.Time `json:"updatedAt"`        // last change or progress report
+}
+
+// Transcode statuses.
+const (
+	TranscodeQueued  = "queued"
+	TranscodeRunning = "running"
+	TranscodeDone    = "done"
+	TranscodeFailed  = "failed"
+)
+
+// TranscodeProgressRequest is the expected payload for
+// PUT /api/transcode/{id}/progress.
+type TranscodeProgressRequest struct {
+	Progress float64 `json:"progress"` // 0 to 1
+}
+
+// FinishTranscodeRequest is the expected payload for
+// POST /api/transcode/{id}/finish.
+type FinishTranscodeRequest struct {
+	Error string `json:"error,omitempty"` // why the job failed; empty if it is done
+}
+
+// CreateMediaUploadRequest is the expected payload for POST /api/rooms/{id}/media.
+type CreateMediaUploadRequest struct {
+	FileName string `json:"fileName"`
+	MimeType string `json:"mimeType"` // video/*
+	Size     int64  `json:"size"`
+}
+
+// Subtitle is a subtitle track uploaded for a MediaFile, converted to
+// WebVTT and served from URL to those who may watch the room. A
+// video_sync payload names the track everyone is shown by its ID.
+type Subtitle struct {
+	ID         string    `json:"id"`
+	MediaID    string    `json:"mediaId"`
+	RoomID     string    `json:"roomId"`
+	UploadedBy string    `json:"uploadedBy"`
+	Label      string    `json:"label"`              // shown in players' track menus
+	Language   string    `json:"language,omitempty"` // BCP 47 tag, e.g. "en" or "pt-BR"
+	Format     string    `json:"format"`             // SubtitleFormat*, of the uploaded file
+	URL        string    `json:"url"`                // the WebVTT track; not persisted
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Subtitle formats accepted for upload.
+const (
+	SubtitleFormatSRT = "srt" // SubRip
+	SubtitleFormatASS = "ass" // Advanced SubStation Alpha, and SSA
+)
+
+// --- Library ---
+
+// LibraryItem is a saved video source, so frequently watched videos need
+// not be pasted as URLs every session. Personal items have an OwnerID and
+// are seen only by them; room items have a RoomID and are shared by its
+// members. Sharing a personal item into a room copies it.
+type LibraryItem struct {
+	ID        string         `json:"id"`
+	OwnerID   string         `json:"ownerId,omitempty"` // personal items only
+	RoomID    string         `json:"roomId,omitempty"`  // room items only
+	AddedBy   string         `json:"addedBy"`
+	Title     string         `json:"title"`
+	URL       string         `json:"url"`                // an http(s) URL, or an upload's /media/ path
+	Folder    string         `json:"folder,omitempty"`   // "" for the top level
+	Tags      []string       `json:"tags"`               // lowercase, sorted
+	Metadata  *MediaMetadata `json:"metadata,omitempty"` // nil unless the title was recognized (METADATA_PROVIDER)
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// LibraryItemRequest is the expected payload for POST /api/library,
+// POST /api/rooms/{id}/library and PUT /api/library/{id}.
+type LibraryItemRequest struct {
+	Title  string   `json:"title"`
+	URL    string   `json:"url"`
+	Folder string   `json:"folder,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// MediaMetadata describes the movie or TV episode a video was recognized
+// as, from The Movie Database (TMDB) or the Open Movie Database (OMDb).
+type MediaMetadata struct {
+	Kind         string `json:"kind"`                   // MetadataKind*
+	Title        string `json:"title"`                  // of the movie, or the series
+	Year         int    `json:"year,omitempty"`         // of release, or of the episode's air date
+	Season       int    `json:"season,omitempty"`       // episodes only
+	Episode      int    `json:"episode,omitempty"`      // episodes only
+	EpisodeTitle string `json:"episodeTitle,omitempty"` // episodes only
+	PosterURL    string `json:"posterUrl,omitempty"`
+	Runtime      int    `json:"runtime,omitempty"` // minutes
+	Source       string `json:"source"`            // MetadataSource*
+	SourceID     string `json:"sourceId"`          // TMDB ID or IMDb ID
+}
+
+// MediaMetadata kinds.
+const (
+	MetadataKindMovie   = "movie"
+	MetadataKindEpisode = "episode"
+)
+
+// MediaMetadata sources.
+const (
+	MetadataSourceTMDB = "tmdb"
+	MetadataSourceOMDb = "omdb"
+)
+
+// MetadataLookup is a cached answer of the metadata provider, so the same
+// title is not looked up again until it is stale.
+type MetadataLookup struct {
+	Key       string         // metadata.Query.Key
+	Metadata  *MediaMetadata // nil if nothing matched
+	FetchedAt time.Time
+}
+
+// ShareLibraryItemRequest is the expected payload for
+// POST /api/library/{id}/share.
+type ShareLibraryItemRequest struct {
+	RoomID string `json:"roomId"`
+}
+
+// --- Sessions ---
+
+// Session is a server-side login session. Stored in the ephemeral store
+// (memory or Redis) and expired by TTL.
+//
+// "Remember me" logins create one, bound to the device (UserAgent): its
+// refresh token is "<ID>.<secret>", and only the secret's hash is stored.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	TokenHash  string    `json:"tokenHash,omitempty"` // cleared before sessions are sent to clients
+	TokenID    string    `json:"tokenId,omitempty"`   // jti of the last JWT issued for it, revoked with it; cleared like TokenHash
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// --- Idempotency keys ---
+
+// IdempotencyRecord is a request made with an Idempotency-Key and, once it
+// has finished, its response. Stored in the ephemeral store and expired by
+// TTL.
+type IdempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`       // hash of the request body
+	Status      int               `json:"status,omitempty"`  // 0 while the request is in progress
+	Header      map[string]string `json:"header,omitempty"`  // replayed response headers
+	Cookies     []string          `json:"cookies,omitempty"` // replayed Set-Cookie headers, e.g. the session cookie of a registration
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// --- Audit log ---
+
+// AuditEntry records an administrative or automated action.
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	ActorID    string          `json:"actorId,omitempty"` // empty for background jobs
+	Action     string          `json:"action"`            // one of the Audit* constants
+	TargetType string          `json:"targetType,omitempty"`
+	TargetID   string          `json:"targetId,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// Audit actions.
+const (
+	AuditRetentionRun       = "retention.run"  // Details: retention.Report
+	AuditUserDelete         = "user.delete"    // soft delete; target is the user
+	AuditUserAnonymize      = "user.anonymize" // Details: {"messages": n}
+	AuditUserRestore        = "user.restore"
+	AuditUserShadowBan      = "user.shadow_ban"     // Details: {"shadowBanned": bool}
+	AuditReportResolve      = "report.resolve"      // Details: status, action and its outcome
+	AuditWordFilterCreate   = "word_filter.create"  // Details: the filter
+	AuditWordFilterUpdate   = "word_filter.update"  // Details: the filter after the change
+	AuditWordFilterDelete   = "word_filter.delete"  // Details: the deleted filter
+	AuditOriginAdd          = "origin.add"          // Details: the origin
+	AuditOriginRemove       = "origin.remove"       // Details: the removed origin
+	AuditRoleCreate         = "role.create"         // Details: the role
+	AuditRoleUpdate         = "role.update"         // Details: the role after the change
+	AuditRoleDelete         = "role.delete"         // Details: the deleted role
+	AuditUserRole           = "user.role"           // Details: {"from": role, "to": role}
+	AuditBroadcast          = "broadcast.send"      // Details: the BroadcastRequest; target is the room, if any
+	AuditAnnouncementCreate = "announcement.create" // Details: the announcement
+	AuditAnnouncementUpdate = "announcement.update" // Details: the announcement after the change
+	AuditAnnouncementDelete = "announcement.delete" // Details: the deleted announcement
+	AuditInstanceUpdate     = "instance.update"     // Details: the instance metadata after the change
+	AuditInviteCreate       = "invite.create"       // Details: the invite
+	AuditInviteDelete       = "invite.delete"       // Details: the deleted invite
+	AuditLegalPublish       = "legal.publish"       // Details: the document's kind and version
+)
+
+// Account deletion modes (ACCOUNT_DELETION_MODE, or ?mode= on
+// DELETE /api/admin/users/{id}).
+const (
+	DeletionSoft      = "soft"      // hide the account; it can be restored
+	DeletionAnonymize = "anonymize" // also replace personal data with a pseudonym; permanent
+)
+
+// --- Dead letters ---
+
+// DeadLetter is a WebSocket message the Hub could not route: it was not
+// valid JSON, its type or payload was unknown or malformed, or it named a
+// target that is not connected. The sender got the Code as an error.
+type DeadLetter struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	RoomID    string    `json:"roomId"`
+	Type      string    `json:"type,omitempty"`      // empty if the message did not parse
+	MessageID string    `json:"messageId,omitempty"` // the sender's ID of the message, if any
+	Code      string    `json:"code"`                // one of the WSErr* constants
+	Reason    string    `json:"reason"`
+	Data      string    `json:"data"`                // the message as received, cut to DeadLetterDataLimit bytes
+	Truncated bool      `json:"truncated,omitempty"` // Data was cut
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DeadLetterDataLimit caps the bytes of a message kept in a DeadLetter.
+const DeadLetterDataLimit = 2048
+
+// --- Reports ---
+
+// Report is a user's complaint about a message, user or room, queued for
+// moderators.
+type Report struct {
+	ID         string    `json:"id"`
+	ReporterID string    `json:"reporterId"`
+	TargetType string    `json:"targetType"` // one of the ReportTarget* constants
+	TargetID   string    `json:"targetId"`
+	RoomID     string    `json:"roomId,omitempty"` // room of a reported message, or the reported room
+	Reason     string    `json:"reason"`           // one of the ReportReason* constants
+	Details    string    `json:"details,omitempty"`
+	Status     string    `json:"status"` // one of the ReportStatus* constants
+	CreatedAt  time.Time `json:"createdAt"`
+
+	Resolution *ReportResolution `json:"resolution,omitempty"` // set once resolved or dismissed
+}
+
+// ReportResolution records how a moderator handled a report.
+type ReportResolution struct {
+	Action     string     `json:"action,omitempty"` // one of the ModAction* constants; empty for none
+	Note       string     `json:"note,omitempty"`
+	ResolvedBy string     `json:"resolvedBy"`
+	ResolvedAt time.Time  `json:"resolvedAt"`
+	MutedUntil *time.Time `json:"mutedUntil,omitempty"` // ModActionMute only
+}
+
+// Report target types.
+const (
+	ReportTargetMessage = "message"
+	ReportTargetUser    = "user"
+	ReportTargetRoom    = "room"
+)
+
+// Report reasons.
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other" // Details required
+)
+
+// Report statuses.
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed" // no action taken
+)
+
+// Moderation actions taken when resolving a report.
+const (
+	ModActionDeleteMessage = "delete_message" // message reports only
+	ModActionMute          = "mute"           // block the offender's chat for a while
+	ModActionBan           = "ban"            // soft-delete the offender's account and disconnect them
+	ModActionShadowBan     = "shadow_ban"     // only echo the offender's chat back to them (see ShadowBanRequest)
+)
+
+// CreateReportRequest is the expected payload for POST /api/reports.
+type CreateReportRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details,omitempty"`
+}
+
+// ResolveReportRequest is the expected payload for
+// POST /api/admin/reports/{id}/resolve.
+type ResolveReportRequest struct {
+	Status      string `json:"status,omitempty"` // resolved (default) or dismissed
+	Action      string `json:"action,omitempty"` // one of the ModAction* constants; not allowed when dismissing
+	Note        string `json:"note,omitempty"`
+	MuteMinutes int    `json:"muteMinutes,omitempty"` // ModActionMute only (default: 60)
+}
+
+// ShadowBanRequest is the expected payload for
+// PUT /api/admin/users/{id}/shadow-ban. A shadow-banned user's chat
+// messages are echoed back to them but nobody else receives them.
+type ShadowBanRequest struct {
+	ShadowBanned bool `json:"shadowBanned"`
+}
+
+// ReportDetail is a report with its evidence, returned by
+// GET /api/admin/reports/{id}.
+type ReportDetail struct {
+	Report   *Report        `json:"report"`
+	Reporter *User          `json:"reporter,omitempty"`
+	Message  *ChatMessage   `json:"message,omitempty"` // the reported message
+	Context  []*ChatMessage `json:"context,omitempty"` // messages around it, oldest first
+	User     *User          `json:"user,omitempty"`    // the reported user, or the sender of the reported message
+	Room     *Room          `json:"room,omitempty"`
+}
+
+// ModerationEvent is the payload of a "moderation" WebSocket message,
+// sent to admins and to the moderators of the room concerned.
+type ModerationEvent struct {
+	Event  string          `json:"event"` // one of the ModEvent* constants
+	Report *Report         `json:"report,omitempty"`
+	Flag   *FlaggedMessage `json:"flag,omitempty"` // ModEventMessageFlagged only
+}
+
+// Moderation events.
+const (
+	ModEventReportCreated  = "report_created"
+	ModEventReportResolved = "report_resolved"
+	ModEventMessageFlagged = "message_flagged" // sent to admins only
+)
+
+// --- Word filters ---
+
+// WordFilter is a pattern checked against every chat message. Global
+// filters (no RoomID) apply in every room; a room filter with the same
+// Pattern and Regex as a global one replaces it in that room, which is
+// how WordFilterAllow exempts a room.
+type WordFilter struct {
+	ID        string    `json:"id"`
+	Pattern   string    `json:"pattern"`          // case-insensitive
+	Regex     bool      `json:"regex"`            // Pattern is a Go regular expression; otherwise a word or phrase
+	Action    string    `json:"action"`           // one of the WordFilter* constants
+	RoomID    string    `json:"roomId,omitempty"` // empty for global filters
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Word filter actions.
+const (
+	WordFilterBlock = "block" // reject the message
+	WordFilterFlag  = "flag"  // deliver the message and notify admins
+	WordFilterAllow = "allow" // room filters only: lift the global filter with the same pattern
+)
+
+// WordFilterRequest is the expected payload for POST /api/admin/word-filters
+// and PUT /api/admin/word-filters/{id}.
+type WordFilterRequest struct {
+	Pattern string `json:"pattern"`
+	Regex   bool   `json:"regex"`
+	Action  string `json:"action"`
+	RoomID  string `json:"roomId,omitempty"`
+}
+
+// FlaggedMessage is a chat message that matched a WordFilterFlag filter.
+type FlaggedMessage struct {
+	RoomID    string    `json:"roomId"`
+	SenderID  string    `json:"senderId"`
+	Sender    string    `json:"sender"`
+	Content   string    `json:"content"`
+	FilterID  string    `json:"filterId"`
+	Pattern   string    `json:"pattern"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// --- Allowed origins ---
+
+// AllowedOrigin is a browser origin added by an admin at runtime. It is
+// allowed for CORS and WebSocket upgrades on top of CORS_ORIGINS.
+type AllowedOrigin struct {
+	ID        string    `json:"id"`
+	Origin    string    `json:"origin"` // an origin or wildcard pattern, e.g. https://*.example.com
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AllowedOriginRequest is the expected payload for POST /api/admin/origins.
+type AllowedOriginRequest struct {
+	Origin string `json:"origin"`
+}
+
+// --- Invites ---
+
+// Invite is a code admins hand out to let people register while
+// REGISTRATION_MODE is invite-only. Each registration with it uses it up
+// once; it can be used MaxUses times, until ExpiresAt.
+type Invite struct {
+	ID        string     `json:"id"`
+	Code      string     `json:"code"`
+	MaxUses   int        `json:"maxUses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // nil = never
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// InviteRequest is the expected payload for POST /api/admin/invites.
+type InviteRequest struct {
+	MaxUses   int        `json:"maxUses,omitempty"`   // default: 1
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // default: never
+}
+
+// --- Legal documents ---
+
+// LegalDocument is one version of a document users must accept, such as
+// the terms of service. The newest published version of each kind is the
+// current one; users who have not accepted it get 428 from the API until
+// they do.
+type LegalDocument struct {
+	Kind        string    `json:"kind"`    // LegalTerms or LegalPrivacy
+	Version     string    `json:"version"` // chosen by the publisher, e.g. "2026-10"; unique per kind
+	Title       string    `json:"title"`
+	Body        string    `json:"body"` // Markdown
+	PublishedBy string    `json:"publishedBy"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// Legal document kinds.
+const (
+	LegalTerms   = "terms"
+	LegalPrivacy = "privacy"
+)
+
+// LegalAcceptance records that a user accepted a version of a document.
+type LegalAcceptance struct {
+	UserID     string    `json:"userId"`
+	Kind       string    `json:"kind"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+}
+
+// LegalDocumentRequest is the expected payload for
+// POST /api/admin/legal/{kind}.
+type LegalDocumentRequest struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+}
+
+// LegalAcceptRequest is the expected payload for POST /api/me/legal/accept:
+// the version of each document the user was shown, by kind.
+type LegalAcceptRequest struct {
+	Versions map[string]string `json:"versions"`
+}
+
+// LegalStatus is the response of GET /api/me/legal.
+type LegalStatus struct {
+	Pending  []*LegalDocument   `json:"pending"`  // current documents the user has yet to accept
+	Accepted []*LegalAcceptance `json:"accepted"` // everything they accepted, newest first
+}
+
+// --- Announcements ---
+
+// Announcement is a site-wide banner, such as release notes or a downtime
+// notice. It is shown from StartsAt until EndsAt, to every user who has
+// not dismissed it.
+type Announcement struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body,omitempty"`
+	Level     string     `json:"level"` // AnnouncementInfo, AnnouncementWarning or AnnouncementCritical
+	StartsAt  time.Time  `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt,omitempty"` // nil = until deleted
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Announcement levels, from least to most urgent.
+const (
+	AnnouncementInfo     = "info"
+	AnnouncementWarning  = "warning"
+	AnnouncementCritical = "critical"
+)
+
+// AnnouncementRequest is the expected payload for
+// POST /api/admin/announcements and PUT /api/admin/announcements/{id}.
+type AnnouncementRequest struct {
+	Title    string     `json:"title"`
+	Body     string     `json:"body"`
+	Level    string     `json:"level"`              // default: info
+	StartsAt *time.Time `json:"startsAt,omitempty"` // default: now
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+}
+
+// --- Instance ---
+
+// Instance is what a deployment tells the frontend about itself, served
+// from GET /api/instance. Until an admin saves it, the INSTANCE_* settings
+// are used.
+type Instance struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	LogoURL     string     `json:"logoUrl,omitempty"`
+	MOTD        string     `json:"motd,omitempty"`    // message of the day
+	Contact     string     `json:"contact,omitempty"` // e.g. an email address or URL
+	UpdatedBy   string     `json:"updatedBy,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"` // nil = never saved
+}
+
+// InstanceRequest is the expected payload for PUT /api/admin/instance.
+type InstanceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	LogoURL     string `json:"logoUrl"`
+	MOTD        string `json:"motd"`
+	Contact     string `json:"contact"`
+}
+
+// --- Roles ---
+
+// RoleDefinition is a named set of permissions (authz.Perm*). The
+// built-in roles (models.Role*) are fixed; admins define the others.
+type RoleDefinition struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"builtIn"`
+	CreatedAt   time.Time `json:"createdAt,omitzero"` // zero for built-in roles
+	UpdatedAt   time.Time `json:"updatedAt,omitzero"`
+}
+
+// RoleRequest is the expected payload for POST /api/admin/roles and
+// PUT /api/admin/roles/{name} (which ignores Name).
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// SetRoleRequest is the expected payload for PUT /api/admin/users/{id}/role.
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// MyPermissions is the response of GET /api/me/permissions: what the
+// caller may do server-wide, as the server decides it.
+type MyPermissions struct {
+	Role         string   `json:"role"`         // from the caller's token
+	TrustLevel   string   `json:"trustLevel"`   // from the caller's token
+	Permissions  []string `json:"permissions"`  // authz.Perm* in effect, less those the trust level withholds
+	CanPostLinks bool     `json:"canPostLinks"` // links in chat pass TRUST_LEVEL_LINKS
+}
+
+// RoomPermissions is the response of GET /api/rooms/{id}/permissions:
+// what the caller may do in one room.
+type RoomPermissions struct {
+	RoomID      string   `json:"roomId"`
+	Role        string   `json:"role"`        // RoomRole*, "" if not a member
+	Permissions []string `json:"permissions"` // authz.RoomPerm* in effect
+}
+
 // --- Auth DTOs ---
 // Data Transfer Objects for request/response serialization.
 
 // RegisterRequest is the expected payload for POST /api/register.
 type RegisterRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username          string `json:"username"`
+	Password          string `json:"password"`
+	InviteCode        string `json:"inviteCode,omitempty"`        // required when REGISTRATION_MODE is invite-only
+	ChallengeResponse string `json:"challengeResponse,omitempty"` // the answer to GET /api/challenge, when one is required
 }
 
 // LoginRequest is the expected payload for POST /api/login.
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username          string `json:"username"`
+	Password          string `json:"password"`
+	RememberMe        bool   `json:"rememberMe,omitempty"`        // also start a long-lived session for this device
+	ChallengeResponse string `json:"challengeResponse,omitempty"` // as in RegisterRequest
+}
+
+// Challenge is what a client needs to answer the challenge registration
+// and login may require (GET /api/challenge; see internal/challenge).
+type Challenge struct {
+	Provider   string `json:"provider"`             // "hcaptcha", "turnstile", "pow", or "off" if none is ever required
+	SiteKey    string `json:"siteKey,omitempty"`    // hcaptcha and turnstile: the widget's site key
+	Puzzle     string `json:"puzzle,omitempty"`     // pow: answer puzzle + ":" + a solution whose SHA-256 with it starts with Difficulty zero bits
+	Difficulty int    `json:"difficulty,omitempty"` // pow
+	Always     bool   `json:"always"`               // required on every attempt, not only from IPs making many
 }
 
 // AuthResponse is returned on successful login/register.
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token,omitempty"`        // omitted in cookie mode (AUTH_COOKIE)
+	RefreshToken string `json:"refreshToken,omitempty"` // "remember me" logins only; omitted in cookie mode
+	User         User   `json:"user"`
+}
+
+// RefreshSessionRequest is the expected payload for POST
+// /api/sessions/refresh. In cookie mode the token comes from the cookie.
+type RefreshSessionRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 // --- Room DTOs ---
 
 // CreateRoomRequest is the expected payload for POST /api/rooms.
 type CreateRoomRequest struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
-	Type        string  `json:"type"`
-	MaxMembers  int     `json:"maxMembers,omitempty"`
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	Type        string           `json:"type"`
+	MaxMembers  int              `json:"maxMembers,omitempty"`
+	Retention   *RetentionPolicy `json:"retention,omitempty"` // nil = server default
+	Tags        []string         `json:"tags,omitempty"`
 }
 
 // UpdateRoomRequest is the expected payload for PUT /api/rooms/{id}.
 type UpdateRoomRequest struct {
-	Name        *string `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
-	MaxMembers  *int    `json:"maxMembers,omitempty"`
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	MaxMembers  *int      `json:"maxMembers,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"` // replaces the room's tags; [] removes them
+}
+
+// TagCount is how many active public rooms carry a tag, as returned by
+// GET /api/rooms/tags.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Rooms int    `json:"rooms"`
+}
+
+// AddRoomMemberRequest is the expected payload for POST /api/rooms/{id}/members.
+type AddRoomMemberRequest struct {
+	UserID string `json:"userId"`
+	Role   string `json:"role,omitempty"` // RoomRoleMember (default) or RoomRoleViewer
+}
+
+// SetRoomRoleRequest is the expected payload for PUT /api/rooms/{id}/members/{userId}/role.
+type SetRoomRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// TransferOwnershipRequest is the expected payload for POST /api/rooms/{id}/transfer-ownership.
+type TransferOwnershipRequest struct {
+	UserID string `json:"userId"`
+}
+
+// RoomRecap is returned by GET /api/rooms/{id}/recap: a summary of the
+// room's last finished watch party.
+type RoomRecap struct {
+	RoomID          string         `json:"roomId"`
+	StartedAt       time.Time      `json:"startedAt"` // first viewer joined the empty room
+	EndedAt         time.Time      `json:"endedAt"`   // last viewer left
+	DurationSeconds float64        `json:"durationSeconds"`
+	PeakViewers     int            `json:"peakViewers"`
+	Sessions        int            `json:"sessions"`        // viewer connections
+	SyncCorrections int            `json:"syncCorrections"` // seeks
+	Videos          []RecapVideo   `json:"videos"`          // in the order first played
+	Messages        int            `json:"messages"`        // chat messages sent during the party
+	TopChatters     []RecapChatter `json:"topChatters"`     // most messages first, at most 5
+}
+
+// RecapVideo is a video played during a watch party.
+type RecapVideo struct {
+	URL          string  `json:"url"`
+	WatchSeconds float64 `json:"watchSeconds"` // summed over viewers
+}
+
+// RecapChatter is a member who chatted during a watch party.
+type RecapChatter struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	Messages int    `json:"messages"`
 }
 
 // --- Profile DTOs ---
@@ -213,3 +1175,77 @@ type UpdateProfileRequest struct {
 	AvatarURL   *string `json:"avatarUrl"`
 	Bio         *string `json:"bio"`
 }
+
+// Quota is one entry of GET /api/me/quotas: how much of a resource the
+// user may own and how much they do.
+type Quota struct {
+	Resource string `json:"resource"` // "rooms", "library" or "storage" (bytes)
+	Limit    int64  `json:"limit"`    // 0 = unlimited
+	Used     int64  `json:"used"`
+}
+
+// --- Admin DTOs ---
+
+// UserListResponse is returned by GET /api/admin/users.
+type UserListResponse struct {
+	Users      []*User        `json:"users"`
+	RoleCounts map[string]int `json:"roleCounts"` // matches per role, ignoring the role filter
+}
+
+// AnonymizeResponse is returned by DELETE /api/admin/users/{id} when the
+// account is anonymized. A soft delete returns 204 with no body.
+type AnonymizeResponse struct {
+	Username string `json:"username"` // the pseudonym replacing the username
+}
+
+// BulkUser is one user in a bulk import or export file
+// (POST /api/admin/users/import, GET /api/admin/users/export).
+// In CSV files the columns are the snake_case forms of the JSON keys.
+type BulkUser struct {
+	Username     string     `json:"username"`
+	Password     string     `json:"password,omitempty"`     // import only; hashed before storing
+	PasswordHash string     `json:"passwordHash,omitempty"` // bcrypt; exported only on request
+	Role         string     `json:"role,omitempty"`         // defaults to member
+	DisplayName  *string    `json:"displayName,omitempty"`
+	AvatarURL    *string    `json:"avatarUrl,omitempty"`
+	Bio          *string    `json:"bio,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"` // export only
+}
+
+// UserImportReport is returned by POST /api/admin/users/import.
+type UserImportReport struct {
+	DryRun  bool              `json:"dryRun"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Errors  []UserImportError `json:"errors"`
+	// GeneratedPasswords maps username to the password generated for rows
+	// that had neither a password nor a hash. Shown once, never stored.
+	GeneratedPasswords map[string]string `json:"generatedPasswords,omitempty"`
+}
+
+// UserImportError describes one rejected row. Row is 1-based and counts
+// data rows only (the CSV header is not a row).
+type UserImportError struct {
+	Row      int    `json:"row"`
+	Username string `json:"username,omitempty"`
+	Error    string `json:"error"` // translated, like API error messages
+	Code     string `json:"code"`  // error code (see package i18n)
+}
+
+// --- Batch DTOs ---
+
+// BatchRequest is one sub-request of POST /api/batch.
+type BatchRequest struct {
+	ID     string          `json:"id,omitempty"`   // echoed in the result, to match them up
+	Method string          `json:"method"`         // GET, POST, PUT or DELETE
+	Path   string          `json:"path"`           // e.g. "/api/rooms?limit=10"
+	Body   json.RawMessage `json:"body,omitempty"` // JSON request body
+}
+
+// BatchResult is the outcome of one BatchRequest, in request order.
+type BatchResult struct {
+	ID       string          `json:"id,omitempty"`
+	Status   int             `json:"status"`
+	Location string          `json:"location,omitempty"`
+	Body     json.RawMessage `json:"body"` // the sub-response's envelope; null for 204
+}
@@ -0,0 +1,66 @@
+package origin
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"ofenes/internal/repository"
+)
+
+// Dynamic holds patterns that can be replaced while in use, for the
+// origins admins manage at runtime. The zero value allows nothing. It is
+// safe for concurrent use.
+type Dynamic struct {
+	m atomic.Pointer[Matcher]
+}
+
+// Set replaces the patterns.
+func (d *Dynamic) Set(patterns []string) {
+	d.m.Store(New(strings.Join(patterns, ",")))
+}
+
+// Allowed reports whether origin matches the current patterns.
+func (d *Dynamic) Allowed(origin string) bool {
+	m := d.m.Load()
+	return m != nil && m.Allowed(origin)
+}
+
+// Reload replaces the patterns with the origins stored in repo.
+func (d *Dynamic) Reload(ctx context.Context, repo repository.AllowedOriginRepository) error {
+	origins, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	patterns := make([]string, len(origins))
+	for i, o := range origins {
+		patterns[i] = o.Origin
+	}
+	d.Set(patterns)
+	return nil
+}
+
+// Canonical returns pattern the way Matcher compares it (trimmed,
+// lowercase, without a trailing slash) and reports whether it is a single
+// http or https origin, optionally with a "*." subdomain wildcard or a "*"
+// port. The catch-all "*" and scheme-less patterns are rejected: they are
+// fine in CORS_ORIGINS but too broad to add at runtime.
+func Canonical(pattern string) (string, bool) {
+	p := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), "/")
+	scheme, rest, ok := strings.Cut(p, "://")
+	if !ok || (scheme != "http" && scheme != "https") || strings.ContainsAny(rest, "/?#@, \t") {
+		return "", false
+	}
+	host, port, hasPort := strings.Cut(rest, ":")
+	if hasPort && port != "*" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", false
+		}
+	}
+	host = strings.TrimPrefix(host, "*.")
+	if host == "" || strings.Contains(host, "*") {
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,145 @@
+// Package origin matches request Origin headers against configured patterns.
+//
+// It is shared by the CORS middleware and the WebSocket upgrader so both
+// enforce the same CORS_ORIGINS list, plus the origins admins add at
+// runtime (see Dynamic).
+//
+// Supported patterns (comma-separated):
+//
+//	"*"                        any origin
+//	"https://app.example.com"  exact match (scheme, host and port)
+//	"https://*.example.com"    any subdomain of example.com over https (not the apex)
+//	"*.example.com"            any subdomain of example.com over any scheme
+//	"http://localhost:*"       any port on localhost
+package origin
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Matcher checks origins against a fixed set of patterns.
+type Matcher struct {
+	any      bool
+	exact    map[string]bool
+	patterns []pattern
+}
+
+// pattern is a parsed wildcard entry.
+type pattern struct {
+	scheme  string // "" = any scheme
+	suffix  string // ".example.com" for subdomain wildcards, "" otherwise
+	host    string // exact host when suffix is empty
+	anyPort bool
+	port    string
+}
+
+// New parses a comma-separated pattern list. Blank entries are ignored.
+func New(patterns string) *Matcher {
+	m := &Matcher{exact: make(map[string]bool)}
+
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		p = strings.TrimSuffix(p, "/")
+		switch {
+		case p == "":
+			continue
+		case p == "*":
+			m.any = true
+		case strings.Contains(p, "*"):
+			m.patterns = append(m.patterns, parsePattern(p))
+		default:
+			m.exact[p] = true
+		}
+	}
+	return m
+}
+
+// Valid reports whether p is a pattern New understands: "*", a single
+// http or https origin as Canonical accepts, or a scheme-less "*."
+// subdomain wildcard. Anything else would never match.
+func Valid(p string) bool {
+	p = strings.TrimSpace(p)
+	if p == "*" {
+		return true
+	}
+	if !strings.Contains(p, "://") {
+		if !strings.HasPrefix(p, "*.") {
+			return false
+		}
+		p = "https://" + p
+	}
+	_, ok := Canonical(p)
+	return ok
+}
+
+// parsePattern splits a wildcard entry into scheme, host and port parts.
+func parsePattern(p string) pattern {
+	var pt pattern
+	if scheme, rest, ok := strings.Cut(p, "://"); ok {
+		pt.scheme = scheme
+		p = rest
+	}
+
+	host, port, hasPort := strings.Cut(p, ":")
+	if hasPort {
+		pt.anyPort = port == "*"
+		pt.port = port
+	}
+
+	if strings.HasPrefix(host, "*.") {
+		pt.suffix = host[1:]
+	} else {
+		pt.host = host
+	}
+	return pt
+}
+
+// AllowsAny reports whether the "*" pattern is configured.
+func (m *Matcher) AllowsAny() bool {
+	return m.any
+}
+
+// Allowed reports whether origin matches any configured pattern.
+// An empty origin never matches.
+func (m *Matcher) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if m.any {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true
+	}
+	if len(m.patterns) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host, port := u.Hostname(), u.Port()
+
+	for _, p := range m.patterns {
+		if p.scheme != "" && p.scheme != u.Scheme {
+			continue
+		}
+		if !p.anyPort && p.port != port {
+			continue
+		}
+		if p.suffix != "" {
+			if strings.HasSuffix(host, p.suffix) && len(host) > len(p.suffix) {
+				return true
+			}
+			continue
+		}
+		if p.host == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// Package quota limits how much each user may own: active rooms, items in
+// their personal library and bytes of uploaded video.
+//
+// Each resource has a limit per user, set in QUOTAS and overridden per
+// role in QUOTA_ROLES, and for single users by their entitlements (see
+// package entitlement), the resource names being features; roles with
+// the authz.PermQuotaBypass permission have none. Handlers call Check before creating something, which counts
+// what the user already has through the Counter registered for the
+// resource. Two requests racing each other may both pass, so a quota can
+// be overshot by what one request adds.
+//
+// Usage:
+//
+//	limits, _ := quota.ParseLimits(cfg.Quotas)
+//	roles, _ := quota.ParseRoleLimits(cfg.QuotaRoles)
+//	quotas := quota.New(limits, roles, authorizer, entitlements)
+//	quotas.Register(quota.Rooms, func(ctx context.Context, userID string) (int64, error) { ... })
+//	err := quotas.Check(ctx, userID, role, quota.Rooms, 1) // wraps ErrExceeded
+package quota
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/entitlement"
+	"ofenes/internal/models"
+	"ofenes/pkg/apperr"
+)
+
+// Resource names.
+const (
+	Rooms   = "rooms"   // active rooms the user owns
+	Library = "library" // items in the user's personal library
+	Storage = "storage" // bytes of the user's uploads, unfinished ones included
+)
+
+// Resources lists every resource, in the order Usage reports them.
+var Resources = []string{Rooms, Library, Storage}
+
+// DefaultLimits is the limit per user of each resource; 0 means none.
+var DefaultLimits = map[string]int64{
+	Rooms:   20,
+	Library: 1000,
+	Storage: 10 << 30, // 10 GiB
+}
+
+// ErrExceeded is returned (wrapped, with the resource and its limit as
+// args) by Check when what the user asked for would take them over their
+// quota.
+var ErrExceeded = apperr.New(apperr.Forbidden, "quota_exceeded", "quota: exceeded")
+
+// Counter returns how much of a resource userID has.
+type Counter func(ctx context.Context, userID string) (int64, error)
+
+// ParseLimits parses a "resource=n,..." list from config, e.g.
+// "rooms=5,storage=1073741824". Listed resources override DefaultLimits;
+// 0 lifts the limit.
+func ParseLimits(s string) (map[string]int64, error) {
+	limits := maps.Clone(DefaultLimits)
+	if err := parseInto(limits, s); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// ParseRoleLimits parses per-role overrides from config, as
+// "role:resource=n,...;role:...", e.g. "member:rooms=5;moderator:storage=0".
+// Resources a role does not list keep the limits of ParseLimits.
+func ParseRoleLimits(s string) (map[string]map[string]int64, error) {
+	roles := make(map[string]map[string]int64)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		role, list, ok := strings.Cut(entry, ":")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("quota: invalid role quotas %q (want role:resource=n,...)", entry)
+		}
+		if roles[role] == nil {
+			roles[role] = make(map[string]int64)
+		}
+		if err := parseInto(roles[role], list); err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+// parseInto parses a "resource=n,..." list into limits.
+func parseInto(limits map[string]int64, s string) error {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		resource, nStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("quota: invalid limit %q (want resource=n)", entry)
+		}
+		resource = strings.TrimSpace(resource)
+		if _, known := DefaultLimits[resource]; !known {
+			return fmt.Errorf("quota: unknown resource %q", resource)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(nStr), 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("quota: invalid limit %q", entry)
+		}
+		limits[resource] = n
+	}
+	return nil
+}
+
+// Quotas checks what users create against their limits.
+type Quotas struct {
+	limits       map[string]int64
+	roles        map[string]map[string]int64
+	authz        *authz.Authorizer
+	entitlements *entitlement.Entitlements
+	counters     map[string]Counter
+}
+
+// New creates quotas with the given limits and per-role overrides (see
+// ParseLimits and ParseRoleLimits). az decides which roles bypass them;
+// the limits users are entitled to replace their role's.
+func New(limits map[string]int64, roles map[string]map[string]int64, az *authz.Authorizer, entitlements *entitlement.Entitlements) *Quotas {
+	return &Quotas{limits: limits, roles: roles, authz: az, entitlements: entitlements, counters: make(map[string]Counter)}
+}
+
+// Register sets how resource is counted. Resources never registered are
+// not limited.
+func (q *Quotas) Register(resource string, count Counter) {
+	q.counters[resource] = count
+}
+
+// Limit returns userID's limit of resource, given their role, 0 if there
+// is none.
+func (q *Quotas) Limit(ctx context.Context, userID, role, resource string) int64 {
+	if q.counters[resource] == nil || q.authz.Can(role, authz.PermQuotaBypass) {
+		return 0
+	}
+	if n, ok := q.entitlements.Limit(ctx, userID, resource); ok {
+		return n
+	}
+	if n, ok := q.roles[role][resource]; ok {
+		return n
+	}
+	return q.limits[resource]
+}
+
+// Check returns an error wrapping ErrExceeded if adding n of resource
+// would take userID, of role, over their limit.
+func (q *Quotas) Check(ctx context.Context, userID, role, resource string, n int64) error {
+	limit := q.Limit(ctx, userID, role, resource)
+	if limit == 0 {
+		return nil
+	}
+	used, err := q.counters[resource](ctx, userID)
+	if err != nil {
+		return fmt.Errorf("quota: count %s: %w", resource, err)
+	}
+	if used+n > limit {
+		return apperr.Wrap(ErrExceeded, apperr.Forbidden, "quota_exceeded", resource, limit)
+	}
+	return nil
+}
+
+// Usage returns userID's limit and use of every resource, for
+// GET /api/me/quotas.
+func (q *Quotas) Usage(ctx context.Context, userID, role string) ([]models.Quota, error) {
+	usage := make([]models.Quota, 0, len(Resources))
+	for _, resource := range Resources {
+		count := q.counters[resource]
+		if count == nil {
+			continue
+		}
+		used, err := count(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("quota: count %s: %w", resource, err)
+		}
+		usage = append(usage, models.Quota{Resource: resource, Limit: q.Limit(ctx, userID, role, resource), Used: used})
+	}
+	return usage, nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// AllowedOriginRepository stores the origins admins allow at runtime. Like
+// the word filters, the list is small and always loaded whole.
+type AllowedOriginRepository interface {
+	// Create stores a new origin. Returns ErrAlreadyExists if the same
+	// origin is already stored.
+	Create(ctx context.Context, origin *models.AllowedOrigin) error
+
+	// Delete removes an origin. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// GetByID retrieves an origin by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.AllowedOrigin, error)
+
+	// List returns every origin, oldest first.
+	List(ctx context.Context) ([]*models.AllowedOrigin, error)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// AnnouncementRepository stores the site-wide announcements and which
+// users dismissed them. Like the word filters, the list is small: the Hub
+// loads the current announcements whole.
+type AnnouncementRepository interface {
+	// Create stores a new announcement.
+	Create(ctx context.Context, a *models.Announcement) error
+
+	// Update replaces an announcement's title, body, level and schedule,
+	// and sets UpdatedAt. Dismissals are kept. Returns ErrNotFound if
+	// missing.
+	Update(ctx context.Context, a *models.Announcement) error
+
+	// Delete removes an announcement and its dismissals. Returns
+	// ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// GetByID retrieves an announcement by ID. Returns ErrNotFound if
+	// missing.
+	GetByID(ctx context.Context, id string) (*models.Announcement, error)
+
+	// List returns every announcement, latest start first.
+	List(ctx context.Context) ([]*models.Announcement, error)
+
+	// ListCurrent returns the announcements that have not ended at t,
+	// scheduled ones included, earliest start first.
+	ListCurrent(ctx context.Context, t time.Time) ([]*models.Announcement, error)
+
+	// Dismiss records that userID dismissed announcement id at t.
+	// Dismissing it again is a no-op. Returns ErrNotFound if the
+	// announcement is missing.
+	Dismiss(ctx context.Context, id, userID string, t time.Time) error
+
+	// ListDismissed returns the IDs of the announcements userID dismissed.
+	ListDismissed(ctx context.Context, userID string) ([]string, error)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// AuditRepository defines the contract for the append-only audit log.
+type AuditRepository interface {
+	// Create appends an entry.
+	Create(ctx context.Context, entry *models.AuditEntry) error
+
+	// List returns entries matching filter, newest first.
+	List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
+
+	// CountBefore returns how many entries were created before the given time.
+	CountBefore(ctx context.Context, before time.Time) (int, error)
+
+	// DeleteBefore deletes entries created before the given time and returns
+	// how many were deleted.
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Action   string
+	ActorID  string
+	TargetID string
+	Limit    int
+	Offset   int
+}
+
+// matches reports whether e passes the filter.
+func (f AuditFilter) matches(e *models.AuditEntry) bool {
+	return (f.Action == "" || e.Action == f.Action) &&
+		(f.ActorID == "" || e.ActorID == f.ActorID) &&
+		(f.TargetID == "" || e.TargetID == f.TargetID)
+}
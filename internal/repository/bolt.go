@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The bbolt backend keeps one bucket per entity, values JSON-encoded.
+// Composite keys join their parts with a 0 byte, so prefix scans over the
+// leading parts work; timestamps are big-endian nanoseconds so they sort.
+//
+//	users                       user ID -> boltUser
+//	users_by_username           username.Key(username) -> user ID
+//	users_by_skeleton           username.Skeleton(username), user ID -> user ID
+//	rooms                       room ID -> models.Room
+//	room_members                room ID, user ID -> models.RoomMember
+//	member_rooms                user ID, room ID -> (empty)
+//	messages                    room ID, created_at, message ID -> models.ChatMessage
+//	messages_by_id              message ID -> key in messages
+//	media_sessions              session ID -> models.MediaSession
+//	media_session_participants  session ID, user ID, joined_at -> models.MediaSessionParticipant
+//	shared_files                file ID -> models.SharedFile
+//	media_files                 media file ID -> models.MediaFile
+//	subtitles                   subtitle ID -> models.Subtitle
+//	library                     item ID -> models.LibraryItem
+//	metadata_lookups            query key -> models.MetadataLookup
+//	audit_log                   created_at, entry ID -> models.AuditEntry
+//	dead_letters                created_at, dead letter ID -> models.DeadLetter
+//	reports                     created_at, report ID -> models.Report
+//	reports_by_id               report ID -> key in reports
+//	word_filters                filter ID -> models.WordFilter
+//	allowed_origins             origin ID -> models.AllowedOrigin
+//	announcements               announcement ID -> models.Announcement
+//	announcement_dismissals     user ID, announcement ID -> dismissed_at
+//	instance                    "instance" -> models.Instance
+//	invites                     invite ID -> models.Invite
+//	invites_by_code             code -> invite ID
+//	legal_documents             kind, version -> models.LegalDocument
+//	legal_acceptances           user ID, kind, version -> models.LegalAcceptance
+//	roles                       role name -> models.RoleDefinition
+//
+// Buckets are created by database.MigrateBolt.
+
+// boltView runs fn in a read-only transaction unless ctx is done. bbolt
+// transactions can't be interrupted, so a request that has been abandoned
+// (client gone, deadline passed) is stopped before it starts one.
+func boltView(ctx context.Context, db *bolt.DB, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return db.View(fn)
+}
+
+// boltUpdate runs fn in a read-write transaction unless ctx is done. ctx is
+// checked again once the transaction has started, since it may have waited
+// behind other writers, so abandoned requests don't write.
+func boltUpdate(ctx context.Context, db *bolt.DB, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// boltKey joins key parts with a 0 byte separator.
+func boltKey(parts ...[]byte) []byte {
+	return bytes.Join(parts, []byte{0})
+}
+
+// boltPrefix returns the scan prefix for keys starting with parts.
+func boltPrefix(parts ...[]byte) []byte {
+	return append(boltKey(parts...), 0)
+}
+
+// boltTime encodes t so that byte order matches time order.
+func boltTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+// boltGet decodes the value at key into v. Returns ErrNotFound if missing.
+func boltGet(tx *bolt.Tx, bucket string, key []byte, v any) error {
+	data := tx.Bucket([]byte(bucket)).Get(key)
+	if data == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+// boltPut encodes v and stores it at key.
+func boltPut(tx *bolt.Tx, bucket string, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(bucket)).Put(key, data)
+}
+
+// boltScan calls fn for each key/value with the given prefix, in key order.
+func boltScan(tx *bolt.Tx, bucket string, prefix []byte, fn func(k, v []byte) error) error {
+	c := tx.Bucket([]byte(bucket)).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paginate returns items[offset:offset+limit], clamped to the slice.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	end := min(offset+limit, len(items))
+	return items[offset:end]
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltAllowedOriginRepo implements AllowedOriginRepository against a bbolt file.
+type BoltAllowedOriginRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltAllowedOriginRepo creates a new bbolt-backed allowed origin repository.
+func NewBoltAllowedOriginRepo(db *bolt.DB) *BoltAllowedOriginRepo {
+	return &BoltAllowedOriginRepo{db: db}
+}
+
+// Create stores a new origin. The list is small, so duplicates are found
+// by scanning it.
+func (r *BoltAllowedOriginRepo) Create(ctx context.Context, origin *models.AllowedOrigin) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		err := tx.Bucket([]byte("allowed_origins")).ForEach(func(_, v []byte) error {
+			var o models.AllowedOrigin
+			if err := json.Unmarshal(v, &o); err != nil {
+				return err
+			}
+			if o.Origin == origin.Origin {
+				return ErrAlreadyExists
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return boltPut(tx, "allowed_origins", []byte(origin.ID), origin)
+	})
+}
+
+// Delete removes an origin.
+func (r *BoltAllowedOriginRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("allowed_origins"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// GetByID retrieves an origin by ID.
+func (r *BoltAllowedOriginRepo) GetByID(ctx context.Context, id string) (*models.AllowedOrigin, error) {
+	var origin models.AllowedOrigin
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "allowed_origins", []byte(id), &origin)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &origin, nil
+}
+
+// List returns every origin, oldest first.
+func (r *BoltAllowedOriginRepo) List(ctx context.Context) ([]*models.AllowedOrigin, error) {
+	var origins []*models.AllowedOrigin
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("allowed_origins")).ForEach(func(_, v []byte) error {
+			var o models.AllowedOrigin
+			if err := json.Unmarshal(v, &o); err != nil {
+				return err
+			}
+			origins = append(origins, &o)
+			return nil
+		})
+	})
+	sort.Slice(origins, func(i, j int) bool {
+		if !origins[i].CreatedAt.Equal(origins[j].CreatedAt) {
+			return origins[i].CreatedAt.Before(origins[j].CreatedAt)
+		}
+		return origins[i].ID < origins[j].ID
+	})
+	return origins, err
+}
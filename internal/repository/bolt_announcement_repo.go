@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltAnnouncementRepo implements AnnouncementRepository against a bbolt
+// file.
+type BoltAnnouncementRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltAnnouncementRepo creates a new bbolt-backed announcement repository.
+func NewBoltAnnouncementRepo(db *bolt.DB) *BoltAnnouncementRepo {
+	return &BoltAnnouncementRepo{db: db}
+}
+
+// Create stores a new announcement.
+func (r *BoltAnnouncementRepo) Create(ctx context.Context, a *models.Announcement) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "announcements", []byte(a.ID), a)
+	})
+}
+
+// Update replaces an announcement's title, body, level and schedule.
+func (r *BoltAnnouncementRepo) Update(ctx context.Context, a *models.Announcement) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var stored models.Announcement
+		if err := boltGet(tx, "announcements", []byte(a.ID), &stored); err != nil {
+			return err
+		}
+		stored.Title, stored.Body, stored.Level = a.Title, a.Body, a.Level
+		stored.StartsAt, stored.EndsAt, stored.UpdatedAt = a.StartsAt, a.EndsAt, a.UpdatedAt
+		return boltPut(tx, "announcements", []byte(a.ID), &stored)
+	})
+}
+
+// Delete removes an announcement and its dismissals.
+func (r *BoltAnnouncementRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("announcements"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		if err := b.Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		// Dismissals are keyed by user first, so find them by suffix.
+		suffix := append([]byte{0}, id...)
+		dismissals := tx.Bucket([]byte("announcement_dismissals"))
+		var keys [][]byte
+		err := dismissals.ForEach(func(k, _ []byte) error {
+			if bytes.HasSuffix(k, suffix) {
+				keys = append(keys, bytes.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := dismissals.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByID retrieves an announcement by ID.
+func (r *BoltAnnouncementRepo) GetByID(ctx context.Context, id string) (*models.Announcement, error) {
+	var a models.Announcement
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "announcements", []byte(id), &a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// List returns every announcement, latest start first.
+func (r *BoltAnnouncementRepo) List(ctx context.Context) ([]*models.Announcement, error) {
+	all, err := r.all(ctx, func(*models.Announcement) bool { return true })
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	return all, err
+}
+
+// ListCurrent returns the announcements that have not ended at t, earliest
+// start first.
+func (r *BoltAnnouncementRepo) ListCurrent(ctx context.Context, t time.Time) ([]*models.Announcement, error) {
+	return r.all(ctx, func(a *models.Announcement) bool {
+		return a.EndsAt == nil || a.EndsAt.After(t)
+	})
+}
+
+// all returns the announcements keep accepts, earliest start first.
+func (r *BoltAnnouncementRepo) all(ctx context.Context, keep func(*models.Announcement) bool) ([]*models.Announcement, error) {
+	var announcements []*models.Announcement
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("announcements")).ForEach(func(_, v []byte) error {
+			var a models.Announcement
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			if keep(&a) {
+				announcements = append(announcements, &a)
+			}
+			return nil
+		})
+	})
+	sort.Slice(announcements, func(i, j int) bool {
+		if !announcements[i].StartsAt.Equal(announcements[j].StartsAt) {
+			return announcements[i].StartsAt.Before(announcements[j].StartsAt)
+		}
+		return announcements[i].ID < announcements[j].ID
+	})
+	return announcements, err
+}
+
+// Dismiss records that userID dismissed announcement id at t.
+func (r *BoltAnnouncementRepo) Dismiss(ctx context.Context, id, userID string, t time.Time) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("announcements")).Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		b := tx.Bucket([]byte("announcement_dismissals"))
+		key := boltKey([]byte(userID), []byte(id))
+		if b.Get(key) != nil {
+			return nil
+		}
+		return b.Put(key, boltTime(t))
+	})
+}
+
+// ListDismissed returns the IDs of the announcements userID dismissed.
+func (r *BoltAnnouncementRepo) ListDismissed(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	prefix := boltPrefix([]byte(userID))
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltScan(tx, "announcement_dismissals", prefix, func(k, _ []byte) error {
+			ids = append(ids, string(bytes.TrimPrefix(k, prefix)))
+			return nil
+		})
+	})
+	return ids, err
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltAuditRepo implements AuditRepository against a bbolt file. Entries
+// are keyed by creation time, so age-based deletes are a single range.
+type BoltAuditRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltAuditRepo creates a new bbolt-backed audit repository.
+func NewBoltAuditRepo(db *bolt.DB) *BoltAuditRepo {
+	return &BoltAuditRepo{db: db}
+}
+
+// Create appends an entry.
+func (r *BoltAuditRepo) Create(ctx context.Context, entry *models.AuditEntry) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "audit_log", boltKey(boltTime(entry.CreatedAt), []byte(entry.ID)), entry)
+	})
+}
+
+// List returns entries matching filter, newest first.
+func (r *BoltAuditRepo) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	var entries []*models.AuditEntry
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		skip := filter.Offset
+		c := tx.Bucket([]byte("audit_log")).Cursor()
+		for k, v := c.Last(); k != nil && len(entries) < filter.Limit; k, v = c.Prev() {
+			var e models.AuditEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if !filter.matches(&e) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			entries = append(entries, &e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// CountBefore returns how many entries were created before the given time.
+func (r *BoltAuditRepo) CountBefore(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		end := boltTime(before)
+		c := tx.Bucket([]byte("audit_log")).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// DeleteBefore deletes entries created before the given time.
+func (r *BoltAuditRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	var deleted int
+	err := boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("audit_log"))
+		end := boltTime(before)
+
+		// Collect first: deleting while iterating a bbolt cursor skips keys.
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			keys = append(keys, bytes.Clone(k))
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return nil
+	})
+	return deleted, err
+}
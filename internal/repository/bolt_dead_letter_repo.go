@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltDeadLetterRepo implements DeadLetterRepository against a bbolt file.
+// Dead letters are keyed by creation time, so age-based deletes are a
+// single range.
+type BoltDeadLetterRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltDeadLetterRepo creates a new bbolt-backed dead letter repository.
+func NewBoltDeadLetterRepo(db *bolt.DB) *BoltDeadLetterRepo {
+	return &BoltDeadLetterRepo{db: db}
+}
+
+// Create appends a dead letter.
+func (r *BoltDeadLetterRepo) Create(ctx context.Context, letter *models.DeadLetter) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "dead_letters", boltKey(boltTime(letter.CreatedAt), []byte(letter.ID)), letter)
+	})
+}
+
+// List returns dead letters matching filter, newest first.
+func (r *BoltDeadLetterRepo) List(ctx context.Context, filter DeadLetterFilter) ([]*models.DeadLetter, error) {
+	var letters []*models.DeadLetter
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		skip := filter.Offset
+		c := tx.Bucket([]byte("dead_letters")).Cursor()
+		for k, v := c.Last(); k != nil && len(letters) < filter.Limit; k, v = c.Prev() {
+			var l models.DeadLetter
+			if err := json.Unmarshal(v, &l); err != nil {
+				return err
+			}
+			if !filter.Matches(&l) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			letters = append(letters, &l)
+		}
+		return nil
+	})
+	return letters, err
+}
+
+// CountBefore returns how many dead letters were created before the given time.
+func (r *BoltDeadLetterRepo) CountBefore(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		end := boltTime(before)
+		c := tx.Bucket([]byte("dead_letters")).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// DeleteBefore deletes dead letters created before the given time.
+func (r *BoltDeadLetterRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	var deleted int
+	err := boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("dead_letters"))
+		end := boltTime(before)
+
+		// Collect first: deleting while iterating a bbolt cursor skips keys.
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			keys = append(keys, bytes.Clone(k))
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltInstanceKey is the key of the single record in the "instance" bucket.
+var boltInstanceKey = []byte("instance")
+
+// BoltInstanceRepo implements InstanceRepository against a bbolt file.
+type BoltInstanceRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltInstanceRepo creates a new bbolt-backed instance metadata repository.
+func NewBoltInstanceRepo(db *bolt.DB) *BoltInstanceRepo {
+	return &BoltInstanceRepo{db: db}
+}
+
+// Get retrieves the saved instance metadata.
+func (r *BoltInstanceRepo) Get(ctx context.Context) (*models.Instance, error) {
+	var instance models.Instance
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "instance", boltInstanceKey, &instance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// Put saves the instance metadata, replacing what was saved before.
+func (r *BoltInstanceRepo) Put(ctx context.Context, instance *models.Instance) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "instance", boltInstanceKey, instance)
+	})
+}
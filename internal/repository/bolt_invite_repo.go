@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltInviteRepo implements InviteRepository against a bbolt file.
+type BoltInviteRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltInviteRepo creates a new bbolt-backed invite repository.
+func NewBoltInviteRepo(db *bolt.DB) *BoltInviteRepo {
+	return &BoltInviteRepo{db: db}
+}
+
+// Create stores a new invite and indexes its code.
+func (r *BoltInviteRepo) Create(ctx context.Context, invite *models.Invite) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		byCode := tx.Bucket([]byte("invites_by_code"))
+		if byCode.Get([]byte(invite.Code)) != nil {
+			return ErrAlreadyExists
+		}
+		if err := byCode.Put([]byte(invite.Code), []byte(invite.ID)); err != nil {
+			return err
+		}
+		return boltPut(tx, "invites", []byte(invite.ID), invite)
+	})
+}
+
+// Delete removes an invite and its code.
+func (r *BoltInviteRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var invite models.Invite
+		if err := boltGet(tx, "invites", []byte(id), &invite); err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte("invites_by_code")).Delete([]byte(invite.Code)); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("invites")).Delete([]byte(id))
+	})
+}
+
+// GetByID retrieves an invite by ID.
+func (r *BoltInviteRepo) GetByID(ctx context.Context, id string) (*models.Invite, error) {
+	var invite models.Invite
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "invites", []byte(id), &invite)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// List returns every invite, newest first.
+func (r *BoltInviteRepo) List(ctx context.Context) ([]*models.Invite, error) {
+	var invites []*models.Invite
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("invites")).ForEach(func(_, v []byte) error {
+			var i models.Invite
+			if err := json.Unmarshal(v, &i); err != nil {
+				return err
+			}
+			invites = append(invites, &i)
+			return nil
+		})
+	})
+	sort.Slice(invites, func(i, j int) bool {
+		if !invites[i].CreatedAt.Equal(invites[j].CreatedAt) {
+			return invites[i].CreatedAt.After(invites[j].CreatedAt)
+		}
+		return invites[i].ID > invites[j].ID
+	})
+	return invites, err
+}
+
+// Redeem uses up the invite with code once, in one write transaction.
+func (r *BoltInviteRepo) Redeem(ctx context.Context, code string, now time.Time) (*models.Invite, error) {
+	var invite models.Invite
+	err := boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		id := tx.Bucket([]byte("invites_by_code")).Get([]byte(code))
+		if id == nil {
+			return ErrNotFound
+		}
+		if err := boltGet(tx, "invites", id, &invite); err != nil {
+			return err
+		}
+		if invite.Uses >= invite.MaxUses || (invite.ExpiresAt != nil && !invite.ExpiresAt.After(now)) {
+			return ErrNotFound
+		}
+		invite.Uses++
+		return boltPut(tx, "invites", []byte(invite.ID), &invite)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
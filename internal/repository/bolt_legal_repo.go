@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltLegalRepo implements LegalRepository against a bbolt file.
+type BoltLegalRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltLegalRepo creates a new bbolt-backed legal document repository.
+func NewBoltLegalRepo(db *bolt.DB) *BoltLegalRepo {
+	return &BoltLegalRepo{db: db}
+}
+
+// Publish stores a new version of a document.
+func (r *BoltLegalRepo) Publish(ctx context.Context, doc *models.LegalDocument) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := boltKey([]byte(doc.Kind), []byte(doc.Version))
+		if tx.Bucket([]byte("legal_documents")).Get(key) != nil {
+			return ErrAlreadyExists
+		}
+		return boltPut(tx, "legal_documents", key, doc)
+	})
+}
+
+// Current returns the newest published version of kind.
+func (r *BoltLegalRepo) Current(ctx context.Context, kind string) (*models.LegalDocument, error) {
+	docs, err := r.ListVersions(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	return docs[0], nil
+}
+
+// GetVersion retrieves a version of kind.
+func (r *BoltLegalRepo) GetVersion(ctx context.Context, kind, version string) (*models.LegalDocument, error) {
+	var doc models.LegalDocument
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "legal_documents", boltKey([]byte(kind), []byte(version)), &doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ListVersions returns every version of kind, newest first.
+func (r *BoltLegalRepo) ListVersions(ctx context.Context, kind string) ([]*models.LegalDocument, error) {
+	var docs []*models.LegalDocument
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltScan(tx, "legal_documents", boltPrefix([]byte(kind)), func(_, v []byte) error {
+			var doc models.LegalDocument
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return err
+			}
+			docs = append(docs, &doc)
+			return nil
+		})
+	})
+	sort.Slice(docs, func(i, j int) bool {
+		if !docs[i].PublishedAt.Equal(docs[j].PublishedAt) {
+			return docs[i].PublishedAt.After(docs[j].PublishedAt)
+		}
+		return docs[i].Version > docs[j].Version
+	})
+	return docs, err
+}
+
+// Accept records an acceptance unless that version was accepted before.
+func (r *BoltLegalRepo) Accept(ctx context.Context, a *models.LegalAcceptance) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := boltKey([]byte(a.UserID), []byte(a.Kind), []byte(a.Version))
+		if tx.Bucket([]byte("legal_acceptances")).Get(key) != nil {
+			return nil
+		}
+		return boltPut(tx, "legal_acceptances", key, a)
+	})
+}
+
+// HasAccepted reports whether userID accepted that version of kind.
+func (r *BoltLegalRepo) HasAccepted(ctx context.Context, userID, kind, version string) (bool, error) {
+	var ok bool
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		ok = tx.Bucket([]byte("legal_acceptances")).Get(boltKey([]byte(userID), []byte(kind), []byte(version))) != nil
+		return nil
+	})
+	return ok, err
+}
+
+// ListAcceptances returns everything userID accepted, newest first.
+func (r *BoltLegalRepo) ListAcceptances(ctx context.Context, userID string) ([]*models.LegalAcceptance, error) {
+	var accepted []*models.LegalAcceptance
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltScan(tx, "legal_acceptances", boltPrefix([]byte(userID)), func(_, v []byte) error {
+			var a models.LegalAcceptance
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			accepted = append(accepted, &a)
+			return nil
+		})
+	})
+	sort.Slice(accepted, func(i, j int) bool {
+		if !accepted[i].AcceptedAt.Equal(accepted[j].AcceptedAt) {
+			return accepted[i].AcceptedAt.After(accepted[j].AcceptedAt)
+		}
+		if accepted[i].Kind != accepted[j].Kind {
+			return accepted[i].Kind < accepted[j].Kind
+		}
+		return accepted[i].Version > accepted[j].Version
+	})
+	return accepted, err
+}
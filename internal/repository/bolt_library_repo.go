@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltLibraryRepo implements LibraryRepository against a bbolt file.
+type BoltLibraryRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltLibraryRepo creates a new bbolt-backed library repository.
+func NewBoltLibraryRepo(db *bolt.DB) *BoltLibraryRepo {
+	return &BoltLibraryRepo{db: db}
+}
+
+// Create stores an item.
+func (r *BoltLibraryRepo) Create(ctx context.Context, item *models.LibraryItem) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "library", []byte(item.ID), item)
+	})
+}
+
+// GetByID retrieves an item by ID.
+func (r *BoltLibraryRepo) GetByID(ctx context.Context, id string) (*models.LibraryItem, error) {
+	var item models.LibraryItem
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "library", []byte(id), &item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Update replaces the editable fields of an item.
+func (r *BoltLibraryRepo) Update(ctx context.Context, item *models.LibraryItem) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var stored models.LibraryItem
+		if err := boltGet(tx, "library", []byte(item.ID), &stored); err != nil {
+			return err
+		}
+		stored.Title = item.Title
+		stored.URL = item.URL
+		stored.Folder = item.Folder
+		stored.Tags = item.Tags
+		stored.Metadata = item.Metadata
+		stored.UpdatedAt = item.UpdatedAt
+		return boltPut(tx, "library", []byte(item.ID), &stored)
+	})
+}
+
+// Delete removes an item.
+func (r *BoltLibraryRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("library"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// List returns items matching filter, newest first.
+func (r *BoltLibraryRepo) List(ctx context.Context, filter LibraryFilter) ([]*models.LibraryItem, error) {
+	items, err := r.filter(ctx, filter.matches)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return paginate(items, filter.Limit, filter.Offset), nil
+}
+
+// Count returns how many items a user or room has.
+func (r *BoltLibraryRepo) Count(ctx context.Context, ownerID, roomID string) (int, error) {
+	items, err := r.filter(ctx, func(item *models.LibraryItem) bool {
+		return (ownerID != "" && item.OwnerID == ownerID) || (roomID != "" && item.RoomID == roomID)
+	})
+	return len(items), err
+}
+
+// filter returns the items keep accepts, in no particular order.
+func (r *BoltLibraryRepo) filter(ctx context.Context, keep func(*models.LibraryItem) bool) ([]*models.LibraryItem, error) {
+	var items []*models.LibraryItem
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("library")).ForEach(func(_, v []byte) error {
+			var item models.LibraryItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			if keep(&item) {
+				items = append(items, &item)
+			}
+			return nil
+		})
+	})
+	return items, err
+}
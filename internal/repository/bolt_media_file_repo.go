@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltMediaFileRepo implements MediaFileRepository against a bbolt file.
+type BoltMediaFileRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltMediaFileRepo creates a new bbolt-backed media file repository.
+func NewBoltMediaFileRepo(db *bolt.DB) *BoltMediaFileRepo {
+	return &BoltMediaFileRepo{db: db}
+}
+
+// Create stores a record.
+func (r *BoltMediaFileRepo) Create(ctx context.Context, file *models.MediaFile) error {
+	stored := *file
+	stored.Received = 0 // comes from the media store, as do the preview URLs
+	stored.PosterURL, stored.PreviewURL = "", ""
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "media_files", []byte(file.ID), &stored)
+	})
+}
+
+// GetByID retrieves a record by ID.
+func (r *BoltMediaFileRepo) GetByID(ctx context.Context, id string) (*models.MediaFile, error) {
+	var file models.MediaFile
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "media_files", []byte(id), &file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListByRoom returns a room's media, newest first.
+func (r *BoltMediaFileRepo) ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaFile, error) {
+	files, err := r.filter(ctx, func(f *models.MediaFile) bool { return f.RoomID == roomID })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return paginate(files, limit, offset), nil
+}
+
+// ListUploadingBefore returns unfinished uploads started before the cutoff, oldest first.
+func (r *BoltMediaFileRepo) ListUploadingBefore(ctx context.Context, before time.Time) ([]*models.MediaFile, error) {
+	files, err := r.filter(ctx, func(f *models.MediaFile) bool {
+		return f.Status == models.MediaFileUploading && f.CreatedAt.Before(before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.Before(files[j].CreatedAt) })
+	return files, nil
+}
+
+// TotalSizeByUploader returns the sum of the sizes of userID's uploads.
+func (r *BoltMediaFileRepo) TotalSizeByUploader(ctx context.Context, userID string) (int64, error) {
+	files, err := r.filter(ctx, func(f *models.MediaFile) bool { return f.UploadedBy == userID })
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total, err
+}
+
+// MarkReady marks an upload as complete.
+func (r *BoltMediaFileRepo) MarkReady(ctx context.Context, id string, at time.Time) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var file models.MediaFile
+		if err := boltGet(tx, "media_files", []byte(id), &file); err != nil {
+			return err
+		}
+		file.Status = models.MediaFileReady
+		file.UpdatedAt = at
+		return boltPut(tx, "media_files", []byte(id), &file)
+	})
+}
+
+// Delete removes a record.
+func (r *BoltMediaFileRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("media_files"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// SetTranscode replaces the record's transcode job.
+func (r *BoltMediaFileRepo) SetTranscode(ctx context.Context, id string, t models.Transcode) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var file models.MediaFile
+		if err := boltGet(tx, "media_files", []byte(id), &file); err != nil {
+			return err
+		}
+		file.Transcode = &t
+		return boltPut(tx, "media_files", []byte(id), &file)
+	})
+}
+
+// ClaimTranscode hands worker the longest-waiting queued or stale job.
+// bbolt serializes write transactions, so the claim is atomic.
+func (r *BoltMediaFileRepo) ClaimTranscode(ctx context.Context, worker string, at, staleBefore time.Time) (*models.MediaFile, error) {
+	var claimed *models.MediaFile
+	err := boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("media_files"))
+		err := b.ForEach(func(_, v []byte) error {
+			var f models.MediaFile
+			if err := json.Unmarshal(v, &f); err != nil {
+				return err
+			}
+			t := f.Transcode
+			if t == nil || !(t.Status == models.TranscodeQueued ||
+				t.Status == models.TranscodeRunning && t.UpdatedAt.Before(staleBefore)) {
+				return nil
+			}
+			if claimed == nil || t.UpdatedAt.Before(claimed.Transcode.UpdatedAt) {
+				claimed = &f
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if claimed == nil {
+			return ErrNotFound
+		}
+		claimed.Transcode = &models.Transcode{Status: models.TranscodeRunning, Worker: worker, UpdatedAt: at}
+		return boltPut(tx, "media_files", []byte(claimed.ID), claimed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// UpdateTranscode replaces the job of a record while it is running for worker.
+func (r *BoltMediaFileRepo) UpdateTranscode(ctx context.Context, id, worker string, t models.Transcode) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var file models.MediaFile
+		if err := boltGet(tx, "media_files", []byte(id), &file); err != nil {
+			return err
+		}
+		if file.Transcode == nil || file.Transcode.Status != models.TranscodeRunning || file.Transcode.Worker != worker {
+			return ErrNotFound
+		}
+		file.Transcode = &t
+		return boltPut(tx, "media_files", []byte(id), &file)
+	})
+}
+
+// filter returns the records keep accepts, in no particular order.
+func (r *BoltMediaFileRepo) filter(ctx context.Context, keep func(*models.MediaFile) bool) ([]*models.MediaFile, error) {
+	var files []*models.MediaFile
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("media_files")).ForEach(func(_, v []byte) error {
+			var f models.MediaFile
+			if err := json.Unmarshal(v, &f); err != nil {
+				return err
+			}
+			if keep(&f) {
+				files = append(files, &f)
+			}
+			return nil
+		})
+	})
+	return files, err
+}
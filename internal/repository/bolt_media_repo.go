@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltMediaSessionRepo implements MediaSessionRepository against a bbolt file.
+type BoltMediaSessionRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltMediaSessionRepo creates a new bbolt-backed media session repository.
+func NewBoltMediaSessionRepo(db *bolt.DB) *BoltMediaSessionRepo {
+	return &BoltMediaSessionRepo{db: db}
+}
+
+// Create stores a new media session.
+func (r *BoltMediaSessionRepo) Create(ctx context.Context, session *models.MediaSession) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "media_sessions", []byte(session.ID), session)
+	})
+}
+
+// End marks a media session as ended.
+func (r *BoltMediaSessionRepo) End(ctx context.Context, sessionID string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var s models.MediaSession
+		if err := boltGet(tx, "media_sessions", []byte(sessionID), &s); err != nil {
+			return err
+		}
+		if s.EndedAt != nil {
+			return ErrNotFound
+		}
+		now := time.Now()
+		s.EndedAt = &now
+		return boltPut(tx, "media_sessions", []byte(sessionID), &s)
+	})
+}
+
+// GetActive returns currently active sessions in a room, newest first.
+func (r *BoltMediaSessionRepo) GetActive(ctx context.Context, roomID string) ([]*models.MediaSession, error) {
+	return r.find(ctx, func(s *models.MediaSession) bool {
+		return s.RoomID == roomID && s.EndedAt == nil
+	}, -1, 0)
+}
+
+// GetByRoom returns past sessions for a room, newest first.
+func (r *BoltMediaSessionRepo) GetByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaSession, error) {
+	return r.find(ctx, func(s *models.MediaSession) bool { return s.RoomID == roomID }, limit, offset)
+}
+
+// AddParticipant records a user joining a media session.
+func (r *BoltMediaSessionRepo) AddParticipant(ctx context.Context, sessionID, userID string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		now := time.Now()
+		key := boltKey([]byte(sessionID), []byte(userID), boltTime(now))
+		return boltPut(tx, "media_session_participants", key, models.MediaSessionParticipant{
+			SessionID: sessionID, UserID: userID, JoinedAt: now,
+		})
+	})
+}
+
+// RemoveParticipant records a user leaving a media session.
+func (r *BoltMediaSessionRepo) RemoveParticipant(ctx context.Context, sessionID, userID string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		now := time.Now()
+		updated := map[string]models.MediaSessionParticipant{}
+		err := boltScan(tx, "media_session_participants", boltPrefix([]byte(sessionID), []byte(userID)), func(k, v []byte) error {
+			var p models.MediaSessionParticipant
+			if err := json.Unmarshal(v, &p); err != nil {
+				return err
+			}
+			if p.LeftAt == nil {
+				p.LeftAt = &now
+				updated[string(k)] = p
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(updated) == 0 {
+			return ErrNotFound
+		}
+
+		// Writes are deferred until after the scan; bbolt cursors don't
+		// tolerate modification mid-iteration.
+		for k, p := range updated {
+			if err := boltPut(tx, "media_session_participants", []byte(k), p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// find returns sessions matching keep, newest first. A negative limit returns all.
+func (r *BoltMediaSessionRepo) find(ctx context.Context, keep func(*models.MediaSession) bool, limit, offset int) ([]*models.MediaSession, error) {
+	var sessions []*models.MediaSession
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("media_sessions")).ForEach(func(_, v []byte) error {
+			var s models.MediaSession
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			if keep(&s) {
+				sessions = append(sessions, &s)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartedAt.After(sessions[j].StartedAt) })
+	if limit < 0 {
+		return sessions, nil
+	}
+	return paginate(sessions, limit, offset), nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltMessageRepo implements MessageRepository against a bbolt file.
+// Messages are keyed by room and creation time, so a room's history is a
+// single ordered range.
+type BoltMessageRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltMessageRepo creates a new bbolt-backed message repository.
+func NewBoltMessageRepo(db *bolt.DB) *BoltMessageRepo {
+	return &BoltMessageRepo{db: db}
+}
+
+// Create stores a new chat message.
+func (r *BoltMessageRepo) Create(ctx context.Context, msg *models.ChatMessage) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := boltKey([]byte(msg.RoomID), boltTime(msg.CreatedAt), []byte(msg.ID))
+		if err := boltPut(tx, "messages", key, msg); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("messages_by_id")).Put([]byte(msg.ID), key)
+	})
+}
+
+// GetByRoom returns messages for a room before a given timestamp, newest first.
+func (r *BoltMessageRepo) GetByRoom(ctx context.Context, roomID string, before time.Time, limit int) ([]*models.ChatMessage, error) {
+	var messages []*models.ChatMessage
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := boltPrefix([]byte(roomID))
+		c := tx.Bucket([]byte("messages")).Cursor()
+
+		// Seek to the first key at or after `before`, then walk backwards.
+		k, v := c.Seek(boltKey([]byte(roomID), boltTime(before)))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(messages) < limit; k, v = c.Prev() {
+			var msg models.ChatMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			messages = append(messages, &msg)
+		}
+		return nil
+	})
+	return messages, err
+}
+
+// GetByRoomAfter returns messages for a room after a given timestamp, oldest first.
+func (r *BoltMessageRepo) GetByRoomAfter(ctx context.Context, roomID string, after time.Time, limit int) ([]*models.ChatMessage, error) {
+	var messages []*models.ChatMessage
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := boltPrefix([]byte(roomID))
+		c := tx.Bucket([]byte("messages")).Cursor()
+
+		// Seek to the first key after `after`, then walk forwards.
+		start := boltKey([]byte(roomID), boltTime(after.Add(time.Nanosecond)))
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix) && len(messages) < limit; k, v = c.Next() {
+			var msg models.ChatMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			messages = append(messages, &msg)
+		}
+		return nil
+	})
+	return messages, err
+}
+
+// GetByID retrieves a single message by ID.
+func (r *BoltMessageRepo) GetByID(ctx context.Context, id string) (*models.ChatMessage, error) {
+	var msg models.ChatMessage
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		key := tx.Bucket([]byte("messages_by_id")).Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		return boltGet(tx, "messages", key, &msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Delete deletes a single message.
+func (r *BoltMessageRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		byID := tx.Bucket([]byte("messages_by_id"))
+		key := byID.Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		if err := tx.Bucket([]byte("messages")).Delete(key); err != nil {
+			return err
+		}
+		return byID.Delete([]byte(id))
+	})
+}
+
+// DeleteByRoom deletes all messages of a room.
+func (r *BoltMessageRepo) DeleteByRoom(ctx context.Context, roomID string) (int, error) {
+	return r.deleteRange(ctx, roomID, nil)
+}
+
+// DeleteBefore deletes a room's messages created before the given time.
+func (r *BoltMessageRepo) DeleteBefore(ctx context.Context, roomID string, before time.Time) (int, error) {
+	return r.deleteRange(ctx, roomID, boltKey([]byte(roomID), boltTime(before)))
+}
+
+// RenameSender rewrites the sender name on every message by senderID.
+// Messages are keyed by room, so this scans the whole bucket.
+func (r *BoltMessageRepo) RenameSender(ctx context.Context, senderID, name string) (int, error) {
+	var renamed int
+	err := boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		messages := tx.Bucket([]byte("messages"))
+
+		// Collect first: writing while iterating a bbolt cursor is unsafe.
+		var matched []*models.ChatMessage
+		var keys [][]byte
+		err := messages.ForEach(func(k, v []byte) error {
+			var msg models.ChatMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			if msg.SenderID == senderID && msg.Sender != name {
+				matched = append(matched, &msg)
+				keys = append(keys, bytes.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, msg := range matched {
+			msg.Sender = name
+			if err := boltPut(tx, "messages", keys[i], msg); err != nil {
+				return err
+			}
+		}
+		renamed = len(matched)
+		return nil
+	})
+	return renamed, err
+}
+
+// deleteRange deletes the room's messages keyed below end (all of them if
+// end is nil), along with their messages_by_id entries.
+func (r *BoltMessageRepo) deleteRange(ctx context.Context, roomID string, end []byte) (int, error) {
+	var deleted int
+	err := boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := boltPrefix([]byte(roomID))
+		messages := tx.Bucket([]byte("messages"))
+		byID := tx.Bucket([]byte("messages_by_id"))
+
+		// Collect first: deleting while iterating a bbolt cursor skips keys.
+		var keys [][]byte
+		c := messages.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if end != nil && bytes.Compare(k, end) >= 0 {
+				break
+			}
+			keys = append(keys, bytes.Clone(k))
+		}
+
+		for _, k := range keys {
+			// Key layout: room ID, 0, 8-byte created_at, 0, message ID.
+			id := k[len(prefix)+9:]
+			if err := byID.Delete(id); err != nil {
+				return err
+			}
+			if err := messages.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return nil
+	})
+	return deleted, err
+}
+
+// CountBySender returns the number of stored messages per sender ID.
+// It scans every message.
+func (r *BoltMessageRepo) CountBySender(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("messages")).ForEach(func(_, v []byte) error {
+			var msg struct {
+				SenderID string `json:"senderId"`
+			}
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			counts[msg.SenderID]++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltMetadataRepo implements MetadataRepository against a bbolt file.
+type BoltMetadataRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltMetadataRepo creates a new bbolt-backed metadata cache.
+func NewBoltMetadataRepo(db *bolt.DB) *BoltMetadataRepo {
+	return &BoltMetadataRepo{db: db}
+}
+
+// Get retrieves the lookup of key.
+func (r *BoltMetadataRepo) Get(ctx context.Context, key string) (*models.MetadataLookup, error) {
+	var lookup models.MetadataLookup
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "metadata_lookups", []byte(key), &lookup)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lookup, nil
+}
+
+// Put stores a lookup, replacing any earlier one of its key.
+func (r *BoltMetadataRepo) Put(ctx context.Context, lookup *models.MetadataLookup) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "metadata_lookups", []byte(lookup.Key), lookup)
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltReportRepo implements ReportRepository against a bbolt file. Reports
+// are keyed by creation time, so listing newest first is a reverse scan.
+type BoltReportRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltReportRepo creates a new bbolt-backed report repository.
+func NewBoltReportRepo(db *bolt.DB) *BoltReportRepo {
+	return &BoltReportRepo{db: db}
+}
+
+// Create stores a new report.
+func (r *BoltReportRepo) Create(ctx context.Context, report *models.Report) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := boltKey(boltTime(report.CreatedAt), []byte(report.ID))
+		if err := boltPut(tx, "reports", key, report); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("reports_by_id")).Put([]byte(report.ID), key)
+	})
+}
+
+// GetByID retrieves a report by ID.
+func (r *BoltReportRepo) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	var report models.Report
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		key := tx.Bucket([]byte("reports_by_id")).Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		return boltGet(tx, "reports", key, &report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List returns reports matching filter, newest first.
+func (r *BoltReportRepo) List(ctx context.Context, filter ReportFilter) ([]*models.Report, error) {
+	var reports []*models.Report
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		skip := filter.Offset
+		c := tx.Bucket([]byte("reports")).Cursor()
+		for k, v := c.Last(); k != nil && len(reports) < filter.Limit; k, v = c.Prev() {
+			var rep models.Report
+			if err := json.Unmarshal(v, &rep); err != nil {
+				return err
+			}
+			if !filter.matches(&rep) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			reports = append(reports, &rep)
+		}
+		return nil
+	})
+	return reports, err
+}
+
+// Resolve closes an open report.
+func (r *BoltReportRepo) Resolve(ctx context.Context, id, status string, res *models.ReportResolution) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := tx.Bucket([]byte("reports_by_id")).Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		var report models.Report
+		if err := boltGet(tx, "reports", key, &report); err != nil {
+			return err
+		}
+		if report.Status != models.ReportStatusOpen {
+			return ErrNotFound
+		}
+		report.Status, report.Resolution = status, res
+		return boltPut(tx, "reports", key, &report)
+	})
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltRoleRepo implements RoleRepository against a bbolt file.
+type BoltRoleRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltRoleRepo creates a new bbolt-backed role repository.
+func NewBoltRoleRepo(db *bolt.DB) *BoltRoleRepo {
+	return &BoltRoleRepo{db: db}
+}
+
+// Create stores a new role.
+func (r *BoltRoleRepo) Create(ctx context.Context, role *models.RoleDefinition) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("roles")).Get([]byte(role.Name)) != nil {
+			return ErrAlreadyExists
+		}
+		return boltPut(tx, "roles", []byte(role.Name), role)
+	})
+}
+
+// Update replaces a role's description and permissions.
+func (r *BoltRoleRepo) Update(ctx context.Context, role *models.RoleDefinition) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var stored models.RoleDefinition
+		if err := boltGet(tx, "roles", []byte(role.Name), &stored); err != nil {
+			return err
+		}
+		stored.Description = role.Description
+		stored.Permissions = role.Permissions
+		stored.UpdatedAt = role.UpdatedAt
+		return boltPut(tx, "roles", []byte(role.Name), &stored)
+	})
+}
+
+// Delete removes a role.
+func (r *BoltRoleRepo) Delete(ctx context.Context, name string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("roles"))
+		if b.Get([]byte(name)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+// Get retrieves a role by name.
+func (r *BoltRoleRepo) Get(ctx context.Context, name string) (*models.RoleDefinition, error) {
+	var role models.RoleDefinition
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "roles", []byte(name), &role)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// List returns every role, by name (the bucket's key order).
+func (r *BoltRoleRepo) List(ctx context.Context) ([]*models.RoleDefinition, error) {
+	var roles []*models.RoleDefinition
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("roles")).ForEach(func(_, v []byte) error {
+			var role models.RoleDefinition
+			if err := json.Unmarshal(v, &role); err != nil {
+				return err
+			}
+			roles = append(roles, &role)
+			return nil
+		})
+	})
+	return roles, err
+}
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltRoomRepo implements RoomRepository against a bbolt file.
+type BoltRoomRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltRoomRepo creates a new bbolt-backed room repository.
+func NewBoltRoomRepo(db *bolt.DB) *BoltRoomRepo {
+	return &BoltRoomRepo{db: db}
+}
+
+// Create stores a new room.
+func (r *BoltRoomRepo) Create(ctx context.Context, room *models.Room) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("rooms")).Get([]byte(room.ID)) != nil {
+			return ErrAlreadyExists
+		}
+		return boltPut(tx, "rooms", []byte(room.ID), room)
+	})
+}
+
+// GetByID retrieves a room by ID.
+func (r *BoltRoomRepo) GetByID(ctx context.Context, id string) (*models.Room, error) {
+	var room models.Room
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "rooms", []byte(id), &room)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// List returns active rooms the user is a member of, newest first.
+func (r *BoltRoomRepo) List(ctx context.Context, userID string, limit, offset int) ([]*models.Room, error) {
+	var rooms []*models.Room
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := boltPrefix([]byte(userID))
+		return boltScan(tx, "member_rooms", prefix, func(k, _ []byte) error {
+			var room models.Room
+			err := boltGet(tx, "rooms", bytes.TrimPrefix(k, prefix), &room)
+			if err == ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if room.IsActive {
+				rooms = append(rooms, &room)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortRooms(rooms, limit, offset), nil
+}
+
+// CountOwned returns how many active rooms userID owns.
+func (r *BoltRoomRepo) CountOwned(ctx context.Context, userID string) (int, error) {
+	n := 0
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := boltPrefix([]byte(userID))
+		return boltScan(tx, "member_rooms", prefix, func(k, _ []byte) error {
+			roomID := bytes.TrimPrefix(k, prefix)
+			var m models.RoomMember
+			err := boltGet(tx, "room_members", boltKey(roomID, []byte(userID)), &m)
+			if err == ErrNotFound || (err == nil && m.Role != models.RoomRoleOwner) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			var room models.Room
+			err = boltGet(tx, "rooms", roomID, &room)
+			if err == ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if room.IsActive {
+				n++
+			}
+			return nil
+		})
+	})
+	return n, err
+}
+
+// ListPublic returns all active public rooms, or those tagged tag,
+// newest first.
+func (r *BoltRoomRepo) ListPublic(ctx context.Context, tag string, limit, offset int) ([]*models.Room, error) {
+	rooms, err := r.public(ctx, func(room *models.Room) bool {
+		return tag == "" || slices.Contains(room.Tags, tag)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortRooms(rooms, limit, offset), nil
+}
+
+// TagCounts returns the tags of active public rooms created at or after
+// since, with how many of them carry each, most used first.
+func (r *BoltRoomRepo) TagCounts(ctx context.Context, since time.Time, limit int) ([]models.TagCount, error) {
+	rooms, err := r.public(ctx, func(room *models.Room) bool { return !room.CreatedAt.Before(since) })
+	if err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[string]int)
+	for _, room := range rooms {
+		for _, tag := range room.Tags {
+			byTag[tag]++
+		}
+	}
+	counts := make([]models.TagCount, 0, len(byTag))
+	for tag, n := range byTag {
+		counts = append(counts, models.TagCount{Tag: tag, Rooms: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Rooms != counts[j].Rooms {
+			return counts[i].Rooms > counts[j].Rooms
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts, nil
+}
+
+// public returns the active public rooms keep accepts.
+func (r *BoltRoomRepo) public(ctx context.Context, keep func(*models.Room) bool) ([]*models.Room, error) {
+	var rooms []*models.Room
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("rooms")).ForEach(func(_, v []byte) error {
+			var room models.Room
+			if err := json.Unmarshal(v, &room); err != nil {
+				return err
+			}
+			if room.Type == models.RoomTypePublic && room.IsActive && keep(&room) {
+				rooms = append(rooms, &room)
+			}
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+// ListAll returns every room, including inactive ones, oldest first.
+func (r *BoltRoomRepo) ListAll(ctx context.Context, limit, offset int) ([]*models.Room, error) {
+	var rooms []*models.Room
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("rooms")).ForEach(func(_, v []byte) error {
+			var room models.Room
+			if err := json.Unmarshal(v, &room); err != nil {
+				return err
+			}
+			rooms = append(rooms, &room)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		if !rooms[i].CreatedAt.Equal(rooms[j].CreatedAt) {
+			return rooms[i].CreatedAt.Before(rooms[j].CreatedAt)
+		}
+		return rooms[i].ID < rooms[j].ID
+	})
+	return paginate(rooms, limit, offset), nil
+}
+
+// Update updates a room's mutable fields.
+func (r *BoltRoomRepo) Update(ctx context.Context, room *models.Room) error {
+	return r.update(ctx, room.ID, func(stored *models.Room) {
+		stored.Name = room.Name
+		stored.Description = room.Description
+		stored.MaxMembers = room.MaxMembers
+		stored.Tags = room.Tags
+	})
+}
+
+// Delete soft-deletes a room.
+func (r *BoltRoomRepo) Delete(ctx context.Context, id string) error {
+	return r.update(ctx, id, func(room *models.Room) { room.IsActive = false })
+}
+
+// AddMember adds a user to a room. Adding an existing member is a no-op.
+func (r *BoltRoomRepo) AddMember(ctx context.Context, roomID, userID, role string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := boltKey([]byte(roomID), []byte(userID))
+		if tx.Bucket([]byte("room_members")).Get(key) != nil {
+			return nil
+		}
+		member := models.RoomMember{RoomID: roomID, UserID: userID, Role: role, JoinedAt: time.Now()}
+		if err := boltPut(tx, "room_members", key, member); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("member_rooms")).Put(boltKey([]byte(userID), []byte(roomID)), nil)
+	})
+}
+
+// RemoveMember removes a user from a room.
+func (r *BoltRoomRepo) RemoveMember(ctx context.Context, roomID, userID string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		if err := tx.Bucket([]byte("room_members")).Delete(boltKey([]byte(roomID), []byte(userID))); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("member_rooms")).Delete(boltKey([]byte(userID), []byte(roomID)))
+	})
+}
+
+// GetMembers returns all members of a room with their usernames, earliest joined first.
+func (r *BoltRoomRepo) GetMembers(ctx context.Context, roomID string) ([]*models.RoomMember, error) {
+	var members []*models.RoomMember
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltScan(tx, "room_members", boltPrefix([]byte(roomID)), func(_, v []byte) error {
+			var m models.RoomMember
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+			user, err := getBoltUser(tx, m.UserID)
+			if err == ErrNotFound {
+				return nil // matches the inner join on users
+			}
+			if err != nil {
+				return err
+			}
+			m.Username = user.Username
+			members = append(members, &m)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(members, func(i, j int) bool { return members[i].JoinedAt.Before(members[j].JoinedAt) })
+	return members, nil
+}
+
+// GetMemberRole returns the role of a user in a room.
+func (r *BoltRoomRepo) GetMemberRole(ctx context.Context, roomID, userID string) (string, error) {
+	var m models.RoomMember
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "room_members", boltKey([]byte(roomID), []byte(userID)), &m)
+	})
+	if err != nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+// SetMemberRole changes the role of a room member.
+func (r *BoltRoomRepo) SetMemberRole(ctx context.Context, roomID, userID, role string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		key := boltKey([]byte(roomID), []byte(userID))
+		var m models.RoomMember
+		if err := boltGet(tx, "room_members", key, &m); err != nil {
+			return err
+		}
+		m.Role = role
+		return boltPut(tx, "room_members", key, m)
+	})
+}
+
+// UpdateVideoState updates the video sync state for a room.
+func (r *BoltRoomRepo) UpdateVideoState(ctx context.Context, roomID string, state models.VideoState) error {
+	return r.update(ctx, roomID, func(room *models.Room) { room.VideoState = state })
+}
+
+// UpdateRetention sets a room's message retention policy (nil = server default).
+func (r *BoltRoomRepo) UpdateRetention(ctx context.Context, roomID string, policy *models.RetentionPolicy) error {
+	return r.update(ctx, roomID, func(room *models.Room) { room.Retention = policy })
+}
+
+// UpdateSyncPolicy sets a room's sync tolerance (nil = server default).
+func (r *BoltRoomRepo) UpdateSyncPolicy(ctx context.Context, roomID string, policy *models.SyncPolicy) error {
+	return r.update(ctx, roomID, func(room *models.Room) { room.Sync = policy })
+}
+
+// UpdateScreens replaces a room's screens besides the main one (none if
+// empty).
+func (r *BoltRoomRepo) UpdateScreens(ctx context.Context, roomID string, screens []models.Screen) error {
+	return r.update(ctx, roomID, func(room *models.Room) { room.Screens = screens })
+}
+
+// update applies fn to a stored room and bumps UpdatedAt.
+func (r *BoltRoomRepo) update(ctx context.Context, id string, fn func(*models.Room)) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var room models.Room
+		if err := boltGet(tx, "rooms", []byte(id), &room); err != nil {
+			return err
+		}
+		fn(&room)
+		room.UpdatedAt = time.Now()
+		return boltPut(tx, "rooms", []byte(id), &room)
+	})
+}
+
+// sortRooms orders rooms newest first and paginates them.
+func sortRooms(rooms []*models.Room, limit, offset int) []*models.Room {
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].CreatedAt.After(rooms[j].CreatedAt) })
+	return paginate(rooms, limit, offset)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltSharedFileRepo implements SharedFileRepository against a bbolt file.
+type BoltSharedFileRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltSharedFileRepo creates a new bbolt-backed shared file repository.
+func NewBoltSharedFileRepo(db *bolt.DB) *BoltSharedFileRepo {
+	return &BoltSharedFileRepo{db: db}
+}
+
+// boltSharedFile is the stored form of models.SharedFile. The storage path
+// is kept under its own key because models.SharedFile never serializes it.
+type boltSharedFile struct {
+	*models.SharedFile
+	StoragePath string `json:"storagePath"`
+}
+
+// Create stores file metadata.
+func (r *BoltSharedFileRepo) Create(ctx context.Context, file *models.SharedFile) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "shared_files", []byte(file.ID), boltSharedFile{SharedFile: file, StoragePath: file.StoragePath})
+	})
+}
+
+// GetByRoom returns files shared in a room, newest first.
+func (r *BoltSharedFileRepo) GetByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.SharedFile, error) {
+	var files []*models.SharedFile
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("shared_files")).ForEach(func(_, v []byte) error {
+			f, err := decodeBoltSharedFile(v)
+			if err != nil {
+				return err
+			}
+			if f.RoomID == roomID {
+				files = append(files, f)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return paginate(files, limit, offset), nil
+}
+
+// GetByID retrieves a file by ID.
+func (r *BoltSharedFileRepo) GetByID(ctx context.Context, id string) (*models.SharedFile, error) {
+	var file *models.SharedFile
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte("shared_files")).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		var err error
+		file, err = decodeBoltSharedFile(data)
+		return err
+	})
+	return file, err
+}
+
+// Delete removes a shared file record.
+func (r *BoltSharedFileRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("shared_files"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// decodeBoltSharedFile decodes a stored file, restoring the storage path.
+func decodeBoltSharedFile(data []byte) (*models.SharedFile, error) {
+	doc := boltSharedFile{SharedFile: &models.SharedFile{}}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc.SharedFile.StoragePath = doc.StoragePath
+	return doc.SharedFile, nil
+}
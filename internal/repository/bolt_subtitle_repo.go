@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltSubtitleRepo implements SubtitleRepository against a bbolt file.
+type BoltSubtitleRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltSubtitleRepo creates a new bbolt-backed subtitle repository.
+func NewBoltSubtitleRepo(db *bolt.DB) *BoltSubtitleRepo {
+	return &BoltSubtitleRepo{db: db}
+}
+
+// Create stores a track.
+func (r *BoltSubtitleRepo) Create(ctx context.Context, sub *models.Subtitle) error {
+	stored := *sub
+	stored.URL = "" // derived from the IDs
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "subtitles", []byte(sub.ID), &stored)
+	})
+}
+
+// GetByID retrieves a track by ID.
+func (r *BoltSubtitleRepo) GetByID(ctx context.Context, id string) (*models.Subtitle, error) {
+	var sub models.Subtitle
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "subtitles", []byte(id), &sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListByMedia returns the tracks of a media file, oldest first.
+func (r *BoltSubtitleRepo) ListByMedia(ctx context.Context, mediaID string) ([]*models.Subtitle, error) {
+	subs, err := r.filter(ctx, func(s *models.Subtitle) bool { return s.MediaID == mediaID })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	return subs, nil
+}
+
+// ListByRoom returns the tracks of a room's media, newest first.
+func (r *BoltSubtitleRepo) ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.Subtitle, error) {
+	subs, err := r.filter(ctx, func(s *models.Subtitle) bool { return s.RoomID == roomID })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.After(subs[j].CreatedAt) })
+	return paginate(subs, limit, offset), nil
+}
+
+// Delete removes a track.
+func (r *BoltSubtitleRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("subtitles"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// DeleteByMedia removes the tracks of a media file.
+func (r *BoltSubtitleRepo) DeleteByMedia(ctx context.Context, mediaID string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("subtitles"))
+
+		// Collect first: deleting while iterating a bbolt cursor skips keys.
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var s models.Subtitle
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			if s.MediaID == mediaID {
+				keys = append(keys, bytes.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// filter returns the tracks keep accepts, in no particular order.
+func (r *BoltSubtitleRepo) filter(ctx context.Context, keep func(*models.Subtitle) bool) ([]*models.Subtitle, error) {
+	var subs []*models.Subtitle
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("subtitles")).ForEach(func(_, v []byte) error {
+			var s models.Subtitle
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			if keep(&s) {
+				subs = append(subs, &s)
+			}
+			return nil
+		})
+	})
+	return subs, err
+}
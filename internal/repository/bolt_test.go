@@ -0,0 +1,42 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ofenes/internal/database"
+	"ofenes/internal/repository"
+	"ofenes/internal/repository/repotest"
+)
+
+func TestBoltRepos(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repos {
+		db, err := database.OpenBolt(filepath.Join(t.TempDir(), "ofenes.db"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := database.MigrateBolt(db); err != nil {
+			t.Fatal(err)
+		}
+		return repository.Repos{
+			Users:          repository.NewBoltUserRepo(db),
+			Rooms:          repository.NewBoltRoomRepo(db),
+			Messages:       repository.NewBoltMessageRepo(db),
+			Audit:          repository.NewBoltAuditRepo(db),
+			DeadLetters:    repository.NewBoltDeadLetterRepo(db),
+			Reports:        repository.NewBoltReportRepo(db),
+			WordFilters:    repository.NewBoltWordFilterRepo(db),
+			AllowedOrigins: repository.NewBoltAllowedOriginRepo(db),
+			Announcements:  repository.NewBoltAnnouncementRepo(db),
+			Instance:       repository.NewBoltInstanceRepo(db),
+			Invites:        repository.NewBoltInviteRepo(db),
+			Legal:          repository.NewBoltLegalRepo(db),
+			Roles:          repository.NewBoltRoleRepo(db),
+			MediaFiles:     repository.NewBoltMediaFileRepo(db),
+			Subtitles:      repository.NewBoltSubtitleRepo(db),
+			Library:        repository.NewBoltLibraryRepo(db),
+			Metadata:       repository.NewBoltMetadataRepo(db),
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/username"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltUserRepo implements UserRepository against a bbolt file.
+type BoltUserRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltUserRepo creates a new bbolt-backed user repository.
+func NewBoltUserRepo(db *bolt.DB) *BoltUserRepo {
+	return &BoltUserRepo{db: db}
+}
+
+// boltUser is the stored form of models.User. The password hash is kept
+// under its own key because models.User never serializes it.
+type boltUser struct {
+	*models.User
+	PasswordHash string `json:"passwordHash"`
+}
+
+// Create stores a new user. Returns ErrAlreadyExists if the username is taken.
+func (r *BoltUserRepo) Create(ctx context.Context, user *models.User) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		byName := tx.Bucket([]byte("users_by_username"))
+		key := []byte(username.Key(user.Username))
+		if byName.Get(key) != nil {
+			return ErrAlreadyExists
+		}
+		if err := byName.Put(key, []byte(user.ID)); err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte("users_by_skeleton")).Put(boltSkeletonKey(user.Username, user.ID), []byte(user.ID)); err != nil {
+			return err
+		}
+		return boltPut(tx, "users", []byte(user.ID), boltUser{User: user, PasswordHash: user.PasswordHash})
+	})
+}
+
+// GetByID retrieves a user by ID. Returns ErrNotFound if missing or soft-deleted.
+func (r *BoltUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	var user *models.User
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		var err error
+		user, err = getActiveBoltUser(tx, id)
+		return err
+	})
+	return user, err
+}
+
+// GetByUsername retrieves a user by username, ignoring case. Returns ErrNotFound if missing or soft-deleted.
+func (r *BoltUserRepo) GetByUsername(ctx context.Context, name string) (*models.User, error) {
+	var user *models.User
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		id := tx.Bucket([]byte("users_by_username")).Get([]byte(username.Key(name)))
+		if id == nil {
+			return ErrNotFound
+		}
+		var err error
+		user, err = getActiveBoltUser(tx, string(id))
+		return err
+	})
+	return user, err
+}
+
+// GetBySkeleton retrieves a user whose username has the given skeleton. Returns ErrNotFound if none is active.
+func (r *BoltUserRepo) GetBySkeleton(ctx context.Context, skeleton string) (*models.User, error) {
+	var user *models.User
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := boltPrefix([]byte(skeleton))
+		c := tx.Bucket([]byte("users_by_skeleton")).Cursor()
+		for k, id := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, id = c.Next() {
+			u, err := getActiveBoltUser(tx, string(id))
+			if errors.Is(err, ErrNotFound) {
+				continue // soft-deleted
+			}
+			user = u
+			return err
+		}
+		return ErrNotFound
+	})
+	return user, err
+}
+
+// boltSkeletonKey is the users_by_skeleton key of the user id named name.
+func boltSkeletonKey(name, id string) []byte {
+	return boltKey([]byte(username.Skeleton(name)), []byte(id))
+}
+
+// Update updates a user's profile fields.
+func (r *BoltUserRepo) Update(ctx context.Context, user *models.User) error {
+	return r.update(ctx, user.ID, func(u *models.User) {
+		u.DisplayName = user.DisplayName
+		u.AvatarURL = user.AvatarURL
+		u.Bio = user.Bio
+	})
+}
+
+// UpdateStatus sets the user's online status.
+func (r *BoltUserRepo) UpdateStatus(ctx context.Context, userID string, status string) error {
+	return r.update(ctx, userID, func(u *models.User) { u.Status = status })
+}
+
+// UpdatePreferences replaces the user's preferences JSON.
+func (r *BoltUserRepo) UpdatePreferences(ctx context.Context, userID string, prefs json.RawMessage) error {
+	return r.update(ctx, userID, func(u *models.User) { u.Preferences = prefs })
+}
+
+// SetShadowBanned sets or clears a user's shadow ban.
+func (r *BoltUserRepo) SetShadowBanned(ctx context.Context, id string, banned bool) error {
+	return r.update(ctx, id, func(u *models.User) { setShadowBanned(u, banned, time.Now()) })
+}
+
+// SetTrustLevel sets a user's trust level.
+func (r *BoltUserRepo) SetTrustLevel(ctx context.Context, id, level string) error {
+	return r.update(ctx, id, func(u *models.User) { u.TrustLevel = level })
+}
+
+// SetRole sets a user's role.
+func (r *BoltUserRepo) SetRole(ctx context.Context, id, role string) error {
+	return r.update(ctx, id, func(u *models.User) { u.Role = role })
+}
+
+// List returns the users matching filter. A username prefix is resolved
+// through the users_by_username index instead of a full scan.
+func (r *BoltUserRepo) List(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	users, err := r.match(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return filter.less(users[i], users[j]) })
+	return paginate(users, filter.Limit, filter.Offset), nil
+}
+
+// CountByRole returns the number of users matching filter per role.
+func (r *BoltUserRepo) CountByRole(ctx context.Context, filter UserFilter) (map[string]int, error) {
+	filter.Role = ""
+	users, err := r.match(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, u := range users {
+		counts[u.Role]++
+	}
+	return counts, nil
+}
+
+// match returns all users passing filter, unordered.
+func (r *BoltUserRepo) match(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	if filter.UsernamePrefix == "" {
+		return r.find(ctx, filter.matches)
+	}
+
+	var users []*models.User
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltScan(tx, "users_by_username", []byte(filter.UsernamePrefix), func(_, id []byte) error {
+			u, err := getBoltUser(tx, string(id))
+			if err != nil {
+				return err
+			}
+			if filter.matches(u) {
+				users = append(users, u)
+			}
+			return nil
+		})
+	})
+	return users, err
+}
+
+// Delete soft-deletes a user.
+func (r *BoltUserRepo) Delete(ctx context.Context, id string) error {
+	return r.update(ctx, id, func(u *models.User) {
+		now := time.Now()
+		u.DeletedAt = &now
+		u.Status = models.StatusOffline
+	})
+}
+
+// Restore clears a user's soft-delete mark.
+func (r *BoltUserRepo) Restore(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		u, err := getBoltUser(tx, id)
+		if err != nil {
+			return err
+		}
+		if u.DeletedAt == nil || u.AnonymizedAt != nil {
+			return ErrNotFound
+		}
+		u.DeletedAt = nil
+		return boltPut(tx, "users", []byte(id), boltUser{User: u, PasswordHash: u.PasswordHash})
+	})
+}
+
+// Anonymize replaces a user's personal data with pseudonym and soft-deletes them.
+func (r *BoltUserRepo) Anonymize(ctx context.Context, id, pseudonym string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		u, err := getBoltUser(tx, id)
+		if err != nil {
+			return err
+		}
+		if u.AnonymizedAt != nil {
+			return ErrNotFound
+		}
+		byName := tx.Bucket([]byte("users_by_username"))
+		if byName.Get([]byte(username.Key(pseudonym))) != nil {
+			return ErrAlreadyExists
+		}
+		if err := byName.Delete([]byte(username.Key(u.Username))); err != nil {
+			return err
+		}
+		if err := byName.Put([]byte(username.Key(pseudonym)), []byte(id)); err != nil {
+			return err
+		}
+		bySkel := tx.Bucket([]byte("users_by_skeleton"))
+		if err := bySkel.Delete(boltSkeletonKey(u.Username, id)); err != nil {
+			return err
+		}
+		if err := bySkel.Put(boltSkeletonKey(pseudonym, id), []byte(id)); err != nil {
+			return err
+		}
+		anonymize(u, pseudonym, time.Now())
+		return boltPut(tx, "users", []byte(id), boltUser{User: u, PasswordHash: u.PasswordHash})
+	})
+}
+
+// GetByIDWithDeleted retrieves a user by ID whether or not it is soft-deleted.
+func (r *BoltUserRepo) GetByIDWithDeleted(ctx context.Context, id string) (*models.User, error) {
+	var user *models.User
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		var err error
+		user, err = getBoltUser(tx, id)
+		return err
+	})
+	return user, err
+}
+
+// ListDeleted returns soft-deleted users, most recently deleted first.
+func (r *BoltUserRepo) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	users, err := r.find(ctx, func(u *models.User) bool { return u.DeletedAt != nil })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].DeletedAt.After(*users[j].DeletedAt) })
+	return paginate(users, limit, offset), nil
+}
+
+// find returns all users matching keep, unordered.
+func (r *BoltUserRepo) find(ctx context.Context, keep func(*models.User) bool) ([]*models.User, error) {
+	var users []*models.User
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("users")).ForEach(func(_, v []byte) error {
+			u, err := decodeBoltUser(v)
+			if err != nil {
+				return err
+			}
+			if keep(u) {
+				users = append(users, u)
+			}
+			return nil
+		})
+	})
+	return users, err
+}
+
+// update applies fn to a stored, not soft-deleted user and bumps UpdatedAt.
+func (r *BoltUserRepo) update(ctx context.Context, id string, fn func(*models.User)) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		u, err := getActiveBoltUser(tx, id)
+		if err != nil {
+			return err
+		}
+		fn(u)
+		u.UpdatedAt = time.Now()
+		return boltPut(tx, "users", []byte(id), boltUser{User: u, PasswordHash: u.PasswordHash})
+	})
+}
+
+// getBoltUser loads a user by ID. Returns ErrNotFound if missing.
+func getBoltUser(tx *bolt.Tx, id string) (*models.User, error) {
+	data := tx.Bucket([]byte("users")).Get([]byte(id))
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	return decodeBoltUser(data)
+}
+
+// getActiveBoltUser loads a user by ID. Returns ErrNotFound if missing or soft-deleted.
+func getActiveBoltUser(tx *bolt.Tx, id string) (*models.User, error) {
+	u, err := getBoltUser(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// decodeBoltUser decodes a stored user, restoring the password hash.
+func decodeBoltUser(data []byte) (*models.User, error) {
+	doc := boltUser{User: &models.User{}}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc.User.PasswordHash = doc.PasswordHash
+	return doc.User, nil
+}
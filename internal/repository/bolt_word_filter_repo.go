@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"ofenes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltWordFilterRepo implements WordFilterRepository against a bbolt file.
+type BoltWordFilterRepo struct {
+	db *bolt.DB
+}
+
+// NewBoltWordFilterRepo creates a new bbolt-backed word filter repository.
+func NewBoltWordFilterRepo(db *bolt.DB) *BoltWordFilterRepo {
+	return &BoltWordFilterRepo{db: db}
+}
+
+// Create stores a new filter.
+func (r *BoltWordFilterRepo) Create(ctx context.Context, filter *models.WordFilter) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltPut(tx, "word_filters", []byte(filter.ID), filter)
+	})
+}
+
+// Update replaces a filter's pattern, regex flag, action and room.
+func (r *BoltWordFilterRepo) Update(ctx context.Context, filter *models.WordFilter) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		var stored models.WordFilter
+		if err := boltGet(tx, "word_filters", []byte(filter.ID), &stored); err != nil {
+			return err
+		}
+		stored.Pattern, stored.Regex, stored.Action, stored.RoomID = filter.Pattern, filter.Regex, filter.Action, filter.RoomID
+		stored.UpdatedAt = filter.UpdatedAt
+		return boltPut(tx, "word_filters", []byte(filter.ID), &stored)
+	})
+}
+
+// Delete removes a filter.
+func (r *BoltWordFilterRepo) Delete(ctx context.Context, id string) error {
+	return boltUpdate(ctx, r.db, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("word_filters"))
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// GetByID retrieves a filter by ID.
+func (r *BoltWordFilterRepo) GetByID(ctx context.Context, id string) (*models.WordFilter, error) {
+	var filter models.WordFilter
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return boltGet(tx, "word_filters", []byte(id), &filter)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// List returns every filter, oldest first.
+func (r *BoltWordFilterRepo) List(ctx context.Context) ([]*models.WordFilter, error) {
+	var filters []*models.WordFilter
+	err := boltView(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("word_filters")).ForEach(func(_, v []byte) error {
+			var f models.WordFilter
+			if err := json.Unmarshal(v, &f); err != nil {
+				return err
+			}
+			filters = append(filters, &f)
+			return nil
+		})
+	})
+	sort.Slice(filters, func(i, j int) bool {
+		if !filters[i].CreatedAt.Equal(filters[j].CreatedAt) {
+			return filters[i].CreatedAt.Before(filters[j].CreatedAt)
+		}
+		return filters[i].ID < filters[j].ID
+	})
+	return filters, err
+}
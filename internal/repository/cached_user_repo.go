@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/username"
+)
+
+// CachedUserRepo decorates a UserRepository with a read-through LRU cache
+// for GetByID and GetByUsername. The auth-protected endpoints look the
+// current user up on every request; with a SQL backend that is a round trip
+// per request without the cache.
+//
+// Writes made through the decorator invalidate the affected entry. Writes
+// made elsewhere (another instance, manual SQL) are picked up once the
+// entry's TTL expires, so keep the TTL short when running several instances.
+//
+// Callers get their own copy of each user and may modify it freely.
+type CachedUserRepo struct {
+	next UserRepository
+	size int
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	lru    *list.List               // front = most recently used
+	byID   map[string]*list.Element // user ID -> element holding *cachedUser
+	byName map[string]string        // username.Key(username) -> user ID
+	gen    uint64                   // bumped on every invalidation
+}
+
+// cachedUser is one cache entry.
+type cachedUser struct {
+	user    models.User
+	expires time.Time
+}
+
+// NewCachedUserRepo wraps next with a cache of up to size users, each kept
+// for at most ttl. A size of 0 or less returns next unwrapped.
+func NewCachedUserRepo(next UserRepository, size int, ttl time.Duration) UserRepository {
+	if size <= 0 {
+		return next
+	}
+	return &CachedUserRepo{
+		next:   next,
+		size:   size,
+		ttl:    ttl,
+		lru:    list.New(),
+		byID:   make(map[string]*list.Element),
+		byName: make(map[string]string),
+	}
+}
+
+// Create stores a new user. The user is not cached until it is first read.
+func (r *CachedUserRepo) Create(ctx context.Context, user *models.User) error {
+	return r.next.Create(ctx, user)
+}
+
+// GetByID retrieves a user by ID, from the cache when possible.
+func (r *CachedUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.mu.Lock()
+	user, ok := r.lookup(id)
+	gen := r.gen
+	r.mu.Unlock()
+	if ok {
+		return user, nil
+	}
+
+	user, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.store(user, gen)
+	return copyUser(user), nil
+}
+
+// GetByUsername retrieves a user by username, from the cache when possible.
+func (r *CachedUserRepo) GetByUsername(ctx context.Context, name string) (*models.User, error) {
+	r.mu.Lock()
+	var user *models.User
+	ok := false
+	if id, found := r.byName[username.Key(name)]; found {
+		user, ok = r.lookup(id)
+	}
+	gen := r.gen
+	r.mu.Unlock()
+	if ok {
+		return user, nil
+	}
+
+	user, err := r.next.GetByUsername(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r.store(user, gen)
+	return copyUser(user), nil
+}
+
+// GetBySkeleton is not cached.
+func (r *CachedUserRepo) GetBySkeleton(ctx context.Context, skeleton string) (*models.User, error) {
+	return r.next.GetBySkeleton(ctx, skeleton)
+}
+
+// Update updates a user's profile fields and invalidates the cached copy.
+func (r *CachedUserRepo) Update(ctx context.Context, user *models.User) error {
+	defer r.invalidate(user.ID)
+	return r.next.Update(ctx, user)
+}
+
+// UpdateStatus sets the user's online status and invalidates the cached copy.
+func (r *CachedUserRepo) UpdateStatus(ctx context.Context, userID string, status string) error {
+	defer r.invalidate(userID)
+	return r.next.UpdateStatus(ctx, userID, status)
+}
+
+// UpdatePreferences replaces the user's preferences and invalidates the cached copy.
+func (r *CachedUserRepo) UpdatePreferences(ctx context.Context, userID string, prefs json.RawMessage) error {
+	defer r.invalidate(userID)
+	return r.next.UpdatePreferences(ctx, userID, prefs)
+}
+
+// SetShadowBanned sets or clears a user's shadow ban and invalidates the cached copy.
+func (r *CachedUserRepo) SetShadowBanned(ctx context.Context, id string, banned bool) error {
+	defer r.invalidate(id)
+	return r.next.SetShadowBanned(ctx, id, banned)
+}
+
+// SetTrustLevel sets a user's trust level and invalidates the cached copy.
+func (r *CachedUserRepo) SetTrustLevel(ctx context.Context, id, level string) error {
+	defer r.invalidate(id)
+	return r.next.SetTrustLevel(ctx, id, level)
+}
+
+// SetRole sets a user's role and invalidates the cached copy.
+func (r *CachedUserRepo) SetRole(ctx context.Context, id, role string) error {
+	defer r.invalidate(id)
+	return r.next.SetRole(ctx, id, role)
+}
+
+// List is not cached.
+func (r *CachedUserRepo) List(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	return r.next.List(ctx, filter)
+}
+
+// CountByRole is not cached.
+func (r *CachedUserRepo) CountByRole(ctx context.Context, filter UserFilter) (map[string]int, error) {
+	return r.next.CountByRole(ctx, filter)
+}
+
+// Delete soft-deletes a user and invalidates the cached copy.
+func (r *CachedUserRepo) Delete(ctx context.Context, id string) error {
+	defer r.invalidate(id)
+	return r.next.Delete(ctx, id)
+}
+
+// Restore restores a soft-deleted user. Deleted users are never cached,
+// so there is nothing to invalidate.
+func (r *CachedUserRepo) Restore(ctx context.Context, id string) error {
+	return r.next.Restore(ctx, id)
+}
+
+// Anonymize erases a user's personal data and invalidates the cached copy.
+func (r *CachedUserRepo) Anonymize(ctx context.Context, id, pseudonym string) error {
+	defer r.invalidate(id)
+	return r.next.Anonymize(ctx, id, pseudonym)
+}
+
+// GetByIDWithDeleted is not cached.
+func (r *CachedUserRepo) GetByIDWithDeleted(ctx context.Context, id string) (*models.User, error) {
+	return r.next.GetByIDWithDeleted(ctx, id)
+}
+
+// ListDeleted is not cached.
+func (r *CachedUserRepo) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	return r.next.ListDeleted(ctx, limit, offset)
+}
+
+// lookup returns a copy of the cached user if present and fresh.
+// Must be called with r.mu held.
+func (r *CachedUserRepo) lookup(id string) (*models.User, bool) {
+	el, ok := r.byID[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cachedUser)
+	if r.ttl > 0 && time.Now().After(entry.expires) {
+		r.remove(el)
+		return nil, false
+	}
+	r.lru.MoveToFront(el)
+	return copyUser(&entry.user), true
+}
+
+// store caches user unless an invalidation happened since gen was read,
+// in which case user may already be stale.
+func (r *CachedUserRepo) store(user *models.User, gen uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if gen != r.gen {
+		return
+	}
+	if el, ok := r.byID[user.ID]; ok {
+		r.remove(el)
+	}
+	entry := &cachedUser{user: *user, expires: time.Now().Add(r.ttl)}
+	r.byID[user.ID] = r.lru.PushFront(entry)
+	r.byName[username.Key(user.Username)] = user.ID
+
+	for r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+	}
+}
+
+// invalidate drops the cached copy of a user.
+func (r *CachedUserRepo) invalidate(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gen++
+	if el, ok := r.byID[id]; ok {
+		r.remove(el)
+	}
+}
+
+// remove deletes an element and its indexes. Must be called with r.mu held.
+func (r *CachedUserRepo) remove(el *list.Element) {
+	entry := r.lru.Remove(el).(*cachedUser)
+	delete(r.byID, entry.user.ID)
+	if key := username.Key(entry.user.Username); r.byName[key] == entry.user.ID {
+		delete(r.byName, key)
+	}
+}
+
+// copyUser returns a shallow copy of u, so callers can't modify cached entries.
+func copyUser(u *models.User) *models.User {
+	c := *u
+	return &c
+}
+
+// deferInvalidation wraps a transaction-bound user repository so that users
+// written through it are evicted only when flush is called, after the
+// transaction ends. Evicting earlier would let a concurrent read cache the
+// pre-commit row again.
+func (r *CachedUserRepo) deferInvalidation(next UserRepository) (UserRepository, func()) {
+	tx := &txUserRepo{UserRepository: next}
+	return tx, func() {
+		for _, id := range tx.written {
+			r.invalidate(id)
+		}
+	}
+}
+
+// txUserRepo records the IDs of users written through it.
+type txUserRepo struct {
+	UserRepository
+	written []string
+}
+
+func (r *txUserRepo) Update(ctx context.Context, user *models.User) error {
+	r.written = append(r.written, user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *txUserRepo) UpdateStatus(ctx context.Context, userID string, status string) error {
+	r.written = append(r.written, userID)
+	return r.UserRepository.UpdateStatus(ctx, userID, status)
+}
+
+func (r *txUserRepo) UpdatePreferences(ctx context.Context, userID string, prefs json.RawMessage) error {
+	r.written = append(r.written, userID)
+	return r.UserRepository.UpdatePreferences(ctx, userID, prefs)
+}
+
+func (r *txUserRepo) SetShadowBanned(ctx context.Context, id string, banned bool) error {
+	r.written = append(r.written, id)
+	return r.UserRepository.SetShadowBanned(ctx, id, banned)
+}
+
+func (r *txUserRepo) SetTrustLevel(ctx context.Context, id, level string) error {
+	r.written = append(r.written, id)
+	return r.UserRepository.SetTrustLevel(ctx, id, level)
+}
+
+func (r *txUserRepo) SetRole(ctx context.Context, id, role string) error {
+	r.written = append(r.written, id)
+	return r.UserRepository.SetRole(ctx, id, role)
+}
+
+func (r *txUserRepo) Delete(ctx context.Context, id string) error {
+	r.written = append(r.written, id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r *txUserRepo) Anonymize(ctx context.Context, id, pseudonym string) error {
+	r.written = append(r.written, id)
+	return r.UserRepository.Anonymize(ctx, id, pseudonym)
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// DeadLetterRepository stores the WebSocket messages the Hub could not
+// route (WS_DEAD_LETTER_PERSIST), append-only like the audit log.
+type DeadLetterRepository interface {
+	// Create appends a dead letter.
+	Create(ctx context.Context, letter *models.DeadLetter) error
+
+	// List returns dead letters matching filter, newest first.
+	List(ctx context.Context, filter DeadLetterFilter) ([]*models.DeadLetter, error)
+
+	// CountBefore returns how many dead letters were created before the given time.
+	CountBefore(ctx context.Context, before time.Time) (int, error)
+
+	// DeleteBefore deletes dead letters created before the given time and
+	// returns how many were deleted.
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// DeadLetterFilter selects dead letters. Zero fields match everything.
+type DeadLetterFilter struct {
+	UserID string
+	RoomID string
+	Code   string
+	Limit  int
+	Offset int
+}
+
+// Matches reports whether l passes the filter. Backends that cannot query
+// by field use it, and so does the Hub's in-memory buffer.
+func (f DeadLetterFilter) Matches(l *models.DeadLetter) bool {
+	return (f.UserID == "" || l.UserID == f.UserID) &&
+		(f.RoomID == "" || l.RoomID == f.RoomID) &&
+		(f.Code == "" || l.Code == f.Code)
+}
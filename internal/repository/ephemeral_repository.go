@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// Ephemeral data — presence, sessions, counters, revoked tokens,
+// idempotency keys — lives
+// behind the small interfaces below rather than in PostgreSQL. Everything
+// here expires on its own. The memory implementations are per-process; the
+// Redis implementations survive restarts and are shared between instances.
+
+// PresenceRepository tracks which users are connected to which rooms.
+type PresenceRepository interface {
+	// Touch marks username present in roomID for ttl. Call again before ttl
+	// elapses to stay present.
+	Touch(ctx context.Context, roomID, username string, ttl time.Duration) error
+
+	// Remove marks username absent from roomID.
+	Remove(ctx context.Context, roomID, username string) error
+
+	// List returns the usernames present in roomID, in no particular order.
+	List(ctx context.Context, roomID string) ([]string, error)
+}
+
+// SessionRepository stores server-side login sessions until they expire.
+type SessionRepository interface {
+	// Create stores a session until session.ExpiresAt.
+	Create(ctx context.Context, session *models.Session) error
+
+	// Get retrieves a session. Returns ErrNotFound if missing or expired.
+	Get(ctx context.Context, id string) (*models.Session, error)
+
+	// Update replaces a stored session and keeps it until its new
+	// ExpiresAt. Returns ErrNotFound if missing or expired.
+	Update(ctx context.Context, session *models.Session) error
+
+	// ListByUser returns a user's unexpired sessions, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]*models.Session, error)
+
+	// Delete removes a session. Deleting a missing session is not an error.
+	Delete(ctx context.Context, id string) error
+
+	// DeleteByUser removes all of a user's sessions.
+	DeleteByUser(ctx context.Context, userID string) error
+
+	// CountCreatedBefore returns how many unexpired sessions were created
+	// before the given time.
+	CountCreatedBefore(ctx context.Context, before time.Time) (int, error)
+
+	// DeleteCreatedBefore removes sessions created before the given time,
+	// however long they had left, and returns how many were removed.
+	DeleteCreatedBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// CounterRepository implements fixed-window counters for rate limiting.
+type CounterRepository interface {
+	// Incr increments key and returns the new count. The counter starts at
+	// the first increment and resets window later.
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// RevokedTokenRepository remembers revoked JWTs (by jti) until they would
+// have expired anyway.
+type RevokedTokenRepository interface {
+	// Revoke marks tokenID revoked until expiresAt.
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	// IsRevoked reports whether tokenID has been revoked.
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// IdempotencyRepository remembers requests made with an Idempotency-Key
+// and their responses, so a retried request can be answered without
+// running it again.
+type IdempotencyRepository interface {
+	// Reserve claims key for a request with the given body fingerprint for
+	// ttl. It returns nil if the key was free; otherwise it returns the
+	// existing record, whose Status is 0 while that request is in progress.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, error)
+
+	// Complete stores the response of the request holding key for ttl.
+	Complete(ctx context.Context, key string, record *models.IdempotencyRecord, ttl time.Duration) error
+
+	// Release drops the reservation of key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// EphemeralStores groups the ephemeral repositories of one backend.
+type EphemeralStores struct {
+	Presence      PresenceRepository
+	Sessions      SessionRepository
+	Counters      CounterRepository
+	RevokedTokens RevokedTokenRepository
+	Idempotency   IdempotencyRepository
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// InstanceRepository stores the instance metadata an admin saved: a single
+// record, so there is no ID.
+type InstanceRepository interface {
+	// Get retrieves the saved instance metadata. Returns ErrNotFound if it
+	// was never saved.
+	Get(ctx context.Context) (*models.Instance, error)
+
+	// Put saves the instance metadata, replacing what was saved before.
+	Put(ctx context.Context, instance *models.Instance) error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// InviteRepository stores the invite codes admins hand out for
+// invite-only registration.
+type InviteRepository interface {
+	// Create stores a new invite. Returns ErrAlreadyExists if its code is
+	// taken.
+	Create(ctx context.Context, invite *models.Invite) error
+
+	// Delete removes an invite. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// GetByID retrieves an invite by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.Invite, error)
+
+	// List returns every invite, newest first.
+	List(ctx context.Context) ([]*models.Invite, error)
+
+	// Redeem uses up the invite with code once, atomically, and returns it
+	// as updated. Returns ErrNotFound if there is no such invite, or it
+	// expired by now or was used MaxUses times.
+	Redeem(ctx context.Context, code string, now time.Time) (*models.Invite, error)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// LegalRepository stores the versions of the legal documents users must
+// accept and who accepted which. Versions are never changed or removed
+// once published, and neither are acceptances: they are the record.
+type LegalRepository interface {
+	// Publish stores a new version of a document. Returns
+	// ErrAlreadyExists if its kind already has that version.
+	Publish(ctx context.Context, doc *models.LegalDocument) error
+
+	// Current returns the newest published version of kind. Returns
+	// ErrNotFound if none was published.
+	Current(ctx context.Context, kind string) (*models.LegalDocument, error)
+
+	// GetVersion retrieves a version of kind. Returns ErrNotFound if
+	// missing.
+	GetVersion(ctx context.Context, kind, version string) (*models.LegalDocument, error)
+
+	// ListVersions returns every version of kind, newest first.
+	ListVersions(ctx context.Context, kind string) ([]*models.LegalDocument, error)
+
+	// Accept records an acceptance. Accepting the same version again is a
+	// no-op, keeping the first time.
+	Accept(ctx context.Context, a *models.LegalAcceptance) error
+
+	// HasAccepted reports whether userID accepted that version of kind.
+	HasAccepted(ctx context.Context, userID, kind, version string) (bool, error)
+
+	// ListAcceptances returns everything userID accepted, newest first.
+	ListAcceptances(ctx context.Context, userID string) ([]*models.LegalAcceptance, error)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"ofenes/internal/models"
+)
+
+// LibraryRepository defines the contract for the saved videos of users and
+// rooms.
+type LibraryRepository interface {
+	// Create stores a new item.
+	Create(ctx context.Context, item *models.LibraryItem) error
+
+	// GetByID retrieves an item by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.LibraryItem, error)
+
+	// Update replaces the title, URL, folder, tags, metadata and UpdatedAt
+	// of an item. Returns ErrNotFound if missing.
+	Update(ctx context.Context, item *models.LibraryItem) error
+
+	// Delete removes an item. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// List returns items matching filter, newest first.
+	List(ctx context.Context, filter LibraryFilter) ([]*models.LibraryItem, error)
+
+	// Count returns how many items a user (ownerID) or room (roomID) has.
+	Count(ctx context.Context, ownerID, roomID string) (int, error)
+}
+
+// LibraryFilter selects library items. Zero fields match everything, but
+// callers set OwnerID or RoomID to list one library.
+type LibraryFilter struct {
+	OwnerID string
+	RoomID  string
+	Folder  string // exact folder
+	Tag     string // one of the item's tags
+	Query   string // case-insensitive substring of the title or URL
+	Limit   int
+	Offset  int
+}
+
+// matches reports whether item passes the filter.
+func (f LibraryFilter) matches(item *models.LibraryItem) bool {
+	q := strings.ToLower(f.Query)
+	return (f.OwnerID == "" || item.OwnerID == f.OwnerID) &&
+		(f.RoomID == "" || item.RoomID == f.RoomID) &&
+		(f.Folder == "" || item.Folder == f.Folder) &&
+		(f.Tag == "" || slices.Contains(item.Tags, f.Tag)) &&
+		(q == "" || strings.Contains(strings.ToLower(item.Title), q) || strings.Contains(strings.ToLower(item.URL), q))
+}
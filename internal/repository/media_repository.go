@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"ofenes/internal/models"
 )
@@ -41,3 +42,69 @@ type SharedFileRepository interface {
 	// Delete removes a shared file record.
 	Delete(ctx context.Context, id string) error
 }
+
+// MediaFileRepository defines the contract for uploaded media records. The
+// bytes themselves live in a media.Store under the record's ID.
+type MediaFileRepository interface {
+	// Create stores a new record.
+	Create(ctx context.Context, file *models.MediaFile) error
+
+	// GetByID retrieves a record by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.MediaFile, error)
+
+	// ListByRoom returns a room's media, newest first.
+	ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaFile, error)
+
+	// ListUploadingBefore returns the uploads started before the cutoff
+	// that never finished, oldest first.
+	ListUploadingBefore(ctx context.Context, before time.Time) ([]*models.MediaFile, error)
+
+	// TotalSizeByUploader returns the sum of the sizes of userID's
+	// uploads, unfinished ones included.
+	TotalSizeByUploader(ctx context.Context, userID string) (int64, error)
+
+	// MarkReady sets Status to models.MediaFileReady and UpdatedAt to at.
+	// Returns ErrNotFound if missing.
+	MarkReady(ctx context.Context, id string, at time.Time) error
+
+	// Delete removes a record. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// SetTranscode replaces the record's transcode job, e.g. to queue
+	// it. Returns ErrNotFound if missing.
+	SetTranscode(ctx context.Context, id string, t models.Transcode) error
+
+	// ClaimTranscode atomically hands worker the longest-waiting job that
+	// is queued, or running but not updated since staleBefore (its worker
+	// is presumed dead): it becomes running for worker, with Progress 0
+	// and UpdatedAt at. Returns ErrNotFound if there is none.
+	ClaimTranscode(ctx context.Context, worker string, at, staleBefore time.Time) (*models.MediaFile, error)
+
+	// UpdateTranscode replaces the job of a record while it is running
+	// for worker, to report progress or the outcome. Returns ErrNotFound
+	// if the record is missing or the job is no longer worker's.
+	UpdateTranscode(ctx context.Context, id, worker string, t models.Transcode) error
+}
+
+// SubtitleRepository defines the contract for the subtitle tracks of
+// uploaded media. The WebVTT files live in a media.Store next to the
+// media.
+type SubtitleRepository interface {
+	// Create stores a new track.
+	Create(ctx context.Context, sub *models.Subtitle) error
+
+	// GetByID retrieves a track by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.Subtitle, error)
+
+	// ListByMedia returns the tracks of a media file, oldest first.
+	ListByMedia(ctx context.Context, mediaID string) ([]*models.Subtitle, error)
+
+	// ListByRoom returns the tracks of a room's media, newest first.
+	ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.Subtitle, error)
+
+	// Delete removes a track. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// DeleteByMedia removes the tracks of a media file, if any.
+	DeleteByMedia(ctx context.Context, mediaID string) error
+}
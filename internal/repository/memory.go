@@ -3,17 +3,19 @@ package repository
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"ofenes/internal/models"
+	"ofenes/internal/username"
+	"ofenes/pkg/apperr"
 )
 
 // Common errors returned by repository implementations.
 var (
-	ErrNotFound      = errors.New("repository: not found")
-	ErrAlreadyExists = errors.New("repository: already exists")
+	ErrNotFound      = apperr.New(apperr.NotFound, "", "repository: not found")
+	ErrAlreadyExists = apperr.New(apperr.Conflict, "", "repository: already exists")
 )
 
 // MemoryUserRepo is an in-memory implementation of UserRepository.
@@ -22,14 +24,18 @@ var (
 // This is suitable for development and testing. For production,
 // implement UserRepository against PostgreSQL or another persistent store.
 type MemoryUserRepo struct {
-	mu    sync.RWMutex
-	users map[string]*models.User // keyed by user ID
+	mu     sync.RWMutex
+	users  map[string]*models.User    // keyed by user ID
+	byName map[string]string          // username.Key(username) -> user ID
+	bySkel map[string]map[string]bool // username.Skeleton(username) -> user IDs
 }
 
 // NewMemoryUserRepo creates an empty in-memory user store.
 func NewMemoryUserRepo() *MemoryUserRepo {
 	return &MemoryUserRepo{
-		users: make(map[string]*models.User),
+		users:  make(map[string]*models.User),
+		byName: make(map[string]string),
+		bySkel: make(map[string]map[string]bool),
 	}
 }
 
@@ -38,52 +44,75 @@ func (r *MemoryUserRepo) Create(_ context.Context, user *models.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Check for duplicate username
-	for _, existing := range r.users {
-		if existing.Username == user.Username {
-			return ErrAlreadyExists
-		}
+	if _, taken := r.byName[username.Key(user.Username)]; taken {
+		return ErrAlreadyExists
 	}
 
 	r.users[user.ID] = user
+	r.byName[username.Key(user.Username)] = user.ID
+	r.indexSkeleton(user.Username, user.ID)
 	return nil
 }
 
-// GetByID retrieves a user by ID. Returns ErrNotFound if missing.
+// GetByID retrieves a user by ID. Returns ErrNotFound if missing or soft-deleted.
 func (r *MemoryUserRepo) GetByID(_ context.Context, id string) (*models.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	user, ok := r.users[id]
+	user, ok := r.active(id)
 	if !ok {
 		return nil, ErrNotFound
 	}
 	return user, nil
 }
 
-// GetByUsername retrieves a user by username. Returns ErrNotFound if missing.
-func (r *MemoryUserRepo) GetByUsername(_ context.Context, username string) (*models.User, error) {
+// GetByUsername retrieves a user by username, ignoring case. Returns ErrNotFound if missing or soft-deleted.
+func (r *MemoryUserRepo) GetByUsername(_ context.Context, name string) (*models.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	for _, user := range r.users {
-		if user.Username == username {
+	user, ok := r.active(r.byName[username.Key(name)])
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+// GetBySkeleton retrieves a user whose username has the given skeleton. Returns ErrNotFound if none is active.
+func (r *MemoryUserRepo) GetBySkeleton(_ context.Context, skeleton string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for id := range r.bySkel[skeleton] {
+		if user, ok := r.active(id); ok {
 			return user, nil
 		}
 	}
 	return nil, ErrNotFound
 }
 
+// indexSkeleton adds id to the users whose name has name's skeleton.
+func (r *MemoryUserRepo) indexSkeleton(name, id string) {
+	skeleton := username.Skeleton(name)
+	if r.bySkel[skeleton] == nil {
+		r.bySkel[skeleton] = make(map[string]bool)
+	}
+	r.bySkel[skeleton][id] = true
+}
+
 // Update updates a user's profile fields.
 func (r *MemoryUserRepo) Update(_ context.Context, user *models.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.users[user.ID]; !ok {
+	existing, ok := r.active(user.ID)
+	if !ok {
 		return ErrNotFound
 	}
-	user.UpdatedAt = time.Now()
-	r.users[user.ID] = user
+	existing.DisplayName = user.DisplayName
+	existing.AvatarURL = user.AvatarURL
+	existing.Bio = user.Bio
+	existing.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -92,7 +121,7 @@ func (r *MemoryUserRepo) UpdateStatus(_ context.Context, userID string, status s
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	user, ok := r.users[userID]
+	user, ok := r.active(userID)
 	if !ok {
 		return ErrNotFound
 	}
@@ -101,12 +130,55 @@ func (r *MemoryUserRepo) UpdateStatus(_ context.Context, userID string, status s
 	return nil
 }
 
+// SetShadowBanned sets or clears a user's shadow ban.
+func (r *MemoryUserRepo) SetShadowBanned(_ context.Context, id string, banned bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.active(id)
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	setShadowBanned(user, banned, now)
+	user.UpdatedAt = now
+	return nil
+}
+
+// SetTrustLevel sets a user's trust level.
+func (r *MemoryUserRepo) SetTrustLevel(_ context.Context, id, level string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.active(id)
+	if !ok {
+		return ErrNotFound
+	}
+	user.TrustLevel = level
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetRole sets a user's role.
+func (r *MemoryUserRepo) SetRole(_ context.Context, id, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.active(id)
+	if !ok {
+		return ErrNotFound
+	}
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
 // UpdatePreferences replaces a user's preferences JSON.
 func (r *MemoryUserRepo) UpdatePreferences(_ context.Context, userID string, prefs json.RawMessage) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	user, ok := r.users[userID]
+	user, ok := r.active(userID)
 	if !ok {
 		return ErrNotFound
 	}
@@ -115,22 +187,117 @@ func (r *MemoryUserRepo) UpdatePreferences(_ context.Context, userID string, pre
 	return nil
 }
 
-// List returns a paginated list of users.
-func (r *MemoryUserRepo) List(_ context.Context, limit, offset int) ([]*models.User, error) {
+// List returns the users matching filter.
+func (r *MemoryUserRepo) List(_ context.Context, filter UserFilter) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*models.User
+	for _, u := range r.users {
+		if filter.matches(u) {
+			matched = append(matched, u)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return filter.less(matched[i], matched[j]) })
+	return paginate(matched, filter.Limit, filter.Offset), nil
+}
+
+// CountByRole returns the number of users matching filter per role.
+func (r *MemoryUserRepo) CountByRole(_ context.Context, filter UserFilter) (map[string]int, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	all := make([]*models.User, 0, len(r.users))
+	filter.Role = ""
+	counts := make(map[string]int)
 	for _, u := range r.users {
-		all = append(all, u)
+		if filter.matches(u) {
+			counts[u.Role]++
+		}
 	}
+	return counts, nil
+}
+
+// Delete soft-deletes a user.
+func (r *MemoryUserRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.active(id)
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	user.DeletedAt = &now
+	user.Status = models.StatusOffline
+	return nil
+}
+
+// Restore clears a user's soft-delete mark.
+func (r *MemoryUserRepo) Restore(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if offset >= len(all) {
-		return nil, nil
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil || user.AnonymizedAt != nil {
+		return ErrNotFound
 	}
-	end := offset + limit
-	if end > len(all) {
-		end = len(all)
+	user.DeletedAt = nil
+	return nil
+}
+
+// Anonymize replaces a user's personal data with pseudonym and soft-deletes them.
+func (r *MemoryUserRepo) Anonymize(_ context.Context, id, pseudonym string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.AnonymizedAt != nil {
+		return ErrNotFound
+	}
+	if _, taken := r.byName[username.Key(pseudonym)]; taken {
+		return ErrAlreadyExists
+	}
+	delete(r.byName, username.Key(user.Username))
+	r.byName[username.Key(pseudonym)] = id
+	delete(r.bySkel[username.Skeleton(user.Username)], id)
+	r.indexSkeleton(pseudonym, id)
+	anonymize(user, pseudonym, time.Now())
+	return nil
+}
+
+// GetByIDWithDeleted retrieves a user by ID whether or not it is soft-deleted.
+func (r *MemoryUserRepo) GetByIDWithDeleted(_ context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+// ListDeleted returns soft-deleted users, most recently deleted first.
+func (r *MemoryUserRepo) ListDeleted(_ context.Context, limit, offset int) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deleted []*models.User
+	for _, u := range r.users {
+		if u.DeletedAt != nil {
+			deleted = append(deleted, u)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].DeletedAt.After(*deleted[j].DeletedAt) })
+	return paginate(deleted, limit, offset), nil
+}
+
+// active returns the user with id unless it is missing or soft-deleted.
+// Must be called with r.mu held.
+func (r *MemoryUserRepo) active(id string) (*models.User, bool) {
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, false
 	}
-	return all[offset:end], nil
+	return user, true
 }
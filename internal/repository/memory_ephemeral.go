@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// sweepEvery is how many writes an in-memory ephemeral store takes between
+// sweeps of expired entries. Reads skip expired entries regardless.
+const sweepEvery = 1024
+
+// NewMemoryEphemeralStores creates per-process ephemeral stores. State is
+// lost on restart and not shared between instances — use Redis for that.
+func NewMemoryEphemeralStores() EphemeralStores {
+	return EphemeralStores{
+		Presence:      &MemoryPresenceRepo{rooms: make(map[string]map[string]time.Time)},
+		Sessions:      &MemorySessionRepo{sessions: make(map[string]*models.Session)},
+		Counters:      &MemoryCounterRepo{counters: make(map[string]*memoryCounter)},
+		RevokedTokens: &MemoryRevokedTokenRepo{tokens: make(map[string]time.Time)},
+		Idempotency:   &MemoryIdempotencyRepo{records: make(map[string]*memoryIdempotencyRecord)},
+	}
+}
+
+// --- Presence ---
+
+// MemoryPresenceRepo is an in-memory PresenceRepository.
+type MemoryPresenceRepo struct {
+	mu    sync.Mutex
+	rooms map[string]map[string]time.Time // room ID -> username -> expiry
+}
+
+// Touch marks username present in roomID for ttl.
+func (r *MemoryPresenceRepo) Touch(_ context.Context, roomID, username string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, ok := r.rooms[roomID]
+	if !ok {
+		users = make(map[string]time.Time)
+		r.rooms[roomID] = users
+	}
+	users[username] = time.Now().Add(ttl)
+	return nil
+}
+
+// Remove marks username absent from roomID.
+func (r *MemoryPresenceRepo) Remove(_ context.Context, roomID, username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.rooms[roomID], username)
+	if len(r.rooms[roomID]) == 0 {
+		delete(r.rooms, roomID)
+	}
+	return nil
+}
+
+// List returns the usernames present in roomID, dropping expired entries.
+func (r *MemoryPresenceRepo) List(_ context.Context, roomID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var names []string
+	for name, expires := range r.rooms[roomID] {
+		if now.After(expires) {
+			delete(r.rooms[roomID], name)
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(r.rooms[roomID]) == 0 {
+		delete(r.rooms, roomID)
+	}
+	return names, nil
+}
+
+// --- Sessions ---
+
+// MemorySessionRepo is an in-memory SessionRepository.
+type MemorySessionRepo struct {
+	mu       sync.Mutex
+	sessions map[string]*models.Session
+	writes   int
+}
+
+// Create stores a session until session.ExpiresAt.
+func (r *MemorySessionRepo) Create(_ context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.writes++; r.writes%sweepEvery == 0 {
+		now := time.Now()
+		for id, s := range r.sessions {
+			if now.After(s.ExpiresAt) {
+				delete(r.sessions, id)
+			}
+		}
+	}
+	if _, ok := r.sessions[session.ID]; ok {
+		return ErrAlreadyExists
+	}
+	s := *session
+	r.sessions[session.ID] = &s
+	return nil
+}
+
+// Get retrieves a session. Returns ErrNotFound if missing or expired.
+func (r *MemorySessionRepo) Get(_ context.Context, id string) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sessions[id]
+	if !ok || time.Now().After(s.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	c := *s
+	return &c, nil
+}
+
+// Update replaces a stored session.
+func (r *MemorySessionRepo) Update(_ context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.sessions[session.ID]; !ok || time.Now().After(s.ExpiresAt) {
+		return ErrNotFound
+	}
+	s := *session
+	r.sessions[session.ID] = &s
+	return nil
+}
+
+// ListByUser returns a user's unexpired sessions, oldest first.
+func (r *MemorySessionRepo) ListByUser(_ context.Context, userID string) ([]*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var sessions []*models.Session
+	for _, s := range r.sessions {
+		if s.UserID == userID && !now.After(s.ExpiresAt) {
+			c := *s
+			sessions = append(sessions, &c)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// Delete removes a session.
+func (r *MemorySessionRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, id)
+	return nil
+}
+
+// DeleteByUser removes all of a user's sessions.
+func (r *MemorySessionRepo) DeleteByUser(_ context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, s := range r.sessions {
+		if s.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+// CountCreatedBefore returns how many unexpired sessions were created before the given time.
+func (r *MemorySessionRepo) CountCreatedBefore(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, s := range r.sessions {
+		if s.CreatedAt.Before(before) && !now.After(s.ExpiresAt) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteCreatedBefore removes sessions created before the given time.
+// Expired sessions are dropped too but not counted.
+func (r *MemorySessionRepo) DeleteCreatedBefore(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for id, s := range r.sessions {
+		expired := now.After(s.ExpiresAt)
+		if expired || s.CreatedAt.Before(before) {
+			delete(r.sessions, id)
+			if !expired {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// --- Counters ---
+
+// MemoryCounterRepo is an in-memory CounterRepository.
+type MemoryCounterRepo struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	writes   int
+}
+
+type memoryCounter struct {
+	count   int64
+	expires time.Time
+}
+
+// Incr increments key within its fixed window and returns the new count.
+func (r *MemoryCounterRepo) Incr(_ context.Context, key string, window time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.writes++; r.writes%sweepEvery == 0 {
+		for k, c := range r.counters {
+			if now.After(c.expires) {
+				delete(r.counters, k)
+			}
+		}
+	}
+
+	c, ok := r.counters[key]
+	if !ok || now.After(c.expires) {
+		c = &memoryCounter{expires: now.Add(window)}
+		r.counters[key] = c
+	}
+	c.count++
+	return c.count, nil
+}
+
+// --- Revoked tokens ---
+
+// MemoryRevokedTokenRepo is an in-memory RevokedTokenRepository.
+type MemoryRevokedTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time // jti -> expiry
+	writes int
+}
+
+// Revoke marks tokenID revoked until expiresAt.
+func (r *MemoryRevokedTokenRepo) Revoke(_ context.Context, tokenID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.writes++; r.writes%sweepEvery == 0 {
+		now := time.Now()
+		for id, exp := range r.tokens {
+			if now.After(exp) {
+				delete(r.tokens, id)
+			}
+		}
+	}
+	r.tokens[tokenID] = expiresAt
+	return nil
+}
+
+// IsRevoked reports whether tokenID has been revoked.
+func (r *MemoryRevokedTokenRepo) IsRevoked(_ context.Context, tokenID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exp, ok := r.tokens[tokenID]
+	return ok && time.Now().Before(exp), nil
+}
+
+// --- Idempotency keys ---
+
+// MemoryIdempotencyRepo is an in-memory IdempotencyRepository.
+type MemoryIdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]*memoryIdempotencyRecord
+	writes  int
+}
+
+type memoryIdempotencyRecord struct {
+	record  models.IdempotencyRecord
+	expires time.Time
+}
+
+// Reserve claims key unless it is already taken, in which case the
+// existing record is returned.
+func (r *MemoryIdempotencyRepo) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.writes++; r.writes%sweepEvery == 0 {
+		for k, rec := range r.records {
+			if now.After(rec.expires) {
+				delete(r.records, k)
+			}
+		}
+	}
+
+	if rec, ok := r.records[key]; ok && now.Before(rec.expires) {
+		existing := rec.record
+		return &existing, nil
+	}
+	r.records[key] = &memoryIdempotencyRecord{
+		record:  models.IdempotencyRecord{Fingerprint: fingerprint},
+		expires: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete stores the response for key.
+func (r *MemoryIdempotencyRepo) Complete(_ context.Context, key string, record *models.IdempotencyRecord, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[key] = &memoryIdempotencyRecord{record: *record, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release drops the record of key.
+func (r *MemoryIdempotencyRepo) Release(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, key)
+	return nil
+}
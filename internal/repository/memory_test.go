@@ -0,0 +1,15 @@
+package repository_test
+
+import (
+	"testing"
+
+	"ofenes/internal/repository"
+	"ofenes/internal/repository/repotest"
+)
+
+// The memory backend only stores users.
+func TestMemoryUserRepo(t *testing.T) {
+	repotest.UserRepository(t, func(t *testing.T) repository.Repos {
+		return repository.Repos{Users: repository.NewMemoryUserRepo()}
+	})
+}
@@ -16,6 +16,30 @@ type MessageRepository interface {
 	// Results are ordered newest-first (DESC).
 	GetByRoom(ctx context.Context, roomID string, before time.Time, limit int) ([]*models.ChatMessage, error)
 
+	// GetByRoomAfter returns up to limit messages of a room created after
+	// the given time, oldest first (ASC).
+	GetByRoomAfter(ctx context.Context, roomID string, after time.Time, limit int) ([]*models.ChatMessage, error)
+
 	// GetByID retrieves a single message by ID. Returns ErrNotFound if missing.
 	GetByID(ctx context.Context, id string) (*models.ChatMessage, error)
+
+	// Delete deletes a single message. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// DeleteByRoom deletes all messages of a room and returns how many were deleted.
+	DeleteByRoom(ctx context.Context, roomID string) (int, error)
+
+	// DeleteBefore deletes a room's messages created before the given time
+	// and returns how many were deleted.
+	DeleteBefore(ctx context.Context, roomID string, before time.Time) (int, error)
+
+	// RenameSender sets the sender name stored on every message by
+	// senderID and returns how many were changed. Backends that join the
+	// name from the users table at read time have nothing to rewrite and
+	// return 0.
+	RenameSender(ctx context.Context, senderID, name string) (int, error)
+
+	// CountBySender returns the number of stored messages per sender ID.
+	// Senders without messages are omitted.
+	CountBySender(ctx context.Context) (map[string]int, error)
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// MetadataRepository caches the answers of the metadata provider (TMDB or
+// OMDb), misses included, by query key.
+type MetadataRepository interface {
+	// Get retrieves the lookup of key. Returns ErrNotFound if missing.
+	Get(ctx context.Context, key string) (*models.MetadataLookup, error)
+
+	// Put stores a lookup, replacing any earlier one of its key.
+	Put(ctx context.Context, lookup *models.MetadataLookup) error
+}
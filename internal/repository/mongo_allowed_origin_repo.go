@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoAllowedOriginRepo implements AllowedOriginRepository against MongoDB.
+type MongoAllowedOriginRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoAllowedOriginRepo creates a new MongoDB-backed allowed origin repository.
+func NewMongoAllowedOriginRepo(db *mongo.Database) *MongoAllowedOriginRepo {
+	return &MongoAllowedOriginRepo{coll: db.Collection("allowed_origins")}
+}
+
+// mongoAllowedOrigin is the stored form of models.AllowedOrigin.
+type mongoAllowedOrigin struct {
+	ID        string    `bson:"_id"`
+	Origin    string    `bson:"origin"`
+	CreatedBy string    `bson:"created_by"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func (d *mongoAllowedOrigin) toModel() *models.AllowedOrigin {
+	return &models.AllowedOrigin{ID: d.ID, Origin: d.Origin, CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt}
+}
+
+// Create stores a new origin. The unique index on origin rejects duplicates.
+func (r *MongoAllowedOriginRepo) Create(ctx context.Context, origin *models.AllowedOrigin) error {
+	_, err := r.coll.InsertOne(ctx, mongoAllowedOrigin{
+		ID: origin.ID, Origin: origin.Origin, CreatedBy: origin.CreatedBy, CreatedAt: origin.CreatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// Delete removes an origin.
+func (r *MongoAllowedOriginRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves an origin by ID.
+func (r *MongoAllowedOriginRepo) GetByID(ctx context.Context, id string) (*models.AllowedOrigin, error) {
+	var doc mongoAllowedOrigin
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns every origin, oldest first.
+func (r *MongoAllowedOriginRepo) List(ctx context.Context) ([]*models.AllowedOrigin, error) {
+	cur, err := r.coll.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoAllowedOrigin
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	origins := make([]*models.AllowedOrigin, 0, len(docs))
+	for i := range docs {
+		origins = append(origins, docs[i].toModel())
+	}
+	return origins, nil
+}
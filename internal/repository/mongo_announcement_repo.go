@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoAnnouncementRepo implements AnnouncementRepository against MongoDB.
+type MongoAnnouncementRepo struct {
+	coll       *mongo.Collection
+	dismissals *mongo.Collection
+}
+
+// NewMongoAnnouncementRepo creates a new MongoDB-backed announcement repository.
+func NewMongoAnnouncementRepo(db *mongo.Database) *MongoAnnouncementRepo {
+	return &MongoAnnouncementRepo{
+		coll:       db.Collection("announcements"),
+		dismissals: db.Collection("announcement_dismissals"),
+	}
+}
+
+// mongoAnnouncement is the stored form of models.Announcement.
+type mongoAnnouncement struct {
+	ID        string     `bson:"_id"`
+	Title     string     `bson:"title"`
+	Body      string     `bson:"body,omitempty"`
+	Level     string     `bson:"level"`
+	StartsAt  time.Time  `bson:"starts_at"`
+	EndsAt    *time.Time `bson:"ends_at,omitempty"`
+	CreatedBy string     `bson:"created_by"`
+	CreatedAt time.Time  `bson:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at"`
+}
+
+func (d *mongoAnnouncement) toModel() *models.Announcement {
+	return &models.Announcement{
+		ID: d.ID, Title: d.Title, Body: d.Body, Level: d.Level, StartsAt: d.StartsAt, EndsAt: d.EndsAt,
+		CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// mongoAnnouncementDismissal records that a user dismissed an announcement.
+type mongoAnnouncementDismissal struct {
+	AnnouncementID string    `bson:"announcement_id"`
+	UserID         string    `bson:"user_id"`
+	DismissedAt    time.Time `bson:"dismissed_at"`
+}
+
+// Create stores a new announcement.
+func (r *MongoAnnouncementRepo) Create(ctx context.Context, a *models.Announcement) error {
+	_, err := r.coll.InsertOne(ctx, mongoAnnouncement{
+		ID: a.ID, Title: a.Title, Body: a.Body, Level: a.Level, StartsAt: a.StartsAt, EndsAt: a.EndsAt,
+		CreatedBy: a.CreatedBy, CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt,
+	})
+	return err
+}
+
+// Update replaces an announcement's title, body, level and schedule.
+func (r *MongoAnnouncementRepo) Update(ctx context.Context, a *models.Announcement) error {
+	update := bson.M{"$set": bson.M{
+		"title": a.Title, "body": a.Body, "level": a.Level, "starts_at": a.StartsAt, "updated_at": a.UpdatedAt,
+	}}
+	if a.EndsAt != nil {
+		update["$set"].(bson.M)["ends_at"] = *a.EndsAt
+	} else {
+		update["$unset"] = bson.M{"ends_at": ""}
+	}
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": a.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes an announcement and its dismissals.
+func (r *MongoAnnouncementRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	_, err = r.dismissals.DeleteMany(ctx, bson.M{"announcement_id": id})
+	return err
+}
+
+// GetByID retrieves an announcement by ID.
+func (r *MongoAnnouncementRepo) GetByID(ctx context.Context, id string) (*models.Announcement, error) {
+	var doc mongoAnnouncement
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns every announcement, latest start first.
+func (r *MongoAnnouncementRepo) List(ctx context.Context) ([]*models.Announcement, error) {
+	return r.find(ctx, bson.M{}, -1)
+}
+
+// ListCurrent returns the announcements that have not ended at t, earliest
+// start first.
+func (r *MongoAnnouncementRepo) ListCurrent(ctx context.Context, t time.Time) ([]*models.Announcement, error) {
+	return r.find(ctx, bson.M{"$or": bson.A{
+		bson.M{"ends_at": bson.M{"$exists": false}},
+		bson.M{"ends_at": bson.M{"$gt": t}},
+	}}, 1)
+}
+
+// find returns the announcements matching filter, sorted by start in
+// order (1 or -1).
+func (r *MongoAnnouncementRepo) find(ctx context.Context, filter bson.M, order int) ([]*models.Announcement, error) {
+	cur, err := r.coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "starts_at", Value: order}, {Key: "_id", Value: order}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoAnnouncement
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	announcements := make([]*models.Announcement, 0, len(docs))
+	for i := range docs {
+		announcements = append(announcements, docs[i].toModel())
+	}
+	return announcements, nil
+}
+
+// Dismiss records that userID dismissed announcement id at t.
+func (r *MongoAnnouncementRepo) Dismiss(ctx context.Context, id, userID string, t time.Time) error {
+	n, err := r.coll.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	_, err = r.dismissals.UpdateOne(ctx,
+		bson.M{"announcement_id": id, "user_id": userID},
+		bson.M{"$setOnInsert": mongoAnnouncementDismissal{AnnouncementID: id, UserID: userID, DismissedAt: t}},
+		options.UpdateOne().SetUpsert(true))
+	return err
+}
+
+// ListDismissed returns the IDs of the announcements userID dismissed.
+func (r *MongoAnnouncementRepo) ListDismissed(ctx context.Context, userID string) ([]string, error) {
+	cur, err := r.dismissals.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoAnnouncementDismissal
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(docs))
+	for _, d := range docs {
+		ids = append(ids, d.AnnouncementID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoAuditRepo implements AuditRepository against MongoDB.
+type MongoAuditRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoAuditRepo creates a new MongoDB-backed audit repository.
+func NewMongoAuditRepo(db *mongo.Database) *MongoAuditRepo {
+	return &MongoAuditRepo{coll: db.Collection("audit_log")}
+}
+
+// mongoAuditEntry is the stored form of models.AuditEntry.
+type mongoAuditEntry struct {
+	ID         string    `bson:"_id"`
+	ActorID    string    `bson:"actor_id,omitempty"`
+	Action     string    `bson:"action"`
+	TargetType string    `bson:"target_type,omitempty"`
+	TargetID   string    `bson:"target_id,omitempty"`
+	Details    []byte    `bson:"details,omitempty"`
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+func (d *mongoAuditEntry) toModel() *models.AuditEntry {
+	return &models.AuditEntry{
+		ID: d.ID, ActorID: d.ActorID, Action: d.Action,
+		TargetType: d.TargetType, TargetID: d.TargetID,
+		Details: d.Details, CreatedAt: d.CreatedAt,
+	}
+}
+
+// Create appends an entry.
+func (r *MongoAuditRepo) Create(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := r.coll.InsertOne(ctx, mongoAuditEntry{
+		ID: entry.ID, ActorID: entry.ActorID, Action: entry.Action,
+		TargetType: entry.TargetType, TargetID: entry.TargetID,
+		Details: entry.Details, CreatedAt: entry.CreatedAt,
+	})
+	return err
+}
+
+// List returns entries matching filter, newest first.
+func (r *MongoAuditRepo) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	q := bson.M{}
+	if filter.Action != "" {
+		q["action"] = filter.Action
+	}
+	if filter.ActorID != "" {
+		q["actor_id"] = filter.ActorID
+	}
+	if filter.TargetID != "" {
+		q["target_id"] = filter.TargetID
+	}
+
+	cur, err := r.coll.Find(ctx, q, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoAuditEntry
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	entries := make([]*models.AuditEntry, 0, len(docs))
+	for i := range docs {
+		entries = append(entries, docs[i].toModel())
+	}
+	return entries, nil
+}
+
+// CountBefore returns how many entries were created before the given time.
+func (r *MongoAuditRepo) CountBefore(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.coll.CountDocuments(ctx, bson.M{"created_at": bson.M{"$lt": before}})
+	return int(n), err
+}
+
+// DeleteBefore deletes entries created before the given time.
+func (r *MongoAuditRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.coll.DeleteMany(ctx, bson.M{"created_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoDeadLetterRepo implements DeadLetterRepository against MongoDB.
+type MongoDeadLetterRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoDeadLetterRepo creates a new MongoDB-backed dead letter repository.
+func NewMongoDeadLetterRepo(db *mongo.Database) *MongoDeadLetterRepo {
+	return &MongoDeadLetterRepo{coll: db.Collection("dead_letters")}
+}
+
+// mongoDeadLetter is the stored form of models.DeadLetter.
+type mongoDeadLetter struct {
+	ID        string    `bson:"_id"`
+	UserID    string    `bson:"user_id"`
+	Username  string    `bson:"username"`
+	RoomID    string    `bson:"room_id"`
+	Type      string    `bson:"type,omitempty"`
+	MessageID string    `bson:"message_id,omitempty"`
+	Code      string    `bson:"code"`
+	Reason    string    `bson:"reason"`
+	Data      string    `bson:"data"`
+	Truncated bool      `bson:"truncated,omitempty"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func (d *mongoDeadLetter) toModel() *models.DeadLetter {
+	return &models.DeadLetter{
+		ID: d.ID, UserID: d.UserID, Username: d.Username, RoomID: d.RoomID,
+		Type: d.Type, MessageID: d.MessageID, Code: d.Code, Reason: d.Reason,
+		Data: d.Data, Truncated: d.Truncated, CreatedAt: d.CreatedAt,
+	}
+}
+
+// Create appends a dead letter.
+func (r *MongoDeadLetterRepo) Create(ctx context.Context, l *models.DeadLetter) error {
+	_, err := r.coll.InsertOne(ctx, mongoDeadLetter{
+		ID: l.ID, UserID: l.UserID, Username: l.Username, RoomID: l.RoomID,
+		Type: l.Type, MessageID: l.MessageID, Code: l.Code, Reason: l.Reason,
+		Data: l.Data, Truncated: l.Truncated, CreatedAt: l.CreatedAt,
+	})
+	return err
+}
+
+// List returns dead letters matching filter, newest first.
+func (r *MongoDeadLetterRepo) List(ctx context.Context, filter DeadLetterFilter) ([]*models.DeadLetter, error) {
+	q := bson.M{}
+	if filter.UserID != "" {
+		q["user_id"] = filter.UserID
+	}
+	if filter.RoomID != "" {
+		q["room_id"] = filter.RoomID
+	}
+	if filter.Code != "" {
+		q["code"] = filter.Code
+	}
+
+	cur, err := r.coll.Find(ctx, q, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoDeadLetter
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	letters := make([]*models.DeadLetter, 0, len(docs))
+	for i := range docs {
+		letters = append(letters, docs[i].toModel())
+	}
+	return letters, nil
+}
+
+// CountBefore returns how many dead letters were created before the given time.
+func (r *MongoDeadLetterRepo) CountBefore(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.coll.CountDocuments(ctx, bson.M{"created_at": bson.M{"$lt": before}})
+	return int(n), err
+}
+
+// DeleteBefore deletes dead letters created before the given time.
+func (r *MongoDeadLetterRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.coll.DeleteMany(ctx, bson.M{"created_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// mongoInstanceID is the _id of the single document in "instance".
+const mongoInstanceID = "instance"
+
+// MongoInstanceRepo implements InstanceRepository against MongoDB.
+type MongoInstanceRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoInstanceRepo creates a new MongoDB-backed instance metadata repository.
+func NewMongoInstanceRepo(db *mongo.Database) *MongoInstanceRepo {
+	return &MongoInstanceRepo{coll: db.Collection("instance")}
+}
+
+// mongoInstance is the stored form of models.Instance.
+type mongoInstance struct {
+	ID          string     `bson:"_id"`
+	Name        string     `bson:"name"`
+	Description string     `bson:"description,omitempty"`
+	LogoURL     string     `bson:"logo_url,omitempty"`
+	MOTD        string     `bson:"motd,omitempty"`
+	Contact     string     `bson:"contact,omitempty"`
+	UpdatedBy   string     `bson:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `bson:"updated_at,omitempty"`
+}
+
+// Get retrieves the saved instance metadata.
+func (r *MongoInstanceRepo) Get(ctx context.Context) (*models.Instance, error) {
+	var doc mongoInstance
+	if err := r.coll.FindOne(ctx, bson.M{"_id": mongoInstanceID}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &models.Instance{
+		Name: doc.Name, Description: doc.Description, LogoURL: doc.LogoURL, MOTD: doc.MOTD, Contact: doc.Contact,
+		UpdatedBy: doc.UpdatedBy, UpdatedAt: doc.UpdatedAt,
+	}, nil
+}
+
+// Put saves the instance metadata, replacing what was saved before.
+func (r *MongoInstanceRepo) Put(ctx context.Context, instance *models.Instance) error {
+	_, err := r.coll.ReplaceOne(ctx, bson.M{"_id": mongoInstanceID}, mongoInstance{
+		ID: mongoInstanceID, Name: instance.Name, Description: instance.Description, LogoURL: instance.LogoURL,
+		MOTD: instance.MOTD, Contact: instance.Contact, UpdatedBy: instance.UpdatedBy, UpdatedAt: instance.UpdatedAt,
+	}, options.Replace().SetUpsert(true))
+	return err
+}
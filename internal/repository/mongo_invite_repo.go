@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoInviteRepo implements InviteRepository against MongoDB.
+type MongoInviteRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoInviteRepo creates a new MongoDB-backed invite repository.
+func NewMongoInviteRepo(db *mongo.Database) *MongoInviteRepo {
+	return &MongoInviteRepo{coll: db.Collection("invites")}
+}
+
+// mongoInvite is the stored form of models.Invite.
+type mongoInvite struct {
+	ID        string     `bson:"_id"`
+	Code      string     `bson:"code"`
+	MaxUses   int        `bson:"max_uses"`
+	Uses      int        `bson:"uses"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty"`
+	CreatedBy string     `bson:"created_by"`
+	CreatedAt time.Time  `bson:"created_at"`
+}
+
+func (d *mongoInvite) toModel() *models.Invite {
+	return &models.Invite{
+		ID: d.ID, Code: d.Code, MaxUses: d.MaxUses, Uses: d.Uses, ExpiresAt: d.ExpiresAt,
+		CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt,
+	}
+}
+
+// Create stores a new invite. The unique index on code rejects duplicates.
+func (r *MongoInviteRepo) Create(ctx context.Context, invite *models.Invite) error {
+	_, err := r.coll.InsertOne(ctx, mongoInvite{
+		ID: invite.ID, Code: invite.Code, MaxUses: invite.MaxUses, Uses: invite.Uses, ExpiresAt: invite.ExpiresAt,
+		CreatedBy: invite.CreatedBy, CreatedAt: invite.CreatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// Delete removes an invite.
+func (r *MongoInviteRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves an invite by ID.
+func (r *MongoInviteRepo) GetByID(ctx context.Context, id string) (*models.Invite, error) {
+	var doc mongoInvite
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns every invite, newest first.
+func (r *MongoInviteRepo) List(ctx context.Context) ([]*models.Invite, error) {
+	cur, err := r.coll.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoInvite
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	invites := make([]*models.Invite, 0, len(docs))
+	for i := range docs {
+		invites = append(invites, docs[i].toModel())
+	}
+	return invites, nil
+}
+
+// Redeem uses up the invite with code once. The conditions are part of the
+// filter of a single FindOneAndUpdate, so concurrent registrations can't
+// overuse it.
+func (r *MongoInviteRepo) Redeem(ctx context.Context, code string, now time.Time) (*models.Invite, error) {
+	filter := bson.M{
+		"code":  code,
+		"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}},
+		"$or":   bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": now}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var doc mongoInvite
+	if err := r.coll.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}}, opts).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
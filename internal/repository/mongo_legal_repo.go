@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoLegalRepo implements LegalRepository against MongoDB.
+type MongoLegalRepo struct {
+	docs        *mongo.Collection
+	acceptances *mongo.Collection
+}
+
+// NewMongoLegalRepo creates a new MongoDB-backed legal document repository.
+func NewMongoLegalRepo(db *mongo.Database) *MongoLegalRepo {
+	return &MongoLegalRepo{docs: db.Collection("legal_documents"), acceptances: db.Collection("legal_acceptances")}
+}
+
+// mongoLegalDocument is the stored form of models.LegalDocument.
+type mongoLegalDocument struct {
+	Kind        string    `bson:"kind"`
+	Version     string    `bson:"version"`
+	Title       string    `bson:"title"`
+	Body        string    `bson:"body"`
+	PublishedBy string    `bson:"published_by"`
+	PublishedAt time.Time `bson:"published_at"`
+}
+
+func (d *mongoLegalDocument) toModel() *models.LegalDocument {
+	return &models.LegalDocument{
+		Kind: d.Kind, Version: d.Version, Title: d.Title, Body: d.Body,
+		PublishedBy: d.PublishedBy, PublishedAt: d.PublishedAt,
+	}
+}
+
+// mongoLegalAcceptance is the stored form of models.LegalAcceptance.
+type mongoLegalAcceptance struct {
+	UserID     string    `bson:"user_id"`
+	Kind       string    `bson:"kind"`
+	Version    string    `bson:"version"`
+	AcceptedAt time.Time `bson:"accepted_at"`
+}
+
+// Publish stores a new version of a document. The unique index on kind
+// and version rejects duplicates.
+func (r *MongoLegalRepo) Publish(ctx context.Context, doc *models.LegalDocument) error {
+	_, err := r.docs.InsertOne(ctx, mongoLegalDocument{
+		Kind: doc.Kind, Version: doc.Version, Title: doc.Title, Body: doc.Body,
+		PublishedBy: doc.PublishedBy, PublishedAt: doc.PublishedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// Current returns the newest published version of kind.
+func (r *MongoLegalRepo) Current(ctx context.Context, kind string) (*models.LegalDocument, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "version", Value: -1}})
+	return r.findOne(ctx, bson.M{"kind": kind}, opts)
+}
+
+// GetVersion retrieves a version of kind.
+func (r *MongoLegalRepo) GetVersion(ctx context.Context, kind, version string) (*models.LegalDocument, error) {
+	return r.findOne(ctx, bson.M{"kind": kind, "version": version})
+}
+
+// findOne returns the single document matching filter.
+func (r *MongoLegalRepo) findOne(ctx context.Context, filter bson.M, opts ...options.Lister[options.FindOneOptions]) (*models.LegalDocument, error) {
+	var doc mongoLegalDocument
+	if err := r.docs.FindOne(ctx, filter, opts...).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// ListVersions returns every version of kind, newest first.
+func (r *MongoLegalRepo) ListVersions(ctx context.Context, kind string) ([]*models.LegalDocument, error) {
+	cur, err := r.docs.Find(ctx, bson.M{"kind": kind}, options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "version", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoLegalDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]*models.LegalDocument, 0, len(docs))
+	for i := range docs {
+		out = append(out, docs[i].toModel())
+	}
+	return out, nil
+}
+
+// Accept records an acceptance unless that version was accepted before.
+func (r *MongoLegalRepo) Accept(ctx context.Context, a *models.LegalAcceptance) error {
+	_, err := r.acceptances.UpdateOne(ctx,
+		bson.M{"user_id": a.UserID, "kind": a.Kind, "version": a.Version},
+		bson.M{"$setOnInsert": mongoLegalAcceptance{UserID: a.UserID, Kind: a.Kind, Version: a.Version, AcceptedAt: a.AcceptedAt}},
+		options.UpdateOne().SetUpsert(true))
+	return err
+}
+
+// HasAccepted reports whether userID accepted that version of kind.
+func (r *MongoLegalRepo) HasAccepted(ctx context.Context, userID, kind, version string) (bool, error) {
+	n, err := r.acceptances.CountDocuments(ctx, bson.M{"user_id": userID, "kind": kind, "version": version})
+	return n > 0, err
+}
+
+// ListAcceptances returns everything userID accepted, newest first.
+func (r *MongoLegalRepo) ListAcceptances(ctx context.Context, userID string) ([]*models.LegalAcceptance, error) {
+	cur, err := r.acceptances.Find(ctx, bson.M{"user_id": userID}, options.Find().
+		SetSort(bson.D{{Key: "accepted_at", Value: -1}, {Key: "kind", Value: 1}, {Key: "version", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoLegalAcceptance
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	accepted := make([]*models.LegalAcceptance, 0, len(docs))
+	for _, d := range docs {
+		accepted = append(accepted, &models.LegalAcceptance{UserID: d.UserID, Kind: d.Kind, Version: d.Version, AcceptedAt: d.AcceptedAt})
+	}
+	return accepted, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoLibraryRepo implements LibraryRepository against MongoDB.
+type MongoLibraryRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoLibraryRepo creates a new MongoDB-backed library repository.
+func NewMongoLibraryRepo(db *mongo.Database) *MongoLibraryRepo {
+	return &MongoLibraryRepo{coll: db.Collection("library_items")}
+}
+
+// mongoLibraryItem is the stored form of models.LibraryItem.
+type mongoLibraryItem struct {
+	ID        string              `bson:"_id"`
+	OwnerID   string              `bson:"owner_id,omitempty"`
+	RoomID    string              `bson:"room_id,omitempty"`
+	AddedBy   string              `bson:"added_by"`
+	Title     string              `bson:"title"`
+	URL       string              `bson:"url"`
+	Folder    string              `bson:"folder"`
+	Tags      []string            `bson:"tags"`
+	Metadata  *mongoMediaMetadata `bson:"metadata,omitempty"`
+	CreatedAt time.Time           `bson:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at"`
+}
+
+func (d *mongoLibraryItem) toModel() *models.LibraryItem {
+	tags := d.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return &models.LibraryItem{
+		ID:        d.ID,
+		OwnerID:   d.OwnerID,
+		RoomID:    d.RoomID,
+		AddedBy:   d.AddedBy,
+		Title:     d.Title,
+		URL:       d.URL,
+		Folder:    d.Folder,
+		Tags:      tags,
+		Metadata:  d.Metadata.toModel(),
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// Create inserts an item.
+func (r *MongoLibraryRepo) Create(ctx context.Context, item *models.LibraryItem) error {
+	_, err := r.coll.InsertOne(ctx, mongoLibraryItem{
+		ID:        item.ID,
+		OwnerID:   item.OwnerID,
+		RoomID:    item.RoomID,
+		AddedBy:   item.AddedBy,
+		Title:     item.Title,
+		URL:       item.URL,
+		Folder:    item.Folder,
+		Tags:      item.Tags,
+		Metadata:  toMongoMetadata(item.Metadata),
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	})
+	return err
+}
+
+// GetByID retrieves an item by ID.
+func (r *MongoLibraryRepo) GetByID(ctx context.Context, id string) (*models.LibraryItem, error) {
+	var doc mongoLibraryItem
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// Update replaces the editable fields of an item.
+func (r *MongoLibraryRepo) Update(ctx context.Context, item *models.LibraryItem) error {
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": item.ID}, bson.M{"$set": bson.M{
+		"title":      item.Title,
+		"url":        item.URL,
+		"folder":     item.Folder,
+		"tags":       item.Tags,
+		"metadata":   toMongoMetadata(item.Metadata),
+		"updated_at": item.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes an item.
+func (r *MongoLibraryRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns items matching filter, newest first.
+func (r *MongoLibraryRepo) List(ctx context.Context, filter LibraryFilter) ([]*models.LibraryItem, error) {
+	q := bson.M{}
+	if filter.OwnerID != "" {
+		q["owner_id"] = filter.OwnerID
+	}
+	if filter.RoomID != "" {
+		q["room_id"] = filter.RoomID
+	}
+	if filter.Folder != "" {
+		q["folder"] = filter.Folder
+	}
+	if filter.Tag != "" {
+		q["tags"] = filter.Tag // matches any element
+	}
+	if filter.Query != "" {
+		re := bson.M{"$regex": regexp.QuoteMeta(filter.Query), "$options": "i"}
+		q["$or"] = bson.A{bson.M{"title": re}, bson.M{"url": re}}
+	}
+
+	cur, err := r.coll.Find(ctx, q, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit)))
+	if err != nil {
+		return nil, err
+	}
+	var docs []mongoLibraryItem
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	items := make([]*models.LibraryItem, 0, len(docs))
+	for i := range docs {
+		items = append(items, docs[i].toModel())
+	}
+	return items, nil
+}
+
+// Count returns how many items a user or room has.
+func (r *MongoLibraryRepo) Count(ctx context.Context, ownerID, roomID string) (int, error) {
+	var or bson.A
+	if ownerID != "" {
+		or = append(or, bson.M{"owner_id": ownerID})
+	}
+	if roomID != "" {
+		or = append(or, bson.M{"room_id": roomID})
+	}
+	if or == nil {
+		return 0, nil
+	}
+	n, err := r.coll.CountDocuments(ctx, bson.M{"$or": or})
+	return int(n), err
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoMediaFileRepo implements MediaFileRepository against MongoDB.
+type MongoMediaFileRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoMediaFileRepo creates a new MongoDB-backed media file repository.
+func NewMongoMediaFileRepo(db *mongo.Database) *MongoMediaFileRepo {
+	return &MongoMediaFileRepo{coll: db.Collection("media_files")}
+}
+
+// mongoMediaFile is the stored form of models.MediaFile.
+type mongoMediaFile struct {
+	ID         string    `bson:"_id"`
+	RoomID     string    `bson:"room_id"`
+	UploadedBy string    `bson:"uploaded_by"`
+	FileName   string    `bson:"file_name"`
+	MimeType   string    `bson:"mime_type"`
+	Size       int64     `bson:"size"`
+	Status     string    `bson:"status"`
+	CreatedAt  time.Time `bson:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+
+	Transcode *mongoTranscode `bson:"transcode,omitempty"`
+}
+
+// mongoTranscode is the stored form of models.Transcode.
+type mongoTranscode struct {
+	Status    string    `bson:"status"`
+	Progress  float64   `bson:"progress"`
+	Worker    string    `bson:"worker"`
+	Error     string    `bson:"error"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func newMongoTranscode(t models.Transcode) *mongoTranscode {
+	return &mongoTranscode{
+		Status:    t.Status,
+		Progress:  t.Progress,
+		Worker:    t.Worker,
+		Error:     t.Error,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+func (d *mongoMediaFile) toModel() *models.MediaFile {
+	f := &models.MediaFile{
+		ID:         d.ID,
+		RoomID:     d.RoomID,
+		UploadedBy: d.UploadedBy,
+		FileName:   d.FileName,
+		MimeType:   d.MimeType,
+		Size:       d.Size,
+		Status:     d.Status,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+	}
+	if t := d.Transcode; t != nil {
+		f.Transcode = &models.Transcode{
+			Status:    t.Status,
+			Progress:  t.Progress,
+			Worker:    t.Worker,
+			Error:     t.Error,
+			UpdatedAt: t.UpdatedAt,
+		}
+	}
+	return f
+}
+
+// Create inserts a record.
+func (r *MongoMediaFileRepo) Create(ctx context.Context, file *models.MediaFile) error {
+	_, err := r.coll.InsertOne(ctx, mongoMediaFile{
+		ID:         file.ID,
+		RoomID:     file.RoomID,
+		UploadedBy: file.UploadedBy,
+		FileName:   file.FileName,
+		MimeType:   file.MimeType,
+		Size:       file.Size,
+		Status:     file.Status,
+		CreatedAt:  file.CreatedAt,
+		UpdatedAt:  file.UpdatedAt,
+	})
+	return err
+}
+
+// GetByID retrieves a record by ID.
+func (r *MongoMediaFileRepo) GetByID(ctx context.Context, id string) (*models.MediaFile, error) {
+	var doc mongoMediaFile
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// ListByRoom returns a room's media, newest first.
+func (r *MongoMediaFileRepo) ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaFile, error) {
+	return r.find(ctx, bson.M{"room_id": roomID}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+}
+
+// ListUploadingBefore returns unfinished uploads started before the cutoff, oldest first.
+func (r *MongoMediaFileRepo) ListUploadingBefore(ctx context.Context, before time.Time) ([]*models.MediaFile, error) {
+	return r.find(ctx, bson.M{
+		"status":     models.MediaFileUploading,
+		"created_at": bson.M{"$lt": before},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+// TotalSizeByUploader returns the sum of the sizes of userID's uploads.
+func (r *MongoMediaFileRepo) TotalSizeByUploader(ctx context.Context, userID string) (int64, error) {
+	cur, err := r.coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"uploaded_by": userID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$size"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var groups []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+	if len(groups) == 0 {
+		return 0, nil
+	}
+	return groups[0].Total, nil
+}
+
+// MarkReady marks an upload as complete.
+func (r *MongoMediaFileRepo) MarkReady(ctx context.Context, id string, at time.Time) error {
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":     models.MediaFileReady,
+		"updated_at": at,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a record.
+func (r *MongoMediaFileRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetTranscode replaces the record's transcode job.
+func (r *MongoMediaFileRepo) SetTranscode(ctx context.Context, id string, t models.Transcode) error {
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"transcode": newMongoTranscode(t),
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimTranscode hands worker the longest-waiting queued or stale job.
+func (r *MongoMediaFileRepo) ClaimTranscode(ctx context.Context, worker string, at, staleBefore time.Time) (*models.MediaFile, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"transcode.status": models.TranscodeQueued},
+		bson.M{
+			"transcode.status":     models.TranscodeRunning,
+			"transcode.updated_at": bson.M{"$lt": staleBefore},
+		},
+	}}
+	update := bson.M{"$set": bson.M{"transcode": newMongoTranscode(models.Transcode{
+		Status:    models.TranscodeRunning,
+		Worker:    worker,
+		UpdatedAt: at,
+	})}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "transcode.updated_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var doc mongoMediaFile
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// UpdateTranscode replaces the job of a record while it is running for worker.
+func (r *MongoMediaFileRepo) UpdateTranscode(ctx context.Context, id, worker string, t models.Transcode) error {
+	res, err := r.coll.UpdateOne(ctx, bson.M{
+		"_id":              id,
+		"transcode.status": models.TranscodeRunning,
+		"transcode.worker": worker,
+	}, bson.M{"$set": bson.M{"transcode": newMongoTranscode(t)}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// find decodes the records matching filter.
+func (r *MongoMediaFileRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*models.MediaFile, error) {
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []mongoMediaFile
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	files := make([]*models.MediaFile, 0, len(docs))
+	for i := range docs {
+		files = append(files, docs[i].toModel())
+	}
+	return files, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoMediaSessionRepo implements MediaSessionRepository against MongoDB.
+type MongoMediaSessionRepo struct {
+	sessions     *mongo.Collection
+	participants *mongo.Collection
+}
+
+// NewMongoMediaSessionRepo creates a new MongoDB-backed media session repository.
+func NewMongoMediaSessionRepo(db *mongo.Database) *MongoMediaSessionRepo {
+	return &MongoMediaSessionRepo{
+		sessions:     db.Collection("media_sessions"),
+		participants: db.Collection("media_session_participants"),
+	}
+}
+
+// mongoMediaSession is the stored form of models.MediaSession.
+type mongoMediaSession struct {
+	ID        string     `bson:"_id"`
+	RoomID    string     `bson:"room_id"`
+	Type      string     `bson:"type"`
+	StartedBy string     `bson:"started_by"`
+	StartedAt time.Time  `bson:"started_at"`
+	EndedAt   *time.Time `bson:"ended_at"`
+	Metadata  []byte     `bson:"metadata,omitempty"`
+}
+
+// mongoMediaParticipant is the stored form of models.MediaSessionParticipant.
+type mongoMediaParticipant struct {
+	SessionID string     `bson:"session_id"`
+	UserID    string     `bson:"user_id"`
+	JoinedAt  time.Time  `bson:"joined_at"`
+	LeftAt    *time.Time `bson:"left_at"`
+}
+
+// Create inserts a new media session.
+func (r *MongoMediaSessionRepo) Create(ctx context.Context, session *models.MediaSession) error {
+	_, err := r.sessions.InsertOne(ctx, mongoMediaSession{
+		ID: session.ID, RoomID: session.RoomID, Type: session.Type, StartedBy: session.StartedBy,
+		StartedAt: session.StartedAt, Metadata: session.Metadata,
+	})
+	return err
+}
+
+// End marks a media session as ended.
+func (r *MongoMediaSessionRepo) End(ctx context.Context, sessionID string) error {
+	res, err := r.sessions.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "ended_at": nil},
+		bson.M{"$set": bson.M{"ended_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetActive returns currently active sessions in a room.
+func (r *MongoMediaSessionRepo) GetActive(ctx context.Context, roomID string) ([]*models.MediaSession, error) {
+	return r.find(ctx, bson.M{"room_id": roomID, "ended_at": nil},
+		options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}))
+}
+
+// GetByRoom returns past sessions for a room.
+func (r *MongoMediaSessionRepo) GetByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaSession, error) {
+	return r.find(ctx, bson.M{"room_id": roomID}, options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+}
+
+// AddParticipant records a user joining a media session.
+func (r *MongoMediaSessionRepo) AddParticipant(ctx context.Context, sessionID, userID string) error {
+	_, err := r.participants.InsertOne(ctx, mongoMediaParticipant{
+		SessionID: sessionID, UserID: userID, JoinedAt: time.Now(),
+	})
+	return err
+}
+
+// RemoveParticipant records a user leaving a media session.
+func (r *MongoMediaSessionRepo) RemoveParticipant(ctx context.Context, sessionID, userID string) error {
+	res, err := r.participants.UpdateMany(ctx,
+		bson.M{"session_id": sessionID, "user_id": userID, "left_at": nil},
+		bson.M{"$set": bson.M{"left_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// find returns the sessions matching filter.
+func (r *MongoMediaSessionRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*models.MediaSession, error) {
+	cur, err := r.sessions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoMediaSession
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	sessions := make([]*models.MediaSession, 0, len(docs))
+	for _, d := range docs {
+		sessions = append(sessions, &models.MediaSession{
+			ID: d.ID, RoomID: d.RoomID, Type: d.Type, StartedBy: d.StartedBy,
+			StartedAt: d.StartedAt, EndedAt: d.EndedAt, Metadata: d.Metadata,
+		})
+	}
+	return sessions, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoMessageRepo implements MessageRepository against MongoDB.
+type MongoMessageRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoMessageRepo creates a new MongoDB-backed message repository.
+func NewMongoMessageRepo(db *mongo.Database) *MongoMessageRepo {
+	return &MongoMessageRepo{coll: db.Collection("messages")}
+}
+
+// mongoMessage is the stored form of models.ChatMessage. The sender's
+// username is stored with the message instead of joined from users.
+type mongoMessage struct {
+	ID        string    `bson:"_id"`
+	RoomID    string    `bson:"room_id"`
+	SenderID  string    `bson:"sender_id"`
+	Sender    string    `bson:"sender"`
+	Type      string    `bson:"type"`
+	Content   string    `bson:"content"`
+	Metadata  []byte    `bson:"metadata,omitempty"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func (d *mongoMessage) toModel() *models.ChatMessage {
+	return &models.ChatMessage{
+		ID: d.ID, RoomID: d.RoomID, SenderID: d.SenderID, Sender: d.Sender,
+		Type: d.Type, Content: d.Content, Metadata: d.Metadata, CreatedAt: d.CreatedAt,
+	}
+}
+
+// Create inserts a new chat message.
+func (r *MongoMessageRepo) Create(ctx context.Context, msg *models.ChatMessage) error {
+	_, err := r.coll.InsertOne(ctx, mongoMessage{
+		ID: msg.ID, RoomID: msg.RoomID, SenderID: msg.SenderID, Sender: msg.Sender,
+		Type: msg.Type, Content: msg.Content, Metadata: msg.Metadata, CreatedAt: msg.CreatedAt,
+	})
+	return err
+}
+
+// GetByRoom returns messages for a room before a given timestamp, newest first.
+func (r *MongoMessageRepo) GetByRoom(ctx context.Context, roomID string, before time.Time, limit int) ([]*models.ChatMessage, error) {
+	return r.find(ctx,
+		bson.M{"room_id": roomID, "created_at": bson.M{"$lt": before}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit)))
+}
+
+// GetByRoomAfter returns messages for a room after a given timestamp, oldest first.
+func (r *MongoMessageRepo) GetByRoomAfter(ctx context.Context, roomID string, after time.Time, limit int) ([]*models.ChatMessage, error) {
+	return r.find(ctx,
+		bson.M{"room_id": roomID, "created_at": bson.M{"$gt": after}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(int64(limit)))
+}
+
+// find runs a message query and decodes the results.
+func (r *MongoMessageRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*models.ChatMessage, error) {
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoMessage
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	messages := make([]*models.ChatMessage, 0, len(docs))
+	for i := range docs {
+		messages = append(messages, docs[i].toModel())
+	}
+	return messages, nil
+}
+
+// GetByID retrieves a single message by ID.
+func (r *MongoMessageRepo) GetByID(ctx context.Context, id string) (*models.ChatMessage, error) {
+	var doc mongoMessage
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// Delete deletes a single message.
+func (r *MongoMessageRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByRoom deletes all messages of a room.
+func (r *MongoMessageRepo) DeleteByRoom(ctx context.Context, roomID string) (int, error) {
+	res, err := r.coll.DeleteMany(ctx, bson.M{"room_id": roomID})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
+
+// DeleteBefore deletes a room's messages created before the given time.
+func (r *MongoMessageRepo) DeleteBefore(ctx context.Context, roomID string, before time.Time) (int, error) {
+	res, err := r.coll.DeleteMany(ctx, bson.M{"room_id": roomID, "created_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
+
+// RenameSender rewrites the sender name on every message by senderID.
+func (r *MongoMessageRepo) RenameSender(ctx context.Context, senderID, name string) (int, error) {
+	res, err := r.coll.UpdateMany(ctx,
+		bson.M{"sender_id": senderID, "sender": bson.M{"$ne": name}},
+		bson.M{"$set": bson.M{"sender": name}})
+	if err != nil {
+		return 0, err
+	}
+	return int(res.ModifiedCount), nil
+}
+
+// CountBySender returns the number of stored messages per sender ID.
+func (r *MongoMessageRepo) CountBySender(ctx context.Context) (map[string]int, error) {
+	cur, err := r.coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$sender_id", "n": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		SenderID string `bson:"_id"`
+		N        int    `bson:"n"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(groups))
+	for _, g := range groups {
+		counts[g.SenderID] = g.N
+	}
+	return counts, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoMetadataRepo implements MetadataRepository against MongoDB.
+type MongoMetadataRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoMetadataRepo creates a new MongoDB-backed metadata cache.
+func NewMongoMetadataRepo(db *mongo.Database) *MongoMetadataRepo {
+	return &MongoMetadataRepo{coll: db.Collection("metadata_lookups")}
+}
+
+// mongoMetadataLookup is the stored form of models.MetadataLookup.
+type mongoMetadataLookup struct {
+	Key       string              `bson:"_id"`
+	Metadata  *mongoMediaMetadata `bson:"metadata,omitempty"`
+	FetchedAt time.Time           `bson:"fetched_at"`
+}
+
+// mongoMediaMetadata is the stored form of models.MediaMetadata.
+type mongoMediaMetadata struct {
+	Kind         string `bson:"kind"`
+	Title        string `bson:"title"`
+	Year         int    `bson:"year,omitempty"`
+	Season       int    `bson:"season,omitempty"`
+	Episode      int    `bson:"episode,omitempty"`
+	EpisodeTitle string `bson:"episode_title,omitempty"`
+	PosterURL    string `bson:"poster_url,omitempty"`
+	Runtime      int    `bson:"runtime,omitempty"`
+	Source       string `bson:"source"`
+	SourceID     string `bson:"source_id"`
+}
+
+// toMongoMetadata returns the stored form of m, nil if m is.
+func toMongoMetadata(m *models.MediaMetadata) *mongoMediaMetadata {
+	if m == nil {
+		return nil
+	}
+	d := mongoMediaMetadata(*m)
+	return &d
+}
+
+func (d *mongoMediaMetadata) toModel() *models.MediaMetadata {
+	if d == nil {
+		return nil
+	}
+	m := models.MediaMetadata(*d)
+	return &m
+}
+
+// Get retrieves the lookup of key.
+func (r *MongoMetadataRepo) Get(ctx context.Context, key string) (*models.MetadataLookup, error) {
+	var doc mongoMetadataLookup
+	if err := r.coll.FindOne(ctx, bson.M{"_id": key}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &models.MetadataLookup{Key: doc.Key, Metadata: doc.Metadata.toModel(), FetchedAt: doc.FetchedAt}, nil
+}
+
+// Put stores a lookup, replacing any earlier one of its key.
+func (r *MongoMetadataRepo) Put(ctx context.Context, lookup *models.MetadataLookup) error {
+	_, err := r.coll.ReplaceOne(ctx, bson.M{"_id": lookup.Key}, mongoMetadataLookup{
+		Key:       lookup.Key,
+		Metadata:  toMongoMetadata(lookup.Metadata),
+		FetchedAt: lookup.FetchedAt,
+	}, options.Replace().SetUpsert(true))
+	return err
+}
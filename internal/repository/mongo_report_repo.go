@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoReportRepo implements ReportRepository against MongoDB.
+type MongoReportRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoReportRepo creates a new MongoDB-backed report repository.
+func NewMongoReportRepo(db *mongo.Database) *MongoReportRepo {
+	return &MongoReportRepo{coll: db.Collection("reports")}
+}
+
+// mongoReport is the stored form of models.Report.
+type mongoReport struct {
+	ID         string    `bson:"_id"`
+	ReporterID string    `bson:"reporter_id"`
+	TargetType string    `bson:"target_type"`
+	TargetID   string    `bson:"target_id"`
+	RoomID     string    `bson:"room_id,omitempty"`
+	Reason     string    `bson:"reason"`
+	Details    string    `bson:"details,omitempty"`
+	Status     string    `bson:"status"`
+	CreatedAt  time.Time `bson:"created_at"`
+
+	Resolution *mongoReportResolution `bson:"resolution,omitempty"`
+}
+
+// mongoReportResolution is the stored form of models.ReportResolution.
+type mongoReportResolution struct {
+	Action     string     `bson:"action,omitempty"`
+	Note       string     `bson:"note,omitempty"`
+	ResolvedBy string     `bson:"resolved_by"`
+	ResolvedAt time.Time  `bson:"resolved_at"`
+	MutedUntil *time.Time `bson:"muted_until,omitempty"`
+}
+
+func (d *mongoReport) toModel() *models.Report {
+	rep := &models.Report{
+		ID: d.ID, ReporterID: d.ReporterID, TargetType: d.TargetType, TargetID: d.TargetID,
+		RoomID: d.RoomID, Reason: d.Reason, Details: d.Details, Status: d.Status, CreatedAt: d.CreatedAt,
+	}
+	if res := d.Resolution; res != nil {
+		rep.Resolution = &models.ReportResolution{
+			Action: res.Action, Note: res.Note, ResolvedBy: res.ResolvedBy, ResolvedAt: res.ResolvedAt,
+			MutedUntil: res.MutedUntil,
+		}
+	}
+	return rep
+}
+
+// Create stores a new report.
+func (r *MongoReportRepo) Create(ctx context.Context, report *models.Report) error {
+	_, err := r.coll.InsertOne(ctx, mongoReport{
+		ID: report.ID, ReporterID: report.ReporterID, TargetType: report.TargetType, TargetID: report.TargetID,
+		RoomID: report.RoomID, Reason: report.Reason, Details: report.Details, Status: report.Status,
+		CreatedAt: report.CreatedAt,
+	})
+	return err
+}
+
+// GetByID retrieves a report by ID.
+func (r *MongoReportRepo) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	var doc mongoReport
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns reports matching filter, newest first.
+func (r *MongoReportRepo) List(ctx context.Context, filter ReportFilter) ([]*models.Report, error) {
+	q := bson.M{}
+	if filter.Status != "" {
+		q["status"] = filter.Status
+	}
+	if filter.TargetType != "" {
+		q["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		q["target_id"] = filter.TargetID
+	}
+	if filter.ReporterID != "" {
+		q["reporter_id"] = filter.ReporterID
+	}
+	if filter.RoomID != "" {
+		q["room_id"] = filter.RoomID
+	}
+
+	cur, err := r.coll.Find(ctx, q, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoReport
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	reports := make([]*models.Report, 0, len(docs))
+	for i := range docs {
+		reports = append(reports, docs[i].toModel())
+	}
+	return reports, nil
+}
+
+// Resolve closes an open report.
+func (r *MongoReportRepo) Resolve(ctx context.Context, id, status string, res *models.ReportResolution) error {
+	result, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ReportStatusOpen},
+		bson.M{"$set": bson.M{"status": status, "resolution": mongoReportResolution{
+			Action: res.Action, Note: res.Note, ResolvedBy: res.ResolvedBy, ResolvedAt: res.ResolvedAt,
+			MutedUntil: res.MutedUntil,
+		}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoRoleRepo implements RoleRepository against MongoDB.
+type MongoRoleRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoRoleRepo creates a new MongoDB-backed role repository.
+func NewMongoRoleRepo(db *mongo.Database) *MongoRoleRepo {
+	return &MongoRoleRepo{coll: db.Collection("roles")}
+}
+
+// mongoRole is the stored form of models.RoleDefinition, keyed by name.
+type mongoRole struct {
+	Name        string    `bson:"_id"`
+	Description string    `bson:"description"`
+	Permissions []string  `bson:"permissions"`
+	CreatedAt   time.Time `bson:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+func (d *mongoRole) toModel() *models.RoleDefinition {
+	perms := d.Permissions
+	if perms == nil {
+		perms = []string{}
+	}
+	return &models.RoleDefinition{
+		Name: d.Name, Description: d.Description, Permissions: perms, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// Create stores a new role.
+func (r *MongoRoleRepo) Create(ctx context.Context, role *models.RoleDefinition) error {
+	_, err := r.coll.InsertOne(ctx, mongoRole{
+		Name: role.Name, Description: role.Description, Permissions: role.Permissions,
+		CreatedAt: role.CreatedAt, UpdatedAt: role.UpdatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// Update replaces a role's description and permissions.
+func (r *MongoRoleRepo) Update(ctx context.Context, role *models.RoleDefinition) error {
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": role.Name}, bson.M{"$set": bson.M{
+		"description": role.Description,
+		"permissions": role.Permissions,
+		"updated_at":  role.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a role.
+func (r *MongoRoleRepo) Delete(ctx context.Context, name string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Get retrieves a role by name.
+func (r *MongoRoleRepo) Get(ctx context.Context, name string) (*models.RoleDefinition, error) {
+	var doc mongoRole
+	if err := r.coll.FindOne(ctx, bson.M{"_id": name}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns every role, by name.
+func (r *MongoRoleRepo) List(ctx context.Context) ([]*models.RoleDefinition, error) {
+	cur, err := r.coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoRole
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	roles := make([]*models.RoleDefinition, 0, len(docs))
+	for i := range docs {
+		roles = append(roles, docs[i].toModel())
+	}
+	return roles, nil
+}
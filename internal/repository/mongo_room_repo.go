@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoRoomRepo implements RoomRepository against MongoDB. Memberships live
+// in their own collection, like the room_members table.
+type MongoRoomRepo struct {
+	rooms   *mongo.Collection
+	members *mongo.Collection
+}
+
+// NewMongoRoomRepo creates a new MongoDB-backed room repository.
+func NewMongoRoomRepo(db *mongo.Database) *MongoRoomRepo {
+	return &MongoRoomRepo{
+		rooms:   db.Collection("rooms"),
+		members: db.Collection("room_members"),
+	}
+}
+
+// mongoRoom is the stored form of models.Room.
+type mongoRoom struct {
+	ID          string          `bson:"_id"`
+	Name        string          `bson:"name"`
+	Description *string         `bson:"description,omitempty"`
+	Type        string          `bson:"type"`
+	CreatedBy   string          `bson:"created_by"`
+	IsActive    bool            `bson:"is_active"`
+	VideoState  mongoVideoState `bson:"video_state"`
+	MaxMembers  int             `bson:"max_members"`
+	Retention   *mongoRetention `bson:"retention,omitempty"`
+	Sync        *mongoSync      `bson:"sync_policy,omitempty"`
+	Screens     []mongoScreen   `bson:"screens,omitempty"`
+	Tags        []string        `bson:"tags,omitempty"`
+	CreatedAt   time.Time       `bson:"created_at"`
+	UpdatedAt   time.Time       `bson:"updated_at"`
+}
+
+// mongoVideoState is the stored form of models.VideoState.
+type mongoVideoState struct {
+	URL       string  `bson:"url"`
+	Playing   bool    `bson:"playing"`
+	Timestamp float64 `bson:"timestamp"`
+	Subtitle  string  `bson:"subtitle,omitempty"`
+}
+
+// mongoRetention is the stored form of models.RetentionPolicy.
+type mongoRetention struct {
+	Mode string `bson:"mode"`
+	Days int    `bson:"days,omitempty"`
+}
+
+// toMongoRetention converts a retention policy, keeping nil as nil.
+func toMongoRetention(p *models.RetentionPolicy) *mongoRetention {
+	if p == nil {
+		return nil
+	}
+	d := mongoRetention(*p)
+	return &d
+}
+
+// mongoSync is the stored form of models.SyncPolicy.
+type mongoSync struct {
+	ToleranceMs       int    `bson:"tolerance_ms"`
+	NudgeMs           int    `bson:"nudge_ms"`
+	PauseOnDisconnect string `bson:"pause_on_disconnect,omitempty"`
+}
+
+// toMongoSync converts a sync policy, keeping nil as nil.
+func toMongoSync(p *models.SyncPolicy) *mongoSync {
+	if p == nil {
+		return nil
+	}
+	d := mongoSync(*p)
+	return &d
+}
+
+// mongoScreen is the stored form of models.Screen.
+type mongoScreen struct {
+	ID      string `bson:"id"`
+	Name    string `bson:"name,omitempty"`
+	Control string `bson:"control,omitempty"`
+}
+
+// toMongoScreens converts a room's screens; none is stored as nothing.
+func toMongoScreens(screens []models.Screen) []mongoScreen {
+	if len(screens) == 0 {
+		return nil
+	}
+	d := make([]mongoScreen, len(screens))
+	for i, s := range screens {
+		d[i] = mongoScreen(s)
+	}
+	return d
+}
+
+// mongoRoomMember is the stored form of models.RoomMember. Username is
+// joined from users on read.
+type mongoRoomMember struct {
+	RoomID   string    `bson:"room_id"`
+	UserID   string    `bson:"user_id"`
+	Username string    `bson:"username,omitempty"`
+	Role     string    `bson:"role"`
+	JoinedAt time.Time `bson:"joined_at"`
+}
+
+func (d *mongoRoom) toModel() *models.Room {
+	room := &models.Room{
+		ID: d.ID, Name: d.Name, Description: d.Description, Type: d.Type,
+		CreatedBy: d.CreatedBy, IsActive: d.IsActive,
+		VideoState: models.VideoState(d.VideoState),
+		MaxMembers: d.MaxMembers, Tags: d.Tags, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt,
+	}
+	if d.Retention != nil {
+		p := models.RetentionPolicy(*d.Retention)
+		room.Retention = &p
+	}
+	if d.Sync != nil {
+		p := models.SyncPolicy(*d.Sync)
+		room.Sync = &p
+	}
+	for _, s := range d.Screens {
+		room.Screens = append(room.Screens, models.Screen(s))
+	}
+	return room
+}
+
+// Create inserts a new room.
+func (r *MongoRoomRepo) Create(ctx context.Context, room *models.Room) error {
+	_, err := r.rooms.InsertOne(ctx, mongoRoom{
+		ID: room.ID, Name: room.Name, Description: room.Description, Type: room.Type,
+		CreatedBy: room.CreatedBy, IsActive: room.IsActive,
+		VideoState: mongoVideoState(room.VideoState),
+		MaxMembers: room.MaxMembers, Retention: toMongoRetention(room.Retention), Sync: toMongoSync(room.Sync),
+		Screens: toMongoScreens(room.Screens), Tags: room.Tags,
+		CreatedAt: room.CreatedAt, UpdatedAt: room.UpdatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// GetByID retrieves a room by ID.
+func (r *MongoRoomRepo) GetByID(ctx context.Context, id string) (*models.Room, error) {
+	var doc mongoRoom
+	if err := r.rooms.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns rooms the user is a member of.
+func (r *MongoRoomRepo) List(ctx context.Context, userID string, limit, offset int) ([]*models.Room, error) {
+	cur, err := r.members.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetProjection(bson.M{"room_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var memberships []mongoRoomMember
+	if err := cur.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+	if len(memberships) == 0 {
+		return nil, nil
+	}
+
+	roomIDs := make([]string, len(memberships))
+	for i, m := range memberships {
+		roomIDs[i] = m.RoomID
+	}
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": roomIDs}, "is_active": true}, limit, offset)
+}
+
+// CountOwned returns how many active rooms userID owns.
+func (r *MongoRoomRepo) CountOwned(ctx context.Context, userID string) (int, error) {
+	cur, err := r.members.Find(ctx, bson.M{"user_id": userID, "role": models.RoomRoleOwner},
+		options.Find().SetProjection(bson.M{"room_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var memberships []mongoRoomMember
+	if err := cur.All(ctx, &memberships); err != nil {
+		return 0, err
+	}
+	if len(memberships) == 0 {
+		return 0, nil
+	}
+
+	roomIDs := make([]string, len(memberships))
+	for i, m := range memberships {
+		roomIDs[i] = m.RoomID
+	}
+	n, err := r.rooms.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": roomIDs}, "is_active": true})
+	return int(n), err
+}
+
+// ListPublic returns all active public rooms, or those tagged tag.
+func (r *MongoRoomRepo) ListPublic(ctx context.Context, tag string, limit, offset int) ([]*models.Room, error) {
+	filter := bson.M{"type": models.RoomTypePublic, "is_active": true}
+	if tag != "" {
+		filter["tags"] = tag // matches any element
+	}
+	return r.find(ctx, filter, limit, offset)
+}
+
+// TagCounts returns the tags of active public rooms created at or after
+// since, with how many of them carry each, most used first.
+func (r *MongoRoomRepo) TagCounts(ctx context.Context, since time.Time, limit int) ([]models.TagCount, error) {
+	cur, err := r.rooms.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"type": models.RoomTypePublic, "is_active": true, "created_at": bson.M{"$gte": since}}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "n": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "n", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		Tag string `bson:"_id"`
+		N   int    `bson:"n"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := make([]models.TagCount, len(groups))
+	for i, g := range groups {
+		counts[i] = models.TagCount{Tag: g.Tag, Rooms: g.N}
+	}
+	return counts, nil
+}
+
+// ListAll returns every room, including inactive ones, oldest first.
+func (r *MongoRoomRepo) ListAll(ctx context.Context, limit, offset int) ([]*models.Room, error) {
+	cur, err := r.rooms.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoRoom
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	rooms := make([]*models.Room, 0, len(docs))
+	for i := range docs {
+		rooms = append(rooms, docs[i].toModel())
+	}
+	return rooms, nil
+}
+
+// Update updates a room's mutable fields.
+func (r *MongoRoomRepo) Update(ctx context.Context, room *models.Room) error {
+	return r.set(ctx, room.ID, bson.M{
+		"name":        room.Name,
+		"description": room.Description,
+		"max_members": room.MaxMembers,
+		"tags":        room.Tags,
+	})
+}
+
+// Delete soft-deletes a room.
+func (r *MongoRoomRepo) Delete(ctx context.Context, id string) error {
+	return r.set(ctx, id, bson.M{"is_active": false})
+}
+
+// AddMember adds a user to a room. Adding an existing member is a no-op.
+func (r *MongoRoomRepo) AddMember(ctx context.Context, roomID, userID, role string) error {
+	_, err := r.members.UpdateOne(ctx,
+		bson.M{"room_id": roomID, "user_id": userID},
+		bson.M{"$setOnInsert": mongoRoomMember{RoomID: roomID, UserID: userID, Role: role, JoinedAt: time.Now()}},
+		options.UpdateOne().SetUpsert(true))
+	return err
+}
+
+// RemoveMember removes a user from a room.
+func (r *MongoRoomRepo) RemoveMember(ctx context.Context, roomID, userID string) error {
+	_, err := r.members.DeleteOne(ctx, bson.M{"room_id": roomID, "user_id": userID})
+	return err
+}
+
+// GetMembers returns all members of a room with their usernames.
+func (r *MongoRoomRepo) GetMembers(ctx context.Context, roomID string) ([]*models.RoomMember, error) {
+	cur, err := r.members.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"room_id": roomID}}},
+		{{Key: "$sort", Value: bson.M{"joined_at": 1}}},
+		{{Key: "$lookup", Value: bson.M{"from": "users", "localField": "user_id", "foreignField": "_id", "as": "user"}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$set", Value: bson.M{"username": "$user.username"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoRoomMember
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	members := make([]*models.RoomMember, 0, len(docs))
+	for _, d := range docs {
+		m := models.RoomMember(d)
+		members = append(members, &m)
+	}
+	return members, nil
+}
+
+// GetMemberRole returns the role of a user in a room.
+func (r *MongoRoomRepo) GetMemberRole(ctx context.Context, roomID, userID string) (string, error) {
+	var doc mongoRoomMember
+	err := r.members.FindOne(ctx, bson.M{"room_id": roomID, "user_id": userID}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return doc.Role, nil
+}
+
+// SetMemberRole changes the role of a room member.
+func (r *MongoRoomRepo) SetMemberRole(ctx context.Context, roomID, userID, role string) error {
+	res, err := r.members.UpdateOne(ctx,
+		bson.M{"room_id": roomID, "user_id": userID},
+		bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateVideoState updates the video sync state for a room.
+func (r *MongoRoomRepo) UpdateVideoState(ctx context.Context, roomID string, state models.VideoState) error {
+	return r.set(ctx, roomID, bson.M{"video_state": mongoVideoState(state)})
+}
+
+// UpdateRetention sets a room's message retention policy (nil = server default).
+func (r *MongoRoomRepo) UpdateRetention(ctx context.Context, roomID string, policy *models.RetentionPolicy) error {
+	return r.set(ctx, roomID, bson.M{"retention": toMongoRetention(policy)})
+}
+
+// UpdateSyncPolicy sets a room's sync tolerance (nil = server default).
+func (r *MongoRoomRepo) UpdateSyncPolicy(ctx context.Context, roomID string, policy *models.SyncPolicy) error {
+	return r.set(ctx, roomID, bson.M{"sync_policy": toMongoSync(policy)})
+}
+
+// UpdateScreens replaces a room's screens besides the main one (none if
+// empty).
+func (r *MongoRoomRepo) UpdateScreens(ctx context.Context, roomID string, screens []models.Screen) error {
+	return r.set(ctx, roomID, bson.M{"screens": toMongoScreens(screens)})
+}
+
+// find returns rooms matching filter, newest first.
+func (r *MongoRoomRepo) find(ctx context.Context, filter bson.M, limit, offset int) ([]*models.Room, error) {
+	cur, err := r.rooms.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoRoom
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	rooms := make([]*models.Room, 0, len(docs))
+	for i := range docs {
+		rooms = append(rooms, docs[i].toModel())
+	}
+	return rooms, nil
+}
+
+// set updates fields on one room and bumps updated_at.
+func (r *MongoRoomRepo) set(ctx context.Context, id string, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+	res, err := r.rooms.UpdateByID(ctx, id, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
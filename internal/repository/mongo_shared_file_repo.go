@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoSharedFileRepo implements SharedFileRepository against MongoDB.
+type MongoSharedFileRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoSharedFileRepo creates a new MongoDB-backed shared file repository.
+func NewMongoSharedFileRepo(db *mongo.Database) *MongoSharedFileRepo {
+	return &MongoSharedFileRepo{coll: db.Collection("shared_files")}
+}
+
+// mongoSharedFile is the stored form of models.SharedFile.
+type mongoSharedFile struct {
+	ID          string    `bson:"_id"`
+	RoomID      string    `bson:"room_id"`
+	UploadedBy  string    `bson:"uploaded_by"`
+	FileName    string    `bson:"file_name"`
+	FileSize    int64     `bson:"file_size"`
+	MimeType    string    `bson:"mime_type"`
+	StoragePath string    `bson:"storage_path"`
+	MessageID   *string   `bson:"message_id,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+// Create inserts file metadata.
+func (r *MongoSharedFileRepo) Create(ctx context.Context, file *models.SharedFile) error {
+	_, err := r.coll.InsertOne(ctx, mongoSharedFile(*file))
+	return err
+}
+
+// GetByRoom returns files shared in a room, newest first.
+func (r *MongoSharedFileRepo) GetByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.SharedFile, error) {
+	cur, err := r.coll.Find(ctx, bson.M{"room_id": roomID}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoSharedFile
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	files := make([]*models.SharedFile, 0, len(docs))
+	for _, d := range docs {
+		f := models.SharedFile(d)
+		files = append(files, &f)
+	}
+	return files, nil
+}
+
+// GetByID retrieves a file by ID.
+func (r *MongoSharedFileRepo) GetByID(ctx context.Context, id string) (*models.SharedFile, error) {
+	var doc mongoSharedFile
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	f := models.SharedFile(doc)
+	return &f, nil
+}
+
+// Delete removes a shared file record.
+func (r *MongoSharedFileRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
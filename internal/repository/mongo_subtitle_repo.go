@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoSubtitleRepo implements SubtitleRepository against MongoDB.
+type MongoSubtitleRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoSubtitleRepo creates a new MongoDB-backed subtitle repository.
+func NewMongoSubtitleRepo(db *mongo.Database) *MongoSubtitleRepo {
+	return &MongoSubtitleRepo{coll: db.Collection("subtitles")}
+}
+
+// mongoSubtitle is the stored form of models.Subtitle.
+type mongoSubtitle struct {
+	ID         string    `bson:"_id"`
+	MediaID    string    `bson:"media_id"`
+	RoomID     string    `bson:"room_id"`
+	UploadedBy string    `bson:"uploaded_by"`
+	Label      string    `bson:"label"`
+	Language   string    `bson:"language"`
+	Format     string    `bson:"format"`
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+func (d *mongoSubtitle) toModel() *models.Subtitle {
+	return &models.Subtitle{
+		ID:         d.ID,
+		MediaID:    d.MediaID,
+		RoomID:     d.RoomID,
+		UploadedBy: d.UploadedBy,
+		Label:      d.Label,
+		Language:   d.Language,
+		Format:     d.Format,
+		CreatedAt:  d.CreatedAt,
+	}
+}
+
+// Create inserts a track.
+func (r *MongoSubtitleRepo) Create(ctx context.Context, sub *models.Subtitle) error {
+	_, err := r.coll.InsertOne(ctx, mongoSubtitle{
+		ID:         sub.ID,
+		MediaID:    sub.MediaID,
+		RoomID:     sub.RoomID,
+		UploadedBy: sub.UploadedBy,
+		Label:      sub.Label,
+		Language:   sub.Language,
+		Format:     sub.Format,
+		CreatedAt:  sub.CreatedAt,
+	})
+	return err
+}
+
+// GetByID retrieves a track by ID.
+func (r *MongoSubtitleRepo) GetByID(ctx context.Context, id string) (*models.Subtitle, error) {
+	var doc mongoSubtitle
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// ListByMedia returns the tracks of a media file, oldest first.
+func (r *MongoSubtitleRepo) ListByMedia(ctx context.Context, mediaID string) ([]*models.Subtitle, error) {
+	return r.find(ctx, bson.M{"media_id": mediaID}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+// ListByRoom returns the tracks of a room's media, newest first.
+func (r *MongoSubtitleRepo) ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.Subtitle, error) {
+	return r.find(ctx, bson.M{"room_id": roomID}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+}
+
+// Delete removes a track.
+func (r *MongoSubtitleRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByMedia removes the tracks of a media file.
+func (r *MongoSubtitleRepo) DeleteByMedia(ctx context.Context, mediaID string) error {
+	_, err := r.coll.DeleteMany(ctx, bson.M{"media_id": mediaID})
+	return err
+}
+
+// find decodes the tracks matching filter.
+func (r *MongoSubtitleRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*models.Subtitle, error) {
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []mongoSubtitle
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	subs := make([]*models.Subtitle, 0, len(docs))
+	for i := range docs {
+		subs = append(subs, docs[i].toModel())
+	}
+	return subs, nil
+}
@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"ofenes/internal/database"
+	"ofenes/internal/repository"
+	"ofenes/internal/repository/repotest"
+
+	"github.com/google/uuid"
+)
+
+// TestMongoRepos runs against the MongoDB URL in TEST_MONGO_URL, each test
+// in a fresh database that is dropped afterwards.
+func TestMongoRepos(t *testing.T) {
+	mongoURL := os.Getenv("TEST_MONGO_URL")
+	if mongoURL == "" {
+		t.Skip("TEST_MONGO_URL not set")
+	}
+	ctx := context.Background()
+
+	repotest.Run(t, func(t *testing.T) repository.Repos {
+		name := "repotest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		db, err := database.ConnectMongo(ctx, mongoURL, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			db.Drop(ctx)
+			db.Client().Disconnect(ctx)
+		})
+		if err := database.MigrateMongo(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+		return repository.Repos{
+			Users:          repository.NewMongoUserRepo(db),
+			Rooms:          repository.NewMongoRoomRepo(db),
+			Messages:       repository.NewMongoMessageRepo(db),
+			Audit:          repository.NewMongoAuditRepo(db),
+			DeadLetters:    repository.NewMongoDeadLetterRepo(db),
+			Reports:        repository.NewMongoReportRepo(db),
+			WordFilters:    repository.NewMongoWordFilterRepo(db),
+			AllowedOrigins: repository.NewMongoAllowedOriginRepo(db),
+			Announcements:  repository.NewMongoAnnouncementRepo(db),
+			Instance:       repository.NewMongoInstanceRepo(db),
+			Invites:        repository.NewMongoInviteRepo(db),
+			Legal:          repository.NewMongoLegalRepo(db),
+			Roles:          repository.NewMongoRoleRepo(db),
+			MediaFiles:     repository.NewMongoMediaFileRepo(db),
+			Subtitles:      repository.NewMongoSubtitleRepo(db),
+			Library:        repository.NewMongoLibraryRepo(db),
+			Metadata:       repository.NewMongoMetadataRepo(db),
+		}
+	})
+}
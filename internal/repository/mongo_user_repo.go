@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/username"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoUserRepo implements UserRepository against MongoDB.
+type MongoUserRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoUserRepo creates a new MongoDB-backed user repository.
+func NewMongoUserRepo(db *mongo.Database) *MongoUserRepo {
+	return &MongoUserRepo{coll: db.Collection("users")}
+}
+
+// mongoUser is the stored form of models.User.
+type mongoUser struct {
+	ID           string     `bson:"_id"`
+	Username     string     `bson:"username"`
+	Skeleton     string     `bson:"username_skeleton"` // username.Skeleton(Username)
+	PasswordHash string     `bson:"password_hash"`
+	Role         string     `bson:"role"`
+	TrustLevel   string     `bson:"trust_level,omitempty"`
+	DisplayName  *string    `bson:"display_name,omitempty"`
+	AvatarURL    *string    `bson:"avatar_url,omitempty"`
+	Status       string     `bson:"status"`
+	Bio          *string    `bson:"bio,omitempty"`
+	Preferences  []byte     `bson:"preferences,omitempty"`
+	CreatedAt    time.Time  `bson:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at"`
+	DeletedAt    *time.Time `bson:"deleted_at,omitempty"`
+	AnonymizedAt *time.Time `bson:"anonymized_at,omitempty"`
+
+	ShadowBannedAt *time.Time `bson:"shadow_banned_at,omitempty"`
+}
+
+func (d *mongoUser) toModel() *models.User {
+	return &models.User{
+		ID: d.ID, Username: d.Username, PasswordHash: d.PasswordHash, Role: d.Role, TrustLevel: d.TrustLevel,
+		DisplayName: d.DisplayName, AvatarURL: d.AvatarURL, Status: d.Status, Bio: d.Bio,
+		Preferences: d.Preferences, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt, DeletedAt: d.DeletedAt,
+		AnonymizedAt: d.AnonymizedAt, ShadowBannedAt: d.ShadowBannedAt,
+	}
+}
+
+// Create inserts a new user. Returns ErrAlreadyExists if the username is taken.
+func (r *MongoUserRepo) Create(ctx context.Context, user *models.User) error {
+	_, err := r.coll.InsertOne(ctx, mongoUser{
+		ID: user.ID, Username: user.Username, Skeleton: username.Skeleton(user.Username), PasswordHash: user.PasswordHash, Role: user.Role, TrustLevel: user.TrustLevel,
+		DisplayName: user.DisplayName, AvatarURL: user.AvatarURL, Status: user.Status, Bio: user.Bio,
+		Preferences: user.Preferences, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// GetByID retrieves a user by ID. Returns ErrNotFound if missing or soft-deleted.
+func (r *MongoUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "deleted_at": nil})
+}
+
+// mongoUsernameCollation compares usernames without case. It must match
+// the collation of the unique username index in database/mongo.go, which
+// only serves queries that use it.
+var mongoUsernameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// GetByUsername retrieves a user by username, ignoring case. Returns ErrNotFound if missing or soft-deleted.
+func (r *MongoUserRepo) GetByUsername(ctx context.Context, name string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"username": username.Normalize(name), "deleted_at": nil},
+		options.FindOne().SetCollation(mongoUsernameCollation))
+}
+
+// GetBySkeleton retrieves a user whose username has the given skeleton. Returns ErrNotFound if none is active.
+func (r *MongoUserRepo) GetBySkeleton(ctx context.Context, skeleton string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"username_skeleton": skeleton, "deleted_at": nil})
+}
+
+// Update updates a user's profile fields.
+func (r *MongoUserRepo) Update(ctx context.Context, user *models.User) error {
+	return r.set(ctx, user.ID, bson.M{
+		"display_name": user.DisplayName,
+		"avatar_url":   user.AvatarURL,
+		"bio":          user.Bio,
+	})
+}
+
+// UpdateStatus sets the user's online status.
+func (r *MongoUserRepo) UpdateStatus(ctx context.Context, userID string, status string) error {
+	return r.set(ctx, userID, bson.M{"status": status})
+}
+
+// UpdatePreferences replaces the user's preferences JSON.
+func (r *MongoUserRepo) UpdatePreferences(ctx context.Context, userID string, prefs json.RawMessage) error {
+	return r.set(ctx, userID, bson.M{"preferences": []byte(prefs)})
+}
+
+// SetShadowBanned sets or clears a user's shadow ban. It uses a pipeline
+// update so an existing shadow_banned_at is kept.
+func (r *MongoUserRepo) SetShadowBanned(ctx context.Context, id string, banned bool) error {
+	now := time.Now()
+	update := mongo.Pipeline{{{Key: "$unset", Value: "shadow_banned_at"}}}
+	if banned {
+		update = mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"shadow_banned_at": bson.M{"$ifNull": bson.A{"$shadow_banned_at", now}},
+		}}}}
+	}
+	update = append(update, bson.D{{Key: "$set", Value: bson.M{"updated_at": now}}})
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": nil}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetTrustLevel sets a user's trust level.
+func (r *MongoUserRepo) SetTrustLevel(ctx context.Context, id, level string) error {
+	return r.set(ctx, id, bson.M{"trust_level": level})
+}
+
+// SetRole sets a user's role.
+func (r *MongoUserRepo) SetRole(ctx context.Context, id, role string) error {
+	return r.set(ctx, id, bson.M{"role": role})
+}
+
+// List returns the users matching filter.
+func (r *MongoUserRepo) List(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	key := "created_at"
+	if filter.Sort == UserSortUsername {
+		key = "username"
+	}
+	dir := 1
+	if filter.Desc {
+		dir = -1
+	}
+	return r.find(ctx, mongoUserFilter(filter), options.Find().
+		SetSort(bson.D{{Key: key, Value: dir}, {Key: "_id", Value: dir}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit)))
+}
+
+// CountByRole returns the number of users matching filter per role.
+func (r *MongoUserRepo) CountByRole(ctx context.Context, filter UserFilter) (map[string]int, error) {
+	filter.Role = ""
+	cur, err := r.coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: mongoUserFilter(filter)}},
+		{{Key: "$group", Value: bson.M{"_id": "$role", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Role  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
+
+// mongoUserFilter translates a UserFilter into a query document.
+func mongoUserFilter(f UserFilter) bson.M {
+	q := bson.M{}
+	if !f.IncludeDeleted {
+		q["deleted_at"] = nil
+	}
+	if f.Role != "" {
+		q["role"] = f.Role
+	}
+	if !f.CreatedAfter.IsZero() {
+		q["created_at"] = bson.M{"$gt": f.CreatedAfter}
+	}
+	if f.ShadowBanned {
+		q["shadow_banned_at"] = bson.M{"$ne": nil}
+	}
+	if f.UsernamePrefix != "" {
+		// An anchored, literal regex is served by the username index.
+		q["username"] = bson.M{"$regex": "^" + regexp.QuoteMeta(f.UsernamePrefix)}
+	}
+	return q
+}
+
+// Delete soft-deletes a user.
+func (r *MongoUserRepo) Delete(ctx context.Context, id string) error {
+	return r.set(ctx, id, bson.M{"deleted_at": time.Now(), "status": models.StatusOffline})
+}
+
+// Restore clears a user's soft-delete mark.
+func (r *MongoUserRepo) Restore(ctx context.Context, id string) error {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}, "anonymized_at": nil},
+		bson.M{"$unset": bson.M{"deleted_at": ""}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Anonymize replaces a user's personal data with pseudonym and soft-deletes them.
+// It uses a pipeline update so an existing deleted_at is kept.
+func (r *MongoUserRepo) Anonymize(ctx context.Context, id, pseudonym string) error {
+	now := time.Now()
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": id, "anonymized_at": nil}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"username":          pseudonym,
+			"username_skeleton": username.Skeleton(pseudonym),
+			"password_hash":     "",
+			"preferences":       []byte(`{}`),
+			"status":            models.StatusOffline,
+			"deleted_at":        bson.M{"$ifNull": bson.A{"$deleted_at", now}},
+			"anonymized_at":     now,
+			"updated_at":        now,
+		}}},
+		{{Key: "$unset", Value: bson.A{"display_name", "avatar_url", "bio"}}},
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByIDWithDeleted retrieves a user by ID whether or not it is soft-deleted.
+func (r *MongoUserRepo) GetByIDWithDeleted(ctx context.Context, id string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// ListDeleted returns soft-deleted users, most recently deleted first.
+func (r *MongoUserRepo) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	return r.find(ctx, bson.M{"deleted_at": bson.M{"$ne": nil}}, options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+}
+
+// find returns the users matching filter.
+func (r *MongoUserRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*models.User, error) {
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoUser
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	users := make([]*models.User, 0, len(docs))
+	for i := range docs {
+		users = append(users, docs[i].toModel())
+	}
+	return users, nil
+}
+
+// findOne returns the single user matching filter.
+func (r *MongoUserRepo) findOne(ctx context.Context, filter bson.M, opts ...options.Lister[options.FindOneOptions]) (*models.User, error) {
+	var doc mongoUser
+	if err := r.coll.FindOne(ctx, filter, opts...).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// set updates fields on one not soft-deleted user and bumps updated_at.
+func (r *MongoUserRepo) set(ctx context.Context, id string, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": nil}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoWordFilterRepo implements WordFilterRepository against MongoDB.
+type MongoWordFilterRepo struct {
+	coll *mongo.Collection
+}
+
+// NewMongoWordFilterRepo creates a new MongoDB-backed word filter repository.
+func NewMongoWordFilterRepo(db *mongo.Database) *MongoWordFilterRepo {
+	return &MongoWordFilterRepo{coll: db.Collection("word_filters")}
+}
+
+// mongoWordFilter is the stored form of models.WordFilter.
+type mongoWordFilter struct {
+	ID        string    `bson:"_id"`
+	Pattern   string    `bson:"pattern"`
+	Regex     bool      `bson:"regex"`
+	Action    string    `bson:"action"`
+	RoomID    string    `bson:"room_id,omitempty"`
+	CreatedBy string    `bson:"created_by"`
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func (d *mongoWordFilter) toModel() *models.WordFilter {
+	return &models.WordFilter{
+		ID: d.ID, Pattern: d.Pattern, Regex: d.Regex, Action: d.Action, RoomID: d.RoomID,
+		CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// Create stores a new filter.
+func (r *MongoWordFilterRepo) Create(ctx context.Context, filter *models.WordFilter) error {
+	_, err := r.coll.InsertOne(ctx, mongoWordFilter{
+		ID: filter.ID, Pattern: filter.Pattern, Regex: filter.Regex, Action: filter.Action, RoomID: filter.RoomID,
+		CreatedBy: filter.CreatedBy, CreatedAt: filter.CreatedAt, UpdatedAt: filter.UpdatedAt,
+	})
+	return err
+}
+
+// Update replaces a filter's pattern, regex flag, action and room.
+func (r *MongoWordFilterRepo) Update(ctx context.Context, filter *models.WordFilter) error {
+	update := bson.M{"$set": bson.M{
+		"pattern": filter.Pattern, "regex": filter.Regex, "action": filter.Action, "updated_at": filter.UpdatedAt,
+	}}
+	if filter.RoomID != "" {
+		update["$set"].(bson.M)["room_id"] = filter.RoomID
+	} else {
+		update["$unset"] = bson.M{"room_id": ""}
+	}
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": filter.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a filter.
+func (r *MongoWordFilterRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves a filter by ID.
+func (r *MongoWordFilterRepo) GetByID(ctx context.Context, id string) (*models.WordFilter, error) {
+	var doc mongoWordFilter
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return doc.toModel(), nil
+}
+
+// List returns every filter, oldest first.
+func (r *MongoWordFilterRepo) List(ctx context.Context) ([]*models.WordFilter, error) {
+	cur, err := r.coll.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []mongoWordFilter
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	filters := make([]*models.WordFilter, 0, len(docs))
+	for i := range docs {
+		filters = append(filters, docs[i].toModel())
+	}
+	return filters, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgAllowedOriginRepo implements AllowedOriginRepository against PostgreSQL.
+type PgAllowedOriginRepo struct {
+	db pgDB
+}
+
+// NewPgAllowedOriginRepo creates a new PostgreSQL-backed allowed origin repository.
+func NewPgAllowedOriginRepo(pool *pgxpool.Pool) *PgAllowedOriginRepo {
+	return &PgAllowedOriginRepo{db: pool}
+}
+
+// pgAllowedOriginColumns is the column list matched by scanAllowedOrigin.
+const pgAllowedOriginColumns = `id, origin, created_by, created_at`
+
+// Create stores a new origin.
+func (r *PgAllowedOriginRepo) Create(ctx context.Context, origin *models.AllowedOrigin) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO allowed_origins (id, origin, created_by, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, origin.ID, origin.Origin, origin.CreatedBy, origin.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes an origin.
+func (r *PgAllowedOriginRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM allowed_origins WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves an origin by ID.
+func (r *PgAllowedOriginRepo) GetByID(ctx context.Context, id string) (*models.AllowedOrigin, error) {
+	origin, err := scanAllowedOrigin(r.db.QueryRow(ctx, `SELECT `+pgAllowedOriginColumns+` FROM allowed_origins WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return origin, err
+}
+
+// List returns every origin, oldest first.
+func (r *PgAllowedOriginRepo) List(ctx context.Context) ([]*models.AllowedOrigin, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+pgAllowedOriginColumns+` FROM allowed_origins ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var origins []*models.AllowedOrigin
+	for rows.Next() {
+		origin, err := scanAllowedOrigin(rows)
+		if err != nil {
+			return nil, err
+		}
+		origins = append(origins, origin)
+	}
+	return origins, rows.Err()
+}
+
+// scanAllowedOrigin scans the columns in pgAllowedOriginColumns.
+func scanAllowedOrigin(row pgx.Row) (*models.AllowedOrigin, error) {
+	var o models.AllowedOrigin
+	if err := row.Scan(&o.ID, &o.Origin, &o.CreatedBy, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
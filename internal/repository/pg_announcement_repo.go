@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgAnnouncementRepo implements AnnouncementRepository against PostgreSQL.
+type PgAnnouncementRepo struct {
+	db pgDB
+}
+
+// NewPgAnnouncementRepo creates a new PostgreSQL-backed announcement repository.
+func NewPgAnnouncementRepo(pool *pgxpool.Pool) *PgAnnouncementRepo {
+	return &PgAnnouncementRepo{db: pool}
+}
+
+// pgAnnouncementColumns is the column list matched by scanAnnouncement.
+const pgAnnouncementColumns = `id, title, body, level, starts_at, ends_at, created_by, created_at, updated_at`
+
+// Create stores a new announcement.
+func (r *PgAnnouncementRepo) Create(ctx context.Context, a *models.Announcement) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO announcements (id, title, body, level, starts_at, ends_at, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, a.ID, a.Title, a.Body, a.Level, a.StartsAt, a.EndsAt, a.CreatedBy, a.CreatedAt, a.UpdatedAt)
+	return err
+}
+
+// Update replaces an announcement's title, body, level and schedule.
+func (r *PgAnnouncementRepo) Update(ctx context.Context, a *models.Announcement) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE announcements SET title = $2, body = $3, level = $4, starts_at = $5, ends_at = $6, updated_at = $7
+		WHERE id = $1
+	`, a.ID, a.Title, a.Body, a.Level, a.StartsAt, a.EndsAt, a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes an announcement; its dismissals go with it (ON DELETE
+// CASCADE).
+func (r *PgAnnouncementRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves an announcement by ID.
+func (r *PgAnnouncementRepo) GetByID(ctx context.Context, id string) (*models.Announcement, error) {
+	a, err := scanAnnouncement(r.db.QueryRow(ctx, `SELECT `+pgAnnouncementColumns+` FROM announcements WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return a, err
+}
+
+// List returns every announcement, latest start first.
+func (r *PgAnnouncementRepo) List(ctx context.Context) ([]*models.Announcement, error) {
+	return r.query(ctx, `SELECT `+pgAnnouncementColumns+` FROM announcements ORDER BY starts_at DESC, id DESC`)
+}
+
+// ListCurrent returns the announcements that have not ended at t, earliest
+// start first.
+func (r *PgAnnouncementRepo) ListCurrent(ctx context.Context, t time.Time) ([]*models.Announcement, error) {
+	return r.query(ctx, `
+		SELECT `+pgAnnouncementColumns+` FROM announcements
+		WHERE ends_at IS NULL OR ends_at > $1
+		ORDER BY starts_at, id
+	`, t)
+}
+
+// query runs a SELECT of pgAnnouncementColumns.
+func (r *PgAnnouncementRepo) query(ctx context.Context, sql string, args ...any) ([]*models.Announcement, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []*models.Announcement
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, rows.Err()
+}
+
+// Dismiss records that userID dismissed announcement id at t.
+func (r *PgAnnouncementRepo) Dismiss(ctx context.Context, id, userID string, t time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO announcement_dismissals (announcement_id, user_id, dismissed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, announcement_id) DO NOTHING
+	`, id, userID, t)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListDismissed returns the IDs of the announcements userID dismissed.
+func (r *PgAnnouncementRepo) ListDismissed(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT announcement_id::text FROM announcement_dismissals WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// scanAnnouncement scans the columns in pgAnnouncementColumns.
+func scanAnnouncement(row pgx.Row) (*models.Announcement, error) {
+	var a models.Announcement
+	err := row.Scan(&a.ID, &a.Title, &a.Body, &a.Level, &a.StartsAt, &a.EndsAt, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgAuditRepo implements AuditRepository against PostgreSQL.
+type PgAuditRepo struct {
+	db pgDB
+}
+
+// NewPgAuditRepo creates a new PostgreSQL-backed audit repository.
+func NewPgAuditRepo(pool *pgxpool.Pool) *PgAuditRepo {
+	return &PgAuditRepo{db: pool}
+}
+
+// Create appends an entry.
+func (r *PgAuditRepo) Create(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO audit_log (id, actor_id, action, target_type, target_id, details, created_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $7)
+	`, entry.ID, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.Details, entry.CreatedAt)
+	return err
+}
+
+// List returns entries matching filter, newest first.
+func (r *PgAuditRepo) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.ActorID != "" {
+		add("actor_id = $%d", filter.ActorID) // must be a UUID
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+
+	sql := `SELECT id, COALESCE(actor_id::text, ''), action, target_type, target_id, details, created_at FROM audit_log`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	sql += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// CountBefore returns how many entries were created before the given time.
+func (r *PgAuditRepo) CountBefore(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM audit_log WHERE created_at < $1`, before).Scan(&n)
+	return n, err
+}
+
+// DeleteBefore deletes entries created before the given time.
+func (r *PgAuditRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM audit_log WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
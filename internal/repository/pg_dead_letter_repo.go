@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgDeadLetterRepo implements DeadLetterRepository against PostgreSQL.
+type PgDeadLetterRepo struct {
+	db pgDB
+}
+
+// NewPgDeadLetterRepo creates a new PostgreSQL-backed dead letter repository.
+func NewPgDeadLetterRepo(pool *pgxpool.Pool) *PgDeadLetterRepo {
+	return &PgDeadLetterRepo{db: pool}
+}
+
+// Create appends a dead letter.
+func (r *PgDeadLetterRepo) Create(ctx context.Context, l *models.DeadLetter) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO dead_letters (id, user_id, username, room_id, type, message_id, code, reason, data, truncated, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, l.ID, l.UserID, l.Username, l.RoomID, l.Type, l.MessageID, l.Code, l.Reason, l.Data, l.Truncated, l.CreatedAt)
+	return err
+}
+
+// List returns dead letters matching filter, newest first.
+func (r *PgDeadLetterRepo) List(ctx context.Context, filter DeadLetterFilter) ([]*models.DeadLetter, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.UserID != "" {
+		add("user_id = $%d", filter.UserID)
+	}
+	if filter.RoomID != "" {
+		add("room_id = $%d", filter.RoomID)
+	}
+	if filter.Code != "" {
+		add("code = $%d", filter.Code)
+	}
+
+	sql := `SELECT id, user_id, username, room_id, type, message_id, code, reason, data, truncated, created_at FROM dead_letters`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	sql += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []*models.DeadLetter
+	for rows.Next() {
+		var l models.DeadLetter
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Username, &l.RoomID, &l.Type, &l.MessageID, &l.Code, &l.Reason, &l.Data, &l.Truncated, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		letters = append(letters, &l)
+	}
+	return letters, rows.Err()
+}
+
+// CountBefore returns how many dead letters were created before the given time.
+func (r *PgDeadLetterRepo) CountBefore(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM dead_letters WHERE created_at < $1`, before).Scan(&n)
+	return n, err
+}
+
+// DeleteBefore deletes dead letters created before the given time.
+func (r *PgDeadLetterRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM dead_letters WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgInstanceRepo implements InstanceRepository against PostgreSQL.
+type PgInstanceRepo struct {
+	db pgDB
+}
+
+// NewPgInstanceRepo creates a new PostgreSQL-backed instance metadata repository.
+func NewPgInstanceRepo(pool *pgxpool.Pool) *PgInstanceRepo {
+	return &PgInstanceRepo{db: pool}
+}
+
+// Get retrieves the saved instance metadata.
+func (r *PgInstanceRepo) Get(ctx context.Context) (*models.Instance, error) {
+	var instance models.Instance
+	err := r.db.QueryRow(ctx, `
+		SELECT name, description, logo_url, motd, contact, COALESCE(updated_by::text, ''), updated_at
+		FROM instance WHERE id
+	`).Scan(&instance.Name, &instance.Description, &instance.LogoURL, &instance.MOTD, &instance.Contact,
+		&instance.UpdatedBy, &instance.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// Put saves the instance metadata, replacing what was saved before.
+func (r *PgInstanceRepo) Put(ctx context.Context, instance *models.Instance) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO instance (id, name, description, logo_url, motd, contact, updated_by, updated_at)
+		VALUES (TRUE, $1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, description = EXCLUDED.description, logo_url = EXCLUDED.logo_url,
+			motd = EXCLUDED.motd, contact = EXCLUDED.contact,
+			updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+	`, instance.Name, instance.Description, instance.LogoURL, instance.MOTD, instance.Contact,
+		instance.UpdatedBy, instance.UpdatedAt)
+	return err
+}
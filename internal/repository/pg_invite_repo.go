@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgInviteRepo implements InviteRepository against PostgreSQL.
+type PgInviteRepo struct {
+	db pgDB
+}
+
+// NewPgInviteRepo creates a new PostgreSQL-backed invite repository.
+func NewPgInviteRepo(pool *pgxpool.Pool) *PgInviteRepo {
+	return &PgInviteRepo{db: pool}
+}
+
+// pgInviteColumns is the column list matched by scanInvite.
+const pgInviteColumns = `id, code, max_uses, uses, expires_at, created_by, created_at`
+
+// Create stores a new invite.
+func (r *PgInviteRepo) Create(ctx context.Context, invite *models.Invite) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO invites (id, code, max_uses, uses, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, invite.ID, invite.Code, invite.MaxUses, invite.Uses, invite.ExpiresAt, invite.CreatedBy, invite.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes an invite.
+func (r *PgInviteRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM invites WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves an invite by ID.
+func (r *PgInviteRepo) GetByID(ctx context.Context, id string) (*models.Invite, error) {
+	invite, err := scanInvite(r.db.QueryRow(ctx, `SELECT `+pgInviteColumns+` FROM invites WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return invite, err
+}
+
+// List returns every invite, newest first.
+func (r *PgInviteRepo) List(ctx context.Context) ([]*models.Invite, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+pgInviteColumns+` FROM invites ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []*models.Invite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// Redeem uses up the invite with code once. The conditions are checked in
+// the UPDATE itself, so concurrent registrations can't overuse it.
+func (r *PgInviteRepo) Redeem(ctx context.Context, code string, now time.Time) (*models.Invite, error) {
+	invite, err := scanInvite(r.db.QueryRow(ctx, `
+		UPDATE invites SET uses = uses + 1
+		WHERE code = $1 AND uses < max_uses AND (expires_at IS NULL OR expires_at > $2)
+		RETURNING `+pgInviteColumns, code, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return invite, err
+}
+
+// scanInvite scans the columns in pgInviteColumns.
+func scanInvite(row pgx.Row) (*models.Invite, error) {
+	var i models.Invite
+	if err := row.Scan(&i.ID, &i.Code, &i.MaxUses, &i.Uses, &i.ExpiresAt, &i.CreatedBy, &i.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
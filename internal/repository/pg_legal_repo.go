@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgLegalRepo implements LegalRepository against PostgreSQL.
+type PgLegalRepo struct {
+	db pgDB
+}
+
+// NewPgLegalRepo creates a new PostgreSQL-backed legal document repository.
+func NewPgLegalRepo(pool *pgxpool.Pool) *PgLegalRepo {
+	return &PgLegalRepo{db: pool}
+}
+
+// pgLegalDocumentColumns is the column list matched by scanLegalDocument.
+const pgLegalDocumentColumns = `kind, version, title, body, published_by, published_at`
+
+// Publish stores a new version of a document.
+func (r *PgLegalRepo) Publish(ctx context.Context, doc *models.LegalDocument) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO legal_documents (kind, version, title, body, published_by, published_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, doc.Kind, doc.Version, doc.Title, doc.Body, doc.PublishedBy, doc.PublishedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Current returns the newest published version of kind.
+func (r *PgLegalRepo) Current(ctx context.Context, kind string) (*models.LegalDocument, error) {
+	doc, err := scanLegalDocument(r.db.QueryRow(ctx, `
+		SELECT `+pgLegalDocumentColumns+` FROM legal_documents WHERE kind = $1
+		ORDER BY published_at DESC, version DESC LIMIT 1
+	`, kind))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return doc, err
+}
+
+// GetVersion retrieves a version of kind.
+func (r *PgLegalRepo) GetVersion(ctx context.Context, kind, version string) (*models.LegalDocument, error) {
+	doc, err := scanLegalDocument(r.db.QueryRow(ctx, `
+		SELECT `+pgLegalDocumentColumns+` FROM legal_documents WHERE kind = $1 AND version = $2
+	`, kind, version))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return doc, err
+}
+
+// ListVersions returns every version of kind, newest first.
+func (r *PgLegalRepo) ListVersions(ctx context.Context, kind string) ([]*models.LegalDocument, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+pgLegalDocumentColumns+` FROM legal_documents WHERE kind = $1
+		ORDER BY published_at DESC, version DESC
+	`, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*models.LegalDocument
+	for rows.Next() {
+		doc, err := scanLegalDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// Accept records an acceptance unless that version was accepted before.
+func (r *PgLegalRepo) Accept(ctx context.Context, a *models.LegalAcceptance) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO legal_acceptances (user_id, kind, version, accepted_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, kind, version) DO NOTHING
+	`, a.UserID, a.Kind, a.Version, a.AcceptedAt)
+	return err
+}
+
+// HasAccepted reports whether userID accepted that version of kind.
+func (r *PgLegalRepo) HasAccepted(ctx context.Context, userID, kind, version string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM legal_acceptances WHERE user_id = $1 AND kind = $2 AND version = $3)
+	`, userID, kind, version).Scan(&ok)
+	return ok, err
+}
+
+// ListAcceptances returns everything userID accepted, newest first.
+func (r *PgLegalRepo) ListAcceptances(ctx context.Context, userID string) ([]*models.LegalAcceptance, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id, kind, version, accepted_at FROM legal_acceptances WHERE user_id = $1
+		ORDER BY accepted_at DESC, kind, version DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accepted []*models.LegalAcceptance
+	for rows.Next() {
+		var a models.LegalAcceptance
+		if err := rows.Scan(&a.UserID, &a.Kind, &a.Version, &a.AcceptedAt); err != nil {
+			return nil, err
+		}
+		accepted = append(accepted, &a)
+	}
+	return accepted, rows.Err()
+}
+
+// scanLegalDocument scans the columns in pgLegalDocumentColumns.
+func scanLegalDocument(row pgx.Row) (*models.LegalDocument, error) {
+	var d models.LegalDocument
+	if err := row.Scan(&d.Kind, &d.Version, &d.Title, &d.Body, &d.PublishedBy, &d.PublishedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
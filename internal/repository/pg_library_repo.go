@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgLibraryRepo implements LibraryRepository against PostgreSQL.
+type PgLibraryRepo struct {
+	db pgDB
+}
+
+// NewPgLibraryRepo creates a new PostgreSQL-backed library repository.
+func NewPgLibraryRepo(pool *pgxpool.Pool) *PgLibraryRepo {
+	return &PgLibraryRepo{db: pool}
+}
+
+const pgLibraryColumns = `id, COALESCE(owner_id::text, ''), COALESCE(room_id::text, ''), added_by,
+	title, url, folder, tags, metadata, created_at, updated_at`
+
+// Create inserts an item.
+func (r *PgLibraryRepo) Create(ctx context.Context, item *models.LibraryItem) error {
+	metadataJSON, err := marshalMetadata(item.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO library_items (id, owner_id, room_id, added_by, title, url, folder, tags, metadata, created_at, updated_at)
+		VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, item.ID, item.OwnerID, item.RoomID, item.AddedBy, item.Title, item.URL, item.Folder,
+		pgTags(item.Tags), metadataJSON, item.CreatedAt, item.UpdatedAt)
+	return err
+}
+
+// GetByID retrieves an item by ID.
+func (r *PgLibraryRepo) GetByID(ctx context.Context, id string) (*models.LibraryItem, error) {
+	item, err := scanLibraryItem(r.db.QueryRow(ctx, `
+		SELECT `+pgLibraryColumns+` FROM library_items WHERE id = $1
+	`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// Update replaces the editable fields of an item.
+func (r *PgLibraryRepo) Update(ctx context.Context, item *models.LibraryItem) error {
+	metadataJSON, err := marshalMetadata(item.Metadata)
+	if err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, `
+		UPDATE library_items
+		SET title = $2, url = $3, folder = $4, tags = $5, metadata = $6, updated_at = $7
+		WHERE id = $1
+	`, item.ID, item.Title, item.URL, item.Folder, pgTags(item.Tags), metadataJSON, item.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes an item.
+func (r *PgLibraryRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM library_items WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns items matching filter, newest first.
+func (r *PgLibraryRepo) List(ctx context.Context, filter LibraryFilter) ([]*models.LibraryItem, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.OwnerID != "" {
+		add("owner_id = $%d", filter.OwnerID) // must be a UUID
+	}
+	if filter.RoomID != "" {
+		add("room_id = $%d", filter.RoomID) // must be a UUID
+	}
+	if filter.Folder != "" {
+		add("folder = $%d", filter.Folder)
+	}
+	if filter.Tag != "" {
+		add("$%d = ANY(tags)", filter.Tag)
+	}
+	if filter.Query != "" {
+		// Escape LIKE wildcards so the query matches literally.
+		add("(title ILIKE $%[1]d OR url ILIKE $%[1]d)", "%"+likeEscaper.Replace(filter.Query)+"%")
+	}
+
+	sql := `SELECT ` + pgLibraryColumns + ` FROM library_items`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	sql += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.LibraryItem
+	for rows.Next() {
+		item, err := scanLibraryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Count returns how many items a user or room has.
+func (r *PgLibraryRepo) Count(ctx context.Context, ownerID, roomID string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT count(*) FROM library_items
+		WHERE owner_id = NULLIF($1, '')::uuid OR room_id = NULLIF($2, '')::uuid
+	`, ownerID, roomID).Scan(&n)
+	return n, err
+}
+
+// pgTags returns tags for a NOT NULL array column: nil slices encode as NULL.
+func pgTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// scanLibraryItem scans the columns in pgLibraryColumns.
+func scanLibraryItem(row pgx.Row) (*models.LibraryItem, error) {
+	var (
+		item         models.LibraryItem
+		metadataJSON []byte
+		err          error
+	)
+	if err := row.Scan(
+		&item.ID, &item.OwnerID, &item.RoomID, &item.AddedBy,
+		&item.Title, &item.URL, &item.Folder, &item.Tags, &metadataJSON, &item.CreatedAt, &item.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if item.Metadata, err = unmarshalMetadata(metadataJSON); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
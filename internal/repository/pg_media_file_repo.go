@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgMediaFileRepo implements MediaFileRepository against PostgreSQL.
+type PgMediaFileRepo struct {
+	db pgDB
+}
+
+// NewPgMediaFileRepo creates a new PostgreSQL-backed media file repository.
+func NewPgMediaFileRepo(pool *pgxpool.Pool) *PgMediaFileRepo {
+	return &PgMediaFileRepo{db: pool}
+}
+
+const pgMediaFileColumns = `id, room_id, uploaded_by, file_name, mime_type, size, status, created_at, updated_at,
+	transcode_status, transcode_progress, transcode_worker, transcode_error, transcode_updated_at`
+
+// Create inserts a record. New uploads have no transcode job.
+func (r *PgMediaFileRepo) Create(ctx context.Context, file *models.MediaFile) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO media_files (id, room_id, uploaded_by, file_name, mime_type, size, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, file.ID, file.RoomID, file.UploadedBy, file.FileName, file.MimeType,
+		file.Size, file.Status, file.CreatedAt, file.UpdatedAt)
+	return err
+}
+
+// GetByID retrieves a record by ID.
+func (r *PgMediaFileRepo) GetByID(ctx context.Context, id string) (*models.MediaFile, error) {
+	f, err := scanMediaFile(r.db.QueryRow(ctx, `
+		SELECT `+pgMediaFileColumns+` FROM media_files WHERE id = $1
+	`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// ListByRoom returns a room's media, newest first.
+func (r *PgMediaFileRepo) ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaFile, error) {
+	return r.list(ctx, `
+		SELECT `+pgMediaFileColumns+`
+		FROM media_files
+		WHERE room_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, roomID, limit, offset)
+}
+
+// ListUploadingBefore returns unfinished uploads started before the cutoff, oldest first.
+func (r *PgMediaFileRepo) ListUploadingBefore(ctx context.Context, before time.Time) ([]*models.MediaFile, error) {
+	return r.list(ctx, `
+		SELECT `+pgMediaFileColumns+`
+		FROM media_files
+		WHERE status = $1 AND created_at < $2
+		ORDER BY created_at
+	`, models.MediaFileUploading, before)
+}
+
+// TotalSizeByUploader returns the sum of the sizes of userID's uploads.
+func (r *PgMediaFileRepo) TotalSizeByUploader(ctx context.Context, userID string) (int64, error) {
+	var total int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(size), 0)::bigint FROM media_files WHERE uploaded_by = $1
+	`, userID).Scan(&total)
+	return total, err
+}
+
+// MarkReady marks an upload as complete.
+func (r *PgMediaFileRepo) MarkReady(ctx context.Context, id string, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE media_files SET status = $2, updated_at = $3 WHERE id = $1
+	`, id, models.MediaFileReady, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a record.
+func (r *PgMediaFileRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM media_files WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetTranscode replaces the record's transcode job.
+func (r *PgMediaFileRepo) SetTranscode(ctx context.Context, id string, t models.Transcode) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE media_files
+		SET transcode_status = $2, transcode_progress = $3, transcode_worker = $4,
+			transcode_error = $5, transcode_updated_at = $6
+		WHERE id = $1
+	`, id, t.Status, t.Progress, t.Worker, t.Error, t.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimTranscode hands worker the longest-waiting queued or stale job.
+// SKIP LOCKED lets concurrent workers claim different jobs.
+func (r *PgMediaFileRepo) ClaimTranscode(ctx context.Context, worker string, at, staleBefore time.Time) (*models.MediaFile, error) {
+	f, err := scanMediaFile(r.db.QueryRow(ctx, `
+		UPDATE media_files
+		SET transcode_status = $1, transcode_progress = 0, transcode_worker = $2,
+			transcode_error = '', transcode_updated_at = $3
+		WHERE id = (
+			SELECT id FROM media_files
+			WHERE transcode_status = $4
+				OR (transcode_status = $1 AND transcode_updated_at < $5)
+			ORDER BY transcode_updated_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+pgMediaFileColumns+`
+	`, models.TranscodeRunning, worker, at, models.TranscodeQueued, staleBefore))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// UpdateTranscode replaces the job of a record while it is running for worker.
+func (r *PgMediaFileRepo) UpdateTranscode(ctx context.Context, id, worker string, t models.Transcode) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE media_files
+		SET transcode_status = $4, transcode_progress = $5, transcode_worker = $6,
+			transcode_error = $7, transcode_updated_at = $8
+		WHERE id = $1 AND transcode_status = $2 AND transcode_worker = $3
+	`, id, models.TranscodeRunning, worker, t.Status, t.Progress, t.Worker, t.Error, t.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// list runs a query selecting pgMediaFileColumns.
+func (r *PgMediaFileRepo) list(ctx context.Context, query string, args ...any) ([]*models.MediaFile, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.MediaFile
+	for rows.Next() {
+		f, err := scanMediaFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// scanMediaFile scans the columns in pgMediaFileColumns.
+func scanMediaFile(row pgx.Row) (*models.MediaFile, error) {
+	var f models.MediaFile
+	var t models.Transcode
+	var status *string
+	var updatedAt *time.Time
+	if err := row.Scan(
+		&f.ID, &f.RoomID, &f.UploadedBy, &f.FileName, &f.MimeType,
+		&f.Size, &f.Status, &f.CreatedAt, &f.UpdatedAt,
+		&status, &t.Progress, &t.Worker, &t.Error, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if status != nil {
+		t.Status = *status
+		if updatedAt != nil {
+			t.UpdatedAt = *updatedAt
+		}
+		f.Transcode = &t
+	}
+	return &f, nil
+}
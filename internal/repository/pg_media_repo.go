@@ -11,17 +11,17 @@ import (
 
 // PgMediaSessionRepo implements MediaSessionRepository against PostgreSQL.
 type PgMediaSessionRepo struct {
-	pool *pgxpool.Pool
+	db pgDB
 }
 
 // NewPgMediaSessionRepo creates a new PostgreSQL-backed media session repository.
 func NewPgMediaSessionRepo(pool *pgxpool.Pool) *PgMediaSessionRepo {
-	return &PgMediaSessionRepo{pool: pool}
+	return &PgMediaSessionRepo{db: pool}
 }
 
 // Create inserts a new media session.
 func (r *PgMediaSessionRepo) Create(ctx context.Context, session *models.MediaSession) error {
-	_, err := r.pool.Exec(ctx, `
+	_, err := r.db.Exec(ctx, `
 		INSERT INTO media_sessions (id, room_id, type, started_by, started_at, metadata)
 		VALUES ($1, $2, $3, $4, $5, $6)
 	`, session.ID, session.RoomID, session.Type, session.StartedBy, session.StartedAt, session.Metadata)
@@ -30,7 +30,7 @@ func (r *PgMediaSessionRepo) Create(ctx context.Context, session *models.MediaSe
 
 // End marks a media session as ended.
 func (r *PgMediaSessionRepo) End(ctx context.Context, sessionID string) error {
-	tag, err := r.pool.Exec(ctx, `
+	tag, err := r.db.Exec(ctx, `
 		UPDATE media_sessions SET ended_at = now() WHERE id = $1 AND ended_at IS NULL
 	`, sessionID)
 	if err != nil {
@@ -44,7 +44,7 @@ func (r *PgMediaSessionRepo) End(ctx context.Context, sessionID string) error {
 
 // GetActive returns currently active sessions in a room.
 func (r *PgMediaSessionRepo) GetActive(ctx context.Context, roomID string) ([]*models.MediaSession, error) {
-	rows, err := r.pool.Query(ctx, `
+	rows, err := r.db.Query(ctx, `
 		SELECT id, room_id, type, started_by, started_at, ended_at, metadata
 		FROM media_sessions
 		WHERE room_id = $1 AND ended_at IS NULL
@@ -60,7 +60,7 @@ func (r *PgMediaSessionRepo) GetActive(ctx context.Context, roomID string) ([]*m
 
 // GetByRoom returns past sessions for a room.
 func (r *PgMediaSessionRepo) GetByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.MediaSession, error) {
-	rows, err := r.pool.Query(ctx, `
+	rows, err := r.db.Query(ctx, `
 		SELECT id, room_id, type, started_by, started_at, ended_at, metadata
 		FROM media_sessions
 		WHERE room_id = $1
@@ -77,7 +77,7 @@ func (r *PgMediaSessionRepo) GetByRoom(ctx context.Context, roomID string, limit
 
 // AddParticipant records a user joining a media session.
 func (r *PgMediaSessionRepo) AddParticipant(ctx context.Context, sessionID, userID string) error {
-	_, err := r.pool.Exec(ctx, `
+	_, err := r.db.Exec(ctx, `
 		INSERT INTO media_session_participants (session_id, user_id)
 		VALUES ($1, $2)
 	`, sessionID, userID)
@@ -86,7 +86,7 @@ func (r *PgMediaSessionRepo) AddParticipant(ctx context.Context, sessionID, user
 
 // RemoveParticipant records a user leaving a media session.
 func (r *PgMediaSessionRepo) RemoveParticipant(ctx context.Context, sessionID, userID string) error {
-	tag, err := r.pool.Exec(ctx, `
+	tag, err := r.db.Exec(ctx, `
 		UPDATE media_session_participants SET left_at = now()
 		WHERE session_id = $1 AND user_id = $2 AND left_at IS NULL
 	`, sessionID, userID)
@@ -13,17 +13,17 @@ import (
 
 // PgMessageRepo implements MessageRepository against PostgreSQL.
 type PgMessageRepo struct {
-	pool *pgxpool.Pool
+	db pgDB
 }
 
 // NewPgMessageRepo creates a new PostgreSQL-backed message repository.
 func NewPgMessageRepo(pool *pgxpool.Pool) *PgMessageRepo {
-	return &PgMessageRepo{pool: pool}
+	return &PgMessageRepo{db: pool}
 }
 
 // Create inserts a new chat message.
 func (r *PgMessageRepo) Create(ctx context.Context, msg *models.ChatMessage) error {
-	_, err := r.pool.Exec(ctx, `
+	_, err := r.db.Exec(ctx, `
 		INSERT INTO messages (id, room_id, sender_id, type, content, metadata, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`, msg.ID, msg.RoomID, msg.SenderID, msg.Type, msg.Content, msg.Metadata, msg.CreatedAt)
@@ -32,7 +32,7 @@ func (r *PgMessageRepo) Create(ctx context.Context, msg *models.ChatMessage) err
 
 // GetByRoom returns messages for a room before a given timestamp, newest first.
 func (r *PgMessageRepo) GetByRoom(ctx context.Context, roomID string, before time.Time, limit int) ([]*models.ChatMessage, error) {
-	rows, err := r.pool.Query(ctx, `
+	return r.query(ctx, `
 		SELECT m.id, m.room_id, m.sender_id, u.username, m.type, m.content, m.metadata, m.created_at
 		FROM messages m
 		JOIN users u ON u.id = m.sender_id
@@ -40,6 +40,23 @@ func (r *PgMessageRepo) GetByRoom(ctx context.Context, roomID string, before tim
 		ORDER BY m.created_at DESC
 		LIMIT $3
 	`, roomID, before, limit)
+}
+
+// GetByRoomAfter returns messages for a room after a given timestamp, oldest first.
+func (r *PgMessageRepo) GetByRoomAfter(ctx context.Context, roomID string, after time.Time, limit int) ([]*models.ChatMessage, error) {
+	return r.query(ctx, `
+		SELECT m.id, m.room_id, m.sender_id, u.username, m.type, m.content, m.metadata, m.created_at
+		FROM messages m
+		JOIN users u ON u.id = m.sender_id
+		WHERE m.room_id = $1 AND m.created_at > $2
+		ORDER BY m.created_at ASC
+		LIMIT $3
+	`, roomID, after, limit)
+}
+
+// query runs a message SELECT and scans the rows.
+func (r *PgMessageRepo) query(ctx context.Context, sql string, args ...any) ([]*models.ChatMessage, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +79,7 @@ func (r *PgMessageRepo) GetByRoom(ctx context.Context, roomID string, before tim
 // GetByID retrieves a single message by ID.
 func (r *PgMessageRepo) GetByID(ctx context.Context, id string) (*models.ChatMessage, error) {
 	var msg models.ChatMessage
-	err := r.pool.QueryRow(ctx, `
+	err := r.db.QueryRow(ctx, `
 		SELECT m.id, m.room_id, m.sender_id, u.username, m.type, m.content, m.metadata, m.created_at
 		FROM messages m
 		JOIN users u ON u.id = m.sender_id
@@ -79,3 +96,61 @@ func (r *PgMessageRepo) GetByID(ctx context.Context, id string) (*models.ChatMes
 	}
 	return &msg, nil
 }
+
+// Delete deletes a single message.
+func (r *PgMessageRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM messages WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByRoom deletes all messages of a room.
+func (r *PgMessageRepo) DeleteByRoom(ctx context.Context, roomID string) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM messages WHERE room_id = $1`, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// DeleteBefore deletes a room's messages created before the given time.
+func (r *PgMessageRepo) DeleteBefore(ctx context.Context, roomID string, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM messages WHERE room_id = $1 AND created_at < $2
+	`, roomID, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// RenameSender is a no-op: sender names are joined from the users table
+// when messages are read, so renaming the user is enough.
+func (r *PgMessageRepo) RenameSender(_ context.Context, _, _ string) (int, error) {
+	return 0, nil
+}
+
+// CountBySender returns the number of stored messages per sender ID.
+func (r *PgMessageRepo) CountBySender(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.Query(ctx, `SELECT sender_id, count(*) FROM messages GROUP BY sender_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var senderID string
+		var n int
+		if err := rows.Scan(&senderID, &n); err != nil {
+			return nil, err
+		}
+		counts[senderID] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgMetadataRepo implements MetadataRepository against PostgreSQL.
+type PgMetadataRepo struct {
+	db pgDB
+}
+
+// NewPgMetadataRepo creates a new PostgreSQL-backed metadata cache.
+func NewPgMetadataRepo(pool *pgxpool.Pool) *PgMetadataRepo {
+	return &PgMetadataRepo{db: pool}
+}
+
+// Get retrieves the lookup of key.
+func (r *PgMetadataRepo) Get(ctx context.Context, key string) (*models.MetadataLookup, error) {
+	lookup := models.MetadataLookup{Key: key}
+	var metadataJSON []byte
+	err := r.db.QueryRow(ctx, `
+		SELECT metadata, fetched_at FROM metadata_lookups WHERE key = $1
+	`, key).Scan(&metadataJSON, &lookup.FetchedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if lookup.Metadata, err = unmarshalMetadata(metadataJSON); err != nil {
+		return nil, err
+	}
+	return &lookup, nil
+}
+
+// Put stores a lookup, replacing any earlier one of its key.
+func (r *PgMetadataRepo) Put(ctx context.Context, lookup *models.MetadataLookup) error {
+	metadataJSON, err := marshalMetadata(lookup.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO metadata_lookups (key, metadata, fetched_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET metadata = EXCLUDED.metadata, fetched_at = EXCLUDED.fetched_at
+	`, lookup.Key, metadataJSON, lookup.FetchedAt)
+	return err
+}
+
+// marshalMetadata encodes m for a nullable JSONB column.
+func marshalMetadata(m *models.MediaMetadata) ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// unmarshalMetadata decodes a nullable JSONB column.
+func unmarshalMetadata(data []byte) (*models.MediaMetadata, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var m models.MediaMetadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgReportRepo implements ReportRepository against PostgreSQL.
+type PgReportRepo struct {
+	db pgDB
+}
+
+// NewPgReportRepo creates a new PostgreSQL-backed report repository.
+func NewPgReportRepo(pool *pgxpool.Pool) *PgReportRepo {
+	return &PgReportRepo{db: pool}
+}
+
+// pgReportColumns is the column list matched by scanReport.
+const pgReportColumns = `id, reporter_id, target_type, target_id, COALESCE(room_id::text, ''), reason, details, status, created_at, resolution`
+
+// Create stores a new report.
+func (r *PgReportRepo) Create(ctx context.Context, report *models.Report) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO reports (id, reporter_id, target_type, target_id, room_id, reason, details, status, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7, $8, $9)
+	`, report.ID, report.ReporterID, report.TargetType, report.TargetID, report.RoomID,
+		report.Reason, report.Details, report.Status, report.CreatedAt)
+	return err
+}
+
+// GetByID retrieves a report by ID.
+func (r *PgReportRepo) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	report, err := scanReport(r.db.QueryRow(ctx, `SELECT `+pgReportColumns+` FROM reports WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return report, err
+}
+
+// List returns reports matching filter, newest first.
+func (r *PgReportRepo) List(ctx context.Context, filter ReportFilter) ([]*models.Report, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.ReporterID != "" {
+		add("reporter_id = $%d", filter.ReporterID) // must be a UUID
+	}
+	if filter.RoomID != "" {
+		add("room_id = $%d", filter.RoomID) // must be a UUID
+	}
+
+	sql := `SELECT ` + pgReportColumns + ` FROM reports`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	sql += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*models.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// Resolve closes an open report.
+func (r *PgReportRepo) Resolve(ctx context.Context, id, status string, res *models.ReportResolution) error {
+	resolutionJSON, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, `
+		UPDATE reports SET status = $2, resolution = $3 WHERE id = $1 AND status = 'open'
+	`, id, status, resolutionJSON)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanReport scans the columns in pgReportColumns.
+func scanReport(row pgx.Row) (*models.Report, error) {
+	var rep models.Report
+	var resolutionJSON []byte
+	err := row.Scan(&rep.ID, &rep.ReporterID, &rep.TargetType, &rep.TargetID, &rep.RoomID,
+		&rep.Reason, &rep.Details, &rep.Status, &rep.CreatedAt, &resolutionJSON)
+	if err != nil {
+		return nil, err
+	}
+	if resolutionJSON != nil {
+		if err := json.Unmarshal(resolutionJSON, &rep.Resolution); err != nil {
+			return nil, err
+		}
+	}
+	return &rep, nil
+}
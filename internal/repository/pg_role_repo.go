@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgRoleRepo implements RoleRepository against PostgreSQL.
+type PgRoleRepo struct {
+	db pgDB
+}
+
+// NewPgRoleRepo creates a new PostgreSQL-backed role repository.
+func NewPgRoleRepo(pool *pgxpool.Pool) *PgRoleRepo {
+	return &PgRoleRepo{db: pool}
+}
+
+// pgRoleColumns is the column list matched by scanRole.
+const pgRoleColumns = `name, description, permissions, created_at, updated_at`
+
+// Create stores a new role.
+func (r *PgRoleRepo) Create(ctx context.Context, role *models.RoleDefinition) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO roles (name, description, permissions, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, role.Name, role.Description, role.Permissions, role.CreatedAt, role.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Update replaces a role's description and permissions.
+func (r *PgRoleRepo) Update(ctx context.Context, role *models.RoleDefinition) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE roles SET description = $2, permissions = $3, updated_at = $4
+		WHERE name = $1
+	`, role.Name, role.Description, role.Permissions, role.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a role.
+func (r *PgRoleRepo) Delete(ctx context.Context, name string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Get retrieves a role by name.
+func (r *PgRoleRepo) Get(ctx context.Context, name string) (*models.RoleDefinition, error) {
+	role, err := scanRole(r.db.QueryRow(ctx, `SELECT `+pgRoleColumns+` FROM roles WHERE name = $1`, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return role, err
+}
+
+// List returns every role, by name.
+func (r *PgRoleRepo) List(ctx context.Context) ([]*models.RoleDefinition, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+pgRoleColumns+` FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*models.RoleDefinition
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// scanRole scans the columns in pgRoleColumns.
+func scanRole(row pgx.Row) (*models.RoleDefinition, error) {
+	var role models.RoleDefinition
+	if err := row.Scan(&role.Name, &role.Description, &role.Permissions, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
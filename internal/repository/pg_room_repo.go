@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"ofenes/internal/models"
 
@@ -13,12 +14,12 @@ import (
 
 // PgRoomRepo implements RoomRepository against PostgreSQL.
 type PgRoomRepo struct {
-	pool *pgxpool.Pool
+	db pgDB
 }
 
 // NewPgRoomRepo creates a new PostgreSQL-backed room repository.
 func NewPgRoomRepo(pool *pgxpool.Pool) *PgRoomRepo {
-	return &PgRoomRepo{pool: pool}
+	return &PgRoomRepo{db: pool}
 }
 
 // Create inserts a new room.
@@ -27,46 +28,47 @@ func (r *PgRoomRepo) Create(ctx context.Context, room *models.Room) error {
 	if err != nil {
 		return err
 	}
+	retentionJSON, err := marshalRetention(room.Retention)
+	if err != nil {
+		return err
+	}
+	syncJSON, err := marshalSyncPolicy(room.Sync)
+	if err != nil {
+		return err
+	}
+	screensJSON, err := marshalScreens(room.Screens)
+	if err != nil {
+		return err
+	}
 
-	_, err = r.pool.Exec(ctx, `
-		INSERT INTO rooms (id, name, description, type, created_by, is_active, video_state, max_members, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO rooms (id, name, description, type, created_by, is_active, video_state, max_members, retention, sync_policy, screens, tags, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`, room.ID, room.Name, room.Description, room.Type,
 		room.CreatedBy, room.IsActive, videoStateJSON,
-		room.MaxMembers, room.CreatedAt, room.UpdatedAt)
+		room.MaxMembers, retentionJSON, syncJSON, screensJSON, pgTags(room.Tags), room.CreatedAt, room.UpdatedAt)
 	return err
 }
 
 // GetByID retrieves a room by ID.
 func (r *PgRoomRepo) GetByID(ctx context.Context, id string) (*models.Room, error) {
-	var room models.Room
-	var videoStateJSON []byte
-
-	err := r.pool.QueryRow(ctx, `
-		SELECT id, name, description, type, created_by, is_active, video_state, max_members, created_at, updated_at
+	room, err := scanRoom(r.db.QueryRow(ctx, `
+		SELECT id, name, description, type, created_by, is_active, video_state, max_members, retention, sync_policy, screens, tags, created_at, updated_at
 		FROM rooms WHERE id = $1
-	`, id).Scan(
-		&room.ID, &room.Name, &room.Description, &room.Type,
-		&room.CreatedBy, &room.IsActive, &videoStateJSON,
-		&room.MaxMembers, &room.CreatedAt, &room.UpdatedAt,
-	)
+	`, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-
-	if err := json.Unmarshal(videoStateJSON, &room.VideoState); err != nil {
-		return nil, err
-	}
-	return &room, nil
+	return room, nil
 }
 
 // List returns rooms the user is a member of.
 func (r *PgRoomRepo) List(ctx context.Context, userID string, limit, offset int) ([]*models.Room, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT r.id, r.name, r.description, r.type, r.created_by, r.is_active, r.video_state, r.max_members, r.created_at, r.updated_at
+	rows, err := r.db.Query(ctx, `
+		SELECT r.id, r.name, r.description, r.type, r.created_by, r.is_active, r.video_state, r.max_members, r.retention, r.sync_policy, r.screens, r.tags, r.created_at, r.updated_at
 		FROM rooms r
 		JOIN room_members rm ON rm.room_id = r.id
 		WHERE rm.user_id = $1 AND r.is_active = true
@@ -81,14 +83,69 @@ func (r *PgRoomRepo) List(ctx context.Context, userID string, limit, offset int)
 	return r.scanRooms(rows)
 }
 
-// ListPublic returns all active public rooms.
-func (r *PgRoomRepo) ListPublic(ctx context.Context, limit, offset int) ([]*models.Room, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT id, name, description, type, created_by, is_active, video_state, max_members, created_at, updated_at
+// CountOwned returns how many active rooms userID owns.
+func (r *PgRoomRepo) CountOwned(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM rooms r
+		JOIN room_members rm ON rm.room_id = r.id
+		WHERE rm.user_id = $1 AND rm.role = 'owner' AND r.is_active = true
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// ListPublic returns all active public rooms, or those tagged tag.
+func (r *PgRoomRepo) ListPublic(ctx context.Context, tag string, limit, offset int) ([]*models.Room, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, name, description, type, created_by, is_active, video_state, max_members, retention, sync_policy, screens, tags, created_at, updated_at
 		FROM rooms
-		WHERE type = 'public' AND is_active = true
+		WHERE type = 'public' AND is_active = true AND ($3 = '' OR $3 = ANY(tags))
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
+	`, limit, offset, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRooms(rows)
+}
+
+// TagCounts returns the tags of active public rooms created at or after
+// since, with how many of them carry each, most used first.
+func (r *PgRoomRepo) TagCounts(ctx context.Context, since time.Time, limit int) ([]models.TagCount, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT tag, count(*)
+		FROM rooms, unnest(tags) AS tag
+		WHERE type = 'public' AND is_active = true AND created_at >= $1
+		GROUP BY tag
+		ORDER BY count(*) DESC, tag
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var c models.TagCount
+		if err := rows.Scan(&c.Tag, &c.Rooms); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// ListAll returns every room, including inactive ones, oldest first.
+func (r *PgRoomRepo) ListAll(ctx context.Context, limit, offset int) ([]*models.Room, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, name, description, type, created_by, is_active, video_state, max_members, retention, sync_policy, screens, tags, created_at, updated_at
+		FROM rooms
+		ORDER BY created_at, id
+		LIMIT $1 OFFSET $2
 	`, limit, offset)
 	if err != nil {
 		return nil, err
@@ -100,10 +157,10 @@ func (r *PgRoomRepo) ListPublic(ctx context.Context, limit, offset int) ([]*mode
 
 // Update updates a room's mutable fields.
 func (r *PgRoomRepo) Update(ctx context.Context, room *models.Room) error {
-	tag, err := r.pool.Exec(ctx, `
-		UPDATE rooms SET name = $2, description = $3, max_members = $4
+	tag, err := r.db.Exec(ctx, `
+		UPDATE rooms SET name = $2, description = $3, max_members = $4, tags = $5
 		WHERE id = $1
-	`, room.ID, room.Name, room.Description, room.MaxMembers)
+	`, room.ID, room.Name, room.Description, room.MaxMembers, pgTags(room.Tags))
 	if err != nil {
 		return err
 	}
@@ -115,7 +172,7 @@ func (r *PgRoomRepo) Update(ctx context.Context, room *models.Room) error {
 
 // Delete soft-deletes a room.
 func (r *PgRoomRepo) Delete(ctx context.Context, id string) error {
-	tag, err := r.pool.Exec(ctx, `
+	tag, err := r.db.Exec(ctx, `
 		UPDATE rooms SET is_active = false WHERE id = $1
 	`, id)
 	if err != nil {
@@ -129,7 +186,7 @@ func (r *PgRoomRepo) Delete(ctx context.Context, id string) error {
 
 // AddMember adds a user to a room.
 func (r *PgRoomRepo) AddMember(ctx context.Context, roomID, userID, role string) error {
-	_, err := r.pool.Exec(ctx, `
+	_, err := r.db.Exec(ctx, `
 		INSERT INTO room_members (room_id, user_id, role)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (room_id, user_id) DO NOTHING
@@ -139,7 +196,7 @@ func (r *PgRoomRepo) AddMember(ctx context.Context, roomID, userID, role string)
 
 // RemoveMember removes a user from a room.
 func (r *PgRoomRepo) RemoveMember(ctx context.Context, roomID, userID string) error {
-	_, err := r.pool.Exec(ctx, `
+	_, err := r.db.Exec(ctx, `
 		DELETE FROM room_members WHERE room_id = $1 AND user_id = $2
 	`, roomID, userID)
 	return err
@@ -147,7 +204,7 @@ func (r *PgRoomRepo) RemoveMember(ctx context.Context, roomID, userID string) er
 
 // GetMembers returns all members of a room with their usernames.
 func (r *PgRoomRepo) GetMembers(ctx context.Context, roomID string) ([]*models.RoomMember, error) {
-	rows, err := r.pool.Query(ctx, `
+	rows, err := r.db.Query(ctx, `
 		SELECT rm.room_id, rm.user_id, u.username, rm.role, rm.joined_at
 		FROM room_members rm
 		JOIN users u ON u.id = rm.user_id
@@ -173,7 +230,7 @@ func (r *PgRoomRepo) GetMembers(ctx context.Context, roomID string) ([]*models.R
 // GetMemberRole returns the role of a user in a room.
 func (r *PgRoomRepo) GetMemberRole(ctx context.Context, roomID, userID string) (string, error) {
 	var role string
-	err := r.pool.QueryRow(ctx, `
+	err := r.db.QueryRow(ctx, `
 		SELECT role FROM room_members WHERE room_id = $1 AND user_id = $2
 	`, roomID, userID).Scan(&role)
 	if err != nil {
@@ -185,6 +242,20 @@ func (r *PgRoomRepo) GetMemberRole(ctx context.Context, roomID, userID string) (
 	return role, nil
 }
 
+// SetMemberRole changes the role of a room member.
+func (r *PgRoomRepo) SetMemberRole(ctx context.Context, roomID, userID, role string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE room_members SET role = $3 WHERE room_id = $1 AND user_id = $2
+	`, roomID, userID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // UpdateVideoState updates the video sync state for a room.
 func (r *PgRoomRepo) UpdateVideoState(ctx context.Context, roomID string, state models.VideoState) error {
 	videoJSON, err := json.Marshal(state)
@@ -192,7 +263,7 @@ func (r *PgRoomRepo) UpdateVideoState(ctx context.Context, roomID string, state
 		return err
 	}
 
-	tag, err := r.pool.Exec(ctx, `
+	tag, err := r.db.Exec(ctx, `
 		UPDATE rooms SET video_state = $2 WHERE id = $1
 	`, roomID, videoJSON)
 	if err != nil {
@@ -204,25 +275,135 @@ func (r *PgRoomRepo) UpdateVideoState(ctx context.Context, roomID string, state
 	return nil
 }
 
+// UpdateRetention sets a room's message retention policy (nil = server default).
+func (r *PgRoomRepo) UpdateRetention(ctx context.Context, roomID string, policy *models.RetentionPolicy) error {
+	retentionJSON, err := marshalRetention(policy)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE rooms SET retention = $2 WHERE id = $1
+	`, roomID, retentionJSON)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateSyncPolicy sets a room's sync tolerance (nil = server default).
+func (r *PgRoomRepo) UpdateSyncPolicy(ctx context.Context, roomID string, policy *models.SyncPolicy) error {
+	syncJSON, err := marshalSyncPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE rooms SET sync_policy = $2 WHERE id = $1
+	`, roomID, syncJSON)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateScreens replaces a room's screens besides the main one (none if
+// empty).
+func (r *PgRoomRepo) UpdateScreens(ctx context.Context, roomID string, screens []models.Screen) error {
+	screensJSON, err := marshalScreens(screens)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE rooms SET screens = $2 WHERE id = $1
+	`, roomID, screensJSON)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // scanRooms scans multiple room rows from a query result.
 func (r *PgRoomRepo) scanRooms(rows pgx.Rows) ([]*models.Room, error) {
 	var rooms []*models.Room
 	for rows.Next() {
-		var room models.Room
-		var videoStateJSON []byte
-
-		if err := rows.Scan(
-			&room.ID, &room.Name, &room.Description, &room.Type,
-			&room.CreatedBy, &room.IsActive, &videoStateJSON,
-			&room.MaxMembers, &room.CreatedAt, &room.UpdatedAt,
-		); err != nil {
+		room, err := scanRoom(rows)
+		if err != nil {
 			return nil, err
 		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+// scanRoom scans one room row selected with the retention, sync_policy,
+// screens and tags columns.
+func scanRoom(row pgx.Row) (*models.Room, error) {
+	var room models.Room
+	var videoStateJSON, retentionJSON, syncJSON, screensJSON []byte
 
-		if err := json.Unmarshal(videoStateJSON, &room.VideoState); err != nil {
+	if err := row.Scan(
+		&room.ID, &room.Name, &room.Description, &room.Type,
+		&room.CreatedBy, &room.IsActive, &videoStateJSON,
+		&room.MaxMembers, &retentionJSON, &syncJSON, &screensJSON, &room.Tags, &room.CreatedAt, &room.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(videoStateJSON, &room.VideoState); err != nil {
+		return nil, err
+	}
+	if retentionJSON != nil {
+		if err := json.Unmarshal(retentionJSON, &room.Retention); err != nil {
 			return nil, err
 		}
-		rooms = append(rooms, &room)
 	}
-	return rooms, rows.Err()
+	if syncJSON != nil {
+		if err := json.Unmarshal(syncJSON, &room.Sync); err != nil {
+			return nil, err
+		}
+	}
+	if screensJSON != nil {
+		if err := json.Unmarshal(screensJSON, &room.Screens); err != nil {
+			return nil, err
+		}
+	}
+	return &room, nil
+}
+
+// marshalRetention encodes a retention policy for the JSONB column; nil
+// stays SQL NULL.
+func marshalRetention(policy *models.RetentionPolicy) ([]byte, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	return json.Marshal(policy)
+}
+
+// marshalSyncPolicy encodes a sync policy for the JSONB column; nil stays
+// SQL NULL.
+func marshalSyncPolicy(policy *models.SyncPolicy) ([]byte, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	return json.Marshal(policy)
+}
+
+// marshalScreens encodes a room's screens for the JSONB column; none
+// stays SQL NULL.
+func marshalScreens(screens []models.Screen) ([]byte, error) {
+	if len(screens) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(screens)
 }
@@ -12,17 +12,17 @@ import (
 
 // PgSharedFileRepo implements SharedFileRepository against PostgreSQL.
 type PgSharedFileRepo struct {
-	pool *pgxpool.Pool
+	db pgDB
 }
 
 // NewPgSharedFileRepo creates a new PostgreSQL-backed shared file repository.
 func NewPgSharedFileRepo(pool *pgxpool.Pool) *PgSharedFileRepo {
-	return &PgSharedFileRepo{pool: pool}
+	return &PgSharedFileRepo{db: pool}
 }
 
 // Create inserts file metadata.
 func (r *PgSharedFileRepo) Create(ctx context.Context, file *models.SharedFile) error {
-	_, err := r.pool.Exec(ctx, `
+	_, err := r.db.Exec(ctx, `
 		INSERT INTO shared_files (id, room_id, uploaded_by, file_name, file_size, mime_type, storage_path, message_id, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`, file.ID, file.RoomID, file.UploadedBy, file.FileName, file.FileSize,
@@ -32,7 +32,7 @@ func (r *PgSharedFileRepo) Create(ctx context.Context, file *models.SharedFile)
 
 // GetByRoom returns files shared in a room, newest first.
 func (r *PgSharedFileRepo) GetByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.SharedFile, error) {
-	rows, err := r.pool.Query(ctx, `
+	rows, err := r.db.Query(ctx, `
 		SELECT id, room_id, uploaded_by, file_name, file_size, mime_type, storage_path, message_id, created_at
 		FROM shared_files
 		WHERE room_id = $1
@@ -61,7 +61,7 @@ func (r *PgSharedFileRepo) GetByRoom(ctx context.Context, roomID string, limit,
 // GetByID retrieves a file by ID.
 func (r *PgSharedFileRepo) GetByID(ctx context.Context, id string) (*models.SharedFile, error) {
 	var f models.SharedFile
-	err := r.pool.QueryRow(ctx, `
+	err := r.db.QueryRow(ctx, `
 		SELECT id, room_id, uploaded_by, file_name, file_size, mime_type, storage_path, message_id, created_at
 		FROM shared_files WHERE id = $1
 	`, id).Scan(
@@ -79,7 +79,7 @@ func (r *PgSharedFileRepo) GetByID(ctx context.Context, id string) (*models.Shar
 
 // Delete removes a shared file record.
 func (r *PgSharedFileRepo) Delete(ctx context.Context, id string) error {
-	tag, err := r.pool.Exec(ctx, `
+	tag, err := r.db.Exec(ctx, `
 		DELETE FROM shared_files WHERE id = $1
 	`, id)
 	if err != nil {
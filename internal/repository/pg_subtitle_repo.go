@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgSubtitleRepo implements SubtitleRepository against PostgreSQL.
+type PgSubtitleRepo struct {
+	db pgDB
+}
+
+// NewPgSubtitleRepo creates a new PostgreSQL-backed subtitle repository.
+func NewPgSubtitleRepo(pool *pgxpool.Pool) *PgSubtitleRepo {
+	return &PgSubtitleRepo{db: pool}
+}
+
+const pgSubtitleColumns = `id, media_id, room_id, uploaded_by, label, language, format, created_at`
+
+// Create inserts a track.
+func (r *PgSubtitleRepo) Create(ctx context.Context, sub *models.Subtitle) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO subtitles (id, media_id, room_id, uploaded_by, label, language, format, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, sub.ID, sub.MediaID, sub.RoomID, sub.UploadedBy, sub.Label, sub.Language, sub.Format, sub.CreatedAt)
+	return err
+}
+
+// GetByID retrieves a track by ID.
+func (r *PgSubtitleRepo) GetByID(ctx context.Context, id string) (*models.Subtitle, error) {
+	sub, err := scanSubtitle(r.db.QueryRow(ctx, `
+		SELECT `+pgSubtitleColumns+` FROM subtitles WHERE id = $1
+	`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ListByMedia returns the tracks of a media file, oldest first.
+func (r *PgSubtitleRepo) ListByMedia(ctx context.Context, mediaID string) ([]*models.Subtitle, error) {
+	return r.list(ctx, `
+		SELECT `+pgSubtitleColumns+`
+		FROM subtitles
+		WHERE media_id = $1
+		ORDER BY created_at
+	`, mediaID)
+}
+
+// ListByRoom returns the tracks of a room's media, newest first.
+func (r *PgSubtitleRepo) ListByRoom(ctx context.Context, roomID string, limit, offset int) ([]*models.Subtitle, error) {
+	return r.list(ctx, `
+		SELECT `+pgSubtitleColumns+`
+		FROM subtitles
+		WHERE room_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, roomID, limit, offset)
+}
+
+// Delete removes a track.
+func (r *PgSubtitleRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM subtitles WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByMedia removes the tracks of a media file. Deleting the media
+// file cascades here too.
+func (r *PgSubtitleRepo) DeleteByMedia(ctx context.Context, mediaID string) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM subtitles WHERE media_id = $1
+	`, mediaID)
+	return err
+}
+
+func (r *PgSubtitleRepo) list(ctx context.Context, query string, args ...any) ([]*models.Subtitle, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*models.Subtitle
+	for rows.Next() {
+		sub, err := scanSubtitle(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// scanSubtitle scans the columns in pgSubtitleColumns.
+func scanSubtitle(row pgx.Row) (*models.Subtitle, error) {
+	var s models.Subtitle
+	if err := row.Scan(
+		&s.ID, &s.MediaID, &s.RoomID, &s.UploadedBy, &s.Label, &s.Language, &s.Format, &s.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"ofenes/internal/database"
+	"ofenes/internal/repository"
+	"ofenes/internal/repository/repotest"
+
+	"github.com/google/uuid"
+)
+
+// TestPgRepos runs against the PostgreSQL URL in TEST_DATABASE_URL, each
+// test in a fresh schema that is dropped afterwards.
+func TestPgRepos(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	admin, err := database.Connect(ctx, dsn, database.PoolOptions{MaxConns: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+
+	repotest.Run(t, func(t *testing.T) repository.Repos {
+		schema := "repotest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL: %v", err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		pool, err := database.Connect(ctx, u.String(), database.PoolOptions{MaxConns: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+		if err := database.Migrate(ctx, pool); err != nil {
+			t.Fatal(err)
+		}
+		return repository.Repos{
+			Users:          repository.NewPgUserRepo(pool),
+			Rooms:          repository.NewPgRoomRepo(pool),
+			Messages:       repository.NewPgMessageRepo(pool),
+			Audit:          repository.NewPgAuditRepo(pool),
+			DeadLetters:    repository.NewPgDeadLetterRepo(pool),
+			Reports:        repository.NewPgReportRepo(pool),
+			WordFilters:    repository.NewPgWordFilterRepo(pool),
+			AllowedOrigins: repository.NewPgAllowedOriginRepo(pool),
+			Announcements:  repository.NewPgAnnouncementRepo(pool),
+			Instance:       repository.NewPgInstanceRepo(pool),
+			Invites:        repository.NewPgInviteRepo(pool),
+			Legal:          repository.NewPgLegalRepo(pool),
+			Roles:          repository.NewPgRoleRepo(pool),
+			MediaFiles:     repository.NewPgMediaFileRepo(pool),
+			Subtitles:      repository.NewPgSubtitleRepo(pool),
+			Library:        repository.NewPgLibraryRepo(pool),
+			Metadata:       repository.NewPgMetadataRepo(pool),
+		}
+	})
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
 	"ofenes/internal/models"
+	"ofenes/internal/username"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -14,22 +17,25 @@ import (
 
 // PgUserRepo implements UserRepository against PostgreSQL.
 type PgUserRepo struct {
-	pool *pgxpool.Pool
+	db pgDB
 }
 
 // NewPgUserRepo creates a new PostgreSQL-backed user repository.
 func NewPgUserRepo(pool *pgxpool.Pool) *PgUserRepo {
-	return &PgUserRepo{pool: pool}
+	return &PgUserRepo{db: pool}
 }
 
+// pgUserColumns is the column list matched by scanUser.
+const pgUserColumns = `id, username, password_hash, role, trust_level, display_name, avatar_url, status, bio, preferences, created_at, updated_at, deleted_at, anonymized_at, shadow_banned_at`
+
 // Create inserts a new user. Returns ErrAlreadyExists on unique constraint violation.
 func (r *PgUserRepo) Create(ctx context.Context, user *models.User) error {
-	_, err := r.pool.Exec(ctx, `
-		INSERT INTO users (id, username, password_hash, role, display_name, avatar_url, status, bio, preferences, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO users (id, username, password_hash, role, display_name, avatar_url, status, bio, preferences, created_at, updated_at, trust_level, username_skeleton)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE(NULLIF($12, ''), 'new'), $13)
 	`, user.ID, user.Username, user.PasswordHash, user.Role,
 		user.DisplayName, user.AvatarURL, user.Status, user.Bio,
-		user.Preferences, user.CreatedAt, user.UpdatedAt)
+		user.Preferences, user.CreatedAt, user.UpdatedAt, user.TrustLevel, username.Skeleton(user.Username))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -40,27 +46,36 @@ func (r *PgUserRepo) Create(ctx context.Context, user *models.User) error {
 	return nil
 }
 
-// GetByID retrieves a user by ID. Returns ErrNotFound if missing.
+// GetByID retrieves a user by ID. Returns ErrNotFound if missing or soft-deleted.
 func (r *PgUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
-	return r.scanUser(r.pool.QueryRow(ctx, `
-		SELECT id, username, password_hash, role, display_name, avatar_url, status, bio, preferences, created_at, updated_at
-		FROM users WHERE id = $1
+	return r.scanUser(r.db.QueryRow(ctx, `
+		SELECT `+pgUserColumns+`
+		FROM users WHERE id = $1 AND deleted_at IS NULL
 	`, id))
 }
 
-// GetByUsername retrieves a user by username. Returns ErrNotFound if missing.
-func (r *PgUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
-	return r.scanUser(r.pool.QueryRow(ctx, `
-		SELECT id, username, password_hash, role, display_name, avatar_url, status, bio, preferences, created_at, updated_at
-		FROM users WHERE username = $1
-	`, username))
+// GetByUsername retrieves a user by username, ignoring case. Returns ErrNotFound if missing or soft-deleted.
+func (r *PgUserRepo) GetByUsername(ctx context.Context, name string) (*models.User, error) {
+	return r.scanUser(r.db.QueryRow(ctx, `
+		SELECT `+pgUserColumns+`
+		FROM users WHERE lower(username) = $1 AND deleted_at IS NULL
+	`, username.Key(name)))
+}
+
+// GetBySkeleton retrieves a user whose username has the given skeleton. Returns ErrNotFound if none is active.
+func (r *PgUserRepo) GetBySkeleton(ctx context.Context, skeleton string) (*models.User, error) {
+	return r.scanUser(r.db.QueryRow(ctx, `
+		SELECT `+pgUserColumns+`
+		FROM users WHERE username_skeleton = $1 AND deleted_at IS NULL
+		LIMIT 1
+	`, skeleton))
 }
 
 // Update updates a user's profile fields.
 func (r *PgUserRepo) Update(ctx context.Context, user *models.User) error {
-	tag, err := r.pool.Exec(ctx, `
+	tag, err := r.db.Exec(ctx, `
 		UPDATE users SET display_name = $2, avatar_url = $3, bio = $4
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`, user.ID, user.DisplayName, user.AvatarURL, user.Bio)
 	if err != nil {
 		return err
@@ -73,8 +88,8 @@ func (r *PgUserRepo) Update(ctx context.Context, user *models.User) error {
 
 // UpdateStatus sets the user's online status.
 func (r *PgUserRepo) UpdateStatus(ctx context.Context, userID string, status string) error {
-	tag, err := r.pool.Exec(ctx, `
-		UPDATE users SET status = $2 WHERE id = $1
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET status = $2 WHERE id = $1 AND deleted_at IS NULL
 	`, userID, status)
 	if err != nil {
 		return err
@@ -87,8 +102,8 @@ func (r *PgUserRepo) UpdateStatus(ctx context.Context, userID string, status str
 
 // UpdatePreferences replaces the user's preferences JSON.
 func (r *PgUserRepo) UpdatePreferences(ctx context.Context, userID string, prefs json.RawMessage) error {
-	tag, err := r.pool.Exec(ctx, `
-		UPDATE users SET preferences = $2 WHERE id = $1
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET preferences = $2 WHERE id = $1 AND deleted_at IS NULL
 	`, userID, prefs)
 	if err != nil {
 		return err
@@ -99,12 +114,192 @@ func (r *PgUserRepo) UpdatePreferences(ctx context.Context, userID string, prefs
 	return nil
 }
 
-// List returns a paginated list of users.
-func (r *PgUserRepo) List(ctx context.Context, limit, offset int) ([]*models.User, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT id, username, password_hash, role, display_name, avatar_url, status, bio, preferences, created_at, updated_at
-		FROM users ORDER BY created_at ASC LIMIT $1 OFFSET $2
+// List returns the users matching filter.
+func (r *PgUserRepo) List(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	where, args := pgUserWhere(filter)
+	order := "created_at"
+	if filter.Sort == UserSortUsername {
+		order = "username"
+	}
+	dir := "ASC"
+	if filter.Desc {
+		dir = "DESC"
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	return r.query(ctx, fmt.Sprintf(`
+		SELECT `+pgUserColumns+`
+		FROM users %s ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d
+	`, where, order, dir, dir, len(args)-1, len(args)), args...)
+}
+
+// CountByRole returns the number of users matching filter per role.
+func (r *PgUserRepo) CountByRole(ctx context.Context, filter UserFilter) (map[string]int, error) {
+	filter.Role = ""
+	where, args := pgUserWhere(filter)
+	rows, err := r.db.Query(ctx, `SELECT role, count(*) FROM users `+where+` GROUP BY role`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var role string
+		var n int
+		if err := rows.Scan(&role, &n); err != nil {
+			return nil, err
+		}
+		counts[role] = n
+	}
+	return counts, rows.Err()
+}
+
+// SetShadowBanned sets or clears a user's shadow ban.
+func (r *PgUserRepo) SetShadowBanned(ctx context.Context, id string, banned bool) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET shadow_banned_at = CASE WHEN $2 THEN COALESCE(shadow_banned_at, now()) END
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, banned)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetTrustLevel sets a user's trust level.
+func (r *PgUserRepo) SetTrustLevel(ctx context.Context, id, level string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET trust_level = $2, updated_at = now() WHERE id = $1 AND deleted_at IS NULL
+	`, id, level)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetRole sets a user's role.
+func (r *PgUserRepo) SetRole(ctx context.Context, id, role string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET role = $2, updated_at = now() WHERE id = $1 AND deleted_at IS NULL
+	`, id, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// pgUserWhere builds the WHERE clause for a UserFilter. Placeholders are
+// numbered from $1.
+func pgUserWhere(f UserFilter) (string, []any) {
+	var conds []string
+	var args []any
+	if !f.IncludeDeleted {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+	if f.Role != "" {
+		args = append(args, f.Role)
+		conds = append(conds, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if !f.CreatedAfter.IsZero() {
+		args = append(args, f.CreatedAfter)
+		conds = append(conds, fmt.Sprintf("created_at > $%d", len(args)))
+	}
+	if f.ShadowBanned {
+		conds = append(conds, "shadow_banned_at IS NOT NULL")
+	}
+	if f.UsernamePrefix != "" {
+		// Escape LIKE wildcards so the prefix matches literally.
+		args = append(args, likeEscaper.Replace(f.UsernamePrefix)+"%")
+		conds = append(conds, fmt.Sprintf("username LIKE $%d", len(args)))
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// likeEscaper escapes the LIKE metacharacters (backslash is the default escape).
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Delete soft-deletes a user.
+func (r *PgUserRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET deleted_at = now(), status = 'offline' WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore clears a user's soft-delete mark.
+func (r *PgUserRepo) Restore(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Anonymize replaces a user's personal data with pseudonym and soft-deletes them.
+func (r *PgUserRepo) Anonymize(ctx context.Context, id, pseudonym string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET
+			username = $2, username_skeleton = $3, password_hash = '', display_name = NULL, avatar_url = NULL,
+			bio = NULL, preferences = '{}', status = 'offline',
+			deleted_at = COALESCE(deleted_at, now()), anonymized_at = now()
+		WHERE id = $1 AND anonymized_at IS NULL
+	`, id, pseudonym, username.Skeleton(pseudonym))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByIDWithDeleted retrieves a user by ID whether or not it is soft-deleted.
+func (r *PgUserRepo) GetByIDWithDeleted(ctx context.Context, id string) (*models.User, error) {
+	return r.scanUser(r.db.QueryRow(ctx, `
+		SELECT `+pgUserColumns+`
+		FROM users WHERE id = $1
+	`, id))
+}
+
+// ListDeleted returns soft-deleted users, most recently deleted first.
+func (r *PgUserRepo) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	return r.query(ctx, `
+		SELECT `+pgUserColumns+`
+		FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $1 OFFSET $2
 	`, limit, offset)
+}
+
+// query runs a multi-row user query.
+func (r *PgUserRepo) query(ctx context.Context, sql string, args ...any) ([]*models.User, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -125,9 +320,9 @@ func (r *PgUserRepo) List(ctx context.Context, limit, offset int) ([]*models.Use
 func (r *PgUserRepo) scanUser(row pgx.Row) (*models.User, error) {
 	var u models.User
 	err := row.Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.Role,
+		&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.TrustLevel,
 		&u.DisplayName, &u.AvatarURL, &u.Status, &u.Bio,
-		&u.Preferences, &u.CreatedAt, &u.UpdatedAt,
+		&u.Preferences, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt, &u.AnonymizedAt, &u.ShadowBannedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -138,13 +333,13 @@ func (r *PgUserRepo) scanUser(row pgx.Row) (*models.User, error) {
 	return &u, nil
 }
 
-// scanUserFromRow scans a user from pgx.Rows (used in List).
+// scanUserFromRow scans a user from pgx.Rows (used in query).
 func (r *PgUserRepo) scanUserFromRow(rows pgx.Rows) (*models.User, error) {
 	var u models.User
 	err := rows.Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.Role,
+		&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.TrustLevel,
 		&u.DisplayName, &u.AvatarURL, &u.Status, &u.Bio,
-		&u.Preferences, &u.CreatedAt, &u.UpdatedAt,
+		&u.Preferences, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt, &u.AnonymizedAt, &u.ShadowBannedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ofenes/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgWordFilterRepo implements WordFilterRepository against PostgreSQL.
+type PgWordFilterRepo struct {
+	db pgDB
+}
+
+// NewPgWordFilterRepo creates a new PostgreSQL-backed word filter repository.
+func NewPgWordFilterRepo(pool *pgxpool.Pool) *PgWordFilterRepo {
+	return &PgWordFilterRepo{db: pool}
+}
+
+// pgWordFilterColumns is the column list matched by scanWordFilter.
+const pgWordFilterColumns = `id, pattern, regex, action, COALESCE(room_id::text, ''), created_by, created_at, updated_at`
+
+// Create stores a new filter.
+func (r *PgWordFilterRepo) Create(ctx context.Context, filter *models.WordFilter) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO word_filters (id, pattern, regex, action, room_id, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7, $8)
+	`, filter.ID, filter.Pattern, filter.Regex, filter.Action, filter.RoomID,
+		filter.CreatedBy, filter.CreatedAt, filter.UpdatedAt)
+	return err
+}
+
+// Update replaces a filter's pattern, regex flag, action and room.
+func (r *PgWordFilterRepo) Update(ctx context.Context, filter *models.WordFilter) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE word_filters SET pattern = $2, regex = $3, action = $4, room_id = NULLIF($5, '')::uuid, updated_at = $6
+		WHERE id = $1
+	`, filter.ID, filter.Pattern, filter.Regex, filter.Action, filter.RoomID, filter.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a filter.
+func (r *PgWordFilterRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM word_filters WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves a filter by ID.
+func (r *PgWordFilterRepo) GetByID(ctx context.Context, id string) (*models.WordFilter, error) {
+	filter, err := scanWordFilter(r.db.QueryRow(ctx, `SELECT `+pgWordFilterColumns+` FROM word_filters WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return filter, err
+}
+
+// List returns every filter, oldest first.
+func (r *PgWordFilterRepo) List(ctx context.Context) ([]*models.WordFilter, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+pgWordFilterColumns+` FROM word_filters ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filters []*models.WordFilter
+	for rows.Next() {
+		filter, err := scanWordFilter(rows)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, rows.Err()
+}
+
+// scanWordFilter scans the columns in pgWordFilterColumns.
+func scanWordFilter(row pgx.Row) (*models.WordFilter, error) {
+	var f models.WordFilter
+	err := row.Scan(&f.ID, &f.Pattern, &f.Regex, &f.Action, &f.RoomID, &f.CreatedBy, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
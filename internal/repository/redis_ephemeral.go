@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"ofenes/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis key layout (all keys start with the configured prefix):
+//
+//	presence:<roomID>        sorted set, member = username, score = expiry (unix ms)
+//	session:<id>             JSON-encoded models.Session, expires with the session
+//	user_sessions:<userID>   set of session IDs, expires with the longest-lived session
+//	counter:<key>            integer, expires at the end of its window
+//	revoked:<jti>            "1", expires with the token
+//	idempotency:<key>        JSON-encoded models.IdempotencyRecord, expires after the TTL
+
+// extendTTL sets a key's expiry to ARGV[1] ms unless it already lives longer,
+// so a set of expiring members lives as long as its longest-lived member.
+const extendTTL = `
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl >= 0 and ttl >= tonumber(ARGV[1]) then
+	return 0
+end
+return redis.call("PEXPIRE", KEYS[1], ARGV[1])
+`
+
+// NewRedisEphemeralStores creates ephemeral stores backed by Redis. Every key
+// is prefixed with prefix so several deployments can share one server.
+func NewRedisEphemeralStores(client *redis.Client, prefix string) EphemeralStores {
+	return EphemeralStores{
+		Presence:      &RedisPresenceRepo{client: client, prefix: prefix},
+		Sessions:      &RedisSessionRepo{client: client, prefix: prefix},
+		Counters:      &RedisCounterRepo{client: client, prefix: prefix},
+		RevokedTokens: &RedisRevokedTokenRepo{client: client, prefix: prefix},
+		Idempotency:   &RedisIdempotencyRepo{client: client, prefix: prefix},
+	}
+}
+
+// --- Presence ---
+
+// RedisPresenceRepo implements PresenceRepository with one sorted set per room.
+type RedisPresenceRepo struct {
+	client *redis.Client
+	prefix string
+}
+
+// Touch marks username present in roomID for ttl.
+func (r *RedisPresenceRepo) Touch(ctx context.Context, roomID, username string, ttl time.Duration) error {
+	key := r.prefix + "presence:" + roomID
+	expires := time.Now().Add(ttl)
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(expires.UnixMilli()), Member: username})
+	pipe.Eval(ctx, extendTTL, []string{key}, ttl.Milliseconds())
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Remove marks username absent from roomID.
+func (r *RedisPresenceRepo) Remove(ctx context.Context, roomID, username string) error {
+	return r.client.ZRem(ctx, r.prefix+"presence:"+roomID, username).Err()
+}
+
+// List returns the usernames present in roomID, dropping expired entries.
+func (r *RedisPresenceRepo) List(ctx context.Context, roomID string) ([]string, error) {
+	key := r.prefix + "presence:" + roomID
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	pipe := r.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+now)
+	members := pipe.ZRange(ctx, key, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+// --- Sessions ---
+
+// RedisSessionRepo implements SessionRepository with one key per session and
+// a per-user index for DeleteByUser.
+type RedisSessionRepo struct {
+	client *redis.Client
+	prefix string
+}
+
+// Create stores a session until session.ExpiresAt.
+func (r *RedisSessionRepo) Create(ctx context.Context, session *models.Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.client.SetNX(ctx, r.prefix+"session:"+session.ID, data, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAlreadyExists
+	}
+
+	index := r.prefix + "user_sessions:" + session.UserID
+	pipe := r.client.TxPipeline()
+	pipe.SAdd(ctx, index, session.ID)
+	pipe.Eval(ctx, extendTTL, []string{index}, ttl.Milliseconds()) // stale IDs are harmless
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get retrieves a session. Returns ErrNotFound if missing or expired.
+func (r *RedisSessionRepo) Get(ctx context.Context, id string) (*models.Session, error) {
+	data, err := r.client.Get(ctx, r.prefix+"session:"+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var s models.Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Update replaces a stored session and keeps it until its new ExpiresAt.
+func (r *RedisSessionRepo) Update(ctx context.Context, session *models.Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return r.Delete(ctx, session.ID)
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.client.SetXX(ctx, r.prefix+"session:"+session.ID, data, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return r.client.Eval(ctx, extendTTL, []string{r.prefix + "user_sessions:" + session.UserID}, ttl.Milliseconds()).Err()
+}
+
+// ListByUser returns a user's unexpired sessions, oldest first.
+func (r *RedisSessionRepo) ListByUser(ctx context.Context, userID string) ([]*models.Session, error) {
+	ids, err := r.client.SMembers(ctx, r.prefix+"user_sessions:"+userID).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.prefix + "session:" + id
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*models.Session
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue // expired; its ID lingers in the index
+		}
+		var s models.Session
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// Delete removes a session.
+func (r *RedisSessionRepo) Delete(ctx context.Context, id string) error {
+	s, err := r.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.prefix+"session:"+id)
+	pipe.SRem(ctx, r.prefix+"user_sessions:"+s.UserID, id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeleteByUser removes all of a user's sessions.
+func (r *RedisSessionRepo) DeleteByUser(ctx context.Context, userID string) error {
+	index := r.prefix + "user_sessions:" + userID
+	ids, err := r.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, r.prefix+"session:"+id)
+	}
+	keys = append(keys, index)
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// CountCreatedBefore returns how many sessions were created before the given time.
+func (r *RedisSessionRepo) CountCreatedBefore(ctx context.Context, before time.Time) (int, error) {
+	sessions, err := r.createdBefore(ctx, before)
+	return len(sessions), err
+}
+
+// DeleteCreatedBefore removes sessions created before the given time.
+func (r *RedisSessionRepo) DeleteCreatedBefore(ctx context.Context, before time.Time) (int, error) {
+	sessions, err := r.createdBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	pipe := r.client.Pipeline()
+	for _, s := range sessions {
+		pipe.Del(ctx, r.prefix+"session:"+s.ID)
+		pipe.SRem(ctx, r.prefix+"user_sessions:"+s.UserID, s.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return len(sessions), nil
+}
+
+// createdBefore scans all session keys for sessions created before the
+// given time. SCAN walks the keyspace in batches without blocking Redis.
+func (r *RedisSessionRepo) createdBefore(ctx context.Context, before time.Time) ([]*models.Session, error) {
+	var (
+		sessions []*models.Session
+		keys     []string
+	)
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		values, err := r.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			data, ok := v.(string)
+			if !ok {
+				continue // expired since SCAN saw it
+			}
+			var s models.Session
+			if err := json.Unmarshal([]byte(data), &s); err != nil {
+				return err
+			}
+			if s.CreatedAt.Before(before) {
+				sessions = append(sessions, &s)
+			}
+		}
+		keys = keys[:0]
+		return nil
+	}
+
+	iter := r.client.Scan(ctx, 0, r.prefix+"session:*", 500).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 500 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// --- Counters ---
+
+// incrWindow increments a counter and starts its window on the first
+// increment, atomically.
+var incrWindow = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RedisCounterRepo implements CounterRepository with expiring integer keys.
+type RedisCounterRepo struct {
+	client *redis.Client
+	prefix string
+}
+
+// Incr increments key within its fixed window and returns the new count.
+func (r *RedisCounterRepo) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return incrWindow.Run(ctx, r.client, []string{r.prefix + "counter:" + key}, window.Milliseconds()).Int64()
+}
+
+// --- Revoked tokens ---
+
+// RedisRevokedTokenRepo implements RevokedTokenRepository with one expiring
+// key per revoked token.
+type RedisRevokedTokenRepo struct {
+	client *redis.Client
+	prefix string
+}
+
+// Revoke marks tokenID revoked until expiresAt.
+func (r *RedisRevokedTokenRepo) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(ctx, r.prefix+"revoked:"+tokenID, "1", ttl).Err()
+}
+
+// IsRevoked reports whether tokenID has been revoked.
+func (r *RedisRevokedTokenRepo) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.prefix+"revoked:"+tokenID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// --- Idempotency keys ---
+
+// RedisIdempotencyRepo implements IdempotencyRepository with one expiring
+// JSON key per idempotency key.
+type RedisIdempotencyRepo struct {
+	client *redis.Client
+	prefix string
+}
+
+// Reserve claims key with SET NX, or returns the record already there.
+func (r *RedisIdempotencyRepo) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, error) {
+	data, err := json.Marshal(models.IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	k := r.prefix + "idempotency:" + key
+	ok, err := r.client.SetNX(ctx, k, data, ttl).Result()
+	if err != nil || ok {
+		return nil, err
+	}
+
+	existing, err := r.client.Get(ctx, k).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Expired between the two calls: report it as in progress so the
+		// client retries.
+		return &models.IdempotencyRecord{Fingerprint: fingerprint}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record models.IdempotencyRecord
+	if err := json.Unmarshal(existing, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response for key.
+func (r *RedisIdempotencyRepo) Complete(ctx context.Context, key string, record *models.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+"idempotency:"+key, data, ttl).Err()
+}
+
+// Release drops the record of key.
+func (r *RedisIdempotencyRepo) Release(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+"idempotency:"+key).Err()
+}
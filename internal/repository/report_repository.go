@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// ReportRepository defines the contract for content reports.
+type ReportRepository interface {
+	// Create stores a new report.
+	Create(ctx context.Context, report *models.Report) error
+
+	// GetByID retrieves a report by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.Report, error)
+
+	// List returns reports matching filter, newest first.
+	List(ctx context.Context, filter ReportFilter) ([]*models.Report, error)
+
+	// Resolve closes an open report with status (resolved or dismissed)
+	// and records res. Returns ErrNotFound if the report does not exist or
+	// is no longer open.
+	Resolve(ctx context.Context, id, status string, res *models.ReportResolution) error
+}
+
+// ReportFilter selects reports. Zero fields match everything.
+type ReportFilter struct {
+	Status     string
+	TargetType string
+	TargetID   string
+	ReporterID string
+	RoomID     string
+	Limit      int
+	Offset     int
+}
+
+// matches reports whether rep passes the filter.
+func (f ReportFilter) matches(rep *models.Report) bool {
+	return (f.Status == "" || rep.Status == f.Status) &&
+		(f.TargetType == "" || rep.TargetType == f.TargetType) &&
+		(f.TargetID == "" || rep.TargetID == f.TargetID) &&
+		(f.ReporterID == "" || rep.ReporterID == f.ReporterID) &&
+		(f.RoomID == "" || rep.RoomID == f.RoomID)
+}
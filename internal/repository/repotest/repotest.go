@@ -0,0 +1,2489 @@
+// Package repotest is a conformance suite for repository implementations.
+//
+// Every storage backend must behave the same from the handlers' point of
+// view: the same errors for duplicates and missing rows, the same ordering
+// and pagination. Run the suite from the backend's own test file:
+//
+//	func TestBoltRepos(t *testing.T) {
+//	    repotest.Run(t, func(t *testing.T) repository.Repos {
+//	        db := openTempBolt(t) // fresh, empty store per test
+//	        return repository.Repos{
+//	            Users:          repository.NewBoltUserRepo(db),
+//	            Rooms:          repository.NewBoltRoomRepo(db),
+//	            Messages:       repository.NewBoltMessageRepo(db),
+//	            Audit:          repository.NewBoltAuditRepo(db),
+//	            DeadLetters:    repository.NewBoltDeadLetterRepo(db),
+//	            Reports:        repository.NewBoltReportRepo(db),
+//	            WordFilters:    repository.NewBoltWordFilterRepo(db),
+//	            AllowedOrigins: repository.NewBoltAllowedOriginRepo(db),
+//	            Announcements:  repository.NewBoltAnnouncementRepo(db),
+//	            Instance:       repository.NewBoltInstanceRepo(db),
+//	            Invites:        repository.NewBoltInviteRepo(db),
+//	            Legal:          repository.NewBoltLegalRepo(db),
+//	            Roles:          repository.NewBoltRoleRepo(db),
+//	            MediaFiles:     repository.NewBoltMediaFileRepo(db),
+//	            Subtitles:      repository.NewBoltSubtitleRepo(db),
+//	            Library:        repository.NewBoltLibraryRepo(db),
+//	            Metadata:       repository.NewBoltMetadataRepo(db),
+//	        }
+//	    })
+//	}
+//
+// The suite only uses Users, Rooms, Messages, Audit, DeadLetters, Reports,
+// WordFilters, AllowedOrigins, Announcements, Instance, Invites, Legal, Roles,
+// MediaFiles, Subtitles, Library and Metadata. IDs are UUIDs and
+// timestamps are truncated to milliseconds, so SQL and document stores can
+// round-trip them exactly.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/internal/username"
+
+	"github.com/google/uuid"
+)
+
+// NewRepos returns repositories backed by a fresh, empty store. It is
+// called once per subtest; register cleanup with t.Cleanup.
+type NewRepos func(t *testing.T) repository.Repos
+
+// Run runs the whole suite.
+func Run(t *testing.T, newRepos NewRepos) {
+	t.Run("Users", func(t *testing.T) { UserRepository(t, newRepos) })
+	t.Run("Rooms", func(t *testing.T) { RoomRepository(t, newRepos) })
+	t.Run("Messages", func(t *testing.T) { MessageRepository(t, newRepos) })
+	t.Run("Audit", func(t *testing.T) { AuditRepository(t, newRepos) })
+	t.Run("DeadLetters", func(t *testing.T) { DeadLetterRepository(t, newRepos) })
+	t.Run("Reports", func(t *testing.T) { ReportRepository(t, newRepos) })
+	t.Run("WordFilters", func(t *testing.T) { WordFilterRepository(t, newRepos) })
+	t.Run("AllowedOrigins", func(t *testing.T) { AllowedOriginRepository(t, newRepos) })
+	t.Run("Announcements", func(t *testing.T) { AnnouncementRepository(t, newRepos) })
+	t.Run("Instance", func(t *testing.T) { InstanceRepository(t, newRepos) })
+	t.Run("Invites", func(t *testing.T) { InviteRepository(t, newRepos) })
+	t.Run("Legal", func(t *testing.T) { LegalRepository(t, newRepos) })
+	t.Run("Roles", func(t *testing.T) { RoleRepository(t, newRepos) })
+	t.Run("MediaFiles", func(t *testing.T) { MediaFileRepository(t, newRepos) })
+	t.Run("Subtitles", func(t *testing.T) { SubtitleRepository(t, newRepos) })
+	t.Run("Library", func(t *testing.T) { LibraryRepository(t, newRepos) })
+	t.Run("Metadata", func(t *testing.T) { MetadataRepository(t, newRepos) })
+}
+
+// --- Users ---
+
+// UserRepository checks the UserRepository contract.
+func UserRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepos(t).Users
+		want := newUser("alice", now())
+		mustCreateUser(t, repo, want)
+
+		got, err := repo.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		assertUser(t, got, want)
+
+		got, err = repo.GetByUsername(ctx, want.Username)
+		if err != nil {
+			t.Fatalf("GetByUsername: %v", err)
+		}
+		assertUser(t, got, want)
+	})
+
+	t.Run("DuplicateUsername", func(t *testing.T) {
+		repo := newRepos(t).Users
+		mustCreateUser(t, repo, newUser("alice", now()))
+
+		err := repo.Create(ctx, newUser("alice", now()))
+		if !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Fatalf("Create duplicate: got %v, want ErrAlreadyExists", err)
+		}
+	})
+
+	t.Run("UsernameIgnoresCase", func(t *testing.T) {
+		repo := newRepos(t).Users
+		want := newUser("Alice", now())
+		mustCreateUser(t, repo, want)
+
+		got, err := repo.GetByUsername(ctx, "aLICE")
+		if err != nil {
+			t.Fatalf("GetByUsername: %v", err)
+		}
+		assertUser(t, got, want)
+
+		if err := repo.Create(ctx, newUser("alice", now())); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Fatalf("Create differing in case: got %v, want ErrAlreadyExists", err)
+		}
+	})
+
+	t.Run("Skeleton", func(t *testing.T) {
+		repo := newRepos(t).Users
+		want := newUser("bоb", now()) // Cyrillic "о"
+		mustCreateUser(t, repo, want)
+
+		got, err := repo.GetBySkeleton(ctx, username.Skeleton("Bob"))
+		if err != nil {
+			t.Fatalf("GetBySkeleton: %v", err)
+		}
+		assertUser(t, got, want)
+		if _, err := repo.GetBySkeleton(ctx, username.Skeleton("alice")); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetBySkeleton other name: got %v, want ErrNotFound", err)
+		}
+
+		if err := repo.Anonymize(ctx, want.ID, "deleted-1"); err != nil {
+			t.Fatalf("Anonymize: %v", err)
+		}
+		if _, err := repo.GetBySkeleton(ctx, username.Skeleton("bob")); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetBySkeleton after Anonymize: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Users
+		missing := uuid.NewString()
+
+		if _, err := repo.GetByID(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID: got %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByUsername(ctx, "nobody"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByUsername: got %v, want ErrNotFound", err)
+		}
+		if err := repo.Update(ctx, &models.User{ID: missing}); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update: got %v, want ErrNotFound", err)
+		}
+		if err := repo.UpdateStatus(ctx, missing, models.StatusAway); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateStatus: got %v, want ErrNotFound", err)
+		}
+		if err := repo.UpdatePreferences(ctx, missing, json.RawMessage(`{}`)); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdatePreferences: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		repo := newRepos(t).Users
+		user := newUser("alice", now())
+		mustCreateUser(t, repo, user)
+
+		name, bio := "Alice", "hi"
+		if err := repo.Update(ctx, &models.User{ID: user.ID, DisplayName: &name, Bio: &bio}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := repo.UpdateStatus(ctx, user.ID, models.StatusBusy); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+		if err := repo.UpdatePreferences(ctx, user.ID, json.RawMessage(`{"theme":"dark"}`)); err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+
+		got, err := repo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.DisplayName == nil || *got.DisplayName != name || got.Bio == nil || *got.Bio != bio {
+			t.Errorf("profile not updated: displayName=%v bio=%v", got.DisplayName, got.Bio)
+		}
+		if got.Status != models.StatusBusy {
+			t.Errorf("status = %q, want %q", got.Status, models.StatusBusy)
+		}
+		assertJSON(t, "preferences", got.Preferences, `{"theme":"dark"}`)
+		if got.Username != user.Username || got.PasswordHash != user.PasswordHash {
+			t.Errorf("Update changed non-profile fields: %+v", got)
+		}
+	})
+
+	t.Run("ListPagination", func(t *testing.T) {
+		repo := newRepos(t).Users
+		base := now()
+		var want []string
+		for i := range 5 {
+			u := newUser(fmt.Sprintf("user%d", i), base.Add(time.Duration(i)*time.Second))
+			mustCreateUser(t, repo, u)
+			want = append(want, u.ID)
+		}
+
+		var got []string
+		for offset := 0; offset < 6; offset += 2 {
+			page, err := repo.List(ctx, repository.UserFilter{Limit: 2, Offset: offset})
+			if err != nil {
+				t.Fatalf("List(2, %d): %v", offset, err)
+			}
+			if wantLen := min(2, max(0, 5-offset)); len(page) != wantLen {
+				t.Fatalf("List(2, %d) returned %d users, want %d", offset, len(page), wantLen)
+			}
+			for _, u := range page {
+				got = append(got, u.ID)
+			}
+		}
+		assertOrder(t, "List (oldest first)", got, want)
+	})
+
+	t.Run("ListFilter", func(t *testing.T) {
+		repo := newRepos(t).Users
+		base := now()
+		mk := func(name, role string, i int) *models.User {
+			u := newUser(name, base.Add(time.Duration(i)*time.Second))
+			u.Role = role
+			return mustCreateUser(t, repo, u)
+		}
+		carol := mk("carol", models.RoleAdmin, 0)
+		ann := mk("ann", models.RoleMember, 1)
+		andy := mk("andy", models.RoleMember, 2)
+		ab := mk("a_b", models.RoleViewer, 3)
+		amy := mk("amy", models.RoleMember, 4)
+		if err := repo.Delete(ctx, amy.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		cases := []struct {
+			name   string
+			filter repository.UserFilter
+			want   []*models.User
+		}{
+			{"role", repository.UserFilter{Role: models.RoleMember}, []*models.User{ann, andy}},
+			{"created after", repository.UserFilter{CreatedAfter: ann.CreatedAt}, []*models.User{andy, ab}},
+			{"prefix", repository.UserFilter{UsernamePrefix: "an"}, []*models.User{ann, andy}},
+			{"literal underscore", repository.UserFilter{UsernamePrefix: "a_"}, []*models.User{ab}},
+			{"include deleted", repository.UserFilter{Role: models.RoleMember, IncludeDeleted: true}, []*models.User{ann, andy, amy}},
+			{"by username", repository.UserFilter{Sort: repository.UserSortUsername}, []*models.User{ab, andy, ann, carol}},
+			{"newest first", repository.UserFilter{Desc: true}, []*models.User{ab, andy, ann, carol}},
+			{"paginated", repository.UserFilter{Sort: repository.UserSortUsername, Desc: true, Offset: 1}, []*models.User{ann, andy, ab}},
+		}
+		for _, tc := range cases {
+			tc.filter.Limit = max(tc.filter.Limit, 10)
+			got, err := repo.List(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("List(%s): %v", tc.name, err)
+			}
+			assertOrder(t, "List("+tc.name+")", userIDs(got), userIDs(tc.want))
+		}
+
+		counts, err := repo.CountByRole(ctx, repository.UserFilter{Role: models.RoleAdmin, UsernamePrefix: "a"})
+		if err != nil {
+			t.Fatalf("CountByRole: %v", err)
+		}
+		if want := map[string]int{models.RoleMember: 2, models.RoleViewer: 1}; fmt.Sprint(counts) != fmt.Sprint(want) {
+			t.Errorf("CountByRole = %v, want %v", counts, want)
+		}
+	})
+
+	t.Run("SoftDeleteAndRestore", func(t *testing.T) {
+		repo := newRepos(t).Users
+		alice := mustCreateUser(t, repo, newUser("alice", now()))
+		bob := mustCreateUser(t, repo, newUser("bob", now().Add(time.Second)))
+
+		if err := repo.Delete(ctx, alice.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := repo.Delete(ctx, alice.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("second Delete = %v, want ErrNotFound", err)
+		}
+
+		// Hidden from every regular read and write.
+		if _, err := repo.GetByID(ctx, alice.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(deleted) = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByUsername(ctx, "alice"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByUsername(deleted) = %v, want ErrNotFound", err)
+		}
+		if err := repo.UpdateStatus(ctx, alice.ID, models.StatusOnline); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateStatus(deleted) = %v, want ErrNotFound", err)
+		}
+		list, err := repo.List(ctx, repository.UserFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List after Delete", userIDs(list), []string{bob.ID})
+
+		// The username stays reserved.
+		if err := repo.Create(ctx, newUser("alice", now())); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Create(deleted username) = %v, want ErrAlreadyExists", err)
+		}
+
+		// Visible through the admin methods.
+		got, err := repo.GetByIDWithDeleted(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetByIDWithDeleted: %v", err)
+		}
+		if got.DeletedAt == nil {
+			t.Error("GetByIDWithDeleted: DeletedAt not set")
+		}
+		deleted, err := repo.ListDeleted(ctx, 10, 0)
+		if err != nil {
+			t.Fatalf("ListDeleted: %v", err)
+		}
+		assertOrder(t, "ListDeleted", userIDs(deleted), []string{alice.ID})
+
+		if err := repo.Restore(ctx, alice.ID); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if err := repo.Restore(ctx, alice.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("second Restore = %v, want ErrNotFound", err)
+		}
+		got, err = repo.GetByUsername(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetByUsername after Restore: %v", err)
+		}
+		if got.DeletedAt != nil {
+			t.Errorf("DeletedAt = %v after Restore, want nil", got.DeletedAt)
+		}
+		assertUser(t, got, alice)
+	})
+
+	t.Run("Anonymize", func(t *testing.T) {
+		repo := newRepos(t).Users
+		alice := newUser("alice", now())
+		bio := "likes films"
+		alice.Bio = &bio
+		mustCreateUser(t, repo, alice)
+		bob := mustCreateUser(t, repo, newUser("bob", now()))
+
+		if err := repo.Anonymize(ctx, alice.ID, "bob"); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Anonymize(taken pseudonym) = %v, want ErrAlreadyExists", err)
+		}
+		if err := repo.Anonymize(ctx, alice.ID, "deleted-1"); err != nil {
+			t.Fatalf("Anonymize: %v", err)
+		}
+		if err := repo.Anonymize(ctx, alice.ID, "deleted-2"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("second Anonymize = %v, want ErrNotFound", err)
+		}
+
+		got, err := repo.GetByIDWithDeleted(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetByIDWithDeleted: %v", err)
+		}
+		if got.Username != "deleted-1" || got.Bio != nil || got.PasswordHash != "" {
+			t.Errorf("anonymized user = %q bio=%v hash=%q, want pseudonym and no personal data",
+				got.Username, got.Bio, got.PasswordHash)
+		}
+		if got.DeletedAt == nil || got.AnonymizedAt == nil {
+			t.Errorf("DeletedAt = %v, AnonymizedAt = %v, want both set", got.DeletedAt, got.AnonymizedAt)
+		}
+		if err := repo.Restore(ctx, alice.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Restore(anonymized) = %v, want ErrNotFound", err)
+		}
+
+		// The original username is released; the pseudonym is not.
+		mustCreateUser(t, repo, newUser("alice", now()))
+		if err := repo.Create(ctx, newUser("deleted-1", now())); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Create(pseudonym) = %v, want ErrAlreadyExists", err)
+		}
+
+		// Already soft-deleted users can be anonymized too.
+		if err := repo.Delete(ctx, bob.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		deleted, err := repo.GetByIDWithDeleted(ctx, bob.ID)
+		if err != nil {
+			t.Fatalf("GetByIDWithDeleted: %v", err)
+		}
+		if err := repo.Anonymize(ctx, bob.ID, "deleted-3"); err != nil {
+			t.Fatalf("Anonymize(deleted): %v", err)
+		}
+		got, err = repo.GetByIDWithDeleted(ctx, bob.ID)
+		if err != nil {
+			t.Fatalf("GetByIDWithDeleted: %v", err)
+		}
+		if got.DeletedAt == nil || !got.DeletedAt.Equal(*deleted.DeletedAt) {
+			t.Errorf("DeletedAt = %v after Anonymize, want unchanged %v", got.DeletedAt, deleted.DeletedAt)
+		}
+	})
+
+	t.Run("ShadowBan", func(t *testing.T) {
+		repo := newRepos(t).Users
+		alice := mustCreateUser(t, repo, newUser("alice", now()))
+		mustCreateUser(t, repo, newUser("bob", now()))
+
+		if err := repo.SetShadowBanned(ctx, alice.ID, true); err != nil {
+			t.Fatalf("SetShadowBanned: %v", err)
+		}
+		got, err := repo.GetByID(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.ShadowBannedAt == nil {
+			t.Fatal("ShadowBannedAt = nil after SetShadowBanned(true)")
+		}
+		since := *got.ShadowBannedAt
+
+		// Banning again keeps the original time.
+		time.Sleep(5 * time.Millisecond)
+		if err := repo.SetShadowBanned(ctx, alice.ID, true); err != nil {
+			t.Fatalf("SetShadowBanned: %v", err)
+		}
+		if got, err = repo.GetByID(ctx, alice.ID); err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.ShadowBannedAt == nil || !got.ShadowBannedAt.Equal(since) {
+			t.Errorf("ShadowBannedAt = %v after second ban, want unchanged %v", got.ShadowBannedAt, since)
+		}
+
+		banned, err := repo.List(ctx, repository.UserFilter{ShadowBanned: true, Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (shadow-banned)", userIDs(banned), []string{alice.ID})
+
+		if err := repo.SetShadowBanned(ctx, alice.ID, false); err != nil {
+			t.Fatalf("SetShadowBanned(false): %v", err)
+		}
+		if got, err = repo.GetByID(ctx, alice.ID); err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.ShadowBannedAt != nil {
+			t.Errorf("ShadowBannedAt = %v after lifting the ban, want nil", got.ShadowBannedAt)
+		}
+		if err := repo.SetShadowBanned(ctx, uuid.NewString(), true); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("SetShadowBanned(missing) = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("TrustLevel", func(t *testing.T) {
+		repo := newRepos(t).Users
+		alice := mustCreateUser(t, repo, newUser("alice", now()))
+
+		if err := repo.SetTrustLevel(ctx, alice.ID, models.TrustRegular); err != nil {
+			t.Fatalf("SetTrustLevel: %v", err)
+		}
+		got, err := repo.GetByID(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.TrustLevel != models.TrustRegular {
+			t.Errorf("TrustLevel = %q, want %q", got.TrustLevel, models.TrustRegular)
+		}
+		if err := repo.SetTrustLevel(ctx, uuid.NewString(), models.TrustMember); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("SetTrustLevel(missing) = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Role", func(t *testing.T) {
+		repo := newRepos(t).Users
+		alice := mustCreateUser(t, repo, newUser("alice", now()))
+
+		if err := repo.SetRole(ctx, alice.ID, models.RoleAdmin); err != nil {
+			t.Fatalf("SetRole: %v", err)
+		}
+		got, err := repo.GetByID(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Role != models.RoleAdmin {
+			t.Errorf("Role = %q, want %q", got.Role, models.RoleAdmin)
+		}
+		if err := repo.SetRole(ctx, uuid.NewString(), models.RoleMember); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("SetRole(missing) = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		repo := newRepos(t).Users
+		const n = 16
+
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- repo.Create(ctx, newUser("racer", now()))
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, repository.ErrAlreadyExists):
+				t.Errorf("Create: unexpected error %v", err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("%d concurrent creates of one username succeeded, want 1", created)
+		}
+	})
+}
+
+// --- Rooms ---
+
+// RoomRepository checks the RoomRepository contract. It needs Users to
+// create room owners and members.
+func RoomRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		owner := mustCreateUser(t, repos.Users, newUser("owner", now()))
+		want := newRoom(owner.ID, models.RoomTypePublic, now())
+		want.VideoState = models.VideoState{URL: "https://example.com/v.mp4", Playing: true, Timestamp: 12.5}
+		mustCreateRoom(t, repos.Rooms, want)
+
+		got, err := repos.Rooms.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Name != want.Name || got.Type != want.Type || got.CreatedBy != want.CreatedBy ||
+			!got.IsActive || got.MaxMembers != want.MaxMembers || got.VideoState != want.VideoState ||
+			!got.CreatedAt.Equal(want.CreatedAt) {
+			t.Errorf("GetByID = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Rooms
+		missing := uuid.NewString()
+
+		if _, err := repo.GetByID(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID: got %v, want ErrNotFound", err)
+		}
+		if err := repo.Update(ctx, &models.Room{ID: missing, Name: "x"}); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update: got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete: got %v, want ErrNotFound", err)
+		}
+		if err := repo.UpdateVideoState(ctx, missing, models.VideoState{}); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateVideoState: got %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetMemberRole(ctx, missing, uuid.NewString()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetMemberRole: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("UpdateAndSoftDelete", func(t *testing.T) {
+		repos := newRepos(t)
+		owner := mustCreateUser(t, repos.Users, newUser("owner", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, now()))
+
+		room.Name = "renamed"
+		room.MaxMembers = 7
+		if err := repos.Rooms.Update(ctx, room); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		state := models.VideoState{URL: "u", Timestamp: 3, Subtitle: "s"}
+		if err := repos.Rooms.UpdateVideoState(ctx, room.ID, state); err != nil {
+			t.Fatalf("UpdateVideoState: %v", err)
+		}
+		if err := repos.Rooms.Delete(ctx, room.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		got, err := repos.Rooms.GetByID(ctx, room.ID)
+		if err != nil {
+			t.Fatalf("GetByID after soft delete: %v", err)
+		}
+		if got.Name != "renamed" || got.MaxMembers != 7 || got.VideoState != state || got.IsActive {
+			t.Errorf("GetByID = %+v, want renamed, 7 members, new video state, inactive", got)
+		}
+
+		public, err := repos.Rooms.ListPublic(ctx, "", 10, 0)
+		if err != nil {
+			t.Fatalf("ListPublic: %v", err)
+		}
+		if len(public) != 0 {
+			t.Errorf("ListPublic returned %d rooms, want deleted room excluded", len(public))
+		}
+	})
+
+	t.Run("ListPublicPagination", func(t *testing.T) {
+		repos := newRepos(t)
+		owner := mustCreateUser(t, repos.Users, newUser("owner", now()))
+		base := now()
+		var want []string
+		for i := range 5 {
+			r := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, base.Add(time.Duration(i)*time.Second)))
+			want = append([]string{r.ID}, want...) // newest first
+		}
+		mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePrivate, base))
+
+		var got []string
+		for offset := 0; offset < 6; offset += 2 {
+			page, err := repos.Rooms.ListPublic(ctx, "", 2, offset)
+			if err != nil {
+				t.Fatalf("ListPublic(2, %d): %v", offset, err)
+			}
+			for _, r := range page {
+				got = append(got, r.ID)
+			}
+		}
+		assertOrder(t, "ListPublic (newest first, public only)", got, want)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		repos := newRepos(t)
+		owner := mustCreateUser(t, repos.Users, newUser("owner", now()))
+		base := now()
+		tagged := func(typ string, at time.Time, tags ...string) *models.Room {
+			r := newRoom(owner.ID, typ, at)
+			r.Tags = tags
+			return mustCreateRoom(t, repos.Rooms, r)
+		}
+		old := tagged(models.RoomTypePublic, base.Add(-48*time.Hour), "movies")
+		anime := tagged(models.RoomTypePublic, base, "anime", "movies")
+		music := tagged(models.RoomTypePublic, base.Add(time.Second), "music")
+		tagged(models.RoomTypePrivate, base, "anime")
+		gone := tagged(models.RoomTypePublic, base, "anime")
+		if err := repos.Rooms.Delete(ctx, gone.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		if got, _ := repos.Rooms.GetByID(ctx, anime.ID); !reflect.DeepEqual(got.Tags, anime.Tags) {
+			t.Errorf("Tags = %v, want %v", got.Tags, anime.Tags)
+		}
+		byTag, err := repos.Rooms.ListPublic(ctx, "movies", 10, 0)
+		if err != nil {
+			t.Fatalf("ListPublic(movies): %v", err)
+		}
+		assertOrder(t, "ListPublic(movies)", roomIDs(byTag), []string{anime.ID, old.ID})
+
+		counts, err := repos.Rooms.TagCounts(ctx, base.Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatalf("TagCounts: %v", err)
+		}
+		want := []models.TagCount{{Tag: "anime", Rooms: 1}, {Tag: "movies", Rooms: 1}, {Tag: "music", Rooms: 1}}
+		if !reflect.DeepEqual(counts, want) {
+			t.Errorf("TagCounts since an hour ago = %+v, want %+v", counts, want)
+		}
+		counts, err = repos.Rooms.TagCounts(ctx, time.Time{}, 1)
+		if err != nil {
+			t.Fatalf("TagCounts: %v", err)
+		}
+		if want := []models.TagCount{{Tag: "movies", Rooms: 2}}; !reflect.DeepEqual(counts, want) {
+			t.Errorf("TagCounts(limit 1) = %+v, want %+v", counts, want)
+		}
+
+		music.Tags = nil
+		if err := repos.Rooms.Update(ctx, music); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, _ := repos.Rooms.GetByID(ctx, music.ID); len(got.Tags) != 0 {
+			t.Errorf("Tags after removing them = %v, want none", got.Tags)
+		}
+	})
+
+	t.Run("Members", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePrivate, now()))
+		other := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePrivate, now().Add(time.Second)))
+
+		mustAddMember(t, repos.Rooms, room.ID, alice.ID, models.RoomRoleOwner)
+		time.Sleep(5 * time.Millisecond) // distinct joined_at for ordering
+		mustAddMember(t, repos.Rooms, room.ID, bob.ID, models.RoomRoleMember)
+		mustAddMember(t, repos.Rooms, other.ID, alice.ID, models.RoomRoleOwner)
+
+		// Adding an existing member is a no-op and keeps the original role.
+		mustAddMember(t, repos.Rooms, room.ID, bob.ID, models.RoomRoleModerator)
+
+		members, err := repos.Rooms.GetMembers(ctx, room.ID)
+		if err != nil {
+			t.Fatalf("GetMembers: %v", err)
+		}
+		if len(members) != 2 {
+			t.Fatalf("GetMembers returned %d members, want 2", len(members))
+		}
+		if members[0].Username != "alice" || members[1].Username != "bob" {
+			t.Errorf("GetMembers = [%s, %s], want [alice, bob] (earliest joined first, with usernames)",
+				members[0].Username, members[1].Username)
+		}
+
+		role, err := repos.Rooms.GetMemberRole(ctx, room.ID, bob.ID)
+		if err != nil || role != models.RoomRoleMember {
+			t.Errorf("GetMemberRole = %q, %v, want %q", role, err, models.RoomRoleMember)
+		}
+
+		if err := repos.Rooms.SetMemberRole(ctx, room.ID, bob.ID, models.RoomRoleCoHost); err != nil {
+			t.Fatalf("SetMemberRole: %v", err)
+		}
+		role, err = repos.Rooms.GetMemberRole(ctx, room.ID, bob.ID)
+		if err != nil || role != models.RoomRoleCoHost {
+			t.Errorf("GetMemberRole after SetMemberRole = %q, %v, want %q", role, err, models.RoomRoleCoHost)
+		}
+		if err := repos.Rooms.SetMemberRole(ctx, other.ID, bob.ID, models.RoomRoleMember); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("SetMemberRole for a non-member: got %v, want ErrNotFound", err)
+		}
+
+		rooms, err := repos.Rooms.List(ctx, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (member rooms, newest first)", roomIDs(rooms), []string{other.ID, room.ID})
+		if n, err := repos.Rooms.CountOwned(ctx, alice.ID); err != nil || n != 2 {
+			t.Errorf("CountOwned(alice) = %d, %v; want 2", n, err)
+		}
+		if n, err := repos.Rooms.CountOwned(ctx, bob.ID); err != nil || n != 0 {
+			t.Errorf("CountOwned(co-host) = %d, %v; want 0", n, err)
+		}
+
+		if err := repos.Rooms.RemoveMember(ctx, room.ID, bob.ID); err != nil {
+			t.Fatalf("RemoveMember: %v", err)
+		}
+		if _, err := repos.Rooms.GetMemberRole(ctx, room.ID, bob.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetMemberRole after RemoveMember: got %v, want ErrNotFound", err)
+		}
+		rooms, err = repos.Rooms.List(ctx, bob.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(rooms) != 0 {
+			t.Errorf("List for removed member returned %d rooms, want 0", len(rooms))
+		}
+
+		if err := repos.Rooms.Delete(ctx, other.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		rooms, err = repos.Rooms.List(ctx, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (deleted rooms excluded)", roomIDs(rooms), []string{room.ID})
+		if n, err := repos.Rooms.CountOwned(ctx, alice.ID); err != nil || n != 1 {
+			t.Errorf("CountOwned(deleted rooms excluded) = %d, %v; want 1", n, err)
+		}
+	})
+
+	t.Run("RetentionAndListAll", func(t *testing.T) {
+		repos := newRepos(t)
+		owner := mustCreateUser(t, repos.Users, newUser("owner", now()))
+		base := now()
+		withPolicy := newRoom(owner.ID, models.RoomTypePublic, base)
+		withPolicy.Retention = &models.RetentionPolicy{Mode: models.RetentionDays, Days: 7}
+		mustCreateRoom(t, repos.Rooms, withPolicy)
+		closed := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePrivate, base.Add(time.Second)))
+		plain := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, base.Add(2*time.Second)))
+		if err := repos.Rooms.Delete(ctx, closed.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		got, err := repos.Rooms.GetByID(ctx, withPolicy.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Retention == nil || *got.Retention != *withPolicy.Retention {
+			t.Errorf("Retention = %+v, want %+v", got.Retention, withPolicy.Retention)
+		}
+		if got, _ := repos.Rooms.GetByID(ctx, plain.ID); got.Retention != nil {
+			t.Errorf("Retention of room without policy = %+v, want nil", got.Retention)
+		}
+
+		onClose := &models.RetentionPolicy{Mode: models.RetentionOnClose}
+		if err := repos.Rooms.UpdateRetention(ctx, plain.ID, onClose); err != nil {
+			t.Fatalf("UpdateRetention: %v", err)
+		}
+		if err := repos.Rooms.UpdateRetention(ctx, withPolicy.ID, nil); err != nil {
+			t.Fatalf("UpdateRetention(nil): %v", err)
+		}
+		if err := repos.Rooms.UpdateRetention(ctx, uuid.NewString(), onClose); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateRetention on missing room: got %v, want ErrNotFound", err)
+		}
+
+		var all []*models.Room
+		for offset := 0; offset < 4; offset += 2 {
+			page, err := repos.Rooms.ListAll(ctx, 2, offset)
+			if err != nil {
+				t.Fatalf("ListAll(2, %d): %v", offset, err)
+			}
+			all = append(all, page...)
+		}
+		assertOrder(t, "ListAll (oldest first, inactive included)", roomIDs(all), []string{withPolicy.ID, closed.ID, plain.ID})
+		if all[0].Retention != nil {
+			t.Errorf("Retention after reset = %+v, want nil", all[0].Retention)
+		}
+		if all[2].Retention == nil || *all[2].Retention != *onClose {
+			t.Errorf("Retention after update = %+v, want %+v", all[2].Retention, onClose)
+		}
+
+		sync := &models.SyncPolicy{ToleranceMs: 1000, NudgeMs: 200, PauseOnDisconnect: models.PauseOnHost}
+		if err := repos.Rooms.UpdateSyncPolicy(ctx, plain.ID, sync); err != nil {
+			t.Fatalf("UpdateSyncPolicy: %v", err)
+		}
+		if got, _ := repos.Rooms.GetByID(ctx, plain.ID); got.Sync == nil || *got.Sync != *sync {
+			t.Errorf("Sync after update = %+v, want %+v", got.Sync, sync)
+		}
+		if err := repos.Rooms.UpdateSyncPolicy(ctx, plain.ID, nil); err != nil {
+			t.Fatalf("UpdateSyncPolicy(nil): %v", err)
+		}
+		if got, _ := repos.Rooms.GetByID(ctx, plain.ID); got.Sync != nil {
+			t.Errorf("Sync after reset = %+v, want nil", got.Sync)
+		}
+		if err := repos.Rooms.UpdateSyncPolicy(ctx, uuid.NewString(), sync); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateSyncPolicy on missing room: got %v, want ErrNotFound", err)
+		}
+
+		screens := []models.Screen{{ID: "stage", Name: "Stage", Control: "room.chat"}, {ID: "cam"}}
+		if err := repos.Rooms.UpdateScreens(ctx, plain.ID, screens); err != nil {
+			t.Fatalf("UpdateScreens: %v", err)
+		}
+		if got, _ := repos.Rooms.GetByID(ctx, plain.ID); !reflect.DeepEqual(got.Screens, screens) {
+			t.Errorf("Screens after update = %+v, want %+v", got.Screens, screens)
+		}
+		if err := repos.Rooms.UpdateScreens(ctx, plain.ID, nil); err != nil {
+			t.Fatalf("UpdateScreens(nil): %v", err)
+		}
+		if got, _ := repos.Rooms.GetByID(ctx, plain.ID); len(got.Screens) != 0 {
+			t.Errorf("Screens after reset = %+v, want none", got.Screens)
+		}
+		if err := repos.Rooms.UpdateScreens(ctx, uuid.NewString(), screens); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateScreens on missing room: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// --- Messages ---
+
+// MessageRepository checks the MessageRepository contract. It needs Users
+// and Rooms to create senders and rooms.
+func MessageRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		want := newMessage(room.ID, alice, "hello", now())
+		mustCreateMessage(t, repos.Messages, want)
+
+		got, err := repos.Messages.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.RoomID != want.RoomID || got.SenderID != want.SenderID || got.Sender != "alice" ||
+			got.Type != want.Type || got.Content != want.Content || !got.CreatedAt.Equal(want.CreatedAt) {
+			t.Errorf("GetByID = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Messages
+		if _, err := repo.GetByID(ctx, uuid.NewString()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("GetByRoomCursor", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		other := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+
+		base := now()
+		var ids []string
+		for i := range 5 {
+			m := newMessage(room.ID, alice, fmt.Sprint(i), base.Add(time.Duration(i)*time.Second))
+			mustCreateMessage(t, repos.Messages, m)
+			ids = append(ids, m.ID)
+		}
+		mustCreateMessage(t, repos.Messages, newMessage(other.ID, alice, "elsewhere", base.Add(2*time.Second)))
+
+		// Walk the history newest first, two at a time, using the oldest
+		// message of each page as the next cursor.
+		var got []string
+		before := base.Add(time.Hour)
+		for range 4 {
+			page, err := repos.Messages.GetByRoom(ctx, room.ID, before, 2)
+			if err != nil {
+				t.Fatalf("GetByRoom: %v", err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			for _, m := range page {
+				got = append(got, m.ID)
+			}
+			before = page[len(page)-1].CreatedAt
+		}
+		assertOrder(t, "GetByRoom (newest first, cursor pagination)", got, []string{ids[4], ids[3], ids[2], ids[1], ids[0]})
+
+		// The cursor is exclusive.
+		page, err := repos.Messages.GetByRoom(ctx, room.ID, base.Add(2*time.Second), 10)
+		if err != nil {
+			t.Fatalf("GetByRoom: %v", err)
+		}
+		assertOrder(t, "GetByRoom (before is exclusive)", messageIDs(page), []string{ids[1], ids[0]})
+
+		// GetByRoomAfter walks the other way, also exclusive.
+		page, err = repos.Messages.GetByRoomAfter(ctx, room.ID, base.Add(2*time.Second), 10)
+		if err != nil {
+			t.Fatalf("GetByRoomAfter: %v", err)
+		}
+		assertOrder(t, "GetByRoomAfter (oldest first, after is exclusive)", messageIDs(page), []string{ids[3], ids[4]})
+		page, err = repos.Messages.GetByRoomAfter(ctx, room.ID, base.Add(-time.Second), 2)
+		if err != nil {
+			t.Fatalf("GetByRoomAfter: %v", err)
+		}
+		assertOrder(t, "GetByRoomAfter (limit)", messageIDs(page), []string{ids[0], ids[1]})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		gone := newMessage(room.ID, alice, "oops", now())
+		kept := newMessage(room.ID, alice, "fine", now().Add(time.Second))
+		mustCreateMessage(t, repos.Messages, gone)
+		mustCreateMessage(t, repos.Messages, kept)
+
+		if err := repos.Messages.Delete(ctx, gone.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Messages.GetByID(ctx, gone.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID of deleted message: got %v, want ErrNotFound", err)
+		}
+		page, err := repos.Messages.GetByRoom(ctx, room.ID, now().Add(time.Hour), 10)
+		if err != nil {
+			t.Fatalf("GetByRoom: %v", err)
+		}
+		assertOrder(t, "GetByRoom after Delete", messageIDs(page), []string{kept.ID})
+		if err := repos.Messages.Delete(ctx, gone.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DeleteByRoomAndBefore", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		other := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+
+		base := now()
+		var ids []string
+		for i := range 4 {
+			m := newMessage(room.ID, alice, fmt.Sprint(i), base.Add(time.Duration(i)*time.Second))
+			mustCreateMessage(t, repos.Messages, m)
+			ids = append(ids, m.ID)
+		}
+		kept := newMessage(other.ID, alice, "elsewhere", base)
+		mustCreateMessage(t, repos.Messages, kept)
+
+		// before is exclusive, like the GetByRoom cursor.
+		n, err := repos.Messages.DeleteBefore(ctx, room.ID, base.Add(2*time.Second))
+		if err != nil || n != 2 {
+			t.Fatalf("DeleteBefore = %d, %v, want 2", n, err)
+		}
+		page, err := repos.Messages.GetByRoom(ctx, room.ID, base.Add(time.Hour), 10)
+		if err != nil {
+			t.Fatalf("GetByRoom: %v", err)
+		}
+		assertOrder(t, "GetByRoom after DeleteBefore", messageIDs(page), []string{ids[3], ids[2]})
+		if _, err := repos.Messages.GetByID(ctx, ids[0]); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID of deleted message: got %v, want ErrNotFound", err)
+		}
+
+		n, err = repos.Messages.DeleteByRoom(ctx, room.ID)
+		if err != nil || n != 2 {
+			t.Fatalf("DeleteByRoom = %d, %v, want 2", n, err)
+		}
+		page, err = repos.Messages.GetByRoom(ctx, room.ID, base.Add(time.Hour), 10)
+		if err != nil {
+			t.Fatalf("GetByRoom: %v", err)
+		}
+		if len(page) != 0 {
+			t.Errorf("GetByRoom after DeleteByRoom returned %d messages, want 0", len(page))
+		}
+		if _, err := repos.Messages.GetByID(ctx, kept.ID); err != nil {
+			t.Errorf("GetByID of other room's message: %v", err)
+		}
+	})
+
+	t.Run("RenameSender", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		mine := newMessage(room.ID, alice, "hi", now())
+		theirs := newMessage(room.ID, bob, "hello", now())
+		mustCreateMessage(t, repos.Messages, mine)
+		mustCreateMessage(t, repos.Messages, theirs)
+
+		// Backends that join the sender name may report 0 renamed, so
+		// check what readers see rather than the count.
+		if err := repos.Users.Anonymize(ctx, alice.ID, "deleted-1"); err != nil {
+			t.Fatalf("Anonymize: %v", err)
+		}
+		if _, err := repos.Messages.RenameSender(ctx, alice.ID, "deleted-1"); err != nil {
+			t.Fatalf("RenameSender: %v", err)
+		}
+		for id, want := range map[string]string{mine.ID: "deleted-1", theirs.ID: "bob"} {
+			got, err := repos.Messages.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if got.Sender != want || got.SenderID == "" {
+				t.Errorf("Sender = %q (id %q), want %q", got.Sender, got.SenderID, want)
+			}
+		}
+	})
+
+	t.Run("CountBySender", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		mustCreateUser(t, repos.Users, newUser("carol", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		other := mustCreateRoom(t, repos.Rooms, newRoom(bob.ID, models.RoomTypePublic, now()))
+		mustCreateMessage(t, repos.Messages, newMessage(room.ID, alice, "one", now()))
+		mustCreateMessage(t, repos.Messages, newMessage(other.ID, alice, "two", now()))
+		mustCreateMessage(t, repos.Messages, newMessage(room.ID, bob, "three", now()))
+
+		counts, err := repos.Messages.CountBySender(ctx)
+		if err != nil {
+			t.Fatalf("CountBySender: %v", err)
+		}
+		if len(counts) != 2 || counts[alice.ID] != 2 || counts[bob.ID] != 1 {
+			t.Errorf("CountBySender = %v, want alice: 2, bob: 1", counts)
+		}
+	})
+
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		const n = 16
+
+		var wg sync.WaitGroup
+		base := now()
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m := newMessage(room.ID, alice, fmt.Sprint(i), base.Add(time.Duration(i)*time.Millisecond))
+				if err := repos.Messages.Create(ctx, m); err != nil {
+					t.Errorf("Create: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		page, err := repos.Messages.GetByRoom(ctx, room.ID, base.Add(time.Hour), 2*n)
+		if err != nil {
+			t.Fatalf("GetByRoom: %v", err)
+		}
+		if len(page) != n {
+			t.Errorf("GetByRoom returned %d messages after %d concurrent creates", len(page), n)
+		}
+	})
+}
+
+// --- Fixtures and helpers ---
+
+var ctx = context.Background()
+
+// now returns the current time truncated to what every backend can store.
+// --- Audit ---
+
+// AuditRepository checks the AuditRepository contract.
+func AuditRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("ListFilterAndDeleteBefore", func(t *testing.T) {
+		repo := newRepos(t).Audit
+		actor := uuid.NewString()
+		base := now()
+		var ids []string
+		for i := range 4 {
+			e := &models.AuditEntry{
+				ID:        uuid.NewString(),
+				Action:    "test.a",
+				TargetID:  fmt.Sprint(i),
+				Details:   json.RawMessage(`{"n":1}`),
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+			}
+			if i%2 == 1 {
+				e.Action, e.ActorID = "test.b", actor
+			}
+			if err := repo.Create(ctx, e); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids = append(ids, e.ID)
+		}
+
+		all, err := repo.List(ctx, repository.AuditFilter{Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (newest first, paginated)", auditIDs(all), []string{ids[2], ids[1]})
+		assertJSON(t, "Details", all[0].Details, `{"n":1}`)
+
+		byActor, err := repo.List(ctx, repository.AuditFilter{Action: "test.b", ActorID: actor, Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (action and actor filter)", auditIDs(byActor), []string{ids[3], ids[1]})
+		byTarget, err := repo.List(ctx, repository.AuditFilter{TargetID: "2", Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (target filter)", auditIDs(byTarget), []string{ids[2]})
+		if byTarget[0].ActorID != "" {
+			t.Errorf("ActorID = %q, want empty for system entries", byTarget[0].ActorID)
+		}
+
+		cutoff := base.Add(2 * time.Second)
+		if n, err := repo.CountBefore(ctx, cutoff); err != nil || n != 2 {
+			t.Errorf("CountBefore = %d, %v, want 2", n, err)
+		}
+		if n, err := repo.DeleteBefore(ctx, cutoff); err != nil || n != 2 {
+			t.Errorf("DeleteBefore = %d, %v, want 2", n, err)
+		}
+		rest, err := repo.List(ctx, repository.AuditFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List after DeleteBefore", auditIDs(rest), []string{ids[3], ids[2]})
+	})
+}
+
+// --- Dead letters ---
+
+// DeadLetterRepository checks the DeadLetterRepository contract.
+func DeadLetterRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("ListFilterAndDeleteBefore", func(t *testing.T) {
+		repo := newRepos(t).DeadLetters
+		user := uuid.NewString()
+		base := now()
+		var ids []string
+		for i := range 4 {
+			l := &models.DeadLetter{
+				ID:        uuid.NewString(),
+				UserID:    uuid.NewString(),
+				Username:  "alice",
+				RoomID:    uuid.NewString(),
+				Code:      models.WSErrInvalidMessage,
+				Reason:    "message is not valid JSON",
+				Data:      `{"type":`,
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+			}
+			if i%2 == 1 {
+				l.UserID, l.Type, l.MessageID, l.Code, l.Truncated = user, "webrtc", fmt.Sprint(i), models.WSErrUnknownTarget, true
+			}
+			if err := repo.Create(ctx, l); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids = append(ids, l.ID)
+		}
+
+		all, err := repo.List(ctx, repository.DeadLetterFilter{Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (newest first, paginated)", deadLetterIDs(all), []string{ids[2], ids[1]})
+		if got := all[1]; got.Type != "webrtc" || got.MessageID != "1" || !got.Truncated || got.Data != `{"type":` {
+			t.Errorf("List[1] = %+v, fields not round-tripped", got)
+		}
+
+		byUser, err := repo.List(ctx, repository.DeadLetterFilter{UserID: user, Code: models.WSErrUnknownTarget, Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (user and code filter)", deadLetterIDs(byUser), []string{ids[3], ids[1]})
+
+		cutoff := base.Add(2 * time.Second)
+		if n, err := repo.CountBefore(ctx, cutoff); err != nil || n != 2 {
+			t.Errorf("CountBefore = %d, %v, want 2", n, err)
+		}
+		if n, err := repo.DeleteBefore(ctx, cutoff); err != nil || n != 2 {
+			t.Errorf("DeleteBefore = %d, %v, want 2", n, err)
+		}
+		rest, err := repo.List(ctx, repository.DeadLetterFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List after DeleteBefore", deadLetterIDs(rest), []string{ids[3], ids[2]})
+	})
+}
+
+// ReportRepository checks the ReportRepository contract.
+func ReportRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CreateGetAndList", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		repo := repos.Reports
+
+		base := now()
+		var ids []string
+		for i, target := range []string{models.ReportTargetMessage, models.ReportTargetUser, models.ReportTargetRoom} {
+			rep := &models.Report{
+				ID:         uuid.NewString(),
+				ReporterID: alice.ID,
+				TargetType: target,
+				TargetID:   uuid.NewString(),
+				Reason:     models.ReportReasonSpam,
+				Status:     models.ReportStatusOpen,
+				CreatedAt:  base.Add(time.Duration(i) * time.Second),
+			}
+			if target != models.ReportTargetUser {
+				rep.RoomID = room.ID
+			}
+			if target == models.ReportTargetRoom {
+				rep.ReporterID, rep.TargetID, rep.Details = bob.ID, room.ID, "details"
+			}
+			if err := repo.Create(ctx, rep); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids = append(ids, rep.ID)
+		}
+
+		got, err := repo.GetByID(ctx, ids[2])
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.ReporterID != bob.ID || got.TargetID != room.ID || got.RoomID != room.ID ||
+			got.Details != "details" || !got.CreatedAt.Equal(base.Add(2*time.Second)) {
+			t.Errorf("GetByID = %+v, want the stored report", got)
+		}
+		if _, err := repo.GetByID(ctx, uuid.NewString()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(missing) = %v, want ErrNotFound", err)
+		}
+
+		all, err := repo.List(ctx, repository.ReportFilter{Status: models.ReportStatusOpen, Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (newest first, paginated)", reportIDs(all), []string{ids[1], ids[0]})
+
+		byReporter, err := repo.List(ctx, repository.ReportFilter{ReporterID: alice.ID, RoomID: room.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (reporter and room filter)", reportIDs(byReporter), []string{ids[0]})
+		byTarget, err := repo.List(ctx, repository.ReportFilter{TargetType: models.ReportTargetUser, Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List (target type filter)", reportIDs(byTarget), []string{ids[1]})
+		if byTarget[0].RoomID != "" {
+			t.Errorf("RoomID = %q, want empty for user reports", byTarget[0].RoomID)
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		repo := repos.Reports
+
+		rep := &models.Report{
+			ID:         uuid.NewString(),
+			ReporterID: alice.ID,
+			TargetType: models.ReportTargetUser,
+			TargetID:   bob.ID,
+			Reason:     models.ReportReasonHarassment,
+			Status:     models.ReportStatusOpen,
+			CreatedAt:  now(),
+		}
+		if err := repo.Create(ctx, rep); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		until := now().Add(time.Hour)
+		res := &models.ReportResolution{
+			Action: models.ModActionMute, Note: "first warning",
+			ResolvedBy: alice.ID, ResolvedAt: now(), MutedUntil: &until,
+		}
+		if err := repo.Resolve(ctx, rep.ID, models.ReportStatusResolved, res); err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+		got, err := repo.GetByID(ctx, rep.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Status != models.ReportStatusResolved || got.Resolution == nil {
+			t.Fatalf("GetByID = %+v, want a resolved report", got)
+		}
+		if r := got.Resolution; r.Action != res.Action || r.Note != res.Note || r.ResolvedBy != alice.ID ||
+			!r.ResolvedAt.Equal(res.ResolvedAt) || r.MutedUntil == nil || !r.MutedUntil.Equal(until) {
+			t.Errorf("Resolution = %+v, want %+v", r, res)
+		}
+
+		open, err := repo.List(ctx, repository.ReportFilter{Status: models.ReportStatusOpen, Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(open) != 0 {
+			t.Errorf("List(open) returned %d reports, want 0", len(open))
+		}
+
+		// Only open reports can be resolved.
+		if err := repo.Resolve(ctx, rep.ID, models.ReportStatusDismissed, res); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Resolve twice: got %v, want ErrNotFound", err)
+		}
+		if err := repo.Resolve(ctx, uuid.NewString(), models.ReportStatusDismissed, res); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Resolve(missing): got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// --- Word filters ---
+
+// WordFilterRepository checks the WordFilterRepository contract.
+func WordFilterRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CRUD", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, now()))
+		repo := repos.WordFilters
+
+		base := now()
+		global := &models.WordFilter{
+			ID: uuid.NewString(), Pattern: "spoiler", Action: models.WordFilterBlock,
+			CreatedBy: alice.ID, CreatedAt: base, UpdatedAt: base,
+		}
+		override := &models.WordFilter{
+			ID: uuid.NewString(), Pattern: "spoiler", Action: models.WordFilterAllow, RoomID: room.ID,
+			CreatedBy: alice.ID, CreatedAt: base.Add(time.Second), UpdatedAt: base.Add(time.Second),
+		}
+		for _, f := range []*models.WordFilter{override, global} {
+			if err := repo.Create(ctx, f); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		got, err := repo.GetByID(ctx, override.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if *got != *override {
+			t.Errorf("GetByID = %+v, want %+v", got, override)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List", wordFilterIDs(list), []string{global.ID, override.ID})
+
+		updated := *override
+		updated.Pattern, updated.Regex, updated.Action, updated.RoomID = `sp[o0]iler`, true, models.WordFilterFlag, ""
+		updated.UpdatedAt = base.Add(time.Minute)
+		if err := repo.Update(ctx, &updated); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err = repo.GetByID(ctx, override.ID); err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if *got != updated {
+			t.Errorf("after Update: %+v, want %+v", got, updated)
+		}
+
+		if err := repo.Delete(ctx, global.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, global.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+		}
+		missing := &models.WordFilter{ID: uuid.NewString(), Pattern: "x", Action: models.WordFilterBlock}
+		if err := repo.Update(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update(missing): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, global.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// --- Allowed origins ---
+
+// AllowedOriginRepository checks the AllowedOriginRepository contract.
+func AllowedOriginRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CRUD", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		repo := repos.AllowedOrigins
+
+		base := now()
+		tenant := &models.AllowedOrigin{
+			ID: uuid.NewString(), Origin: "https://*.tenant.example", CreatedBy: alice.ID, CreatedAt: base,
+		}
+		app := &models.AllowedOrigin{
+			ID: uuid.NewString(), Origin: "https://app.example.com", CreatedBy: alice.ID, CreatedAt: base.Add(time.Second),
+		}
+		for _, o := range []*models.AllowedOrigin{app, tenant} {
+			if err := repo.Create(ctx, o); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		dup := &models.AllowedOrigin{ID: uuid.NewString(), Origin: app.Origin, CreatedBy: alice.ID, CreatedAt: base}
+		if err := repo.Create(ctx, dup); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Create(duplicate origin): got %v, want ErrAlreadyExists", err)
+		}
+
+		got, err := repo.GetByID(ctx, app.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if *got != *app {
+			t.Errorf("GetByID = %+v, want %+v", got, app)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids := make([]string, len(list))
+		for i, o := range list {
+			ids[i] = o.ID
+		}
+		assertOrder(t, "List", ids, []string{tenant.ID, app.ID})
+
+		if err := repo.Delete(ctx, tenant.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, tenant.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, tenant.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// --- Announcements ---
+
+// AnnouncementRepository checks the AnnouncementRepository contract.
+func AnnouncementRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CRUD", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		repo := repos.Announcements
+
+		base := now()
+		ended := base.Add(-time.Hour)
+		past := &models.Announcement{
+			ID: uuid.NewString(), Title: "Maintenance done", Level: models.AnnouncementInfo,
+			StartsAt: base.Add(-2 * time.Hour), EndsAt: &ended,
+			CreatedBy: alice.ID, CreatedAt: base, UpdatedAt: base,
+		}
+		live := &models.Announcement{
+			ID: uuid.NewString(), Title: "v2.1 is out", Body: "See the release notes.", Level: models.AnnouncementInfo,
+			StartsAt: base.Add(-time.Minute), CreatedBy: alice.ID, CreatedAt: base, UpdatedAt: base,
+		}
+		downtime := base.Add(25 * time.Hour)
+		scheduled := &models.Announcement{
+			ID: uuid.NewString(), Title: "Downtime tomorrow", Level: models.AnnouncementWarning,
+			StartsAt: base.Add(time.Hour), EndsAt: &downtime,
+			CreatedBy: alice.ID, CreatedAt: base, UpdatedAt: base,
+		}
+		for _, a := range []*models.Announcement{scheduled, past, live} {
+			if err := repo.Create(ctx, a); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		got, err := repo.GetByID(ctx, scheduled.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !reflect.DeepEqual(got, scheduled) {
+			t.Errorf("GetByID = %+v, want %+v", got, scheduled)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List", announcementIDs(list), []string{scheduled.ID, live.ID, past.ID})
+		current, err := repo.ListCurrent(ctx, base)
+		if err != nil {
+			t.Fatalf("ListCurrent: %v", err)
+		}
+		assertOrder(t, "ListCurrent", announcementIDs(current), []string{live.ID, scheduled.ID})
+
+		updated := *scheduled
+		updated.Title, updated.Level, updated.EndsAt = "Downtime moved", models.AnnouncementCritical, nil
+		updated.UpdatedAt = base.Add(time.Minute)
+		if err := repo.Update(ctx, &updated); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err = repo.GetByID(ctx, scheduled.ID); err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !reflect.DeepEqual(got, &updated) {
+			t.Errorf("after Update: %+v, want %+v", got, &updated)
+		}
+
+		missing := &models.Announcement{ID: uuid.NewString(), Title: "x", Level: models.AnnouncementInfo}
+		if err := repo.Update(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update(missing): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, past.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, past.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, past.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Dismiss", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		repo := repos.Announcements
+
+		base := now()
+		var news, notice *models.Announcement
+		for _, a := range []**models.Announcement{&news, &notice} {
+			*a = &models.Announcement{
+				ID: uuid.NewString(), Title: "hello", Level: models.AnnouncementInfo,
+				StartsAt: base, CreatedBy: alice.ID, CreatedAt: base, UpdatedAt: base,
+			}
+			if err := repo.Create(ctx, *a); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		for i := 0; i < 2; i++ { // dismissing twice is a no-op
+			if err := repo.Dismiss(ctx, news.ID, bob.ID, base); err != nil {
+				t.Fatalf("Dismiss: %v", err)
+			}
+		}
+		if err := repo.Dismiss(ctx, notice.ID, bob.ID, base); err != nil {
+			t.Fatalf("Dismiss: %v", err)
+		}
+		if err := repo.Dismiss(ctx, uuid.NewString(), bob.ID, base); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Dismiss(missing): got %v, want ErrNotFound", err)
+		}
+
+		ids, err := repo.ListDismissed(ctx, bob.ID)
+		if err != nil {
+			t.Fatalf("ListDismissed: %v", err)
+		}
+		want := []string{news.ID, notice.ID}
+		slices.Sort(ids)
+		slices.Sort(want)
+		assertOrder(t, "ListDismissed", ids, want)
+		if ids, err = repo.ListDismissed(ctx, alice.ID); err != nil || len(ids) != 0 {
+			t.Errorf("ListDismissed(alice) = %v, %v; want none", ids, err)
+		}
+
+		// Deleting an announcement forgets its dismissals.
+		if err := repo.Delete(ctx, news.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if ids, err = repo.ListDismissed(ctx, bob.ID); err != nil {
+			t.Fatalf("ListDismissed: %v", err)
+		}
+		assertOrder(t, "ListDismissed after Delete", ids, []string{notice.ID})
+	})
+}
+
+// --- Instance ---
+
+// InstanceRepository checks the InstanceRepository contract.
+func InstanceRepository(t *testing.T, newRepos NewRepos) {
+	repos := newRepos(t)
+	admin := mustCreateUser(t, repos.Users, newUser("admin", now()))
+	repo := repos.Instance
+
+	if _, err := repo.Get(ctx); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get(never saved): got %v, want ErrNotFound", err)
+	}
+
+	first := now().Add(-time.Hour)
+	saved := &models.Instance{
+		Name: "Movie Night", Description: "Friday films", LogoURL: "https://example.com/logo.png",
+		MOTD: "Welcome!", Contact: "admin@example.com", UpdatedBy: admin.ID, UpdatedAt: &first,
+	}
+	if err := repo.Put(ctx, saved); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := repo.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got, saved) {
+		t.Errorf("Get = %+v, want %+v", got, saved)
+	}
+
+	// Put replaces every field, empty ones included.
+	second := now()
+	replaced := &models.Instance{Name: "Movie Night", UpdatedBy: admin.ID, UpdatedAt: &second}
+	if err := repo.Put(ctx, replaced); err != nil {
+		t.Fatalf("Put(replacing): %v", err)
+	}
+	if got, err = repo.Get(ctx); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got, replaced) {
+		t.Errorf("Get after replacing = %+v, want %+v", got, replaced)
+	}
+}
+
+// --- Invites ---
+
+// InviteRepository checks the InviteRepository contract.
+func InviteRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CRUD", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := mustCreateUser(t, repos.Users, newUser("admin", now()))
+		repo := repos.Invites
+
+		base := now()
+		expires := base.Add(24 * time.Hour)
+		once := &models.Invite{
+			ID: uuid.NewString(), Code: "ONCE", MaxUses: 1, ExpiresAt: &expires, CreatedBy: admin.ID, CreatedAt: base,
+		}
+		team := &models.Invite{
+			ID: uuid.NewString(), Code: "TEAM", MaxUses: 10, CreatedBy: admin.ID, CreatedAt: base.Add(time.Second),
+		}
+		for _, i := range []*models.Invite{once, team} {
+			if err := repo.Create(ctx, i); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		dup := &models.Invite{ID: uuid.NewString(), Code: "TEAM", MaxUses: 1, CreatedBy: admin.ID, CreatedAt: base}
+		if err := repo.Create(ctx, dup); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Create(duplicate code): got %v, want ErrAlreadyExists", err)
+		}
+
+		got, err := repo.GetByID(ctx, once.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !reflect.DeepEqual(got, once) {
+			t.Errorf("GetByID = %+v, want %+v", got, once)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids := make([]string, len(list))
+		for i, inv := range list {
+			ids[i] = inv.ID
+		}
+		assertOrder(t, "List", ids, []string{team.ID, once.ID})
+
+		if err := repo.Delete(ctx, team.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, team.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, team.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+		if _, err := repo.Redeem(ctx, "TEAM", base); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Redeem(deleted): got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Redeem", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := mustCreateUser(t, repos.Users, newUser("admin", now()))
+		repo := repos.Invites
+
+		base := now()
+		expires := base.Add(time.Hour)
+		twice := &models.Invite{
+			ID: uuid.NewString(), Code: "TWICE", MaxUses: 2, ExpiresAt: &expires, CreatedBy: admin.ID, CreatedAt: base,
+		}
+		if err := repo.Create(ctx, twice); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		if _, err := repo.Redeem(ctx, "NOPE", base); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Redeem(unknown): got %v, want ErrNotFound", err)
+		}
+		if _, err := repo.Redeem(ctx, "TWICE", expires); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Redeem(expired): got %v, want ErrNotFound", err)
+		}
+		for n := 1; n <= 2; n++ {
+			got, err := repo.Redeem(ctx, "TWICE", base)
+			if err != nil {
+				t.Fatalf("Redeem #%d: %v", n, err)
+			}
+			if got.ID != twice.ID || got.Uses != n {
+				t.Errorf("Redeem #%d = %+v, want %s with %d uses", n, got, twice.ID, n)
+			}
+		}
+		if _, err := repo.Redeem(ctx, "TWICE", base); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Redeem(used up): got %v, want ErrNotFound", err)
+		}
+		if got, err := repo.GetByID(ctx, twice.ID); err != nil || got.Uses != 2 {
+			t.Errorf("GetByID after use = %+v, %v; want 2 uses", got, err)
+		}
+	})
+}
+
+// --- Legal documents ---
+
+// LegalRepository checks the LegalRepository contract.
+func LegalRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("Documents", func(t *testing.T) {
+		repos := newRepos(t)
+		admin := mustCreateUser(t, repos.Users, newUser("admin", now()))
+		repo := repos.Legal
+
+		if _, err := repo.Current(ctx, models.LegalTerms); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Current(none published): got %v, want ErrNotFound", err)
+		}
+
+		base := now()
+		v1 := &models.LegalDocument{
+			Kind: models.LegalTerms, Version: "v1", Title: "Terms", Body: "Be nice.", PublishedBy: admin.ID, PublishedAt: base,
+		}
+		v2 := &models.LegalDocument{
+			Kind: models.LegalTerms, Version: "v2", Title: "Terms", Body: "Be nicer.", PublishedBy: admin.ID, PublishedAt: base.Add(time.Hour),
+		}
+		privacy := &models.LegalDocument{
+			Kind: models.LegalPrivacy, Version: "v1", Title: "Privacy", Body: "We keep little.", PublishedBy: admin.ID, PublishedAt: base.Add(2 * time.Hour),
+		}
+		for _, d := range []*models.LegalDocument{v1, v2, privacy} {
+			if err := repo.Publish(ctx, d); err != nil {
+				t.Fatalf("Publish(%s %s): %v", d.Kind, d.Version, err)
+			}
+		}
+		dup := &models.LegalDocument{Kind: models.LegalTerms, Version: "v1", Title: "Again", Body: "x", PublishedBy: admin.ID, PublishedAt: base}
+		if err := repo.Publish(ctx, dup); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Publish(duplicate version): got %v, want ErrAlreadyExists", err)
+		}
+
+		got, err := repo.Current(ctx, models.LegalTerms)
+		if err != nil {
+			t.Fatalf("Current: %v", err)
+		}
+		if !reflect.DeepEqual(got, v2) {
+			t.Errorf("Current = %+v, want %+v", got, v2)
+		}
+		got, err = repo.GetVersion(ctx, models.LegalTerms, "v1")
+		if err != nil {
+			t.Fatalf("GetVersion: %v", err)
+		}
+		if !reflect.DeepEqual(got, v1) {
+			t.Errorf("GetVersion = %+v, want %+v", got, v1)
+		}
+		if _, err := repo.GetVersion(ctx, models.LegalPrivacy, "v2"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetVersion(missing): got %v, want ErrNotFound", err)
+		}
+
+		list, err := repo.ListVersions(ctx, models.LegalTerms)
+		if err != nil {
+			t.Fatalf("ListVersions: %v", err)
+		}
+		versions := make([]string, len(list))
+		for i, d := range list {
+			versions[i] = d.Version
+		}
+		assertOrder(t, "ListVersions", versions, []string{"v2", "v1"})
+	})
+
+	t.Run("Acceptances", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := mustCreateUser(t, repos.Users, newUser("alice", now()))
+		bob := mustCreateUser(t, repos.Users, newUser("bob", now()))
+		repo := repos.Legal
+
+		base := now()
+		first := &models.LegalAcceptance{UserID: alice.ID, Kind: models.LegalTerms, Version: "v1", AcceptedAt: base}
+		second := &models.LegalAcceptance{UserID: alice.ID, Kind: models.LegalTerms, Version: "v2", AcceptedAt: base.Add(time.Hour)}
+		for _, a := range []*models.LegalAcceptance{first, second} {
+			if err := repo.Accept(ctx, a); err != nil {
+				t.Fatalf("Accept(%s): %v", a.Version, err)
+			}
+		}
+		again := &models.LegalAcceptance{UserID: alice.ID, Kind: models.LegalTerms, Version: "v1", AcceptedAt: base.Add(2 * time.Hour)}
+		if err := repo.Accept(ctx, again); err != nil {
+			t.Fatalf("Accept(again): %v", err)
+		}
+
+		for _, tc := range []struct {
+			userID, kind, version string
+			want                  bool
+		}{
+			{alice.ID, models.LegalTerms, "v1", true},
+			{alice.ID, models.LegalTerms, "v2", true},
+			{alice.ID, models.LegalPrivacy, "v1", false},
+			{bob.ID, models.LegalTerms, "v1", false},
+		} {
+			ok, err := repo.HasAccepted(ctx, tc.userID, tc.kind, tc.version)
+			if err != nil {
+				t.Fatalf("HasAccepted: %v", err)
+			}
+			if ok != tc.want {
+				t.Errorf("HasAccepted(%s, %s, %s) = %v, want %v", tc.userID, tc.kind, tc.version, ok, tc.want)
+			}
+		}
+
+		list, err := repo.ListAcceptances(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("ListAcceptances: %v", err)
+		}
+		if !reflect.DeepEqual(list, []*models.LegalAcceptance{second, first}) {
+			t.Errorf("ListAcceptances = %+v, want v2 then v1 as first accepted", list)
+		}
+		if list, err := repo.ListAcceptances(ctx, bob.ID); err != nil || len(list) != 0 {
+			t.Errorf("ListAcceptances(none) = %v, %v; want empty", list, err)
+		}
+	})
+}
+
+// --- Roles ---
+
+// RoleRepository checks the RoleRepository contract.
+func RoleRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("CRUD", func(t *testing.T) {
+		repo := newRepos(t).Roles
+
+		base := now()
+		streamer := &models.RoleDefinition{
+			Name: "streamer", Description: "Hosts watch parties", Permissions: []string{"chat.send", "rooms.create"},
+			CreatedAt: base, UpdatedAt: base,
+		}
+		bot := &models.RoleDefinition{Name: "bot", Permissions: []string{}, CreatedAt: base, UpdatedAt: base}
+		for _, r := range []*models.RoleDefinition{streamer, bot} {
+			if err := repo.Create(ctx, r); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		dup := &models.RoleDefinition{Name: "bot", Permissions: []string{}, CreatedAt: base, UpdatedAt: base}
+		if err := repo.Create(ctx, dup); !errors.Is(err, repository.ErrAlreadyExists) {
+			t.Errorf("Create(duplicate name): got %v, want ErrAlreadyExists", err)
+		}
+
+		got, err := repo.Get(ctx, streamer.Name)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if fmt.Sprint(*got) != fmt.Sprint(*streamer) {
+			t.Errorf("Get = %+v, want %+v", got, streamer)
+		}
+
+		bot.Description = "Posts announcements"
+		bot.Permissions = []string{"chat.send", "broadcast.send"}
+		bot.UpdatedAt = base.Add(time.Minute)
+		if err := repo.Update(ctx, bot); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err := repo.Get(ctx, bot.Name); err != nil || fmt.Sprint(*got) != fmt.Sprint(*bot) {
+			t.Errorf("Get(updated) = %+v, %v, want %+v", got, err, bot)
+		}
+		missing := &models.RoleDefinition{Name: "ghost", Permissions: []string{}, UpdatedAt: base}
+		if err := repo.Update(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Update(missing): got %v, want ErrNotFound", err)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		names := make([]string, len(list))
+		for i, r := range list {
+			names[i] = r.Name
+		}
+		assertOrder(t, "List", names, []string{"bot", "streamer"})
+
+		if err := repo.Delete(ctx, streamer.Name); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.Get(ctx, streamer.Name); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Get(deleted): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, streamer.Name); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// --- Media files ---
+
+// MediaFileRepository checks the MediaFileRepository contract.
+func MediaFileRepository(t *testing.T, newRepos NewRepos) {
+	t.Run("Lifecycle", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.MediaFiles
+
+		base := now()
+		owner := mustCreateUser(t, repos.Users, newUser("uploader", base))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, base))
+		other := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, base))
+
+		newFile := func(roomID string, at time.Time) *models.MediaFile {
+			f := &models.MediaFile{
+				ID: uuid.NewString(), RoomID: roomID, UploadedBy: owner.ID, FileName: "clip.mp4",
+				MimeType: "video/mp4", Size: 1 << 20, Status: models.MediaFileUploading, CreatedAt: at, UpdatedAt: at,
+			}
+			if err := repo.Create(ctx, f); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			return f
+		}
+		oldest := newFile(room.ID, base.Add(-3*time.Hour))
+		older := newFile(room.ID, base.Add(-2*time.Hour))
+		newest := newFile(room.ID, base)
+		elsewhere := newFile(other.ID, base.Add(-4*time.Hour))
+
+		got, err := repo.GetByID(ctx, older.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if fmt.Sprint(*got) != fmt.Sprint(*older) {
+			t.Errorf("GetByID = %+v, want %+v", got, older)
+		}
+		if _, err := repo.GetByID(ctx, uuid.NewString()); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(missing): got %v, want ErrNotFound", err)
+		}
+
+		ready := base.Add(time.Minute)
+		if err := repo.MarkReady(ctx, older.ID, ready); err != nil {
+			t.Fatalf("MarkReady: %v", err)
+		}
+		if got, err := repo.GetByID(ctx, older.ID); err != nil || got.Status != models.MediaFileReady || !got.UpdatedAt.Equal(ready) {
+			t.Errorf("GetByID(ready) = %+v, %v, want status ready, updated %v", got, err, ready)
+		}
+		if err := repo.MarkReady(ctx, uuid.NewString(), ready); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("MarkReady(missing): got %v, want ErrNotFound", err)
+		}
+
+		ids := func(files []*models.MediaFile) []string {
+			out := make([]string, len(files))
+			for i, f := range files {
+				out[i] = f.ID
+			}
+			return out
+		}
+		list, err := repo.ListByRoom(ctx, room.ID, 2, 0)
+		if err != nil {
+			t.Fatalf("ListByRoom: %v", err)
+		}
+		assertOrder(t, "ListByRoom page 1", ids(list), []string{newest.ID, older.ID})
+		list, err = repo.ListByRoom(ctx, room.ID, 2, 2)
+		if err != nil {
+			t.Fatalf("ListByRoom: %v", err)
+		}
+		assertOrder(t, "ListByRoom page 2", ids(list), []string{oldest.ID})
+
+		stale, err := repo.ListUploadingBefore(ctx, base.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("ListUploadingBefore: %v", err)
+		}
+		assertOrder(t, "ListUploadingBefore", ids(stale), []string{elsewhere.ID, oldest.ID})
+
+		if total, err := repo.TotalSizeByUploader(ctx, owner.ID); err != nil || total != 4<<20 {
+			t.Errorf("TotalSizeByUploader = %d, %v; want %d", total, err, 4<<20)
+		}
+		if total, err := repo.TotalSizeByUploader(ctx, uuid.NewString()); err != nil || total != 0 {
+			t.Errorf("TotalSizeByUploader(no uploads) = %d, %v; want 0", total, err)
+		}
+
+		if err := repo.Delete(ctx, oldest.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, oldest.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, oldest.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+		}
+	})
+	t.Run("Transcode", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.MediaFiles
+
+		base := now()
+		owner := mustCreateUser(t, repos.Users, newUser("uploader", base))
+		room := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, base))
+
+		newFile := func() *models.MediaFile {
+			f := &models.MediaFile{
+				ID: uuid.NewString(), RoomID: room.ID, UploadedBy: owner.ID, FileName: "clip.mp4",
+				MimeType: "video/mp4", Size: 1 << 20, Status: models.MediaFileReady, CreatedAt: base, UpdatedAt: base,
+			}
+			if err := repo.Create(ctx, f); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			return f
+		}
+		queue := func(f *models.MediaFile, at time.Time) {
+			if err := repo.SetTranscode(ctx, f.ID, models.Transcode{Status: models.TranscodeQueued, UpdatedAt: at}); err != nil {
+				t.Fatalf("SetTranscode: %v", err)
+			}
+		}
+		untouched := newFile()
+		second := newFile()
+		first := newFile()
+		queue(second, base.Add(-time.Minute))
+		queue(first, base.Add(-2*time.Minute))
+
+		if got, err := repo.GetByID(ctx, untouched.ID); err != nil || got.Transcode != nil {
+			t.Errorf("GetByID(never queued) = %+v, %v, want no transcode", got, err)
+		}
+		if err := repo.SetTranscode(ctx, uuid.NewString(), models.Transcode{Status: models.TranscodeQueued}); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("SetTranscode(missing): got %v, want ErrNotFound", err)
+		}
+
+		claimed, err := repo.ClaimTranscode(ctx, "w1", base, base.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("ClaimTranscode: %v", err)
+		}
+		if claimed.ID != first.ID || claimed.Transcode == nil || claimed.Transcode.Status != models.TranscodeRunning ||
+			claimed.Transcode.Worker != "w1" || !claimed.Transcode.UpdatedAt.Equal(base) {
+			t.Errorf("ClaimTranscode = %+v (%+v), want %s running for w1", claimed, claimed.Transcode, first.ID)
+		}
+		if claimed, err := repo.ClaimTranscode(ctx, "w2", base, base.Add(-time.Hour)); err != nil || claimed.ID != second.ID {
+			t.Errorf("ClaimTranscode(second) = %+v, %v, want %s", claimed, err, second.ID)
+		}
+		if _, err := repo.ClaimTranscode(ctx, "w3", base, base.Add(-time.Hour)); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("ClaimTranscode(none left): got %v, want ErrNotFound", err)
+		}
+
+		progress := models.Transcode{Status: models.TranscodeRunning, Progress: 0.5, Worker: "w1", UpdatedAt: base.Add(time.Second)}
+		if err := repo.UpdateTranscode(ctx, first.ID, "w1", progress); err != nil {
+			t.Fatalf("UpdateTranscode: %v", err)
+		}
+		if got, err := repo.GetByID(ctx, first.ID); err != nil || got.Transcode == nil || fmt.Sprint(*got.Transcode) != fmt.Sprint(progress) {
+			t.Errorf("GetByID(progress) = %+v, %v, want transcode %+v", got, err, progress)
+		}
+		if err := repo.UpdateTranscode(ctx, first.ID, "w2", progress); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateTranscode(other worker): got %v, want ErrNotFound", err)
+		}
+
+		// w2 went quiet: its job is stale and goes to w3, and w2 loses it.
+		stolen, err := repo.ClaimTranscode(ctx, "w3", base.Add(2*time.Second), base.Add(time.Millisecond))
+		if err != nil || stolen.ID != second.ID || stolen.Transcode.Worker != "w3" {
+			t.Fatalf("ClaimTranscode(stale) = %+v, %v, want %s for w3", stolen, err, second.ID)
+		}
+		failed := models.Transcode{Status: models.TranscodeFailed, Worker: "w2", Error: "boom", UpdatedAt: base.Add(3 * time.Second)}
+		if err := repo.UpdateTranscode(ctx, second.ID, "w2", failed); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateTranscode(lost job): got %v, want ErrNotFound", err)
+		}
+
+		done := models.Transcode{Status: models.TranscodeDone, Progress: 1, Worker: "w1", UpdatedAt: base.Add(3 * time.Second)}
+		if err := repo.UpdateTranscode(ctx, first.ID, "w1", done); err != nil {
+			t.Fatalf("UpdateTranscode(done): %v", err)
+		}
+		if err := repo.UpdateTranscode(ctx, first.ID, "w1", done); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateTranscode(finished): got %v, want ErrNotFound", err)
+		}
+		if _, err := repo.ClaimTranscode(ctx, "w4", base.Add(time.Hour), base.Add(time.Hour)); err != nil {
+			t.Errorf("ClaimTranscode(w3's job, stale by now): %v", err)
+		}
+		if _, err := repo.ClaimTranscode(ctx, "w4", base.Add(time.Hour), base.Add(time.Hour)); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("ClaimTranscode(done jobs only): got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// --- Subtitles ---
+
+// SubtitleRepository checks the SubtitleRepository contract.
+func SubtitleRepository(t *testing.T, newRepos NewRepos) {
+	repos := newRepos(t)
+	repo := repos.Subtitles
+
+	base := now()
+	owner := mustCreateUser(t, repos.Users, newUser("uploader", base))
+	room := mustCreateRoom(t, repos.Rooms, newRoom(owner.ID, models.RoomTypePublic, base))
+	newFile := func() *models.MediaFile {
+		f := &models.MediaFile{
+			ID: uuid.NewString(), RoomID: room.ID, UploadedBy: owner.ID, FileName: "clip.mp4",
+			MimeType: "video/mp4", Size: 1 << 20, Status: models.MediaFileReady, CreatedAt: base, UpdatedAt: base,
+		}
+		if err := repos.MediaFiles.Create(ctx, f); err != nil {
+			t.Fatalf("Create media file: %v", err)
+		}
+		return f
+	}
+	movie, trailer := newFile(), newFile()
+
+	newSub := func(mediaID, label string, at time.Time) *models.Subtitle {
+		s := &models.Subtitle{
+			ID: uuid.NewString(), MediaID: mediaID, RoomID: room.ID, UploadedBy: owner.ID,
+			Label: label, Language: "en", Format: models.SubtitleFormatSRT, CreatedAt: at,
+		}
+		if err := repo.Create(ctx, s); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return s
+	}
+	english := newSub(movie.ID, "English", base.Add(-3*time.Hour))
+	sdh := newSub(movie.ID, "English (SDH)", base.Add(-time.Hour))
+	promo := newSub(trailer.ID, "English", base.Add(-2*time.Hour))
+
+	got, err := repo.GetByID(ctx, sdh.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if fmt.Sprint(*got) != fmt.Sprint(*sdh) {
+		t.Errorf("GetByID = %+v, want %+v", got, sdh)
+	}
+	if _, err := repo.GetByID(ctx, uuid.NewString()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID(missing): got %v, want ErrNotFound", err)
+	}
+
+	ids := func(subs []*models.Subtitle) []string {
+		out := make([]string, len(subs))
+		for i, s := range subs {
+			out[i] = s.ID
+		}
+		return out
+	}
+	list, err := repo.ListByMedia(ctx, movie.ID)
+	if err != nil {
+		t.Fatalf("ListByMedia: %v", err)
+	}
+	assertOrder(t, "ListByMedia", ids(list), []string{english.ID, sdh.ID})
+	list, err = repo.ListByRoom(ctx, room.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("ListByRoom: %v", err)
+	}
+	assertOrder(t, "ListByRoom page 1", ids(list), []string{sdh.ID, promo.ID})
+	list, err = repo.ListByRoom(ctx, room.ID, 2, 2)
+	if err != nil {
+		t.Fatalf("ListByRoom: %v", err)
+	}
+	assertOrder(t, "ListByRoom page 2", ids(list), []string{english.ID})
+
+	if err := repo.Delete(ctx, sdh.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, sdh.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+	}
+	if err := repo.DeleteByMedia(ctx, movie.ID); err != nil {
+		t.Fatalf("DeleteByMedia: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, english.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID(deleted with media): got %v, want ErrNotFound", err)
+	}
+	if _, err := repo.GetByID(ctx, promo.ID); err != nil {
+		t.Errorf("GetByID(other media's): %v", err)
+	}
+}
+
+// --- Library ---
+
+// LibraryRepository checks the LibraryRepository contract.
+func LibraryRepository(t *testing.T, newRepos NewRepos) {
+	repos := newRepos(t)
+	repo := repos.Library
+
+	base := now()
+	alice := mustCreateUser(t, repos.Users, newUser("alice", base))
+	bob := mustCreateUser(t, repos.Users, newUser("bob", base))
+	room := mustCreateRoom(t, repos.Rooms, newRoom(alice.ID, models.RoomTypePublic, base))
+
+	newItem := func(ownerID, roomID, title, url, folder string, tags []string, at time.Time) *models.LibraryItem {
+		item := &models.LibraryItem{
+			ID: uuid.NewString(), OwnerID: ownerID, RoomID: roomID, AddedBy: alice.ID,
+			Title: title, URL: url, Folder: folder, Tags: tags, CreatedAt: at, UpdatedAt: at,
+		}
+		if err := repo.Create(ctx, item); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return item
+	}
+	trailer := newItem(alice.ID, "", "Space Trailer", "https://example.com/space.m3u8", "Trailers", []string{"scifi", "space"}, base.Add(-3*time.Hour))
+	trailer.Metadata = &models.MediaMetadata{
+		Kind: models.MetadataKindMovie, Title: "Space", Year: 2024, PosterURL: "https://example.com/poster.jpg",
+		Runtime: 3, Source: models.MetadataSourceTMDB, SourceID: "42",
+	}
+	if err := repo.Update(ctx, trailer); err != nil {
+		t.Fatalf("Update(metadata): %v", err)
+	}
+	concert := newItem(alice.ID, "", "Live 100% Concert", "https://example.com/live.mp4", "", []string{"music"}, base.Add(-2*time.Hour))
+	lecture := newItem(alice.ID, "", "Physics Lecture", "https://example.com/SPACE_time.mp4", "Study", []string{}, base.Add(-time.Hour))
+	bobs := newItem(bob.ID, "", "Space Documentary", "https://example.com/doc.mp4", "", []string{"space"}, base)
+	shared := newItem("", room.ID, "Space Trailer", trailer.URL, "Trailers", []string{"scifi", "space"}, base.Add(-30*time.Minute))
+
+	got, err := repo.GetByID(ctx, trailer.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertLibraryItem(t, "GetByID", got, trailer)
+	if got, err = repo.GetByID(ctx, concert.ID); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertLibraryItem(t, "GetByID(no metadata)", got, concert)
+	if _, err := repo.GetByID(ctx, uuid.NewString()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID(missing): got %v, want ErrNotFound", err)
+	}
+
+	ids := func(items []*models.LibraryItem) []string {
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = item.ID
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name   string
+		filter repository.LibraryFilter
+		want   []string
+	}{
+		{"owner", repository.LibraryFilter{OwnerID: alice.ID, Limit: 10}, []string{lecture.ID, concert.ID, trailer.ID}},
+		{"page", repository.LibraryFilter{OwnerID: alice.ID, Limit: 2, Offset: 1}, []string{concert.ID, trailer.ID}},
+		{"room", repository.LibraryFilter{RoomID: room.ID, Limit: 10}, []string{shared.ID}},
+		{"folder", repository.LibraryFilter{OwnerID: alice.ID, Folder: "Trailers", Limit: 10}, []string{trailer.ID}},
+		{"tag", repository.LibraryFilter{Tag: "space", Limit: 10}, []string{bobs.ID, shared.ID, trailer.ID}},
+		{"query title or url, any case", repository.LibraryFilter{OwnerID: alice.ID, Query: "sPaCe", Limit: 10}, []string{lecture.ID, trailer.ID}},
+		{"query is literal", repository.LibraryFilter{OwnerID: alice.ID, Query: "100%", Limit: 10}, []string{concert.ID}},
+		{"query underscore is literal", repository.LibraryFilter{OwnerID: alice.ID, Query: "e_t", Limit: 10}, []string{lecture.ID}},
+	} {
+		list, err := repo.List(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("List(%s): %v", tc.name, err)
+		}
+		assertOrder(t, "List("+tc.name+")", ids(list), tc.want)
+	}
+
+	if n, err := repo.Count(ctx, alice.ID, ""); err != nil || n != 3 {
+		t.Errorf("Count(alice) = %d, %v; want 3", n, err)
+	}
+	if n, err := repo.Count(ctx, "", room.ID); err != nil || n != 1 {
+		t.Errorf("Count(room) = %d, %v; want 1", n, err)
+	}
+
+	updated := *concert
+	updated.Title, updated.Folder, updated.Tags = "Live Concert", "Music", []string{"live", "music"}
+	updated.UpdatedAt = base.Add(time.Minute)
+	if err := repo.Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = repo.GetByID(ctx, concert.ID)
+	if err != nil {
+		t.Fatalf("GetByID(updated): %v", err)
+	}
+	assertLibraryItem(t, "GetByID(updated)", got, &updated)
+	missing := updated
+	missing.ID = uuid.NewString()
+	if err := repo.Update(ctx, &missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update(missing): got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, lecture.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, lecture.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete twice: got %v, want ErrNotFound", err)
+	}
+	if n, err := repo.Count(ctx, alice.ID, ""); err != nil || n != 2 {
+		t.Errorf("Count(alice) after Delete = %d, %v; want 2", n, err)
+	}
+}
+
+// --- Metadata ---
+
+// MetadataRepository checks the MetadataRepository contract.
+func MetadataRepository(t *testing.T, newRepos NewRepos) {
+	repo := newRepos(t).Metadata
+	base := now()
+
+	if _, err := repo.Get(ctx, "alien (1979)"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get(missing): got %v, want ErrNotFound", err)
+	}
+
+	miss := &models.MetadataLookup{Key: "alien (1979)", FetchedAt: base.Add(-time.Hour)}
+	if err := repo.Put(ctx, miss); err != nil {
+		t.Fatalf("Put(miss): %v", err)
+	}
+	got, err := repo.Get(ctx, miss.Key)
+	if err != nil {
+		t.Fatalf("Get(miss): %v", err)
+	}
+	if got.Key != miss.Key || got.Metadata != nil || !got.FetchedAt.Equal(miss.FetchedAt) {
+		t.Errorf("Get(miss) = %+v, want %+v", got, miss)
+	}
+
+	hit := &models.MetadataLookup{Key: miss.Key, FetchedAt: base, Metadata: &models.MediaMetadata{
+		Kind: models.MetadataKindMovie, Title: "Alien", Year: 1979, PosterURL: "https://example.com/alien.jpg",
+		Runtime: 117, Source: models.MetadataSourceTMDB, SourceID: "348",
+	}}
+	if err := repo.Put(ctx, hit); err != nil {
+		t.Fatalf("Put(replacing): %v", err)
+	}
+	got, err = repo.Get(ctx, hit.Key)
+	if err != nil {
+		t.Fatalf("Get(hit): %v", err)
+	}
+	if got.Metadata == nil || *got.Metadata != *hit.Metadata || !got.FetchedAt.Equal(hit.FetchedAt) {
+		t.Errorf("Get(hit) = %+v, want %+v", got, hit)
+	}
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func newUser(username string, createdAt time.Time) *models.User {
+	return &models.User{
+		ID:           uuid.NewString(),
+		Username:     username,
+		PasswordHash: "hash-" + username,
+		Role:         models.RoleMember,
+		TrustLevel:   models.TrustNew,
+		Status:       models.StatusOffline,
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+	}
+}
+
+func newRoom(ownerID, roomType string, createdAt time.Time) *models.Room {
+	return &models.Room{
+		ID:         uuid.NewString(),
+		Name:       "room",
+		Type:       roomType,
+		CreatedBy:  ownerID,
+		IsActive:   true,
+		MaxMembers: 50,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+}
+
+func newMessage(roomID string, sender *models.User, content string, createdAt time.Time) *models.ChatMessage {
+	return &models.ChatMessage{
+		ID:        uuid.NewString(),
+		RoomID:    roomID,
+		SenderID:  sender.ID,
+		Sender:    sender.Username,
+		Type:      models.MsgTypeChat,
+		Content:   content,
+		CreatedAt: createdAt,
+	}
+}
+
+func mustCreateUser(t *testing.T, repo repository.UserRepository, u *models.User) *models.User {
+	t.Helper()
+	if err := repo.Create(ctx, u); err != nil {
+		t.Fatalf("create user %s: %v", u.Username, err)
+	}
+	return u
+}
+
+func mustCreateRoom(t *testing.T, repo repository.RoomRepository, r *models.Room) *models.Room {
+	t.Helper()
+	if err := repo.Create(ctx, r); err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	return r
+}
+
+func mustAddMember(t *testing.T, repo repository.RoomRepository, roomID, userID, role string) {
+	t.Helper()
+	if err := repo.AddMember(ctx, roomID, userID, role); err != nil {
+		t.Fatalf("AddMember: %v", err)
+	}
+}
+
+func mustCreateMessage(t *testing.T, repo repository.MessageRepository, m *models.ChatMessage) {
+	t.Helper()
+	if err := repo.Create(ctx, m); err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+}
+
+func assertUser(t *testing.T, got, want *models.User) {
+	t.Helper()
+	if got.ID != want.ID || got.Username != want.Username || got.PasswordHash != want.PasswordHash ||
+		got.Role != want.Role || got.Status != want.Status || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("user = %+v, want %+v", got, want)
+	}
+}
+
+func assertJSON(t *testing.T, field string, got json.RawMessage, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Errorf("%s: invalid JSON %q: %v", field, got, err)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	if fmt.Sprint(g) != fmt.Sprint(w) {
+		t.Errorf("%s = %s, want %s", field, got, want)
+	}
+}
+
+func assertOrder(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s:\n got  %v\n want %v", what, got, want)
+	}
+}
+
+// assertLibraryItem compares items field by field, metadata by value.
+func assertLibraryItem(t *testing.T, what string, got, want *models.LibraryItem) {
+	t.Helper()
+	g, w := *got, *want
+	g.Metadata, w.Metadata = nil, nil
+	if fmt.Sprint(g) != fmt.Sprint(w) || !reflect.DeepEqual(got.Metadata, want.Metadata) {
+		t.Errorf("%s = %+v, want %+v", what, got, want)
+	}
+}
+
+func userIDs(users []*models.User) []string {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+func roomIDs(rooms []*models.Room) []string {
+	ids := make([]string, len(rooms))
+	for i, r := range rooms {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func messageIDs(msgs []*models.ChatMessage) []string {
+	ids := make([]string, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func auditIDs(entries []*models.AuditEntry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func deadLetterIDs(letters []*models.DeadLetter) []string {
+	ids := make([]string, len(letters))
+	for i, l := range letters {
+		ids[i] = l.ID
+	}
+	return ids
+}
+
+func reportIDs(reports []*models.Report) []string {
+	ids := make([]string, len(reports))
+	for i, r := range reports {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func announcementIDs(announcements []*models.Announcement) []string {
+	ids := make([]string, len(announcements))
+	for i, a := range announcements {
+		ids[i] = a.ID
+	}
+	return ids
+}
+
+func wordFilterIDs(filters []*models.WordFilter) []string {
+	ids := make([]string, len(filters))
+	for i, f := range filters {
+		ids[i] = f.ID
+	}
+	return ids
+}
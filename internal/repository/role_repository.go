@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// RoleRepository stores the custom roles admins define; the built-in
+// roles live in internal/authz and are never stored. Roles are keyed by
+// name. Like the allowed origins, the list is small and always loaded
+// whole.
+type RoleRepository interface {
+	// Create stores a new role. Returns ErrAlreadyExists if the name is taken.
+	Create(ctx context.Context, role *models.RoleDefinition) error
+
+	// Update replaces a role's description and permissions and sets
+	// UpdatedAt. Returns ErrNotFound if missing.
+	Update(ctx context.Context, role *models.RoleDefinition) error
+
+	// Delete removes a role. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, name string) error
+
+	// Get retrieves a role by name. Returns ErrNotFound if missing.
+	Get(ctx context.Context, name string) (*models.RoleDefinition, error)
+
+	// List returns every role, by name.
+	List(ctx context.Context) ([]*models.RoleDefinition, error)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"ofenes/internal/models"
 )
@@ -17,10 +18,23 @@ type RoomRepository interface {
 	// List returns rooms the given user is a member of.
 	List(ctx context.Context, userID string, limit, offset int) ([]*models.Room, error)
 
-	// ListPublic returns all active public rooms.
-	ListPublic(ctx context.Context, limit, offset int) ([]*models.Room, error)
+	// CountOwned returns how many active rooms userID owns.
+	CountOwned(ctx context.Context, userID string) (int, error)
 
-	// Update updates a room's name, description, or max members.
+	// ListPublic returns all active public rooms, newest first, or only
+	// those tagged tag if it is not empty.
+	ListPublic(ctx context.Context, tag string, limit, offset int) ([]*models.Room, error)
+
+	// TagCounts returns the tags of active public rooms created at or
+	// after since, each with how many of those rooms carry it, most used
+	// first (ties by tag), at most limit of them.
+	TagCounts(ctx context.Context, since time.Time, limit int) ([]models.TagCount, error)
+
+	// ListAll returns every room, including inactive ones, oldest first.
+	// Used by background jobs.
+	ListAll(ctx context.Context, limit, offset int) ([]*models.Room, error)
+
+	// Update updates a room's name, description, max members and tags.
 	Update(ctx context.Context, room *models.Room) error
 
 	// Delete soft-deletes a room (sets is_active = false).
@@ -38,6 +52,22 @@ type RoomRepository interface {
 	// GetMemberRole returns the role of a user in a room. Returns ErrNotFound if not a member.
 	GetMemberRole(ctx context.Context, roomID, userID string) (string, error)
 
+	// SetMemberRole changes the role of a room member. Returns ErrNotFound
+	// if the user is not a member.
+	SetMemberRole(ctx context.Context, roomID, userID, role string) error
+
 	// UpdateVideoState updates the synchronized video state for a room.
 	UpdateVideoState(ctx context.Context, roomID string, state models.VideoState) error
+
+	// UpdateRetention sets a room's message retention policy; nil restores
+	// the server default.
+	UpdateRetention(ctx context.Context, roomID string, policy *models.RetentionPolicy) error
+
+	// UpdateSyncPolicy sets how far a room's players may drift before they
+	// are corrected; nil restores the server default.
+	UpdateSyncPolicy(ctx context.Context, roomID string, policy *models.SyncPolicy) error
+
+	// UpdateScreens replaces a room's screens besides the main one; nil or
+	// empty leaves it only the main one.
+	UpdateScreens(ctx context.Context, roomID string, screens []models.Screen) error
 }
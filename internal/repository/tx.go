@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repos groups the repositories available inside a unit of work.
+type Repos struct {
+	Users          UserRepository
+	Rooms          RoomRepository
+	Messages       MessageRepository
+	Media          MediaSessionRepository
+	Files          SharedFileRepository
+	MediaFiles     MediaFileRepository
+	Subtitles      SubtitleRepository
+	Library        LibraryRepository
+	Metadata       MetadataRepository
+	Audit          AuditRepository
+	DeadLetters    DeadLetterRepository
+	Reports        ReportRepository
+	WordFilters    WordFilterRepository
+	AllowedOrigins AllowedOriginRepository
+	Announcements  AnnouncementRepository
+	Instance       InstanceRepository
+	Invites        InviteRepository
+	Legal          LegalRepository
+	Roles          RoleRepository
+}
+
+// UnitOfWork runs multi-step operations atomically.
+//
+// Usage:
+//
+//	err := uow.WithinTx(ctx, func(tx repository.Repos) error {
+//	    if err := tx.Rooms.Create(ctx, room); err != nil {
+//	        return err
+//	    }
+//	    return tx.Rooms.AddMember(ctx, room.ID, userID, models.RoomRoleOwner)
+//	})
+type UnitOfWork interface {
+	// WithinTx calls fn with repositories bound to a single transaction.
+	// If fn returns an error (or panics) the transaction is rolled back,
+	// otherwise it is committed. Use only the repositories passed to fn.
+	WithinTx(ctx context.Context, fn func(tx Repos) error) error
+}
+
+// --- PostgreSQL ---
+
+// pgDB is the query interface shared by *pgxpool.Pool and pgx.Tx, so the
+// same Pg*Repo code runs inside or outside a transaction.
+type pgDB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PgUnitOfWork implements UnitOfWork with PostgreSQL transactions.
+type PgUnitOfWork struct {
+	pool  *pgxpool.Pool
+	cache *CachedUserRepo
+}
+
+// NewPgUnitOfWork creates a UnitOfWork over pool. If users is a
+// CachedUserRepo, users written inside a transaction are evicted from the
+// cache once it ends.
+func NewPgUnitOfWork(pool *pgxpool.Pool, users UserRepository) *PgUnitOfWork {
+	cache, _ := users.(*CachedUserRepo)
+	return &PgUnitOfWork{pool: pool, cache: cache}
+}
+
+// WithinTx runs fn in a PostgreSQL transaction.
+func (u *PgUnitOfWork) WithinTx(ctx context.Context, fn func(tx Repos) error) error {
+	tx, err := u.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback(ctx)
+
+	repos := Repos{
+		Users:          &PgUserRepo{db: tx},
+		Rooms:          &PgRoomRepo{db: tx},
+		Messages:       &PgMessageRepo{db: tx},
+		Media:          &PgMediaSessionRepo{db: tx},
+		Files:          &PgSharedFileRepo{db: tx},
+		MediaFiles:     &PgMediaFileRepo{db: tx},
+		Subtitles:      &PgSubtitleRepo{db: tx},
+		Library:        &PgLibraryRepo{db: tx},
+		Metadata:       &PgMetadataRepo{db: tx},
+		Audit:          &PgAuditRepo{db: tx},
+		DeadLetters:    &PgDeadLetterRepo{db: tx},
+		Reports:        &PgReportRepo{db: tx},
+		WordFilters:    &PgWordFilterRepo{db: tx},
+		AllowedOrigins: &PgAllowedOriginRepo{db: tx},
+		Announcements:  &PgAnnouncementRepo{db: tx},
+		Instance:       &PgInstanceRepo{db: tx},
+		Invites:        &PgInviteRepo{db: tx},
+		Legal:          &PgLegalRepo{db: tx},
+		Roles:          &PgRoleRepo{db: tx},
+	}
+	if u.cache != nil {
+		var flush func()
+		repos.Users, flush = u.cache.deferInvalidation(repos.Users)
+		defer flush()
+	}
+
+	if err := fn(repos); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// --- Non-transactional backends ---
+
+// NoTxUnitOfWork runs fn against the regular repositories without a
+// transaction: steps are applied one by one and not rolled back on error.
+// Used for the memory, MongoDB and bbolt backends.
+type NoTxUnitOfWork struct {
+	repos Repos
+}
+
+// NewNoTxUnitOfWork creates a pass-through UnitOfWork over repos.
+func NewNoTxUnitOfWork(repos Repos) *NoTxUnitOfWork {
+	return &NoTxUnitOfWork{repos: repos}
+}
+
+// WithinTx calls fn with the regular repositories.
+func (u *NoTxUnitOfWork) WithinTx(_ context.Context, fn func(tx Repos) error) error {
+	return fn(u.repos)
+}
@@ -8,6 +8,8 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"strings"
+	"time"
 
 	"ofenes/internal/models"
 )
@@ -15,17 +17,25 @@ import (
 // UserRepository defines the contract for user data access.
 // Any storage backend (memory, PostgreSQL) must satisfy this.
 type UserRepository interface {
-	// Create stores a new user. Returns an error if the username already exists.
+	// Create stores a new user. Returns an error if the username already
+	// exists in any case.
 	Create(ctx context.Context, user *models.User) error
 
 	// GetByID retrieves a user by their unique ID.
-	// Returns ErrNotFound if the user does not exist.
+	// Returns ErrNotFound if the user does not exist or is soft-deleted.
 	GetByID(ctx context.Context, id string) (*models.User, error)
 
-	// GetByUsername retrieves a user by their username.
-	// Returns ErrNotFound if the user does not exist.
+	// GetByUsername retrieves a user by their username, ignoring case
+	// (usernames are unique by username.Key).
+	// Returns ErrNotFound if the user does not exist or is soft-deleted.
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 
+	// GetBySkeleton retrieves a user whose username has the given
+	// username.Skeleton, i.e. reads the same as a name with that skeleton.
+	// If several do, it returns any of them.
+	// Returns ErrNotFound if there is none that is not soft-deleted.
+	GetBySkeleton(ctx context.Context, skeleton string) (*models.User, error)
+
 	// Update updates a user's profile fields (display name, avatar, bio).
 	Update(ctx context.Context, user *models.User) error
 
@@ -35,6 +45,126 @@ type UserRepository interface {
 	// UpdatePreferences replaces the user's preferences JSON.
 	UpdatePreferences(ctx context.Context, userID string, prefs json.RawMessage) error
 
-	// List returns a paginated list of users.
-	List(ctx context.Context, limit, offset int) ([]*models.User, error)
+	// List returns the users matching filter, ordered and paginated as it
+	// specifies. Soft-deleted users are excluded unless IncludeDeleted is set.
+	List(ctx context.Context, filter UserFilter) ([]*models.User, error)
+
+	// CountByRole returns how many users match filter for each role,
+	// ignoring filter.Role and the pagination fields. Roles with no
+	// matching users are omitted.
+	CountByRole(ctx context.Context, filter UserFilter) (map[string]int, error)
+
+	// SetShadowBanned shadow-bans a user (ShadowBannedAt is set, and kept
+	// if already set) or lifts the shadow ban.
+	SetShadowBanned(ctx context.Context, id string, banned bool) error
+
+	// SetTrustLevel sets a user's trust level (models.Trust*).
+	SetTrustLevel(ctx context.Context, id, level string) error
+
+	// SetRole sets a user's role (models.Role*).
+	SetRole(ctx context.Context, id, role string) error
+
+	// --- Soft delete ---
+	// Soft-deleted users are hidden from every method above (updates return
+	// ErrNotFound) but keep their username, so it cannot be re-registered
+	// and Restore always succeeds. Only the methods below see them; they
+	// are meant for admin tooling.
+
+	// Delete soft-deletes a user and sets their status to offline.
+	// Returns ErrNotFound if the user does not exist or is already deleted.
+	Delete(ctx context.Context, id string) error
+
+	// Restore undoes Delete.
+	// Returns ErrNotFound if the user does not exist, is not deleted, or
+	// has been anonymized.
+	Restore(ctx context.Context, id string) error
+
+	// Anonymize erases a user's personal data for good: the username is
+	// replaced by pseudonym, profile fields, preferences and the password
+	// hash are cleared, and the user is soft-deleted (if not already) with
+	// AnonymizedAt set. The row itself is kept so messages and audit
+	// entries still resolve. Returns ErrNotFound if the user does not exist
+	// or is already anonymized, ErrAlreadyExists if pseudonym is taken.
+	Anonymize(ctx context.Context, id, pseudonym string) error
+
+	// GetByIDWithDeleted retrieves a user by ID, including soft-deleted users.
+	GetByIDWithDeleted(ctx context.Context, id string) (*models.User, error)
+
+	// ListDeleted returns soft-deleted users, most recently deleted first.
+	ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error)
+}
+
+// User list sort keys for UserFilter.Sort.
+const (
+	UserSortCreatedAt = "created_at" // default
+	UserSortUsername  = "username"
+)
+
+// UserFilter selects and orders users for UserRepository.List.
+// Zero-valued fields don't filter.
+type UserFilter struct {
+	Role           string    // exact role
+	CreatedAfter   time.Time // created strictly after this instant
+	UsernamePrefix string    // case-sensitive username prefix
+	IncludeDeleted bool      // also return soft-deleted users
+	ShadowBanned   bool      // only shadow-banned users
+
+	Sort   string // UserSortCreatedAt or UserSortUsername
+	Desc   bool   // reverse the sort order
+	Limit  int
+	Offset int
+}
+
+// matches reports whether u passes the filter's conditions. Used by the
+// backends that filter in Go rather than in a query.
+func (f UserFilter) matches(u *models.User) bool {
+	return (f.IncludeDeleted || u.DeletedAt == nil) &&
+		(f.Role == "" || u.Role == f.Role) &&
+		(f.CreatedAfter.IsZero() || u.CreatedAt.After(f.CreatedAfter)) &&
+		(!f.ShadowBanned || u.ShadowBannedAt != nil) &&
+		strings.HasPrefix(u.Username, f.UsernamePrefix)
+}
+
+// anonymize applies UserRepository.Anonymize to u. Used by the backends
+// that update users in Go rather than in a query.
+func anonymize(u *models.User, pseudonym string, now time.Time) {
+	u.Username = pseudonym
+	u.PasswordHash = ""
+	u.DisplayName, u.AvatarURL, u.Bio = nil, nil, nil
+	u.Preferences = json.RawMessage(`{}`)
+	u.Status = models.StatusOffline
+	if u.DeletedAt == nil {
+		u.DeletedAt = &now
+	}
+	u.AnonymizedAt = &now
+	u.UpdatedAt = now
+}
+
+// setShadowBanned applies UserRepository.SetShadowBanned to u. Used by
+// the backends that update users in Go rather than in a query.
+func setShadowBanned(u *models.User, banned bool, now time.Time) {
+	switch {
+	case !banned:
+		u.ShadowBannedAt = nil
+	case u.ShadowBannedAt == nil:
+		u.ShadowBannedAt = &now
+	}
+}
+
+// less orders users by the filter's sort key, breaking ties by ID.
+func (f UserFilter) less(a, b *models.User) bool {
+	if f.Desc {
+		a, b = b, a
+	}
+	switch f.Sort {
+	case UserSortUsername:
+		if a.Username != b.Username {
+			return a.Username < b.Username
+		}
+	default:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	return a.ID < b.ID
 }
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"ofenes/internal/models"
+)
+
+// WordFilterRepository stores the chat word filters. The lists are small
+// and the Hub always loads them whole, so there is no filtering or
+// pagination.
+type WordFilterRepository interface {
+	// Create stores a new filter.
+	Create(ctx context.Context, filter *models.WordFilter) error
+
+	// Update replaces a filter's pattern, regex flag, action and room, and
+	// sets UpdatedAt. Returns ErrNotFound if missing.
+	Update(ctx context.Context, filter *models.WordFilter) error
+
+	// Delete removes a filter. Returns ErrNotFound if missing.
+	Delete(ctx context.Context, id string) error
+
+	// GetByID retrieves a filter by ID. Returns ErrNotFound if missing.
+	GetByID(ctx context.Context, id string) (*models.WordFilter, error)
+
+	// List returns every filter, oldest first.
+	List(ctx context.Context) ([]*models.WordFilter, error)
+}
@@ -0,0 +1,204 @@
+// Package retention deletes old data according to configured policies.
+//
+// Each kind of data registers a Target that can count and delete records
+// older than a cutoff; a policy gives each target a maximum age in days.
+// The Engine runs as a scheduled job (see jobs.Scheduler) and appends a
+// report to the audit log whenever a run deletes something or fails. In
+// dry-run mode nothing is deleted and the report lists what would have been.
+//
+// Usage:
+//
+//	policies, _ := retention.ParsePolicies(cfg.RetentionPolicies)
+//	engine := retention.NewEngine(policies, cfg.RetentionDryRun, auditRepo)
+//	engine.Register(retention.TargetAuditLog, retention.Target{Count: auditRepo.CountBefore, Delete: auditRepo.DeleteBefore})
+//	scheduler.Add("retention", cfg.CleanupInterval, engine.Run)
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Target names.
+const (
+	TargetAuditLog    = "audit_log"    // audit entries
+	TargetSessions    = "sessions"     // login sessions, by creation time
+	TargetAnalytics   = "analytics"    // watch analytics of idle rooms
+	TargetUploads     = "uploads"      // media uploads never finished, by start time
+	TargetDeadLetters = "dead_letters" // persisted unroutable WebSocket messages
+)
+
+// DefaultPolicies is the maximum age in days per target.
+var DefaultPolicies = map[string]int{
+	TargetAuditLog:    365,
+	TargetSessions:    30,
+	TargetAnalytics:   7,
+	TargetUploads:     1,
+	TargetDeadLetters: 7,
+}
+
+// ParsePolicies parses a "target=days,..." list from config, e.g.
+// "audit_log=90,sessions=14". Listed targets override DefaultPolicies; 0
+// days disables the target's cleanup.
+func ParsePolicies(s string) (map[string]int, error) {
+	policies := make(map[string]int, len(DefaultPolicies))
+	for t, days := range DefaultPolicies {
+		policies[t] = days
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, daysStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("retention: invalid policy %q (want target=days)", entry)
+		}
+		target = strings.TrimSpace(target)
+		if _, known := DefaultPolicies[target]; !known {
+			return nil, fmt.Errorf("retention: unknown target %q", target)
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("retention: invalid policy %q", entry)
+		}
+		if days == 0 {
+			delete(policies, target)
+			continue
+		}
+		policies[target] = days
+	}
+	return policies, nil
+}
+
+// Target counts and deletes one kind of data older than a cutoff.
+type Target struct {
+	Count  func(ctx context.Context, before time.Time) (int, error)
+	Delete func(ctx context.Context, before time.Time) (int, error)
+}
+
+// Engine applies the policies to the registered targets.
+type Engine struct {
+	policies map[string]int
+	dryRun   bool
+	audit    repository.AuditRepository
+	targets  map[string]Target
+	now      func() time.Time
+}
+
+// NewEngine creates an engine for policies (see ParsePolicies). With dryRun
+// set, runs only count what they would delete.
+func NewEngine(policies map[string]int, dryRun bool, audit repository.AuditRepository) *Engine {
+	return &Engine{
+		policies: policies,
+		dryRun:   dryRun,
+		audit:    audit,
+		targets:  make(map[string]Target),
+		now:      time.Now,
+	}
+}
+
+// Register adds a target. Policies for targets that were never registered
+// (say, analytics while it is disabled) are skipped.
+func (e *Engine) Register(name string, t Target) {
+	e.targets[name] = t
+}
+
+// Run applies every policy once and records a report in the audit log.
+// A failing target does not stop the others.
+func (e *Engine) Run(ctx context.Context) error {
+	start := e.now()
+	report := Report{DryRun: e.dryRun, StartedAt: start, Results: []Result{}}
+
+	names := make([]string, 0, len(e.policies))
+	for name := range e.policies {
+		if _, ok := e.targets[name]; ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	failed := 0
+	for _, name := range names {
+		days := e.policies[name]
+		res := Result{Target: name, MaxAgeDays: days, Before: start.AddDate(0, 0, -days)}
+
+		target := e.targets[name]
+		op := target.Delete
+		if e.dryRun {
+			op = target.Count
+		}
+		n, err := op(ctx, res.Before)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			res.Error = err.Error()
+			failed++
+		}
+		res.Deleted = n
+		report.Total += n
+		report.Results = append(report.Results, res)
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	if report.Total > 0 || failed > 0 {
+		verb := "deleted"
+		if e.dryRun {
+			verb = "would delete"
+		}
+		log.Printf("retention: %s %d records (%d targets failed)", verb, report.Total, failed)
+		if err := e.record(ctx, report); err != nil {
+			return fmt.Errorf("record report: %w", err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d targets failed", failed)
+	}
+	return nil
+}
+
+// record appends report to the audit log.
+func (e *Engine) record(ctx context.Context, report Report) error {
+	details, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return e.audit.Create(ctx, &models.AuditEntry{
+		ID:         uuid.New().String(),
+		Action:     models.AuditRetentionRun,
+		TargetType: "retention",
+		Details:    details,
+		CreatedAt:  e.now(),
+	})
+}
+
+// Report is the outcome of one run, stored as the audit entry's details.
+type Report struct {
+	DryRun     bool      `json:"dryRun"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Total      int       `json:"total"`
+	Results    []Result  `json:"results"`
+}
+
+// Result is the outcome for one target.
+type Result struct {
+	Target     string    `json:"target"`
+	MaxAgeDays int       `json:"maxAgeDays"`
+	Before     time.Time `json:"before"`  // records older than this were eligible
+	Deleted    int       `json:"deleted"` // would be deleted, in a dry run
+	Error      string    `json:"error,omitempty"`
+}
@@ -7,60 +7,291 @@ package router
 
 import (
 	"net/http"
+	"strings"
 
 	"ofenes/internal/app"
+	"ofenes/internal/auth"
+	"ofenes/internal/authz"
 	"ofenes/internal/handler"
 	"ofenes/internal/middleware"
 	"ofenes/internal/ws"
+	"ofenes/pkg/response"
 )
 
 // New creates a fully configured HTTP handler with all routes and middleware.
-func New(application *app.App) http.Handler {
+// cors is the CORS configuration (see middleware.NewCORSOptions).
+func New(application *app.App, cors middleware.CORSOptions) http.Handler {
 	h := handler.New(application)
 	mux := http.NewServeMux()
 
+	// Creates accept an Idempotency-Key header so retries don't duplicate
+	// them. Behind authMw, keys are scoped to the user.
+	idem := middleware.Idempotency(application.Ephemeral.Idempotency, application.Config.IdempotencyTTL)
+
 	// --- Public Routes (no auth required) ---
 	mux.HandleFunc("GET /api/hello", h.HelloHandler)
-	mux.HandleFunc("POST /api/register", h.Register)
+	mux.HandleFunc("GET /api/instance", h.GetInstance)
+	mux.HandleFunc("GET /api/challenge", h.GetChallenge)
+	mux.HandleFunc("GET /api/legal", h.GetLegal)
+	mux.HandleFunc("GET /api/legal/{kind}/{version}", h.GetLegalVersion)
+	mux.Handle("POST /api/register", idem(http.HandlerFunc(h.Register)))
 	mux.HandleFunc("POST /api/login", h.Login)
+	mux.HandleFunc("POST /api/logout", h.Logout)
+	mux.HandleFunc("POST /api/sessions/refresh", h.RefreshSession)
+
+	// --- Metrics (Prometheus text format) ---
+	// Scrapers authenticate with a service token once SERVICE_JWT_SECRET is set.
+	var metrics http.Handler = application.Metrics.Handler()
+	if application.Config.ServiceJWTSecret != "" {
+		metrics = middleware.RequireService(application.Config.ServiceToken(), auth.ScopeMetrics)(metrics)
+	}
+	mux.Handle("GET /metrics", metrics)
+
+	// --- Transcode workers (TRANSCODE_MODE=remote, service token with the transcode scope) ---
+	if application.Config.TranscodeMode == "remote" {
+		worker := middleware.RequireService(application.Config.ServiceToken(), auth.ScopeTranscode)
+		mux.Handle("POST /api/transcode/claim", worker(http.HandlerFunc(h.ClaimTranscode)))
+		mux.Handle("GET /api/transcode/{id}/source", worker(http.HandlerFunc(h.GetTranscodeSource)))
+		mux.Handle("PUT /api/transcode/{id}/progress", worker(http.HandlerFunc(h.ReportTranscodeProgress)))
+		mux.Handle("PUT /api/transcode/{id}/files/{name}", worker(http.HandlerFunc(h.PutTranscodeFile)))
+		mux.Handle("POST /api/transcode/{id}/finish", worker(http.HandlerFunc(h.FinishTranscode)))
+	}
+
+	// --- Now playing, for bots and rich presence (service token with the presence scope) ---
+	if application.Config.ServiceJWTSecret != "" {
+		presence := middleware.RequireService(application.Config.ServiceToken(), auth.ScopePresence)
+		mux.Handle("GET /api/now-playing", presence(http.HandlerFunc(h.ListNowPlaying)))
+	}
 
 	// --- Protected Routes (JWT required) ---
-	authMw := middleware.Auth(application.Config.JWTSecret)
+	// Users must also have accepted the current legal documents, except on
+	// the routes in legalExempt.
+	jwtMw := middleware.Auth(application.Config.Token(), application.Revocations)
+	legalMw := middleware.RequireLegal(application.Legal, func(r *http.Request) bool {
+		_, pattern := mux.Handler(r)
+		return legalExempt[pattern]
+	})
+	authMw := func(h http.Handler) http.Handler {
+		return jwtMw(legalMw(h))
+	}
+
+	// can requires a JWT whose role grants perm (see internal/authz).
+	can := func(perm string, h http.Handler) http.Handler {
+		return authMw(middleware.RequirePermission(application.Authz, perm)(h))
+	}
+
+	// Batch (sub-requests go through this mux, with their own auth)
+	mux.Handle("POST /api/batch", authMw(h.Batch(mux)))
 
 	// User
+	mux.Handle("POST /api/token/refresh", authMw(http.HandlerFunc(h.RefreshToken)))
 	mux.Handle("GET /api/me", authMw(http.HandlerFunc(h.Me)))
+	mux.Handle("GET /api/me/permissions", authMw(http.HandlerFunc(h.MyPermissions)))
+	mux.Handle("GET /api/me/quotas", authMw(http.HandlerFunc(h.MyQuotas)))
+	mux.Handle("GET /api/me/entitlements", authMw(http.HandlerFunc(h.MyEntitlements)))
+	mux.Handle("GET /api/me/legal", authMw(http.HandlerFunc(h.MyLegal)))
+	mux.Handle("POST /api/me/legal/accept", authMw(http.HandlerFunc(h.AcceptLegal)))
+	mux.Handle("GET /api/me/sessions", authMw(http.HandlerFunc(h.ListSessions)))
+	mux.Handle("DELETE /api/me/sessions/{id}", authMw(http.HandlerFunc(h.RevokeSession)))
 	mux.Handle("PUT /api/me/profile", authMw(http.HandlerFunc(h.UpdateProfile)))
 	mux.Handle("PUT /api/me/preferences", authMw(http.HandlerFunc(h.UpdatePreferences)))
 
 	// Rooms
-	mux.Handle("POST /api/rooms", authMw(http.HandlerFunc(h.CreateRoom)))
+	mux.Handle("POST /api/rooms", can(authz.PermRoomsCreate, idem(middleware.RequireTrust(application.Authz, application.Config.TrustLevelCreateRooms)(http.HandlerFunc(h.CreateRoom)))))
 	mux.Handle("GET /api/rooms", authMw(http.HandlerFunc(h.ListRooms)))
 	mux.Handle("GET /api/rooms/public", authMw(http.HandlerFunc(h.ListPublicRooms)))
+	mux.Handle("GET /api/rooms/tags", authMw(http.HandlerFunc(h.ListTrendingTags)))
 	mux.Handle("GET /api/rooms/{id}", authMw(http.HandlerFunc(h.GetRoom)))
 	mux.Handle("PUT /api/rooms/{id}", authMw(http.HandlerFunc(h.UpdateRoom)))
 	mux.Handle("DELETE /api/rooms/{id}", authMw(http.HandlerFunc(h.DeleteRoom)))
 	mux.Handle("POST /api/rooms/{id}/join", authMw(http.HandlerFunc(h.JoinRoom)))
 	mux.Handle("POST /api/rooms/{id}/leave", authMw(http.HandlerFunc(h.LeaveRoom)))
+	mux.Handle("GET /api/rooms/{id}/permissions", authMw(http.HandlerFunc(h.RoomPermissions)))
 	mux.Handle("GET /api/rooms/{id}/members", authMw(http.HandlerFunc(h.GetRoomMembers)))
+	mux.Handle("POST /api/rooms/{id}/members", authMw(http.HandlerFunc(h.AddRoomMember)))
+	mux.Handle("PUT /api/rooms/{id}/members/{userId}/role", authMw(http.HandlerFunc(h.SetRoomMemberRole)))
+	mux.Handle("DELETE /api/rooms/{id}/members/{userId}", authMw(http.HandlerFunc(h.RemoveRoomMember)))
+	mux.Handle("POST /api/rooms/{id}/transfer-ownership", authMw(http.HandlerFunc(h.TransferRoomOwnership)))
+	mux.Handle("GET /api/rooms/{id}/analytics", authMw(http.HandlerFunc(h.GetRoomAnalytics)))
+	mux.Handle("GET /api/rooms/{id}/recap", authMw(http.HandlerFunc(h.GetRoomRecap)))
+	mux.Handle("PUT /api/rooms/{id}/retention", authMw(http.HandlerFunc(h.UpdateRoomRetention)))
+	mux.Handle("DELETE /api/rooms/{id}/retention", authMw(http.HandlerFunc(h.ResetRoomRetention)))
+	mux.Handle("PUT /api/rooms/{id}/sync", authMw(http.HandlerFunc(h.UpdateRoomSync)))
+	mux.Handle("DELETE /api/rooms/{id}/sync", authMw(http.HandlerFunc(h.ResetRoomSync)))
+	mux.Handle("PUT /api/rooms/{id}/screens", authMw(http.HandlerFunc(h.UpdateRoomScreens)))
 
 	// Messages
 	mux.Handle("GET /api/rooms/{id}/messages", authMw(http.HandlerFunc(h.GetRoomMessages)))
+	mux.Handle("GET /api/rooms/{id}/messages/export", authMw(http.HandlerFunc(h.ExportRoomMessages)))
 
 	// Media & Files
 	mux.Handle("GET /api/rooms/{id}/media-sessions", authMw(http.HandlerFunc(h.GetRoomMediaSessions)))
 	mux.Handle("GET /api/rooms/{id}/files", authMw(http.HandlerFunc(h.GetRoomFiles)))
+	mux.Handle("POST /api/rooms/{id}/media", authMw(idem(middleware.RequireTrust(application.Authz, application.Config.TrustLevelUploads)(http.HandlerFunc(h.CreateMediaUpload)))))
+	mux.Handle("GET /api/rooms/{id}/media", authMw(http.HandlerFunc(h.ListRoomMedia)))
+	mux.Handle("GET /api/media/{id}", authMw(http.HandlerFunc(h.GetMediaFile)))
+	mux.Handle("PUT /api/media/{id}/content", authMw(http.HandlerFunc(h.UploadMediaChunk)))
+	mux.Handle("DELETE /api/media/{id}", authMw(http.HandlerFunc(h.DeleteMediaFile)))
+	mux.Handle("POST /api/media/{id}/subtitles", authMw(idem(middleware.RequireTrust(application.Authz, application.Config.TrustLevelUploads)(http.HandlerFunc(h.UploadSubtitle)))))
+	mux.Handle("GET /api/media/{id}/subtitles", authMw(http.HandlerFunc(h.ListMediaSubtitles)))
+	mux.Handle("POST /api/media/{id}/playback-token", authMw(http.HandlerFunc(h.CreatePlaybackToken)))
+	mux.Handle("GET /api/rooms/{id}/subtitles", authMw(http.HandlerFunc(h.ListRoomSubtitles)))
+	mux.Handle("DELETE /api/subtitles/{id}", authMw(http.HandlerFunc(h.DeleteSubtitle)))
+
+	// Library: saved videos, personal or per room
+	mux.Handle("POST /api/library", authMw(idem(http.HandlerFunc(h.CreateLibraryItem))))
+	mux.Handle("GET /api/library", authMw(http.HandlerFunc(h.ListLibrary)))
+	mux.Handle("GET /api/library/{id}", authMw(http.HandlerFunc(h.GetLibraryItem)))
+	mux.Handle("PUT /api/library/{id}", authMw(http.HandlerFunc(h.UpdateLibraryItem)))
+	mux.Handle("DELETE /api/library/{id}", authMw(http.HandlerFunc(h.DeleteLibraryItem)))
+	mux.Handle("POST /api/library/{id}/share", authMw(idem(http.HandlerFunc(h.ShareLibraryItem))))
+	mux.Handle("POST /api/rooms/{id}/library", authMw(idem(http.HandlerFunc(h.CreateRoomLibraryItem))))
+	mux.Handle("GET /api/rooms/{id}/library", authMw(http.HandlerFunc(h.ListRoomLibrary)))
+
+	// Uploaded videos (Range requests; cookie auth works for <video> elements)
+	mux.Handle("GET /media/{id}", authMw(http.HandlerFunc(h.StreamMedia)))
+	mux.Handle("GET /media/{id}/hls/{name}", authMw(http.HandlerFunc(h.StreamMediaHLS)))
+	mux.Handle("GET /media/{id}/preview/{name}", authMw(http.HandlerFunc(h.StreamMediaPreview)))
+	mux.Handle("GET /media/{id}/subtitles/{subtitle}", authMw(http.HandlerFunc(h.StreamSubtitle)))
+	mux.HandleFunc("GET /api/media/{id}/stream", h.StreamMediaWithToken) // playback token instead of a JWT
+
+	// HLS proxy (HLS_PROXY_ENABLED): the room's stream, re-served to those who may watch it
+	mux.Handle("GET /api/rooms/{id}/hls", authMw(http.HandlerFunc(h.GetRoomHLS)))
+	mux.Handle("GET /hls/{id}/index.m3u8", authMw(http.HandlerFunc(h.HLSPlaylist)))
+	mux.Handle("GET /hls/{id}/r", authMw(http.HandlerFunc(h.HLSResource)))
+
+	// Reports
+	mux.Handle("POST /api/reports", authMw(idem(http.HandlerFunc(h.CreateReport))))
+
+	// Announcements
+	mux.Handle("GET /api/announcements", authMw(http.HandlerFunc(h.ListAnnouncements)))
+	mux.Handle("POST /api/announcements/{id}/dismiss", authMw(http.HandlerFunc(h.DismissAnnouncement)))
+
+	// --- Admin Routes (JWT whose role grants the route's permission) ---
+	mux.Handle("GET /api/admin/overview", can(authz.PermStatsRead, http.HandlerFunc(h.Overview)))
+	mux.Handle("GET /api/admin/connections", can(authz.PermStatsRead, http.HandlerFunc(h.ListConnections)))
+	mux.Handle("GET /api/admin/audit", can(authz.PermAuditRead, http.HandlerFunc(h.ListAuditLog)))
+	mux.Handle("GET /api/admin/dead-letters", can(authz.PermAuditRead, http.HandlerFunc(h.ListDeadLetters)))
+	mux.Handle("POST /api/admin/broadcast", can(authz.PermBroadcast, idem(http.HandlerFunc(h.Broadcast))))
+
+	// Users
+	mux.Handle("GET /api/admin/users", can(authz.PermUsersRead, http.HandlerFunc(h.ListUsers)))
+	mux.Handle("POST /api/admin/users/import", can(authz.PermUsersManage, http.HandlerFunc(h.ImportUsers)))
+	mux.Handle("GET /api/admin/users/export", can(authz.PermUsersManage, http.HandlerFunc(h.ExportUsers)))
+	mux.Handle("GET /api/admin/users/deleted", can(authz.PermUsersRead, http.HandlerFunc(h.ListDeletedUsers)))
+	mux.Handle("GET /api/admin/users/{id}", can(authz.PermUsersRead, http.HandlerFunc(h.GetUserAdmin)))
+	mux.Handle("DELETE /api/admin/users/{id}", can(authz.PermUsersManage, http.HandlerFunc(h.DeleteUser)))
+	mux.Handle("POST /api/admin/users/{id}/restore", can(authz.PermUsersManage, http.HandlerFunc(h.RestoreUser)))
+	mux.Handle("PUT /api/admin/users/{id}/shadow-ban", can(authz.PermUsersModerate, http.HandlerFunc(h.SetShadowBan)))
+	mux.Handle("PUT /api/admin/users/{id}/role", can(authz.PermUsersManage, http.HandlerFunc(h.SetUserRole)))
+
+	// Roles and permissions
+	mux.Handle("GET /api/admin/roles", can(authz.PermRolesManage, http.HandlerFunc(h.ListRoles)))
+	mux.Handle("POST /api/admin/roles", can(authz.PermRolesManage, idem(http.HandlerFunc(h.CreateRole))))
+	mux.Handle("PUT /api/admin/roles/{name}", can(authz.PermRolesManage, http.HandlerFunc(h.UpdateRole)))
+	mux.Handle("DELETE /api/admin/roles/{name}", can(authz.PermRolesManage, http.HandlerFunc(h.DeleteRole)))
+	mux.Handle("GET /api/admin/permissions", can(authz.PermRolesManage, http.HandlerFunc(h.ListPermissions)))
+
+	// Moderation queue
+	mux.Handle("GET /api/admin/reports", can(authz.PermReportsReview, http.HandlerFunc(h.ListReports)))
+	mux.Handle("GET /api/admin/reports/{id}", can(authz.PermReportsReview, http.HandlerFunc(h.GetReport)))
+	mux.Handle("POST /api/admin/reports/{id}/resolve", can(authz.PermReportsReview, http.HandlerFunc(h.ResolveReport)))
+
+	// Word filters
+	mux.Handle("GET /api/admin/word-filters", can(authz.PermWordFiltersManage, http.HandlerFunc(h.ListWordFilters)))
+	mux.Handle("POST /api/admin/word-filters", can(authz.PermWordFiltersManage, idem(http.HandlerFunc(h.CreateWordFilter))))
+	mux.Handle("PUT /api/admin/word-filters/{id}", can(authz.PermWordFiltersManage, http.HandlerFunc(h.UpdateWordFilter)))
+	mux.Handle("DELETE /api/admin/word-filters/{id}", can(authz.PermWordFiltersManage, http.HandlerFunc(h.DeleteWordFilter)))
+
+	// Allowed origins (on top of CORS_ORIGINS, no restart needed)
+	mux.Handle("GET /api/admin/origins", can(authz.PermOriginsManage, http.HandlerFunc(h.ListAllowedOrigins)))
+	mux.Handle("POST /api/admin/origins", can(authz.PermOriginsManage, idem(http.HandlerFunc(h.AddAllowedOrigin))))
+	mux.Handle("DELETE /api/admin/origins/{id}", can(authz.PermOriginsManage, http.HandlerFunc(h.RemoveAllowedOrigin)))
+
+	// Announcements (shown from startsAt until endsAt)
+	mux.Handle("GET /api/admin/announcements", can(authz.PermAnnouncementsManage, http.HandlerFunc(h.ListAllAnnouncements)))
+	mux.Handle("POST /api/admin/announcements", can(authz.PermAnnouncementsManage, idem(http.HandlerFunc(h.CreateAnnouncement))))
+	mux.Handle("PUT /api/admin/announcements/{id}", can(authz.PermAnnouncementsManage, http.HandlerFunc(h.UpdateAnnouncement)))
+	mux.Handle("DELETE /api/admin/announcements/{id}", can(authz.PermAnnouncementsManage, http.HandlerFunc(h.DeleteAnnouncement)))
+
+	// Instance metadata (served from GET /api/instance)
+	mux.Handle("PUT /api/admin/instance", can(authz.PermInstanceManage, http.HandlerFunc(h.UpdateInstance)))
+
+	// Invite codes (REGISTRATION_MODE=invite-only)
+	mux.Handle("GET /api/admin/invites", can(authz.PermInvitesManage, http.HandlerFunc(h.ListInvites)))
+	mux.Handle("POST /api/admin/invites", can(authz.PermInvitesManage, idem(http.HandlerFunc(h.CreateInvite))))
+	mux.Handle("DELETE /api/admin/invites/{id}", can(authz.PermInvitesManage, http.HandlerFunc(h.DeleteInvite)))
+
+	// Legal documents (served from GET /api/legal)
+	mux.Handle("GET /api/admin/legal/{kind}", can(authz.PermLegalManage, http.HandlerFunc(h.ListLegalVersions)))
+	mux.Handle("POST /api/admin/legal/{kind}", can(authz.PermLegalManage, idem(http.HandlerFunc(h.PublishLegal))))
 
 	// --- WebSocket (JWT authenticated, room-scoped) ---
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		ws.ServeWs(application.Hub, application.Config.JWTSecret, w, r)
+		ws.ServeWs(application.Hub, application.Config.Token(), application.Revocations, w, r)
 	})
 
 	// --- Apply global middleware stack ---
-	// Order: CORS → Logging → Router
+	// Order: CORS → RequestID → Logging → CSRF → Timeout → Router
 	// (outermost middleware runs first)
 	var handler http.Handler = mux
+	handler = middleware.Timeout(application.Config.RequestTimeout, func(r *http.Request) bool {
+		_, pattern := mux.Handler(r)
+		return longLived[pattern] || response.WantsNDJSON(r)
+	})(handler)
+	handler = middleware.CSRF(middleware.CSRFOptions{
+		SessionCookies: []string{middleware.AuthCookieName, middleware.RefreshCookieName},
+		ExemptPaths:    splitList(application.Config.CSRFExemptPaths),
+		Secure:         application.Config.CookieSecure,
+	})(handler)
 	handler = middleware.Logging(handler)
-	handler = middleware.CORS(application.Config.AllowOrigins)(handler)
+	handler = middleware.RequestID(handler)
+	handler = middleware.CORS(cors)(handler)
 
 	return handler
 }
+
+// longLived are the routes exempt from REQUEST_TIMEOUT_MS: they stream or
+// take uploads for as long as the client keeps up. NDJSON listings are
+// exempt too, whatever the route.
+var longLived = map[string]bool{
+	"GET /api/rooms/{id}/messages/export":  true,
+	"PUT /api/media/{id}/content":          true,
+	"GET /media/{id}":                      true,
+	"GET /media/{id}/hls/{name}":           true,
+	"GET /media/{id}/preview/{name}":       true,
+	"GET /media/{id}/subtitles/{subtitle}": true,
+	"GET /api/media/{id}/stream":           true,
+	"GET /hls/{id}/index.m3u8":             true,
+	"GET /hls/{id}/r":                      true,
+	"GET /api/transcode/{id}/source":       true,
+	"PUT /api/transcode/{id}/files/{name}": true,
+	"POST /api/admin/users/import":         true,
+	"GET /api/admin/users/export":          true,
+	"/ws":                                  true,
+}
+
+// legalExempt are the routes users may call before accepting the current
+// legal documents: to read and accept them, refresh their token, see who
+// they are and manage their sessions. Batches pass; their sub-requests are
+// checked on their own routes.
+var legalExempt = map[string]bool{
+	"POST /api/batch":              true,
+	"POST /api/token/refresh":      true,
+	"GET /api/me":                  true,
+	"GET /api/me/legal":            true,
+	"POST /api/me/legal/accept":    true,
+	"GET /api/me/sessions":         true,
+	"DELETE /api/me/sessions/{id}": true,
+}
+
+// splitList splits a comma-separated config value, dropping blanks.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials sign requests to AWS: an access key, and the session
+// token of temporary credentials.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWS reads secrets from AWS Secrets Manager (the AWSCURRENT version):
+// secret://ofenes/prod#jwt_secret is key jwt_secret of the JSON object
+// stored in secret ofenes/prod, which may also be given by ARN. Requests
+// are signed with Signature Version 4 from static credentials; instance
+// and task roles are not looked up.
+type AWS struct {
+	endpoint string
+	region   string
+	creds    AWSCredentials
+	client   *http.Client
+}
+
+// awsResponse is the body of a GetSecretValue call.
+type awsResponse struct {
+	SecretString *string `json:"SecretString"`
+}
+
+// NewAWS creates an AWS Secrets Manager provider for region. endpoint
+// replaces the regional one if not empty, e.g. for a VPC endpoint or
+// LocalStack.
+func NewAWS(region, endpoint string, creds AWSCredentials) (*AWS, error) {
+	if region == "" {
+		return nil, fmt.Errorf("secrets: an AWS region is required")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("secrets: AWS credentials are required")
+	}
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("secrets: %q is not an http(s) URL", endpoint)
+	}
+	return &AWS{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   region,
+		creds:    creds,
+		client:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Fetch reads the current version of the secret with ID path. A secret
+// that is not a JSON object has a single field, its whole string.
+func (a *AWS) Fetch(ctx context.Context, path string) (map[string]string, error) {
+	body, _ := json.Marshal(map[string]string{"SecretId": path})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, body, time.Now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxSecretBytes))
+		return nil, fmt.Errorf("aws: status %d", resp.StatusCode)
+	}
+	var out awsResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSecretBytes)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("aws: decode: %w", err)
+	}
+	if out.SecretString == nil {
+		return nil, fmt.Errorf("aws: binary secrets are not supported")
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(*out.SecretString), &raw); err == nil {
+		return stringFields(raw), nil
+	}
+	return map[string]string{"": *out.SecretString}, nil
+}
+
+// sign adds Signature Version 4 headers to req, whose body is body.
+func (a *AWS) sign(req *http.Request, body []byte, now time.Time) {
+	const service = "secretsmanager"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + a.region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.creds.SecretAccessKey), date)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+a.creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
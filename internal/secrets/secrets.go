@@ -0,0 +1,130 @@
+// Package secrets fetches credentials from a secret manager, so JWT keys,
+// database credentials and API secrets need not sit in the environment.
+//
+// A setting that holds a secret may instead hold a reference to one,
+// "secret://<path>#<field>", which config.Load resolves with the Provider
+// chosen by SECRETS_PROVIDER: Vault (a KV version 2 engine) or AWS Secrets
+// Manager. A secret is a set of fields (a Vault entry, or an AWS secret
+// holding a JSON object); "#field" picks one and may be left out if there
+// is only one, or if the AWS secret is a plain string. Store caches what
+// it fetched, so settings referring to fields of one secret fetch it once.
+//
+// Usage:
+//
+//	provider, _ := secrets.NewVault(addr, token, "secret", "")
+//	store := secrets.New(provider, 5*time.Minute)
+//	jwtSecret, err := store.Get(ctx, "secret://ofenes/prod#jwt_secret")
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheme prefixes a reference to a secret.
+const Scheme = "secret://"
+
+// requestTimeout bounds one request to a secret manager.
+const requestTimeout = 10 * time.Second
+
+// maxSecretBytes bounds the response read from a secret manager.
+const maxSecretBytes = 1 << 20
+
+// Provider fetches secrets from a secret manager.
+type Provider interface {
+	// Fetch returns the fields of the secret at path.
+	Fetch(ctx context.Context, path string) (map[string]string, error)
+}
+
+// IsRef reports whether value is a reference to a secret.
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// parseRef splits a reference into the secret's path and the field.
+func parseRef(ref string) (path, field string, err error) {
+	if !IsRef(ref) {
+		return "", "", fmt.Errorf("secrets: %q is not a %s reference", ref, Scheme)
+	}
+	path, field, _ = strings.Cut(strings.TrimPrefix(ref, Scheme), "#")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", "", fmt.Errorf("secrets: %q has no path", ref)
+	}
+	return path, field, nil
+}
+
+// Store resolves references with a Provider, caching each secret for a
+// TTL. Safe for concurrent use.
+type Store struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret // by path
+}
+
+// cachedSecret is a fetched secret and when it goes stale.
+type cachedSecret struct {
+	fields  map[string]string
+	expires time.Time
+}
+
+// New creates a Store fetching from provider and caching secrets for ttl
+// (0 = not cached).
+func New(provider Provider, ttl time.Duration) *Store {
+	return &Store{provider: provider, ttl: ttl, cache: make(map[string]cachedSecret)}
+}
+
+// Get resolves ref, from the cache if the secret was fetched less than
+// the TTL ago.
+func (s *Store) Get(ctx context.Context, ref string) (string, error) {
+	path, field, err := parseRef(ref)
+	if err != nil {
+		return "", err
+	}
+	fields, err := s.fetch(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %s: %w", path, err)
+	}
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secrets: %s has %d fields; pick one with #field", path, len(fields))
+		}
+		for _, v := range fields {
+			return v, nil
+		}
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secrets: %s has no field %q", path, field)
+	}
+	return v, nil
+}
+
+// fetch returns the fields of the secret at path.
+func (s *Store) fetch(ctx context.Context, path string) (map[string]string, error) {
+	now := time.Now()
+	s.mu.Lock()
+	c, ok := s.cache[path]
+	s.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.fields, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	fields, err := s.provider.Fetch(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if s.ttl > 0 {
+		s.mu.Lock()
+		s.cache[path] = cachedSecret{fields: fields, expires: now.Add(s.ttl)}
+		s.mu.Unlock()
+	}
+	return fields, nil
+}
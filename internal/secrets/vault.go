@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Vault reads secrets from a HashiCorp Vault KV version 2 engine with a
+// token: secret://ofenes/prod#jwt_secret is field jwt_secret of the
+// latest version of ofenes/prod under the engine's mount.
+type Vault struct {
+	addr      string
+	token     string
+	mount     string
+	namespace string
+	client    *http.Client
+}
+
+// vaultResponse is the body of a KV version 2 read.
+type vaultResponse struct {
+	Data struct {
+		Data map[string]json.RawMessage `json:"data"`
+	} `json:"data"`
+}
+
+// NewVault creates a Vault provider for the server at addr, reading the
+// KV engine mounted at mount with token, in namespace if not empty
+// (Vault Enterprise).
+func NewVault(addr, token, mount, namespace string) (*Vault, error) {
+	u, err := url.Parse(addr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("secrets: %q is not an http(s) URL", addr)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("secrets: a Vault token is required")
+	}
+	return &Vault{
+		addr:      strings.TrimSuffix(addr, "/"),
+		token:     token,
+		mount:     strings.Trim(mount, "/"),
+		namespace: namespace,
+		client:    &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Fetch reads the latest version of the secret at path.
+func (v *Vault) Fetch(ctx context.Context, path string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+v.mount+"/data/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxSecretBytes))
+		return nil, fmt.Errorf("vault: status %d", resp.StatusCode)
+	}
+	var out vaultResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSecretBytes)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("vault: decode: %w", err)
+	}
+	return stringFields(out.Data.Data), nil
+}
+
+// stringFields turns JSON fields into strings: strings unquoted, other
+// values as their JSON text.
+func stringFields(raw map[string]json.RawMessage) map[string]string {
+	fields := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			fields[k] = s
+			continue
+		}
+		fields[k] = string(v)
+	}
+	return fields
+}
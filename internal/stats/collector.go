@@ -0,0 +1,192 @@
+// Package stats keeps usage statistics for the admin overview.
+//
+// The Collector is fed events as they happen (registrations, logins,
+// connections, chat messages, room creation) and keeps per-day totals in
+// memory, so the overview never has to scan the database. Totals cover
+// this process only and start empty at boot; Overview reports the start
+// time so clients can tell.
+//
+// Usage:
+//
+//	collector := stats.NewCollector(90)
+//	collector.UserRegistered()
+//	overview := collector.Overview(30, currentConnections)
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// dayFormat keys daily buckets (UTC dates).
+const dayFormat = "2006-01-02"
+
+// day holds one UTC day's totals.
+type day struct {
+	registrations   int
+	roomsCreated    int
+	messages        int
+	peakConnections int
+	active          map[string]struct{} // user IDs seen that day
+}
+
+// Collector aggregates usage events into daily buckets. Safe for
+// concurrent use; every method is O(1) except Overview.
+type Collector struct {
+	retention int // days kept
+	started   time.Time
+	now       func() time.Time
+
+	mu   sync.Mutex
+	days map[string]*day
+
+	peakConnections int
+	peakAt          time.Time
+}
+
+// NewCollector creates a collector that keeps retentionDays of history
+// (at least 7, so weekly active users can always be computed).
+func NewCollector(retentionDays int) *Collector {
+	return &Collector{
+		retention: max(retentionDays, 7),
+		started:   time.Now(),
+		now:       time.Now,
+		days:      make(map[string]*day),
+	}
+}
+
+// UserRegistered records a new account.
+func (c *Collector) UserRegistered() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.today().registrations++
+}
+
+// UserActive records that userID used the service today (logged in,
+// connected, or sent a message).
+func (c *Collector) UserActive(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.today().active[userID] = struct{}{}
+}
+
+// RoomCreated records a new room.
+func (c *Collector) RoomCreated() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.today().roomsCreated++
+}
+
+// ConnectionOpened records a WebSocket connection by userID, with open the
+// number of connections now open. Implements ws.StatsRecorder.
+func (c *Collector) ConnectionOpened(userID string, open int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d := c.today()
+	d.active[userID] = struct{}{}
+	d.peakConnections = max(d.peakConnections, open)
+	if open > c.peakConnections {
+		c.peakConnections = open
+		c.peakAt = c.now()
+	}
+}
+
+// MessageSent records a chat message by userID. Implements ws.StatsRecorder.
+func (c *Collector) MessageSent(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d := c.today()
+	d.messages++
+	d.active[userID] = struct{}{}
+}
+
+// Overview returns the last days days of history (capped at the retention)
+// plus current figures. currentConnections is passed in by the caller,
+// which owns the live count.
+func (c *Collector) Overview(days, currentConnections int) Overview {
+	days = min(max(days, 1), c.retention)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now().UTC()
+	o := Overview{
+		Since:              c.started,
+		CurrentConnections: currentConnections,
+		PeakConnections:    c.peakConnections,
+		Days:               make([]DailyStats, 0, days),
+	}
+	if !c.peakAt.IsZero() {
+		o.PeakConnectionsAt = &c.peakAt
+	}
+
+	weekly := make(map[string]struct{})
+	for i := days - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format(dayFormat)
+		ds := DailyStats{Date: date}
+		if d, ok := c.days[date]; ok {
+			ds.Registrations = d.registrations
+			ds.ActiveUsers = len(d.active)
+			ds.RoomsCreated = d.roomsCreated
+			ds.Messages = d.messages
+			ds.PeakConnections = d.peakConnections
+		}
+		o.Days = append(o.Days, ds)
+	}
+	for i := range 7 {
+		if d, ok := c.days[now.AddDate(0, 0, -i).Format(dayFormat)]; ok {
+			for id := range d.active {
+				weekly[id] = struct{}{}
+			}
+		}
+	}
+	if d, ok := c.days[now.Format(dayFormat)]; ok {
+		o.DailyActiveUsers = len(d.active)
+	}
+	o.WeeklyActiveUsers = len(weekly)
+	return o
+}
+
+// today returns the bucket for the current UTC day, creating it (and
+// dropping buckets past the retention) on the first event of a day.
+// Must be called with c.mu held.
+func (c *Collector) today() *day {
+	now := c.now().UTC()
+	key := now.Format(dayFormat)
+	if d, ok := c.days[key]; ok {
+		return d
+	}
+
+	cutoff := now.AddDate(0, 0, -c.retention).Format(dayFormat)
+	for k := range c.days {
+		if k <= cutoff {
+			delete(c.days, k)
+		}
+	}
+	d := &day{active: make(map[string]struct{})}
+	c.days[key] = d
+	return d
+}
+
+// Overview is the admin dashboard summary returned by GET /api/admin/overview.
+type Overview struct {
+	Since              time.Time    `json:"since"` // collector start; earlier activity is not counted
+	DailyActiveUsers   int          `json:"dailyActiveUsers"`
+	WeeklyActiveUsers  int          `json:"weeklyActiveUsers"` // distinct users over the last 7 days
+	CurrentConnections int          `json:"currentConnections"`
+	PeakConnections    int          `json:"peakConnections"` // since Since
+	PeakConnectionsAt  *time.Time   `json:"peakConnectionsAt,omitempty"`
+	Days               []DailyStats `json:"days"` // oldest first, today last
+}
+
+// DailyStats holds one UTC day's totals.
+type DailyStats struct {
+	Date            string `json:"date"` // YYYY-MM-DD (UTC)
+	Registrations   int    `json:"registrations"`
+	ActiveUsers     int    `json:"activeUsers"`
+	RoomsCreated    int    `json:"roomsCreated"`
+	Messages        int    `json:"messages"`
+	PeakConnections int    `json:"peakConnections"`
+}
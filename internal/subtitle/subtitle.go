@@ -0,0 +1,282 @@
+// Package subtitle converts subtitle files to WebVTT, the format browsers
+// play in <track> elements.
+//
+// SubRip (.srt) and Advanced SubStation Alpha (.ass, .ssa) files are
+// accepted. Only the timing and the text survive: bold, italic and
+// underline markup of SubRip is kept, while styles, positioning and
+// effects are dropped, as are ASS drawings.
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ofenes/internal/models"
+	"ofenes/pkg/apperr"
+)
+
+var (
+	// ErrNotUTF8 is returned for files in another encoding.
+	ErrNotUTF8 = apperr.New(apperr.Invalid, "", "subtitle: file is not UTF-8 text")
+
+	// ErrNoCues is returned for files without any subtitle in them.
+	ErrNoCues = apperr.New(apperr.Invalid, "", "subtitle: no cues found")
+)
+
+// SyntaxError reports a line of a file that could not be read.
+type SyntaxError struct {
+	Line int // 1-based
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("subtitle: line %d: %s", e.Line, e.Msg)
+}
+
+// cue is a piece of text shown from start to end.
+type cue struct {
+	start, end time.Duration
+	text       string
+}
+
+// utf8BOM starts files saved by some Windows editors.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Convert reads a SubRip or ASS file and returns it as WebVTT, with the
+// format it was in (models.SubtitleFormat*).
+func Convert(data []byte) (vtt []byte, format string, err error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		return nil, "", ErrNotUTF8
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+
+	var cues []cue
+	if isASS(lines) {
+		format = models.SubtitleFormatASS
+		cues, err = parseASS(lines)
+	} else {
+		format = models.SubtitleFormatSRT
+		cues, err = parseSRT(lines)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(cues) == 0 {
+		return nil, "", ErrNoCues
+	}
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, c := range cues {
+		fmt.Fprintf(&b, "\n%s --> %s\n%s\n", Timestamp(c.start), Timestamp(c.end), c.text)
+	}
+	return []byte(b.String()), format, nil
+}
+
+// Timestamp formats d as a WebVTT timestamp, hh:mm:ss.ttt.
+func Timestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// isASS reports whether lines are an ASS file, with a [Script Info] or
+// [Events] section.
+func isASS(lines []string) bool {
+	for _, line := range lines {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "[script info]" || line == "[events]" {
+			return true
+		}
+	}
+	return false
+}
+
+// --- SubRip ---
+
+// srtTiming matches the timing line of a SubRip cue. Some files use a dot
+// before the milliseconds, or add coordinates after the end time.
+var srtTiming = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})\s*-->\s*(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})(\s|$)`)
+
+// parseSRT reads the cues of a SubRip file: blocks of a number, a timing
+// line and text lines, separated by blank lines.
+func parseSRT(lines []string) ([]cue, error) {
+	var cues []cue
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.Contains(line, "-->") {
+			continue // cue numbers and stray lines
+		}
+		m := srtTiming.FindStringSubmatch(line)
+		if m == nil {
+			return nil, &SyntaxError{Line: i + 1, Msg: "invalid timing " + strconv.Quote(line)}
+		}
+		start, end := duration(m[1], m[2], m[3], m[4]), duration(m[5], m[6], m[7], m[8])
+		if end < start {
+			return nil, &SyntaxError{Line: i + 1, Msg: "cue ends before it starts"}
+		}
+
+		var text []string
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			i++
+			text = append(text, lines[i])
+		}
+		if t := cueText(strings.Join(text, "\n")); t != "" {
+			cues = append(cues, cue{start: start, end: end, text: t})
+		}
+	}
+	return cues, nil
+}
+
+// --- Advanced SubStation Alpha ---
+
+// assTime matches ASS times, h:mm:ss.cc.
+var assTime = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})\.(\d{1,3})$`)
+
+// assDrawing matches the override starting an ASS drawing, which is shapes
+// rather than text.
+var assDrawing = regexp.MustCompile(`\{[^}]*\\p[1-9]`)
+
+// parseASS reads the Dialogue lines of the [Events] section of an ASS
+// file, whose fields are named by the section's Format line, and returns
+// them in order of start time.
+func parseASS(lines []string) ([]cue, error) {
+	var (
+		section             string
+		fields              []string
+		start, end, textCol = -1, -1, -1
+		cues                []cue
+	)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if section != "[events]" || !ok {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "format":
+			fields = strings.Split(value, ",")
+			for j, f := range fields {
+				switch strings.ToLower(strings.TrimSpace(f)) {
+				case "start":
+					start = j
+				case "end":
+					end = j
+				case "text":
+					textCol = j
+				}
+			}
+			if start < 0 || end < 0 || textCol != len(fields)-1 {
+				return nil, &SyntaxError{Line: i + 1, Msg: "Format needs Start, End and Text, last"}
+			}
+		case "dialogue":
+			if fields == nil {
+				return nil, &SyntaxError{Line: i + 1, Msg: "Dialogue before Format"}
+			}
+			parts := strings.SplitN(value, ",", len(fields))
+			if len(parts) != len(fields) {
+				return nil, &SyntaxError{Line: i + 1, Msg: fmt.Sprintf("Dialogue has %d fields, want %d", len(parts), len(fields))}
+			}
+			from, err := assDuration(parts[start])
+			if err != nil {
+				return nil, &SyntaxError{Line: i + 1, Msg: err.Error()}
+			}
+			to, err := assDuration(parts[end])
+			if err != nil {
+				return nil, &SyntaxError{Line: i + 1, Msg: err.Error()}
+			}
+			if to < from {
+				return nil, &SyntaxError{Line: i + 1, Msg: "cue ends before it starts"}
+			}
+			text := parts[textCol]
+			if assDrawing.MatchString(text) {
+				continue
+			}
+			// \N breaks lines; \n only does in a wrapping mode players lack.
+			text = strings.NewReplacer(`\N`, "\n", `\n`, " ", `\h`, "\u00a0").Replace(text)
+			if t := cueText(text); t != "" {
+				cues = append(cues, cue{start: from, end: to, text: t})
+			}
+		}
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+	return cues, nil
+}
+
+// assDuration parses an ASS time.
+func assDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	m := assTime.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return duration(m[1], m[2], m[3], m[4]), nil
+}
+
+// --- Text ---
+
+// duration returns the time of hours, minutes, seconds and a decimal
+// fraction of a second, all strings of digits.
+func duration(h, m, s, frac string) time.Duration {
+	hours, _ := strconv.Atoi(h)
+	minutes, _ := strconv.Atoi(m)
+	seconds, _ := strconv.Atoi(s)
+	ms, _ := strconv.Atoi((frac + "00")[:3])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(ms)*time.Millisecond
+}
+
+// markup matches HTML-like tags, kept if bold, italic or underline, and
+// ASS override blocks, which some SubRip files have too.
+var markup = regexp.MustCompile(`</?([A-Za-z]+)[^>]*>|\{\\[^}]*\}`)
+
+// cueText returns text as WebVTT cue text: markup other than <b>, <i> and
+// <u> removed, everything else escaped, and without blank lines, which
+// would end the cue.
+func cueText(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range markup.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(escape(text[last:m[0]]))
+		last = m[1]
+		if m[2] < 0 {
+			continue
+		}
+		switch name := strings.ToLower(text[m[2]:m[3]]); name {
+		case "b", "i", "u":
+			if text[m[0]+1] == '/' {
+				b.WriteString("</" + name + ">")
+			} else {
+				b.WriteString("<" + name + ">")
+			}
+		}
+	}
+	b.WriteString(escape(text[last:]))
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escape escapes the characters WebVTT cue text reserves.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
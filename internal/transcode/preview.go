@@ -0,0 +1,165 @@
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ofenes/internal/media"
+	"ofenes/internal/models"
+	"ofenes/internal/subtitle"
+)
+
+// Files of the previews of an upload, made before its renditions so lists
+// can show them while it is still being transcoded.
+const (
+	PosterFile = "poster.jpg" // a representative frame
+	SpriteFile = "sprite.jpg" // thumbnails at regular intervals, in a grid
+	SpriteVTT  = "sprite.vtt" // WebVTT thumbnails track pointing into SpriteFile
+)
+
+const (
+	// posterWidth is the width of posters, in pixels.
+	posterWidth = 640
+
+	// spriteColumns and spriteRows bound the thumbnails in a sprite; long
+	// videos get thumbnails further apart.
+	spriteColumns, spriteRows = 10, 10
+
+	// thumbWidth and thumbHeight are the size of each thumbnail. Frames
+	// of other shapes are letterboxed.
+	thumbWidth, thumbHeight = 160, 90
+
+	// minThumbInterval is the shortest time between thumbnails, in seconds.
+	minThumbInterval = 1.0
+)
+
+// PreviewURL returns where file name of the previews of upload id is served.
+func PreviewURL(id, name string) string {
+	return "/media/" + id + "/preview/" + name
+}
+
+// SetPreviews fills in the preview URLs of file from what store holds.
+func SetPreviews(store media.Store, file *models.MediaFile) error {
+	// The poster is stored last: with it, the sprite is there too, if any.
+	n, err := store.Size(media.DerivedKey(file.ID, PosterFile))
+	if err != nil || n == 0 {
+		return err
+	}
+	file.PosterURL = PreviewURL(file.ID, PosterFile)
+	if n, err = store.Size(media.DerivedKey(file.ID, SpriteVTT)); err != nil {
+		return err
+	}
+	if n > 0 {
+		file.PreviewURL = PreviewURL(file.ID, SpriteVTT)
+	}
+	return nil
+}
+
+// previews makes the poster and preview sprite of upload id from input in
+// a directory under dir, and puts them. ffmpeg failures are only logged:
+// the renditions matter more. Queue errors are returned.
+func (w *Worker) previews(ctx context.Context, id, input, dir string) error {
+	out := filepath.Join(dir, "previews")
+	if err := os.Mkdir(out, 0o755); err != nil {
+		return err
+	}
+
+	var failed *ffmpegError
+	duration, err := w.ffmpeg(ctx, input, posterArgs(input, filepath.Join(out, PosterFile)), nil)
+	if errors.As(err, &failed) {
+		log.Printf("transcode: %s: no poster: %v", id, failed)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	names := []string{PosterFile}
+	if duration > 0 {
+		interval, count := spriteLayout(duration)
+		_, err := w.ffmpeg(ctx, input, spriteArgs(input, filepath.Join(out, SpriteFile), interval), nil)
+		switch {
+		case errors.As(err, &failed):
+			log.Printf("transcode: %s: no preview sprite: %v", id, failed)
+		case err != nil:
+			return err
+		default:
+			vtt := spriteTrack(duration, interval, count)
+			if err := os.WriteFile(filepath.Join(out, SpriteVTT), vtt, 0o644); err != nil {
+				return err
+			}
+			names = []string{SpriteFile, SpriteVTT, PosterFile}
+		}
+	}
+
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(out, name))
+		if err != nil {
+			return err
+		}
+		err = w.Queue.Put(ctx, id, name, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("put %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// posterArgs returns ffmpeg's arguments for the poster: the most typical
+// of the first frames (ffmpeg's thumbnail filter), so not a black one.
+func posterArgs(input, poster string) []string {
+	return []string{
+		"-hide_banner", "-nostdin", "-y", "-i", input,
+		"-map", "0:v:0", "-vf", fmt.Sprintf("thumbnail,scale=%d:-2", posterWidth),
+		"-frames:v", "1", "-q:v", "3", poster,
+	}
+}
+
+// spriteArgs returns ffmpeg's arguments for the sprite: a frame every
+// interval seconds, tiled. Only keyframes are decoded, which is much
+// faster and close enough for previews.
+func spriteArgs(input, sprite string, interval float64) []string {
+	filter := fmt.Sprintf(
+		"fps=%f,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		1/interval, thumbWidth, thumbHeight, thumbWidth, thumbHeight, spriteColumns, spriteRows)
+	return []string{
+		"-hide_banner", "-nostdin", "-y", "-skip_frame", "nokey", "-i", input,
+		"-map", "0:v:0", "-vf", filter, "-frames:v", "1", "-q:v", "5", sprite,
+	}
+}
+
+// spriteLayout returns the seconds between thumbnails for a video of
+// duration seconds, and how many there are.
+func spriteLayout(duration float64) (interval float64, count int) {
+	interval = max(duration/(spriteColumns*spriteRows), minThumbInterval)
+	count = min(int(math.Ceil(duration/interval)), spriteColumns*spriteRows)
+	return interval, count
+}
+
+// spriteTrack returns the WebVTT track mapping each stretch of the video
+// to its thumbnail in the sprite, as players' seek bar previews expect.
+func spriteTrack(duration, interval float64, count int) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i := range count {
+		start := float64(i) * interval
+		end := min(start+interval, duration)
+		x, y := i%spriteColumns*thumbWidth, i/spriteColumns*thumbHeight
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			subtitle.Timestamp(seconds(start)), subtitle.Timestamp(seconds(end)), SpriteFile, x, y, thumbWidth, thumbHeight)
+	}
+	return []byte(b.String())
+}
+
+// seconds converts s seconds to a duration, to the millisecond.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s*1000)) * time.Millisecond
+}
@@ -0,0 +1,124 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ofenes/internal/models"
+	"ofenes/pkg/response"
+)
+
+// RemoteQueue is the Queue of a worker outside the server: it calls the
+// server's /api/transcode endpoints with a service token granting the
+// transcode scope. The token's service name is the worker's name, so each
+// worker needs a token of its own.
+type RemoteQueue struct {
+	BaseURL string       // of the server, e.g. https://watch.example.com
+	Token   string       // service token
+	Client  *http.Client // nil means http.DefaultClient
+}
+
+// Claim implements Queue.
+func (q *RemoteQueue) Claim(ctx context.Context) (*models.MediaFile, error) {
+	resp, err := q.do(ctx, http.MethodPost, "/api/transcode/claim", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, ErrNoJob
+	}
+
+	var env struct {
+		Data *models.MediaFile `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil || env.Data == nil {
+		return nil, fmt.Errorf("transcode: bad claim response: %v", err)
+	}
+	return env.Data, nil
+}
+
+// Source implements Queue.
+func (q *RemoteQueue) Source(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := q.do(ctx, http.MethodGet, "/api/transcode/"+url.PathEscape(id)+"/source", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Progress implements Queue.
+func (q *RemoteQueue) Progress(ctx context.Context, id string, progress float64) error {
+	return q.send(ctx, http.MethodPut, "/api/transcode/"+url.PathEscape(id)+"/progress",
+		models.TranscodeProgressRequest{Progress: progress})
+}
+
+// Put implements Queue.
+func (q *RemoteQueue) Put(ctx context.Context, id, name string, r io.Reader) error {
+	resp, err := q.do(ctx, http.MethodPut, "/api/transcode/"+url.PathEscape(id)+"/files/"+url.PathEscape(name),
+		"application/octet-stream", r)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Finish implements Queue.
+func (q *RemoteQueue) Finish(ctx context.Context, id, failure string) error {
+	return q.send(ctx, http.MethodPost, "/api/transcode/"+url.PathEscape(id)+"/finish",
+		models.FinishTranscodeRequest{Error: failure})
+}
+
+// send makes a request with a JSON body, discarding the response.
+func (q *RemoteQueue) send(ctx context.Context, method, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := q.do(ctx, method, path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// do makes an authenticated request. Responses other than 2xx are closed
+// and returned as errors; 409 means the job was lost.
+func (q *RemoteQueue) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(q.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+q.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	client := q.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil, ErrLost
+	}
+	var env response.Envelope
+	if json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&env) == nil && env.Error != nil {
+		return nil, fmt.Errorf("transcode: %s %s: %s: %s", method, path, resp.Status, env.Error.Message)
+	}
+	return nil, fmt.Errorf("transcode: %s %s: %s", method, path, resp.Status)
+}
@@ -0,0 +1,277 @@
+// Package transcode converts uploaded videos to HLS renditions with ffmpeg.
+//
+// When an upload completes its record gets a queued models.Transcode job.
+// A Worker claims jobs from a Queue, runs ffmpeg on the upload, and hands
+// back a poster and preview sprite, then the playlists and segments, which
+// are stored next to the upload (see media.DerivedKey) and served from
+// /media/{id}/preview/ and /media/{id}/hls/. Workers run either
+// inside the server (TRANSCODE_MODE=local, a Jobs queue used directly) or
+// as separate cmd/transcoder processes talking to the server over HTTP
+// (TRANSCODE_MODE=remote, RemoteQueue).
+//
+// A worker reports progress while it runs. A job whose worker has not
+// been heard from for ClaimTimeout is handed to the next worker asking,
+// and the silent one learns it lost the job (ErrLost) when it next reports.
+// Everyone in the room is told with a "media" message once the previews
+// are in, and once the job is done or failed.
+package transcode
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"regexp"
+	"time"
+
+	"ofenes/internal/media"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+	"ofenes/pkg/apperr"
+)
+
+// ClaimTimeout is how long a running job may go without a progress
+// report before another worker may claim it.
+const ClaimTimeout = 5 * time.Minute
+
+// MasterPlaylist is the name of the multivariant playlist of a transcoded
+// upload, the file players load.
+const MasterPlaylist = "master.m3u8"
+
+// maxFailureLength bounds the failure messages stored with jobs.
+const maxFailureLength = 500
+
+var (
+	// ErrNoJob is returned by Claim when no job is waiting.
+	ErrNoJob = apperr.New(apperr.NotFound, "", "transcode: no job waiting")
+
+	// ErrLost is returned to a worker reporting on a job that is no longer
+	// its own: it was deleted, or claimed by another worker.
+	ErrLost = apperr.New(apperr.Conflict, "transcode_job_lost", "transcode: job no longer held by this worker")
+
+	// ErrInvalidName is returned by Put for names that are not plain file
+	// names.
+	ErrInvalidName = apperr.New(apperr.Invalid, "invalid_file_name", "transcode: invalid file name")
+)
+
+// validName matches the names of playlists and segments a worker may put.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidName reports whether name may be used for a transcoded file.
+func ValidName(name string) bool {
+	return len(name) <= 100 && validName.MatchString(name)
+}
+
+// PlaylistURL returns where players load the transcoded upload id.
+func PlaylistURL(id string) string {
+	return "/media/" + id + "/hls/" + MasterPlaylist
+}
+
+// Queue is where a Worker gets its jobs and sends back their results. Job
+// IDs are media file IDs.
+type Queue interface {
+	// Claim returns the upload to transcode next, or ErrNoJob.
+	Claim(ctx context.Context) (*models.MediaFile, error)
+
+	// Source opens the bytes of the upload.
+	Source(ctx context.Context, id string) (io.ReadCloser, error)
+
+	// Progress reports how far the job is, from 0 to 1.
+	Progress(ctx context.Context, id string, progress float64) error
+
+	// Put stores a preview, or a playlist or segment of the renditions.
+	Put(ctx context.Context, id, name string, r io.Reader) error
+
+	// Finish ends the job: done if failure is empty, failed otherwise.
+	Finish(ctx context.Context, id, failure string) error
+}
+
+// Notifier tells a room about its media; *ws.Hub implements it.
+type Notifier interface {
+	NotifyMedia(event models.MediaEvent)
+}
+
+// Jobs is the server's side of the queue: it keeps jobs in the media file
+// records and the transcoded files in the media store. Worker names come
+// from the workers themselves (Local) or their service tokens. Safe for
+// concurrent use.
+type Jobs struct {
+	files  repository.MediaFileRepository
+	store  media.Store
+	notify Notifier
+	now    func() time.Time
+}
+
+// NewJobs creates the queue.
+func NewJobs(files repository.MediaFileRepository, store media.Store, notify Notifier) *Jobs {
+	return &Jobs{files: files, store: store, notify: notify, now: time.Now}
+}
+
+// Enqueue queues a job for the completed upload file and records it in
+// file.Transcode.
+func (j *Jobs) Enqueue(ctx context.Context, file *models.MediaFile) error {
+	t := models.Transcode{Status: models.TranscodeQueued, UpdatedAt: j.now()}
+	if err := j.files.SetTranscode(ctx, file.ID, t); err != nil {
+		return err
+	}
+	file.Transcode = &t
+	return nil
+}
+
+// Claim hands worker the job waiting longest, or returns ErrNoJob.
+func (j *Jobs) Claim(ctx context.Context, worker string) (*models.MediaFile, error) {
+	now := j.now()
+	file, err := j.files.ClaimTranscode(ctx, worker, now, now.Add(-ClaimTimeout))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNoJob
+	}
+	return file, err
+}
+
+// Source opens the upload of worker's job id.
+func (j *Jobs) Source(ctx context.Context, id, worker string) (io.ReadSeekCloser, error) {
+	if _, err := j.held(ctx, id, worker); err != nil {
+		return nil, err
+	}
+	return j.store.Open(id)
+}
+
+// Progress records how far worker's job id is.
+func (j *Jobs) Progress(ctx context.Context, id, worker string, progress float64) error {
+	return j.update(ctx, id, worker, models.Transcode{
+		Status:   models.TranscodeRunning,
+		Progress: min(max(progress, 0), 1),
+		Worker:   worker,
+	})
+}
+
+// Put stores file name of worker's job id, replacing any earlier one
+// (from a previous attempt). Counts as a progress report. The room is
+// told when the previews are in, with the poster.
+func (j *Jobs) Put(ctx context.Context, id, worker, name string, r io.Reader) error {
+	if !ValidName(name) {
+		return ErrInvalidName
+	}
+	file, err := j.held(ctx, id, worker)
+	if err != nil {
+		return err
+	}
+	t := *file.Transcode
+	if err := j.update(ctx, id, worker, t); err != nil {
+		return err
+	}
+
+	key := media.DerivedKey(id, name)
+	if err := j.store.Delete(key); err != nil {
+		return err
+	}
+	if _, err := j.store.Append(key, 0, r); err != nil {
+		return err
+	}
+	if name == PosterFile {
+		j.announce(ctx, id, models.MediaEventPreviews, "")
+	}
+	return nil
+}
+
+// Finish ends worker's job id, done if failure is empty and failed
+// otherwise, and tells the room. A job cannot be done without its master
+// playlist.
+func (j *Jobs) Finish(ctx context.Context, id, worker, failure string) error {
+	if failure == "" {
+		if n, err := j.store.Size(media.DerivedKey(id, MasterPlaylist)); err != nil {
+			return err
+		} else if n == 0 {
+			failure = "no " + MasterPlaylist + " was stored"
+		}
+	}
+
+	t := models.Transcode{Status: models.TranscodeDone, Progress: 1, Worker: worker}
+	if failure != "" {
+		if len(failure) > maxFailureLength {
+			failure = failure[:maxFailureLength]
+		}
+		t = models.Transcode{Status: models.TranscodeFailed, Worker: worker, Error: failure}
+	}
+	if err := j.update(ctx, id, worker, t); err != nil {
+		return err
+	}
+
+	if failure == "" {
+		j.announce(ctx, id, models.MediaEventPlayable, PlaylistURL(id))
+	} else {
+		j.announce(ctx, id, models.MediaEventTranscodeFailed, "")
+	}
+	return nil
+}
+
+// Local returns a Queue for a Worker inside the server, named worker.
+func (j *Jobs) Local(worker string) Queue {
+	return localQueue{jobs: j, worker: worker}
+}
+
+// announce tells the room of upload id about it, unless it was deleted in
+// the meantime.
+func (j *Jobs) announce(ctx context.Context, id, event, url string) {
+	file, err := j.files.GetByID(ctx, id)
+	if err != nil {
+		return
+	}
+	file.Received = file.Size
+	if err := SetPreviews(j.store, file); err != nil {
+		log.Printf("transcode: %s: %v", id, err)
+	}
+	j.notify.NotifyMedia(models.MediaEvent{Event: event, Media: file, URL: url})
+}
+
+// held returns the record of job id if worker holds it, or ErrLost.
+func (j *Jobs) held(ctx context.Context, id, worker string) (*models.MediaFile, error) {
+	file, err := j.files.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrLost
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t := file.Transcode; t == nil || t.Status != models.TranscodeRunning || t.Worker != worker {
+		return nil, ErrLost
+	}
+	return file, nil
+}
+
+// update stores t, stamped now, as job id if worker holds it, or returns
+// ErrLost.
+func (j *Jobs) update(ctx context.Context, id, worker string, t models.Transcode) error {
+	t.UpdatedAt = j.now()
+	err := j.files.UpdateTranscode(ctx, id, worker, t)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrLost
+	}
+	return err
+}
+
+// localQueue is the Queue of a Worker inside the server.
+type localQueue struct {
+	jobs   *Jobs
+	worker string
+}
+
+func (q localQueue) Claim(ctx context.Context) (*models.MediaFile, error) {
+	return q.jobs.Claim(ctx, q.worker)
+}
+
+func (q localQueue) Source(ctx context.Context, id string) (io.ReadCloser, error) {
+	return q.jobs.Source(ctx, id, q.worker)
+}
+
+func (q localQueue) Progress(ctx context.Context, id string, progress float64) error {
+	return q.jobs.Progress(ctx, id, q.worker, progress)
+}
+
+func (q localQueue) Put(ctx context.Context, id, name string, r io.Reader) error {
+	return q.jobs.Put(ctx, id, q.worker, name, r)
+}
+
+func (q localQueue) Finish(ctx context.Context, id, failure string) error {
+	return q.jobs.Finish(ctx, id, q.worker, failure)
+}
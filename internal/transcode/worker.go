@@ -0,0 +1,326 @@
+package transcode
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+const (
+	// segmentSeconds is the target duration of HLS segments.
+	segmentSeconds = 6
+
+	// audioKbps is the audio bitrate of every rendition.
+	audioKbps = 128
+
+	// progressInterval is how often a running job reports progress, well
+	// within ClaimTimeout.
+	progressInterval = 30 * time.Second
+
+	// stderrLines is how many lines of ffmpeg's output are kept to explain
+	// a failure.
+	stderrLines = 20
+)
+
+// durationLine matches the input duration ffmpeg prints to stderr.
+var durationLine = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// Worker transcodes the jobs of a Queue, one at a time.
+type Worker struct {
+	Queue      Queue
+	FFmpeg     string // path of the ffmpeg binary
+	Renditions []int  // heights of the renditions, e.g. 720, 480; never upscaled
+}
+
+// Run transcodes jobs until none is waiting. It has the signature of a
+// jobs.Func. A job is abandoned, to be claimed again after ClaimTimeout,
+// if ctx is done or the queue cannot be reached; the error is returned.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		file, err := w.Queue.Claim(ctx)
+		if errors.Is(err, ErrNoJob) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("claim: %w", err)
+		}
+
+		start := time.Now()
+		log.Printf("transcode: %s (%s) started", file.ID, file.FileName)
+		err = w.transcode(ctx, file)
+		switch {
+		case errors.Is(err, ErrLost):
+			log.Printf("transcode: %s was taken over or deleted, giving up", file.ID)
+		case err != nil:
+			return fmt.Errorf("%s: %w", file.ID, err)
+		default:
+			log.Printf("transcode: %s finished in %s", file.ID, time.Since(start).Round(time.Second))
+		}
+	}
+}
+
+// transcode runs one job. ffmpeg failures end the job as failed; the
+// errors returned leave it to be claimed again.
+func (w *Worker) transcode(ctx context.Context, file *models.MediaFile) error {
+	dir, err := os.MkdirTemp("", "transcode-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src, err := w.Queue.Source(ctx, file.ID)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer src.Close()
+	input, err := localInput(src, dir)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+
+	out := filepath.Join(dir, "out")
+	if err := os.Mkdir(out, 0o755); err != nil {
+		return err
+	}
+	if err := w.previews(ctx, file.ID, input, dir); err != nil {
+		return err
+	}
+
+	_, err = w.ffmpeg(ctx, input, w.hlsArgs(input, out), func(progress float64) error {
+		return w.Queue.Progress(ctx, file.ID, progress)
+	})
+	var failed *ffmpegError
+	if errors.As(err, &failed) {
+		log.Printf("transcode: %s failed: %v", file.ID, failed)
+		return w.Queue.Finish(ctx, file.ID, failed.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(out, MasterPlaylist), w.master(), 0o644); err != nil {
+		return err
+	}
+	if err := w.putAll(ctx, file.ID, out); err != nil {
+		return err
+	}
+	return w.Queue.Finish(ctx, file.ID, "")
+}
+
+// localInput returns a path ffmpeg can read src from. Uploads in the
+// server's own store are read in place; others are copied into dir first,
+// since ffmpeg needs to seek in most containers.
+func localInput(src io.Reader, dir string) (string, error) {
+	if f, ok := src.(*os.File); ok {
+		return f.Name(), nil
+	}
+	f, err := os.Create(filepath.Join(dir, "source"))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// ffmpegError is an ffmpeg run that failed on its own account, most
+// likely because of the input: the job fails rather than being retried.
+type ffmpegError struct {
+	err    error
+	stderr string // the last line ffmpeg printed
+}
+
+func (e *ffmpegError) Error() string {
+	if e.stderr == "" {
+		return "ffmpeg: " + e.err.Error()
+	}
+	return "ffmpeg: " + e.err.Error() + ": " + e.stderr
+}
+
+// ffmpeg runs ffmpeg with args reading input, and returns the input's
+// duration in seconds (0 if ffmpeg did not say). If report is set, it is
+// called with the progress every progressInterval, for args asking for
+// -progress on stdout; an error from report stops ffmpeg and is returned.
+func (w *Worker) ffmpeg(ctx context.Context, input string, args []string, report func(float64) error) (float64, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	cmd := exec.CommandContext(ctx, w.FFmpeg, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err // not the input's fault: leave the job to be retried
+	}
+
+	// stderr carries the input's duration and, on failure, the reason.
+	type summary struct {
+		duration float64
+		lines    []string
+	}
+	durations := make(chan float64, 1)
+	summaries := make(chan summary, 1)
+	go func() {
+		var sum summary
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if d := durationLine.FindStringSubmatch(line); d != nil && sum.duration == 0 {
+				h, _ := strconv.ParseFloat(d[1], 64)
+				m, _ := strconv.ParseFloat(d[2], 64)
+				s, _ := strconv.ParseFloat(d[3], 64)
+				sum.duration = h*3600 + m*60 + s
+				durations <- sum.duration
+			}
+			if line != "" {
+				sum.lines = append(sum.lines, line)
+				if len(sum.lines) > stderrLines {
+					sum.lines = sum.lines[1:]
+				}
+			}
+		}
+		summaries <- sum
+	}()
+
+	// stdout carries -progress reports: key=value lines, a block every
+	// half second or so.
+	var duration float64
+	lastReport := time.Now()
+	sc := bufio.NewScanner(stdout)
+	for report != nil && sc.Scan() {
+		key, value, _ := strings.Cut(sc.Text(), "=")
+		if key != "out_time_us" || time.Since(lastReport) < progressInterval {
+			continue
+		}
+		select {
+		case duration = <-durations:
+		default:
+		}
+		var progress float64
+		if us, err := strconv.ParseFloat(value, 64); err == nil && duration > 0 {
+			progress = min(us/1e6/duration, 0.99)
+		}
+		if err := report(progress); err != nil {
+			cancel(err)
+			break
+		}
+		lastReport = time.Now()
+	}
+	io.Copy(io.Discard, stdout)
+
+	sum := <-summaries
+	err = cmd.Wait()
+	if cause := context.Cause(ctx); cause != nil {
+		return 0, cause
+	}
+	if err != nil {
+		var last string
+		if len(sum.lines) > 0 {
+			// The failure is shown to the room: keep local paths out of it.
+			last = strings.ReplaceAll(sum.lines[len(sum.lines)-1], input, "input")
+		}
+		return 0, &ffmpegError{err: err, stderr: last}
+	}
+	return sum.duration, nil
+}
+
+// hlsArgs returns ffmpeg's arguments for the renditions: one HLS output
+// per rendition, scaled down to its height (never up) with keyframes on
+// segment boundaries.
+func (w *Worker) hlsArgs(input, out string) []string {
+	args := []string{"-hide_banner", "-nostdin", "-y", "-i", input, "-progress", "pipe:1", "-nostats"}
+	for _, height := range w.Renditions {
+		name := renditionName(height)
+		kbps := videoKbps(height)
+		args = append(args,
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", fmt.Sprintf(`scale=-2:min(%d\,trunc(ih/2)*2)`, height),
+			"-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p",
+			"-b:v", fmt.Sprintf("%dk", kbps),
+			"-maxrate", fmt.Sprintf("%dk", kbps*107/100),
+			"-bufsize", fmt.Sprintf("%dk", kbps*2),
+			"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentSeconds),
+			"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", audioKbps), "-ac", "2",
+			"-f", "hls", "-hls_time", strconv.Itoa(segmentSeconds), "-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(out, name+"_%05d.ts"),
+			filepath.Join(out, name+".m3u8"),
+		)
+	}
+	return args
+}
+
+// master returns the multivariant playlist listing the renditions.
+func (w *Worker) master() []byte {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, height := range w.Renditions {
+		peak := (videoKbps(height)*107/100 + audioKbps) * 1000
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d\n%s.m3u8\n", peak, renditionName(height))
+	}
+	return []byte(b.String())
+}
+
+// putAll hands every file in out to the queue: segments, then the media
+// playlists, and the master playlist last, so that a job with a master
+// playlist has all its files.
+func (w *Worker) putAll(ctx context.Context, id, out string) error {
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		return err
+	}
+	rank := func(name string) int {
+		switch {
+		case name == MasterPlaylist:
+			return 2
+		case strings.HasSuffix(name, ".m3u8"):
+			return 1
+		default:
+			return 0
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return rank(entries[i].Name()) < rank(entries[j].Name()) })
+
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(out, e.Name()))
+		if err != nil {
+			return err
+		}
+		err = w.Queue.Put(ctx, id, e.Name(), f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("put %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// renditionName is the base name of the files of a rendition.
+func renditionName(height int) string {
+	return strconv.Itoa(height) + "p"
+}
+
+// videoKbps is the video bitrate of a rendition, growing with its area:
+// about 2.6 Mbit/s at 720p and 1.2 Mbit/s at 480p.
+func videoKbps(height int) int {
+	return height * height / 200
+}
@@ -0,0 +1,69 @@
+// Package trust assigns users trust levels from account age and activity
+// and decides which capabilities a level unlocks.
+//
+// Levels only go up: the trust job (jobs.TrustLevels) promotes users once
+// they meet a level's thresholds and never demotes them, e.g. when
+// retention deletes their old messages. The level is embedded in the JWT
+// when it is issued, so a promotion takes effect once the client
+// refreshes its token (POST /api/token/refresh) or logs in again.
+package trust
+
+import (
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// Policy holds the thresholds for automatic promotion. A user reaches a
+// level once their account is old enough AND they have sent enough
+// messages.
+type Policy struct {
+	MemberAge       time.Duration
+	MemberMessages  int
+	RegularAge      time.Duration
+	RegularMessages int
+}
+
+// Level returns the highest level that an account created at createdAt,
+// with messages stored messages, qualifies for at now.
+func (p Policy) Level(createdAt time.Time, messages int, now time.Time) string {
+	age := now.Sub(createdAt)
+	switch {
+	case age >= p.RegularAge && messages >= p.RegularMessages:
+		return models.TrustRegular
+	case age >= p.MemberAge && messages >= p.MemberMessages:
+		return models.TrustMember
+	default:
+		return models.TrustNew
+	}
+}
+
+// Rank orders levels: 0 for new, 1 for member, 2 for regular. Empty and
+// unknown levels (e.g. tokens issued before trust levels existed) rank
+// as new.
+func Rank(level string) int {
+	switch level {
+	case models.TrustMember:
+		return 1
+	case models.TrustRegular:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Valid reports whether level is one of the models.Trust* constants.
+func Valid(level string) bool {
+	switch level {
+	case models.TrustNew, models.TrustMember, models.TrustRegular:
+		return true
+	}
+	return false
+}
+
+// Allows reports whether a user at the given trust level may use a
+// capability that requires min. Roles with the authz.PermTrustBypass
+// permission skip this check; callers test that first.
+func Allows(level, min string) bool {
+	return Rank(level) >= Rank(min)
+}
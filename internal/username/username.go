@@ -0,0 +1,139 @@
+// Package username normalizes usernames and decides which ones may be
+// registered.
+//
+// Usernames are stored as typed after NFKC normalization, so "ｂｏｂ" and
+// "bob" are the same name, and compared without case: Key gives the form
+// every storage backend indexes them by, and logins as "Bob" find "bob".
+// Names that only differ from a reserved one, or from another user's, by
+// look-alike letters of another script are refused at registration (see
+// Skeleton), since they would read the same in chat.
+//
+// Usage:
+//
+//	name := username.Normalize(req.Username)
+//	if err := policy.Check(name); err != nil {
+//	    // errors.Is(err, username.ErrReserved), ...
+//	}
+package username
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ofenes/pkg/apperr"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Reasons a name fails Policy.Check, with their error codes.
+var (
+	ErrLength      = apperr.New(apperr.Invalid, "username_length", "username: too short or too long")
+	ErrCharset     = apperr.New(apperr.Invalid, "username_invalid_characters", "username: only letters, digits and . _ - allowed, starting and ending with a letter or digit")
+	ErrMixedScript = apperr.New(apperr.Invalid, "username_mixed_scripts", "username: letters from more than one script")
+	ErrReserved    = apperr.New(apperr.Invalid, "username_reserved", "username: reserved")
+)
+
+// Policy holds the rules a new username must meet.
+type Policy struct {
+	MinLength int      // in characters
+	MaxLength int      // in characters
+	Reserved  []string // names nobody may register, e.g. "system"; compared by Skeleton
+}
+
+// Normalize returns name in NFKC form without surrounding whitespace: the
+// form usernames are stored and checked in.
+func Normalize(name string) string {
+	return strings.TrimSpace(norm.NFKC.String(name))
+}
+
+// Key returns the form usernames are unique and looked up by: normalized
+// and lower case.
+func Key(name string) string {
+	return strings.ToLower(Normalize(name))
+}
+
+// Skeleton returns Key(name) with letters that look like Latin ones, such
+// as Cyrillic "а" and Greek "ο", replaced by them. Two names with the same
+// skeleton read the same.
+func Skeleton(name string) string {
+	return strings.Map(func(r rune) rune {
+		if l, ok := confusables[r]; ok {
+			return l
+		}
+		return r
+	}, Key(name))
+}
+
+// Check reports whether the normalized name may be registered. An
+// ErrLength is wrapped with the lengths allowed, for its message.
+func (p Policy) Check(name string) error {
+	if n := utf8.RuneCountInString(name); n < p.MinLength || n > p.MaxLength {
+		return apperr.Wrap(ErrLength, apperr.Invalid, "username_length", p.MinLength, p.MaxLength)
+	}
+
+	runes := []rune(name)
+	var script string
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case unicode.Is(unicode.Mn, r) && i > 0:
+			// Combining marks of scripts NFKC does not compose.
+		case strings.ContainsRune("._-", r) && i > 0 && i < len(runes)-1:
+		default:
+			return ErrCharset
+		}
+		if s := scriptOf(r); s != "" {
+			if script != "" && !compatibleScripts(script, s) {
+				return ErrMixedScript
+			}
+			if script == "" || script == "Han" {
+				script = s
+			}
+		}
+	}
+
+	skeleton := Skeleton(name)
+	for _, reserved := range p.Reserved {
+		if skeleton == Skeleton(reserved) {
+			return ErrReserved
+		}
+	}
+	return nil
+}
+
+// scriptOf returns the name of r's script, or "" for characters shared by
+// all scripts (digits, punctuation, marks).
+func scriptOf(r rune) string {
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// compatibleScripts reports whether a name may mix scripts a and b:
+// Japanese and Korean are written with Han characters among their own.
+func compatibleScripts(a, b string) bool {
+	japanese := func(s string) bool { return s == "Han" || s == "Hiragana" || s == "Katakana" }
+	korean := func(s string) bool { return s == "Han" || s == "Hangul" }
+	return a == b || japanese(a) && japanese(b) || korean(a) && korean(b)
+}
+
+// confusables maps lower-case letters of other scripts to the Latin letter
+// they are mistaken for.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'к': 'k',
+	'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y',
+	'х': 'x', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ү': 'y', 'ӏ': 'l',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+	// Armenian
+	'օ': 'o', 'ս': 'u', 'ց': 'g', 'հ': 'h', 'ո': 'n',
+}
@@ -0,0 +1,148 @@
+// Package webhook posts server events to URLs configured by the operator,
+// for bots and integrations that would rather be told than poll: each
+// event is a JSON Event POSTed to every URL, one at a time, in order.
+//
+// With a secret, each request carries an X-Ofenes-Signature header,
+// "sha256=" and the hex HMAC-SHA256 of the body under the secret, so
+// receivers can check it came from this server. Deliveries are attempted
+// once; failures are logged and the event is dropped.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+const (
+	// queueSize bounds the events waiting for delivery.
+	queueSize = 256
+
+	// timeout bounds one request.
+	timeout = 5 * time.Second
+)
+
+// Event types.
+const (
+	EventNowPlaying = "now_playing" // Data is a models.NowPlaying
+)
+
+// Event is the body of a webhook request.
+type Event struct {
+	Event  string    `json:"event"` // Event*
+	Data   any       `json:"data"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// Sender posts events to its URLs. Create one with New and start it with
+// Run; its methods are safe for concurrent use and never block.
+type Sender struct {
+	urls   []string
+	secret []byte
+	client *http.Client
+	queue  chan Event
+}
+
+// ParseURLs parses a comma-separated list of http(s) URLs from config.
+func ParseURLs(s string) ([]string, error) {
+	var urls []string
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook: %q is not an http(s) URL", raw)
+		}
+		urls = append(urls, raw)
+	}
+	return urls, nil
+}
+
+// New creates a Sender posting to urls, signing with secret unless it is
+// empty.
+func New(urls []string, secret string) *Sender {
+	s := &Sender{
+		urls:   urls,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan Event, queueSize),
+	}
+	if secret != "" {
+		s.secret = []byte(secret)
+	}
+	return s
+}
+
+// NowPlaying queues a now_playing event. Implements ws.NowPlayingListener.
+func (s *Sender) NowPlaying(np models.NowPlaying) {
+	s.enqueue(Event{Event: EventNowPlaying, Data: np, SentAt: np.UpdatedAt})
+}
+
+// enqueue queues e, or drops it with a log line if the queue is full.
+func (s *Sender) enqueue(e Event) {
+	select {
+	case s.queue <- e:
+	default:
+		log.Printf("webhook: queue full, dropping %s event", e.Event)
+	}
+}
+
+// Run delivers queued events until ctx is done. Call it in a goroutine.
+func (s *Sender) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-s.queue:
+			body, err := json.Marshal(e)
+			if err != nil {
+				log.Printf("webhook: failed to marshal %s event: %v", e.Event, err)
+				continue
+			}
+			for _, u := range s.urls {
+				if err := s.post(ctx, u, e.Event, body); err != nil {
+					log.Printf("webhook: failed to deliver %s event to %s: %v", e.Event, u, err)
+				}
+			}
+		}
+	}
+}
+
+// post sends one event to u.
+func (s *Sender) post(ctx context.Context, u, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Ofenes-Event", event)
+	if s.secret != nil {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		req.Header.Set("X-Ofenes-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
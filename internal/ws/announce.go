@@ -0,0 +1,75 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"ofenes/internal/models"
+)
+
+// announceQueueSize bounds the Announce calls waiting for the Hub.
+const announceQueueSize = 16
+
+// Announce sends an "admin" message from an operator, text from the user
+// userID/username, to everyone in room, or in every room if room is "", as
+// if they had sent it there (POST /api/admin/broadcast). Each room's copy
+// is stamped with the server time and the room's next Seq, and goes
+// through the pre-broadcast hooks. It returns how many rooms and
+// connections it was sent to on this instance.
+//
+// Safe to call from any goroutine; like Connections, it waits for the Hub,
+// and returns ctx.Err() if ctx is done first and nothing once the Hub has
+// stopped.
+func (h *Hub) Announce(ctx context.Context, room, userID, username, text string) (models.BroadcastResult, error) {
+	a := announcement{
+		room:  room,
+		msg:   models.Message{Type: models.MsgTypeAdmin, Sender: username, SenderID: userID, Payload: text},
+		reply: make(chan models.BroadcastResult, 1),
+	}
+	select {
+	case h.announcements <- a:
+	case <-h.done:
+		return models.BroadcastResult{}, nil
+	case <-ctx.Done():
+		return models.BroadcastResult{}, ctx.Err()
+	}
+	select {
+	case res := <-a.reply:
+		return res, nil
+	case <-h.done:
+		return models.BroadcastResult{}, nil
+	case <-ctx.Done():
+		return models.BroadcastResult{}, ctx.Err()
+	}
+}
+
+// announcement is a pending Announce call.
+type announcement struct {
+	room  string
+	msg   models.Message
+	reply chan models.BroadcastResult
+}
+
+// announce answers an Announce call.
+func (h *Hub) announce(a announcement) {
+	var res models.BroadcastResult
+	for room, roomClients := range h.clients {
+		if a.room != "" && room != a.room {
+			continue
+		}
+		msg := a.msg
+		msg.Timestamp = h.now()
+		msg.Seq = h.nextSeq(room, msg.Timestamp)
+		data, err := json.Marshal(msg)
+		if err != nil {
+			log.Printf("ws: failed to marshal announcement: %v", err)
+			break
+		}
+		res.Rooms++
+		res.Connections += len(roomClients)
+		h.broadcastToRoom(room, data)
+	}
+	log.Printf("ws: announcement sent (user=%s, rooms=%d, connections=%d)", a.msg.Sender, res.Rooms, res.Connections)
+	a.reply <- res
+}
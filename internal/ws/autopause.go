@@ -0,0 +1,167 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// Rooms may have the Hub pause their playback when the host, or everyone,
+// is gone (SyncPolicy.PauseOnDisconnect), instead of letting the video
+// "play" to nobody and finding it far ahead when people come back. The
+// Hub pauses each playing screen at its current position with a
+// video_sync from "system", remembers that it did, and resumes those
+// screens from there when they return. Anyone controlling a screen in the
+// meantime takes it over.
+
+// heldStateTTL is how long the paused playback of an empty room is kept
+// for its members to come back to.
+const heldStateTTL = 12 * time.Hour
+
+// ParsePauseOnDisconnect validates a pause-on-disconnect mode from config:
+// "host", "everyone" or "off", returned as "".
+func ParsePauseOnDisconnect(s string) (string, error) {
+	switch s {
+	case models.PauseOnHost, models.PauseOnEveryone:
+		return s, nil
+	case "off":
+		return "", nil
+	default:
+		return "", fmt.Errorf("ws: unknown pause-on-disconnect mode %q", s)
+	}
+}
+
+// pauseIfHostLeft pauses room's playback if its policy pauses while the
+// host is away and no owner is connected any more. Called after a
+// departure that is final; rooms without room roles have no host, and
+// are only paused once empty (see holdPaused).
+func (h *Hub) pauseIfHostLeft(room string) {
+	if h.syncPolicy(room).PauseOnDisconnect != models.PauseOnHost {
+		return
+	}
+	if _, tracked := h.hosts[room]; !tracked || h.ownerConnected(room) {
+		return
+	}
+	h.autoPause(room)
+}
+
+// holdPaused pauses the playback of room, just emptied, if its policy
+// pauses on disconnect, and reports whether it is paused: the state is
+// then kept for heldStateTTL instead of dropped.
+func (h *Hub) holdPaused(room string) bool {
+	if h.syncPolicy(room).PauseOnDisconnect == "" {
+		return false
+	}
+	h.autoPause(room)
+	if _, paused := h.autoPaused[room]; !paused {
+		return false
+	}
+	h.autoPaused[room] = h.now()
+	return true
+}
+
+// autoPause pauses the playing screens of room at their current
+// position, telling anyone still there.
+func (h *Hub) autoPause(room string) {
+	now := h.now()
+	for screen, state := range h.syncStates[room] {
+		if !state.payload.Playing {
+			continue
+		}
+		payload := state.payload
+		payload.Event = models.VideoEventPause
+		payload.Playing = false
+		payload.Timestamp = state.position(now)
+		payload.TriggeredBy = "system"
+		h.setSystemVideoState(room, payload, now, true)
+		h.autoPaused[room] = now
+		log.Printf("ws: paused room %s at %.1fs on disconnect", screenLabel(room, screen), payload.Timestamp)
+	}
+}
+
+// resumeIfBack resumes the screens the Hub paused in client's room, if
+// client is who it was waiting for: the owner under PauseOnHost (anyone
+// in rooms without room roles), anyone otherwise. The room, client
+// included, is sent their video_sync; it returns the screens resumed.
+func (h *Hub) resumeIfBack(client *Client) map[string]bool {
+	room := client.RoomID
+	if _, paused := h.autoPaused[room]; !paused {
+		return nil
+	}
+	if h.syncPolicy(room).PauseOnDisconnect == models.PauseOnHost && client.RoomRole != "" && client.RoomRole != models.RoomRoleOwner {
+		return nil
+	}
+	delete(h.autoPaused, room)
+
+	resumed := make(map[string]bool)
+	now := h.now()
+	for screen, state := range h.syncStates[room] {
+		if _, ok := h.screenControl(room, screen); !ok || !state.autoPaused {
+			continue
+		}
+		payload := state.payload
+		payload.Event = models.VideoEventPlay
+		payload.Playing = true
+		payload.TriggeredBy = "system"
+		h.setSystemVideoState(room, payload, now, false)
+		resumed[screen] = true
+		log.Printf("ws: resumed room %s at %.1fs (user=%s)", screenLabel(room, screen), payload.Timestamp, client.Username)
+	}
+	return resumed
+}
+
+// setSystemVideoState makes payload the playback of its screen in room as
+// of now and broadcasts it from "system".
+func (h *Hub) setSystemVideoState(room string, payload models.VideoSyncPayload, now time.Time, autoPaused bool) {
+	msg, ok := systemVideoSync(payload, now)
+	if !ok {
+		return
+	}
+	h.setVideoState(room, payload.Screen, syncState{payload: payload, at: now, autoPaused: autoPaused}, msg)
+	h.broadcastToRoom(room, msg)
+	if payload.Screen == "" {
+		h.saveVideoState(room)
+		h.announceNowPlaying(room)
+	}
+}
+
+// systemVideoSync returns a video_sync message from "system" carrying
+// payload, stamped now.
+func systemVideoSync(payload models.VideoSyncPayload, now time.Time) ([]byte, bool) {
+	data, _ := json.Marshal(payload)
+	msg, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeVideoSync,
+		Sender:    "system",
+		Payload:   string(data),
+		Timestamp: now,
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal video_sync: %v", err)
+		return nil, false
+	}
+	return msg, true
+}
+
+// ownerConnected reports whether the owner of room is connected to it.
+func (h *Hub) ownerConnected(room string) bool {
+	for client := range h.clients[room] {
+		if client.RoomRole == models.RoomRoleOwner {
+			return true
+		}
+	}
+	return false
+}
+
+// expireHeldStates drops the paused playback of rooms empty for longer
+// than heldStateTTL.
+func (h *Hub) expireHeldStates() {
+	now := h.now()
+	for room, at := range h.autoPaused {
+		if len(h.clients[room]) == 0 && now.Sub(at) > heldStateTTL {
+			h.dropVideoState(room)
+		}
+	}
+}
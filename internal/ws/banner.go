@@ -0,0 +1,205 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// Site-wide announcements (models.Announcement) are shown as banners. The
+// Hub holds the ones that have not ended, reloaded whenever an admin
+// changes them, and sends each client an "announcements" message listing
+// those in effect that its user hasn't dismissed: on connect if there are
+// any, and again whenever that list changes because an announcement was
+// created, edited or deleted, its schedule started or ended it, or the
+// user dismissed one. Which announcements a user dismissed is looked up
+// when they connect.
+
+const (
+	// bannerQueueSize bounds the announcement changes waiting for the Hub
+	// goroutine.
+	bannerQueueSize = 16
+
+	// bannerCheckInterval is how often the Hub checks whether scheduled
+	// announcements started or ended.
+	bannerCheckInterval = time.Second
+)
+
+// bannerChange asks the Hub goroutine to install the current
+// announcements, or, with reload unset, to hide one from a user who
+// dismissed it.
+type bannerChange struct {
+	reload        bool
+	announcements []*models.Announcement
+	userID        string
+	dismissed     string
+}
+
+// SetAnnouncements installs the announcements that have not ended and
+// sends every client its list again. Safe to call from any goroutine; the
+// change is dropped with a log line if the Hub is backed up.
+func (h *Hub) SetAnnouncements(announcements []*models.Announcement) {
+	h.queueBannerChange(bannerChange{reload: true, announcements: announcements})
+}
+
+// ReloadAnnouncements loads the announcements that have not ended from
+// repo and installs them. On error the current announcements stay in
+// place. Safe to call from any goroutine.
+func (h *Hub) ReloadAnnouncements(ctx context.Context, repo repository.AnnouncementRepository) error {
+	announcements, err := repo.ListCurrent(ctx, h.now())
+	if err != nil {
+		return err
+	}
+	h.SetAnnouncements(announcements)
+	return nil
+}
+
+// DismissAnnouncement hides announcement id from userID's connections,
+// sending them their list again; call it after storing the dismissal.
+// Safe to call from any goroutine, like SetAnnouncements.
+func (h *Hub) DismissAnnouncement(userID, id string) {
+	h.queueBannerChange(bannerChange{userID: userID, dismissed: id})
+}
+
+// queueBannerChange hands c to the Hub goroutine.
+func (h *Hub) queueBannerChange(c bannerChange) {
+	select {
+	case h.bannerChanges <- c:
+	default:
+		log.Printf("ws: announcement queue full, dropping change")
+	}
+}
+
+// applyBannerChange answers SetAnnouncements and DismissAnnouncement.
+func (h *Hub) applyBannerChange(c bannerChange) {
+	if c.reload {
+		// Periodic reloads mostly find nothing new; don't resend then.
+		changed := !slices.EqualFunc(h.banners, c.announcements, func(a, b *models.Announcement) bool {
+			return a.ID == b.ID && a.UpdatedAt.Equal(b.UpdatedAt)
+		})
+		h.banners = c.announcements
+		live := h.bannersInEffect()
+		if !changed && slices.Equal(live, h.liveBanners) {
+			return
+		}
+		h.liveBanners = live
+		h.resendAnnouncements(func(*Client) bool { return true })
+		return
+	}
+	h.resendAnnouncements(func(client *Client) bool {
+		if client.UserID != c.userID || client.dismissed[c.dismissed] {
+			return false
+		}
+		if client.dismissed == nil {
+			client.dismissed = make(map[string]bool)
+		}
+		client.dismissed[c.dismissed] = true
+		return true
+	})
+}
+
+// bannerTicker returns a ticker for checkAnnouncements.
+func (h *Hub) bannerTicker() (<-chan time.Time, func()) {
+	t := time.NewTicker(bannerCheckInterval)
+	return t.C, t.Stop
+}
+
+// checkAnnouncements forgets the announcements that ended and, if any
+// started or ended since the last check, sends every client its list
+// again. Called on every bannerTicker tick.
+func (h *Hub) checkAnnouncements() {
+	if len(h.banners) == 0 {
+		return
+	}
+	now := h.now()
+	h.banners = slices.DeleteFunc(h.banners, func(a *models.Announcement) bool {
+		return a.EndsAt != nil && !a.EndsAt.After(now)
+	})
+	live := h.bannersInEffect()
+	if slices.Equal(live, h.liveBanners) {
+		return
+	}
+	h.liveBanners = live
+	h.resendAnnouncements(func(*Client) bool { return true })
+}
+
+// bannersInEffect returns the IDs of the announcements in effect now, in
+// order.
+func (h *Hub) bannersInEffect() []string {
+	now := h.now()
+	var ids []string
+	for _, a := range h.banners {
+		if !a.StartsAt.After(now) && (a.EndsAt == nil || a.EndsAt.After(now)) {
+			ids = append(ids, a.ID)
+		}
+	}
+	return ids
+}
+
+// resendAnnouncements sends the clients resend accepts their list again.
+// Clients whose queue is full are disconnected.
+func (h *Hub) resendAnnouncements(resend func(*Client) bool) {
+	var slow []*Client
+	for _, roomClients := range h.clients {
+		for client := range roomClients {
+			if resend(client) && !h.sendAnnouncements(client, true) {
+				slow = append(slow, client)
+			}
+		}
+	}
+
+	// Disconnect after the loop: removal modifies h.clients.
+	for _, client := range slow {
+		h.disconnectSlowClient(client)
+	}
+}
+
+// sendAnnouncements sends client the announcements in effect that its
+// user hasn't dismissed; if there are none, only when empty is set. It
+// reports false if the client's queue is full.
+func (h *Hub) sendAnnouncements(client *Client, empty bool) bool {
+	shown := []*models.Announcement{}
+	for _, a := range h.banners {
+		if slices.Contains(h.liveBanners, a.ID) && !client.dismissed[a.ID] {
+			shown = append(shown, a)
+		}
+	}
+	if len(shown) == 0 && !empty {
+		return true
+	}
+
+	payload, _ := json.Marshal(shown)
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeAnnouncements,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal announcements: %v", err)
+		return true
+	}
+	return h.send(client, data)
+}
+
+// dismissedAnnouncements looks up which announcements a connecting user
+// dismissed: none without an Announcements repository.
+func (h *Hub) dismissedAnnouncements(ctx context.Context, userID string) (map[string]bool, error) {
+	if h.opts.Announcements == nil {
+		return nil, nil
+	}
+	ids, err := h.opts.Announcements.ListDismissed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dismissed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		dismissed[id] = true
+	}
+	return dismissed, nil
+}
@@ -0,0 +1,88 @@
+package ws
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// BatchMode selects how writePump coalesces queued messages into frames.
+//
+// Frame formats:
+//
+//	none       — one JSON message per frame
+//	newline    — messages joined by '\n' in one frame (legacy default)
+//	json_array — a JSON array of messages: [{...},{...}]
+//
+// A batch never grows past the configured max frame size; a single message
+// larger than the limit is still sent, alone, in its own frame.
+type BatchMode string
+
+// Supported batch modes.
+const (
+	BatchNone      BatchMode = "none"
+	BatchNewline   BatchMode = "newline"
+	BatchJSONArray BatchMode = "json_array"
+)
+
+// ParseBatchMode validates a batch mode name from config.
+func ParseBatchMode(s string) (BatchMode, error) {
+	switch m := BatchMode(s); m {
+	case BatchNone, BatchNewline, BatchJSONArray:
+		return m, nil
+	default:
+		return "", fmt.Errorf("ws: unknown batch mode %q", s)
+	}
+}
+
+// batcher builds one outgoing frame at a time.
+type batcher struct {
+	mode     BatchMode
+	maxBytes int
+	buf      bytes.Buffer
+	count    int
+}
+
+// reset starts an empty frame.
+func (b *batcher) reset() {
+	b.buf.Reset()
+	b.count = 0
+}
+
+// empty reports whether the frame has no messages yet.
+func (b *batcher) empty() bool {
+	return b.count == 0
+}
+
+// fits reports whether message can join the current frame.
+func (b *batcher) fits(message []byte) bool {
+	if b.count == 0 {
+		return true
+	}
+	if b.mode == BatchNone {
+		return false
+	}
+	// One separator plus, for arrays, the closing bracket.
+	return b.maxBytes <= 0 || b.buf.Len()+1+len(message)+1 <= b.maxBytes
+}
+
+// add appends message to the current frame. Callers must check fits first.
+func (b *batcher) add(message []byte) {
+	switch {
+	case b.count == 0 && b.mode == BatchJSONArray:
+		b.buf.WriteByte('[')
+	case b.count > 0 && b.mode == BatchJSONArray:
+		b.buf.WriteByte(',')
+	case b.count > 0:
+		b.buf.WriteByte('\n')
+	}
+	b.buf.Write(message)
+	b.count++
+}
+
+// frame returns the encoded frame. The slice is valid until the next reset.
+func (b *batcher) frame() []byte {
+	if b.mode == BatchJSONArray {
+		b.buf.WriteByte(']')
+	}
+	return b.buf.Bytes()
+}
@@ -1,45 +1,118 @@
 package ws
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"ofenes/internal/auth"
+	"ofenes/internal/i18n"
+	"ofenes/internal/metrics"
+	"ofenes/internal/middleware"
+	"ofenes/internal/models"
 	"ofenes/pkg/response"
-
-	"github.com/gorilla/websocket"
-)
-
-// Tuning constants — configurable via constants for clarity.
-const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 65536 // 64KB — WebRTC SDP offers can be 4-8KB
 )
 
-// upgrader handles the HTTP → WebSocket protocol upgrade.
-// CheckOrigin is permissive in development.
-// TODO: Read allowed origins from config in production.
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 // Client represents a single WebSocket connection.
 // Each client is associated with an authenticated user (via UserID/Username)
 // and a specific room (via RoomID). It manages two goroutines: readPump and writePump.
 type Client struct {
-	hub      *Hub
-	conn     *websocket.Conn
-	Send     chan []byte
-	UserID   string // From JWT claims
-	Username string // From JWT claims
-	RoomID   string // From "room" query param
+	hub        *Hub
+	conn       Conn
+	Send       chan []byte
+	UserID     string // From JWT claims
+	Username   string // From JWT claims
+	Role       string // From JWT claims (models.Role*)
+	TrustLevel string // From JWT claims (models.Trust*; "" for older tokens)
+	RoomID     string // From "room" query param
+	RoomRole   string // models.RoomRole* in RoomID ("" = the room has no roles); owned by the Hub goroutine
+	Protocol   string // Negotiated subprotocol ("" = legacy client, treated as ProtocolJSONv1)
+
+	// ip is the address the connection is counted against in the connLimiter.
+	ip string
+
+	// sessionID anonymously identifies the connection to the WatchRecorder.
+	sessionID string
+
+	// analyticsOptOut is set by the "analytics=off" query parameter; the
+	// connection is then not reported to the WatchRecorder.
+	analyticsOptOut bool
+
+	// Overflow queue used by PolicyBuffer; wake signals writePump to drain it.
+	mu       sync.Mutex
+	overflow [][]byte
+	wake     chan struct{}
+
+	// Idle and lifetime tracking, owned by the Hub goroutine.
+	lastActivity    time.Time
+	idleWarned      bool
+	connectedAt     time.Time
+	reconnectHinted bool
+	rotating        bool // closed for max lifetime, expected to resume
+
+	// lastSeen is the UnixNano time of the last frame from the client (see seen).
+	lastSeen atomic.Int64
+
+	// probeAt is the UnixNano time of the unanswered keepalive probe, 0 if
+	// none; rtt is the smoothed round-trip time in nanoseconds (see latency.go).
+	probeAt atomic.Int64
+	rtt     atomic.Int64
+
+	// rttSeries is the round-trip histogram of the client's room, set by
+	// the Hub once it joins (see metrics.go).
+	rttSeries atomic.Pointer[metrics.Histogram]
+
+	// buckets holds per-type rate limiters, owned by the Hub goroutine.
+	buckets map[string]*tokenBucket
+
+	// tokenExpiry is when the JWT used to connect expires (zero = never).
+	tokenExpiry time.Time
+
+	// Policy strikes (rejected messages) in the current window, owned by the Hub goroutine.
+	strikes     int
+	strikeStart time.Time
+
+	// resumed is set when the client presented a valid resume token.
+	resumed bool
+
+	// spectator is set while the client only spectates its oversized room
+	// (see spectator.go), and listed while it is on the room's user list
+	// (see userlist.go); both owned by the Hub goroutine.
+	spectator bool
+	listed    bool
+
+	// playback is the last "playback_stats" report (see quality.go), owned
+	// by the Hub goroutine.
+	playback *playbackReport
+
+	// syncPolicy, screens and savedVideo are the room's sync policy (nil =
+	// server default), screens and saved playback (see persist.go) when
+	// the client connected. syncRate is the
+	// rate the client was last nudged to on each screen (absent = not
+	// nudged) and seekedAt when it was last told to seek there; both owned
+	// by the Hub goroutine (see drift.go).
+	syncPolicy *models.SyncPolicy
+	screens    []models.Screen
+	savedVideo *models.VideoState
+	syncRate   map[string]float64
+	seekedAt   map[string]time.Time
+
+	// dismissed holds the IDs of the site-wide announcements the user
+	// dismissed (see banner.go), looked up on connect and then owned by
+	// the Hub goroutine.
+	dismissed map[string]bool
+
+	// Backpressure stats, written by whoever sends to the client (see Hub.send).
+	highWater int
+	dropped   int
+
+	// Close frame sent when the Hub closes Send. Set by the Hub before closing.
+	closeCode   int
+	closeReason string
 }
 
 // ServeWs handles the WebSocket upgrade with JWT authentication.
@@ -48,119 +121,320 @@ type Client struct {
 //
 //	ws://localhost:8080/ws?token=eyJhbGci...
 //
+// or, in cookie mode, via the session cookie, which the browser sends with
+// the upgrade request.
+//
+// Adding "analytics=off" opts the connection out of watch analytics.
+//
 // The token is validated BEFORE the connection is upgraded. If the token
-// is missing or invalid, the request is rejected with 401 — no WebSocket
-// connection is established.
-func ServeWs(hub *Hub, jwtSecret string, w http.ResponseWriter, r *http.Request) {
+// is missing, invalid or revoked, or its user deleted or banned (see
+// auth.Revocations), the request is rejected with 401 — no WebSocket
+// connection is established. The origin and the connection limits are
+// checked as soon as the token is known to be genuine, before anything
+// is looked up, so rejected upgrades cost no database round trips.
+func ServeWs(hub *Hub, tc auth.TokenConfig, rev *auth.Revocations, w http.ResponseWriter, r *http.Request) {
 	// --- Authenticate BEFORE upgrading ---
 	tokenStr := r.URL.Query().Get("token")
+	if c, err := r.Cookie(middleware.AuthCookieName); tokenStr == "" && err == nil {
+		tokenStr = c.Value
+	}
 	if tokenStr == "" {
-		response.Error(w, http.StatusUnauthorized, "missing token query parameter")
+		rejectUpgrade(w, r, http.StatusUnauthorized, "missing_token")
 		return
 	}
 
-	claims, err := auth.ValidateToken(tokenStr, jwtSecret)
+	claims, err := auth.ValidateToken(tokenStr, tc)
 	if err != nil {
-		response.Error(w, http.StatusUnauthorized, "invalid or expired token")
+		rejectUpgrade(w, r, http.StatusUnauthorized, "invalid_or_expired_token")
+		return
+	}
+
+	// --- Reject cross-site upgrades ---
+	if !hub.originAllowed(r) {
+		log.Printf("ws: origin rejected (origin=%s, user=%s)", r.Header.Get("Origin"), claims.Username)
+		rejectUpgrade(w, r, http.StatusForbidden, "origin_not_allowed")
+		return
+	}
+
+	// --- Enforce connection limits ---
+	ip := ClientIP(r, hub.opts.TrustProxy)
+	if reason := hub.limiter.acquire(ip); reason != "" {
+		log.Printf("ws: connection rejected (ip=%s, limit=%s)", ip, reason)
+		w.Header().Set("Retry-After", "30")
+		rejectUpgrade(w, r, http.StatusTooManyRequests, "too_many_connections")
+		return
+	}
+
+	// From here on a rejected upgrade gives the slot back.
+	reject := func(status int, code string, args ...any) {
+		hub.limiter.release(ip)
+		rejectUpgrade(w, r, status, code, args...)
+	}
+
+	// --- Check the token is still good (revoked token, deleted user) ---
+	if err := rev.Check(r.Context(), claims); err != nil {
+		if errors.Is(err, auth.ErrTokenRevoked) {
+			reject(http.StatusUnauthorized, "token_revoked")
+			return
+		}
+		if errors.Is(err, auth.ErrAccountGone) {
+			reject(http.StatusUnauthorized, "account_gone")
+			return
+		}
+		log.Printf("ws: %v", err)
+		reject(http.StatusInternalServerError, "failed_to_check_token")
 		return
 	}
 
 	// --- Extract room ID ---
 	roomID := r.URL.Query().Get("room")
 	if roomID == "" {
-		response.Error(w, http.StatusBadRequest, "missing room query parameter")
+		reject(http.StatusBadRequest, "missing_room_id")
+		return
+	}
+
+	// --- Look up the room role ---
+	roomRole, err := hub.roomRole(r.Context(), roomID, claims.UserID, claims.Role)
+	if err != nil {
+		if errors.Is(err, errNotInvited) {
+			reject(http.StatusForbidden, "not_a_room_member")
+			return
+		}
+		log.Printf("ws: room role lookup failed (user=%s, room=%s): %v", claims.Username, roomID, err)
+		reject(http.StatusInternalServerError, "failed_to_check_membership")
+		return
+	}
+
+	// --- Look up the room's sync policy, screens and saved playback ---
+	stored, err := hub.storedRoom(r.Context(), roomID)
+	if err != nil {
+		// Not worth refusing the connection: the server default applies.
+		log.Printf("ws: sync policy lookup failed (room=%s): %v", roomID, err)
+	}
+	var syncPolicy *models.SyncPolicy
+	var screens []models.Screen
+	var savedVideo *models.VideoState
+	if stored != nil {
+		syncPolicy, screens, savedVideo = stored.Sync, stored.Screens, &stored.VideoState
+	}
+
+	// --- Look up the announcements the user dismissed ---
+	dismissed, err := hub.dismissedAnnouncements(r.Context(), claims.UserID)
+	if err != nil {
+		// Not worth refusing the connection: they are shown again.
+		log.Printf("ws: dismissed announcements lookup failed (user=%s): %v", claims.Username, err)
+	}
+
+	// --- Resume a rotated-out connection (optional) ---
+	resumed := false
+	if token := r.URL.Query().Get("resume"); token != "" {
+		if err := verifyResumeToken(hub.opts.ResumeSecret, token, claims.UserID, roomID, hub.now()); err != nil {
+			log.Printf("ws: ignoring resume token (user=%s): %v", claims.Username, err)
+		} else {
+			resumed = true
+		}
+	}
+
+	// --- Negotiate the wire format ---
+	protocol, ok := negotiateProtocol(r)
+	if !ok {
+		reject(http.StatusBadRequest, "unsupported_subprotocol", strings.Join(SupportedProtocols, ", "))
 		return
 	}
 
 	// --- Upgrade to WebSocket ---
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := hub.upgrader.Upgrade(w, r, protocol)
 	if err != nil {
+		hub.limiter.release(ip)
 		log.Printf("ws: upgrade error: %v", err)
 		return
 	}
 
 	client := &Client{
-		hub:      hub,
-		conn:     conn,
-		Send:     make(chan []byte, 256),
-		UserID:   claims.UserID,
-		Username: claims.Username,
-		RoomID:   roomID,
+		hub:        hub,
+		conn:       conn,
+		Send:       make(chan []byte, hub.opts.SendBufferSize),
+		UserID:     claims.UserID,
+		Username:   claims.Username,
+		Role:       claims.Role,
+		TrustLevel: claims.TrustLevel,
+		RoomID:     roomID,
+		RoomRole:   roomRole,
+		Protocol:   protocol,
+		ip:         ip,
+		resumed:    resumed,
+		syncPolicy: syncPolicy,
+		screens:    screens,
+		savedVideo: savedVideo,
+		dismissed:  dismissed,
+
+		sessionID:       hub.opts.IDs.New(),
+		analyticsOptOut: r.URL.Query().Get("analytics") == "off",
+
+		connectedAt: hub.now(),
+		wake:        make(chan struct{}, 1),
 	}
 
-	client.hub.Register <- client
+	if claims.ExpiresAt != nil {
+		client.tokenExpiry = claims.ExpiresAt.Time
+	}
+
+	select {
+	case client.hub.Register <- client:
+	case <-client.hub.done:
+		conn.SetWriteDeadline(time.Now().Add(hub.opts.WriteWait))
+		conn.WriteClose(CloseServerShutdown, CloseReason(CloseServerShutdown))
+		conn.Close()
+		hub.limiter.release(ip)
+		return
+	}
 
 	go client.writePump()
 	go client.readPump()
 }
 
+// rejectUpgrade answers an upgrade request that won't become a connection
+// with an error code (see package i18n), in the language of its
+// Accept-Language header.
+func rejectUpgrade(w http.ResponseWriter, r *http.Request, status int, code string, args ...any) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	response.ErrorCode(w, status, code, i18n.T(lang, code, args...))
+}
+
 // readPump reads messages from the WebSocket and forwards them to the Hub.
 // One readPump goroutine per connection — guarantees single reader.
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.Unregister <- c
+		select {
+		case c.hub.Unregister <- c:
+		case <-c.hub.done:
+		}
 		c.conn.Close()
+		c.hub.limiter.release(c.ip)
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
-		return nil
+	c.conn.SetReadLimit(c.hub.opts.MaxMessageSize)
+	c.seen()
+	c.conn.SetPongHandler(func() {
+		c.seen()
+		c.probeAnswered()
 	})
+	c.conn.SetPingHandler(c.seen)
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		message, err := c.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var ce *CloseError
+			if errors.As(err, &ce) && ce.Code != closeGoingAway && ce.Code != closeAbnormal {
 				log.Printf("ws: read error (user=%s): %v", c.Username, err)
 			}
 			break
 		}
-		c.hub.Broadcast <- message
+		c.seen()
+		select {
+		case c.hub.Broadcast <- Inbound{Client: c, Data: message}:
+		case <-c.hub.done:
+			return
+		}
 	}
 }
 
 // writePump writes messages from the Hub to the WebSocket.
 // One writePump goroutine per connection — guarantees single writer.
+//
+// Messages already queued when a write starts are coalesced into frames
+// according to the Hub's BatchMode (see batch.go).
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.hub.opts.PingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.writers.Done()
 	}()
 
+	b := &batcher{mode: c.hub.opts.BatchMode, maxBytes: c.hub.opts.BatchMaxBytes}
+
+	// Probe once right away, so the round-trip time is known well before
+	// the first PingPeriod has passed (see latency.go).
+	if err := c.writeKeepalive(); err != nil {
+		return
+	}
+
 	for {
 		select {
 		case message, ok := <-c.Send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.writeClose()
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
+			b.reset()
+			b.add(message)
 
-			// Batch queued messages for efficiency
+			// Coalesce whatever is already queued. Non-blocking receive:
+			// under PolicyDropOldest the Hub may take from Send concurrently.
 			n := len(c.Send)
+		batch:
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
+				select {
+				case queued, ok := <-c.Send:
+					if !ok {
+						break batch
+					}
+					if !b.fits(queued) {
+						if !c.writeFrame(b) {
+							return
+						}
+						b.reset()
+					}
+					b.add(queued)
+				default:
+					break batch
+				}
 			}
 
-			if err := w.Close(); err != nil {
+			if !c.writeFrame(b) {
+				return
+			}
+
+		case <-c.wake:
+			// Buffer policy: flush the Send backlog, then the overflow queue.
+			messages, closed := c.drainOverflow()
+			b.reset()
+			for _, message := range messages {
+				if !b.fits(message) {
+					if !c.writeFrame(b) {
+						return
+					}
+					b.reset()
+				}
+				b.add(message)
+			}
+			if !b.empty() && !c.writeFrame(b) {
+				return
+			}
+			if closed {
+				c.writeClose()
 				return
 			}
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.writeKeepalive(); err != nil {
 				return
 			}
 		}
 	}
 }
+
+// writeFrame sends the batcher's current frame. It returns false on error.
+func (c *Client) writeFrame(b *batcher) bool {
+	c.conn.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+	return c.conn.WriteText(b.frame()) == nil
+}
+
+// writeClose sends the close frame chosen by the Hub, if any.
+func (c *Client) writeClose() {
+	c.conn.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+	c.conn.WriteClose(c.closeCode, c.closeReason)
+}
@@ -0,0 +1,64 @@
+package ws
+
+// Application close codes (RFC 6455 reserves 4000-4999 for applications).
+//
+// Every server-initiated close uses one of these codes or a standard code
+// below, with the matching reason, so frontends can show an accurate message
+// and decide whether to reconnect. Mirrored in frontend/src/types/closeCodes.ts.
+const (
+	// CloseIdleTimeout: no application messages for WS_IDLE_TIMEOUT_MS.
+	// Reconnect on the next user interaction.
+	CloseIdleTimeout = 4000
+
+	// CloseMaxLifetime: connection rotated at WS_MAX_LIFETIME_MS.
+	// Reconnect immediately with the resume token.
+	CloseMaxLifetime = 4001
+
+	// CloseAuthExpired: the JWT used to connect has expired.
+	// Re-authenticate before reconnecting.
+	CloseAuthExpired = 4002
+
+	// CloseKicked: removed from the room by a moderator. Reconnecting is allowed.
+	CloseKicked = 4003
+
+	// CloseBanned: banned from the room or instance. Do not reconnect.
+	CloseBanned = 4004
+
+	// ClosePolicyViolation: repeatedly sent oversized, malformed or
+	// rate-limited messages. Reconnect after a back-off.
+	ClosePolicyViolation = 4005
+
+	// CloseReplaced: a newer session for the same user took over. Do not reconnect.
+	CloseReplaced = 4006
+
+	// CloseServerRestart: the server handed the connection over to a new
+	// process (see Hub.Drain). Reconnect immediately with the resume token.
+	CloseServerRestart = 4007
+
+	// CloseServerShutdown: the server is shutting down or restarting
+	// (standard 1001 Going Away). Reconnect after a back-off.
+	CloseServerShutdown = closeGoingAway
+
+	// CloseSlowClient: the client could not keep up with its send queue
+	// (standard 1013 Try Again Later). Reconnect after a back-off.
+	CloseSlowClient = closeTryAgainLater
+)
+
+// closeReasons holds the reason text sent with each code.
+var closeReasons = map[int]string{
+	CloseIdleTimeout:     "idle timeout",
+	CloseMaxLifetime:     "connection max age",
+	CloseAuthExpired:     "auth expired",
+	CloseKicked:          "kicked",
+	CloseBanned:          "banned",
+	ClosePolicyViolation: "policy violation",
+	CloseReplaced:        "replaced by newer session",
+	CloseServerRestart:   "server restart",
+	CloseServerShutdown:  "server shutdown",
+	CloseSlowClient:      "slow client",
+}
+
+// CloseReason returns the standard reason text for code.
+func CloseReason(code int) string {
+	return closeReasons[code]
+}
@@ -0,0 +1,123 @@
+package ws
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Conn is the transport a Client reads from and writes to. It hides the
+// WebSocket library so the backend can be chosen by config.
+//
+// Implementations must allow one concurrent reader and one concurrent writer
+// (readPump and writePump), and handle control frames internally.
+type Conn interface {
+	// ReadMessage blocks until the next complete data message arrives.
+	// A close frame from the peer is returned as a *CloseError.
+	ReadMessage() ([]byte, error)
+
+	// WriteText sends a single text frame.
+	WriteText(data []byte) error
+
+	// WritePing sends a ping control frame.
+	WritePing() error
+
+	// WriteClose sends a close frame. A zero code sends an empty close payload.
+	WriteClose(code int, reason string) error
+
+	SetReadLimit(limit int64)
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+
+	// SetPongHandler registers a callback invoked for every pong received.
+	SetPongHandler(fn func())
+
+	// SetPingHandler registers a callback invoked for every ping received.
+	// The implementation still answers each ping with a pong.
+	SetPingHandler(fn func())
+
+	Close() error
+}
+
+// Upgrader turns an HTTP request into a Conn.
+type Upgrader interface {
+	// Upgrade completes the handshake, echoing protocol in the
+	// Sec-WebSocket-Protocol response header unless it is empty.
+	Upgrade(w http.ResponseWriter, r *http.Request, protocol string) (Conn, error)
+}
+
+// CloseError is returned by Conn.ReadMessage when the peer closes the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("ws: closed (code=%d, reason=%q)", e.Code, e.Text)
+}
+
+// Standard close codes used by the backends (RFC 6455 §7.4.1).
+const (
+	closeGoingAway       = 1001
+	closeAbnormal        = 1006
+	closeMessageTooBig   = 1009
+	closeTryAgainLater   = 1013
+	closeNoStatusPresent = 1005
+)
+
+// Backend names a Conn implementation.
+type Backend string
+
+// Supported WebSocket backends.
+const (
+	// BackendGorilla uses gorilla/websocket (default).
+	BackendGorilla Backend = "gorilla"
+
+	// BackendGobwas uses gobwas/ws, which upgrades without allocating
+	// per-connection read/write buffers — cheaper for large, read-mostly audiences.
+	BackendGobwas Backend = "gobwas"
+)
+
+// ParseBackend validates a backend name from config.
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(s); b {
+	case BackendGorilla, BackendGobwas:
+		return b, nil
+	default:
+		return "", fmt.Errorf("ws: unknown backend %q", s)
+	}
+}
+
+// newUpgrader returns the Upgrader for the configured backend.
+func newUpgrader(opts Options) Upgrader {
+	if opts.Backend == BackendGobwas {
+		return &gobwasUpgrader{}
+	}
+	return newGorillaUpgrader(opts.ReadBufferSize, opts.WriteBufferSize)
+}
+
+// originAllowed reports whether the upgrade request's Origin may connect.
+// It runs before the upgrade so every backend gets the same check.
+//
+// Requests without an Origin header (non-browser clients) and same-origin
+// requests are always allowed; cross-site WebSocket hijacking needs a browser,
+// and browsers always send Origin.
+func (h *Hub) originAllowed(r *http.Request) bool {
+	if h.opts.AllowAnyOrigin {
+		return true
+	}
+
+	requestOrigin := r.Header.Get("Origin")
+	if requestOrigin == "" {
+		return true
+	}
+	if u, err := url.Parse(requestOrigin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if h.opts.Origins != nil && h.opts.Origins.Allowed(requestOrigin) {
+		return true
+	}
+	return h.opts.RuntimeOrigins != nil && h.opts.RuntimeOrigins.Allowed(requestOrigin)
+}
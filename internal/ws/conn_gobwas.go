@@ -0,0 +1,128 @@
+package ws
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gobwas/ws"
+)
+
+// gobwasUpgrader upgrades connections with gobwas/ws. The handshake is done
+// on the hijacked connection without allocating gorilla-style per-connection
+// read/write buffers, and frames are read straight off the socket.
+type gobwasUpgrader struct {
+	upgrader ws.HTTPUpgrader
+}
+
+// Upgrade implements Upgrader.
+func (u *gobwasUpgrader) Upgrade(w http.ResponseWriter, r *http.Request, protocol string) (Conn, error) {
+	upgrader := u.upgrader
+	if protocol != "" {
+		upgrader.Protocol = func(p string) bool { return p == protocol }
+	}
+
+	conn, rw, _, err := upgrader.Upgrade(r, w)
+	if err != nil {
+		return nil, err
+	}
+	return &gobwasConn{conn: conn, r: rw.Reader}, nil
+}
+
+// gobwasConn implements Conn on top of a raw net.Conn using gobwas/ws framing.
+type gobwasConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// wmu serializes writes: the reader answers pings and closes itself.
+	wmu sync.Mutex
+
+	readLimit int64
+	onPong    func()
+	onPing    func()
+}
+
+// ReadMessage reads frames until a complete data message is assembled,
+// answering control frames along the way.
+func (c *gobwasConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		h, err := ws.ReadHeader(c.r)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.readLimit > 0 && int64(len(message))+h.Length > c.readLimit {
+			c.WriteClose(closeMessageTooBig, "message too big")
+			return nil, &CloseError{Code: closeMessageTooBig, Text: "message too big"}
+		}
+
+		payload := make([]byte, h.Length)
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			return nil, err
+		}
+		if h.Masked {
+			ws.Cipher(payload, h.Mask, 0)
+		}
+
+		switch h.OpCode {
+		case ws.OpPing:
+			if c.onPing != nil {
+				c.onPing()
+			}
+			if err := c.writeFrame(ws.NewPongFrame(payload)); err != nil {
+				return nil, err
+			}
+
+		case ws.OpPong:
+			if c.onPong != nil {
+				c.onPong()
+			}
+
+		case ws.OpClose:
+			code, reason := ws.ParseCloseFrameData(payload)
+			if code == 0 {
+				code = closeNoStatusPresent
+			}
+			c.writeFrame(ws.NewCloseFrame(ws.NewCloseFrameBody(code, "")))
+			return nil, &CloseError{Code: int(code), Text: reason}
+
+		case ws.OpText, ws.OpBinary, ws.OpContinuation:
+			message = append(message, payload...)
+			if h.Fin {
+				return message, nil
+			}
+		}
+	}
+}
+
+func (c *gobwasConn) WriteText(data []byte) error {
+	return c.writeFrame(ws.NewTextFrame(data))
+}
+
+func (c *gobwasConn) WritePing() error {
+	return c.writeFrame(ws.NewPingFrame(nil))
+}
+
+func (c *gobwasConn) WriteClose(code int, reason string) error {
+	if code == 0 {
+		return c.writeFrame(ws.NewCloseFrame(nil))
+	}
+	return c.writeFrame(ws.NewCloseFrame(ws.NewCloseFrameBody(ws.StatusCode(code), reason)))
+}
+
+func (c *gobwasConn) writeFrame(f ws.Frame) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return ws.WriteFrame(c.conn, f)
+}
+
+func (c *gobwasConn) SetReadLimit(limit int64)           { c.readLimit = limit }
+func (c *gobwasConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *gobwasConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+func (c *gobwasConn) SetPongHandler(fn func())           { c.onPong = fn }
+func (c *gobwasConn) SetPingHandler(fn func())           { c.onPing = fn }
+func (c *gobwasConn) Close() error                       { return c.conn.Close() }
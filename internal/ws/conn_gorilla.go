@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// gorillaUpgrader handles the HTTP → WebSocket protocol upgrade via gorilla/websocket.
+type gorillaUpgrader struct {
+	upgrader websocket.Upgrader
+}
+
+// newGorillaUpgrader creates the default upgrader.
+// CheckOrigin is permissive because ServeWs has already run Hub.originAllowed.
+func newGorillaUpgrader(readBufferSize, writeBufferSize int) *gorillaUpgrader {
+	return &gorillaUpgrader{
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  readBufferSize,
+			WriteBufferSize: writeBufferSize,
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+	}
+}
+
+// Upgrade implements Upgrader.
+func (u *gorillaUpgrader) Upgrade(w http.ResponseWriter, r *http.Request, protocol string) (Conn, error) {
+	upgrader := u.upgrader
+	if protocol != "" {
+		upgrader.Subprotocols = []string{protocol}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &gorillaConn{conn: conn}, nil
+}
+
+// gorillaConn adapts *websocket.Conn to Conn.
+type gorillaConn struct {
+	conn *websocket.Conn
+}
+
+func (c *gorillaConn) ReadMessage() ([]byte, error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		var ce *websocket.CloseError
+		if errors.As(err, &ce) {
+			return nil, &CloseError{Code: ce.Code, Text: ce.Text}
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *gorillaConn) WriteText(data []byte) error {
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *gorillaConn) WritePing() error {
+	return c.conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (c *gorillaConn) WriteClose(code int, reason string) error {
+	if code == 0 {
+		return c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+	}
+	return c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
+
+func (c *gorillaConn) SetReadLimit(limit int64)           { c.conn.SetReadLimit(limit) }
+func (c *gorillaConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *gorillaConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+func (c *gorillaConn) Close() error                       { return c.conn.Close() }
+
+func (c *gorillaConn) SetPongHandler(fn func()) {
+	c.conn.SetPongHandler(func(string) error {
+		fn()
+		return nil
+	})
+}
+
+func (c *gorillaConn) SetPingHandler(fn func()) {
+	c.conn.SetPingHandler(func(appData string) error {
+		fn()
+		// Same reply as gorilla's default ping handler.
+		err := c.conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(time.Second))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			return nil
+		}
+		return err
+	})
+}
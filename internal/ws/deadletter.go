@@ -0,0 +1,117 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// deadLetterCodes are the rejections that make a message a dead letter:
+// the client sent something the Hub could not make sense of or deliver,
+// as opposed to something it was not allowed or too quick to send.
+var deadLetterCodes = map[string]bool{
+	models.WSErrInvalidMessage: true,
+	models.WSErrUnknownType:    true,
+	models.WSErrUnknownTarget:  true,
+}
+
+// deadLetterBuffer keeps the most recent dead letters in a ring. It is
+// written on the Hub goroutine and read by admin requests.
+type deadLetterBuffer struct {
+	mu      sync.Mutex
+	letters []*models.DeadLetter
+	next    int // index the next letter goes to
+	full    bool
+}
+
+func newDeadLetterBuffer(size int) *deadLetterBuffer {
+	return &deadLetterBuffer{letters: make([]*models.DeadLetter, size)}
+}
+
+// add stores l, dropping the oldest letter if the buffer is full.
+func (b *deadLetterBuffer) add(l *models.DeadLetter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.letters[b.next] = l
+	b.next = (b.next + 1) % len(b.letters)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// list returns the letters matching filter, newest first.
+func (b *deadLetterBuffer) list(filter repository.DeadLetterFilter) []*models.DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := b.next
+	if b.full {
+		n = len(b.letters)
+	}
+	var letters []*models.DeadLetter
+	skip := filter.Offset
+	for i := 1; i <= n && len(letters) < filter.Limit; i++ {
+		l := b.letters[(b.next-i+len(b.letters))%len(b.letters)]
+		if !filter.Matches(l) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		letters = append(letters, l)
+	}
+	return letters
+}
+
+// DeadLetters returns the recent messages this Hub could not route,
+// newest first, at most Options.DeadLetterBuffer of them. Safe to call
+// from any goroutine.
+func (h *Hub) DeadLetters(filter repository.DeadLetterFilter) []*models.DeadLetter {
+	if h.deadLetters == nil {
+		return nil
+	}
+	return h.deadLetters.list(filter)
+}
+
+// recordDeadLetter keeps raw, the message client sent, as a dead letter
+// if code makes it one. msg is nil if raw did not parse.
+func (h *Hub) recordDeadLetter(client *Client, raw []byte, msg *models.Message, code, reason string) {
+	if h.deadLetters == nil || !deadLetterCodes[code] {
+		return
+	}
+
+	letter := &models.DeadLetter{
+		ID:        h.opts.IDs.New(),
+		UserID:    client.UserID,
+		Username:  client.Username,
+		RoomID:    client.RoomID,
+		Code:      code,
+		Reason:    reason,
+		CreatedAt: h.now(),
+	}
+	if msg != nil {
+		letter.Type, letter.MessageID = msg.Type, msg.ID
+	}
+	if len(raw) > models.DeadLetterDataLimit {
+		raw, letter.Truncated = raw[:models.DeadLetterDataLimit], true
+	}
+	// Stores may reject invalid UTF-8 and NUL bytes, which bad clients send.
+	letter.Data = strings.ReplaceAll(strings.ToValidUTF8(string(raw), "�"), "\x00", "�")
+
+	h.deadLetters.add(letter)
+	h.metrics.deadLetters.Inc()
+
+	if h.opts.DeadLetterRepo != nil {
+		// Fire and forget -- don't block the broadcast loop.
+		go func() {
+			if err := h.opts.DeadLetterRepo.Create(context.Background(), letter); err != nil {
+				log.Printf("ws: failed to persist dead letter: %v", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,109 @@
+package ws
+
+import (
+	"time"
+)
+
+// drainTick is how often a draining Hub hands over the rooms that are due.
+const drainTick = 100 * time.Millisecond
+
+// drainRequest is a pending Drain call.
+type drainRequest struct {
+	window time.Duration
+	done   chan struct{}
+}
+
+// drain is the state of a Drain in progress.
+type drain struct {
+	due  map[string]time.Time // when each room not yet handed over is
+	done chan struct{}        // closed once due is empty
+}
+
+// Drain hands every client over to the process taking over the listener
+// (see the handoff package), spreading rooms over window so they don't all
+// reconnect at once. A room's clients are sent a reconnect hint with a
+// resume token, then closed together with CloseServerRestart, its playback
+// saved just before so the new process picks it up where it is; others in
+// the room never see them leave. Clients connecting meanwhile are handed
+// over too. Drain returns once every room has been; call Stop afterwards.
+// With window 0 it returns at once, leaving Stop to close everyone with
+// CloseServerShutdown.
+func (h *Hub) Drain(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	req := drainRequest{window: window, done: make(chan struct{})}
+	select {
+	case h.drains <- req:
+	case <-h.done:
+		return
+	}
+	select {
+	case <-req.done:
+	case <-h.done:
+	}
+}
+
+// startDrain schedules the handover of every room for a Drain call and
+// returns the ticker to hand them over on.
+func (h *Hub) startDrain(req drainRequest) (<-chan time.Time, func()) {
+	if h.draining != nil {
+		// Already draining: that Drain's schedule stands.
+		close(req.done)
+		return nil, func() {}
+	}
+	now := h.now()
+	h.draining = &drain{due: make(map[string]time.Time, len(h.clients)), done: req.done}
+	i, n := 0, len(h.clients)
+	for room, roomClients := range h.clients {
+		i++
+		at := now.Add(req.window * time.Duration(i) / time.Duration(n))
+		h.draining.due[room] = at
+		for client := range roomClients {
+			h.sendReconnectHint(client, at.Sub(now))
+		}
+	}
+
+	t := time.NewTicker(drainTick)
+	return t.C, t.Stop
+}
+
+// drainClient schedules a client that connected during a Drain: it goes
+// with its room, or at once if the room has gone already.
+func (h *Hub) drainClient(client *Client) {
+	now := h.now()
+	at, ok := h.draining.due[client.RoomID]
+	if !ok {
+		at = now
+		h.draining.due[client.RoomID] = at
+	}
+	h.sendReconnectHint(client, max(at.Sub(now), 0))
+}
+
+// handOffDue hands over the rooms whose turn has come.
+func (h *Hub) handOffDue() {
+	now := h.now()
+	for room, at := range h.draining.due {
+		if now.Before(at) {
+			continue
+		}
+		delete(h.draining.due, room)
+		h.handOffRoom(room)
+	}
+	if len(h.draining.due) == 0 && h.draining.done != nil {
+		close(h.draining.done)
+		h.draining.done = nil
+	}
+}
+
+// handOffRoom closes every client in room with CloseServerRestart, as
+// rotated-out clients so nobody is told they left, then forgets the room's
+// playback so Stop doesn't save it over the new process's.
+func (h *Hub) handOffRoom(room string) {
+	h.saveVideoState(room)
+	for client := range h.clients[room] {
+		client.rotating = true
+		h.closeClient(client, CloseServerRestart)
+	}
+	h.dropVideoState(room)
+}
@@ -0,0 +1,262 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// The Hub keeps each room's playback as the last video_sync it relayed and
+// when: that is where every player should be. Players report where they
+// are in "position" messages, and those that drifted too far are brought
+// back: past the room's tolerance with a seek (a video_sync to them
+// alone), short of it with a "sync_rate" asking them to play slightly
+// faster or slower until they are back, which is smoother than the
+// constant small seeks that jarred viewers before.
+
+const (
+	// nudgeWindow is how long a nudge should take to make up the drift.
+	nudgeWindow = 8 * time.Second
+
+	// maxNudge caps how far a nudged rate strays from 1.
+	maxNudge = 0.05
+
+	// seekGrace is how long position reports are ignored after a seek,
+	// while the player buffers at its new position.
+	seekGrace = 3 * time.Second
+
+	// syncPolicyQueueSize bounds the policy changes waiting for the Hub goroutine.
+	syncPolicyQueueSize = 64
+)
+
+// syncState is the playback of a room's screen as of its last video_sync.
+type syncState struct {
+	payload    models.VideoSyncPayload
+	at         time.Time
+	autoPaused bool // paused by the Hub, see autopause.go
+}
+
+// position returns where the room's video is at now, in seconds.
+func (s syncState) position(now time.Time) float64 {
+	if !s.payload.Playing {
+		return s.payload.Timestamp
+	}
+	return s.payload.Timestamp + now.Sub(s.at).Seconds()
+}
+
+// currentVideoState returns the last video_sync of a room's screen for a
+// client that just joined, its position moved forward to where the screen
+// is now and its timestamp to now, or nil if there is none. Without this
+// late joiners would start at the position of the last play, pause or
+// seek, however long ago. Fields of the payload the server doesn't know
+// are kept as they were.
+func (h *Hub) currentVideoState(room, screen string) []byte {
+	raw, ok := h.lastVideoState[room][screen]
+	if !ok {
+		return nil
+	}
+	state, ok := h.syncStates[room][screen]
+	if !ok || !state.payload.Playing {
+		return raw
+	}
+
+	var msg models.Message
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return raw
+	}
+	if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+		return raw
+	}
+	now := h.now()
+	payload["timestamp"], _ = json.Marshal(state.position(now))
+	data, _ := json.Marshal(payload)
+	msg.Payload = string(data)
+	msg.Timestamp = now
+	out, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("ws: failed to re-encode video state for room %s: %v", screenLabel(room, screen), err)
+		return raw
+	}
+	return out
+}
+
+// syncPolicyChange asks the Hub goroutine to apply a room's new policy.
+type syncPolicyChange struct {
+	roomID string
+	policy *models.SyncPolicy // nil = server default
+}
+
+// storedRoom looks up roomID for a connecting client's sync policy and
+// screens: nil if the room is not stored.
+func (h *Hub) storedRoom(ctx context.Context, roomID string) (*models.Room, error) {
+	if h.opts.Rooms == nil {
+		return nil, nil
+	}
+	room, err := h.opts.Rooms.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return room, nil
+}
+
+// SetRoomSyncPolicy applies a room's changed sync policy to its open
+// connections; call it after storing the change. nil restores the server
+// default. Safe to call from any goroutine; like SetRoomRole, the change
+// is dropped with a log line if the Hub is backed up.
+func (h *Hub) SetRoomSyncPolicy(roomID string, policy *models.SyncPolicy) {
+	select {
+	case h.syncChanges <- syncPolicyChange{roomID: roomID, policy: policy}:
+	default:
+		log.Printf("ws: sync policy queue full, dropping change for room %s", roomID)
+	}
+}
+
+// applySyncPolicy records a room's policy while anyone is connected to it;
+// the next connection looks it up again.
+func (h *Hub) applySyncPolicy(c syncPolicyChange) {
+	if _, ok := h.clients[c.roomID]; !ok {
+		return
+	}
+	if c.policy == nil {
+		delete(h.syncPolicies, c.roomID)
+		return
+	}
+	h.syncPolicies[c.roomID] = *c.policy
+}
+
+// syncPolicy returns the policy in force in room.
+func (h *Hub) syncPolicy(room string) models.SyncPolicy {
+	if p, ok := h.syncPolicies[room]; ok {
+		return p
+	}
+	return models.SyncPolicy{
+		ToleranceMs:       int(h.opts.SyncTolerance / time.Millisecond),
+		NudgeMs:           int(h.opts.SyncNudge / time.Millisecond),
+		PauseOnDisconnect: h.opts.PauseOnDisconnect,
+	}
+}
+
+// handlePosition compares a player's reported position with its screen's
+// and corrects it if it drifted further than the room's policy allows.
+func (h *Hub) handlePosition(ctx *Context) {
+	var p models.PositionPayload
+	if err := json.Unmarshal([]byte(ctx.Message.Payload), &p); err != nil || p.Position < 0 {
+		ctx.Reject(models.WSErrInvalidMessage, `position payload must be {"position": seconds}`)
+		return
+	}
+
+	state, ok := h.syncStates[ctx.Room][p.Screen]
+	policy := h.syncPolicy(ctx.Room)
+	client := ctx.Client
+	now := h.now()
+	if !ok || policy.ToleranceMs <= 0 || now.Sub(client.seekedAt[p.Screen]) < seekGrace {
+		return
+	}
+
+	// The report left the player half a round trip ago.
+	position := p.Position
+	if state.payload.Playing {
+		position += (client.RTT() / 2).Seconds()
+	}
+	drift := time.Duration((position - state.position(now)) * float64(time.Second))
+	abs := max(drift, -drift)
+
+	// Nudged players are nudged until they are well inside the threshold,
+	// so they don't hover on its edge.
+	nudge := time.Duration(policy.NudgeMs) * time.Millisecond
+	rate := client.syncRate[p.Screen]
+	if rate != 0 {
+		nudge /= 2
+	}
+
+	switch {
+	case abs > time.Duration(policy.ToleranceMs)*time.Millisecond:
+		h.sendSeek(client, state, now)
+	case policy.NudgeMs > 0 && abs > nudge:
+		if next := nudgeRate(drift); next != rate {
+			h.sendSyncRate(client, p.Screen, next, drift)
+		}
+	case rate != 0:
+		h.sendSyncRate(client, p.Screen, 1, drift)
+	}
+}
+
+// nudgeRate returns the playback rate that makes up drift over
+// nudgeWindow, within maxNudge of 1 and rounded to a hundredth (but never
+// 1). Players ahead (positive drift) slow down.
+func nudgeRate(drift time.Duration) float64 {
+	change := -drift.Seconds() / nudgeWindow.Seconds()
+	change = math.Round(max(-maxNudge, min(maxNudge, change))*100) / 100
+	if change == 0 {
+		change = math.Copysign(0.01, -drift.Seconds())
+	}
+	return 1 + change
+}
+
+// sendSeek sends client a video_sync seek to where the screen of state
+// is, and returns it to the normal rate there.
+func (h *Hub) sendSeek(client *Client, state syncState, now time.Time) {
+	payload := state.payload
+	payload.Event = models.VideoEventSeek
+	payload.Timestamp = state.position(now)
+	if payload.Playing {
+		payload.Timestamp += (client.RTT() / 2).Seconds()
+	}
+	payload.TriggeredBy = "system"
+	if !h.sendSyncMessage(client, models.MsgTypeVideoSync, payload) {
+		return
+	}
+	if client.seekedAt == nil {
+		client.seekedAt = make(map[string]time.Time)
+	}
+	client.seekedAt[payload.Screen] = now
+	delete(client.syncRate, payload.Screen)
+}
+
+// sendSyncRate sends client a "sync_rate" message for screen; rate 1 ends
+// a nudge.
+func (h *Hub) sendSyncRate(client *Client, screen string, rate float64, drift time.Duration) {
+	payload := models.SyncRatePayload{Rate: rate, DriftMs: int(drift.Milliseconds()), Screen: screen}
+	if !h.sendSyncMessage(client, models.MsgTypeSyncRate, payload) {
+		return
+	}
+	if rate == 1 {
+		delete(client.syncRate, screen)
+		return
+	}
+	if client.syncRate == nil {
+		client.syncRate = make(map[string]float64)
+	}
+	client.syncRate[screen] = rate
+}
+
+// sendSyncMessage sends client a system message of msgType carrying
+// payload. It reports false if it could not, disconnecting slow clients.
+func (h *Hub) sendSyncMessage(client *Client, msgType string, payload any) bool {
+	data, _ := json.Marshal(payload)
+	msg, err := json.Marshal(models.Message{
+		Type:      msgType,
+		Sender:    "system",
+		Payload:   string(data),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal %s message: %v", msgType, err)
+		return false
+	}
+	if !h.send(client, msg) {
+		h.disconnectSlowClient(client)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+
+	"ofenes/internal/models"
+)
+
+// Handle registers fn for messages of msgType, replacing any existing
+// handler. Messages with no registered handler are rejected with an
+// unknown_type error. Handlers run on the Hub goroutine and must not block.
+// Register handlers before calling Run.
+func (h *Hub) Handle(msgType string, fn Handler) {
+	h.handlers[msgType] = fn
+}
+
+// Broadcast sends the message (ctx.Raw) to everyone in the sender's room.
+func (ctx *Context) Broadcast() {
+	ctx.Hub.broadcastToRoom(ctx.Room, ctx.Raw)
+}
+
+// SendTo sends data to a single user by username. It reports false if the
+// user is not connected.
+func (ctx *Context) SendTo(username string, data []byte) bool {
+	return ctx.Hub.sendToUser(username, data)
+}
+
+// dispatch hands a message that passed the pre-route hooks to its handler.
+func (h *Hub) dispatch(ctx *Context) {
+	handler, ok := h.handlers[ctx.Message.Type]
+	if !ok {
+		log.Printf("ws: unknown message type (user=%s, type=%q)", ctx.Client.Username, ctx.Message.Type)
+		if h.strike(ctx.Client) {
+			ctx.Reject(models.WSErrUnknownType, "unknown message type")
+		}
+		return
+	}
+	handler(ctx)
+}
+
+// registerBuiltinHandlers installs the core message types.
+func (h *Hub) registerBuiltinHandlers() {
+	h.Handle(models.MsgTypeChat, h.handleChat)
+	h.Handle(models.MsgTypeVideoSync, h.handleVideoSync)
+	h.Handle(models.MsgTypeWebRTC, h.handleWebRTC)
+	h.Handle(models.MsgTypeAdmin, h.handleAdmin)
+	h.Handle(models.MsgTypeCoHost, h.handleCoHost)
+	h.Handle(models.MsgTypePosition, h.handlePosition)
+	if h.opts.QualityMode != QualityOff {
+		h.Handle(models.MsgTypePlaybackStats, h.handlePlaybackStats)
+	}
+	h.Handle(models.MsgTypeActivity, func(*Context) {
+		// Keeps the client from being closed as idle (see trackActivity); nothing to route.
+	})
+}
+
+// handleChat persists and broadcasts a chat message. Messages from
+// shadow-banned users only go back to the sender (see SetShadowBanned).
+func (h *Hub) handleChat(ctx *Context) {
+	if h.opts.Stats != nil {
+		h.opts.Stats.MessageSent(ctx.Client.UserID)
+	}
+	if h.sanctions.isShadowBanned(ctx.Client.UserID) {
+		h.echoToSender(ctx)
+		return
+	}
+	h.persistMessage(ctx.Room, ctx.Message)
+	ctx.Broadcast()
+}
+
+// handleVideoSync stores the playback state of the room's screen for late
+// joiners and broadcasts it, if the sender may control that screen. The
+// position of a playing video is first moved forward by the sender's
+// one-way delay (see compensateSync).
+func (h *Hub) handleVideoSync(ctx *Context) {
+	var payload models.VideoSyncPayload
+	err := json.Unmarshal([]byte(ctx.Message.Payload), &payload)
+	if err != nil {
+		payload = models.VideoSyncPayload{} // relayed as is, to the main screen
+	}
+	perm, ok := h.screenControl(ctx.Room, payload.Screen)
+	if !ok {
+		ctx.Reject(models.WSErrInvalidMessage, "this room has no screen "+payload.Screen)
+		return
+	}
+	if !h.roomCan(ctx.Client, perm) {
+		ctx.Reject(models.WSErrForbidden, "sending video_sync messages requires the "+perm+" room permission")
+		return
+	}
+
+	prev, had := h.syncStates[ctx.Room][payload.Screen]
+	changed := false
+	if err == nil {
+		compensated := compensateSync(ctx.Client, &payload)
+		if kept := keepNowPlaying(prev, had, &payload); compensated || kept {
+			msg := ctx.Message
+			data, _ := json.Marshal(payload)
+			msg.Payload = string(data)
+			if err := ctx.SetMessage(msg); err != nil {
+				log.Printf("ws: failed to re-encode video_sync (user=%s): %v", ctx.Client.Username, err)
+			}
+		}
+		// Analytics and the HLS proxy follow the main screen.
+		if h.opts.Analytics != nil && payload.Screen == "" {
+			h.opts.Analytics.VideoSync(ctx.Room, payload)
+		}
+		if h.opts.VideoSources != nil && payload.Screen == "" {
+			h.opts.VideoSources.VideoSync(ctx.Room, payload)
+		}
+	}
+	// A new state is not auto-paused: whoever controls playback takes over.
+	h.setVideoState(ctx.Room, payload.Screen, syncState{payload: payload, at: ctx.Message.Timestamp}, ctx.Raw)
+	if err != nil {
+		delete(h.syncStates[ctx.Room], "") // nothing to extrapolate or correct from
+	} else if payload.Screen == "" {
+		h.saveVideoState(ctx.Room)
+		changed = nowPlayingChanged(prev, had, payload)
+	}
+	ctx.Broadcast()
+	if changed {
+		h.announceNowPlaying(ctx.Room)
+	}
+}
+
+// roomEmptied saves and drops the playback state of a room nobody is
+// left in, or keeps it paused if the room's policy says so (see
+// holdPaused), and forgets the room's sync policy.
+func (h *Hub) roomEmptied(room string) {
+	if !h.holdPaused(room) {
+		h.saveVideoState(room)
+		h.dropVideoState(room)
+	}
+	delete(h.syncPolicies, room)
+	delete(h.screens, room)
+}
+
+// dropVideoState forgets the playback state of a room nobody is left in.
+func (h *Hub) dropVideoState(room string) {
+	h.announceStopped(room)
+	delete(h.lastVideoState, room)
+	delete(h.syncStates, room)
+	delete(h.autoPaused, room)
+	if h.opts.VideoSources != nil {
+		h.opts.VideoSources.RoomEmpty(room)
+	}
+}
+
+// handleWebRTC forwards signaling to a specific target user (cross-room).
+// The sender is told if the payload names no target or the target is not
+// connected, or only spectates (see spectator.go), so it can give up on
+// the call instead of waiting.
+func (h *Hub) handleWebRTC(ctx *Context) {
+	var payload struct {
+		Target string `json:"target"`
+	}
+	if err := json.Unmarshal([]byte(ctx.Message.Payload), &payload); err != nil || payload.Target == "" {
+		ctx.Reject(models.WSErrInvalidMessage, `webrtc payload must name a "target" user`)
+		return
+	}
+	target := h.userClient(payload.Target)
+	switch {
+	case target == nil:
+		ctx.Reject(models.WSErrUnknownTarget, "target user is not connected")
+	case target.spectator:
+		ctx.Reject(models.WSErrSpectator, "target user is a spectator")
+	case !h.send(target, ctx.Raw):
+		h.disconnectSlowClient(target)
+	}
+}
+
+// handleAdmin broadcasts admin messages. Only roles with the
+// broadcast.send permission get here (see requirePermission).
+func (h *Hub) handleAdmin(ctx *Context) {
+	ctx.Broadcast()
+}
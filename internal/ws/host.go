@@ -0,0 +1,151 @@
+package ws
+
+import (
+	"fmt"
+
+	"ofenes/internal/models"
+)
+
+// HostFailover decides who stands in as host when a room's owner is not
+// connected, so playback control is not orphaned when the owner's
+// connection drops.
+type HostFailover string
+
+// Supported failover policies.
+const (
+	// FailoverCoHost hands host rights to the co-host connected longest.
+	FailoverCoHost HostFailover = "cohost"
+
+	// FailoverLongestPresent prefers a co-host too, but falls back to the
+	// moderator or member connected longest. Viewers never host.
+	FailoverLongestPresent HostFailover = "longest_present"
+
+	// FailoverOff leaves the room without a host until the owner returns.
+	FailoverOff HostFailover = "off"
+)
+
+// ParseHostFailover validates a failover policy name from config.
+func ParseHostFailover(s string) (HostFailover, error) {
+	switch p := HostFailover(s); p {
+	case FailoverCoHost, FailoverLongestPresent, FailoverOff:
+		return p, nil
+	default:
+		return "", fmt.Errorf("ws: unknown host failover policy %q", s)
+	}
+}
+
+// host is who currently hosts a room with room roles.
+type host struct {
+	userID string // "" = nobody
+	acting bool   // a stand-in for the absent owner
+}
+
+// roomRoleOf returns client's effective room role: its own, or owner while
+// it stands in for the room's absent owner. A stand-in's stored role does
+// not change; it hosts until the owner reconnects.
+func (h *Hub) roomRoleOf(client *Client) string {
+	if hst := h.hosts[client.RoomID]; hst.acting && hst.userID == client.UserID {
+		return models.RoomRoleOwner
+	}
+	return client.RoomRole
+}
+
+// updateHost works out who hosts room now: the owner if connected, else
+// the current stand-in if still connected, else one picked by
+// Options.HostFailover. A change is announced to the room with a
+// "host_changed" system message (empty userId: nobody hosts). Rooms
+// without room roles have no host.
+func (h *Hub) updateHost(room string) {
+	old, tracked := h.hosts[room]
+
+	var owner, stayer, candidate *Client
+	for client := range h.clients[room] {
+		switch {
+		case client.RoomRole == models.RoomRoleOwner:
+			if owner == nil || client.UserID == old.userID {
+				owner = client // keep the current host while ownership moves
+			}
+		case old.acting && client.UserID == old.userID:
+			stayer = client
+		}
+		if h.canStandIn(client) && (candidate == nil || standsInBefore(client, candidate)) {
+			candidate = client
+		}
+	}
+
+	var next host
+	var nextClient *Client
+	switch {
+	case owner != nil:
+		next, nextClient = host{userID: owner.UserID}, owner
+	case stayer != nil && h.canStandIn(stayer):
+		next, nextClient = old, stayer
+	case candidate != nil:
+		next, nextClient = host{userID: candidate.UserID, acting: true}, candidate
+	}
+
+	if nextClient == nil && !tracked {
+		return // no room roles here, or nobody who could host
+	}
+	h.hosts[room] = next
+	if next == old && tracked {
+		return
+	}
+	if nextClient == nil {
+		h.broadcastSystemMessage(room, "host_changed", "", "")
+		return
+	}
+	h.broadcastSystemMessage(room, "host_changed", nextClient.UserID, nextClient.Username)
+}
+
+// canStandIn reports whether client may stand in for an absent owner
+// under Options.HostFailover. Spectators never do.
+func (h *Hub) canStandIn(client *Client) bool {
+	if client.spectator {
+		return false
+	}
+	switch h.opts.HostFailover {
+	case FailoverCoHost:
+		return client.RoomRole == models.RoomRoleCoHost
+	case FailoverLongestPresent:
+		switch client.RoomRole {
+		case models.RoomRoleCoHost, models.RoomRoleModerator, models.RoomRoleMember:
+			return true
+		}
+	}
+	return false
+}
+
+// standsInBefore orders stand-in candidates: co-hosts first, then the
+// connection open longest.
+func standsInBefore(a, b *Client) bool {
+	aCoHost, bCoHost := a.RoomRole == models.RoomRoleCoHost, b.RoomRole == models.RoomRoleCoHost
+	if aCoHost != bCoHost {
+		return aCoHost
+	}
+	return a.connectedAt.Before(b.connectedAt)
+}
+
+// hostJoined updates the host after client connects, if that could change
+// it: the owner returning, or the first possible host of a room.
+func (h *Hub) hostJoined(client *Client) {
+	if client.RoomRole == "" {
+		return
+	}
+	hst, tracked := h.hosts[client.RoomID]
+	if !tracked || hst.userID == "" || (hst.acting && client.RoomRole == models.RoomRoleOwner) {
+		h.updateHost(client.RoomID)
+	}
+}
+
+// hostLeft updates the host after userID's connection to room is gone
+// for good.
+func (h *Hub) hostLeft(room, userID string) {
+	if len(h.clients[room]) == 0 {
+		delete(h.hosts, room)
+		return
+	}
+	if h.hosts[room].userID == userID {
+		h.updateHost(room)
+	}
+}
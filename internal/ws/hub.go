@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"ofenes/internal/authz"
+	"ofenes/internal/clock"
+	"ofenes/internal/idgen"
+	"ofenes/internal/metrics"
 	"ofenes/internal/models"
+	"ofenes/internal/origin"
 	"ofenes/internal/repository"
-
-	"github.com/google/uuid"
 )
 
 // Hub maintains the set of active clients grouped by room and routes messages.
@@ -18,67 +23,785 @@ type Hub struct {
 	// clients maps roomID -> set of clients in that room.
 	clients map[string]map[*Client]bool
 
-	Broadcast  chan []byte
+	Broadcast  chan Inbound
 	Register   chan *Client
 	Unregister chan *Client
 
-	// lastVideoState stores the most recent video sync payload per room.
-	lastVideoState map[string][]byte
+	// stop asks Run to return; done is closed once it has, after which
+	// sends to the channels above must not block (see Stop). writers
+	// counts the writePumps of registered clients, which send the close
+	// frames.
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	writers  sync.WaitGroup
+
+	// notify queues notifications from other goroutines (see notify.go).
+	notify chan notification
+
+	// kick queues disconnect requests; sanctions holds muted and
+	// shadow-banned users (see moderation.go).
+	kick      chan kick
+	sanctions *sanctions
+
+	// roomRoles queues room role changes (see roomrole.go); hosts tracks
+	// who hosts each room with room roles (see host.go).
+	roomRoles chan roomRoleChange
+	hosts     map[string]host
+
+	// suggestions holds the bitrate suggested to each room, in kbit/s
+	// (see quality.go).
+	suggestions map[string]int
+
+	// syncStates holds the playback of each room's screens as of their
+	// last video_sync, syncPolicies the sync policy of rooms that have
+	// one, and syncChanges queues changes to them (see drift.go).
+	syncStates   map[string]map[string]syncState
+	syncPolicies map[string]models.SyncPolicy
+	syncChanges  chan syncPolicyChange
+
+	// autoPaused holds the rooms whose playback the Hub paused on
+	// disconnect, and when (see autopause.go).
+	autoPaused map[string]time.Time
+
+	// screens holds the screens of rooms that have more than the main
+	// one, by ID, and screenChanges queues changes to them (see screen.go).
+	screens       map[string]map[string]models.Screen
+	screenChanges chan screensChange
+
+	// connections queues Connections calls (see latency.go).
+	connections chan connectionsRequest
+
+	// nowPlaying carries NowPlaying calls to the Hub goroutine.
+	nowPlaying chan nowPlayingRequest
+
+	// announcements queues Announce calls (see announce.go).
+	announcements chan announcement
+
+	// banners holds the site-wide announcements that have not ended,
+	// liveBanners the IDs of those in effect, and bannerChanges queues
+	// changes to them (see banner.go).
+	banners       []*models.Announcement
+	liveBanners   []string
+	bannerChanges chan bannerChange
+
+	// seqs holds the last Seq given to a message in each room (see
+	// stampMessage).
+	seqs map[string]int64
+
+	// deadLetters holds the recent messages the Hub could not route (see
+	// deadletter.go); nil if DeadLetterBuffer is 0.
+	deadLetters *deadLetterBuffer
+
+	// wordFilters is the compiled chat filter list, swapped whole on
+	// reload (see wordfilter.go). Nil until the first SetWordFilters.
+	wordFilters atomic.Pointer[WordFilters]
+
+	// lastVideoState stores the most recent video sync message per room
+	// and screen.
+	lastVideoState map[string]map[string][]byte
+
+	// videoSaves queues playback to save (see persist.go).
+	videoSaves chan videoSave
+
+	// present counts each user's connections per room, and presenceWrites
+	// queues their entries in Options.Presence (see presence.go).
+	present           map[string]map[string]int
+	presenceWrites    chan presenceWrite
+	presenceRefreshed time.Time
+
+	// roomShards partitions each room's clients across the shard workers.
+	roomShards map[string][]map[*Client]bool
+	shards     *shardPool
+
+	upgrader Upgrader
+	limiter  *connLimiter
+
+	// pendingLeaves holds departures of rotated-out clients awaiting resume.
+	pendingLeaves map[string]pendingLeave
+
+	// drains queues Drain calls; draining is the one in progress, nil
+	// before (see drain.go).
+	drains   chan drainRequest
+	draining *drain
+
+	// userLists holds each room's user-list changes not sent yet (see userlist.go).
+	userLists map[string]*userListRoom
+
+	// spectators counts each room's spectators, and spectatorChat samples
+	// their chat (see spectator.go).
+	spectators    map[string]int
+	spectatorChat map[string]*tokenBucket
 
 	messageRepo repository.MessageRepository
+
+	// Interceptor chains (see pipeline.go). route and broadcast are the
+	// composed entry points.
+	handlers     map[string]Handler
+	preRoute     []Middleware
+	preBroadcast []BroadcastMiddleware
+	route        Handler
+	broadcast    BroadcastFunc
+
+	opts          Options
+	policyMetrics policyMetrics
+	metrics       hubMetrics
+}
+
+// Options configures Hub behavior. Zero values fall back to safe defaults.
+type Options struct {
+	// WriteWait is the time allowed to write a frame to the peer (default: 10s).
+	WriteWait time.Duration
+
+	// PongWait is the time allowed to read the next pong from the peer (default: 60s).
+	PongWait time.Duration
+
+	// PingPeriod is how often pings are sent; must be less than PongWait
+	// (default: 90% of PongWait).
+	PingPeriod time.Duration
+
+	// KeepaliveMode selects server pings, client pings or JSON heartbeats
+	// (default: server_ping).
+	KeepaliveMode KeepaliveMode
+
+	// MaxMessageSize is the read limit per incoming message in bytes
+	// (default: 64KB — WebRTC SDP offers can be 4-8KB). Larger frames close
+	// the connection with 1009.
+	MaxMessageSize int64
+
+	// PayloadLimits caps the payload size per message type; oversized messages
+	// are rejected with an error reply (default: DefaultPayloadLimits).
+	PayloadLimits map[string]int
+
+	// RateLimits throttles each client per message type; excess messages are
+	// rejected with an error reply (default: DefaultRateLimits).
+	RateLimits map[string]RateLimit
+
+	// SendBufferSize is the capacity of each client's Send channel (default: 256).
+	SendBufferSize int
+
+	// ReadBufferSize and WriteBufferSize size the upgrader's I/O buffers
+	// (gorilla backend only; default: 1024 each).
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// SlowClientPolicy decides what happens when a client's Send buffer is full.
+	SlowClientPolicy SlowClientPolicy
+
+	// OverflowQueueSize caps the per-client overflow queue under PolicyBuffer.
+	OverflowQueueSize int
+
+	// ShardCount is the number of fan-out workers for large rooms (1 disables sharding).
+	ShardCount int
+
+	// ShardThreshold is the room size at which broadcasts are sharded and
+	// user-list updates are throttled.
+	ShardThreshold int
+
+	// IdleTimeout closes clients that send no application messages for this
+	// long (0 disables). Pings and pongs do not count as activity.
+	IdleTimeout time.Duration
+
+	// IdleWarning is how long before IdleTimeout the client is warned (default: 1m).
+	IdleWarning time.Duration
+
+	// MaxLifetime closes connections older than this (0 disables), after
+	// sending a "reconnect" message with a resume token LifetimeNotice earlier.
+	// Useful behind load balancers and to force periodic JWT re-validation.
+	MaxLifetime time.Duration
+
+	// LifetimeNotice is how long before MaxLifetime the reconnect hint is sent (default: 30s).
+	LifetimeNotice time.Duration
+
+	// ResumeSecret signs resume tokens (usually the JWT secret).
+	ResumeSecret string
+
+	// UserListInterval is how long user-list changes are gathered in large
+	// rooms.
+	UserListInterval time.Duration
+
+	// UserListWindow is how long user-list changes are gathered in other
+	// rooms (0 = sent at once).
+	UserListWindow time.Duration
+
+	// UserListResync is how often rooms whose members changed are sent
+	// their full user list again (0 = only joiners get it).
+	UserListResync time.Duration
+
+	// SpectatorThreshold is how many participants a room takes; later
+	// joiners only spectate (0 disables; see spectator.go).
+	SpectatorThreshold int
+
+	// SpectatorChatRate is how many chat messages per second the
+	// spectators of a room may send between them (0 = none).
+	SpectatorChatRate float64
+
+	// Backend selects the WebSocket implementation (default: gorilla).
+	Backend Backend
+
+	// BatchMode selects how queued messages are coalesced into frames (default: newline).
+	BatchMode BatchMode
+
+	// BatchMaxBytes caps the size of a coalesced frame (0 = unlimited).
+	BatchMaxBytes int
+
+	// MaxConnections caps concurrent connections across the server (0 = unlimited).
+	MaxConnections int
+
+	// MaxConnectionsPerIP caps concurrent connections from one client IP (0 = unlimited).
+	MaxConnectionsPerIP int
+
+	// Origins lists the browser origins allowed to connect (usually CORS_ORIGINS).
+	// Same-origin requests and requests without an Origin header are always allowed.
+	Origins *origin.Matcher
+
+	// RuntimeOrigins are allowed on top of Origins; admins manage them
+	// through the API.
+	RuntimeOrigins *origin.Dynamic
+
+	// AllowAnyOrigin disables origin checking entirely (development only).
+	AllowAnyOrigin bool
+
+	// TrustProxy makes per-IP limits use X-Forwarded-For / X-Real-IP.
+	// Enable only behind a reverse proxy that sets these headers.
+	TrustProxy bool
+
+	// Metrics receives hub counters. A private registry is used if nil.
+	Metrics *metrics.Registry
+
+	// Stats receives usage events for the admin overview (optional).
+	Stats StatsRecorder
+
+	// Analytics receives anonymized watch events per room (optional).
+	Analytics WatchRecorder
+
+	// VideoSources follows each room's current video, for the HLS proxy
+	// (optional).
+	VideoSources VideoSourceTracker
+
+	// LinkTrustLevel is the trust level (models.Trust*) needed to post
+	// links in chat ("" = anyone). Roles with the trust.bypass permission
+	// are exempt.
+	LinkTrustLevel string
+
+	// Authz decides what each role may send (default: built-in roles only).
+	Authz *authz.Authorizer
+
+	// Rooms looks up each connecting user's room role, which decides what
+	// they may send in that room, and the room's settings and saved
+	// playback, and saves its playback (optional; without it room roles
+	// are not enforced and playback is not saved).
+	Rooms repository.RoomRepository
+
+	// HostFailover picks who stands in as host while a room's owner is
+	// disconnected (default: FailoverCoHost).
+	HostFailover HostFailover
+
+	// QualityMode decides what is done with clients' playback reports
+	// (default: QualitySuggest).
+	QualityMode QualityMode
+
+	// LatencyReportInterval is how often those who control a room's
+	// playback are sent its members' round-trip times (0 disables).
+	LatencyReportInterval time.Duration
+
+	// SyncTolerance is how far a player may drift from its room before it
+	// is told to seek, in rooms without their own sync policy (0 disables
+	// corrections).
+	SyncTolerance time.Duration
+
+	// SyncNudge is how far a player may drift before it is nudged to play
+	// faster or slower, in rooms without their own sync policy (0 = only
+	// seek).
+	SyncNudge time.Duration
+
+	// NowPlaying receives what each room's main screen plays, for
+	// webhooks (optional).
+	NowPlaying NowPlayingListener
+
+	// NowPlayingInterval is how often rooms whose main screen is playing
+	// are sent a now_playing message, besides when it changes (0 = only
+	// on changes).
+	NowPlayingInterval time.Duration
+
+	// PauseOnDisconnect pauses playback while the host (models.PauseOnHost)
+	// or everyone (models.PauseOnEveryone) is gone, in rooms without their
+	// own sync policy; "" keeps it playing.
+	PauseOnDisconnect string
+
+	// Clock stamps messages and times idleness, last-seen, round trips,
+	// lifetimes, rate limits and the latency metrics (default: the system
+	// clock). Network deadlines always use real time.
+	Clock clock.Clock
+
+	// IDs generates the IDs of persisted messages, dead letters and
+	// analytics sessions (default: random UUIDs).
+	IDs idgen.Generator
+
+	// DeadLetterBuffer is how many of the most recent messages the Hub
+	// could not route it keeps for GET /api/admin/dead-letters (0 keeps
+	// none and persists none).
+	DeadLetterBuffer int
+
+	// DeadLetterRepo also stores every dead letter (optional).
+	DeadLetterRepo repository.DeadLetterRepository
+
+	// Announcements looks up which site-wide announcements each connecting
+	// user dismissed (optional; without it dismissals last only until the
+	// user reconnects).
+	Announcements repository.AnnouncementRepository
+
+	// Presence records who is connected to which room, for other instances
+	// and tools to read (optional; see presence.go).
+	Presence repository.PresenceRepository
+}
+
+// StatsRecorder receives usage events from the Hub. Methods are called on
+// the Hub goroutine and must not block.
+type StatsRecorder interface {
+	// ConnectionOpened is called for every new connection, with open the
+	// number of connections now open server-wide.
+	ConnectionOpened(userID string, open int)
+
+	// MessageSent is called for every chat message.
+	MessageSent(userID string)
+}
+
+// WatchRecorder receives anonymized viewing events from the Hub. Viewers
+// are identified by a random per-connection session ID, never by user,
+// and clients that opted out (see ServeWs) are not reported. Methods are
+// called on the Hub goroutine and must not block.
+type WatchRecorder interface {
+	ViewerJoined(room, session string)
+	ViewerLeft(room, session string)
+	VideoSync(room string, payload models.VideoSyncPayload)
+}
+
+// VideoSourceTracker learns each room's current video from its video_sync
+// events, and when the room's video state is dropped because nobody is
+// left. Methods are called on the Hub goroutine and must not block.
+type VideoSourceTracker interface {
+	VideoSync(room string, payload models.VideoSyncPayload)
+	RoomEmpty(room string)
 }
 
 // NewHub creates and returns a new Hub instance.
 // The messageRepo can be nil if message persistence is not needed.
-func NewHub(messageRepo repository.MessageRepository) *Hub {
-	return &Hub{
-		Broadcast:      make(chan []byte),
+func NewHub(messageRepo repository.MessageRepository, opts Options) *Hub {
+	if opts.WriteWait <= 0 {
+		opts.WriteWait = 10 * time.Second
+	}
+	if opts.PongWait <= 0 {
+		opts.PongWait = 60 * time.Second
+	}
+	if opts.PingPeriod <= 0 || opts.PingPeriod >= opts.PongWait {
+		opts.PingPeriod = (opts.PongWait * 9) / 10
+	}
+	if opts.KeepaliveMode == "" {
+		opts.KeepaliveMode = KeepaliveServerPing
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 65536
+	}
+	if opts.PayloadLimits == nil {
+		opts.PayloadLimits = DefaultPayloadLimits
+	}
+	if opts.RateLimits == nil {
+		opts.RateLimits = DefaultRateLimits
+	}
+	if opts.SendBufferSize <= 0 {
+		opts.SendBufferSize = 256
+	}
+	if opts.ReadBufferSize <= 0 {
+		opts.ReadBufferSize = 1024
+	}
+	if opts.WriteBufferSize <= 0 {
+		opts.WriteBufferSize = 1024
+	}
+	if opts.SlowClientPolicy == "" {
+		opts.SlowClientPolicy = PolicyDisconnect
+	}
+	if opts.HostFailover == "" {
+		opts.HostFailover = FailoverCoHost
+	}
+	if opts.OverflowQueueSize <= 0 {
+		opts.OverflowQueueSize = 1024
+	}
+	if opts.BatchMode == "" {
+		opts.BatchMode = BatchNewline
+	}
+	if opts.ShardCount <= 0 {
+		opts.ShardCount = 1
+	}
+	if opts.ShardThreshold <= 0 {
+		opts.ShardThreshold = 500
+	}
+	if opts.UserListInterval <= 0 {
+		opts.UserListInterval = 2 * time.Second
+	}
+	if opts.IdleWarning <= 0 || opts.IdleWarning >= opts.IdleTimeout {
+		opts.IdleWarning = min(time.Minute, opts.IdleTimeout/2)
+	}
+	if opts.LifetimeNotice <= 0 || opts.LifetimeNotice >= opts.MaxLifetime {
+		opts.LifetimeNotice = min(30*time.Second, opts.MaxLifetime/2)
+	}
+	if opts.QualityMode == "" {
+		opts.QualityMode = QualitySuggest
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	if opts.IDs == nil {
+		opts.IDs = idgen.UUID{}
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NewRegistry()
+	}
+	if opts.Authz == nil {
+		opts.Authz = &authz.Authorizer{}
+	}
+
+	h := &Hub{
+		Broadcast:      make(chan Inbound),
 		Register:       make(chan *Client),
 		Unregister:     make(chan *Client),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+		notify:         make(chan notification, notifyQueueSize),
+		kick:           make(chan kick, kickQueueSize),
+		roomRoles:      make(chan roomRoleChange, roomRoleQueueSize),
+		connections:    make(chan connectionsRequest, connectionsQueueSize),
+		nowPlaying:     make(chan nowPlayingRequest, nowPlayingQueueSize),
+		announcements:  make(chan announcement, announceQueueSize),
+		bannerChanges:  make(chan bannerChange, bannerQueueSize),
+		drains:         make(chan drainRequest),
+		syncStates:     make(map[string]map[string]syncState),
+		syncPolicies:   make(map[string]models.SyncPolicy),
+		syncChanges:    make(chan syncPolicyChange, syncPolicyQueueSize),
+		autoPaused:     make(map[string]time.Time),
+		screens:        make(map[string]map[string]models.Screen),
+		screenChanges:  make(chan screensChange, screenQueueSize),
+		hosts:          make(map[string]host),
+		suggestions:    make(map[string]int),
+		seqs:           make(map[string]int64),
+		sanctions:      newSanctions(),
 		clients:        make(map[string]map[*Client]bool),
-		lastVideoState: make(map[string][]byte),
+		lastVideoState: make(map[string]map[string][]byte),
+		videoSaves:     make(chan videoSave, videoSaveQueueSize),
+		present:        make(map[string]map[string]int),
+		presenceWrites: make(chan presenceWrite, presenceQueueSize),
+		roomShards:     make(map[string][]map[*Client]bool),
+		userLists:      make(map[string]*userListRoom),
+		spectators:     make(map[string]int),
+		spectatorChat:  make(map[string]*tokenBucket),
+		pendingLeaves:  make(map[string]pendingLeave),
+		handlers:       make(map[string]Handler),
 		messageRepo:    messageRepo,
+		opts:           opts,
+		policyMetrics:  newPolicyMetrics(opts.Metrics, opts.SlowClientPolicy),
+		metrics:        newHubMetrics(opts.Metrics, opts.SlowClientPolicy),
+		upgrader:       newUpgrader(opts),
+		limiter:        newConnLimiter(opts.MaxConnections, opts.MaxConnectionsPerIP, opts.Metrics),
 	}
+	h.shards = newShardPool(h, opts.ShardCount)
+	if opts.DeadLetterBuffer > 0 {
+		h.deadLetters = newDeadLetterBuffer(opts.DeadLetterBuffer)
+	}
+	h.registerBuiltinHandlers()
+	h.UsePreRoute(h.limitPayload, h.limitRate, h.trackActivity, h.stampMessage, h.requirePermission, h.enforceMutes, h.restrictLinks, h.filterWords, h.restrictSpectators)
+	h.UsePreBroadcast()
+	return h
+}
+
+// ConnectionCount returns the number of open WebSocket connections.
+// Safe to call from any goroutine.
+func (h *Hub) ConnectionCount() int {
+	return h.limiter.count()
+}
+
+// now returns the time on the Hub's clock.
+func (h *Hub) now() time.Time {
+	return h.opts.Clock.Now()
 }
 
-// Run starts the Hub's main event loop. Call this in a goroutine.
-func (h *Hub) Run() {
+// Run starts the Hub's main event loop. Call this in a goroutine. It
+// returns when ctx is done or Stop is called, after closing every client
+// with CloseServerShutdown.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.shutdown()
+
+	userListC, stopUserList := h.userListTicker()
+	defer stopUserList()
+
+	housekeepingC, stopHousekeeping := h.housekeepingTicker()
+	defer stopHousekeeping()
+
+	latencyC, stopLatency := h.latencyTicker()
+	defer stopLatency()
+
+	nowPlayingC, stopNowPlaying := h.nowPlayingTicker()
+	defer stopNowPlaying()
+
+	bannerC, stopBanner := h.bannerTicker()
+	defer stopBanner()
+
+	if h.opts.Rooms != nil {
+		h.writers.Add(1) // done by videoStateWriter
+		go h.videoStateWriter()
+	}
+	if h.opts.Presence != nil {
+		h.writers.Add(1) // done by presenceWriter
+		go h.presenceWriter(h.opts.Presence)
+	}
+
+	// Nil until Drain is called.
+	var drainC <-chan time.Time
+	stopDrain := func() {}
+	defer func() { stopDrain() }()
+
 	for {
 		select {
+		case <-ctx.Done():
+			return
+
+		case <-h.stop:
+			return
+
+		case <-userListC:
+			h.flushUserLists()
+
+		case <-housekeepingC:
+			h.housekeeping()
+
+		case <-latencyC:
+			h.reportLatency()
+
+		case <-nowPlayingC:
+			h.reportNowPlaying()
+
+		case <-bannerC:
+			h.checkAnnouncements()
+
+		case req := <-h.drains:
+			if c, stop := h.startDrain(req); c != nil {
+				drainC, stopDrain = c, stop
+			}
+
+		case <-drainC:
+			h.handOffDue()
+
 		case client := <-h.Register:
 			h.addClient(client)
 
 		case client := <-h.Unregister:
 			h.removeClient(client)
 
-		case message := <-h.Broadcast:
-			h.routeMessage(message)
+		case in := <-h.Broadcast:
+			h.routeMessage(in)
+
+		case n := <-h.notify:
+			h.deliverNotification(n)
+
+		case k := <-h.kick:
+			h.disconnectUser(k)
+
+		case c := <-h.roomRoles:
+			h.applyRoomRole(c)
+
+		case req := <-h.connections:
+			h.listConnections(req)
+
+		case req := <-h.nowPlaying:
+			h.listNowPlaying(req)
+
+		case a := <-h.announcements:
+			h.announce(a)
+
+		case c := <-h.bannerChanges:
+			h.applyBannerChange(c)
+
+		case c := <-h.syncChanges:
+			h.applySyncPolicy(c)
+
+		case c := <-h.screenChanges:
+			h.applyScreens(c)
+		}
+	}
+}
+
+// Stop makes Run return and waits until every client has been sent its
+// close frame, or WriteWait has passed for those that do not take it.
+// Connections that arrive afterwards are closed with CloseServerShutdown
+// too. Safe to call from any goroutine, more than once, but only once Run
+// has started.
+func (h *Hub) Stop() {
+	h.stopOnce.Do(func() { close(h.stop) })
+	<-h.done
+	h.writers.Wait()
+}
+
+// shutdown saves every room's playback, closes every client with
+// CloseServerShutdown and stops the shard workers. Nobody is told who
+// left: everyone is leaving.
+func (h *Hub) shutdown() {
+	h.saveAllVideoStates()
+	close(h.videoSaves)
+	h.removeAllPresence()
+	close(h.presenceWrites)
+
+	n := 0
+	for room, roomClients := range h.clients {
+		for client := range roomClients {
+			client.closeCode = CloseServerShutdown
+			client.closeReason = CloseReason(CloseServerShutdown)
+			close(client.Send)
+			h.recordDisconnect(client)
+			if h.opts.Analytics != nil && !client.analyticsOptOut {
+				h.opts.Analytics.ViewerLeft(room, client.sessionID)
+			}
+			n++
+		}
+		h.dropVideoState(room)
+	}
+	h.clients = make(map[string]map[*Client]bool)
+	h.roomShards = make(map[string][]map[*Client]bool)
+	h.shards.stop()
+	close(h.done)
+	log.Printf("ws: hub stopped, closed %d connections", n)
+}
+
+// housekeepingInterval bounds how often the Hub scans for idle and aged clients.
+const housekeepingInterval = 15 * time.Second
+
+// housekeepingTicker returns a ticker for connection housekeeping, fast
+// enough for the configured idle timeout and lifetime notice.
+func (h *Hub) housekeepingTicker() (<-chan time.Time, func()) {
+	interval := housekeepingInterval
+	if h.opts.IdleTimeout > 0 {
+		interval = min(interval, h.opts.IdleTimeout/4)
+	}
+	if h.opts.MaxLifetime > 0 {
+		interval = min(interval, h.opts.LifetimeNotice/2)
+	}
+
+	t := time.NewTicker(interval)
+	return t.C, t.Stop
+}
+
+// housekeeping enforces token expiry, idle timeouts and connection
+// lifetimes, and samples the Hub's metrics.
+func (h *Hub) housekeeping() {
+	h.checkAuthExpiry()
+	if h.opts.IdleTimeout > 0 {
+		h.checkIdle()
+	}
+	if h.opts.MaxLifetime > 0 {
+		h.checkLifetime()
+	}
+	h.flushPendingLeaves()
+	h.expireHeldStates()
+	h.refreshPresence()
+	h.sampleMetrics()
+}
+
+// checkAuthExpiry closes clients whose JWT has expired since they connected.
+func (h *Hub) checkAuthExpiry() {
+	now := h.now()
+	for _, roomClients := range h.clients {
+		for client := range roomClients {
+			if !client.tokenExpiry.IsZero() && now.After(client.tokenExpiry) {
+				h.closeClient(client, CloseAuthExpired)
+			}
 		}
 	}
 }
 
 // addClient registers a new client in its room.
 func (h *Hub) addClient(client *Client) {
+	h.writers.Add(1) // done by client.writePump
 	room := client.RoomID
 	if h.clients[room] == nil {
 		h.clients[room] = make(map[*Client]bool)
 	}
 	h.clients[room][client] = true
+	h.recordConnect(client)
+	h.assignShard(client)
+	h.seat(client)
+	h.enterPresence(client)
+	if client.syncPolicy != nil {
+		h.syncPolicies[room] = *client.syncPolicy
+	}
+	if len(client.screens) > 0 {
+		h.setScreens(room, client.screens)
+	}
+	client.touch()
 
 	log.Printf("ws: client connected (user=%s, room=%s, total_in_room=%d)",
 		client.Username, room, len(h.clients[room]))
+	if h.opts.Stats != nil {
+		h.opts.Stats.ConnectionOpened(client.UserID, h.limiter.count())
+	}
+	if h.opts.Analytics != nil && !client.analyticsOptOut {
+		h.opts.Analytics.ViewerJoined(room, client.sessionID)
+	}
+
+	// A client resuming a rotated-out connection rejoins silently, even if
+	// another process rotated it out (see Drain), and nobody is told about
+	// spectators.
+	if client.resumed {
+		h.resumeLeave(client)
+	}
+	if !client.spectator && !client.resumed {
+		h.broadcastSystemMessage(room, "user_joined", client.UserID, client.Username)
+	}
 
-	h.broadcastSystemMessage(room, "user_joined", client.UserID, client.Username)
+	h.hostJoined(client)
 
-	// Push the current video state to the new client
-	if state, ok := h.lastVideoState[room]; ok {
-		select {
-		case client.Send <- state:
-		default:
+	// Push the current video state of each screen to the new client,
+	// unless its return resumes playback there: then the whole room is
+	// sent it.
+	h.restoreVideoState(client)
+	resumed := h.resumeIfBack(client)
+	for _, screen := range h.videoScreens(room) {
+		if resumed[screen] {
+			continue
+		}
+		if state := h.currentVideoState(room, screen); state != nil && !h.send(client, state) {
+			h.disconnectSlowClient(client)
+			return
 		}
 	}
+	if !h.sendSuggestion(client) {
+		h.disconnectSlowClient(client)
+		return
+	}
+	if client.spectator && !h.sendSpectator(client) {
+		h.disconnectSlowClient(client)
+		return
+	}
+	if !h.sendUserList(client) {
+		h.disconnectSlowClient(client)
+		return
+	}
+	if !h.sendAnnouncements(client, false) {
+		h.disconnectSlowClient(client)
+		return
+	}
+
+	if !client.spectator {
+		h.listUser(client)
+	}
+	if h.draining != nil {
+		h.drainClient(client)
+	}
+}
 
-	h.broadcastUserList(room)
+// closeClient removes a client, sending code and its standard reason
+// (see closecodes.go).
+func (h *Hub) closeClient(client *Client, code int) {
+	client.closeCode = code
+	client.closeReason = CloseReason(code)
+	h.removeClient(client)
 }
 
 // removeClient unregisters a client and cleans up empty rooms.
@@ -94,56 +817,81 @@ func (h *Hub) removeClient(client *Client) {
 	}
 
 	delete(roomClients, client)
+	h.unassignShard(client)
 	close(client.Send)
+	h.recordDisconnect(client)
+	h.recordLeave(client)
+	h.leavePresence(client)
+	if h.opts.Analytics != nil && !client.analyticsOptOut {
+		h.opts.Analytics.ViewerLeft(room, client.sessionID)
+	}
 
-	log.Printf("ws: client disconnected (user=%s, room=%s, total_in_room=%d)",
-		client.Username, room, len(roomClients))
-
-	h.broadcastSystemMessage(room, "user_left", client.UserID, client.Username)
-	h.broadcastUserList(room)
+	log.Printf("ws: client disconnected (user=%s, room=%s, total_in_room=%d, last_seen=%s ago)",
+		client.Username, room, len(roomClients), h.now().Sub(client.LastSeen()).Round(time.Millisecond))
+
+	// A rotated-out client is expected back; announce its departure only
+	// if it doesn't resume in time (see flushPendingLeaves). Spectators
+	// leave silently; a participant leaving for good makes room for one.
+	switch {
+	case client.spectator:
+		h.unseat(client)
+	case client.rotating:
+		h.holdLeave(client)
+	default:
+		h.broadcastSystemMessage(room, "user_left", client.UserID, client.Username)
+		h.promoteSpectator(room)
+		h.hostLeft(room, client.UserID)
+		h.pauseIfHostLeft(room)
+	}
+	h.unlistUser(client)
+	if client.playback != nil && h.opts.QualityMode == QualitySuggest {
+		h.suggestQuality(room)
+	}
 
-	// Clean up empty rooms from memory
+	// Clean up empty rooms from memory. The video state survives while a
+	// rotated-out client may still resume, or paused if the room's policy
+	// says so.
 	if len(roomClients) == 0 {
 		delete(h.clients, room)
-		delete(h.lastVideoState, room)
+		if !client.rotating {
+			h.roomEmptied(room)
+		}
+		delete(h.roomShards, room)
+		delete(h.userLists, room)
+		delete(h.spectatorChat, room)
+		delete(h.hosts, room)
+		delete(h.suggestions, room)
+		delete(h.seqs, room)
 	}
 }
 
-// routeMessage parses incoming JSON and routes by message type.
-func (h *Hub) routeMessage(raw []byte) {
-	var msg models.Message
-	if err := json.Unmarshal(raw, &msg); err != nil {
-		log.Printf("ws: invalid message format: %v", err)
+// routeMessage parses incoming JSON and runs it through the pre-route hooks
+// to the handler registered for its type (see handlers.go).
+func (h *Hub) routeMessage(in Inbound) {
+	client, raw := in.Client, in.Data
+
+	// The client may already have been removed (e.g. disconnected as slow).
+	room := client.RoomID
+	if !h.clients[room][client] {
 		return
 	}
 
-	// Find which room this sender is in.
-	room := h.findClientRoom(msg.Sender)
-	if room == "" {
-		log.Printf("ws: sender %s not found in any room", msg.Sender)
+	var msg models.Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		log.Printf("ws: invalid message format (user=%s): %v", client.Username, err)
+		h.recordDeadLetter(client, raw, nil, models.WSErrInvalidMessage, "message is not valid JSON")
+		if !h.strike(client) {
+			return
+		}
+		h.sendError(client, models.ErrorPayload{
+			Code:    models.WSErrInvalidMessage,
+			Message: "message is not valid JSON",
+		})
 		return
 	}
 
-	switch msg.Type {
-	case models.MsgTypeChat:
-		h.persistMessage(room, msg)
-		h.broadcastToRoom(room, raw)
-
-	case models.MsgTypeVideoSync:
-		h.lastVideoState[room] = raw
-		h.broadcastToRoom(room, raw)
-
-	case models.MsgTypeWebRTC:
-		// WebRTC signaling: forward to a specific target user (cross-room)
-		h.routeWebRTCMessage(msg)
-
-	case models.MsgTypeAdmin:
-		h.broadcastToRoom(room, raw)
-
-	default:
-		log.Printf("ws: unknown message type: %s", msg.Type)
-		h.broadcastToRoom(room, raw)
-	}
+	defer h.observeRoute(msg.Type, h.now())
+	h.route(&Context{Hub: h, Client: client, Room: room, Message: msg, Raw: raw})
 }
 
 // persistMessage saves a chat message to the database asynchronously.
@@ -152,16 +900,10 @@ func (h *Hub) persistMessage(roomID string, msg models.Message) {
 		return
 	}
 
-	// Find sender's user ID
-	senderID := h.findClientUserID(msg.Sender)
-	if senderID == "" {
-		return
-	}
-
 	chatMsg := &models.ChatMessage{
-		ID:        uuid.New().String(),
+		ID:        h.opts.IDs.New(),
 		RoomID:    roomID,
-		SenderID:  senderID,
+		SenderID:  msg.SenderID,
 		Sender:    msg.Sender,
 		Type:      msg.Type,
 		Content:   msg.Payload,
@@ -176,117 +918,61 @@ func (h *Hub) persistMessage(roomID string, msg models.Message) {
 	}()
 }
 
-// findClientRoom returns the room ID of a client identified by username.
-func (h *Hub) findClientRoom(username string) string {
-	for roomID, roomClients := range h.clients {
-		for client := range roomClients {
-			if client.Username == username {
-				return roomID
-			}
-		}
-	}
-	return ""
-}
-
-// findClientUserID returns the user ID of a client identified by username.
-func (h *Hub) findClientUserID(username string) string {
-	for _, roomClients := range h.clients {
-		for client := range roomClients {
-			if client.Username == username {
-				return client.UserID
-			}
-		}
-	}
-	return ""
-}
-
-// routeWebRTCMessage parses the target from the payload and sends directly.
-func (h *Hub) routeWebRTCMessage(msg models.Message) {
-	var payload struct {
-		Target string `json:"target"`
+// sendToUser sends a message to a specific user by username (across all
+// rooms). It reports false if the user is not connected.
+func (h *Hub) sendToUser(username string, message []byte) bool {
+	client := h.userClient(username)
+	if client == nil {
+		return false
 	}
-	if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
-		log.Printf("ws: webrtc message missing target: %v", err)
-		return
-	}
-
-	if payload.Target == "" {
-		log.Printf("ws: webrtc message has empty target")
-		return
-	}
-
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("ws: failed to marshal webrtc message: %v", err)
-		return
+	if !h.send(client, message) {
+		h.disconnectSlowClient(client)
 	}
-
-	h.sendToUser(payload.Target, data)
+	return true
 }
 
-// sendToUser sends a message to a specific user by username (across all rooms).
-func (h *Hub) sendToUser(username string, message []byte) {
+// userClient returns a connection of username (across all rooms), or nil
+// if the user is not connected.
+func (h *Hub) userClient(username string) *Client {
 	for _, roomClients := range h.clients {
 		for client := range roomClients {
 			if client.Username == username {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(roomClients, client)
-				}
-				return
+				return client
 			}
 		}
 	}
-	log.Printf("ws: target user not found: %s", username)
+	return nil
 }
 
-// broadcastUserList sends the current list of connected usernames in a room.
-func (h *Hub) broadcastUserList(roomID string) {
-	roomClients := h.clients[roomID]
-	if roomClients == nil {
-		return
-	}
-
-	usernames := make([]string, 0, len(roomClients))
-	for client := range roomClients {
-		usernames = append(usernames, client.Username)
-	}
-
-	payload, _ := json.Marshal(usernames)
-
-	msg := models.Message{
-		Type:      models.MsgTypeUserList,
-		Sender:    "system",
-		Payload:   string(payload),
-		Timestamp: time.Now(),
-	}
-
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("ws: failed to marshal user list: %v", err)
-		return
-	}
-
-	h.broadcastToRoom(roomID, data)
+// broadcastToRoom sends a message to every client in a specific room,
+// through the pre-broadcast hooks.
+func (h *Hub) broadcastToRoom(roomID string, message []byte) {
+	h.broadcast(roomID, message)
 }
 
-// broadcastToRoom sends a message to every client in a specific room.
-func (h *Hub) broadcastToRoom(roomID string, message []byte) {
+// deliverToRoom fans a message out to the room's clients.
+func (h *Hub) deliverToRoom(roomID string, message []byte) {
 	roomClients := h.clients[roomID]
 	if roomClients == nil {
 		return
 	}
+	defer h.observeBroadcast(h.now())
 
-	for client := range roomClients {
-		select {
-		case client.Send <- message:
-		default:
-			close(client.Send)
-			delete(roomClients, client)
+	var slow []*Client
+	if h.opts.ShardCount > 1 && h.isLargeRoom(roomID) {
+		slow = h.shards.broadcast(h.roomShards[roomID], message)
+	} else {
+		for client := range roomClients {
+			if !h.send(client, message) {
+				slow = append(slow, client)
+			}
 		}
 	}
+
+	// Disconnect after the loop: removal broadcasts user_left to the room.
+	for _, client := range slow {
+		h.disconnectSlowClient(client)
+	}
 }
 
 // broadcastSystemMessage sends a system notification to all clients in a room.
@@ -301,7 +987,7 @@ func (h *Hub) broadcastSystemMessage(roomID, event, userID, username string) {
 		Type:      models.MsgTypeSystem,
 		Sender:    "system",
 		Payload:   string(payload),
-		Timestamp: time.Now(),
+		Timestamp: h.now(),
 	}
 
 	data, err := json.Marshal(msg)
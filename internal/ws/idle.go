@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// touch records application-level activity from a client.
+func (c *Client) touch() {
+	c.lastActivity = c.hub.now()
+	c.idleWarned = false
+}
+
+// checkIdle warns clients approaching the idle timeout and closes those past it.
+func (h *Hub) checkIdle() {
+	now := h.now()
+	warnAt := h.opts.IdleTimeout - h.opts.IdleWarning
+
+	for _, roomClients := range h.clients {
+		for client := range roomClients {
+			idle := now.Sub(client.lastActivity)
+
+			switch {
+			case idle >= h.opts.IdleTimeout:
+				log.Printf("ws: closing idle client (user=%s, room=%s, idle=%s)",
+					client.Username, client.RoomID, idle.Round(time.Second))
+				h.closeClient(client, CloseIdleTimeout)
+
+			case idle >= warnAt && !client.idleWarned:
+				client.idleWarned = true
+				h.sendIdleWarning(client, h.opts.IdleTimeout-idle)
+			}
+		}
+	}
+}
+
+// sendIdleWarning tells a client it will be disconnected unless it sends
+// something (any message, e.g. "activity") within closesIn.
+func (h *Hub) sendIdleWarning(client *Client, closesIn time.Duration) {
+	payload, _ := json.Marshal(map[string]any{
+		"event":           "idle_warning",
+		"closesInSeconds": int(closesIn.Seconds()),
+	})
+
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeSystem,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal idle warning: %v", err)
+		return
+	}
+
+	if !h.send(client, data) {
+		h.disconnectSlowClient(client)
+	}
+}
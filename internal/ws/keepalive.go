@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// KeepaliveMode selects how dead connections are detected.
+//
+// In every mode, any frame received from the client (data, ping or pong)
+// counts as a sign of life: it updates the client's last-seen time and
+// extends the read deadline by PongWait. The modes differ only in what the
+// server sends to provoke that traffic.
+type KeepaliveMode string
+
+// Supported keepalive modes.
+const (
+	// KeepaliveServerPing sends WebSocket ping frames every PingPeriod (default).
+	KeepaliveServerPing KeepaliveMode = "server_ping"
+
+	// KeepaliveClientPing sends nothing; clients must send ping frames or
+	// messages at least every PongWait.
+	KeepaliveClientPing KeepaliveMode = "client_ping"
+
+	// KeepaliveHeartbeat sends a JSON {"type":"heartbeat"} message every
+	// PingPeriod, which clients echo back. Use behind proxies that strip
+	// ping/pong control frames.
+	KeepaliveHeartbeat KeepaliveMode = "heartbeat"
+)
+
+// ParseKeepaliveMode validates a keepalive mode name from config.
+func ParseKeepaliveMode(s string) (KeepaliveMode, error) {
+	switch m := KeepaliveMode(s); m {
+	case KeepaliveServerPing, KeepaliveClientPing, KeepaliveHeartbeat:
+		return m, nil
+	default:
+		return "", fmt.Errorf("ws: unknown keepalive mode %q", s)
+	}
+}
+
+// seen records a frame from the client and extends the read deadline.
+// Called from readPump and the pong/ping handlers.
+func (c *Client) seen() {
+	c.lastSeen.Store(c.hub.now().UnixNano())
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.opts.PongWait))
+}
+
+// LastSeen returns when the client last sent any frame. Safe for concurrent use.
+func (c *Client) LastSeen() time.Time {
+	return time.Unix(0, c.lastSeen.Load())
+}
+
+// writeKeepalive sends the mode's keepalive probe. Called from writePump.
+func (c *Client) writeKeepalive() error {
+	if c.hub.opts.KeepaliveMode == KeepaliveClientPing {
+		return nil
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+	c.probeSent()
+
+	switch c.hub.opts.KeepaliveMode {
+	case KeepaliveHeartbeat:
+		data, err := json.Marshal(models.Message{
+			Type:      models.MsgTypeHeartbeat,
+			Sender:    "system",
+			Timestamp: c.hub.now(),
+		})
+		if err != nil {
+			return err
+		}
+		return c.conn.WriteText(data)
+
+	default:
+		return c.conn.WritePing()
+	}
+}
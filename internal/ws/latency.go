@@ -0,0 +1,194 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+)
+
+// Round-trip times are measured with the keepalive probes the server sends
+// anyway: the time from a ping frame to its pong under KeepaliveServerPing,
+// or from a heartbeat to the client's echo under KeepaliveHeartbeat. Under
+// KeepaliveClientPing the server sends no probes and measures nothing.
+//
+// Samples are smoothed like TCP's SRTT, so one slow pong doesn't mark a
+// member as lagging.
+const rttSmoothing = 8 // each sample moves the estimate by 1/rttSmoothing
+
+// connectionsQueueSize bounds the pending Connections calls.
+const connectionsQueueSize = 16
+
+// probeSent records that a keepalive probe is being sent. Called from
+// writePump.
+func (c *Client) probeSent() {
+	c.probeAt.Store(c.hub.now().UnixNano())
+}
+
+// probeAnswered records the answer to the last probe, if it is still
+// unanswered. Called from the pong handler, and from the Hub for heartbeats.
+func (c *Client) probeAnswered() {
+	sent := c.probeAt.Swap(0)
+	if sent == 0 {
+		return
+	}
+	sample := c.hub.now().Sub(time.Unix(0, sent))
+	if srtt := time.Duration(c.rtt.Load()); srtt > 0 {
+		sample = srtt + (sample-srtt)/rttSmoothing
+	}
+	c.rtt.Store(int64(sample))
+	if series := c.rttSeries.Load(); series != nil {
+		series.Observe(sample.Seconds())
+	}
+}
+
+// RTT returns the client's smoothed round-trip time, 0 until measured.
+// Safe for concurrent use.
+func (c *Client) RTT() time.Duration {
+	return time.Duration(c.rtt.Load())
+}
+
+// rttMillis rounds a round-trip time to milliseconds, at least 1 once
+// measured, so a fast connection doesn't read as unmeasured.
+func rttMillis(rtt time.Duration) int {
+	if rtt <= 0 {
+		return 0
+	}
+	return max(1, int(rtt.Round(time.Millisecond)/time.Millisecond))
+}
+
+// compensateSync moves the position of a playing video_sync payload
+// forward by half the sender's round-trip time, the time it took to reach
+// the server, so the position broadcast is the one at the server's
+// timestamp. It reports whether the payload changed.
+func compensateSync(sender *Client, payload *models.VideoSyncPayload) bool {
+	rtt := sender.RTT()
+	if !payload.Playing || rtt <= 0 {
+		return false
+	}
+	payload.Timestamp += (rtt / 2).Seconds()
+	return true
+}
+
+// reportLatency sends each room's round-trip times to those who control
+// its playback. Rooms with a single member are skipped: there is nobody
+// to compare with.
+func (h *Hub) reportLatency() {
+	for _, roomClients := range h.clients {
+		if len(roomClients) < 2 {
+			continue
+		}
+		report := models.LatencyReport{Members: []models.MemberLatency{}}
+		for client := range roomClients {
+			if rtt := client.RTT(); rtt > 0 {
+				report.Members = append(report.Members, models.MemberLatency{
+					UserID:   client.UserID,
+					Username: client.Username,
+					RTTMs:    rttMillis(rtt),
+				})
+			}
+		}
+		if len(report.Members) == 0 {
+			continue
+		}
+		sort.Slice(report.Members, func(i, j int) bool {
+			return report.Members[i].RTTMs > report.Members[j].RTTMs
+		})
+
+		payload, _ := json.Marshal(report)
+		data, err := json.Marshal(models.Message{
+			Type:      models.MsgTypeLatency,
+			Sender:    "system",
+			Payload:   string(payload),
+			Timestamp: h.now(),
+		})
+		if err != nil {
+			log.Printf("ws: failed to marshal latency report: %v", err)
+			continue
+		}
+
+		var slow []*Client
+		for client := range roomClients {
+			if h.roomCan(client, authz.RoomPermVideoControl) && !h.send(client, data) {
+				slow = append(slow, client)
+			}
+		}
+		// Disconnect after the loop: removal modifies h.clients.
+		for _, client := range slow {
+			h.disconnectSlowClient(client)
+		}
+	}
+}
+
+// latencyTicker returns a ticker for latency reports, or a nil channel if
+// they are disabled.
+func (h *Hub) latencyTicker() (<-chan time.Time, func()) {
+	if h.opts.LatencyReportInterval <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(h.opts.LatencyReportInterval)
+	return t.C, t.Stop
+}
+
+// Connections returns the open connections, for GET /api/admin/connections:
+// those in room, or all if room is empty, sorted by room and username.
+// Safe to call from any goroutine; it waits for the Hub, and returns
+// ctx.Err() if ctx is done first and nil once the Hub has stopped.
+func (h *Hub) Connections(ctx context.Context, room string) ([]models.Connection, error) {
+	req := connectionsRequest{room: room, reply: make(chan []models.Connection, 1)}
+	select {
+	case h.connections <- req:
+	case <-h.done:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case conns := <-req.reply:
+		return conns, nil
+	case <-h.done:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// connectionsRequest is a pending Connections call.
+type connectionsRequest struct {
+	room  string
+	reply chan []models.Connection
+}
+
+// listConnections answers a Connections call.
+func (h *Hub) listConnections(req connectionsRequest) {
+	conns := []models.Connection{}
+	for room, roomClients := range h.clients {
+		if req.room != "" && room != req.room {
+			continue
+		}
+		for client := range roomClients {
+			conns = append(conns, models.Connection{
+				UserID:      client.UserID,
+				Username:    client.Username,
+				RoomID:      client.RoomID,
+				RoomRole:    client.RoomRole,
+				Protocol:    client.Protocol,
+				ConnectedAt: client.connectedAt,
+				LastSeen:    client.LastSeen(),
+				RTTMs:       rttMillis(client.RTT()),
+				Spectator:   client.spectator,
+			})
+		}
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		if conns[i].RoomID != conns[j].RoomID {
+			return conns[i].RoomID < conns[j].RoomID
+		}
+		return conns[i].Username < conns[j].Username
+	})
+	req.reply <- conns
+}
@@ -0,0 +1,152 @@
+package ws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// resumeWindow is how long a resume token stays valid after the close, and
+// how long the user's departure is withheld from the room waiting for it.
+const resumeWindow = 30 * time.Second
+
+// pendingLeave is a rotated-out client whose user_left announcement is
+// withheld until it resumes or the resume window passes.
+type pendingLeave struct {
+	userID   string
+	username string
+	room     string
+	deadline time.Time
+}
+
+// checkLifetime hints clients nearing MaxLifetime and closes those past it.
+func (h *Hub) checkLifetime() {
+	now := h.now()
+	hintAt := h.opts.MaxLifetime - h.opts.LifetimeNotice
+
+	for _, roomClients := range h.clients {
+		for client := range roomClients {
+			age := now.Sub(client.connectedAt)
+
+			switch {
+			case age >= h.opts.MaxLifetime:
+				client.rotating = true
+				h.closeClient(client, CloseMaxLifetime)
+
+			case age >= hintAt && !client.reconnectHinted:
+				client.reconnectHinted = true
+				h.sendReconnectHint(client, h.opts.MaxLifetime-age)
+			}
+		}
+	}
+}
+
+// sendReconnectHint tells a client its connection will be closed soon and
+// hands it a resume token for the replacement connection.
+func (h *Hub) sendReconnectHint(client *Client, closesIn time.Duration) {
+	token := signResumeToken(h.opts.ResumeSecret, client.UserID, client.RoomID,
+		h.now().Add(closesIn+resumeWindow))
+
+	payload, _ := json.Marshal(map[string]any{
+		"resumeToken":     token,
+		"closesInSeconds": int(closesIn.Seconds()),
+	})
+
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeReconnect,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal reconnect hint: %v", err)
+		return
+	}
+
+	if !h.send(client, data) {
+		h.disconnectSlowClient(client)
+	}
+}
+
+// holdLeave withholds a rotating client's departure from the room.
+func (h *Hub) holdLeave(client *Client) {
+	h.pendingLeaves[leaveKey(client.UserID, client.RoomID)] = pendingLeave{
+		userID:   client.UserID,
+		username: client.Username,
+		room:     client.RoomID,
+		deadline: h.now().Add(resumeWindow),
+	}
+}
+
+// resumeLeave cancels the withheld departure of a resuming client, if
+// this process withheld one.
+func (h *Hub) resumeLeave(client *Client) {
+	delete(h.pendingLeaves, leaveKey(client.UserID, client.RoomID))
+}
+
+// flushPendingLeaves announces departures whose resume window has passed.
+func (h *Hub) flushPendingLeaves() {
+	now := h.now()
+	for key, p := range h.pendingLeaves {
+		if now.Before(p.deadline) {
+			continue
+		}
+		delete(h.pendingLeaves, key)
+
+		if len(h.clients[p.room]) == 0 {
+			h.roomEmptied(p.room)
+			continue
+		}
+		h.broadcastSystemMessage(p.room, "user_left", p.userID, p.username)
+		h.hostLeft(p.room, p.userID)
+		h.pauseIfHostLeft(p.room)
+	}
+}
+
+func leaveKey(userID, room string) string {
+	return userID + "\x00" + room
+}
+
+// signResumeToken creates a token binding userID and room until expires.
+// Format: base64url("userID|room|expiresUnix") + "." + base64url(HMAC-SHA256).
+func signResumeToken(secret, userID, room string, expires time.Time) string {
+	body := strings.Join([]string{userID, room, strconv.FormatInt(expires.Unix(), 10)}, "|")
+	enc := base64.RawURLEncoding.EncodeToString([]byte(body))
+	return enc + "." + resumeMAC(secret, enc)
+}
+
+// verifyResumeToken checks a resume token for userID and room.
+func verifyResumeToken(secret, token, userID, room string, now time.Time) error {
+	enc, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(resumeMAC(secret, enc))) {
+		return errors.New("ws: invalid resume token")
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return errors.New("ws: invalid resume token")
+	}
+	parts := strings.Split(string(body), "|")
+	if len(parts) != 3 || parts[0] != userID || parts[1] != room {
+		return errors.New("ws: resume token does not match connection")
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return errors.New("ws: resume token expired")
+	}
+	return nil
+}
+
+func resumeMAC(secret, data string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,109 @@
+package ws
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"ofenes/internal/metrics"
+)
+
+// connLimiter caps concurrent WebSocket connections globally and per client IP.
+//
+// It is checked in ServeWs before the upgrade, from the HTTP handler goroutine,
+// so unlike the rest of the Hub state it is guarded by a mutex.
+type connLimiter struct {
+	maxTotal int // 0 = unlimited
+	maxPerIP int // 0 = unlimited
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+
+	active   *metrics.Gauge
+	rejected map[string]*metrics.Counter
+}
+
+// Rejection reasons reported by connLimiter.acquire.
+const (
+	limitReasonTotal = "total"
+	limitReasonPerIP = "per_ip"
+)
+
+func newConnLimiter(maxTotal, maxPerIP int, reg *metrics.Registry) *connLimiter {
+	const help = "WebSocket connections rejected by connection limits."
+	return &connLimiter{
+		maxTotal: maxTotal,
+		maxPerIP: maxPerIP,
+		perIP:    make(map[string]int),
+		active:   reg.Gauge("ws_connections", "Currently open WebSocket connections."),
+		rejected: map[string]*metrics.Counter{
+			limitReasonTotal: reg.Counter("ws_connections_rejected_total", help, "reason", limitReasonTotal),
+			limitReasonPerIP: reg.Counter("ws_connections_rejected_total", help, "reason", limitReasonPerIP),
+		},
+	}
+}
+
+// acquire reserves a connection slot for ip. It returns the rejection reason
+// if a limit is reached, or "" on success. Every successful acquire must be
+// paired with a release.
+func (l *connLimiter) acquire(ip string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		l.rejected[limitReasonTotal].Inc()
+		return limitReasonTotal
+	}
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		l.rejected[limitReasonPerIP].Inc()
+		return limitReasonPerIP
+	}
+
+	l.total++
+	l.perIP[ip]++
+	l.active.Set(float64(l.total))
+	return ""
+}
+
+// release frees a slot previously reserved by acquire.
+func (l *connLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	if l.perIP[ip]--; l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+	l.active.Set(float64(l.total))
+}
+
+// ClientIP returns the address used for per-IP limits, here and for the
+// registration and login challenges. Proxy headers are only honored when
+// trustProxy is set — otherwise any client could spoof them.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			// The left-most entry is the original client.
+			first, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(first)
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// count returns the number of reserved slots.
+func (l *connLimiter) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
@@ -0,0 +1,242 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"ofenes/internal/metrics"
+)
+
+// dropLogEvery throttles per-client drop logging: the first drop is always
+// logged, then every Nth, so a stuck client cannot flood the log.
+const dropLogEvery = 100
+
+// A connection counts as a reconnect if it presents a resume token, or if
+// the same user left the same room at most reconnectWindow earlier.
+const reconnectWindow = time.Minute
+
+// roomMetricsLinger is how long a room's series outlive its last client,
+// so a room emptied by a reconnecting member keeps its counters.
+const roomMetricsLinger = 5 * time.Minute
+
+// Histogram buckets: routing takes microseconds, round trips tens of
+// milliseconds, and send queues hold up to SendBufferSize plus the
+// overflow queue.
+var (
+	routeBuckets = []float64{0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1}
+	rttBuckets   = []float64{0.01, 0.025, 0.05, 0.1, 0.15, 0.25, 0.5, 1, 2.5}
+	depthBuckets = []float64{0, 1, 4, 16, 64, 256, 1024}
+)
+
+// hubMetrics instruments backpressure, routing, round trips and rooms in
+// the Hub.
+type hubMetrics struct {
+	dropped          *metrics.Counter
+	queueHighWater   *metrics.Gauge
+	clientHighWater  *metrics.Summary
+	broadcastLatency *metrics.Summary
+	deadLetters      *metrics.Counter
+	queueDepth       *metrics.Histogram
+
+	// routeLatency holds the routing histogram of each message type, and
+	// rooms the series of each room that has or recently had clients;
+	// both owned by the Hub goroutine.
+	routeLatency map[string]*metrics.Histogram
+	rooms        map[string]*roomMetrics
+
+	// departures holds when each user last left each room, by leaveKey,
+	// to tell reconnects; owned by the Hub goroutine.
+	departures map[string]time.Time
+}
+
+// roomMetrics are the series labeled with one room.
+type roomMetrics struct {
+	clients    *metrics.Gauge
+	queueDepth *metrics.Gauge
+	rtt        *metrics.Histogram
+	emptiedAt  time.Time // zero while the room has clients
+}
+
+// Room series, all labeled "room"; forgetRoomMetrics removes them.
+const (
+	metricRoomClients    = "ws_room_clients"
+	metricRoomQueueDepth = "ws_room_send_queue_depth"
+	metricRTT            = "ws_ping_rtt_seconds"
+	metricReconnects     = "ws_reconnects_total"
+)
+
+// Reconnect kinds, the "kind" label of ws_reconnects_total.
+const (
+	reconnectResume = "resume" // presented a resume token
+	reconnectReturn = "return" // left the room at most reconnectWindow earlier
+)
+
+func newHubMetrics(reg *metrics.Registry, policy SlowClientPolicy) hubMetrics {
+	return hubMetrics{
+		dropped: reg.Counter("ws_messages_dropped_total",
+			"Messages not delivered because a client's send queue was full.", "policy", string(policy)),
+		queueHighWater: reg.Gauge("ws_send_queue_high_water",
+			"Deepest send queue (Send + overflow) observed on any client since startup."),
+		clientHighWater: reg.Summary("ws_client_queue_high_water",
+			"Per-client peak send queue depth, observed when the client disconnects."),
+		broadcastLatency: reg.Summary("ws_broadcast_duration_seconds",
+			"Time to fan a message out to every client in a room."),
+		deadLetters: reg.Counter("ws_dead_letters_total",
+			"Messages the Hub could not route: invalid, of an unknown type or for a target that is not connected."),
+		queueDepth: reg.Histogram("ws_send_queue_depth",
+			"Send queue depths (Send + overflow) of every client, sampled at each housekeeping pass.", depthBuckets),
+		routeLatency: make(map[string]*metrics.Histogram),
+		rooms:        make(map[string]*roomMetrics),
+		departures:   make(map[string]time.Time),
+	}
+}
+
+// recordDepth updates the client's queue high-water mark after an enqueue.
+func (h *Hub) recordDepth(client *Client) {
+	client.mu.Lock()
+	depth := len(client.Send) + len(client.overflow)
+	client.mu.Unlock()
+
+	if depth > client.highWater {
+		client.highWater = depth
+		h.metrics.queueHighWater.SetMax(float64(depth))
+	}
+}
+
+// recordDrop counts an undelivered message and logs it (throttled per client).
+func (h *Hub) recordDrop(client *Client) {
+	client.dropped++
+	h.metrics.dropped.Inc()
+
+	if client.dropped == 1 || client.dropped%dropLogEvery == 0 {
+		log.Printf("ws: dropped message for slow client (user=%s, room=%s, policy=%s, dropped=%d, high_water=%d)",
+			client.Username, client.RoomID, h.opts.SlowClientPolicy, client.dropped, client.highWater)
+	}
+}
+
+// recordDisconnect observes the client's final queue statistics.
+func (h *Hub) recordDisconnect(client *Client) {
+	h.metrics.clientHighWater.Observe(float64(client.highWater))
+	if client.dropped > 0 {
+		log.Printf("ws: client queue stats (user=%s, room=%s, dropped=%d, high_water=%d)",
+			client.Username, client.RoomID, client.dropped, client.highWater)
+	}
+}
+
+// observeBroadcast records how long a room fan-out took.
+func (h *Hub) observeBroadcast(start time.Time) {
+	h.metrics.broadcastLatency.Observe(h.now().Sub(start).Seconds())
+}
+
+// observeRoute records how long routing a message of msgType took, from
+// parsing to the return of its handler. Types without a handler are
+// labeled "unknown", so clients can't create series.
+func (h *Hub) observeRoute(msgType string, start time.Time) {
+	if _, ok := h.handlers[msgType]; !ok {
+		msgType = "unknown"
+	}
+	hist, ok := h.metrics.routeLatency[msgType]
+	if !ok {
+		hist = h.opts.Metrics.Histogram("ws_route_duration_seconds",
+			"Time to route a client message, by type.", routeBuckets, "type", msgType)
+		h.metrics.routeLatency[msgType] = hist
+	}
+	hist.Observe(h.now().Sub(start).Seconds())
+}
+
+// roomSeries returns the series of room, creating them if needed.
+func (h *Hub) roomSeries(room string) *roomMetrics {
+	rm, ok := h.metrics.rooms[room]
+	if !ok {
+		reg := h.opts.Metrics
+		rm = &roomMetrics{
+			clients: reg.Gauge(metricRoomClients,
+				"Clients connected to each room, spectators included.", "room", room),
+			queueDepth: reg.Gauge(metricRoomQueueDepth,
+				"Deepest send queue (Send + overflow) in each room, sampled at each housekeeping pass.", "room", room),
+			rtt: reg.Histogram(metricRTT,
+				"Smoothed client round-trip times by room, observed at every answered keepalive probe.", rttBuckets, "room", room),
+		}
+		h.metrics.rooms[room] = rm
+	}
+	return rm
+}
+
+// recordConnect updates the room's series for a client that joined it,
+// counting it as a reconnect if it is one.
+func (h *Hub) recordConnect(client *Client) {
+	room := client.RoomID
+	rm := h.roomSeries(room)
+	rm.clients.Set(float64(len(h.clients[room])))
+	rm.emptiedAt = time.Time{}
+	client.rttSeries.Store(rm.rtt)
+
+	key := leaveKey(client.UserID, room)
+	left, returning := h.metrics.departures[key]
+	delete(h.metrics.departures, key)
+	kind := ""
+	switch {
+	case client.resumed:
+		kind = reconnectResume
+	case returning && h.now().Sub(left) <= reconnectWindow:
+		kind = reconnectReturn
+	default:
+		return
+	}
+	h.opts.Metrics.Counter(metricReconnects,
+		"Connections that resumed a rotated-out one or came back to a room soon after leaving it.", "room", room, "kind", kind).Inc()
+}
+
+// recordLeave updates the room's series for a client that left it.
+func (h *Hub) recordLeave(client *Client) {
+	room := client.RoomID
+	now := h.now()
+	h.metrics.departures[leaveKey(client.UserID, room)] = now
+	rm := h.roomSeries(room)
+	rm.clients.Set(float64(len(h.clients[room])))
+	if len(h.clients[room]) == 0 {
+		rm.queueDepth.Set(0)
+		rm.emptiedAt = now
+	}
+}
+
+// sampleMetrics records every client's send queue depth and each room's
+// deepest, and drops the series of rooms empty for roomMetricsLinger and
+// the departures too old to make a reconnect. Called at every
+// housekeeping pass.
+func (h *Hub) sampleMetrics() {
+	for room, roomClients := range h.clients {
+		deepest := 0
+		for client := range roomClients {
+			client.mu.Lock()
+			depth := len(client.Send) + len(client.overflow)
+			client.mu.Unlock()
+			h.metrics.queueDepth.Observe(float64(depth))
+			deepest = max(deepest, depth)
+		}
+		h.roomSeries(room).queueDepth.Set(float64(deepest))
+	}
+
+	now := h.now()
+	for room, rm := range h.metrics.rooms {
+		if !rm.emptiedAt.IsZero() && now.Sub(rm.emptiedAt) > roomMetricsLinger {
+			h.forgetRoomMetrics(room)
+		}
+	}
+	for key, left := range h.metrics.departures {
+		if now.Sub(left) > reconnectWindow {
+			delete(h.metrics.departures, key)
+		}
+	}
+}
+
+// forgetRoomMetrics removes the series of room from the registry.
+func (h *Hub) forgetRoomMetrics(room string) {
+	reg := h.opts.Metrics
+	delete(h.metrics.rooms, room)
+	reg.Remove(metricRoomClients, "room", room)
+	reg.Remove(metricRoomQueueDepth, "room", room)
+	reg.Remove(metricRTT, "room", room)
+	reg.Remove(metricReconnects, "room", room, "kind", reconnectResume)
+	reg.Remove(metricReconnects, "room", room, "kind", reconnectReturn)
+}
@@ -0,0 +1,148 @@
+package ws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// sanctions holds users muted or shadow-banned by moderators. It is
+// written from HTTP handlers and read by the Hub goroutine, so unlike the
+// rest of the Hub state it is guarded by a mutex.
+//
+// Mutes live only here: they are per instance and do not survive a
+// restart. Shadow bans are stored on the user and loaded at startup (see
+// SetShadowBanned).
+type sanctions struct {
+	mu           sync.Mutex
+	mutedUntil   map[string]time.Time // user ID -> end of mute
+	shadowBanned map[string]bool      // user IDs
+}
+
+func newSanctions() *sanctions {
+	return &sanctions{
+		mutedUntil:   make(map[string]time.Time),
+		shadowBanned: make(map[string]bool),
+	}
+}
+
+// muted returns when userID's mute ends, or false if not muted.
+// Expired mutes are dropped.
+func (s *sanctions) muted(userID string, now time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.mutedUntil[userID]
+	if ok && !now.Before(until) {
+		delete(s.mutedUntil, userID)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// isShadowBanned reports whether userID is shadow-banned.
+func (s *sanctions) isShadowBanned(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shadowBanned[userID]
+}
+
+// kickQueueSize bounds the disconnect requests waiting for the Hub goroutine.
+const kickQueueSize = 16
+
+// kick asks the Hub goroutine to close every connection of a user.
+type kick struct {
+	userID string
+	code   int
+}
+
+// Mute stops userID from sending chat messages until the given time, on
+// all of their connections. A zero time lifts the mute. Safe to call from
+// any goroutine.
+func (h *Hub) Mute(userID string, until time.Time) {
+	h.sanctions.mu.Lock()
+	defer h.sanctions.mu.Unlock()
+
+	if until.IsZero() {
+		delete(h.sanctions.mutedUntil, userID)
+		return
+	}
+	h.sanctions.mutedUntil[userID] = until
+}
+
+// SetShadowBanned shadow-bans userID or lifts the ban. A shadow-banned
+// user's chat messages are echoed back to their own connections in the
+// room, so they look sent, but nobody else receives them and they are not
+// persisted. The Hub does not load bans itself: call this at startup for
+// every shadow-banned user, and whenever a ban changes. Safe to call from
+// any goroutine.
+func (h *Hub) SetShadowBanned(userID string, banned bool) {
+	h.sanctions.mu.Lock()
+	defer h.sanctions.mu.Unlock()
+
+	if banned {
+		h.sanctions.shadowBanned[userID] = true
+	} else {
+		delete(h.sanctions.shadowBanned, userID)
+	}
+}
+
+// Disconnect closes every connection of userID with code (see
+// closecodes.go), e.g. CloseBanned after a ban. Safe to call from any
+// goroutine but the Hub's; the connections are closed shortly after it
+// returns. It waits for room in the queue rather than drop the request,
+// and returns at once once the Hub has stopped.
+func (h *Hub) Disconnect(userID string, code int) {
+	select {
+	case h.kick <- kick{userID: userID, code: code}:
+	case <-h.done:
+	}
+}
+
+// disconnectUser closes all of a user's connections.
+func (h *Hub) disconnectUser(k kick) {
+	var matched []*Client
+	for _, roomClients := range h.clients {
+		for client := range roomClients {
+			if client.UserID == k.userID {
+				matched = append(matched, client)
+			}
+		}
+	}
+
+	// Close after the loop: removal modifies h.clients.
+	for _, client := range matched {
+		h.closeClient(client, k.code)
+	}
+}
+
+// enforceMutes rejects chat messages from muted users.
+func (h *Hub) enforceMutes(next Handler) Handler {
+	return func(ctx *Context) {
+		if ctx.Message.Type == models.MsgTypeChat {
+			if until, ok := h.sanctions.muted(ctx.Client.UserID, h.now()); ok {
+				ctx.Reject(models.WSErrMuted, fmt.Sprintf("you are muted until %s", until.UTC().Format(time.RFC3339)))
+				return
+			}
+		}
+		next(ctx)
+	}
+}
+
+// echoToSender sends a message only to the sender's own connections in
+// the room. Used for shadow-banned users.
+func (h *Hub) echoToSender(ctx *Context) {
+	var slow []*Client
+	for client := range h.clients[ctx.Room] {
+		if client.UserID == ctx.Client.UserID && !h.send(client, ctx.Raw) {
+			slow = append(slow, client)
+		}
+	}
+
+	// Disconnect after the loop: removal modifies h.clients.
+	for _, client := range slow {
+		h.disconnectSlowClient(client)
+	}
+}
@@ -0,0 +1,109 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+)
+
+// notifyQueueSize bounds the notifications waiting for the Hub goroutine.
+const notifyQueueSize = 64
+
+// notification is a server message for a subset of connected users,
+// queued from outside the Hub goroutine.
+type notification struct {
+	data    []byte
+	admins  bool            // deliver to every client whose role has the reports.review permission
+	userIDs map[string]bool // and to these users
+	room    string          // and to everyone in this room
+}
+
+// NotifyModerators sends a "moderation" message to every connected user
+// whose role has the reports.review permission and to the users in
+// userIDs (typically those who moderate a room), on each of their
+// connections. Safe to call from any goroutine.
+//
+// Delivery is best effort: nobody may be online, and if the Hub is
+// backed up the notification is dropped rather than blocking the caller.
+func (h *Hub) NotifyModerators(event models.ModerationEvent, userIDs []string) {
+	n, ok := h.moderationNotification(event, userIDs)
+	if !ok {
+		return
+	}
+	select {
+	case h.notify <- n:
+	default:
+		log.Printf("ws: notification queue full, dropping %s event", event.Event)
+	}
+}
+
+// NotifyMedia sends a "media" message to everyone in the room of
+// event.Media. Safe to call from any goroutine; delivery is best effort,
+// as for NotifyModerators.
+func (h *Hub) NotifyMedia(event models.MediaEvent) {
+	payload, _ := json.Marshal(event)
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeMedia,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal media message: %v", err)
+		return
+	}
+	select {
+	case h.notify <- notification{data: data, room: event.Media.RoomID}:
+	default:
+		log.Printf("ws: notification queue full, dropping media %s event", event.Event)
+	}
+}
+
+// moderationNotification builds the "moderation" message for event,
+// addressed to admins and userIDs. Marshal failures are logged.
+func (h *Hub) moderationNotification(event models.ModerationEvent, userIDs []string) (notification, bool) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ws: failed to marshal moderation event: %v", err)
+		return notification{}, false
+	}
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeModeration,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal moderation message: %v", err)
+		return notification{}, false
+	}
+
+	n := notification{data: data, admins: true, userIDs: make(map[string]bool, len(userIDs))}
+	for _, id := range userIDs {
+		n.userIDs[id] = true
+	}
+	return n, true
+}
+
+// deliverNotification sends n to its recipients' connections.
+func (h *Hub) deliverNotification(n notification) {
+	var slow []*Client
+	for roomID, roomClients := range h.clients {
+		toRoom := n.room != "" && roomID == n.room
+		for client := range roomClients {
+			if !toRoom && !(n.admins && h.opts.Authz.Can(client.Role, authz.PermReportsReview)) && !n.userIDs[client.UserID] {
+				continue
+			}
+			if !h.send(client, n.data) {
+				slow = append(slow, client)
+			}
+		}
+	}
+
+	// Disconnect after the loop: removal modifies h.clients.
+	for _, client := range slow {
+		h.disconnectSlowClient(client)
+	}
+}
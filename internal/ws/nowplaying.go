@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ofenes/internal/models"
+)
+
+// Bots and rich presence integrations want to know what a room is
+// watching without following its video_sync messages. The Hub sends each
+// room a now_playing message (models.NowPlaying) about its main screen
+// when the video changes, starts or stops, and every NowPlayingInterval
+// while it plays; the NowPlaying listener gets each of them too, and one
+// without a URL once the room's playback is dropped. Title, thumbnail and
+// duration are whatever the controller put in its video_sync, kept until
+// the URL changes.
+
+const (
+	// nowPlayingQueueSize bounds the NowPlaying calls waiting for the Hub.
+	nowPlayingQueueSize = 16
+
+	// maxNowPlayingTitle caps the title passed on, in runes.
+	maxNowPlayingTitle = 200
+
+	// maxThumbnailURL caps the length of the thumbnail URL passed on.
+	maxThumbnailURL = 2048
+)
+
+// NowPlayingListener receives what each room's main screen plays, as sent
+// to the room (see models.NowPlaying). Methods are called on the Hub
+// goroutine and must not block.
+type NowPlayingListener interface {
+	NowPlaying(np models.NowPlaying)
+}
+
+// keepNowPlaying copies the title, thumbnail and duration of prev, the
+// screen's playback so far, into payload if both play the same video and
+// payload names none of them. It reports whether payload changed.
+func keepNowPlaying(prev syncState, had bool, payload *models.VideoSyncPayload) bool {
+	if !had || prev.payload.URL != payload.URL {
+		return false
+	}
+	if payload.Title != "" || payload.Thumbnail != "" || payload.Duration != 0 {
+		return false
+	}
+	p := prev.payload
+	if p.Title == "" && p.Thumbnail == "" && p.Duration == 0 {
+		return false
+	}
+	payload.Title, payload.Thumbnail, payload.Duration = p.Title, p.Thumbnail, p.Duration
+	return true
+}
+
+// nowPlayingChanged reports whether payload changes what prev, the main
+// screen's playback so far, played: another video, or started or stopped.
+func nowPlayingChanged(prev syncState, had bool, payload models.VideoSyncPayload) bool {
+	return !had || prev.payload.URL != payload.URL || prev.payload.Playing != payload.Playing
+}
+
+// nowPlayingOf returns what the main screen of room plays as of now, or
+// false if it has no playback.
+func (h *Hub) nowPlayingOf(room string, now time.Time) (models.NowPlaying, bool) {
+	state, ok := h.syncStates[room][""]
+	if !ok {
+		return models.NowPlaying{}, false
+	}
+	p := state.payload
+	np := models.NowPlaying{
+		RoomID:    room,
+		URL:       p.URL,
+		Title:     strings.TrimSpace(p.Title),
+		Position:  state.position(now),
+		Playing:   p.Playing,
+		UpdatedAt: now,
+	}
+	if utf8.RuneCountInString(np.Title) > maxNowPlayingTitle {
+		np.Title = string([]rune(np.Title)[:maxNowPlayingTitle])
+	}
+	if len(p.Thumbnail) <= maxThumbnailURL && (strings.HasPrefix(p.Thumbnail, "https://") || strings.HasPrefix(p.Thumbnail, "http://")) {
+		np.Thumbnail = p.Thumbnail
+	}
+	if p.Duration > 0 && !math.IsInf(p.Duration, 0) {
+		np.Duration = p.Duration
+		np.Position = min(np.Position, np.Duration)
+	}
+	return np, true
+}
+
+// announceNowPlaying sends room, and the NowPlaying listener, what its
+// main screen plays.
+func (h *Hub) announceNowPlaying(room string) {
+	np, ok := h.nowPlayingOf(room, h.now())
+	if !ok {
+		return
+	}
+	if h.opts.NowPlaying != nil {
+		h.opts.NowPlaying.NowPlaying(np)
+	}
+	payload, _ := json.Marshal(np)
+	msg, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeNowPlaying,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: np.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal now_playing: %v", err)
+		return
+	}
+	h.broadcastToRoom(room, msg)
+}
+
+// announceStopped tells the NowPlaying listener that nothing plays in
+// room any more, if its main screen had playback.
+func (h *Hub) announceStopped(room string) {
+	if h.opts.NowPlaying == nil {
+		return
+	}
+	if _, ok := h.syncStates[room][""]; !ok {
+		return
+	}
+	h.opts.NowPlaying.NowPlaying(models.NowPlaying{RoomID: room, UpdatedAt: h.now()})
+}
+
+// reportNowPlaying announces what plays in every room whose main screen
+// is playing.
+func (h *Hub) reportNowPlaying() {
+	for room := range h.clients {
+		if state, ok := h.syncStates[room][""]; ok && state.payload.Playing {
+			h.announceNowPlaying(room)
+		}
+	}
+}
+
+// nowPlayingTicker returns a ticker for periodic now_playing messages, or
+// a nil channel if they are disabled.
+func (h *Hub) nowPlayingTicker() (<-chan time.Time, func()) {
+	if h.opts.NowPlayingInterval <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(h.opts.NowPlayingInterval)
+	return t.C, t.Stop
+}
+
+// NowPlaying returns what the main screen of each room with playback
+// plays, for GET /api/now-playing: room's only if room is not empty,
+// sorted by room. Safe to call from any goroutine; like Connections, it
+// waits for the Hub, and returns ctx.Err() if ctx is done first and nil
+// once the Hub has stopped.
+func (h *Hub) NowPlaying(ctx context.Context, room string) ([]models.NowPlaying, error) {
+	req := nowPlayingRequest{room: room, reply: make(chan []models.NowPlaying, 1)}
+	select {
+	case h.nowPlaying <- req:
+	case <-h.done:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case list := <-req.reply:
+		return list, nil
+	case <-h.done:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// nowPlayingRequest is a pending NowPlaying call.
+type nowPlayingRequest struct {
+	room  string
+	reply chan []models.NowPlaying
+}
+
+// listNowPlaying answers a NowPlaying call.
+func (h *Hub) listNowPlaying(req nowPlayingRequest) {
+	list := []models.NowPlaying{}
+	now := h.now()
+	for room := range h.syncStates {
+		if req.room != "" && room != req.room {
+			continue
+		}
+		if np, ok := h.nowPlayingOf(room, now); ok {
+			list = append(list, np)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].RoomID < list[j].RoomID })
+	req.reply <- list
+}
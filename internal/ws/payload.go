@@ -0,0 +1,129 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// Inbound is a raw message read from a client, queued for the Hub.
+type Inbound struct {
+	Client *Client
+	Data   []byte
+}
+
+// DefaultPayloadLimits caps the payload size (in bytes) of each message type.
+// Types not listed are bounded only by the connection read limit.
+var DefaultPayloadLimits = map[string]int{
+	models.MsgTypeChat:          2048,
+	models.MsgTypeVideoSync:     8192, // includes the video and thumbnail URLs
+	models.MsgTypeAdmin:         4096,
+	models.MsgTypeCoHost:        256,
+	models.MsgTypePlaybackStats: 256,
+	models.MsgTypePosition:      128,
+	models.MsgTypeWebRTC:        65536, // SDP offers with many candidates
+}
+
+// ParsePayloadLimits parses a "type=bytes,type=bytes" list from config.
+// Listed types override DefaultPayloadLimits; a limit of 0 removes the cap.
+func ParsePayloadLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int, len(DefaultPayloadLimits))
+	for t, n := range DefaultPayloadLimits {
+		limits[t] = n
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		msgType, size, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("ws: invalid payload limit %q (want type=bytes)", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("ws: invalid payload limit %q", entry)
+		}
+		if n == 0 {
+			delete(limits, strings.TrimSpace(msgType))
+			continue
+		}
+		limits[strings.TrimSpace(msgType)] = n
+	}
+	return limits, nil
+}
+
+// checkPayloadSize rejects msg if its payload exceeds the per-type limit,
+// replying to the sender with a payload_too_large error.
+func (h *Hub) checkPayloadSize(client *Client, msg models.Message) bool {
+	limit, ok := h.opts.PayloadLimits[msg.Type]
+	if !ok || len(msg.Payload) <= limit {
+		return true
+	}
+
+	if !h.strike(client) {
+		return false
+	}
+	log.Printf("ws: payload too large (user=%s, type=%s, size=%d, limit=%d)",
+		client.Username, msg.Type, len(msg.Payload), limit)
+	h.sendError(client, models.ErrorPayload{
+		Code:    models.WSErrPayloadTooLarge,
+		Message: fmt.Sprintf("%s payload exceeds %d bytes", msg.Type, limit),
+		RefType: msg.Type,
+		RefID:   msg.ID,
+		Limit:   limit,
+	})
+	return false
+}
+
+// Clients that get more than policyStrikeLimit messages rejected within
+// policyStrikeWindow are closed with ClosePolicyViolation.
+const (
+	policyStrikeLimit  = 50
+	policyStrikeWindow = time.Minute
+)
+
+// strike records a rejected message. It returns false, after closing the
+// client, once the client has exceeded the strike limit.
+func (h *Hub) strike(client *Client) bool {
+	now := h.now()
+	if now.Sub(client.strikeStart) > policyStrikeWindow {
+		client.strikeStart = now
+		client.strikes = 0
+	}
+	client.strikes++
+
+	if client.strikes > policyStrikeLimit {
+		log.Printf("ws: closing client for policy violations (user=%s, rejected=%d in %s)",
+			client.Username, client.strikes, now.Sub(client.strikeStart).Round(time.Second))
+		h.closeClient(client, ClosePolicyViolation)
+		return false
+	}
+	return true
+}
+
+// sendError delivers an error message to a single client.
+func (h *Hub) sendError(client *Client, e models.ErrorPayload) {
+	payload, _ := json.Marshal(e)
+
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeError,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal error message: %v", err)
+		return
+	}
+
+	if !h.send(client, data) {
+		h.disconnectSlowClient(client)
+	}
+}
@@ -0,0 +1,38 @@
+package ws
+
+import (
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+)
+
+// messagePermissions maps message types to the permission (authz.Perm*)
+// needed to send them. Types not listed need none.
+var messagePermissions = map[string]string{
+	models.MsgTypeChat:  authz.PermChatSend,
+	models.MsgTypeAdmin: authz.PermBroadcast,
+}
+
+// roomMessagePermissions maps message types to the room permission
+// (authz.RoomPerm*) needed to send them in a room with roles, on top of
+// messagePermissions. video_sync needs the permission of the screen it
+// controls, checked by handleVideoSync.
+var roomMessagePermissions = map[string]string{
+	models.MsgTypeChat: authz.RoomPermChat,
+}
+
+// requirePermission rejects messages the sender's role, or their role in
+// the room, may not send. Permissions are looked up on every message, so
+// changes to a role apply to connected clients at once.
+func (h *Hub) requirePermission(next Handler) Handler {
+	return func(ctx *Context) {
+		if perm, ok := messagePermissions[ctx.Message.Type]; ok && !h.opts.Authz.Can(ctx.Client.Role, perm) {
+			ctx.Reject(models.WSErrForbidden, "sending "+ctx.Message.Type+" messages requires the "+perm+" permission")
+			return
+		}
+		if perm, ok := roomMessagePermissions[ctx.Message.Type]; ok && !h.roomCan(ctx.Client, perm) {
+			ctx.Reject(models.WSErrForbidden, "sending "+ctx.Message.Type+" messages requires the "+perm+" room permission")
+			return
+		}
+		next(ctx)
+	}
+}
@@ -0,0 +1,123 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// The playback of each stored room's main screen is saved with
+// RoomRepository.UpdateVideoState, so a restart, or the Hub forgetting a
+// room nobody is left in, doesn't lose what was playing and where. It is
+// saved on every change, and with its position brought up to date when
+// the room empties and when the Hub stops; the room's first client after
+// that gets it back. Saving happens on a goroutine of its own, in order,
+// so the Hub never waits for the database.
+
+const (
+	// videoSaveQueueSize bounds the saves waiting for the writer.
+	videoSaveQueueSize = 256
+
+	// videoSaveTimeout bounds one save.
+	videoSaveTimeout = 5 * time.Second
+)
+
+// videoSave is a queued save of a room's playback.
+type videoSave struct {
+	roomID string
+	state  models.VideoState
+}
+
+// saveVideoState queues the playback of room's main screen, as of now,
+// for saving. Saves are dropped with a log line if the writer is backed up.
+func (h *Hub) saveVideoState(room string) {
+	s, ok := h.videoSaveOf(room)
+	if !ok {
+		return
+	}
+	select {
+	case h.videoSaves <- s:
+	default:
+		log.Printf("ws: video state queue full, not saving room %s", room)
+	}
+}
+
+// saveAllVideoStates queues the playback of every room for saving,
+// waiting for room in the queue; called as the Hub stops.
+func (h *Hub) saveAllVideoStates() {
+	for room := range h.syncStates {
+		if s, ok := h.videoSaveOf(room); ok {
+			h.videoSaves <- s
+		}
+	}
+}
+
+// videoSaveOf returns the save of room's main screen as of now, or false
+// if there is nothing to save.
+func (h *Hub) videoSaveOf(room string) (videoSave, bool) {
+	if h.opts.Rooms == nil {
+		return videoSave{}, false
+	}
+	state, ok := h.syncStates[room][""]
+	if !ok {
+		return videoSave{}, false
+	}
+	return videoSave{roomID: room, state: models.VideoState{
+		URL:       state.payload.URL,
+		Playing:   state.payload.Playing,
+		Timestamp: state.position(h.now()),
+		Subtitle:  state.payload.Subtitle,
+	}}, true
+}
+
+// videoStateWriter saves queued playback until the queue is closed.
+// Rooms that are not stored (ErrNotFound) are skipped silently.
+func (h *Hub) videoStateWriter() {
+	defer h.writers.Done()
+	for s := range h.videoSaves {
+		ctx, cancel := context.WithTimeout(context.Background(), videoSaveTimeout)
+		err := h.opts.Rooms.UpdateVideoState(ctx, s.roomID, s.state)
+		cancel()
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("ws: failed to save video state of room %s: %v", s.roomID, err)
+		}
+	}
+}
+
+// restoreVideoState gives client's room back the playback saved for it,
+// if the Hub has none: client is the first to join since the room was
+// forgotten. A video that was playing picks up where it was saved.
+func (h *Hub) restoreVideoState(client *Client) {
+	room := client.RoomID
+	saved := client.savedVideo
+	if saved == nil || saved.URL == "" {
+		return
+	}
+	if _, ok := h.lastVideoState[room][""]; ok {
+		return
+	}
+
+	payload := models.VideoSyncPayload{
+		Event:       models.VideoEventPause,
+		URL:         saved.URL,
+		Playing:     saved.Playing,
+		Timestamp:   saved.Timestamp,
+		Subtitle:    saved.Subtitle,
+		TriggeredBy: "system",
+	}
+	if saved.Playing {
+		payload.Event = models.VideoEventPlay
+	}
+	now := h.now()
+	msg, ok := systemVideoSync(payload, now)
+	if !ok {
+		return
+	}
+	h.setVideoState(room, "", syncState{payload: payload, at: now}, msg)
+	h.announceNowPlaying(room)
+	log.Printf("ws: restored video state of room %s at %.1fs", room, saved.Timestamp)
+}
@@ -0,0 +1,146 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// Context carries one inbound message through the pre-route hooks to its handler.
+type Context struct {
+	Hub     *Hub
+	Client  *Client
+	Room    string
+	Message models.Message
+
+	// Raw is the message as it will be broadcast. Hooks that change Message
+	// should call SetMessage to keep the two in sync.
+	Raw []byte
+}
+
+// SetMessage replaces the message and re-encodes Raw.
+func (ctx *Context) SetMessage(msg models.Message) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx.Message, ctx.Raw = msg, raw
+	return nil
+}
+
+// Reject replies to the sender with an error message. The hook or handler
+// should return without calling next.
+func (ctx *Context) Reject(code, message string) {
+	ctx.Hub.recordDeadLetter(ctx.Client, ctx.Raw, &ctx.Message, code, message)
+	ctx.Hub.sendError(ctx.Client, models.ErrorPayload{
+		Code:    code,
+		Message: message,
+		RefType: ctx.Message.Type,
+		RefID:   ctx.Message.ID,
+	})
+}
+
+// Handler processes an inbound message.
+type Handler func(ctx *Context)
+
+// Middleware wraps a Handler — the same shape as net/http middleware. Call
+// next(ctx) to continue, or return to drop the message.
+type Middleware func(next Handler) Handler
+
+// BroadcastFunc delivers data to every client in room.
+type BroadcastFunc func(room string, data []byte)
+
+// BroadcastMiddleware wraps a BroadcastFunc. It sees every room broadcast,
+// including system messages, and may rewrite or drop it.
+type BroadcastMiddleware func(next BroadcastFunc) BroadcastFunc
+
+// UsePreRoute adds hooks that run on every parsed inbound message before it
+// is routed, in registration order. Hooks run on the Hub goroutine and must
+// not block. Register hooks before calling Run.
+func (h *Hub) UsePreRoute(mw ...Middleware) {
+	h.preRoute = append(h.preRoute, mw...)
+	h.route = h.dispatch
+	for i := len(h.preRoute) - 1; i >= 0; i-- {
+		h.route = h.preRoute[i](h.route)
+	}
+}
+
+// UsePreBroadcast adds hooks that run on every room broadcast before fan-out,
+// in registration order. Hooks run on the Hub goroutine and must not block.
+// Register hooks before calling Run.
+func (h *Hub) UsePreBroadcast(mw ...BroadcastMiddleware) {
+	h.preBroadcast = append(h.preBroadcast, mw...)
+	h.broadcast = h.deliverToRoom
+	for i := len(h.preBroadcast) - 1; i >= 0; i-- {
+		h.broadcast = h.preBroadcast[i](h.broadcast)
+	}
+}
+
+// --- Built-in pre-route hooks ---
+
+// limitPayload enforces Options.PayloadLimits.
+func (h *Hub) limitPayload(next Handler) Handler {
+	return func(ctx *Context) {
+		if h.checkPayloadSize(ctx.Client, ctx.Message) {
+			next(ctx)
+		}
+	}
+}
+
+// limitRate enforces Options.RateLimits.
+func (h *Hub) limitRate(next Handler) Handler {
+	return func(ctx *Context) {
+		if h.checkRate(ctx.Client, ctx.Message) {
+			next(ctx)
+		}
+	}
+}
+
+// stampMessage sets the sender of every message to the connection's
+// authenticated user, so clients can't send as someone else, and its
+// timestamp and sequence number to the server's, so skewed client clocks
+// can't reorder a room. It runs after the limits, so dead letters of
+// limited messages keep what the client sent.
+func (h *Hub) stampMessage(next Handler) Handler {
+	return func(ctx *Context) {
+		msg := ctx.Message
+		msg.Sender, msg.SenderID = ctx.Client.Username, ctx.Client.UserID
+		msg.Timestamp = h.now()
+		msg.Seq = h.nextSeq(ctx.Room, msg.Timestamp)
+		if err := ctx.SetMessage(msg); err != nil {
+			log.Printf("ws: failed to re-encode message (user=%s): %v", ctx.Client.Username, err)
+			return
+		}
+		next(ctx)
+	}
+}
+
+// nextSeq returns the next sequence number of room. A room's sequence
+// starts from the time in microseconds, so it keeps increasing after the
+// room empties or the server restarts, and stays exact in JavaScript.
+func (h *Hub) nextSeq(room string, now time.Time) int64 {
+	seq, ok := h.seqs[room]
+	if ok {
+		seq++
+	} else {
+		seq = now.UnixMicro()
+	}
+	h.seqs[room] = seq
+	return seq
+}
+
+// trackActivity resets the idle timer for every message except heartbeats,
+// which only keep the connection alive (already recorded by readPump),
+// answer the last probe (see latency.go) and are not routed.
+func (h *Hub) trackActivity(next Handler) Handler {
+	return func(ctx *Context) {
+		if ctx.Message.Type == models.MsgTypeHeartbeat {
+			ctx.Client.probeAnswered()
+			return
+		}
+		ctx.Client.touch()
+		next(ctx)
+	}
+}
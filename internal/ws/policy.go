@@ -0,0 +1,155 @@
+package ws
+
+import (
+	"fmt"
+
+	"ofenes/internal/metrics"
+)
+
+// SlowClientPolicy decides what the Hub does when a client's Send buffer is full.
+type SlowClientPolicy string
+
+// Supported slow-client policies.
+const (
+	// PolicyDisconnect closes the connection with code 1013 (try again later).
+	PolicyDisconnect SlowClientPolicy = "disconnect"
+
+	// PolicyDropOldest discards the oldest queued message to make room.
+	PolicyDropOldest SlowClientPolicy = "drop_oldest"
+
+	// PolicyBuffer spills into a bounded per-client overflow queue and only
+	// disconnects once that queue is also full.
+	PolicyBuffer SlowClientPolicy = "buffer"
+)
+
+// ParseSlowClientPolicy validates a policy name from config.
+func ParseSlowClientPolicy(s string) (SlowClientPolicy, error) {
+	switch p := SlowClientPolicy(s); p {
+	case PolicyDisconnect, PolicyDropOldest, PolicyBuffer:
+		return p, nil
+	default:
+		return "", fmt.Errorf("ws: unknown slow client policy %q", s)
+	}
+}
+
+// policyMetrics counts slow-client events per policy.
+type policyMetrics struct {
+	disconnects *metrics.Counter
+	dropped     *metrics.Counter
+	buffered    *metrics.Counter
+}
+
+func newPolicyMetrics(reg *metrics.Registry, policy SlowClientPolicy) policyMetrics {
+	const name = "ws_slow_client_events_total"
+	const help = "Slow-client events by policy and action taken."
+	p := string(policy)
+	return policyMetrics{
+		disconnects: reg.Counter(name, help, "policy", p, "action", "disconnect"),
+		dropped:     reg.Counter(name, help, "policy", p, "action", "drop"),
+		buffered:    reg.Counter(name, help, "policy", p, "action", "buffer"),
+	}
+}
+
+// send queues a message for a client according to the Hub's slow-client policy.
+// It returns false if the client must be disconnected, which the caller does
+// on the Hub goroutine.
+//
+// One goroutine at a time sends to a given client: the Hub goroutine, or
+// during a sharded broadcast the worker given the client's shard set, while
+// the Hub goroutine waits for it (see shardPool). That is what lets the
+// per-client counters (highWater, dropped) go unlocked; client.mu only
+// guards the overflow queue, which the write pump drains concurrently. Only
+// the Hub goroutine closes client.Send, never during a broadcast.
+func (h *Hub) send(client *Client, message []byte) bool {
+	ok := h.enqueue(client, message)
+	if ok {
+		h.recordDepth(client)
+	}
+	return ok
+}
+
+// enqueue applies the slow-client policy without any bookkeeping.
+func (h *Hub) enqueue(client *Client, message []byte) bool {
+	switch h.opts.SlowClientPolicy {
+	case PolicyDropOldest:
+		for {
+			select {
+			case client.Send <- message:
+				return true
+			default:
+			}
+			// Make room by discarding the oldest queued message.
+			select {
+			case <-client.Send:
+				h.policyMetrics.dropped.Inc()
+				h.recordDrop(client)
+			default:
+			}
+		}
+
+	case PolicyBuffer:
+		client.mu.Lock()
+		defer client.mu.Unlock()
+
+		// Once anything has spilled over, keep spilling to preserve ordering.
+		if len(client.overflow) == 0 {
+			select {
+			case client.Send <- message:
+				return true
+			default:
+			}
+		}
+		if len(client.overflow) >= h.opts.OverflowQueueSize {
+			h.policyMetrics.disconnects.Inc()
+			h.recordDrop(client)
+			return false
+		}
+		client.overflow = append(client.overflow, message)
+		h.policyMetrics.buffered.Inc()
+		select {
+		case client.wake <- struct{}{}:
+		default:
+		}
+		return true
+
+	default:
+		select {
+		case client.Send <- message:
+			return true
+		default:
+			h.policyMetrics.disconnects.Inc()
+			h.recordDrop(client)
+			return false
+		}
+	}
+}
+
+// disconnectSlowClient removes a client whose buffer overflowed, telling it
+// to retry later rather than reconnecting immediately.
+func (h *Hub) disconnectSlowClient(client *Client) {
+	h.closeClient(client, CloseSlowClient)
+}
+
+// drainOverflow returns everything queued for the client, Send backlog first,
+// then the overflow queue. closed reports whether Send has been closed.
+func (c *Client) drainOverflow() (messages [][]byte, closed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		select {
+		case m, ok := <-c.Send:
+			if !ok {
+				return messages, true
+			}
+			messages = append(messages, m)
+			continue
+		default:
+		}
+		break
+	}
+
+	messages = append(messages, c.overflow...)
+	c.overflow = nil
+	return messages, false
+}
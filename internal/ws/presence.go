@@ -0,0 +1,136 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ofenes/internal/repository"
+)
+
+// Who is connected to which room is also written to Options.Presence, so
+// it can be read outside this process: with the Redis stores, by every
+// instance. A user is touched when their first connection to a room
+// registers and again every presenceRefresh while they stay, and removed
+// when their last connection to it goes. The entries of an instance that
+// dies without removing them expire after presenceTTL. Writing happens on a
+// goroutine of its own, in order, so the Hub never waits for the store.
+
+const (
+	// presenceTTL is how long an entry lasts without being touched again.
+	presenceTTL = time.Minute
+
+	// presenceRefresh is how often the entries of connected users are
+	// touched again; the housekeeping pass does it once this has elapsed.
+	presenceRefresh = presenceTTL / 3
+
+	// presenceQueueSize bounds the writes waiting for the writer.
+	presenceQueueSize = 1024
+
+	// presenceTimeout bounds one write.
+	presenceTimeout = 5 * time.Second
+)
+
+// presenceWrite is a queued touch (present) or removal of a user's
+// presence in a room.
+type presenceWrite struct {
+	roomID   string
+	username string
+	present  bool
+}
+
+// enterPresence counts client's connection towards its user's presence in
+// its room, touching the entry on the first one.
+func (h *Hub) enterPresence(client *Client) {
+	if h.opts.Presence == nil {
+		return
+	}
+	room := client.RoomID
+	if h.present[room] == nil {
+		h.present[room] = make(map[string]int)
+	}
+	h.present[room][client.Username]++
+	if h.present[room][client.Username] == 1 {
+		h.writePresence(presenceWrite{roomID: room, username: client.Username, present: true})
+	}
+}
+
+// leavePresence uncounts client's connection, removing its user's entry
+// once it was the last one in the room.
+func (h *Hub) leavePresence(client *Client) {
+	if h.opts.Presence == nil {
+		return
+	}
+	room := client.RoomID
+	users := h.present[room]
+	if users[client.Username] == 0 {
+		return
+	}
+	if users[client.Username]--; users[client.Username] > 0 {
+		return
+	}
+	delete(users, client.Username)
+	if len(users) == 0 {
+		delete(h.present, room)
+	}
+	h.writePresence(presenceWrite{roomID: room, username: client.Username})
+}
+
+// refreshPresence touches the entry of every connected user again, if
+// presenceRefresh has elapsed since the last time. Called on every
+// housekeeping pass.
+func (h *Hub) refreshPresence() {
+	if h.opts.Presence == nil {
+		return
+	}
+	now := h.now()
+	if now.Sub(h.presenceRefreshed) < presenceRefresh {
+		return
+	}
+	h.presenceRefreshed = now
+	for room, users := range h.present {
+		for username := range users {
+			h.writePresence(presenceWrite{roomID: room, username: username, present: true})
+		}
+	}
+}
+
+// writePresence queues w. Writes are dropped with a log line if the writer
+// is backed up; a dropped touch is made up for by the next refresh, a
+// dropped removal by the entry expiring.
+func (h *Hub) writePresence(w presenceWrite) {
+	select {
+	case h.presenceWrites <- w:
+	default:
+		log.Printf("ws: presence queue full, dropping update of %s in room %s", w.username, w.roomID)
+	}
+}
+
+// removeAllPresence queues the removal of every entry, waiting for room in
+// the queue; called as the Hub stops.
+func (h *Hub) removeAllPresence() {
+	for room, users := range h.present {
+		for username := range users {
+			h.presenceWrites <- presenceWrite{roomID: room, username: username}
+		}
+	}
+	h.present = make(map[string]map[string]int)
+}
+
+// presenceWriter applies queued writes until the queue is closed.
+func (h *Hub) presenceWriter(store repository.PresenceRepository) {
+	defer h.writers.Done()
+	for w := range h.presenceWrites {
+		ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+		var err error
+		if w.present {
+			err = store.Touch(ctx, w.roomID, w.username, presenceTTL)
+		} else {
+			err = store.Remove(ctx, w.roomID, w.username)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("ws: failed to update presence of %s in room %s: %v", w.username, w.roomID, err)
+		}
+	}
+}
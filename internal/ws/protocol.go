@@ -0,0 +1,52 @@
+package ws
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Wire-format subprotocols, negotiated via Sec-WebSocket-Protocol.
+//
+// A new wire format gets a new name (e.g. "ofenes.v2.json") so old and new
+// frontends can be served side by side while clients migrate.
+const (
+	// ProtocolJSONv1 is the JSON Message envelope defined in internal/models.
+	ProtocolJSONv1 = "ofenes.v1.json"
+)
+
+// SupportedProtocols lists the subprotocols the server speaks, in order of preference.
+var SupportedProtocols = []string{ProtocolJSONv1}
+
+// negotiateProtocol picks the subprotocol for an upgrade request.
+//
+// Clients that request no subprotocol (legacy frontends) get "" and are
+// treated as ProtocolJSONv1. Clients that only request protocols the server
+// does not know are rejected (ok=false).
+func negotiateProtocol(r *http.Request) (protocol string, ok bool) {
+	requested := requestedProtocols(r)
+	if len(requested) == 0 {
+		return "", true
+	}
+
+	for _, supported := range SupportedProtocols {
+		for _, p := range requested {
+			if p == supported {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+// requestedProtocols parses the client's Sec-WebSocket-Protocol header(s).
+func requestedProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(header, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
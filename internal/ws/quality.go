@@ -0,0 +1,176 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+)
+
+// QualityMode decides what the Hub does with the "playback_stats" reports
+// of clients, so a member on a slow connection does not silently fall
+// behind the room.
+type QualityMode string
+
+// Supported quality modes.
+const (
+	// QualitySuggest flags struggling members like QualityFlag, and
+	// suggests to the whole room a maximum bitrate its slowest member can
+	// keep up with, for players to pick their quality under.
+	QualitySuggest QualityMode = "suggest"
+
+	// QualityFlag tells those who control a room's playback when a member
+	// starts or stops struggling, so they can pause or pick another source.
+	QualityFlag QualityMode = "flag"
+
+	// QualityOff ignores playback: "playback_stats" is an unknown type.
+	QualityOff QualityMode = "off"
+)
+
+// ParseQualityMode validates a quality mode name from config.
+func ParseQualityMode(s string) (QualityMode, error) {
+	switch m := QualityMode(s); m {
+	case QualitySuggest, QualityFlag, QualityOff:
+		return m, nil
+	default:
+		return "", fmt.Errorf("ws: unknown quality mode %q", s)
+	}
+}
+
+const (
+	// qualityReportTTL is how long a report counts: clients report every
+	// few seconds while they play, and stop when they pause.
+	qualityReportTTL = 30 * time.Second
+
+	// lowBuffer is the buffer, in seconds, below which a player loading
+	// slower than it plays is struggling.
+	lowBuffer = 2.0
+
+	// bandwidthHeadroom is the share of a member's measured bandwidth the
+	// suggested bitrate may use.
+	bandwidthHeadroom = 0.8
+
+	// suggestionStep is the relative change a new suggestion must make to
+	// be sent, so measurement noise does not flip players back and forth.
+	suggestionStep = 0.2
+)
+
+// playbackReport is a client's last "playback_stats" report.
+type playbackReport struct {
+	stats      models.PlaybackStatsPayload
+	at         time.Time
+	struggling bool
+}
+
+// handlePlaybackStats records a client's report, flags it to the room's
+// playback controllers if it started or stopped struggling, and under
+// QualitySuggest updates the room's suggested bitrate.
+func (h *Hub) handlePlaybackStats(ctx *Context) {
+	var p models.PlaybackStatsPayload
+	if err := json.Unmarshal([]byte(ctx.Message.Payload), &p); err != nil ||
+		p.Bandwidth < 0 || p.Bitrate < 0 || p.Buffer < 0 || p.Stalls < 0 {
+		ctx.Reject(models.WSErrInvalidMessage, `playback_stats payload must be {"bandwidth": kbps, "bitrate": kbps, "buffer": seconds, "stalls": n}`)
+		return
+	}
+
+	client := ctx.Client
+	wasStruggling := client.playback != nil && client.playback.struggling
+	struggling := p.Stalls > 0 || (p.Buffer < lowBuffer && p.Bandwidth > 0 && p.Bandwidth < p.Bitrate)
+	client.playback = &playbackReport{stats: p, at: h.now(), struggling: struggling}
+
+	if struggling != wasStruggling {
+		event := models.QualityEvent{Event: models.QualityEventRecovered, UserID: client.UserID, Username: client.Username, Stats: &p}
+		if struggling {
+			event.Event = models.QualityEventStruggling
+		}
+		h.flagToControllers(client, event)
+	}
+	if h.opts.QualityMode == QualitySuggest {
+		h.suggestQuality(ctx.Room)
+	}
+}
+
+// flagToControllers sends event about member to the others in its room
+// who may control playback: the host and co-hosts, or everyone in rooms
+// without room roles.
+func (h *Hub) flagToControllers(member *Client, event models.QualityEvent) {
+	data, ok := h.qualityMessage(event)
+	if !ok {
+		return
+	}
+	var slow []*Client
+	for client := range h.clients[member.RoomID] {
+		if client.UserID == member.UserID || !h.roomCan(client, authz.RoomPermVideoControl) {
+			continue
+		}
+		if !h.send(client, data) {
+			slow = append(slow, client)
+		}
+	}
+
+	// Disconnect after the loop: removal modifies h.clients.
+	for _, client := range slow {
+		h.disconnectSlowClient(client)
+	}
+}
+
+// suggestQuality works out the bitrate room's slowest reporting member can
+// keep up with and sends it to the room if it moved by suggestionStep or
+// more. 0, no cap, is sent once nobody reports.
+func (h *Hub) suggestQuality(room string) {
+	limit := 0
+	now := h.now()
+	for client := range h.clients[room] {
+		r := client.playback
+		if r == nil || r.stats.Bandwidth == 0 || now.Sub(r.at) > qualityReportTTL {
+			continue
+		}
+		if sustainable := int(float64(r.stats.Bandwidth) * bandwidthHeadroom); limit == 0 || sustainable < limit {
+			limit = sustainable
+		}
+	}
+
+	old := h.suggestions[room]
+	if limit == old || (limit > 0 && old > 0 && math.Abs(float64(limit-old)) < float64(old)*suggestionStep) {
+		return
+	}
+	if limit == 0 {
+		delete(h.suggestions, room)
+	} else {
+		h.suggestions[room] = limit
+	}
+	if data, ok := h.qualityMessage(models.QualityEvent{Event: models.QualityEventSuggestion, MaxBitrate: limit}); ok {
+		h.broadcastToRoom(room, data)
+	}
+}
+
+// sendSuggestion sends a joining client its room's suggested bitrate, if
+// there is one. It reports false if the client was too slow to take it.
+func (h *Hub) sendSuggestion(client *Client) bool {
+	limit, ok := h.suggestions[client.RoomID]
+	if !ok {
+		return true
+	}
+	data, ok := h.qualityMessage(models.QualityEvent{Event: models.QualityEventSuggestion, MaxBitrate: limit})
+	return !ok || h.send(client, data)
+}
+
+// qualityMessage builds a "quality" message. Marshal failures are logged.
+func (h *Hub) qualityMessage(event models.QualityEvent) ([]byte, bool) {
+	payload, _ := json.Marshal(event)
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeQuality,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal quality message: %v", err)
+		return nil, false
+	}
+	return data, true
+}
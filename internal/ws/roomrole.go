@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// errNotInvited is returned by roomRole for private rooms the user is not
+// a member of.
+var errNotInvited = errors.New("ws: not a member of this private room")
+
+// roomRoleQueueSize bounds the room role changes waiting for the Hub goroutine.
+const roomRoleQueueSize = 64
+
+// roomRoleChange asks the Hub goroutine to apply a new room role to a
+// user's connections.
+type roomRoleChange struct {
+	roomID string
+	userID string
+	role   string // "" = removed from the room
+}
+
+// roomRole returns the room role userID connects to roomID with: their
+// membership role, or viewer for non-members of public rooms. Private and
+// direct rooms are for members only (errNotInvited), unless globalRole
+// has the rooms.moderate permission. Rooms that are not stored, such as
+// the default "general" room, and Hubs without Options.Rooms have no room
+// roles: the role is "" and nothing is restricted.
+func (h *Hub) roomRole(ctx context.Context, roomID, userID, globalRole string) (string, error) {
+	if h.opts.Rooms == nil {
+		return "", nil
+	}
+	role, err := h.opts.Rooms.GetMemberRole(ctx, roomID, userID)
+	if err == nil {
+		return role, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return "", err
+	}
+
+	room, err := h.opts.Rooms.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	role = authz.NonMemberRoomRole(room.Type)
+	if role == "" {
+		if !h.opts.Authz.Can(globalRole, authz.PermRoomsModerate) {
+			return "", errNotInvited
+		}
+		role = models.RoomRoleViewer
+	}
+	return role, nil
+}
+
+// roomCan reports whether client may use the room permission perm in its
+// room: its room role grants it (as owner while it stands in as host; see
+// host.go), the room has no roles, or its global role has the
+// rooms.moderate permission.
+func (h *Hub) roomCan(client *Client, perm string) bool {
+	return client.RoomRole == "" ||
+		authz.RoomCan(h.roomRoleOf(client), perm) ||
+		h.opts.Authz.Can(client.Role, authz.PermRoomsModerate)
+}
+
+// SetRoomRole applies a changed room role to userID's connections to
+// roomID; call it after storing the change. An empty role means the user
+// was removed from the room: their connections to it are closed with
+// CloseKicked. Safe to call from any goroutine; like Disconnect, the
+// change is dropped with a log line if the Hub is backed up.
+func (h *Hub) SetRoomRole(roomID, userID, role string) {
+	select {
+	case h.roomRoles <- roomRoleChange{roomID: roomID, userID: userID, role: role}:
+	default:
+		log.Printf("ws: room role queue full, dropping change for user %s in room %s", userID, roomID)
+	}
+}
+
+// applyRoomRole updates the room role of a user's connections to a room
+// and tells the room with a "room_role" message.
+func (h *Hub) applyRoomRole(c roomRoleChange) {
+	var matched []*Client
+	for client := range h.clients[c.roomID] {
+		if client.UserID == c.userID {
+			matched = append(matched, client)
+		}
+	}
+
+	event := models.RoomRoleEvent{UserID: c.userID, Role: c.role}
+	if len(matched) > 0 {
+		event.Username = matched[0].Username
+	}
+	h.broadcastRoomRole(c.roomID, event)
+
+	// Close after the loop: removal modifies h.clients. Spectators made
+	// co-host or moderator take part at once.
+	for _, client := range matched {
+		if c.role == "" {
+			h.closeClient(client, CloseKicked)
+			continue
+		}
+		client.RoomRole = c.role
+		if client.spectator && h.mustParticipate(client) {
+			h.promote(client)
+		}
+	}
+	if len(matched) > 0 {
+		h.updateHost(c.roomID)
+	}
+}
+
+// broadcastRoomRole sends a "room_role" message to everyone in room.
+func (h *Hub) broadcastRoomRole(room string, event models.RoomRoleEvent) {
+	payload, _ := json.Marshal(event)
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeRoomRole,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal room role message: %v", err)
+		return
+	}
+	h.broadcastToRoom(room, data)
+}
+
+// handleCoHost grants or revokes co-host rights from within a session.
+// Only the room's owner may, or a role with the rooms.moderate permission;
+// a stand-in host (see host.go) controls playback but not roles. The
+// member must be connected to the room. The change is stored in the
+// background, then applied like SetRoomRole: the member's connections
+// follow at once and the room gets a "room_role" message.
+func (h *Hub) handleCoHost(ctx *Context) {
+	var p models.CoHostPayload
+	if err := json.Unmarshal([]byte(ctx.Message.Payload), &p); err != nil || p.UserID == "" {
+		ctx.Reject(models.WSErrInvalidMessage, `cohost payload must be {"userId": "...", "grant": true|false}`)
+		return
+	}
+	if h.opts.Rooms == nil || ctx.Client.RoomRole == "" {
+		ctx.Reject(models.WSErrForbidden, "this room has no room roles")
+		return
+	}
+	if ctx.Client.RoomRole != models.RoomRoleOwner && !h.opts.Authz.Can(ctx.Client.Role, authz.PermRoomsModerate) {
+		ctx.Reject(models.WSErrForbidden, "only the room's owner can grant or revoke co-host rights")
+		return
+	}
+	if p.UserID == ctx.Client.UserID {
+		ctx.Reject(models.WSErrForbidden, "you cannot change your own room role")
+		return
+	}
+
+	var target *Client
+	for client := range h.clients[ctx.Room] {
+		if client.UserID == p.UserID {
+			target = client
+			break
+		}
+	}
+	if target == nil {
+		ctx.Reject(models.WSErrInvalidMessage, "the user is not connected to this room")
+		return
+	}
+
+	role := models.RoomRoleMember
+	switch {
+	case p.Grant && target.RoomRole == models.RoomRoleCoHost, !p.Grant && target.RoomRole == models.RoomRoleMember:
+		return // nothing to change
+	case p.Grant && target.RoomRole != models.RoomRoleMember && target.RoomRole != models.RoomRoleModerator:
+		ctx.Reject(models.WSErrForbidden, "only members and moderators can be made co-hosts")
+		return
+	case p.Grant:
+		role = models.RoomRoleCoHost
+	case target.RoomRole != models.RoomRoleCoHost:
+		ctx.Reject(models.WSErrForbidden, "the user is not a co-host")
+		return
+	}
+
+	// Store off the Hub goroutine; SetRoomRole queues the change back.
+	room, userID := ctx.Room, target.UserID
+	go func() {
+		if err := h.opts.Rooms.SetMemberRole(context.Background(), room, userID, role); err != nil {
+			log.Printf("ws: failed to store room role (user=%s, room=%s): %v", userID, room, err)
+			return
+		}
+		h.SetRoomRole(room, userID, role)
+	}()
+}
@@ -0,0 +1,118 @@
+package ws
+
+import (
+	"log"
+	"sort"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+)
+
+// A room may have screens besides its main one (models.Screen), such as a
+// secondary stream next to the movie. Each has its own playback, kept and
+// replayed to late joiners like the main one's, and its own controllers:
+// video_sync, position and sync_rate messages name their screen in
+// "screen", "" or absent for the main one. video_sync to a screen the room
+// doesn't have is rejected.
+
+// screenQueueSize bounds the screen changes waiting for the Hub goroutine.
+const screenQueueSize = 64
+
+// screensChange asks the Hub goroutine to apply a room's new screens.
+type screensChange struct {
+	roomID  string
+	screens []models.Screen
+}
+
+// SetRoomScreens applies a room's changed screens to its open connections;
+// call it after storing the change. Playback on screens the room no longer
+// has is dropped. Safe to call from any goroutine; like SetRoomRole, the
+// change is dropped with a log line if the Hub is backed up.
+func (h *Hub) SetRoomScreens(roomID string, screens []models.Screen) {
+	select {
+	case h.screenChanges <- screensChange{roomID: roomID, screens: screens}:
+	default:
+		log.Printf("ws: screen queue full, dropping change for room %s", roomID)
+	}
+}
+
+// applyScreens records a room's screens while anyone is connected to it;
+// the next connection looks them up again.
+func (h *Hub) applyScreens(c screensChange) {
+	if _, ok := h.clients[c.roomID]; !ok {
+		return
+	}
+	h.setScreens(c.roomID, c.screens)
+	for screen := range h.syncStates[c.roomID] {
+		if _, ok := h.screenControl(c.roomID, screen); !ok {
+			h.dropScreen(c.roomID, screen)
+		}
+	}
+}
+
+// setScreens records room's screens besides the main one.
+func (h *Hub) setScreens(room string, screens []models.Screen) {
+	if len(screens) == 0 {
+		delete(h.screens, room)
+		return
+	}
+	byID := make(map[string]models.Screen, len(screens))
+	for _, s := range screens {
+		byID[s.ID] = s
+	}
+	h.screens[room] = byID
+}
+
+// screenControl returns the room permission needed to control screen in
+// room, or false if the room has no such screen.
+func (h *Hub) screenControl(room, screen string) (string, bool) {
+	if screen == "" {
+		return authz.RoomPermVideoControl, true
+	}
+	s, ok := h.screens[room][screen]
+	if !ok {
+		return "", false
+	}
+	if s.Control == "" {
+		return authz.RoomPermVideoControl, true
+	}
+	return s.Control, true
+}
+
+// setVideoState records raw, the video_sync message carrying state, as
+// the playback of screen in room.
+func (h *Hub) setVideoState(room, screen string, state syncState, raw []byte) {
+	if h.syncStates[room] == nil {
+		h.syncStates[room] = make(map[string]syncState)
+	}
+	if h.lastVideoState[room] == nil {
+		h.lastVideoState[room] = make(map[string][]byte)
+	}
+	h.syncStates[room][screen] = state
+	h.lastVideoState[room][screen] = raw
+}
+
+// dropScreen forgets the playback of screen in room.
+func (h *Hub) dropScreen(room, screen string) {
+	delete(h.syncStates[room], screen)
+	delete(h.lastVideoState[room], screen)
+}
+
+// videoScreens returns the screens of room with playback to replay, the
+// main one first.
+func (h *Hub) videoScreens(room string) []string {
+	screens := make([]string, 0, len(h.lastVideoState[room]))
+	for screen := range h.lastVideoState[room] {
+		screens = append(screens, screen)
+	}
+	sort.Strings(screens)
+	return screens
+}
+
+// screenLabel names screen of room in log lines.
+func screenLabel(room, screen string) string {
+	if screen == "" {
+		return room
+	}
+	return room + "/" + screen
+}
@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// shardJob asks a shard worker to deliver one message to its slice of a room.
+type shardJob struct {
+	clients map[*Client]bool
+	message []byte
+	slow    []*Client // filled by the worker
+	done    *sync.WaitGroup
+}
+
+// shardPool fans out large-room broadcasts across a fixed set of goroutines.
+//
+// Each room's clients are partitioned into one set per shard (see Hub.assignShard),
+// and a broadcast hands shard i its set. The Hub blocks until every shard is
+// done, so it remains the only goroutine that adds, removes or closes clients.
+type shardPool struct {
+	jobs []chan *shardJob
+}
+
+// newShardPool starts n shard workers delivering through hub.send.
+func newShardPool(h *Hub, n int) *shardPool {
+	p := &shardPool{jobs: make([]chan *shardJob, n)}
+	for i := range p.jobs {
+		ch := make(chan *shardJob)
+		p.jobs[i] = ch
+		go func() {
+			for job := range ch {
+				for client := range job.clients {
+					if !h.send(client, job.message) {
+						job.slow = append(job.slow, client)
+					}
+				}
+				job.done.Done()
+			}
+		}()
+	}
+	return p
+}
+
+// broadcast delivers message to every shard set in parallel and returns the
+// clients whose queues overflowed.
+func (p *shardPool) broadcast(sets []map[*Client]bool, message []byte) []*Client {
+	var wg sync.WaitGroup
+	jobs := make([]*shardJob, 0, len(sets))
+	for i, set := range sets {
+		if len(set) == 0 {
+			continue
+		}
+		job := &shardJob{clients: set, message: message, done: &wg}
+		jobs = append(jobs, job)
+		wg.Add(1)
+		p.jobs[i] <- job
+	}
+	wg.Wait()
+
+	var slow []*Client
+	for _, job := range jobs {
+		slow = append(slow, job.slow...)
+	}
+	return slow
+}
+
+// stop ends the shard workers. The pool must not be used afterwards.
+func (p *shardPool) stop() {
+	for _, ch := range p.jobs {
+		close(ch)
+	}
+}
+
+// shardIndex assigns a client to a shard by user ID, so all of a user's
+// connections land on the same worker.
+func (h *Hub) shardIndex(client *Client) int {
+	f := fnv.New32a()
+	f.Write([]byte(client.UserID))
+	return int(f.Sum32() % uint32(h.opts.ShardCount))
+}
+
+// assignShard adds a client to its room's shard set.
+func (h *Hub) assignShard(client *Client) {
+	sets := h.roomShards[client.RoomID]
+	if sets == nil {
+		sets = make([]map[*Client]bool, h.opts.ShardCount)
+		for i := range sets {
+			sets[i] = make(map[*Client]bool)
+		}
+		h.roomShards[client.RoomID] = sets
+	}
+	sets[h.shardIndex(client)][client] = true
+}
+
+// unassignShard removes a client from its room's shard set.
+func (h *Hub) unassignShard(client *Client) {
+	if sets := h.roomShards[client.RoomID]; sets != nil {
+		delete(sets[h.shardIndex(client)], client)
+	}
+}
+
+// isLargeRoom reports whether a room is big enough for throttled presence,
+// and for sharded fan-out if there is more than one shard.
+func (h *Hub) isLargeRoom(roomID string) bool {
+	return len(h.clients[roomID]) >= h.opts.ShardThreshold
+}
@@ -0,0 +1,146 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+)
+
+// Huge public parties stay stable by letting only SpectatorThreshold
+// connections of a room take part. Later joiners spectate: they get
+// everything sent to the room, but nobody is told they came or left, they
+// are not in its user_list, they are left out of WebRTC calls, and only
+// SpectatorChatRate of their chat per second, across the room, is let
+// through. Owners, co-hosts, moderators and roles with the rooms.moderate
+// permission always take part. When a participant leaves for good, the
+// spectator who has waited longest takes their place. Spectators are sent
+// a "spectator" message (models.SpectatorEvent) when they join as one and
+// when they are promoted.
+
+// mustParticipate reports whether client takes part in its room however
+// full it is.
+func (h *Hub) mustParticipate(client *Client) bool {
+	switch client.RoomRole {
+	case models.RoomRoleOwner, models.RoomRoleCoHost, models.RoomRoleModerator:
+		return true
+	}
+	return h.opts.Authz.Can(client.Role, authz.PermRoomsModerate)
+}
+
+// seat makes client, just added to its room, a spectator if the room
+// already has SpectatorThreshold participants.
+func (h *Hub) seat(client *Client) {
+	if h.opts.SpectatorThreshold <= 0 || h.mustParticipate(client) {
+		return
+	}
+	room := client.RoomID
+	participants := len(h.clients[room]) - 1 - h.spectators[room]
+	if participants < h.opts.SpectatorThreshold {
+		return
+	}
+	client.spectator = true
+	h.spectators[room]++
+}
+
+// unseat forgets client, just removed from its room, as a spectator.
+func (h *Hub) unseat(client *Client) {
+	room := client.RoomID
+	if h.spectators[room]--; h.spectators[room] <= 0 {
+		delete(h.spectators, room)
+	}
+}
+
+// promoteSpectator makes the spectator of room connected longest a
+// participant, announcing it like a join, after a participant left for
+// good.
+func (h *Hub) promoteSpectator(room string) {
+	if h.spectators[room] == 0 {
+		return
+	}
+	var next *Client
+	for client := range h.clients[room] {
+		if client.spectator && (next == nil || client.connectedAt.Before(next.connectedAt)) {
+			next = client
+		}
+	}
+	if next != nil {
+		h.promote(next)
+	}
+}
+
+// promote makes spectator client a participant.
+func (h *Hub) promote(client *Client) {
+	client.spectator = false
+	h.unseat(client)
+	log.Printf("ws: spectator promoted (user=%s, room=%s)", client.Username, client.RoomID)
+	if !h.sendSpectator(client) {
+		h.disconnectSlowClient(client)
+		return
+	}
+	h.broadcastSystemMessage(client.RoomID, "user_joined", client.UserID, client.Username)
+	h.listUser(client)
+}
+
+// sendSpectator tells client whether it spectates. It reports false if
+// the client's queue is full.
+func (h *Hub) sendSpectator(client *Client) bool {
+	payload, _ := json.Marshal(models.SpectatorEvent{
+		Spectator: client.spectator,
+		Chat:      h.opts.SpectatorChatRate > 0,
+		Threshold: h.opts.SpectatorThreshold,
+	})
+	data, err := json.Marshal(models.Message{
+		Type:      models.MsgTypeSpectator,
+		Sender:    "system",
+		Payload:   string(payload),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal spectator message: %v", err)
+		return true
+	}
+	return h.send(client, data)
+}
+
+// restrictSpectators keeps spectators out of WebRTC calls and lets through
+// only SpectatorChatRate of their chat per second, per room. It runs last,
+// so messages other hooks reject don't use up the sample.
+func (h *Hub) restrictSpectators(next Handler) Handler {
+	return func(ctx *Context) {
+		if !ctx.Client.spectator {
+			next(ctx)
+			return
+		}
+		switch ctx.Message.Type {
+		case models.MsgTypeWebRTC:
+			ctx.Reject(models.WSErrSpectator, "spectators can't join calls in this room")
+			return
+		case models.MsgTypeChat:
+			if h.opts.SpectatorChatRate <= 0 {
+				ctx.Reject(models.WSErrSpectator, "spectators can't chat in this room")
+				return
+			}
+			if !h.sampleSpectatorChat(ctx.Room) {
+				ctx.Reject(models.WSErrSpectator, "spectator chat is sampled in this room; this message was left out")
+				return
+			}
+		}
+		next(ctx)
+	}
+}
+
+// sampleSpectatorChat reports whether a spectator's chat message in room
+// makes it into the sample.
+func (h *Hub) sampleSpectatorChat(room string) bool {
+	rate := h.opts.SpectatorChatRate
+	limit := RateLimit{Rate: rate, Burst: max(int(rate), 1)}
+	now := h.now()
+	bucket := h.spectatorChat[room]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		h.spectatorChat[room] = bucket
+	}
+	return bucket.allow(limit, now)
+}
@@ -0,0 +1,136 @@
+package ws
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// RateLimit is a token-bucket limit: Rate messages per second sustained,
+// with bursts of up to Burst messages.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimits caps how fast a single client may send each message type.
+// Types not listed are unthrottled.
+var DefaultRateLimits = map[string]RateLimit{
+	models.MsgTypeChat:          {Rate: 1, Burst: 5},
+	models.MsgTypeVideoSync:     {Rate: 4, Burst: 8},
+	models.MsgTypeWebRTC:        {Rate: 50, Burst: 100}, // ICE candidates arrive in bursts
+	models.MsgTypeAdmin:         {Rate: 1, Burst: 2},
+	models.MsgTypeCoHost:        {Rate: 1, Burst: 3},
+	models.MsgTypePlaybackStats: {Rate: 1, Burst: 3},
+	models.MsgTypePosition:      {Rate: 1, Burst: 3},
+	models.MsgTypeActivity:      {Rate: 1, Burst: 2},
+	models.MsgTypeHeartbeat:     {Rate: 1, Burst: 3},
+}
+
+// ParseRateLimits parses a "type=rate[/burst],..." list from config, e.g.
+// "chat=1/5,video_sync=4". Listed types override DefaultRateLimits; a rate
+// of 0 removes the limit. Burst defaults to twice the rate (at least 1).
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit, len(DefaultRateLimits))
+	for t, l := range DefaultRateLimits {
+		limits[t] = l
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		msgType, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("ws: invalid rate limit %q (want type=rate[/burst])", entry)
+		}
+		msgType = strings.TrimSpace(msgType)
+
+		rateStr, burstStr, hasBurst := strings.Cut(strings.TrimSpace(spec), "/")
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("ws: invalid rate limit %q", entry)
+		}
+		if rate == 0 {
+			delete(limits, msgType)
+			continue
+		}
+
+		burst := max(int(rate*2), 1)
+		if hasBurst {
+			burst, err = strconv.Atoi(burstStr)
+			if err != nil || burst < 1 {
+				return nil, fmt.Errorf("ws: invalid burst in rate limit %q", entry)
+			}
+		}
+		limits[msgType] = RateLimit{Rate: rate, Burst: burst}
+	}
+	return limits, nil
+}
+
+// throttleNoticeEvery limits rate_limited error replies to one per type per
+// interval, so a flooding client isn't sent a reply for every dropped message.
+const throttleNoticeEvery = time.Second
+
+// tokenBucket is a per-client, per-type limiter. Owned by the Hub goroutine.
+type tokenBucket struct {
+	tokens     float64
+	last       time.Time
+	lastNotice time.Time
+}
+
+// allow refills the bucket and takes one token if available.
+func (b *tokenBucket) allow(l RateLimit, now time.Time) bool {
+	b.tokens = min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// checkRate enforces the per-type rate limit for msg, replying to the sender
+// with a rate_limited error (at most once per throttleNoticeEvery).
+func (h *Hub) checkRate(client *Client, msg models.Message) bool {
+	limit, ok := h.opts.RateLimits[msg.Type]
+	if !ok {
+		return true
+	}
+
+	now := h.now()
+	bucket := client.buckets[msg.Type]
+	if bucket == nil {
+		if client.buckets == nil {
+			client.buckets = make(map[string]*tokenBucket)
+		}
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		client.buckets[msg.Type] = bucket
+	}
+	if bucket.allow(limit, now) {
+		return true
+	}
+
+	h.opts.Metrics.Counter("ws_messages_throttled_total",
+		"Messages rejected by per-type rate limits.", "type", msg.Type).Inc()
+	if !h.strike(client) {
+		return false
+	}
+
+	if now.Sub(bucket.lastNotice) >= throttleNoticeEvery {
+		bucket.lastNotice = now
+		log.Printf("ws: rate limited (user=%s, type=%s, limit=%g/s)", client.Username, msg.Type, limit.Rate)
+		h.sendError(client, models.ErrorPayload{
+			Code:    models.WSErrRateLimited,
+			Message: fmt.Sprintf("too many %s messages, slow down", msg.Type),
+			RefType: msg.Type,
+			RefID:   msg.ID,
+		})
+	}
+	return false
+}
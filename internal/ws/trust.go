@@ -0,0 +1,30 @@
+package ws
+
+import (
+	"regexp"
+
+	"ofenes/internal/authz"
+	"ofenes/internal/models"
+	"ofenes/internal/trust"
+)
+
+// linkPattern recognizes links in chat: anything starting with a URL
+// scheme or "www.". Bare domains are not caught.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S`)
+
+// restrictLinks rejects chat messages containing links from users below
+// Options.LinkTrustLevel, unless their role has the trust.bypass
+// permission.
+func (h *Hub) restrictLinks(next Handler) Handler {
+	return func(ctx *Context) {
+		min := h.opts.LinkTrustLevel
+		if min != "" && ctx.Message.Type == models.MsgTypeChat &&
+			!h.opts.Authz.Can(ctx.Client.Role, authz.PermTrustBypass) &&
+			!trust.Allows(ctx.Client.TrustLevel, min) &&
+			linkPattern.MatchString(ctx.Message.Payload) {
+			ctx.Reject(models.WSErrTrustLevel, "posting links requires the "+min+" trust level")
+			return
+		}
+		next(ctx)
+	}
+}
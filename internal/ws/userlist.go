@@ -0,0 +1,181 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"ofenes/internal/models"
+)
+
+// Rooms learn who is connected from the usernames of their participants,
+// one per connection. Resending the whole list on every join and leave is
+// O(n) per change, and O(n²) during a reconnect storm, so the Hub sends a
+// joiner the full user_list and the room only the changes: joins and
+// leaves are gathered for UserListWindow (UserListInterval in large rooms)
+// and sent as one user_list_delta, where a connection that came and went
+// within it cancels out. Rooms whose members changed are sent the full
+// list again every UserListResync, so clients that went wrong
+// applying the changes recover.
+
+// userListRoom is the state of a room's user list.
+type userListRoom struct {
+	pending    map[string]int // net connections joined (+) or left (-) per username, not sent yet
+	since      time.Time      // when the oldest pending change was made
+	snapshotAt time.Time      // when the room was last sent its full list
+	changed    bool           // whether changes were sent since
+}
+
+// listUser adds client to its room's user list.
+func (h *Hub) listUser(client *Client) {
+	client.listed = true
+	h.userListChange(client.RoomID, client.Username, 1)
+}
+
+// unlistUser removes client from its room's user list, if it is on it.
+func (h *Hub) unlistUser(client *Client) {
+	if !client.listed {
+		return
+	}
+	client.listed = false
+	h.userListChange(client.RoomID, client.Username, -1)
+}
+
+// userListChange records n connections of username joining (or leaving,
+// if negative) room, sending the change at once if rooms of its size
+// don't gather them.
+func (h *Hub) userListChange(room, username string, n int) {
+	ul := h.userLists[room]
+	if ul == nil {
+		ul = &userListRoom{pending: make(map[string]int), snapshotAt: h.now()}
+		h.userLists[room] = ul
+	}
+	if len(ul.pending) == 0 {
+		ul.since = h.now()
+	}
+	if ul.pending[username] += n; ul.pending[username] == 0 {
+		delete(ul.pending, username)
+	}
+	if h.opts.UserListWindow <= 0 && !h.isLargeRoom(room) {
+		h.sendUserListDelta(room, ul)
+	}
+}
+
+// flushUserLists sends the changes gathered long enough, and the full list
+// to rooms due one. Called on every userListTicker tick.
+func (h *Hub) flushUserLists() {
+	now := h.now()
+	for room, ul := range h.userLists {
+		wait := h.opts.UserListWindow
+		if h.isLargeRoom(room) {
+			wait = h.opts.UserListInterval
+		}
+		if len(ul.pending) > 0 && now.Sub(ul.since) >= wait {
+			h.sendUserListDelta(room, ul)
+		}
+		if every := h.opts.UserListResync; every > 0 && ul.changed && now.Sub(ul.snapshotAt) >= every {
+			h.broadcastUserList(room, ul)
+		}
+	}
+}
+
+// userListTicker returns a ticker for flushUserLists: every UserListWindow
+// or UserListInterval, whichever is shorter.
+func (h *Hub) userListTicker() (<-chan time.Time, func()) {
+	interval := h.opts.UserListInterval
+	if h.opts.UserListWindow > 0 {
+		interval = min(interval, h.opts.UserListWindow)
+	}
+	t := time.NewTicker(interval)
+	return t.C, t.Stop
+}
+
+// sendUserListDelta sends room its pending changes as a user_list_delta.
+func (h *Hub) sendUserListDelta(room string, ul *userListRoom) {
+	if len(ul.pending) == 0 {
+		return
+	}
+	var delta models.UserListDelta
+	for username, n := range ul.pending {
+		for ; n > 0; n-- {
+			delta.Joined = append(delta.Joined, username)
+		}
+		for ; n < 0; n++ {
+			delta.Left = append(delta.Left, username)
+		}
+	}
+	sort.Strings(delta.Joined)
+	sort.Strings(delta.Left)
+	clear(ul.pending)
+	ul.changed = true
+
+	if data, ok := h.userListMessage(models.MsgTypeUserListDelta, delta); ok {
+		h.broadcastToRoom(room, data)
+	}
+}
+
+// broadcastUserList sends room its full user list, pending changes
+// included.
+func (h *Hub) broadcastUserList(room string, ul *userListRoom) {
+	clear(ul.pending)
+	ul.changed = false
+	ul.snapshotAt = h.now()
+	if data, ok := h.userListMessage(models.MsgTypeUserList, h.usernames(room)); ok {
+		h.broadcastToRoom(room, data)
+	}
+}
+
+// sendUserList sends client, just joined, its room's user list as the rest
+// of the room has it: without changes not sent yet, which it gets with the
+// room. It reports false if the client's queue is full.
+func (h *Hub) sendUserList(client *Client) bool {
+	usernames := h.usernames(client.RoomID)
+	if ul := h.userLists[client.RoomID]; ul != nil && len(ul.pending) > 0 {
+		counts := make(map[string]int, len(usernames))
+		for _, username := range usernames {
+			counts[username]++
+		}
+		for username, n := range ul.pending {
+			counts[username] -= n
+		}
+		usernames = usernames[:0]
+		for username, n := range counts {
+			for ; n > 0; n-- {
+				usernames = append(usernames, username)
+			}
+		}
+		sort.Strings(usernames)
+	}
+	data, ok := h.userListMessage(models.MsgTypeUserList, usernames)
+	return !ok || h.send(client, data)
+}
+
+// usernames returns the usernames of room's listed connections, sorted.
+func (h *Hub) usernames(room string) []string {
+	usernames := []string{}
+	for client := range h.clients[room] {
+		if client.listed {
+			usernames = append(usernames, client.Username)
+		}
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+// userListMessage builds a user_list or user_list_delta message. Marshal
+// failures are logged.
+func (h *Hub) userListMessage(msgType string, payload any) ([]byte, bool) {
+	body, _ := json.Marshal(payload)
+	data, err := json.Marshal(models.Message{
+		Type:      msgType,
+		Sender:    "system",
+		Payload:   string(body),
+		Timestamp: h.now(),
+	})
+	if err != nil {
+		log.Printf("ws: failed to marshal %s: %v", msgType, err)
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,187 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"ofenes/internal/models"
+	"ofenes/internal/repository"
+)
+
+// WordFilters is a compiled, immutable set of word filters. Build one with
+// NewWordFilters and install it with Hub.SetWordFilters.
+type WordFilters struct {
+	global []compiledFilter            // filters without a room
+	rooms  map[string][]compiledFilter // room ID -> effective filters, for rooms with overrides
+}
+
+// compiledFilter is a block or flag filter ready to match.
+type compiledFilter struct {
+	filter models.WordFilter
+	re     *regexp.Regexp
+}
+
+// CompileWordFilter compiles a filter's pattern. Matching is always
+// case-insensitive. A plain (non-regex) pattern matches as a literal
+// phrase, and only as whole words: "ass" does not match "class".
+func CompileWordFilter(f models.WordFilter) (*regexp.Regexp, error) {
+	if f.Regex {
+		return regexp.Compile("(?i)" + f.Pattern)
+	}
+	expr := regexp.QuoteMeta(f.Pattern)
+	if r, _ := utf8.DecodeRuneInString(f.Pattern); isWordRune(r) {
+		expr = `\b` + expr
+	}
+	if r, _ := utf8.DecodeLastRuneInString(f.Pattern); isWordRune(r) {
+		expr += `\b`
+	}
+	return regexp.Compile("(?i)" + expr)
+}
+
+// isWordRune reports whether r counts as a word character for \b.
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// NewWordFilters compiles filters. A room filter replaces the global
+// filter with the same Pattern and Regex in its room; WordFilterAllow
+// filters only do that and match nothing themselves.
+func NewWordFilters(filters []*models.WordFilter) (*WordFilters, error) {
+	type key struct {
+		pattern string
+		regex   bool
+	}
+	type roomOverrides struct {
+		filters []compiledFilter
+		keys    map[key]bool
+	}
+
+	wf := &WordFilters{rooms: make(map[string][]compiledFilter)}
+	overrides := make(map[string]*roomOverrides)
+	for _, f := range filters {
+		var c compiledFilter
+		if f.Action != models.WordFilterAllow {
+			re, err := CompileWordFilter(*f)
+			if err != nil {
+				return nil, fmt.Errorf("word filter %s: %w", f.ID, err)
+			}
+			c = compiledFilter{filter: *f, re: re}
+		}
+
+		if f.RoomID == "" {
+			if c.re != nil {
+				wf.global = append(wf.global, c)
+			}
+			continue
+		}
+		o, ok := overrides[f.RoomID]
+		if !ok {
+			o = &roomOverrides{keys: make(map[key]bool)}
+			overrides[f.RoomID] = o
+		}
+		o.keys[key{f.Pattern, f.Regex}] = true
+		if c.re != nil {
+			o.filters = append(o.filters, c)
+		}
+	}
+
+	for roomID, o := range overrides {
+		effective := o.filters
+		for _, c := range wf.global {
+			if !o.keys[key{c.filter.Pattern, c.filter.Regex}] {
+				effective = append(effective, c)
+			}
+		}
+		wf.rooms[roomID] = effective
+	}
+	return wf, nil
+}
+
+// Match returns the filter that content triggers in roomID, or nil. Block
+// filters take precedence over flag filters.
+func (wf *WordFilters) Match(roomID, content string) *models.WordFilter {
+	filters, ok := wf.rooms[roomID]
+	if !ok {
+		filters = wf.global
+	}
+
+	var flagged *models.WordFilter
+	for i := range filters {
+		c := &filters[i]
+		if !c.re.MatchString(content) {
+			continue
+		}
+		if c.filter.Action == models.WordFilterBlock {
+			return &c.filter
+		}
+		if flagged == nil {
+			flagged = &c.filter
+		}
+	}
+	return flagged
+}
+
+// SetWordFilters installs the filters checked against chat messages; nil
+// disables filtering. Takes effect with the next message. Safe to call
+// from any goroutine.
+func (h *Hub) SetWordFilters(wf *WordFilters) {
+	h.wordFilters.Store(wf)
+}
+
+// ReloadWordFilters loads every filter from repo and installs them. On
+// error the current filters stay in place. Safe to call from any goroutine.
+func (h *Hub) ReloadWordFilters(ctx context.Context, repo repository.WordFilterRepository) error {
+	filters, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	wf, err := NewWordFilters(filters)
+	if err != nil {
+		return err
+	}
+	h.SetWordFilters(wf)
+	return nil
+}
+
+// filterWords rejects chat messages that match a block filter and reports
+// messages that match a flag filter to connected users with the
+// reports.review permission.
+func (h *Hub) filterWords(next Handler) Handler {
+	return func(ctx *Context) {
+		wf := h.wordFilters.Load()
+		if wf == nil || ctx.Message.Type != models.MsgTypeChat {
+			next(ctx)
+			return
+		}
+
+		f := wf.Match(ctx.Room, ctx.Message.Payload)
+		if f == nil {
+			next(ctx)
+			return
+		}
+		if f.Action == models.WordFilterBlock {
+			ctx.Reject(models.WSErrBlockedContent, "message contains blocked content")
+			return
+		}
+
+		next(ctx)
+		n, ok := h.moderationNotification(models.ModerationEvent{
+			Event: models.ModEventMessageFlagged,
+			Flag: &models.FlaggedMessage{
+				RoomID:    ctx.Room,
+				SenderID:  ctx.Client.UserID,
+				Sender:    ctx.Client.Username,
+				Content:   ctx.Message.Payload,
+				FilterID:  f.ID,
+				Pattern:   f.Pattern,
+				Timestamp: h.now(),
+			},
+		}, nil)
+		if ok {
+			h.deliverNotification(n)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package apperr gives errors a kind, so each layer can wrap them with
+// context (fmt.Errorf with %w, or Wrap) while the HTTP layer still knows
+// what to answer.
+//
+// Repositories and services declare their sentinel errors with New, giving
+// each a Kind and, when clients should see something more precise than the
+// kind, an error code (see internal/i18n). Handlers answer any error with
+// one call: the kind picks the status and the code the message (see
+// response.StatusOf and response.CodeOf).
+//
+//	var ErrLost = apperr.New(apperr.Conflict, "transcode_job_lost", "transcode: job no longer held by this worker")
+//
+//	return fmt.Errorf("finish %s: %w", id, ErrLost)  // still a Conflict
+//	errors.Is(err, apperr.Conflict)                    // true
+//	errors.Is(err, ErrLost)                            // true
+package apperr
+
+import "errors"
+
+// Kind classifies an error by what the caller can do about it. A Kind is
+// itself an error, so errors.Is(err, apperr.NotFound) works.
+type Kind uint8
+
+const (
+	Internal     Kind = iota // a bug or a failing dependency; errors without a kind are Internal
+	NotFound                 // the thing asked for does not exist (or is hidden from the caller)
+	Conflict                 // the request clashes with the current state: a duplicate, a stale version
+	Unauthorized             // the caller's credentials are missing, wrong or expired
+	Invalid                  // the request itself is malformed or breaks a rule
+	Forbidden                // the caller is known but may not do this, e.g. over a quota
+)
+
+var kindNames = [...]string{
+	Internal:     "internal",
+	NotFound:     "not found",
+	Conflict:     "conflict",
+	Unauthorized: "unauthorized",
+	Invalid:      "invalid",
+	Forbidden:    "forbidden",
+}
+
+// String returns the kind's name.
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Error makes a Kind usable as an errors.Is target.
+func (k Kind) Error() string {
+	return "apperr: " + k.String()
+}
+
+// Error is an error of a Kind. Code, if set, is what clients see instead of
+// the kind's generic code, formatted with Args; Msg is for logs. Err is the
+// wrapped cause, if any.
+type Error struct {
+	Kind Kind
+	Code string
+	Args []any
+	Msg  string
+	Err  error
+}
+
+// New returns an error of kind with code (may be empty) and log message
+// msg, typically to declare a sentinel error.
+func New(kind Kind, code, msg string) *Error {
+	return &Error{Kind: kind, Code: code, Msg: msg}
+}
+
+// Wrap returns err as the cause of an error of kind that clients see as
+// code, formatted with args. It returns nil if err is nil.
+func Wrap(err error, kind Kind, code string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Code: code, Args: args, Err: err}
+}
+
+// Error returns Msg (or Code, or the kind), followed by the cause.
+func (e *Error) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = e.Code
+	}
+	if msg == "" {
+		msg = e.Kind.Error()
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is e's Kind.
+func (e *Error) Is(target error) bool {
+	k, ok := target.(Kind)
+	return ok && k == e.Kind
+}
+
+// KindOf returns the kind of the outermost Error in err's chain, or
+// Internal if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// CodeOf returns the code and args of the outermost Error in err's chain
+// that has a code, or "" if none has.
+func CodeOf(err error) (string, []any) {
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.Code != "" {
+			return e.Code, e.Args
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				if code, args := CodeOf(inner); code != "" {
+					return code, args
+				}
+			}
+			return "", nil
+		default:
+			return "", nil
+		}
+	}
+	return "", nil
+}
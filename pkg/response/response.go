@@ -1,24 +1,152 @@
 // Package response provides reusable JSON response helpers.
+//
+// Every JSON response uses the same envelope:
+//
+//	{"data": ..., "error": null, "meta": {"requestId": "...", "pagination": {...}}}
+//
+// On success data holds the payload and error is null; on failure data is
+// null and error holds a message and, usually, a machine-readable code.
 package response
 
 import (
 	"encoding/json"
 	"log"
 	"net/http"
+
+	"ofenes/pkg/apperr"
 )
 
-// JSON writes a JSON-encoded value to the ResponseWriter with the given status code.
+// RequestIDHeader carries the request ID. The request ID middleware sets it
+// on the response before the handler runs; the helpers copy it into
+// meta.requestId.
+const RequestIDHeader = "X-Request-ID"
+
+// StatusClientClosedRequest (nginx's 499) answers a request whose client
+// hung up before it was done. Nobody reads it; it keeps such requests apart
+// from real failures in logs and metrics.
+const StatusClientClosedRequest = 499
+
+// Envelope is the body of every JSON response.
+type Envelope struct {
+	Data  any        `json:"data"`
+	Error *ErrorBody `json:"error"`
+	Meta  Meta       `json:"meta"`
+}
+
+// ErrorBody describes a failed request.
+type ErrorBody struct {
+	Code    string `json:"code,omitempty"` // stable, for clients to branch on
+	Message string `json:"message"`        // human-readable, possibly translated
+}
+
+// Meta carries data about the response rather than the resource.
+type Meta struct {
+	RequestID  string      `json:"requestId,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"` // list endpoints only
+}
+
+// Pagination describes one page of a list.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Count      int    `json:"count"`                // items in this page
+	HasMore    bool   `json:"hasMore"`              // the page is full, so the next one may not be empty
+	NextCursor string `json:"nextCursor,omitempty"` // for cursor-based lists, the value to pass for the next page
+}
+
+// Page builds the pagination metadata for a page of count items fetched
+// with limit and offset.
+func Page(limit, offset, count int) Pagination {
+	return Pagination{Limit: limit, Offset: offset, Count: count, HasMore: limit > 0 && count >= limit}
+}
+
+// JSON writes data in the envelope with the given status code.
 // It sets the Content-Type header to application/json.
 func JSON(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	write(w, status, Envelope{Data: data})
+}
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("response.JSON: failed to encode: %v", err)
+// Paginated writes one page of a list (200 OK) with its pagination metadata.
+func Paginated(w http.ResponseWriter, data any, p Pagination) {
+	write(w, http.StatusOK, Envelope{Data: data, Meta: Meta{Pagination: &p}})
+}
+
+// Created writes data with 201 Created. A non-empty location is sent as
+// the Location header, pointing at the new resource.
+func Created(w http.ResponseWriter, location string, data any) {
+	if location != "" {
+		w.Header().Set("Location", location)
 	}
+	JSON(w, http.StatusCreated, data)
+}
+
+// NoContent writes an empty 204 No Content response.
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // Error writes a JSON error response.
 func Error(w http.ResponseWriter, status int, message string) {
-	JSON(w, status, map[string]string{"error": message})
+	write(w, status, Envelope{Error: &ErrorBody{Message: message}})
+}
+
+// ErrorCode writes a JSON error response carrying a machine-readable
+// code next to the (usually translated) message.
+func ErrorCode(w http.ResponseWriter, status int, code, message string) {
+	write(w, status, Envelope{Error: &ErrorBody{Code: code, Message: message}})
+}
+
+// StatusOf returns the status answering err, by its apperr kind: 404 Not
+// Found, 409 Conflict, 401 Unauthorized, 400 Bad Request, 403 Forbidden,
+// and 500 for Internal errors and errors without a kind.
+func StatusOf(err error) int {
+	switch apperr.KindOf(err) {
+	case apperr.NotFound:
+		return http.StatusNotFound
+	case apperr.Conflict:
+		return http.StatusConflict
+	case apperr.Unauthorized:
+		return http.StatusUnauthorized
+	case apperr.Invalid:
+		return http.StatusBadRequest
+	case apperr.Forbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// kindCodes are the codes of errors whose chain carries no code.
+var kindCodes = map[apperr.Kind]string{
+	apperr.Internal:     "internal_error",
+	apperr.NotFound:     "not_found",
+	apperr.Conflict:     "conflict",
+	apperr.Unauthorized: "unauthorized",
+	apperr.Invalid:      "invalid_request",
+	apperr.Forbidden:    "forbidden",
+}
+
+// CodeOf returns the error code answering err, with its args: the apperr
+// code in err's chain if there is one, else internalCode for Internal
+// errors (when not empty), else the generic code of err's kind.
+func CodeOf(err error, internalCode string) (string, []any) {
+	if code, args := apperr.CodeOf(err); code != "" {
+		return code, args
+	}
+	kind := apperr.KindOf(err)
+	if kind == apperr.Internal && internalCode != "" {
+		return internalCode, nil
+	}
+	return kindCodes[kind], nil
+}
+
+// write fills in the request ID and encodes env.
+func write(w http.ResponseWriter, status int, env Envelope) {
+	env.Meta.RequestID = w.Header().Get(RequestIDHeader)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		log.Printf("response.JSON: failed to encode: %v", err)
+	}
 }
@@ -0,0 +1,71 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// NDJSONContentType is the media type of newline-delimited JSON.
+const NDJSONContentType = "application/x-ndjson"
+
+// WantsNDJSON reports whether the client asked for an NDJSON stream, with
+// ?format=ndjson or an Accept header naming application/x-ndjson.
+func WantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, _ := mime.ParseMediaType(accept); mediaType == NDJSONContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream writes a newline-delimited JSON response (one value per line)
+// incrementally, for results too large to build in memory. Lines are the
+// bare items, not envelopes.
+//
+// The status (200) is sent when the stream is created, so check for the
+// errors you can before creating it. A failure after that is reported by
+// Fail as a last line holding an error envelope (data null, error set);
+// a stream that ends without one is complete.
+type Stream struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	enc *json.Encoder
+}
+
+// NewStream starts an NDJSON response.
+func NewStream(w http.ResponseWriter) *Stream {
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	return &Stream{w: w, rc: http.NewResponseController(w), enc: json.NewEncoder(w)}
+}
+
+// Send writes v as one line. Output is buffered; call Flush to push it to
+// the client. An error means the client is gone and the handler can stop.
+func (s *Stream) Send(v any) error {
+	return s.enc.Encode(v)
+}
+
+// Flush sends buffered lines to the client.
+func (s *Stream) Flush() error {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Fail ends the stream with an error line.
+func (s *Stream) Fail(code, message string) {
+	env := Envelope{Error: &ErrorBody{Code: code, Message: message}}
+	env.Meta.RequestID = s.w.Header().Get(RequestIDHeader)
+	if s.enc.Encode(env) == nil {
+		s.Flush()
+	}
+}